@@ -8,21 +8,34 @@ import (
 
 	"github.com/spf13/viper"
 	"github.com/xataio/pgstream/pkg/backoff"
+	"github.com/xataio/pgstream/pkg/blobstore"
 	"github.com/xataio/pgstream/pkg/kafka"
 	"github.com/xataio/pgstream/pkg/otel"
 	pgschemalog "github.com/xataio/pgstream/pkg/schemalog/postgres"
+	"github.com/xataio/pgstream/pkg/schemaregistry"
 	pgsnapshotgenerator "github.com/xataio/pgstream/pkg/snapshot/generator/postgres/data"
 	"github.com/xataio/pgstream/pkg/snapshot/generator/postgres/schema/pgdumprestore"
 	"github.com/xataio/pgstream/pkg/stream"
 	"github.com/xataio/pgstream/pkg/tls"
+	filecheckpoint "github.com/xataio/pgstream/pkg/wal/checkpointer/file"
 	kafkacheckpoint "github.com/xataio/pgstream/pkg/wal/checkpointer/kafka"
+	dlqfile "github.com/xataio/pgstream/pkg/wal/dlq/file"
+	dlqkafka "github.com/xataio/pgstream/pkg/wal/dlq/kafka"
+	dlqpostgres "github.com/xataio/pgstream/pkg/wal/dlq/postgres"
+	filelistener "github.com/xataio/pgstream/pkg/wal/listener/file"
 	"github.com/xataio/pgstream/pkg/wal/listener/snapshot/adapter"
 	snapshotbuilder "github.com/xataio/pgstream/pkg/wal/listener/snapshot/builder"
 	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+	"github.com/xataio/pgstream/pkg/wal/processor/fanout"
+	fileprocessor "github.com/xataio/pgstream/pkg/wal/processor/file"
 	"github.com/xataio/pgstream/pkg/wal/processor/filter"
 	"github.com/xataio/pgstream/pkg/wal/processor/injector"
 	kafkaprocessor "github.com/xataio/pgstream/pkg/wal/processor/kafka"
+	"github.com/xataio/pgstream/pkg/wal/processor/outbox"
+	parquetprocessor "github.com/xataio/pgstream/pkg/wal/processor/parquet"
 	"github.com/xataio/pgstream/pkg/wal/processor/postgres"
+	"github.com/xataio/pgstream/pkg/wal/processor/projection"
+	"github.com/xataio/pgstream/pkg/wal/processor/router"
 	"github.com/xataio/pgstream/pkg/wal/processor/search"
 	"github.com/xataio/pgstream/pkg/wal/processor/search/store"
 	"github.com/xataio/pgstream/pkg/wal/processor/transformer"
@@ -40,6 +53,8 @@ func init() {
 
 	viper.BindEnv("PGSTREAM_POSTGRES_LISTENER_URL")
 	viper.BindEnv("PGSTREAM_POSTGRES_REPLICATION_SLOT_NAME")
+	viper.BindEnv("PGSTREAM_POSTGRES_REPLICATION_PLUGIN")
+	viper.BindEnv("PGSTREAM_POSTGRES_REPLICATION_PUBLICATION_NAME")
 
 	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_LISTENER_URL")
 	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_BATCH_BYTES")
@@ -66,6 +81,32 @@ func init() {
 	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_DISABLE_TRIGGERS")
 	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_ON_CONFLICT_ACTION")
 	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_BULK_INGEST_ENABLED")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_TRANSACTION_CONSISTENT")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_EXACTLY_ONCE_ENABLED")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_EXACTLY_ONCE_STREAM_ID")
+
+	viper.BindEnv("PGSTREAM_FILE_WRITER_PATH")
+	viper.BindEnv("PGSTREAM_FILE_WRITER_FORMAT")
+	viper.BindEnv("PGSTREAM_FILE_WRITER_COMPRESSION")
+	viper.BindEnv("PGSTREAM_FILE_WRITER_ROTATION_MAX_BYTES")
+	viper.BindEnv("PGSTREAM_FILE_WRITER_ROTATION_INTERVAL")
+	viper.BindEnv("PGSTREAM_FILE_WRITER_BATCH_TIMEOUT")
+	viper.BindEnv("PGSTREAM_FILE_WRITER_BATCH_BYTES")
+	viper.BindEnv("PGSTREAM_FILE_WRITER_BATCH_SIZE")
+	viper.BindEnv("PGSTREAM_FILE_WRITER_MAX_QUEUE_BYTES")
+
+	viper.BindEnv("PGSTREAM_PARQUET_WRITER_PATH")
+	viper.BindEnv("PGSTREAM_PARQUET_WRITER_COMPRESSION")
+	viper.BindEnv("PGSTREAM_PARQUET_WRITER_ROW_GROUP_SIZE")
+	viper.BindEnv("PGSTREAM_PARQUET_WRITER_ROTATION_MAX_ROWS")
+	viper.BindEnv("PGSTREAM_PARQUET_WRITER_ROTATION_INTERVAL")
+	viper.BindEnv("PGSTREAM_PARQUET_WRITER_BATCH_TIMEOUT")
+	viper.BindEnv("PGSTREAM_PARQUET_WRITER_BATCH_BYTES")
+	viper.BindEnv("PGSTREAM_PARQUET_WRITER_BATCH_SIZE")
+	viper.BindEnv("PGSTREAM_PARQUET_WRITER_MAX_QUEUE_BYTES")
+
+	viper.BindEnv("PGSTREAM_FILE_LISTENER_PATHS")
+	viper.BindEnv("PGSTREAM_FILE_CHECKPOINTER_PATH")
 
 	viper.BindEnv("PGSTREAM_KAFKA_READER_SERVERS")
 	viper.BindEnv("PGSTREAM_KAFKA_WRITER_SERVERS")
@@ -77,6 +118,16 @@ func init() {
 	viper.BindEnv("PGSTREAM_KAFKA_COMMIT_EXP_BACKOFF_MAX_RETRIES")
 	viper.BindEnv("PGSTREAM_KAFKA_COMMIT_BACKOFF_INTERVAL")
 	viper.BindEnv("PGSTREAM_KAFKA_COMMIT_BACKOFF_MAX_RETRIES")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_RETRY_EXP_BACKOFF_INITIAL_INTERVAL")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_RETRY_EXP_BACKOFF_MAX_INTERVAL")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_RETRY_EXP_BACKOFF_MAX_RETRIES")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_RETRY_BACKOFF_INTERVAL")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_RETRY_BACKOFF_MAX_RETRIES")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_ON_FAILURE")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_ERROR_TOPIC_NAME")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_ERROR_TOPIC_PARTITIONS")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_ERROR_TOPIC_REPLICATION_FACTOR")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_ERROR_TOPIC_AUTO_CREATE")
 	viper.BindEnv("PGSTREAM_KAFKA_TOPIC_PARTITIONS")
 	viper.BindEnv("PGSTREAM_KAFKA_TOPIC_REPLICATION_FACTOR")
 	viper.BindEnv("PGSTREAM_KAFKA_TOPIC_AUTO_CREATE")
@@ -84,6 +135,43 @@ func init() {
 	viper.BindEnv("PGSTREAM_KAFKA_WRITER_BATCH_BYTES")
 	viper.BindEnv("PGSTREAM_KAFKA_WRITER_BATCH_SIZE")
 	viper.BindEnv("PGSTREAM_KAFKA_WRITER_MAX_QUEUE_BYTES")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_FORMAT")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_KEY_STRATEGY")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_KEY_COLUMNS")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_COMPACTION")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_COMPRESSION")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_REQUIRED_ACKS")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_DISABLE_RETRIES")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_TOPIC_TEMPLATE")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_TOPIC_ROUTING_RULES_FILE")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_HEADERS_FILE")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_SCHEMA_REGISTRY_URL")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_SCHEMA_REGISTRY_USERNAME")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_SCHEMA_REGISTRY_PASSWORD")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_FORMAT")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_CONCURRENCY")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_TOPICS")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_TOPIC_PATTERN")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_TOPIC_REFRESH_INTERVAL")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_SCHEMA_REGISTRY_URL")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_SCHEMA_REGISTRY_USERNAME")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_SCHEMA_REGISTRY_PASSWORD")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_LARGE_MESSAGES_STRATEGY")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_BLOB_STORE_DIR")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_BLOB_STORE_S3_ENDPOINT")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_BLOB_STORE_S3_REGION")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_BLOB_STORE_S3_BUCKET")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_BLOB_STORE_S3_PREFIX")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_BLOB_STORE_S3_ACCESS_KEY_ID")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_BLOB_STORE_S3_SECRET_ACCESS_KEY")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_BLOB_STORE_DIR")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_BLOB_STORE_S3_ENDPOINT")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_BLOB_STORE_S3_REGION")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_BLOB_STORE_S3_BUCKET")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_BLOB_STORE_S3_PREFIX")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_BLOB_STORE_S3_ACCESS_KEY_ID")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_BLOB_STORE_S3_SECRET_ACCESS_KEY")
+	viper.BindEnv("PGSTREAM_KAFKA_READER_DELETE_CLAIM_CHECKS")
 
 	viper.BindEnv("PGSTREAM_OPENSEARCH_STORE_URL")
 	viper.BindEnv("PGSTREAM_ELASTICSEARCH_STORE_URL")
@@ -111,11 +199,46 @@ func init() {
 	viper.BindEnv("PGSTREAM_TRANSFORMER_RULES_FILE")
 	viper.BindEnv("PGSTREAM_FILTER_INCLUDE_TABLES")
 	viper.BindEnv("PGSTREAM_FILTER_EXCLUDE_TABLES")
+	viper.BindEnv("PGSTREAM_FILTER_ROW_FILTERS_FILE")
+	viper.BindEnv("PGSTREAM_OUTBOX_RULES_FILE")
+	viper.BindEnv("PGSTREAM_PROJECTION_RULES_FILE")
+	viper.BindEnv("PGSTREAM_ROUTING_RULES_FILE")
+
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_TRANSFORMER_RULES_FILE")
+	viper.BindEnv("PGSTREAM_KAFKA_WRITER_MAX_LAG")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_TRANSFORMER_RULES_FILE")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_MAX_LAG")
+	viper.BindEnv("PGSTREAM_SEARCH_INDEXER_TRANSFORMER_RULES_FILE")
+	viper.BindEnv("PGSTREAM_SEARCH_INDEXER_MAX_LAG")
+	viper.BindEnv("PGSTREAM_WEBHOOK_NOTIFIER_TRANSFORMER_RULES_FILE")
+	viper.BindEnv("PGSTREAM_WEBHOOK_NOTIFIER_MAX_LAG")
+	viper.BindEnv("PGSTREAM_FILE_WRITER_TRANSFORMER_RULES_FILE")
+	viper.BindEnv("PGSTREAM_FILE_WRITER_MAX_LAG")
+	viper.BindEnv("PGSTREAM_PARQUET_WRITER_TRANSFORMER_RULES_FILE")
+	viper.BindEnv("PGSTREAM_PARQUET_WRITER_MAX_LAG")
+
+	viper.BindEnv("PGSTREAM_DLQ_POSTGRES_URL")
+	viper.BindEnv("PGSTREAM_DLQ_KAFKA_SERVERS")
+	viper.BindEnv("PGSTREAM_DLQ_KAFKA_TOPIC_NAME")
+	viper.BindEnv("PGSTREAM_DLQ_KAFKA_TOPIC_PARTITIONS")
+	viper.BindEnv("PGSTREAM_DLQ_KAFKA_TOPIC_REPLICATION_FACTOR")
+	viper.BindEnv("PGSTREAM_DLQ_KAFKA_TOPIC_AUTO_CREATE")
+	viper.BindEnv("PGSTREAM_DLQ_KAFKA_TLS_ENABLED")
+	viper.BindEnv("PGSTREAM_DLQ_KAFKA_TLS_CA_CERT_FILE")
+	viper.BindEnv("PGSTREAM_DLQ_KAFKA_TLS_CLIENT_CERT_FILE")
+	viper.BindEnv("PGSTREAM_DLQ_KAFKA_TLS_CLIENT_KEY_FILE")
+	viper.BindEnv("PGSTREAM_DLQ_KAFKA_SASL_MECHANISM")
+	viper.BindEnv("PGSTREAM_DLQ_KAFKA_SASL_USERNAME")
+	viper.BindEnv("PGSTREAM_DLQ_KAFKA_SASL_PASSWORD")
+	viper.BindEnv("PGSTREAM_DLQ_FILE_PATH")
 
 	viper.BindEnv("PGSTREAM_KAFKA_TLS_ENABLED")
 	viper.BindEnv("PGSTREAM_KAFKA_TLS_CA_CERT_FILE")
 	viper.BindEnv("PGSTREAM_KAFKA_TLS_CLIENT_CERT_FILE")
 	viper.BindEnv("PGSTREAM_KAFKA_TLS_CLIENT_KEY_FILE")
+	viper.BindEnv("PGSTREAM_KAFKA_SASL_MECHANISM")
+	viper.BindEnv("PGSTREAM_KAFKA_SASL_USERNAME")
+	viper.BindEnv("PGSTREAM_KAFKA_SASL_PASSWORD")
 }
 
 func envToOtelConfig() (*otel.Config, error) {
@@ -151,17 +274,55 @@ func envConfigToStreamConfig() (*stream.Config, error) {
 		return nil, err
 	}
 	return &stream.Config{
-		Listener:  parseListenerConfig(),
-		Processor: processorCfg,
+		Listener:        parseListenerConfig(),
+		Processor:       processorCfg,
+		DeadLetterQueue: parseDeadLetterQueueConfig(),
 	}, nil
 }
 
+// dead letter queue parsing
+
+func parseDeadLetterQueueConfig() *stream.DeadLetterQueueConfig {
+	cfg := &stream.DeadLetterQueueConfig{}
+	if pgURL := viper.GetString("PGSTREAM_DLQ_POSTGRES_URL"); pgURL != "" {
+		cfg.Postgres = &dlqpostgres.Config{
+			URL: pgURL,
+		}
+	}
+	if kafkaServers := viper.GetStringSlice("PGSTREAM_DLQ_KAFKA_SERVERS"); len(kafkaServers) > 0 {
+		cfg.Kafka = &dlqkafka.Config{
+			Conn: kafka.ConnConfig{
+				Servers: kafkaServers,
+				Topic: kafka.TopicConfig{
+					Name:              viper.GetString("PGSTREAM_DLQ_KAFKA_TOPIC_NAME"),
+					NumPartitions:     viper.GetInt("PGSTREAM_DLQ_KAFKA_TOPIC_PARTITIONS"),
+					ReplicationFactor: viper.GetInt("PGSTREAM_DLQ_KAFKA_TOPIC_REPLICATION_FACTOR"),
+					AutoCreate:        viper.GetBool("PGSTREAM_DLQ_KAFKA_TOPIC_AUTO_CREATE"),
+				},
+				TLS:  parseTLSConfig("PGSTREAM_DLQ_KAFKA"),
+				SASL: parseSASLConfig("PGSTREAM_DLQ_KAFKA"),
+			},
+		}
+	}
+	if path := viper.GetString("PGSTREAM_DLQ_FILE_PATH"); path != "" {
+		cfg.File = &dlqfile.Config{
+			Path: path,
+		}
+	}
+
+	if cfg.Postgres == nil && cfg.Kafka == nil && cfg.File == nil {
+		return nil
+	}
+	return cfg
+}
+
 // listener parsing
 
 func parseListenerConfig() stream.ListenerConfig {
 	return stream.ListenerConfig{
 		Postgres: parsePostgresListenerConfig(),
 		Kafka:    parseKafkaListenerConfig(),
+		File:     parseFileListenerConfig(),
 		Snapshot: parseSnapshotListenerConfig(),
 	}
 }
@@ -176,6 +337,8 @@ func parsePostgresListenerConfig() *stream.PostgresListenerConfig {
 		Replication: pgreplication.Config{
 			PostgresURL:         pgURL,
 			ReplicationSlotName: viper.GetString("PGSTREAM_POSTGRES_REPLICATION_SLOT_NAME"),
+			Plugin:              viper.GetString("PGSTREAM_POSTGRES_REPLICATION_PLUGIN"),
+			PublicationName:     viper.GetString("PGSTREAM_POSTGRES_REPLICATION_PUBLICATION_NAME"),
 		},
 	}
 
@@ -248,14 +411,30 @@ func parseSchemaSnapshotConfig(pgurl string) snapshotbuilder.SchemaSnapshotConfi
 func parseKafkaListenerConfig() *stream.KafkaListenerConfig {
 	kafkaTopic := viper.GetString("PGSTREAM_KAFKA_TOPIC_NAME")
 	kafkaServers := viper.GetStringSlice("PGSTREAM_KAFKA_READER_SERVERS")
-	if len(kafkaServers) == 0 || kafkaTopic == "" {
+	hasTopics := kafkaTopic != "" || len(viper.GetStringSlice("PGSTREAM_KAFKA_READER_TOPICS")) > 0 || viper.GetString("PGSTREAM_KAFKA_READER_TOPIC_PATTERN") != ""
+	if len(kafkaServers) == 0 || !hasTopics {
 		return nil
 	}
 
 	consumerGroupID := viper.GetString("PGSTREAM_KAFKA_READER_CONSUMER_GROUP_ID")
 	return &stream.KafkaListenerConfig{
-		Reader:       parseKafkaReaderConfig(kafkaServers, kafkaTopic, consumerGroupID),
-		Checkpointer: parseKafkaCheckpointConfig(),
+		Reader:            parseKafkaReaderConfig(kafkaServers, kafkaTopic, consumerGroupID),
+		Checkpointer:      parseKafkaCheckpointConfig(),
+		SchemaRegistry:    parseSchemaRegistryConfig("PGSTREAM_KAFKA_READER"),
+		Format:            viper.GetString("PGSTREAM_KAFKA_READER_FORMAT"),
+		Concurrency:       viper.GetInt("PGSTREAM_KAFKA_READER_CONCURRENCY"),
+		BlobStore:         parseBlobStoreConfig("PGSTREAM_KAFKA_READER"),
+		DeleteClaimChecks: viper.GetBool("PGSTREAM_KAFKA_READER_DELETE_CLAIM_CHECKS"),
+		Retry: stream.KafkaListenerRetryConfig{
+			Backoff:   parseBackoffConfig("PGSTREAM_KAFKA_READER_RETRY"),
+			OnFailure: viper.GetString("PGSTREAM_KAFKA_READER_ON_FAILURE"),
+			ErrorTopic: kafka.TopicConfig{
+				Name:              viper.GetString("PGSTREAM_KAFKA_READER_ERROR_TOPIC_NAME"),
+				NumPartitions:     viper.GetInt("PGSTREAM_KAFKA_READER_ERROR_TOPIC_PARTITIONS"),
+				ReplicationFactor: viper.GetInt("PGSTREAM_KAFKA_READER_ERROR_TOPIC_REPLICATION_FACTOR"),
+				AutoCreate:        viper.GetBool("PGSTREAM_KAFKA_READER_ERROR_TOPIC_AUTO_CREATE"),
+			},
+		},
 	}
 }
 
@@ -266,10 +445,14 @@ func parseKafkaReaderConfig(kafkaServers []string, kafkaTopic, consumerGroupID s
 			Topic: kafka.TopicConfig{
 				Name: kafkaTopic,
 			},
-			TLS: parseTLSConfig("PGSTREAM_KAFKA"),
+			TLS:  parseTLSConfig("PGSTREAM_KAFKA"),
+			SASL: parseSASLConfig("PGSTREAM_KAFKA"),
 		},
 		ConsumerGroupID:          consumerGroupID,
 		ConsumerGroupStartOffset: viper.GetString("PGSTREAM_KAFKA_READER_CONSUMER_GROUP_START_OFFSET"),
+		Topics:                   viper.GetStringSlice("PGSTREAM_KAFKA_READER_TOPICS"),
+		TopicPattern:             viper.GetString("PGSTREAM_KAFKA_READER_TOPIC_PATTERN"),
+		TopicRefreshInterval:     viper.GetDuration("PGSTREAM_KAFKA_READER_TOPIC_REFRESH_INTERVAL"),
 	}
 }
 
@@ -279,6 +462,22 @@ func parseKafkaCheckpointConfig() kafkacheckpoint.Config {
 	}
 }
 
+func parseFileListenerConfig() *stream.FileListenerConfig {
+	paths := viper.GetStringSlice("PGSTREAM_FILE_LISTENER_PATHS")
+	if len(paths) == 0 {
+		return nil
+	}
+
+	return &stream.FileListenerConfig{
+		Reader: filelistener.Config{
+			Paths: paths,
+		},
+		Checkpointer: filecheckpoint.Config{
+			Path: viper.GetString("PGSTREAM_FILE_CHECKPOINTER_PATH"),
+		},
+	}
+}
+
 // processor parsing
 
 func parseProcessorConfig() (stream.ProcessorConfig, error) {
@@ -286,17 +485,144 @@ func parseProcessorConfig() (stream.ProcessorConfig, error) {
 	if err != nil {
 		return stream.ProcessorConfig{}, err
 	}
-	return stream.ProcessorConfig{
+	outboxCfg, err := parseOutboxConfig()
+	if err != nil {
+		return stream.ProcessorConfig{}, err
+	}
+	filterCfg, err := parseFilterConfig()
+	if err != nil {
+		return stream.ProcessorConfig{}, err
+	}
+	projectionCfg, err := parseProjectionConfig()
+	if err != nil {
+		return stream.ProcessorConfig{}, err
+	}
+	routingCfg, err := parseRoutingConfig()
+	if err != nil {
+		return stream.ProcessorConfig{}, err
+	}
+	processorCfg := stream.ProcessorConfig{
 		Kafka:       parseKafkaProcessorConfig(),
 		Search:      parseSearchProcessorConfig(),
 		Webhook:     parseWebhookProcessorConfig(),
+		File:        parseFileProcessorConfig(),
+		Parquet:     parseParquetProcessorConfig(),
 		Postgres:    parsePostgresProcessorConfig(),
 		Injector:    parseInjectorConfig(),
 		Transformer: transformerCfg,
-		Filter:      parseFilterConfig(),
+		Filter:      filterCfg,
+		Outbox:      outboxCfg,
+		Projection:  projectionCfg,
+		Routing:     routingCfg,
+	}
+	if err := parseTargetConfigs(&processorCfg); err != nil {
+		return stream.ProcessorConfig{}, err
+	}
+	return processorCfg, nil
+}
+
+// parseTargetConfigs parses the target specific settings of the configured
+// targets.
+func parseTargetConfigs(cfg *stream.ProcessorConfig) error {
+	var err error
+	if cfg.Kafka != nil {
+		if cfg.Kafka.Target, err = parseTargetConfig("PGSTREAM_KAFKA_WRITER"); err != nil {
+			return err
+		}
+		if cfg.Kafka.Writer.TopicRouting, err = parseKafkaTopicRoutingConfig(); err != nil {
+			return err
+		}
+		if cfg.Kafka.Writer.Headers, err = parseKafkaHeadersFile(); err != nil {
+			return err
+		}
+	}
+	if cfg.Postgres != nil {
+		if cfg.Postgres.Target, err = parseTargetConfig("PGSTREAM_POSTGRES_WRITER"); err != nil {
+			return err
+		}
+	}
+	if cfg.Search != nil {
+		if cfg.Search.Target, err = parseTargetConfig("PGSTREAM_SEARCH_INDEXER"); err != nil {
+			return err
+		}
+	}
+	if cfg.Webhook != nil {
+		if cfg.Webhook.Target, err = parseTargetConfig("PGSTREAM_WEBHOOK_NOTIFIER"); err != nil {
+			return err
+		}
+	}
+	if cfg.File != nil {
+		if cfg.File.Target, err = parseTargetConfig("PGSTREAM_FILE_WRITER"); err != nil {
+			return err
+		}
+	}
+	if cfg.Parquet != nil {
+		if cfg.Parquet.Target, err = parseTargetConfig("PGSTREAM_PARQUET_WRITER"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseTargetConfig(prefix string) (stream.TargetConfig, error) {
+	transformerCfg, err := parseTransformerRulesFile(fmt.Sprintf("%s_TRANSFORMER_RULES_FILE", prefix))
+	if err != nil {
+		return stream.TargetConfig{}, err
+	}
+	return stream.TargetConfig{
+		Transformer: transformerCfg,
+		Fanout: fanout.TargetConfig{
+			MaxLag: viper.GetInt(fmt.Sprintf("%s_MAX_LAG", prefix)),
+		},
 	}, nil
 }
 
+func parseFileProcessorConfig() *stream.FileProcessorConfig {
+	path := viper.GetString("PGSTREAM_FILE_WRITER_PATH")
+	if path == "" {
+		return nil
+	}
+
+	return &stream.FileProcessorConfig{
+		Writer: fileprocessor.Config{
+			Dir:              path,
+			Format:           fileprocessor.Format(viper.GetString("PGSTREAM_FILE_WRITER_FORMAT")),
+			Compression:      fileprocessor.Compression(viper.GetString("PGSTREAM_FILE_WRITER_COMPRESSION")),
+			MaxFileBytes:     viper.GetInt64("PGSTREAM_FILE_WRITER_ROTATION_MAX_BYTES"),
+			RotationInterval: viper.GetDuration("PGSTREAM_FILE_WRITER_ROTATION_INTERVAL"),
+			Batch: batch.Config{
+				BatchTimeout:  viper.GetDuration("PGSTREAM_FILE_WRITER_BATCH_TIMEOUT"),
+				MaxBatchBytes: viper.GetInt64("PGSTREAM_FILE_WRITER_BATCH_BYTES"),
+				MaxBatchSize:  viper.GetInt64("PGSTREAM_FILE_WRITER_BATCH_SIZE"),
+				MaxQueueBytes: viper.GetInt64("PGSTREAM_FILE_WRITER_MAX_QUEUE_BYTES"),
+			},
+		},
+	}
+}
+
+func parseParquetProcessorConfig() *stream.ParquetProcessorConfig {
+	path := viper.GetString("PGSTREAM_PARQUET_WRITER_PATH")
+	if path == "" {
+		return nil
+	}
+
+	return &stream.ParquetProcessorConfig{
+		Writer: parquetprocessor.Config{
+			Dir:              path,
+			Compression:      parquetprocessor.Compression(viper.GetString("PGSTREAM_PARQUET_WRITER_COMPRESSION")),
+			RowGroupSize:     viper.GetInt64("PGSTREAM_PARQUET_WRITER_ROW_GROUP_SIZE"),
+			MaxFileRows:      viper.GetInt64("PGSTREAM_PARQUET_WRITER_ROTATION_MAX_ROWS"),
+			RotationInterval: viper.GetDuration("PGSTREAM_PARQUET_WRITER_ROTATION_INTERVAL"),
+			Batch: batch.Config{
+				BatchTimeout:  viper.GetDuration("PGSTREAM_PARQUET_WRITER_BATCH_TIMEOUT"),
+				MaxBatchBytes: viper.GetInt64("PGSTREAM_PARQUET_WRITER_BATCH_BYTES"),
+				MaxBatchSize:  viper.GetInt64("PGSTREAM_PARQUET_WRITER_BATCH_SIZE"),
+				MaxQueueBytes: viper.GetInt64("PGSTREAM_PARQUET_WRITER_MAX_QUEUE_BYTES"),
+			},
+		},
+	}
+}
+
 func parseKafkaProcessorConfig() *stream.KafkaProcessorConfig {
 	kafkaTopic := viper.GetString("PGSTREAM_KAFKA_TOPIC_NAME")
 	kafkaServers := viper.GetStringSlice("PGSTREAM_KAFKA_WRITER_SERVERS")
@@ -319,7 +645,11 @@ func parseKafkaWriterConfig(kafkaServers []string, kafkaTopic string) *kafkaproc
 				ReplicationFactor: viper.GetInt("PGSTREAM_KAFKA_TOPIC_REPLICATION_FACTOR"),
 				AutoCreate:        viper.GetBool("PGSTREAM_KAFKA_TOPIC_AUTO_CREATE"),
 			},
-			TLS: parseTLSConfig("PGSTREAM_KAFKA"),
+			TLS:            parseTLSConfig("PGSTREAM_KAFKA"),
+			SASL:           parseSASLConfig("PGSTREAM_KAFKA"),
+			Compression:    viper.GetString("PGSTREAM_KAFKA_WRITER_COMPRESSION"),
+			RequiredAcks:   viper.GetString("PGSTREAM_KAFKA_WRITER_REQUIRED_ACKS"),
+			DisableRetries: viper.GetBool("PGSTREAM_KAFKA_WRITER_DISABLE_RETRIES"),
 		},
 		Batch: batch.Config{
 			BatchTimeout:  viper.GetDuration("PGSTREAM_KAFKA_WRITER_BATCH_TIMEOUT"),
@@ -327,9 +657,64 @@ func parseKafkaWriterConfig(kafkaServers []string, kafkaTopic string) *kafkaproc
 			MaxBatchSize:  viper.GetInt64("PGSTREAM_KAFKA_WRITER_BATCH_SIZE"),
 			MaxQueueBytes: viper.GetInt64("PGSTREAM_KAFKA_WRITER_MAX_QUEUE_BYTES"),
 		},
+		Format:         viper.GetString("PGSTREAM_KAFKA_WRITER_FORMAT"),
+		SchemaRegistry: parseSchemaRegistryConfig("PGSTREAM_KAFKA_WRITER"),
+		KeyStrategy:    viper.GetString("PGSTREAM_KAFKA_WRITER_KEY_STRATEGY"),
+		KeyColumns:     viper.GetStringSlice("PGSTREAM_KAFKA_WRITER_KEY_COLUMNS"),
+		Compaction:     viper.GetBool("PGSTREAM_KAFKA_WRITER_COMPACTION"),
+		LargeMessages:  parseLargeMessagesConfig(),
+	}
+}
+
+func parseSchemaRegistryConfig(prefix string) *schemaregistry.Config {
+	url := viper.GetString(fmt.Sprintf("%s_SCHEMA_REGISTRY_URL", prefix))
+	if url == "" {
+		return nil
+	}
+	return &schemaregistry.Config{
+		URL:      url,
+		Username: viper.GetString(fmt.Sprintf("%s_SCHEMA_REGISTRY_USERNAME", prefix)),
+		Password: viper.GetString(fmt.Sprintf("%s_SCHEMA_REGISTRY_PASSWORD", prefix)),
+	}
+}
+
+func parseLargeMessagesConfig() *kafkaprocessor.LargeMessagesConfig {
+	strategy := viper.GetString("PGSTREAM_KAFKA_WRITER_LARGE_MESSAGES_STRATEGY")
+	if strategy == "" {
+		return nil
+	}
+	return &kafkaprocessor.LargeMessagesConfig{
+		Strategy:  strategy,
+		BlobStore: parseBlobStoreConfig("PGSTREAM_KAFKA_WRITER"),
 	}
 }
 
+func parseBlobStoreConfig(prefix string) *blobstore.Config {
+	dir := viper.GetString(fmt.Sprintf("%s_BLOB_STORE_DIR", prefix))
+	endpoint := viper.GetString(fmt.Sprintf("%s_BLOB_STORE_S3_ENDPOINT", prefix))
+	if dir == "" && endpoint == "" {
+		return nil
+	}
+
+	cfg := &blobstore.Config{}
+	if dir != "" {
+		cfg.Local = &blobstore.LocalConfig{
+			Dir: dir,
+		}
+	}
+	if endpoint != "" {
+		cfg.S3 = &blobstore.S3Config{
+			Endpoint:        endpoint,
+			Region:          viper.GetString(fmt.Sprintf("%s_BLOB_STORE_S3_REGION", prefix)),
+			Bucket:          viper.GetString(fmt.Sprintf("%s_BLOB_STORE_S3_BUCKET", prefix)),
+			Prefix:          viper.GetString(fmt.Sprintf("%s_BLOB_STORE_S3_PREFIX", prefix)),
+			AccessKeyID:     viper.GetString(fmt.Sprintf("%s_BLOB_STORE_S3_ACCESS_KEY_ID", prefix)),
+			SecretAccessKey: viper.GetString(fmt.Sprintf("%s_BLOB_STORE_S3_SECRET_ACCESS_KEY", prefix)),
+		}
+	}
+	return cfg
+}
+
 func parseSearchProcessorConfig() *stream.SearchProcessorConfig {
 	opensearchStore := viper.GetString("PGSTREAM_OPENSEARCH_STORE_URL")
 	elasticsearchStore := viper.GetString("PGSTREAM_ELASTICSEARCH_STORE_URL")
@@ -400,9 +785,14 @@ func parsePostgresProcessorConfig() *stream.PostgresProcessorConfig {
 			SchemaLogStore: pgschemalog.Config{
 				URL: viper.GetString("PGSTREAM_POSTGRES_WRITER_SCHEMALOG_STORE_URL"),
 			},
-			DisableTriggers:   viper.GetBool("PGSTREAM_POSTGRES_WRITER_DISABLE_TRIGGERS"),
-			OnConflictAction:  viper.GetString("PGSTREAM_POSTGRES_WRITER_ON_CONFLICT_ACTION"),
-			BulkIngestEnabled: bulkIngestEnabled,
+			DisableTriggers:       viper.GetBool("PGSTREAM_POSTGRES_WRITER_DISABLE_TRIGGERS"),
+			OnConflictAction:      viper.GetString("PGSTREAM_POSTGRES_WRITER_ON_CONFLICT_ACTION"),
+			BulkIngestEnabled:     bulkIngestEnabled,
+			TransactionConsistent: viper.GetBool("PGSTREAM_POSTGRES_WRITER_TRANSACTION_CONSISTENT"),
+			ExactlyOnce: postgres.ExactlyOnceConfig{
+				Enabled:  viper.GetBool("PGSTREAM_POSTGRES_WRITER_EXACTLY_ONCE_ENABLED"),
+				StreamID: viper.GetString("PGSTREAM_POSTGRES_WRITER_EXACTLY_ONCE_STREAM_ID"),
+			},
 		},
 	}
 
@@ -459,7 +849,11 @@ func parseInjectorConfig() *injector.Config {
 }
 
 func parseTransformerConfig() (*transformer.Config, error) {
-	filename := viper.GetString("PGSTREAM_TRANSFORMER_RULES_FILE")
+	return parseTransformerRulesFile("PGSTREAM_TRANSFORMER_RULES_FILE")
+}
+
+func parseTransformerRulesFile(envVar string) (*transformer.Config, error) {
+	filename := viper.GetString(envVar)
 	if filename == "" {
 		return nil, nil
 	}
@@ -480,16 +874,171 @@ func parseTransformerConfig() (*transformer.Config, error) {
 	return yamlConfig.Transformations.parseTransformationConfig()
 }
 
-func parseFilterConfig() *filter.Config {
+func parseFilterConfig() (*filter.Config, error) {
 	includeTables := viper.GetStringSlice("PGSTREAM_FILTER_INCLUDE_TABLES")
 	excludeTables := viper.GetStringSlice("PGSTREAM_FILTER_EXCLUDE_TABLES")
-	if len(includeTables) == 0 && len(excludeTables) == 0 {
-		return nil
+	rowFilters, err := parseRowFiltersFile()
+	if err != nil {
+		return nil, err
+	}
+	if len(includeTables) == 0 && len(excludeTables) == 0 && len(rowFilters) == 0 {
+		return nil, nil
 	}
 
 	return &filter.Config{
 		IncludeTables: includeTables,
 		ExcludeTables: excludeTables,
+		RowFilters:    rowFilters,
+	}, nil
+}
+
+func parseRowFiltersFile() (map[string]string, error) {
+	filename := viper.GetString("PGSTREAM_FILTER_ROW_FILTERS_FILE")
+	if filename == "" {
+		return nil, nil
+	}
+
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	yamlConfig := struct {
+		RowFilters []RowFilterConfig `mapstructure:"row_filters" yaml:"row_filters"`
+	}{}
+	err = yaml.Unmarshal(buf, &yamlConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format for row filters config in file %q: %w", filename, err)
+	}
+
+	return parseRowFilters(yamlConfig.RowFilters), nil
+}
+
+func parseOutboxConfig() (*outbox.Config, error) {
+	filename := viper.GetString("PGSTREAM_OUTBOX_RULES_FILE")
+	if filename == "" {
+		return nil, nil
+	}
+
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	yamlConfig := struct {
+		Outbox OutboxConfig `mapstructure:"outbox" yaml:"outbox"`
+	}{}
+	err = yaml.Unmarshal(buf, &yamlConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format for outbox config in file %q: %w", filename, err)
+	}
+
+	return yamlConfig.Outbox.parseOutboxConfig(), nil
+}
+
+func parseProjectionConfig() (*projection.Config, error) {
+	filename := viper.GetString("PGSTREAM_PROJECTION_RULES_FILE")
+	if filename == "" {
+		return nil, nil
+	}
+
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	yamlConfig := struct {
+		Projection ProjectionConfig `mapstructure:"projection" yaml:"projection"`
+	}{}
+	err = yaml.Unmarshal(buf, &yamlConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format for projection config in file %q: %w", filename, err)
+	}
+
+	return yamlConfig.Projection.parseProjectionConfig(), nil
+}
+
+func parseRoutingConfig() (*router.Config, error) {
+	filename := viper.GetString("PGSTREAM_ROUTING_RULES_FILE")
+	if filename == "" {
+		return nil, nil
+	}
+
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	yamlConfig := struct {
+		Routing RoutingConfig `mapstructure:"routing" yaml:"routing"`
+	}{}
+	err = yaml.Unmarshal(buf, &yamlConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format for routing config in file %q: %w", filename, err)
+	}
+
+	return yamlConfig.Routing.parseRoutingConfig(), nil
+}
+
+// parseKafkaTopicRoutingConfig returns the kafka writer topic routing, with
+// the rules read from the configured file. The topic template env variable
+// takes precedence over the one in the file.
+func parseKafkaTopicRoutingConfig() (*kafkaprocessor.TopicRoutingConfig, error) {
+	template := viper.GetString("PGSTREAM_KAFKA_WRITER_TOPIC_TEMPLATE")
+	filename := viper.GetString("PGSTREAM_KAFKA_WRITER_TOPIC_ROUTING_RULES_FILE")
+	if template == "" && filename == "" {
+		return nil, nil
+	}
+
+	yamlConfig := struct {
+		TopicRouting TopicRoutingConfig `mapstructure:"topic_routing" yaml:"topic_routing"`
+	}{}
+	if filename != "" {
+		buf, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(buf, &yamlConfig); err != nil {
+			return nil, fmt.Errorf("invalid format for kafka topic routing config in file %q: %w", filename, err)
+		}
+	}
+	if template != "" {
+		yamlConfig.TopicRouting.Template = template
+	}
+
+	return yamlConfig.TopicRouting.parseTopicRoutingConfig(), nil
+}
+
+func parseKafkaHeadersFile() ([]kafkaprocessor.HeaderConfig, error) {
+	filename := viper.GetString("PGSTREAM_KAFKA_WRITER_HEADERS_FILE")
+	if filename == "" {
+		return nil, nil
+	}
+
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	yamlConfig := struct {
+		Headers []KafkaHeaderConfig `mapstructure:"headers" yaml:"headers"`
+	}{}
+	if err := yaml.Unmarshal(buf, &yamlConfig); err != nil {
+		return nil, fmt.Errorf("invalid format for kafka headers config in file %q: %w", filename, err)
+	}
+
+	return parseKafkaHeaders(yamlConfig.Headers), nil
+}
+
+func parseSASLConfig(prefix string) *kafka.SASLConfig {
+	mechanism := viper.GetString(fmt.Sprintf("%s_SASL_MECHANISM", prefix))
+	if mechanism == "" {
+		return nil
+	}
+	return &kafka.SASLConfig{
+		Mechanism: mechanism,
+		Username:  viper.GetString(fmt.Sprintf("%s_SASL_USERNAME", prefix)),
+		Password:  viper.GetString(fmt.Sprintf("%s_SASL_PASSWORD", prefix)),
 	}
 }
 
@@ -8,21 +8,34 @@ import (
 	"time"
 
 	"github.com/xataio/pgstream/pkg/backoff"
+	"github.com/xataio/pgstream/pkg/blobstore"
 	"github.com/xataio/pgstream/pkg/kafka"
 	"github.com/xataio/pgstream/pkg/otel"
 	pgschemalog "github.com/xataio/pgstream/pkg/schemalog/postgres"
+	"github.com/xataio/pgstream/pkg/schemaregistry"
 	pgsnapshotgenerator "github.com/xataio/pgstream/pkg/snapshot/generator/postgres/data"
 	"github.com/xataio/pgstream/pkg/snapshot/generator/postgres/schema/pgdumprestore"
 	"github.com/xataio/pgstream/pkg/stream"
 	"github.com/xataio/pgstream/pkg/tls"
+	filecheckpoint "github.com/xataio/pgstream/pkg/wal/checkpointer/file"
 	kafkacheckpoint "github.com/xataio/pgstream/pkg/wal/checkpointer/kafka"
+	dlqfile "github.com/xataio/pgstream/pkg/wal/dlq/file"
+	dlqkafka "github.com/xataio/pgstream/pkg/wal/dlq/kafka"
+	dlqpostgres "github.com/xataio/pgstream/pkg/wal/dlq/postgres"
+	filelistener "github.com/xataio/pgstream/pkg/wal/listener/file"
 	"github.com/xataio/pgstream/pkg/wal/listener/snapshot/adapter"
 	snapshotbuilder "github.com/xataio/pgstream/pkg/wal/listener/snapshot/builder"
 	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+	"github.com/xataio/pgstream/pkg/wal/processor/fanout"
+	fileprocessor "github.com/xataio/pgstream/pkg/wal/processor/file"
 	"github.com/xataio/pgstream/pkg/wal/processor/filter"
 	"github.com/xataio/pgstream/pkg/wal/processor/injector"
 	kafkaprocessor "github.com/xataio/pgstream/pkg/wal/processor/kafka"
+	"github.com/xataio/pgstream/pkg/wal/processor/outbox"
+	parquetprocessor "github.com/xataio/pgstream/pkg/wal/processor/parquet"
 	"github.com/xataio/pgstream/pkg/wal/processor/postgres"
+	"github.com/xataio/pgstream/pkg/wal/processor/projection"
+	"github.com/xataio/pgstream/pkg/wal/processor/router"
 	"github.com/xataio/pgstream/pkg/wal/processor/search"
 	"github.com/xataio/pgstream/pkg/wal/processor/search/store"
 	"github.com/xataio/pgstream/pkg/wal/processor/transformer"
@@ -47,14 +60,37 @@ type TracesConfig struct {
 }
 
 type YAMLConfig struct {
-	Source    SourceConfig    `mapstructure:"source" yaml:"source"`
-	Target    TargetConfig    `mapstructure:"target" yaml:"target"`
-	Modifiers ModifiersConfig `mapstructure:"modifiers" yaml:"modifiers"`
+	Source          SourceConfig           `mapstructure:"source" yaml:"source"`
+	Target          TargetConfig           `mapstructure:"target" yaml:"target"`
+	Modifiers       ModifiersConfig        `mapstructure:"modifiers" yaml:"modifiers"`
+	DeadLetterQueue *DeadLetterQueueConfig `mapstructure:"dead_letter_queue" yaml:"dead_letter_queue"`
+}
+
+type DeadLetterQueueConfig struct {
+	Postgres *PostgresDeadLetterQueueConfig `mapstructure:"postgres" yaml:"postgres"`
+	Kafka    *KafkaDeadLetterQueueConfig    `mapstructure:"kafka" yaml:"kafka"`
+	File     *FileDeadLetterQueueConfig     `mapstructure:"file" yaml:"file"`
+}
+
+type PostgresDeadLetterQueueConfig struct {
+	URL string `mapstructure:"url" yaml:"url"`
+}
+
+type KafkaDeadLetterQueueConfig struct {
+	Servers []string         `mapstructure:"servers" yaml:"servers"`
+	Topic   KafkaTopicConfig `mapstructure:"topic" yaml:"topic"`
+	TLS     *TLSConfig       `mapstructure:"tls" yaml:"tls"`
+	SASL    *SASLConfig      `mapstructure:"sasl" yaml:"sasl"`
+}
+
+type FileDeadLetterQueueConfig struct {
+	Path string `mapstructure:"path" yaml:"path"`
 }
 
 type SourceConfig struct {
-	Postgres *PostgresConfig `mapstructure:"postgres" yaml:"postgres"`
-	Kafka    *KafkaConfig    `mapstructure:"kafka" yaml:"kafka"`
+	Postgres *PostgresConfig   `mapstructure:"postgres" yaml:"postgres"`
+	Kafka    *KafkaConfig      `mapstructure:"kafka" yaml:"kafka"`
+	File     *FileSourceConfig `mapstructure:"file" yaml:"file"`
 }
 
 type TargetConfig struct {
@@ -62,6 +98,8 @@ type TargetConfig struct {
 	Kafka    *KafkaTargetConfig    `mapstructure:"kafka" yaml:"kafka"`
 	Search   *SearchConfig         `mapstructure:"search" yaml:"search"`
 	Webhooks *WebhooksConfig       `mapstructure:"webhooks" yaml:"webhooks"`
+	File     *FileTargetConfig     `mapstructure:"file" yaml:"file"`
+	Parquet  *ParquetTargetConfig  `mapstructure:"parquet" yaml:"parquet"`
 }
 
 type PostgresConfig struct {
@@ -107,14 +145,37 @@ type PgDumpPgRestoreConfig struct {
 
 type ReplicationConfig struct {
 	ReplicationSlot string `mapstructure:"replication_slot" yaml:"replication_slot"`
+	Plugin          string `mapstructure:"plugin" yaml:"plugin"`
+	Publication     string `mapstructure:"publication" yaml:"publication"`
 }
 
 type KafkaConfig struct {
-	Servers       []string            `mapstructure:"servers" yaml:"servers"`
-	Topic         TopicConfig         `mapstructure:"topic" yaml:"topic"`
-	ConsumerGroup ConsumerGroupConfig `mapstructure:"consumer_group" yaml:"consumer_group"`
-	TLS           *TLSConfig          `mapstructure:"tls" yaml:"tls"`
-	Backoff       *BackoffConfig      `mapstructure:"backoff" yaml:"backoff"`
+	Servers           []string              `mapstructure:"servers" yaml:"servers"`
+	Topic             TopicConfig           `mapstructure:"topic" yaml:"topic"`
+	ConsumerGroup     ConsumerGroupConfig   `mapstructure:"consumer_group" yaml:"consumer_group"`
+	TLS               *TLSConfig            `mapstructure:"tls" yaml:"tls"`
+	SASL              *SASLConfig           `mapstructure:"sasl" yaml:"sasl"`
+	Backoff           *BackoffConfig        `mapstructure:"backoff" yaml:"backoff"`
+	SchemaRegistry    *SchemaRegistryConfig `mapstructure:"schema_registry" yaml:"schema_registry"`
+	Format            string                `mapstructure:"format" yaml:"format"`
+	Topics            []string              `mapstructure:"topics" yaml:"topics"`
+	TopicPattern      string                `mapstructure:"topic_pattern" yaml:"topic_pattern"`
+	TopicRefresh      int                   `mapstructure:"topic_refresh_interval" yaml:"topic_refresh_interval"`
+	Concurrency       int                   `mapstructure:"concurrency" yaml:"concurrency"`
+	BlobStore         *BlobStoreConfig      `mapstructure:"blob_store" yaml:"blob_store"`
+	DeleteClaimChecks bool                  `mapstructure:"delete_claim_checks" yaml:"delete_claim_checks"`
+	Retry             *KafkaRetryConfig     `mapstructure:"retry" yaml:"retry"`
+}
+
+type KafkaRetryConfig struct {
+	Backoff    *BackoffConfig    `mapstructure:"backoff" yaml:"backoff"`
+	OnFailure  string            `mapstructure:"on_failure" yaml:"on_failure"`
+	ErrorTopic *KafkaTopicConfig `mapstructure:"error_topic" yaml:"error_topic"`
+}
+
+type FileSourceConfig struct {
+	Paths          []string `mapstructure:"paths" yaml:"paths"`
+	CheckpointPath string   `mapstructure:"checkpoint_path" yaml:"checkpoint_path"`
 }
 
 type TopicConfig struct {
@@ -124,6 +185,12 @@ type TopicConfig struct {
 	AutoCreate        bool   `mapstructure:"auto_create" yaml:"auto_create"`
 }
 
+type SchemaRegistryConfig struct {
+	URL      string `mapstructure:"url" yaml:"url"`
+	Username string `mapstructure:"username" yaml:"username"`
+	Password string `mapstructure:"password" yaml:"password"`
+}
+
 type ConsumerGroupConfig struct {
 	ID          string `mapstructure:"id" yaml:"id"`
 	StartOffset string `mapstructure:"start_offset" yaml:"start_offset"`
@@ -135,6 +202,12 @@ type TLSConfig struct {
 	ClientKey  string `mapstructure:"client_key" yaml:"client_key"`
 }
 
+type SASLConfig struct {
+	Mechanism string `mapstructure:"mechanism" yaml:"mechanism"`
+	Username  string `mapstructure:"username" yaml:"username"`
+	Password  string `mapstructure:"password" yaml:"password"`
+}
+
 type BackoffConfig struct {
 	Exponential *ExponentialBackoffConfig `mapstructure:"exponential" yaml:"exponential"`
 	Constant    *ConstantBackoffConfig    `mapstructure:"constant" yaml:"constant"`
@@ -152,19 +225,67 @@ type ConstantBackoffConfig struct {
 }
 
 type PostgresTargetConfig struct {
-	URL               string            `mapstructure:"url" yaml:"url"`
-	Batch             *BatchConfig      `mapstructure:"batch" yaml:"batch"`
-	BulkIngest        *BulkIngestConfig `mapstructure:"bulk_ingest" yaml:"bulk_ingest"`
-	SchemaLogStoreURL string            `mapstructure:"schema_log_store_url" yaml:"schema_log_store_url"`
-	DisableTriggers   bool              `mapstructure:"disable_triggers" yaml:"disable_triggers"`
-	OnConflictAction  string            `mapstructure:"on_conflict_action" yaml:"on_conflict_action"`
+	URL                   string                 `mapstructure:"url" yaml:"url"`
+	Batch                 *BatchConfig           `mapstructure:"batch" yaml:"batch"`
+	BulkIngest            *BulkIngestConfig      `mapstructure:"bulk_ingest" yaml:"bulk_ingest"`
+	SchemaLogStoreURL     string                 `mapstructure:"schema_log_store_url" yaml:"schema_log_store_url"`
+	DisableTriggers       bool                   `mapstructure:"disable_triggers" yaml:"disable_triggers"`
+	OnConflictAction      string                 `mapstructure:"on_conflict_action" yaml:"on_conflict_action"`
+	TransactionConsistent bool                   `mapstructure:"transaction_consistent" yaml:"transaction_consistent"`
+	ExactlyOnce           *ExactlyOnceConfig     `mapstructure:"exactly_once" yaml:"exactly_once"`
+	Transformations       *TransformationsConfig `mapstructure:"transformations" yaml:"transformations"`
+	MaxLag                int                    `mapstructure:"max_lag" yaml:"max_lag"`
 }
 
 type KafkaTargetConfig struct {
-	Servers []string         `mapstructure:"servers" yaml:"servers"`
-	Topic   KafkaTopicConfig `mapstructure:"topic" yaml:"topic"`
-	TLS     *TLSConfig       `mapstructure:"tls" yaml:"tls"`
-	Batch   *BatchConfig     `mapstructure:"batch" yaml:"batch"`
+	Servers         []string               `mapstructure:"servers" yaml:"servers"`
+	Topic           KafkaTopicConfig       `mapstructure:"topic" yaml:"topic"`
+	TLS             *TLSConfig             `mapstructure:"tls" yaml:"tls"`
+	SASL            *SASLConfig            `mapstructure:"sasl" yaml:"sasl"`
+	Compression     string                 `mapstructure:"compression" yaml:"compression"`
+	RequiredAcks    string                 `mapstructure:"required_acks" yaml:"required_acks"`
+	DisableRetries  bool                   `mapstructure:"disable_retries" yaml:"disable_retries"`
+	Format          string                 `mapstructure:"format" yaml:"format"`
+	SchemaRegistry  *SchemaRegistryConfig  `mapstructure:"schema_registry" yaml:"schema_registry"`
+	TopicRouting    *TopicRoutingConfig    `mapstructure:"topic_routing" yaml:"topic_routing"`
+	KeyStrategy     string                 `mapstructure:"key_strategy" yaml:"key_strategy"`
+	KeyColumns      []string               `mapstructure:"key_columns" yaml:"key_columns"`
+	Headers         []KafkaHeaderConfig    `mapstructure:"headers" yaml:"headers"`
+	Compaction      bool                   `mapstructure:"compaction" yaml:"compaction"`
+	LargeMessages   *LargeMessagesConfig   `mapstructure:"large_messages" yaml:"large_messages"`
+	Batch           *BatchConfig           `mapstructure:"batch" yaml:"batch"`
+	Transformations *TransformationsConfig `mapstructure:"transformations" yaml:"transformations"`
+	MaxLag          int                    `mapstructure:"max_lag" yaml:"max_lag"`
+}
+
+type FileTargetConfig struct {
+	Path            string                 `mapstructure:"path" yaml:"path"`
+	Format          string                 `mapstructure:"format" yaml:"format"`
+	Compression     string                 `mapstructure:"compression" yaml:"compression"`
+	Rotation        *FileRotationConfig    `mapstructure:"rotation" yaml:"rotation"`
+	Batch           *BatchConfig           `mapstructure:"batch" yaml:"batch"`
+	Transformations *TransformationsConfig `mapstructure:"transformations" yaml:"transformations"`
+	MaxLag          int                    `mapstructure:"max_lag" yaml:"max_lag"`
+}
+
+type FileRotationConfig struct {
+	MaxBytes int `mapstructure:"max_bytes" yaml:"max_bytes"`
+	Interval int `mapstructure:"interval" yaml:"interval"`
+}
+
+type ParquetTargetConfig struct {
+	Path            string                 `mapstructure:"path" yaml:"path"`
+	Compression     string                 `mapstructure:"compression" yaml:"compression"`
+	RowGroupSize    int                    `mapstructure:"row_group_size" yaml:"row_group_size"`
+	Rotation        *ParquetRotationConfig `mapstructure:"rotation" yaml:"rotation"`
+	Batch           *BatchConfig           `mapstructure:"batch" yaml:"batch"`
+	Transformations *TransformationsConfig `mapstructure:"transformations" yaml:"transformations"`
+	MaxLag          int                    `mapstructure:"max_lag" yaml:"max_lag"`
+}
+
+type ParquetRotationConfig struct {
+	MaxRows  int `mapstructure:"max_rows" yaml:"max_rows"`
+	Interval int `mapstructure:"interval" yaml:"interval"`
 }
 
 type KafkaTopicConfig struct {
@@ -174,11 +295,53 @@ type KafkaTopicConfig struct {
 	AutoCreate        bool   `mapstructure:"auto_create" yaml:"auto_create"`
 }
 
+type TopicRoutingConfig struct {
+	Template string                   `mapstructure:"template" yaml:"template"`
+	Rules    []TopicRoutingRuleConfig `mapstructure:"rules" yaml:"rules"`
+}
+
+type LargeMessagesConfig struct {
+	Strategy  string           `mapstructure:"strategy" yaml:"strategy"`
+	BlobStore *BlobStoreConfig `mapstructure:"blob_store" yaml:"blob_store"`
+}
+
+type BlobStoreConfig struct {
+	Local *LocalBlobStoreConfig `mapstructure:"local" yaml:"local"`
+	S3    *S3BlobStoreConfig    `mapstructure:"s3" yaml:"s3"`
+}
+
+type LocalBlobStoreConfig struct {
+	Dir string `mapstructure:"dir" yaml:"dir"`
+}
+
+type S3BlobStoreConfig struct {
+	Endpoint        string `mapstructure:"endpoint" yaml:"endpoint"`
+	Region          string `mapstructure:"region" yaml:"region"`
+	Bucket          string `mapstructure:"bucket" yaml:"bucket"`
+	Prefix          string `mapstructure:"prefix" yaml:"prefix"`
+	AccessKeyID     string `mapstructure:"access_key_id" yaml:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key" yaml:"secret_access_key"`
+}
+
+type KafkaHeaderConfig struct {
+	Key   string `mapstructure:"key" yaml:"key"`
+	Value string `mapstructure:"value" yaml:"value"`
+}
+
+type TopicRoutingRuleConfig struct {
+	Source            string `mapstructure:"source" yaml:"source"`
+	Topic             string `mapstructure:"topic" yaml:"topic"`
+	Partitions        int    `mapstructure:"partitions" yaml:"partitions"`
+	ReplicationFactor int    `mapstructure:"replication_factor" yaml:"replication_factor"`
+}
+
 type SearchConfig struct {
-	Engine  string         `mapstructure:"engine" yaml:"engine"`
-	URL     string         `mapstructure:"url" yaml:"url"`
-	Batch   *BatchConfig   `mapstructure:"batch" yaml:"batch"`
-	Backoff *BackoffConfig `mapstructure:"backoff" yaml:"backoff"`
+	Engine          string                 `mapstructure:"engine" yaml:"engine"`
+	URL             string                 `mapstructure:"url" yaml:"url"`
+	Batch           *BatchConfig           `mapstructure:"batch" yaml:"batch"`
+	Backoff         *BackoffConfig         `mapstructure:"backoff" yaml:"backoff"`
+	Transformations *TransformationsConfig `mapstructure:"transformations" yaml:"transformations"`
+	MaxLag          int                    `mapstructure:"max_lag" yaml:"max_lag"`
 }
 
 type BatchConfig struct {
@@ -192,9 +355,16 @@ type BulkIngestConfig struct {
 	Enabled bool `mapstructure:"enabled" yaml:"enabled"`
 }
 
+type ExactlyOnceConfig struct {
+	Enabled  bool   `mapstructure:"enabled" yaml:"enabled"`
+	StreamID string `mapstructure:"stream_id" yaml:"stream_id"`
+}
+
 type WebhooksConfig struct {
-	Subscriptions WebhookSubscriptionsConfig `mapstructure:"subscriptions" yaml:"subscriptions"`
-	Notifier      WebhookNotifierConfig      `mapstructure:"notifier" yaml:"notifier"`
+	Subscriptions   WebhookSubscriptionsConfig `mapstructure:"subscriptions" yaml:"subscriptions"`
+	Notifier        WebhookNotifierConfig      `mapstructure:"notifier" yaml:"notifier"`
+	Transformations *TransformationsConfig     `mapstructure:"transformations" yaml:"transformations"`
+	MaxLag          int                        `mapstructure:"max_lag" yaml:"max_lag"`
 }
 
 type WebhookSubscriptionsConfig struct {
@@ -227,6 +397,9 @@ type ModifiersConfig struct {
 	Injector        *InjectorConfig        `mapstructure:"injector" yaml:"injector"`
 	Transformations *TransformationsConfig `mapstructure:"transformations" yaml:"transformations"`
 	Filter          *FilterConfig          `mapstructure:"filter" yaml:"filter"`
+	Outbox          *OutboxConfig          `mapstructure:"outbox" yaml:"outbox"`
+	Projection      *ProjectionConfig      `mapstructure:"projection" yaml:"projection"`
+	Routing         *RoutingConfig         `mapstructure:"routing" yaml:"routing"`
 }
 
 type InjectorConfig struct {
@@ -235,8 +408,48 @@ type InjectorConfig struct {
 }
 
 type FilterConfig struct {
-	IncludeTables []string `mapstructure:"include_tables" yaml:"include_tables"`
-	ExcludeTables []string `mapstructure:"exclude_tables" yaml:"exclude_tables"`
+	IncludeTables []string          `mapstructure:"include_tables" yaml:"include_tables"`
+	ExcludeTables []string          `mapstructure:"exclude_tables" yaml:"exclude_tables"`
+	RowFilters    []RowFilterConfig `mapstructure:"row_filters" yaml:"row_filters"`
+}
+
+type RowFilterConfig struct {
+	Table      string `mapstructure:"table" yaml:"table"`
+	Expression string `mapstructure:"expression" yaml:"expression"`
+}
+
+type OutboxConfig struct {
+	Tables              []OutboxTableConfig `mapstructure:"tables" yaml:"tables"`
+	DeleteProcessedRows bool                `mapstructure:"delete_processed_rows" yaml:"delete_processed_rows"`
+}
+
+type OutboxTableConfig struct {
+	Table             string   `mapstructure:"table" yaml:"table"`
+	IDColumn          string   `mapstructure:"id_column" yaml:"id_column"`
+	PayloadColumn     string   `mapstructure:"payload_column" yaml:"payload_column"`
+	AggregateIDColumn string   `mapstructure:"aggregate_id_column" yaml:"aggregate_id_column"`
+	EventTypeColumn   string   `mapstructure:"event_type_column" yaml:"event_type_column"`
+	Topic             string   `mapstructure:"topic" yaml:"topic"`
+	HeaderColumns     []string `mapstructure:"header_columns" yaml:"header_columns"`
+}
+
+type ProjectionConfig struct {
+	Tables []ProjectionTableConfig `mapstructure:"tables" yaml:"tables"`
+}
+
+type ProjectionTableConfig struct {
+	Table          string   `mapstructure:"table" yaml:"table"`
+	IncludeColumns []string `mapstructure:"include_columns" yaml:"include_columns"`
+	ExcludeColumns []string `mapstructure:"exclude_columns" yaml:"exclude_columns"`
+}
+
+type RoutingConfig struct {
+	Rules []RoutingRuleConfig `mapstructure:"rules" yaml:"rules"`
+}
+
+type RoutingRuleConfig struct {
+	Source string `mapstructure:"source" yaml:"source"`
+	Target string `mapstructure:"target" yaml:"target"`
 }
 
 type TransformationsConfig struct {
@@ -337,14 +550,50 @@ func (c *YAMLConfig) toStreamConfig() (*stream.Config, error) {
 		return nil, fmt.Errorf("parsing target and modifier config: %w", err)
 	}
 	return &stream.Config{
-		Listener:  listener,
-		Processor: processor,
+		Listener:        listener,
+		Processor:       processor,
+		DeadLetterQueue: c.DeadLetterQueue.parseDeadLetterQueueConfig(),
 	}, nil
 }
 
+func (c *DeadLetterQueueConfig) parseDeadLetterQueueConfig() *stream.DeadLetterQueueConfig {
+	if c == nil {
+		return nil
+	}
+
+	cfg := &stream.DeadLetterQueueConfig{}
+	if c.Postgres != nil {
+		cfg.Postgres = &dlqpostgres.Config{
+			URL: c.Postgres.URL,
+		}
+	}
+	if c.Kafka != nil {
+		cfg.Kafka = &dlqkafka.Config{
+			Conn: kafka.ConnConfig{
+				Servers: c.Kafka.Servers,
+				Topic: kafka.TopicConfig{
+					Name:              c.Kafka.Topic.Name,
+					NumPartitions:     c.Kafka.Topic.Partitions,
+					ReplicationFactor: c.Kafka.Topic.ReplicationFactor,
+					AutoCreate:        c.Kafka.Topic.AutoCreate,
+				},
+				TLS:  c.Kafka.TLS.parseTLSConfig(),
+				SASL: c.Kafka.SASL.parseSASLConfig(),
+			},
+		}
+	}
+	if c.File != nil {
+		cfg.File = &dlqfile.Config{
+			Path: c.File.Path,
+		}
+	}
+	return cfg
+}
+
 func (c *YAMLConfig) parseListenerConfig() (stream.ListenerConfig, error) {
 	streamCfg := stream.ListenerConfig{
 		Kafka: c.Source.Kafka.parseKafkaListenerConfig(),
+		File:  c.Source.File.parseFileListenerConfig(),
 	}
 
 	if c.Source.Postgres == nil {
@@ -372,10 +621,15 @@ func (c *YAMLConfig) parseListenerConfig() (stream.ListenerConfig, error) {
 
 func (c *YAMLConfig) parseProcessorConfig() (stream.ProcessorConfig, error) {
 	streamCfg := stream.ProcessorConfig{
-		Kafka:    c.parseKafkaProcessorConfig(),
-		Postgres: c.parsePostgresProcessorConfig(),
-		Webhook:  c.parseWebhookProcessorConfig(),
-		Filter:   c.parseFilterConfig(),
+		Kafka:      c.parseKafkaProcessorConfig(),
+		Postgres:   c.parsePostgresProcessorConfig(),
+		Webhook:    c.parseWebhookProcessorConfig(),
+		File:       c.parseFileProcessorConfig(),
+		Parquet:    c.parseParquetProcessorConfig(),
+		Filter:     c.parseFilterConfig(),
+		Outbox:     c.parseOutboxConfig(),
+		Projection: c.parseProjectionConfig(),
+		Routing:    c.parseRoutingConfig(),
 	}
 
 	var err error
@@ -394,9 +648,66 @@ func (c *YAMLConfig) parseProcessorConfig() (stream.ProcessorConfig, error) {
 		return stream.ProcessorConfig{}, err
 	}
 
+	if err := c.parseTargetConfigs(&streamCfg); err != nil {
+		return stream.ProcessorConfig{}, err
+	}
+
 	return streamCfg, nil
 }
 
+// parseTargetConfigs parses the target specific settings of the configured
+// targets.
+func (c *YAMLConfig) parseTargetConfigs(cfg *stream.ProcessorConfig) error {
+	var err error
+	if cfg.Kafka != nil {
+		if cfg.Kafka.Target, err = parseYAMLTargetConfig(c.Target.Kafka.Transformations, c.Target.Kafka.MaxLag); err != nil {
+			return err
+		}
+	}
+	if cfg.Postgres != nil {
+		if cfg.Postgres.Target, err = parseYAMLTargetConfig(c.Target.Postgres.Transformations, c.Target.Postgres.MaxLag); err != nil {
+			return err
+		}
+	}
+	if cfg.Search != nil {
+		if cfg.Search.Target, err = parseYAMLTargetConfig(c.Target.Search.Transformations, c.Target.Search.MaxLag); err != nil {
+			return err
+		}
+	}
+	if cfg.Webhook != nil {
+		if cfg.Webhook.Target, err = parseYAMLTargetConfig(c.Target.Webhooks.Transformations, c.Target.Webhooks.MaxLag); err != nil {
+			return err
+		}
+	}
+	if cfg.File != nil {
+		if cfg.File.Target, err = parseYAMLTargetConfig(c.Target.File.Transformations, c.Target.File.MaxLag); err != nil {
+			return err
+		}
+	}
+	if cfg.Parquet != nil {
+		if cfg.Parquet.Target, err = parseYAMLTargetConfig(c.Target.Parquet.Transformations, c.Target.Parquet.MaxLag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseYAMLTargetConfig(transformations *TransformationsConfig, maxLag int) (stream.TargetConfig, error) {
+	targetCfg := stream.TargetConfig{
+		Fanout: fanout.TargetConfig{
+			MaxLag: maxLag,
+		},
+	}
+	if transformations != nil {
+		var err error
+		targetCfg.Transformer, err = transformations.parseTransformationConfig()
+		if err != nil {
+			return stream.TargetConfig{}, err
+		}
+	}
+	return targetCfg, nil
+}
+
 func (c *YAMLConfig) parsePostgresListenerConfig() (*stream.PostgresListenerConfig, error) {
 	if c.Source.Postgres == nil {
 		return nil, nil
@@ -409,11 +720,11 @@ func (c *YAMLConfig) parsePostgresListenerConfig() (*stream.PostgresListenerConf
 	}
 
 	if c.Source.Postgres.Mode == replicationMode || c.Source.Postgres.Mode == snapshotAndReplicationMode {
-		replicationSlotName := ""
 		if c.Source.Postgres.Replication != nil {
-			replicationSlotName = c.Source.Postgres.Replication.ReplicationSlot
+			streamCfg.Replication.ReplicationSlotName = c.Source.Postgres.Replication.ReplicationSlot
+			streamCfg.Replication.Plugin = c.Source.Postgres.Replication.Plugin
+			streamCfg.Replication.PublicationName = c.Source.Postgres.Replication.Publication
 		}
-		streamCfg.Replication.ReplicationSlotName = replicationSlotName
 	}
 
 	if c.Source.Postgres.Mode == snapshotAndReplicationMode {
@@ -550,13 +861,69 @@ func (c *YAMLConfig) parseKafkaProcessorConfig() *stream.KafkaProcessorConfig {
 					ReplicationFactor: c.Target.Kafka.Topic.ReplicationFactor,
 					AutoCreate:        c.Target.Kafka.Topic.AutoCreate,
 				},
-				TLS: c.Target.Kafka.TLS.parseTLSConfig(),
+				TLS:            c.Target.Kafka.TLS.parseTLSConfig(),
+				SASL:           c.Target.Kafka.SASL.parseSASLConfig(),
+				Compression:    c.Target.Kafka.Compression,
+				RequiredAcks:   c.Target.Kafka.RequiredAcks,
+				DisableRetries: c.Target.Kafka.DisableRetries,
 			},
-			Batch: c.Target.Kafka.Batch.parseBatchConfig(),
+			Batch:          c.Target.Kafka.Batch.parseBatchConfig(),
+			Format:         c.Target.Kafka.Format,
+			SchemaRegistry: c.Target.Kafka.SchemaRegistry.parseSchemaRegistryConfig(),
+			TopicRouting:   c.Target.Kafka.TopicRouting.parseTopicRoutingConfig(),
+			KeyStrategy:    c.Target.Kafka.KeyStrategy,
+			KeyColumns:     c.Target.Kafka.KeyColumns,
+			Headers:        parseKafkaHeaders(c.Target.Kafka.Headers),
+			Compaction:     c.Target.Kafka.Compaction,
+			LargeMessages:  c.Target.Kafka.LargeMessages.parseLargeMessagesConfig(),
 		},
 	}
 }
 
+func (c *YAMLConfig) parseFileProcessorConfig() *stream.FileProcessorConfig {
+	if c.Target.File == nil {
+		return nil
+	}
+
+	cfg := &stream.FileProcessorConfig{
+		Writer: fileprocessor.Config{
+			Dir:         c.Target.File.Path,
+			Format:      fileprocessor.Format(c.Target.File.Format),
+			Compression: fileprocessor.Compression(c.Target.File.Compression),
+			Batch:       c.Target.File.Batch.parseBatchConfig(),
+		},
+	}
+
+	if c.Target.File.Rotation != nil {
+		cfg.Writer.MaxFileBytes = int64(c.Target.File.Rotation.MaxBytes)
+		cfg.Writer.RotationInterval = time.Duration(c.Target.File.Rotation.Interval) * time.Second
+	}
+
+	return cfg
+}
+
+func (c *YAMLConfig) parseParquetProcessorConfig() *stream.ParquetProcessorConfig {
+	if c.Target.Parquet == nil {
+		return nil
+	}
+
+	cfg := &stream.ParquetProcessorConfig{
+		Writer: parquetprocessor.Config{
+			Dir:          c.Target.Parquet.Path,
+			Compression:  parquetprocessor.Compression(c.Target.Parquet.Compression),
+			RowGroupSize: int64(c.Target.Parquet.RowGroupSize),
+			Batch:        c.Target.Parquet.Batch.parseBatchConfig(),
+		},
+	}
+
+	if c.Target.Parquet.Rotation != nil {
+		cfg.Writer.MaxFileRows = int64(c.Target.Parquet.Rotation.MaxRows)
+		cfg.Writer.RotationInterval = time.Duration(c.Target.Parquet.Rotation.Interval) * time.Second
+	}
+
+	return cfg
+}
+
 func (c *YAMLConfig) parsePostgresProcessorConfig() *stream.PostgresProcessorConfig {
 	if c.Target.Postgres == nil {
 		return nil
@@ -569,11 +936,19 @@ func (c *YAMLConfig) parsePostgresProcessorConfig() *stream.PostgresProcessorCon
 			SchemaLogStore: pgschemalog.Config{
 				URL: c.Target.Postgres.SchemaLogStoreURL,
 			},
-			DisableTriggers:  c.Target.Postgres.DisableTriggers,
-			OnConflictAction: c.Target.Postgres.OnConflictAction,
+			DisableTriggers:       c.Target.Postgres.DisableTriggers,
+			OnConflictAction:      c.Target.Postgres.OnConflictAction,
+			TransactionConsistent: c.Target.Postgres.TransactionConsistent,
 		},
 	}
 
+	if c.Target.Postgres.ExactlyOnce != nil {
+		cfg.BatchWriter.ExactlyOnce = postgres.ExactlyOnceConfig{
+			Enabled:  c.Target.Postgres.ExactlyOnce.Enabled,
+			StreamID: c.Target.Postgres.ExactlyOnce.StreamID,
+		}
+	}
+
 	if c.Target.Postgres.BulkIngest != nil {
 		cfg.BatchWriter.BulkIngestEnabled = c.Target.Postgres.BulkIngest.Enabled
 		if cfg.BatchWriter.BulkIngestEnabled {
@@ -654,6 +1029,85 @@ func (c YAMLConfig) parseFilterConfig() *filter.Config {
 	return &filter.Config{
 		ExcludeTables: c.Modifiers.Filter.ExcludeTables,
 		IncludeTables: c.Modifiers.Filter.IncludeTables,
+		RowFilters:    parseRowFilters(c.Modifiers.Filter.RowFilters),
+	}
+}
+
+func parseRowFilters(rowFilters []RowFilterConfig) map[string]string {
+	if len(rowFilters) == 0 {
+		return nil
+	}
+	rowFiltersMap := make(map[string]string, len(rowFilters))
+	for _, rowFilter := range rowFilters {
+		rowFiltersMap[rowFilter.Table] = rowFilter.Expression
+	}
+	return rowFiltersMap
+}
+
+func (c YAMLConfig) parseOutboxConfig() *outbox.Config {
+	if c.Modifiers.Outbox == nil {
+		return nil
+	}
+	return c.Modifiers.Outbox.parseOutboxConfig()
+}
+
+func (c OutboxConfig) parseOutboxConfig() *outbox.Config {
+	tables := make([]outbox.TableConfig, 0, len(c.Tables))
+	for _, table := range c.Tables {
+		tables = append(tables, outbox.TableConfig{
+			Table:             table.Table,
+			IDColumn:          table.IDColumn,
+			PayloadColumn:     table.PayloadColumn,
+			AggregateIDColumn: table.AggregateIDColumn,
+			EventTypeColumn:   table.EventTypeColumn,
+			Topic:             table.Topic,
+			HeaderColumns:     table.HeaderColumns,
+		})
+	}
+	return &outbox.Config{
+		Tables:              tables,
+		DeleteProcessedRows: c.DeleteProcessedRows,
+	}
+}
+
+func (c YAMLConfig) parseProjectionConfig() *projection.Config {
+	if c.Modifiers.Projection == nil {
+		return nil
+	}
+	return c.Modifiers.Projection.parseProjectionConfig()
+}
+
+func (c ProjectionConfig) parseProjectionConfig() *projection.Config {
+	tables := make([]projection.TableConfig, 0, len(c.Tables))
+	for _, table := range c.Tables {
+		tables = append(tables, projection.TableConfig{
+			Table:          table.Table,
+			IncludeColumns: table.IncludeColumns,
+			ExcludeColumns: table.ExcludeColumns,
+		})
+	}
+	return &projection.Config{
+		Tables: tables,
+	}
+}
+
+func (c YAMLConfig) parseRoutingConfig() *router.Config {
+	if c.Modifiers.Routing == nil {
+		return nil
+	}
+	return c.Modifiers.Routing.parseRoutingConfig()
+}
+
+func (c RoutingConfig) parseRoutingConfig() *router.Config {
+	rules := make([]router.RuleConfig, 0, len(c.Rules))
+	for _, rule := range c.Rules {
+		rules = append(rules, router.RuleConfig{
+			Source: rule.Source,
+			Target: rule.Target,
+		})
+	}
+	return &router.Config{
+		Rules: rules,
 	}
 }
 
@@ -727,6 +1181,46 @@ func (c *KafkaConfig) parseKafkaListenerConfig() *stream.KafkaListenerConfig {
 		Checkpointer: kafkacheckpoint.Config{
 			CommitBackoff: c.Backoff.parseBackoffConfig(),
 		},
+		SchemaRegistry:    c.SchemaRegistry.parseSchemaRegistryConfig(),
+		Format:            c.Format,
+		Concurrency:       c.Concurrency,
+		BlobStore:         c.BlobStore.parseBlobStoreConfig(),
+		DeleteClaimChecks: c.DeleteClaimChecks,
+		Retry:             c.Retry.parseKafkaRetryConfig(),
+	}
+}
+
+func (c *KafkaRetryConfig) parseKafkaRetryConfig() stream.KafkaListenerRetryConfig {
+	if c == nil {
+		return stream.KafkaListenerRetryConfig{}
+	}
+	cfg := stream.KafkaListenerRetryConfig{
+		Backoff:   c.Backoff.parseBackoffConfig(),
+		OnFailure: c.OnFailure,
+	}
+	if c.ErrorTopic != nil {
+		cfg.ErrorTopic = kafka.TopicConfig{
+			Name:              c.ErrorTopic.Name,
+			NumPartitions:     c.ErrorTopic.Partitions,
+			ReplicationFactor: c.ErrorTopic.ReplicationFactor,
+			AutoCreate:        c.ErrorTopic.AutoCreate,
+		}
+	}
+	return cfg
+}
+
+func (c *FileSourceConfig) parseFileListenerConfig() *stream.FileListenerConfig {
+	if c == nil {
+		return nil
+	}
+
+	return &stream.FileListenerConfig{
+		Reader: filelistener.Config{
+			Paths: c.Paths,
+		},
+		Checkpointer: filecheckpoint.Config{
+			Path: c.CheckpointPath,
+		},
 	}
 }
 
@@ -743,10 +1237,93 @@ func (c *KafkaConfig) parseKafkaReaderConfig() kafka.ReaderConfig {
 				ReplicationFactor: c.Topic.ReplicationFactor,
 				AutoCreate:        c.Topic.AutoCreate,
 			},
-			TLS: c.TLS.parseTLSConfig(),
+			TLS:  c.TLS.parseTLSConfig(),
+			SASL: c.SASL.parseSASLConfig(),
 		},
 		ConsumerGroupID:          c.ConsumerGroup.ID,
 		ConsumerGroupStartOffset: c.ConsumerGroup.StartOffset,
+		Topics:                   c.Topics,
+		TopicPattern:             c.TopicPattern,
+		TopicRefreshInterval:     time.Duration(c.TopicRefresh) * time.Second,
+	}
+}
+
+func (c *TopicRoutingConfig) parseTopicRoutingConfig() *kafkaprocessor.TopicRoutingConfig {
+	if c == nil {
+		return nil
+	}
+
+	rules := make([]kafkaprocessor.TopicRuleConfig, 0, len(c.Rules))
+	for _, rule := range c.Rules {
+		rules = append(rules, kafkaprocessor.TopicRuleConfig{
+			Source:            rule.Source,
+			Topic:             rule.Topic,
+			NumPartitions:     rule.Partitions,
+			ReplicationFactor: rule.ReplicationFactor,
+		})
+	}
+	return &kafkaprocessor.TopicRoutingConfig{
+		Template: c.Template,
+		Rules:    rules,
+	}
+}
+
+func parseKafkaHeaders(headers []KafkaHeaderConfig) []kafkaprocessor.HeaderConfig {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	headersCfg := make([]kafkaprocessor.HeaderConfig, 0, len(headers))
+	for _, header := range headers {
+		headersCfg = append(headersCfg, kafkaprocessor.HeaderConfig{
+			Key:   header.Key,
+			Value: header.Value,
+		})
+	}
+	return headersCfg
+}
+
+func (c *LargeMessagesConfig) parseLargeMessagesConfig() *kafkaprocessor.LargeMessagesConfig {
+	if c == nil {
+		return nil
+	}
+	return &kafkaprocessor.LargeMessagesConfig{
+		Strategy:  c.Strategy,
+		BlobStore: c.BlobStore.parseBlobStoreConfig(),
+	}
+}
+
+func (c *BlobStoreConfig) parseBlobStoreConfig() *blobstore.Config {
+	if c == nil {
+		return nil
+	}
+	cfg := &blobstore.Config{}
+	if c.Local != nil {
+		cfg.Local = &blobstore.LocalConfig{
+			Dir: c.Local.Dir,
+		}
+	}
+	if c.S3 != nil {
+		cfg.S3 = &blobstore.S3Config{
+			Endpoint:        c.S3.Endpoint,
+			Region:          c.S3.Region,
+			Bucket:          c.S3.Bucket,
+			Prefix:          c.S3.Prefix,
+			AccessKeyID:     c.S3.AccessKeyID,
+			SecretAccessKey: c.S3.SecretAccessKey,
+		}
+	}
+	return cfg
+}
+
+func (c *SchemaRegistryConfig) parseSchemaRegistryConfig() *schemaregistry.Config {
+	if c == nil {
+		return nil
+	}
+	return &schemaregistry.Config{
+		URL:      c.URL,
+		Username: c.Username,
+		Password: c.Password,
 	}
 }
 
@@ -762,6 +1339,17 @@ func (t *TLSConfig) parseTLSConfig() tls.Config {
 	}
 }
 
+func (s *SASLConfig) parseSASLConfig() *kafka.SASLConfig {
+	if s == nil {
+		return nil
+	}
+	return &kafka.SASLConfig{
+		Mechanism: s.Mechanism,
+		Username:  s.Username,
+		Password:  s.Password,
+	}
+}
+
 func (bo *BackoffConfig) parseBackoffConfig() backoff.Config {
 	return backoff.Config{
 		Exponential: bo.parseExponentialBackoffConfig(),
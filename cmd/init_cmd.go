@@ -27,7 +27,8 @@ var initCmd = &cobra.Command{
 			return fmt.Errorf("parsing stream config: %w", err)
 		}
 
-		if err := stream.Init(context.Background(), streamConfig.SourcePostgresURL(), streamConfig.PostgresReplicationSlot()); err != nil {
+		if err := stream.Init(context.Background(), streamConfig.SourcePostgresURL(), streamConfig.PostgresReplicationSlot(),
+			stream.WithReplicationPlugin(streamConfig.PostgresReplicationPlugin(), streamConfig.PostgresPublication())); err != nil {
 			sp.Fail(err.Error())
 			return err
 		}
@@ -37,6 +38,7 @@ var initCmd = &cobra.Command{
 	},
 	Example: `
 	pgstream init --postgres-url <source-postgres-url> --replication-slot <replication-slot-name>
+	pgstream init --postgres-url <source-postgres-url> --replication-plugin pgoutput --publication <publication-name>
 	pgstream init -c config.yaml
 	pgstream init -c config.env`,
 }
@@ -53,7 +55,8 @@ var tearDownCmd = &cobra.Command{
 			return fmt.Errorf("parsing stream config: %w", err)
 		}
 
-		if err := stream.TearDown(context.Background(), streamConfig.SourcePostgresURL(), streamConfig.PostgresReplicationSlot()); err != nil {
+		if err := stream.TearDown(context.Background(), streamConfig.SourcePostgresURL(), streamConfig.PostgresReplicationSlot(),
+			stream.WithReplicationPlugin(streamConfig.PostgresReplicationPlugin(), streamConfig.PostgresPublication())); err != nil {
 			sp.Fail(err.Error())
 			return err
 		}
@@ -72,6 +75,8 @@ func initTeardownFlagBinding(cmd *cobra.Command, _ []string) {
 	// provided
 	viper.BindPFlag("source.postgres.url", cmd.Flags().Lookup("postgres-url"))
 	viper.BindPFlag("source.postgres.replication.replication_slot", cmd.Flags().Lookup("replication-slot"))
+	viper.BindPFlag("source.postgres.replication.plugin", cmd.Flags().Lookup("replication-plugin"))
+	viper.BindPFlag("source.postgres.replication.publication", cmd.Flags().Lookup("publication"))
 	viper.Set("source.postgres.mode", "replication")
 
 	// to be able to overwrite configuration with flags when env config file is
@@ -79,4 +84,6 @@ func initTeardownFlagBinding(cmd *cobra.Command, _ []string) {
 	viper.BindPFlag("PGSTREAM_POSTGRES_LISTENER_URL", cmd.Flags().Lookup("postgres-url"))
 	viper.BindPFlag("PGSTREAM_POSTGRES_SNAPSHOT_LISTENER_URL", cmd.Flags().Lookup("postgres-url"))
 	viper.BindPFlag("PGSTREAM_POSTGRES_REPLICATION_SLOT_NAME", cmd.Flags().Lookup("replication-slot"))
+	viper.BindPFlag("PGSTREAM_POSTGRES_REPLICATION_PLUGIN", cmd.Flags().Lookup("replication-plugin"))
+	viper.BindPFlag("PGSTREAM_POSTGRES_REPLICATION_PUBLICATION_NAME", cmd.Flags().Lookup("publication"))
 }
@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xataio/pgstream/cmd/config"
+	"github.com/xataio/pgstream/internal/log/zerolog"
+	"github.com/xataio/pgstream/pkg/kafka"
+	"github.com/xataio/pgstream/pkg/stream"
+)
+
+var replayCmd = &cobra.Command{
+	Use:     "replay",
+	Short:   "Replay re-processes a range of the events stored in the configured kafka source into the configured target, without committing the consumer group offsets",
+	PreRunE: replayFlagBinding,
+	RunE:    withProfiling(withSignalWatcher(replay)),
+	Example: `
+	pgstream replay --config config.yaml --range 2024-01-01T00:00:00Z..2024-01-02T00:00:00Z
+	pgstream replay --config config.env --partition-ranges 0=1500..,1=1200.. --tables public.users --actions insert,update
+	pgstream replay --source-url <kafka-url> --target postgres --target-url <target-postgres-url> --range 1000..2000`,
+}
+
+const (
+	replayRangeKey           = "replay.range"
+	replayPartitionRangesKey = "replay.partition_ranges"
+	replayTablesKey          = "replay.tables"
+	replayActionsKey         = "replay.actions"
+)
+
+func replay(ctx context.Context) error {
+	logger := zerolog.NewLogger(&zerolog.Config{
+		LogLevel: viper.GetString("PGSTREAM_LOG_LEVEL"),
+	})
+	zerolog.SetGlobalLogger(logger)
+
+	streamConfig, err := config.ParseStreamConfig()
+	if err != nil {
+		return fmt.Errorf("parsing stream config: %w", err)
+	}
+
+	replayConfig, err := parseReplayConfig()
+	if err != nil {
+		return fmt.Errorf("parsing replay config: %w", err)
+	}
+
+	provider, err := newInstrumentationProvider()
+	if err != nil {
+		return err
+	}
+	defer provider.Close()
+
+	return stream.Replay(ctx, zerolog.NewStdLogger(logger), streamConfig, replayConfig, provider.NewInstrumentation("replay"))
+}
+
+func parseReplayConfig() (*stream.ReplayConfig, error) {
+	replayConfig := &stream.ReplayConfig{
+		Tables:  viper.GetStringSlice(replayTablesKey),
+		Actions: viper.GetStringSlice(replayActionsKey),
+	}
+
+	if rangeStr := viper.GetString(replayRangeKey); rangeStr != "" {
+		offsetRange, err := kafka.ParseOffsetRange(rangeStr)
+		if err != nil {
+			return nil, err
+		}
+		replayConfig.Range = *offsetRange
+	}
+
+	for _, partitionRangeStr := range viper.GetStringSlice(replayPartitionRangesKey) {
+		partitionRange, err := kafka.ParsePartitionRange(partitionRangeStr)
+		if err != nil {
+			return nil, err
+		}
+		replayConfig.Partitions = append(replayConfig.Partitions, *partitionRange)
+	}
+
+	if len(replayConfig.Partitions) > 0 && viper.GetString(replayRangeKey) != "" {
+		return nil, errors.New("only one of range or partition ranges can be provided")
+	}
+
+	return replayConfig, nil
+}
+
+func replayFlagBinding(cmd *cobra.Command, args []string) error {
+	viper.BindPFlag(replayRangeKey, cmd.Flags().Lookup("range"))
+	viper.BindPFlag(replayPartitionRangesKey, cmd.Flags().Lookup("partition-ranges"))
+	viper.BindPFlag(replayTablesKey, cmd.Flags().Lookup("tables"))
+	viper.BindPFlag(replayActionsKey, cmd.Flags().Lookup("actions"))
+
+	// the replay source is always kafka
+	if cmd.Flags().Lookup("source-url").Changed {
+		viper.BindPFlag("source.kafka.servers", cmd.Flags().Lookup("source-url"))
+		viper.BindPFlag("PGSTREAM_KAFKA_READER_SERVERS", cmd.Flags().Lookup("source-url"))
+		if viper.GetString("source.kafka.topic.name") == "" {
+			viper.Set("source.kafka.topic.name", defaultKafkaTopicName)
+		}
+		if viper.GetString("PGSTREAM_KAFKA_TOPIC_NAME") == "" {
+			viper.Set("PGSTREAM_KAFKA_TOPIC_NAME", defaultKafkaTopicName)
+		}
+	}
+
+	return targetFlagBinding(cmd)
+}
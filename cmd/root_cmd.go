@@ -49,33 +49,48 @@ func Prepare() *cobra.Command {
 	// init cmd
 	initCmd.Flags().String("postgres-url", "", "Source postgres URL where pgstream setup will be run")
 	initCmd.Flags().String("replication-slot", "", "Name of the postgres replication slot to be created by pgstream on the source url")
+	initCmd.Flags().String("replication-plugin", "", "Logical decoding output plugin used by the replication slot. One of wal2json, pgoutput. Defaults to wal2json")
+	initCmd.Flags().String("publication", "", "Name of the postgres publication to be created by pgstream when using the pgoutput plugin. Defaults to pgstream_publication")
 
 	// tear down cmd
 	tearDownCmd.Flags().String("postgres-url", "", "Source postgres URL where pgstream tear down will be run")
 	tearDownCmd.Flags().String("replication-slot", "", "Name of the postgres replication slot to be deleted by pgstream from the source url")
+	tearDownCmd.Flags().String("replication-plugin", "", "Logical decoding output plugin used by the replication slot. One of wal2json, pgoutput. Defaults to wal2json")
+	tearDownCmd.Flags().String("publication", "", "Name of the postgres publication to be deleted by pgstream when using the pgoutput plugin. Defaults to pgstream_publication")
 
 	// snapshot cmd
 	snapshotCmd.Flags().String("postgres-url", "", "Source postgres database to perform the snapshot from")
-	snapshotCmd.Flags().String("target", "", "Target type. One of postgres, opensearch, elasticsearch, kafka")
-	snapshotCmd.Flags().String("target-url", "", "Target URL")
+	snapshotCmd.Flags().String("target", "", "Target type. One of postgres, opensearch, elasticsearch, kafka, file, parquet")
+	snapshotCmd.Flags().String("target-url", "", "Target URL. Directory path for the file and parquet targets")
 	snapshotCmd.Flags().StringSlice("tables", nil, "List of tables to snapshot, in the format <schema>.<table>. If not specified, the schema `public` will be assumed. Wildcards are supported")
 	snapshotCmd.Flags().Bool("reset", false, "Whether to reset the target before snapshotting (only for postgres target)")
 	snapshotCmd.Flags().Bool("profile", false, "Whether to produce CPU and memory profile files, as well as exposing a /debug/pprof endpoint on localhost:6060")
 	snapshotCmd.Flags().String("dump-file", "", "File where the pg_dump output will be written")
 
 	// run cmd
-	runCmd.Flags().String("source", "", "Source type. One of postgres, kafka")
-	runCmd.Flags().String("source-url", "", "Source URL")
-	runCmd.Flags().String("target", "", "Target type. One of postgres, opensearch, elasticsearch, kafka")
-	runCmd.Flags().String("target-url", "", "Target URL")
+	runCmd.Flags().String("source", "", "Source type. One of postgres, kafka, file")
+	runCmd.Flags().String("source-url", "", "Source URL. Comma separated file paths or glob patterns for the file source")
+	runCmd.Flags().String("target", "", "Target type. One of postgres, opensearch, elasticsearch, kafka, file, parquet")
+	runCmd.Flags().String("target-url", "", "Target URL. Directory path for the file and parquet targets")
 	runCmd.Flags().String("replication-slot", "", "Name of the postgres replication slot for pgstream to connect to")
 	runCmd.Flags().StringSlice("snapshot-tables", nil, "List of tables to snapshot if initial snapshot is required, in the format <schema>.<table>. If not specified, the schema `public` will be assumed. Wildcards are supported")
 	runCmd.Flags().Bool("reset", false, "Whether to reset the target before snapshotting (only for postgres target)")
 	runCmd.Flags().Bool("profile", false, "Whether to expose a /debug/pprof endpoint on localhost:6060")
 
+	// replay cmd
+	replayCmd.Flags().String("source-url", "", "Kafka source URL")
+	replayCmd.Flags().String("target", "", "Target type. One of postgres, opensearch, elasticsearch, kafka, file, parquet")
+	replayCmd.Flags().String("target-url", "", "Target URL. Directory path for the file and parquet targets")
+	replayCmd.Flags().String("range", "", "Range of the kafka topic to replay for all partitions, in the format <start>..<end>. Each bound can be an offset or an RFC3339 timestamp, and can be omitted. The end is excluded, and defaults to the last offset at the start of the replay")
+	replayCmd.Flags().StringSlice("partition-ranges", nil, "List of kafka topic partitions to replay, in the format <partition>=<start>..<end>, following the range format. Only the partitions provided will be replayed, for every topic of the listener")
+	replayCmd.Flags().StringSlice("tables", nil, "List of tables to replay, in the format <schema>.<table>. If not specified, the schema `public` will be assumed. Wildcards are supported")
+	replayCmd.Flags().StringSlice("actions", nil, "List of actions to replay. One of insert, update, delete, truncate")
+	replayCmd.Flags().Bool("profile", false, "Whether to produce CPU and memory profile files, as well as exposing a /debug/pprof endpoint on localhost:6060")
+
 	// status cmd
 	statusCmd.Flags().String("postgres-url", "", "Source postgres URL where pgstream has been initialised")
 	statusCmd.Flags().String("replication-slot", "", "Name of the postgres replication slot created by pgstream on the source url")
+	statusCmd.Flags().String("replication-plugin", "", "Logical decoding output plugin expected for the replication slot. One of wal2json, pgoutput. Defaults to wal2json")
 	statusCmd.Flags().Bool("json", false, "Output the status in JSON format")
 
 	// Flag binding for root cmd
@@ -86,6 +101,7 @@ func Prepare() *cobra.Command {
 	rootCmd.AddCommand(tearDownCmd)
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(replayCmd)
 	rootCmd.AddCommand(statusCmd)
 	return rootCmd
 }
@@ -24,6 +24,7 @@ var runCmd = &cobra.Command{
 	pgstream run --source postgres --source-url <source-postgres-url> --target postgres --target-url <target-postgres-url> --snapshot-tables <schema.table> --reset
 	pgstream run --source kafka --source-url <kafka-url> --target elasticsearch --target-url <elasticsearch-url>
 	pgstream run --source postgres --source-url <postgres-url> --target kafka --target-url <kafka-url>
+	pgstream run --source file --source-url <files-glob> --target postgres --target-url <target-postgres-url>
 	pgstream run --config config.yaml --log-level info
 	pgstream run --config config.env`,
 }
@@ -128,6 +129,9 @@ func sourceFlagBinding(cmd *cobra.Command) error {
 
 		viper.BindPFlag("PGSTREAM_KAFKA_READER_SERVERS", cmd.Flags().Lookup("source-url"))
 		viper.Set("PGSTREAM_KAFKA_TOPIC_NAME", defaultKafkaTopicName)
+	case "file":
+		viper.BindPFlag("source.file.paths", cmd.Flags().Lookup("source-url"))
+		viper.BindPFlag("PGSTREAM_FILE_LISTENER_PATHS", cmd.Flags().Lookup("source-url"))
 	default:
 		return errUnsupportedSource
 	}
@@ -170,6 +174,14 @@ func targetFlagBinding(cmd *cobra.Command) error {
 		viper.BindPFlag("PGSTREAM_KAFKA_WRITER_SERVERS", cmd.Flags().Lookup("target-url"))
 		viper.Set("PGSTREAM_KAFKA_TOPIC_NAME", defaultKafkaTopicName)
 		viper.Set("PGSTREAM_KAFKA_TOPIC_AUTO_CREATE", true)
+	case "file":
+		// the target url is the directory where the files are written
+		viper.BindPFlag("target.file.path", cmd.Flags().Lookup("target-url"))
+		viper.BindPFlag("PGSTREAM_FILE_WRITER_PATH", cmd.Flags().Lookup("target-url"))
+	case "parquet":
+		// the target url is the root directory where the files are written
+		viper.BindPFlag("target.parquet.path", cmd.Flags().Lookup("target-url"))
+		viper.BindPFlag("PGSTREAM_PARQUET_WRITER_PATH", cmd.Flags().Lookup("target-url"))
 	default:
 		return errUnsupportedTarget
 	}
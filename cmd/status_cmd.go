@@ -81,6 +81,7 @@ func statusFlagBinding(cmd *cobra.Command, _ []string) {
 	// provided
 	viper.BindPFlag("source.postgres.url", cmd.Flags().Lookup("postgres-url"))
 	viper.BindPFlag("source.postgres.replication.replication_slot", cmd.Flags().Lookup("replication-slot"))
+	viper.BindPFlag("source.postgres.replication.plugin", cmd.Flags().Lookup("replication-plugin"))
 	viper.Set("source.postgres.mode", "replication")
 
 	// to be able to overwrite configuration with flags when env config file is
@@ -88,4 +89,5 @@ func statusFlagBinding(cmd *cobra.Command, _ []string) {
 	viper.BindPFlag("PGSTREAM_POSTGRES_LISTENER_URL", cmd.Flags().Lookup("postgres-url"))
 	viper.BindPFlag("PGSTREAM_POSTGRES_SNAPSHOT_LISTENER_URL", cmd.Flags().Lookup("postgres-url"))
 	viper.BindPFlag("PGSTREAM_POSTGRES_REPLICATION_SLOT_NAME", cmd.Flags().Lookup("replication-slot"))
+	viper.BindPFlag("PGSTREAM_POSTGRES_REPLICATION_PLUGIN", cmd.Flags().Lookup("replication-plugin"))
 }
@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TextToWalValue converts the text representation of a column value into the
+// same types used by wal2json: booleans and numbers are converted, the rest
+// are kept as strings.
+func TextToWalValue(oid uint32, text string) any {
+	switch oid {
+	case pgtype.BoolOID:
+		return text == "t"
+	case pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID, pgtype.OIDOID,
+		pgtype.Float4OID, pgtype.Float8OID, pgtype.NumericOID:
+		// special values (NaN, Infinity) are kept as strings
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return text
+		}
+		return number
+	default:
+		return text
+	}
+}
+
+// ToWalValue converts a column value decoded by pgx into the same
+// representation used by wal2json, by encoding it in the postgres text format
+// of its type first. Values that can't be encoded are returned as is. The type
+// map on input is not safe for concurrent use.
+func ToWalValue(typeMap *pgtype.Map, oid uint32, value any) any {
+	if value == nil {
+		return nil
+	}
+	text, err := typeMap.Encode(oid, pgtype.TextFormatCode, value, nil)
+	if err != nil || text == nil {
+		return value
+	}
+	return TextToWalValue(oid, string(text))
+}
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextToWalValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		oid  uint32
+		text string
+
+		wantValue any
+	}{
+		{name: "bool true", oid: pgtype.BoolOID, text: "t", wantValue: true},
+		{name: "bool false", oid: pgtype.BoolOID, text: "f", wantValue: false},
+		{name: "integer", oid: pgtype.Int4OID, text: "42", wantValue: float64(42)},
+		{name: "numeric", oid: pgtype.NumericOID, text: "3.14", wantValue: 3.14},
+		{name: "numeric NaN", oid: pgtype.NumericOID, text: "NaN", wantValue: "NaN"},
+		{name: "float infinity", oid: pgtype.Float8OID, text: "Infinity", wantValue: "Infinity"},
+		{name: "text", oid: pgtype.TextOID, text: "hello", wantValue: "hello"},
+		{name: "timestamp", oid: pgtype.TimestamptzOID, text: "2024-05-02 10:30:15+00", wantValue: "2024-05-02 10:30:15+00"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.wantValue, TextToWalValue(tc.oid, tc.text))
+		})
+	}
+}
+
+func TestToWalValue(t *testing.T) {
+	t.Parallel()
+
+	testUUID := [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+
+	tests := []struct {
+		name  string
+		oid   uint32
+		value any
+
+		wantValue any
+	}{
+		{name: "nil", oid: pgtype.Int4OID, value: nil, wantValue: nil},
+		{name: "bool", oid: pgtype.BoolOID, value: true, wantValue: true},
+		{name: "integer", oid: pgtype.Int8OID, value: int64(42), wantValue: float64(42)},
+		{name: "numeric", oid: pgtype.NumericOID, value: pgtype.Numeric{Int: big.NewInt(314), Exp: -2, Valid: true}, wantValue: 3.14},
+		{name: "text", oid: pgtype.TextOID, value: "hello", wantValue: "hello"},
+		{name: "uuid", oid: pgtype.UUIDOID, value: testUUID, wantValue: "123e4567-e89b-12d3-a456-426614174000"},
+		{name: "date", oid: pgtype.DateOID, value: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), wantValue: "2024-05-02"},
+		{name: "jsonb", oid: pgtype.JSONBOID, value: map[string]any{"a": 1}, wantValue: `{"a":1}`},
+		{name: "unsupported value", oid: pgtype.Int4OID, value: struct{}{}, wantValue: struct{}{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.wantValue, ToWalValue(pgtype.NewMap(), tc.oid, tc.value))
+		})
+	}
+}
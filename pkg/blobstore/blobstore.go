@@ -0,0 +1,35 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package blobstore
+
+import (
+	"context"
+	"errors"
+)
+
+// Store is a blob store, where payloads too large to be written inline can be
+// stored, retrieved and deleted by key.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the blob for the key on input. Deleting a missing blob
+	// is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	errInvalidKey = errors.New("invalid blob key")
+)
+
+// NewStore returns the blob store for the configuration on input.
+func NewStore(cfg *Config) (Store, error) {
+	if err := cfg.IsValid(); err != nil {
+		return nil, err
+	}
+
+	if cfg.Local != nil {
+		return NewLocalStore(cfg.Local)
+	}
+	return NewS3Store(cfg.S3)
+}
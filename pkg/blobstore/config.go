@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package blobstore
+
+import (
+	"errors"
+	"time"
+)
+
+// Config of the blob store. Only one of the backends can be configured.
+type Config struct {
+	Local *LocalConfig
+	S3    *S3Config
+}
+
+type LocalConfig struct {
+	// Dir is the directory where the blobs are stored. It's created if it
+	// doesn't exist.
+	Dir string
+}
+
+type S3Config struct {
+	// Endpoint is the URL of the S3 compatible API (i.e.
+	// https://s3.eu-west-1.amazonaws.com, http://localhost:9000). Buckets are
+	// addressed using the path style.
+	Endpoint string
+	// Region used to sign the requests. Defaults to us-east-1.
+	Region string
+	Bucket string
+	// Prefix is prepended to the blob keys. Optional.
+	Prefix string
+	// AccessKeyID and SecretAccessKey are the credentials used to sign the
+	// requests. If not set, the requests are sent unsigned.
+	AccessKeyID     string
+	SecretAccessKey string
+	// Timeout is the max time the client will wait for a response. Defaults
+	// to 30s.
+	Timeout time.Duration
+}
+
+const (
+	defaultRegion  = "us-east-1"
+	defaultTimeout = 30 * time.Second
+)
+
+var (
+	errMissingBackend   = errors.New("blob store requires a local or s3 backend")
+	errMultipleBackends = errors.New("only one blob store backend can be configured")
+	errMissingDir       = errors.New("missing blob store directory")
+	errMissingEndpoint  = errors.New("missing blob store s3 endpoint")
+	errMissingBucket    = errors.New("missing blob store s3 bucket")
+)
+
+func (c *Config) IsValid() error {
+	switch {
+	case c.Local == nil && c.S3 == nil:
+		return errMissingBackend
+	case c.Local != nil && c.S3 != nil:
+		return errMultipleBackends
+	case c.Local != nil:
+		if c.Local.Dir == "" {
+			return errMissingDir
+		}
+	default:
+		if c.S3.Endpoint == "" {
+			return errMissingEndpoint
+		}
+		if c.S3.Bucket == "" {
+			return errMissingBucket
+		}
+	}
+	return nil
+}
+
+func (c *S3Config) region() string {
+	if c.Region != "" {
+		return c.Region
+	}
+	return defaultRegion
+}
+
+func (c *S3Config) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return defaultTimeout
+}
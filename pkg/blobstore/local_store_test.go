@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package blobstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "blobs")
+	store, err := NewLocalStore(&LocalConfig{Dir: dir})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, "a/b", []byte("blob")))
+
+	data, err := store.Get(ctx, "a/b")
+	require.NoError(t, err)
+	require.Equal(t, []byte("blob"), data)
+
+	_, err = store.Get(ctx, "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, store.Delete(ctx, "a/b"))
+	_, err = store.Get(ctx, "a/b")
+	require.ErrorIs(t, err, ErrNotFound)
+	// deleting a missing blob is not an error
+	require.NoError(t, store.Delete(ctx, "a/b"))
+
+	for _, key := range []string{"", "../outside", "/abs"} {
+		err = store.Put(ctx, key, []byte("blob"))
+		require.ErrorIs(t, err, errInvalidKey, key)
+	}
+}
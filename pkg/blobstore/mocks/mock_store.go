@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/xataio/pgstream/pkg/blobstore"
+)
+
+// Store is an in memory blob store.
+type Store struct {
+	PutFn    func(ctx context.Context, key string, data []byte) error
+	GetFn    func(ctx context.Context, key string) ([]byte, error)
+	DeleteFn func(ctx context.Context, key string) error
+
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func (m *Store) Put(ctx context.Context, key string, data []byte) error {
+	if m.PutFn != nil {
+		return m.PutFn(ctx, key, data)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.blobs == nil {
+		m.blobs = map[string][]byte{}
+	}
+	m.blobs[key] = data
+	return nil
+}
+
+func (m *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	if m.GetFn != nil {
+		return m.GetFn(ctx, key)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, found := m.blobs[key]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", blobstore.ErrNotFound, key)
+	}
+	return data, nil
+}
+
+func (m *Store) Delete(ctx context.Context, key string) error {
+	if m.DeleteFn != nil {
+		return m.DeleteFn(ctx, key)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.blobs, key)
+	return nil
+}
+
+// GetBlobs returns the blobs stored.
+func (m *Store) GetBlobs() map[string][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.blobs
+}
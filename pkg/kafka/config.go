@@ -2,12 +2,39 @@
 
 package kafka
 
-import tlslib "github.com/xataio/pgstream/pkg/tls"
+import (
+	"strings"
+	"time"
+
+	tlslib "github.com/xataio/pgstream/pkg/tls"
+)
 
 type ConnConfig struct {
 	Servers []string
 	Topic   TopicConfig
 	TLS     tlslib.Config
+	// SASL authentication settings. If not set, no authentication is used.
+	SASL *SASLConfig
+	// Compression codec of the produced messages. One of gzip, snappy, lz4 or
+	// zstd. Defaults to no compression. Only applies to writers.
+	Compression string
+	// RequiredAcks is the number of acknowledgements required by the
+	// produced messages. One of all, leader or none. Defaults to all. Only
+	// applies to writers.
+	RequiredAcks string
+	// DisableRetries disables the writer retries, which could duplicate the
+	// messages written but not acknowledged. This doesn't make the writes
+	// idempotent, since the kafka client doesn't support the idempotent
+	// producer protocol, and messages can still be duplicated when the writes
+	// are retried by the caller. Only applies to writers.
+	DisableRetries bool
+}
+
+type SASLConfig struct {
+	// Mechanism is one of PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512.
+	Mechanism string
+	Username  string
+	Password  string
 }
 
 type TopicConfig struct {
@@ -19,8 +46,32 @@ type TopicConfig struct {
 	// AutoCreate defines if the topic should be created if it doesn't exist.
 	// Defaults to false.
 	AutoCreate bool
+	// CleanupPolicy of the topic when it's created (i.e. compact). Defaults to
+	// the broker setting.
+	CleanupPolicy string
 }
 
+const CleanupPolicyCompact = "compact"
+
+const (
+	SASLMechanismPlain       = "PLAIN"
+	SASLMechanismScramSHA256 = "SCRAM-SHA-256"
+	SASLMechanismScramSHA512 = "SCRAM-SHA-512"
+)
+
+const (
+	CompressionGzip   = "gzip"
+	CompressionSnappy = "snappy"
+	CompressionLz4    = "lz4"
+	CompressionZstd   = "zstd"
+)
+
+const (
+	RequiredAcksAll    = "all"
+	RequiredAcksLeader = "leader"
+	RequiredAcksNone   = "none"
+)
+
 type ReaderConfig struct {
 	Conn ConnConfig
 	// ConsumerGroupID is the ID of the consumer group to use. If not set,
@@ -29,13 +80,25 @@ type ReaderConfig struct {
 	// ConsumerGroupStartOffset is the offset to start consuming from. If not
 	// set, defaults to "earliest".
 	ConsumerGroupStartOffset string
+	// Topics contains additional topics to read from, along with the
+	// connection topic (i.e. when the events are written to per table topics).
+	Topics []string
+	// TopicPattern is a regular expression used to select the topics to read
+	// from, along with the connection topic and the additional topics. It's
+	// matched against the existing topics when the reader is created, and
+	// again every TopicRefreshInterval.
+	TopicPattern string
+	// TopicRefreshInterval is how often the topic pattern is resolved again,
+	// to pick up new matching topics. Defaults to 1 minute.
+	TopicRefreshInterval time.Duration
 }
 
 const (
-	defaultNumPartitions       = 1
-	defaultReplicationFactor   = 1
-	defaultConsumerGroupOffset = earliestOffset
-	defaultConsumerGroupID     = "pgstream-consumer-group"
+	defaultNumPartitions        = 1
+	defaultReplicationFactor    = 1
+	defaultConsumerGroupOffset  = earliestOffset
+	defaultConsumerGroupID      = "pgstream-consumer-group"
+	defaultTopicRefreshInterval = time.Minute
 )
 
 func (c *TopicConfig) numPartitions() int {
@@ -46,12 +109,19 @@ func (c *TopicConfig) numPartitions() int {
 }
 
 func (c *TopicConfig) replicationFactor() int {
-	if c.NumPartitions > 0 {
+	if c.ReplicationFactor > 0 {
 		return c.ReplicationFactor
 	}
 	return defaultReplicationFactor
 }
 
+func (c *ConnConfig) requiredAcks() string {
+	if c.RequiredAcks != "" {
+		return strings.ToLower(c.RequiredAcks)
+	}
+	return RequiredAcksAll
+}
+
 func (c *ReaderConfig) consumerGroupID() string {
 	if c.ConsumerGroupID != "" {
 		return c.ConsumerGroupID
@@ -59,6 +129,13 @@ func (c *ReaderConfig) consumerGroupID() string {
 	return defaultConsumerGroupID
 }
 
+func (c *ReaderConfig) topicRefreshInterval() time.Duration {
+	if c.TopicRefreshInterval > 0 {
+		return c.TopicRefreshInterval
+	}
+	return defaultTopicRefreshInterval
+}
+
 func (c *ReaderConfig) consumerGroupStartOffset() string {
 	if c.ConsumerGroupStartOffset != "" {
 		return c.ConsumerGroupStartOffset
@@ -7,17 +7,23 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 	"time"
 
 	tlslib "github.com/xataio/pgstream/pkg/tls"
 
 	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
 )
 
+var errUnsupportedSASLMechanism = errors.New("unsupported SASL mechanism")
+
 // withConnection creates a connection that can be used by the kafka operation
 // passed in the parameters. This ensures the cleanup of all connection resources.
 func withConnection(config *ConnConfig, kafkaOperation func(conn *kafka.Conn) error) error {
-	dialer, err := buildDialer(&config.TLS)
+	dialer, err := buildDialer(config)
 	if err != nil {
 		return err
 	}
@@ -54,17 +60,54 @@ func withConnection(config *ConnConfig, kafkaOperation func(conn *kafka.Conn) er
 	return kafkaOperation(controllerConn)
 }
 
-func buildDialer(cfg *tlslib.Config) (*kafka.Dialer, error) {
+func buildDialer(cfg *ConnConfig) (*kafka.Dialer, error) {
 	timeout := 10 * time.Second
 
-	tlsConfig, err := tlslib.NewConfig(cfg)
+	tlsConfig, err := tlslib.NewConfig(&cfg.TLS)
 	if err != nil {
 		return nil, fmt.Errorf("loading TLS configuration: %w", err)
 	}
 
+	mechanism, err := buildSASLMechanism(cfg.SASL)
+	if err != nil {
+		return nil, err
+	}
+
 	return &kafka.Dialer{
-		Timeout:   timeout,
-		DualStack: true,
-		TLS:       tlsConfig,
+		Timeout:       timeout,
+		DualStack:     true,
+		TLS:           tlsConfig,
+		SASLMechanism: mechanism,
 	}, nil
 }
+
+// buildSASLMechanism returns the SASL mechanism for the config on input, or
+// nil if SASL is not configured.
+func buildSASLMechanism(cfg *SASLConfig) (sasl.Mechanism, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	switch strings.ToUpper(cfg.Mechanism) {
+	case SASLMechanismPlain:
+		return plain.Mechanism{
+			Username: cfg.Username,
+			Password: cfg.Password,
+		}, nil
+	case SASLMechanismScramSHA256:
+		return newScramMechanism(scram.SHA256, cfg)
+	case SASLMechanismScramSHA512:
+		return newScramMechanism(scram.SHA512, cfg)
+	default:
+		return nil, fmt.Errorf("%w: %q, must be one of [%s, %s, %s]", errUnsupportedSASLMechanism, cfg.Mechanism,
+			SASLMechanismPlain, SASLMechanismScramSHA256, SASLMechanismScramSHA512)
+	}
+}
+
+func newScramMechanism(algorithm scram.Algorithm, cfg *SASLConfig) (sasl.Mechanism, error) {
+	mechanism, err := scram.Mechanism(algorithm, cfg.Username, cfg.Password)
+	if err != nil {
+		return nil, fmt.Errorf("building SASL %s mechanism: %w", cfg.Mechanism, err)
+	}
+	return mechanism, nil
+}
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSASLMechanism(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		cfg  *SASLConfig
+
+		wantMechanism string
+		wantErr       error
+	}{
+		{
+			name: "ok - not configured",
+			cfg:  nil,
+
+			wantMechanism: "",
+		},
+		{
+			name: "ok - plain",
+			cfg:  &SASLConfig{Mechanism: "PLAIN", Username: "user", Password: "password"},
+
+			wantMechanism: "PLAIN",
+		},
+		{
+			name: "ok - scram sha 256",
+			cfg:  &SASLConfig{Mechanism: "SCRAM-SHA-256", Username: "user", Password: "password"},
+
+			wantMechanism: "SCRAM-SHA-256",
+		},
+		{
+			name: "ok - scram sha 512, case insensitive",
+			cfg:  &SASLConfig{Mechanism: "scram-sha-512", Username: "user", Password: "password"},
+
+			wantMechanism: "SCRAM-SHA-512",
+		},
+		{
+			name: "error - unsupported mechanism",
+			cfg:  &SASLConfig{Mechanism: "GSSAPI"},
+
+			wantErr: errUnsupportedSASLMechanism,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mechanism, err := buildSASLMechanism(tc.cfg)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantMechanism == "" {
+				require.Nil(t, mechanism)
+				return
+			}
+			require.Equal(t, tc.wantMechanism, mechanism.Name())
+		})
+	}
+}
+
+func TestBuildSASLMechanism_Plain(t *testing.T) {
+	t.Parallel()
+
+	mechanism, err := buildSASLMechanism(&SASLConfig{Mechanism: "PLAIN", Username: "user", Password: "password"})
+	require.NoError(t, err)
+	require.Equal(t, plain.Mechanism{Username: "user", Password: "password"}, mechanism)
+}
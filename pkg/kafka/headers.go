@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+// Headers set on the messages written by the pgstream kafka processor, so that
+// consumers can route them without deserialising their value.
+const (
+	HeaderSchema          = "pgstream-schema"
+	HeaderTable           = "pgstream-table"
+	HeaderAction          = "pgstream-action"
+	HeaderLSN             = "pgstream-lsn"
+	HeaderCommitTimestamp = "pgstream-commit-timestamp"
+	HeaderSchemaVersion   = "pgstream-schema-version"
+	HeaderTableID         = "pgstream-table-id"
+	HeaderContentType     = "content-type"
+
+	// HeaderClaimCheck is set on the messages whose value has been written to
+	// a blob store, with the key of the blob.
+	HeaderClaimCheck = "pgstream-claim-check"
+	// The chunk headers are set on the messages whose value has been split
+	// across multiple messages.
+	HeaderChunkID    = "pgstream-chunk-id"
+	HeaderChunkIndex = "pgstream-chunk-index"
+	HeaderChunkCount = "pgstream-chunk-count"
+
+	// The error headers are set on the messages forwarded to an error topic
+	// by the kafka listener, once their processing attempts are exhausted.
+	HeaderError           = "pgstream-error"
+	HeaderErrorAttempts   = "pgstream-error-attempts"
+	HeaderSourceTopic     = "pgstream-source-topic"
+	HeaderSourcePartition = "pgstream-source-partition"
+	HeaderSourceOffset    = "pgstream-source-offset"
+)
+
+// Header returns the value of the last header with the key on input, and
+// whether it was found.
+func (m Message) Header(key string) ([]byte, bool) {
+	for i := len(m.Headers) - 1; i >= 0; i-- {
+		if m.Headers[i].Key == key {
+			return m.Headers[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// SetHeader sets the value of the header with the key on input, replacing any
+// existing headers with the same key.
+func (m *Message) SetHeader(key string, value []byte) {
+	headers := m.Headers[:0:0]
+	for _, h := range m.Headers {
+		if h.Key != key {
+			headers = append(headers, h)
+		}
+	}
+	m.Headers = append(headers, Header{Key: key, Value: value})
+}
+
+// HeadersMap returns the message headers as a map. When there are multiple
+// headers with the same key, the last one is kept.
+func (m Message) HeadersMap() map[string]string {
+	if len(m.Headers) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(m.Headers))
+	for _, h := range m.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	return headers
+}
@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessage_Headers(t *testing.T) {
+	t.Parallel()
+
+	msg := Message{
+		Headers: []Header{
+			{Key: "a", Value: []byte("1")},
+			{Key: "b", Value: []byte("2")},
+			{Key: "a", Value: []byte("3")},
+		},
+	}
+
+	value, found := msg.Header("a")
+	require.True(t, found)
+	require.Equal(t, []byte("3"), value)
+	require.Equal(t, map[string]string{"a": "3", "b": "2"}, msg.HeadersMap())
+
+	_, found = msg.Header("c")
+	require.False(t, found)
+
+	msg.SetHeader("a", []byte("4"))
+	require.Equal(t, []Header{
+		{Key: "b", Value: []byte("2")},
+		{Key: "a", Value: []byte("4")},
+	}, msg.Headers)
+
+	require.Nil(t, Message{}.HeadersMap())
+}
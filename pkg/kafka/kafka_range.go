@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OffsetRange defines the messages to read from a partition. The start bound
+// is inclusive and the end bound exclusive. If not set, the start defaults to
+// the first offset of the partition, and the end to the last offset of the
+// partition at the time the range is resolved.
+type OffsetRange struct {
+	Start RangeBound
+	End   RangeBound
+}
+
+// RangeBound is either an offset or a timestamp. Timestamps are resolved to
+// the offset of the first message produced at or after them.
+type RangeBound struct {
+	Offset    *int64
+	Timestamp *time.Time
+}
+
+// PartitionRange is the offset range to read for a specific partition.
+type PartitionRange struct {
+	Partition int
+	Range     OffsetRange
+}
+
+var (
+	ErrInvalidRangeFormat = errors.New("invalid format for kafka range")
+	errInvalidRangeBound  = errors.New("range bound can only be one of offset or timestamp")
+
+	rangeSeparator     = ".."
+	partitionSeparator = "="
+)
+
+// ParseOffsetRange parses a range in the format <start>..<end>, where each of
+// the bounds is either an offset or an RFC3339 timestamp, and can be omitted.
+func ParseOffsetRange(s string) (*OffsetRange, error) {
+	startStr, endStr, found := strings.Cut(s, rangeSeparator)
+	if !found {
+		return nil, fmt.Errorf("%w: %q, expected <start>..<end>", ErrInvalidRangeFormat, s)
+	}
+
+	start, err := parseRangeBound(startStr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing range start: %w", err)
+	}
+	end, err := parseRangeBound(endStr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing range end: %w", err)
+	}
+
+	return &OffsetRange{
+		Start: *start,
+		End:   *end,
+	}, nil
+}
+
+// ParsePartitionRange parses a partition range in the format
+// <partition>=<start>..<end>, following the ParseOffsetRange range format.
+func ParsePartitionRange(s string) (*PartitionRange, error) {
+	partitionStr, rangeStr, found := strings.Cut(s, partitionSeparator)
+	if !found {
+		return nil, fmt.Errorf("%w: %q, expected <partition>=<start>..<end>", ErrInvalidRangeFormat, s)
+	}
+
+	partition, err := strconv.Atoi(strings.TrimSpace(partitionStr))
+	if err != nil || partition < 0 {
+		return nil, fmt.Errorf("%w: invalid partition %q", ErrInvalidRangeFormat, partitionStr)
+	}
+
+	offsetRange, err := ParseOffsetRange(rangeStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PartitionRange{
+		Partition: partition,
+		Range:     *offsetRange,
+	}, nil
+}
+
+func (r *OffsetRange) IsValid() error {
+	if err := r.Start.IsValid(); err != nil {
+		return fmt.Errorf("invalid range start: %w", err)
+	}
+	if err := r.End.IsValid(); err != nil {
+		return fmt.Errorf("invalid range end: %w", err)
+	}
+	return nil
+}
+
+func (b *RangeBound) IsValid() error {
+	if b.Offset != nil && b.Timestamp != nil {
+		return errInvalidRangeBound
+	}
+	return nil
+}
+
+func parseRangeBound(s string) (*RangeBound, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return &RangeBound{}, nil
+	}
+
+	if offset, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if offset < 0 {
+			return nil, fmt.Errorf("%w: negative offset %d", ErrInvalidRangeFormat, offset)
+		}
+		return &RangeBound{Offset: &offset}, nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q is neither an offset nor an RFC3339 timestamp", ErrInvalidRangeFormat, s)
+	}
+	return &RangeBound{Timestamp: &timestamp}, nil
+}
@@ -0,0 +1,363 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"golang.org/x/sync/errgroup"
+)
+
+// RangeReader reads the messages of one or more topics within a bounded range
+// of offsets per partition. It doesn't use a consumer group, so the offsets are never
+// committed, and it doesn't interfere with the consumers of the topic.
+type RangeReader struct {
+	logger     loglib.Logger
+	partitions []*partitionRangeReader
+
+	once     sync.Once
+	cancel   context.CancelFunc
+	messages chan *Message
+	// err contains the reason the partition readers stopped, if any. It's set
+	// before the messages channel is closed.
+	err error
+}
+
+type RangeReaderConfig struct {
+	Conn ConnConfig
+	// Topics and TopicPattern select additional topics to read from, along
+	// with the connection topic, the same way they do for the kafka reader.
+	Topics       []string
+	TopicPattern string
+	// Range is the offset range read from all the topic partitions when no
+	// partitions are provided.
+	Range OffsetRange
+	// Partitions limits the read to the partitions provided, using their own
+	// offset range. They apply to the matching partition of every topic.
+	Partitions []PartitionRange
+}
+
+type partitionRangeReader struct {
+	topic     string
+	partition int
+	reader    partitionReader
+	// start is inclusive, end exclusive
+	start int64
+	end   int64
+}
+
+type partitionReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	Close() error
+}
+
+type partitionOffsetReader interface {
+	ReadFirstOffset() (int64, error)
+	ReadLastOffset() (int64, error)
+	ReadOffset(t time.Time) (int64, error)
+}
+
+// ErrEndOfRange is returned by the range reader once all the messages in the
+// configured range have been read.
+var ErrEndOfRange = errors.New("end of kafka range reached")
+
+// NewRangeReader returns a kafka reader that will read the messages of the
+// configured topics within the offset range of each partition. The topics are
+// resolved like they are for the kafka reader, so that the range reader reads
+// the same topics as the listener. The timestamp
+// bounds are resolved to offsets on creation, and the unset end bounds are set
+// to the last offset of the partition, so that the reader stops once it
+// reaches the messages produced after its creation.
+func NewRangeReader(ctx context.Context, config RangeReaderConfig, logger loglib.Logger) (*RangeReader, error) {
+	logger.Info("creating kafka range reader", loglib.Fields{
+		"kafka_servers": config.Conn.Servers,
+		"tls_enabled":   config.Conn.TLS.Enabled,
+	})
+
+	if err := config.IsValid(); err != nil {
+		return nil, err
+	}
+
+	topics, err := readerTopics(&ReaderConfig{
+		Conn:         config.Conn,
+		Topics:       config.Topics,
+		TopicPattern: config.TopicPattern,
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("kafka range reader topics", loglib.Fields{"kafka_topics": topics})
+
+	dialer, err := buildDialer(&config.Conn)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &RangeReader{
+		logger:   logger,
+		messages: make(chan *Message),
+	}
+
+	for _, topic := range topics {
+		partitions, err := lookupPartitions(ctx, &config.Conn, topic)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("looking up partitions for topic %s: %w", topic, err)
+		}
+
+		for _, pr := range config.partitionRanges(partitions) {
+			start, end, err := resolvePartitionRange(ctx, &config.Conn, topic, &pr)
+			if err != nil {
+				r.Close()
+				return nil, fmt.Errorf("resolving range for topic %s partition %d: %w", topic, pr.Partition, err)
+			}
+
+			logger.Info("kafka range resolved", loglib.Fields{
+				"topic":        topic,
+				"partition":    pr.Partition,
+				"start_offset": start,
+				"end_offset":   end,
+			})
+			if start >= end {
+				continue
+			}
+
+			reader := kafka.NewReader(kafka.ReaderConfig{
+				Brokers:     config.Conn.Servers,
+				Topic:       topic,
+				Partition:   pr.Partition,
+				MaxBytes:    maxReaderBytes,
+				Dialer:      dialer,
+				Logger:      makeLogger(logger.Trace),
+				ErrorLogger: makeErrLogger(logger.Error),
+			})
+			if err := reader.SetOffset(start); err != nil {
+				reader.Close()
+				r.Close()
+				return nil, fmt.Errorf("setting offset for topic %s partition %d: %w", topic, pr.Partition, err)
+			}
+
+			r.partitions = append(r.partitions, &partitionRangeReader{
+				topic:     topic,
+				partition: pr.Partition,
+				reader:    reader,
+				start:     start,
+				end:       end,
+			})
+		}
+	}
+
+	return r, nil
+}
+
+// FetchMessage returns the next message within the range, from any of the
+// partitions. The order of the messages is only guaranteed within a
+// partition. It returns ErrEndOfRange once all the partitions have been read.
+func (r *RangeReader) FetchMessage(ctx context.Context) (*Message, error) {
+	r.once.Do(r.start)
+
+	select {
+	case msg, ok := <-r.messages:
+		if !ok {
+			if r.err != nil {
+				return nil, r.err
+			}
+			return nil, ErrEndOfRange
+		}
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// CommitOffsets is a noop, since the range reader doesn't use a consumer group.
+func (r *RangeReader) CommitOffsets(ctx context.Context, offsets ...*Offset) error {
+	return nil
+}
+
+func (r *RangeReader) Close() error {
+	if r.cancel != nil {
+		r.cancel()
+	}
+
+	var errs error
+	for _, p := range r.partitions {
+		if err := p.reader.Close(); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("closing reader for topic %s partition %d: %w", p.topic, p.partition, err))
+		}
+	}
+	return errs
+}
+
+func (r *RangeReader) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	eg, ctx := errgroup.WithContext(ctx)
+	for _, p := range r.partitions {
+		eg.Go(func() error {
+			return r.readPartition(ctx, p)
+		})
+	}
+
+	go func() {
+		r.err = eg.Wait()
+		close(r.messages)
+	}()
+}
+
+func (r *RangeReader) readPartition(ctx context.Context, p *partitionRangeReader) error {
+	for offset := p.start; offset < p.end; {
+		kafkaMsg, err := p.reader.FetchMessage(ctx)
+		if err != nil {
+			return fmt.Errorf("reading from topic %s partition %d: %w", p.topic, p.partition, err)
+		}
+		// there can be gaps in the offsets (compacted topics, transaction
+		// markers), so the end might be reached without reading its offset
+		if kafkaMsg.Offset >= p.end {
+			break
+		}
+
+		msg := Message(kafkaMsg)
+		select {
+		case r.messages <- &msg:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		offset = kafkaMsg.Offset + 1
+	}
+
+	r.logger.Info("end of kafka range reached for partition", loglib.Fields{"topic": p.topic, "partition": p.partition})
+	return nil
+}
+
+func (c *RangeReaderConfig) IsValid() error {
+	for _, p := range c.Partitions {
+		if err := p.Range.IsValid(); err != nil {
+			return fmt.Errorf("partition %d: %w", p.Partition, err)
+		}
+	}
+	return c.Range.IsValid()
+}
+
+// partitionRanges returns the offset ranges to read for the topic partitions
+// on input. When partitions are configured, only the ones provided are read,
+// and the ones missing from the topic are ignored.
+func (c *RangeReaderConfig) partitionRanges(partitions []kafka.Partition) []PartitionRange {
+	ranges := make([]PartitionRange, 0, len(partitions))
+	for _, p := range partitions {
+		if len(c.Partitions) == 0 {
+			ranges = append(ranges, PartitionRange{
+				Partition: p.ID,
+				Range:     c.Range,
+			})
+			continue
+		}
+		for _, pr := range c.Partitions {
+			if pr.Partition == p.ID {
+				ranges = append(ranges, pr)
+				break
+			}
+		}
+	}
+	return ranges
+}
+
+func resolvePartitionRange(ctx context.Context, config *ConnConfig, topic string, pr *PartitionRange) (int64, int64, error) {
+	var start, end int64
+	err := withLeaderConnection(ctx, config, topic, pr.Partition, func(conn *kafka.Conn) error {
+		var err error
+		start, end, err = resolveRange(conn, &pr.Range)
+		return err
+	})
+	return start, end, err
+}
+
+// resolveRange returns the start (inclusive) and end (exclusive) offsets of
+// the range on input, limited to the offsets available in the partition.
+func resolveRange(conn partitionOffsetReader, r *OffsetRange) (int64, int64, error) {
+	first, err := conn.ReadFirstOffset()
+	if err != nil {
+		return 0, 0, fmt.Errorf("reading first offset: %w", err)
+	}
+	last, err := conn.ReadLastOffset()
+	if err != nil {
+		return 0, 0, fmt.Errorf("reading last offset: %w", err)
+	}
+
+	start, err := resolveBound(conn, &r.Start, first, last)
+	if err != nil {
+		return 0, 0, fmt.Errorf("resolving range start: %w", err)
+	}
+	end, err := resolveBound(conn, &r.End, last, last)
+	if err != nil {
+		return 0, 0, fmt.Errorf("resolving range end: %w", err)
+	}
+
+	return max(start, first), min(end, last), nil
+}
+
+func resolveBound(conn partitionOffsetReader, b *RangeBound, defaultOffset, last int64) (int64, error) {
+	switch {
+	case b.Offset != nil:
+		return *b.Offset, nil
+	case b.Timestamp != nil:
+		offset, err := conn.ReadOffset(*b.Timestamp)
+		if err != nil {
+			return 0, err
+		}
+		// there are no messages after the timestamp
+		if offset < 0 {
+			return last, nil
+		}
+		return offset, nil
+	default:
+		return defaultOffset, nil
+	}
+}
+
+func lookupPartitions(ctx context.Context, config *ConnConfig, topic string) ([]kafka.Partition, error) {
+	dialer, err := buildDialer(config)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, server := range config.Servers {
+		partitions, err := dialer.LookupPartitions(ctx, "tcp", server, topic)
+		if err != nil {
+			// Try next server in the list
+			continue
+		}
+		return partitions, nil
+	}
+
+	return nil, errors.New("error looking up kafka partitions, all servers failed")
+}
+
+// withLeaderConnection creates a connection to the leader of the topic
+// partition that can be used by the kafka operation passed in the parameters.
+// This ensures the cleanup of all connection resources.
+func withLeaderConnection(ctx context.Context, config *ConnConfig, topic string, partition int, kafkaOperation func(conn *kafka.Conn) error) error {
+	dialer, err := buildDialer(config)
+	if err != nil {
+		return err
+	}
+
+	for _, server := range config.Servers {
+		conn, err := dialer.DialLeader(ctx, "tcp", server, topic, partition)
+		if err != nil {
+			// Try next server in the list
+			continue
+		}
+		defer conn.Close()
+		return kafkaOperation(conn)
+	}
+
+	return errors.New("error connecting to kafka partition leader, all servers failed")
+}
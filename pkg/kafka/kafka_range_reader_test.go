@@ -0,0 +1,268 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	loglib "github.com/xataio/pgstream/pkg/log"
+)
+
+type mockOffsetReader struct {
+	first, last int64
+	offsets     map[time.Time]int64
+}
+
+func (m *mockOffsetReader) ReadFirstOffset() (int64, error) { return m.first, nil }
+func (m *mockOffsetReader) ReadLastOffset() (int64, error)  { return m.last, nil }
+func (m *mockOffsetReader) ReadOffset(t time.Time) (int64, error) {
+	offset, found := m.offsets[t]
+	if !found {
+		return -1, nil
+	}
+	return offset, nil
+}
+
+type mockPartitionReader struct {
+	mu     sync.Mutex
+	msgs   []kafka.Message
+	err    error
+	closed bool
+}
+
+func (m *mockPartitionReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return kafka.Message{}, m.err
+	}
+	if len(m.msgs) == 0 {
+		// block until the reader is stopped, like the kafka reader would
+		m.mu.Unlock()
+		<-ctx.Done()
+		m.mu.Lock()
+		return kafka.Message{}, ctx.Err()
+	}
+	msg := m.msgs[0]
+	m.msgs = m.msgs[1:]
+	return msg, nil
+}
+
+func (m *mockPartitionReader) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+func TestResolveRange(t *testing.T) {
+	t.Parallel()
+
+	offset := func(o int64) *int64 { return &o }
+	before := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	after := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	offsetReader := &mockOffsetReader{
+		first:   10,
+		last:    100,
+		offsets: map[time.Time]int64{before: 20},
+	}
+
+	tests := []struct {
+		name        string
+		offsetRange OffsetRange
+
+		wantStart int64
+		wantEnd   int64
+	}{
+		{
+			name: "unbounded",
+
+			wantStart: 10,
+			wantEnd:   100,
+		},
+		{
+			name: "offsets",
+			offsetRange: OffsetRange{
+				Start: RangeBound{Offset: offset(15)},
+				End:   RangeBound{Offset: offset(50)},
+			},
+
+			wantStart: 15,
+			wantEnd:   50,
+		},
+		{
+			name: "offsets outside of the partition offsets",
+			offsetRange: OffsetRange{
+				Start: RangeBound{Offset: offset(0)},
+				End:   RangeBound{Offset: offset(500)},
+			},
+
+			wantStart: 10,
+			wantEnd:   100,
+		},
+		{
+			name: "timestamps",
+			offsetRange: OffsetRange{
+				Start: RangeBound{Timestamp: &before},
+				End:   RangeBound{Timestamp: &after},
+			},
+
+			wantStart: 20,
+			wantEnd:   100,
+		},
+		{
+			name: "start timestamp after the last message",
+			offsetRange: OffsetRange{
+				Start: RangeBound{Timestamp: &after},
+			},
+
+			wantStart: 100,
+			wantEnd:   100,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			start, end, err := resolveRange(offsetReader, &tc.offsetRange)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantStart, start)
+			require.Equal(t, tc.wantEnd, end)
+		})
+	}
+}
+
+func TestRangeReaderConfig_partitionRanges(t *testing.T) {
+	t.Parallel()
+
+	offset := func(o int64) *int64 { return &o }
+	testRange := OffsetRange{Start: RangeBound{Offset: offset(10)}}
+	partitions := []kafka.Partition{{Topic: "test-topic", ID: 0}, {Topic: "test-topic", ID: 1}}
+
+	tests := []struct {
+		name   string
+		config RangeReaderConfig
+
+		wantRanges []PartitionRange
+	}{
+		{
+			name:   "all partitions",
+			config: RangeReaderConfig{Range: testRange},
+
+			wantRanges: []PartitionRange{
+				{Partition: 0, Range: testRange},
+				{Partition: 1, Range: testRange},
+			},
+		},
+		{
+			name: "configured partitions",
+			config: RangeReaderConfig{
+				Partitions: []PartitionRange{
+					{Partition: 1, Range: testRange},
+					// not in the topic
+					{Partition: 2},
+				},
+			},
+
+			wantRanges: []PartitionRange{
+				{Partition: 1, Range: testRange},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.wantRanges, tc.config.partitionRanges(partitions))
+		})
+	}
+}
+
+func TestRangeReader_FetchMessage(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	errTest := errors.New("oh noes")
+
+	t.Run("ok", func(t *testing.T) {
+		t.Parallel()
+
+		partition0 := &mockPartitionReader{
+			msgs: []kafka.Message{
+				{Partition: 0, Offset: 5},
+				{Partition: 0, Offset: 6},
+				// outside of the range
+				{Partition: 0, Offset: 7},
+			},
+		}
+		partition1 := &mockPartitionReader{
+			msgs: []kafka.Message{
+				{Partition: 1, Offset: 1},
+				// gap in the offsets reaching the end of the range
+				{Partition: 1, Offset: 4},
+			},
+		}
+		r := &RangeReader{
+			logger:   loglib.NewNoopLogger(),
+			messages: make(chan *Message),
+			partitions: []*partitionRangeReader{
+				{topic: "topic-a", partition: 0, reader: partition0, start: 5, end: 7},
+				{topic: "topic-b", partition: 1, reader: partition1, start: 1, end: 3},
+			},
+		}
+
+		msgs := map[int][]int64{}
+		for {
+			msg, err := r.FetchMessage(ctx)
+			if errors.Is(err, ErrEndOfRange) {
+				break
+			}
+			require.NoError(t, err)
+			msgs[msg.Partition] = append(msgs[msg.Partition], msg.Offset)
+		}
+
+		require.Equal(t, map[int][]int64{0: {5, 6}, 1: {1}}, msgs)
+
+		require.NoError(t, r.Close())
+		require.True(t, partition0.closed)
+		require.True(t, partition1.closed)
+	})
+
+	t.Run("error - reading partition", func(t *testing.T) {
+		t.Parallel()
+
+		r := &RangeReader{
+			logger:   loglib.NewNoopLogger(),
+			messages: make(chan *Message),
+			partitions: []*partitionRangeReader{
+				{partition: 0, reader: &mockPartitionReader{}, start: 0, end: 10},
+				{partition: 1, reader: &mockPartitionReader{err: errTest}, start: 0, end: 10},
+			},
+		}
+		defer r.Close()
+
+		_, err := r.FetchMessage(ctx)
+		require.ErrorIs(t, err, errTest)
+	})
+
+	t.Run("no partitions", func(t *testing.T) {
+		t.Parallel()
+
+		r := &RangeReader{
+			logger:   loglib.NewNoopLogger(),
+			messages: make(chan *Message),
+		}
+		defer r.Close()
+
+		_, err := r.FetchMessage(ctx)
+		require.ErrorIs(t, err, ErrEndOfRange)
+	})
+}
@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePartitionRange(t *testing.T) {
+	t.Parallel()
+
+	offset := func(o int64) *int64 { return &o }
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		str  string
+
+		wantRange *PartitionRange
+		wantErr   error
+	}{
+		{
+			name: "ok - offsets",
+			str:  "1=100..200",
+
+			wantRange: &PartitionRange{
+				Partition: 1,
+				Range: OffsetRange{
+					Start: RangeBound{Offset: offset(100)},
+					End:   RangeBound{Offset: offset(200)},
+				},
+			},
+		},
+		{
+			name: "ok - timestamp start, open end",
+			str:  "0=2024-01-02T03:04:05Z..",
+
+			wantRange: &PartitionRange{
+				Partition: 0,
+				Range: OffsetRange{
+					Start: RangeBound{Timestamp: &timestamp},
+				},
+			},
+		},
+		{
+			name: "ok - open start, timestamp end",
+			str:  "2=..2024-01-02T03:04:05Z",
+
+			wantRange: &PartitionRange{
+				Partition: 2,
+				Range: OffsetRange{
+					End: RangeBound{Timestamp: &timestamp},
+				},
+			},
+		},
+		{
+			name: "error - missing partition",
+			str:  "100..200",
+
+			wantErr: ErrInvalidRangeFormat,
+		},
+		{
+			name: "error - invalid partition",
+			str:  "one=100..200",
+
+			wantErr: ErrInvalidRangeFormat,
+		},
+		{
+			name: "error - missing range separator",
+			str:  "1=100",
+
+			wantErr: ErrInvalidRangeFormat,
+		},
+		{
+			name: "error - negative offset",
+			str:  "1=-1..",
+
+			wantErr: ErrInvalidRangeFormat,
+		},
+		{
+			name: "error - invalid bound",
+			str:  "1=yesterday..",
+
+			wantErr: ErrInvalidRangeFormat,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			partitionRange, err := ParsePartitionRange(tc.str)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantRange, partitionRange)
+		})
+	}
+}
+
+func TestOffsetRange_IsValid(t *testing.T) {
+	t.Parallel()
+
+	offset := int64(1)
+	timestamp := time.Now()
+
+	r := OffsetRange{
+		Start: RangeBound{Offset: &offset},
+		End:   RangeBound{Timestamp: &timestamp},
+	}
+	require.NoError(t, r.IsValid())
+
+	r.End.Offset = &offset
+	require.ErrorIs(t, r.IsValid(), errInvalidRangeBound)
+}
@@ -4,7 +4,12 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 	loglib "github.com/xataio/pgstream/pkg/log"
@@ -17,7 +22,20 @@ type MessageReader interface {
 }
 
 type Reader struct {
+	mutex  sync.RWMutex
 	reader *kafka.Reader
+	logger loglib.Logger
+
+	// newReader creates the underlying kafka reader for the topics on input
+	newReader func(topics []string) *kafka.Reader
+	topics    []string
+
+	// resolveTopics is only set when a topic pattern is configured, in which
+	// case the topics are resolved again every refresh interval, and the
+	// underlying reader is recreated if they have changed.
+	resolveTopics   func() ([]string, error)
+	refreshInterval time.Duration
+	lastRefresh     time.Time
 }
 
 const (
@@ -31,6 +49,7 @@ func NewReader(config ReaderConfig, logger loglib.Logger) (*Reader, error) {
 	logger.Info("creating kafka reader", loglib.Fields{
 		"kafka_servers": config.Conn.Servers,
 		"tls_enabled":   config.Conn.TLS.Enabled,
+		"sasl_enabled":  config.Conn.SASL != nil,
 	})
 
 	var startOffset int64
@@ -44,32 +63,93 @@ func NewReader(config ReaderConfig, logger loglib.Logger) (*Reader, error) {
 		return nil, fmt.Errorf("unsupported start offset [%s], must be one of [%s, %s]", config.ConsumerGroupStartOffset, earliestOffset, latestOffset)
 	}
 
-	dialer, err := buildDialer(&config.Conn.TLS)
+	dialer, err := buildDialer(&config.Conn)
+	if err != nil {
+		return nil, err
+	}
+
+	topics, err := readerTopics(&config)
 	if err != nil {
 		return nil, err
 	}
+	logger.Info("kafka reader topics", loglib.Fields{"kafka_topics": topics})
+
+	r := &Reader{
+		logger: logger,
+		topics: topics,
+		newReader: func(topics []string) *kafka.Reader {
+			// the kafka-go reader only allows a single topic to be configured
+			// when not reading from multiple topics
+			var topic string
+			var groupTopics []string
+			if len(topics) == 1 {
+				topic = topics[0]
+			} else {
+				groupTopics = topics
+			}
+
+			return kafka.NewReader(kafka.ReaderConfig{
+				Brokers:        config.Conn.Servers,
+				Topic:          topic,
+				GroupTopics:    groupTopics,
+				GroupID:        config.consumerGroupID(),
+				MaxBytes:       maxReaderBytes, // TODO: this needs to be in sync with the broker max size
+				CommitInterval: 0,              // disabled, we call commit ourselves
+				Dialer:         dialer,
+				Logger:         makeLogger(logger.Trace),
+				ErrorLogger:    makeErrLogger(logger.Error),
+				StartOffset:    startOffset,
+			})
+		},
+	}
+	r.reader = r.newReader(topics)
+
+	if config.TopicPattern != "" {
+		r.resolveTopics = func() ([]string, error) { return readerTopics(&config) }
+		r.refreshInterval = config.topicRefreshInterval()
+		r.lastRefresh = time.Now()
+	}
 
-	return &Reader{
-		reader: kafka.NewReader(kafka.ReaderConfig{
-			Brokers:        config.Conn.Servers,
-			Topic:          config.Conn.Topic.Name,
-			GroupID:        config.consumerGroupID(),
-			MaxBytes:       maxReaderBytes, // TODO: this needs to be in sync with the broker max size
-			CommitInterval: 0,              // disabled, we call commit ourselves
-			Dialer:         dialer,
-			Logger:         makeLogger(logger.Trace),
-			ErrorLogger:    makeErrLogger(logger.Error),
-			StartOffset:    startOffset,
-		}),
-	}, nil
+	return r, nil
 }
 
 // FetchMessage returns the next message from the reader. This call will block
 // until a message is available, or an error occurs. It can be stopped by
 // canceling the context.
 // The message offset needs to be explicitly committed by using CommitMessages.
+// When a topic pattern is configured, the topics are resolved again every
+// refresh interval while fetching.
 func (r *Reader) FetchMessage(ctx context.Context) (*Message, error) {
-	kafkaMsg, err := r.reader.FetchMessage(ctx)
+	for {
+		if r.resolveTopics == nil {
+			return r.fetchMessage(ctx)
+		}
+
+		untilRefresh := r.refreshInterval - time.Since(r.lastRefresh)
+		if untilRefresh <= 0 {
+			r.refreshTopics()
+			continue
+		}
+
+		// the fetch is interrupted when the refresh is due, so that new
+		// topics are picked up even if the current ones are idle. Messages
+		// are not lost when the fetch is canceled.
+		fetchCtx, cancel := context.WithTimeout(ctx, untilRefresh)
+		msg, err := r.fetchMessage(fetchCtx)
+		cancel()
+		if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			continue
+		}
+		return msg, err
+	}
+}
+
+func (r *Reader) fetchMessage(ctx context.Context) (*Message, error) {
+	r.mutex.RLock()
+	reader := r.reader
+	r.mutex.RUnlock()
+
+	kafkaMsg, err := reader.FetchMessage(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -78,6 +158,38 @@ func (r *Reader) FetchMessage(ctx context.Context) (*Message, error) {
 	return &msg, nil
 }
 
+// refreshTopics resolves the reader topics again, and replaces the underlying
+// reader if they have changed. The messages fetched but not committed by the
+// previous reader will be delivered again. Errors resolving the topics are
+// logged, and the current topics are kept until the next refresh.
+func (r *Reader) refreshTopics() {
+	r.lastRefresh = time.Now()
+
+	topics, err := r.resolveTopics()
+	if err != nil {
+		r.logger.Warn(err, "kafka reader: refreshing topics")
+		return
+	}
+	if slices.Equal(topics, r.topics) {
+		return
+	}
+
+	r.logger.Info("kafka reader topics changed", loglib.Fields{
+		"kafka_topics":          topics,
+		"previous_kafka_topics": r.topics,
+	})
+
+	r.mutex.Lock()
+	previousReader := r.reader
+	r.reader = r.newReader(topics)
+	r.topics = topics
+	r.mutex.Unlock()
+
+	if err := previousReader.Close(); err != nil {
+		r.logger.Warn(err, "kafka reader: closing previous reader")
+	}
+}
+
 func (r *Reader) CommitOffsets(ctx context.Context, offsets ...*Offset) error {
 	if len(offsets) == 0 {
 		return nil
@@ -91,9 +203,70 @@ func (r *Reader) CommitOffsets(ctx context.Context, offsets ...*Offset) error {
 			Offset:    offset.Offset,
 		})
 	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
 	return r.reader.CommitMessages(ctx, kafkaMsgs...)
 }
 
 func (r *Reader) Close() error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
 	return r.reader.Close()
 }
+
+// readerTopics returns the deduplicated list of topics the reader consumes
+// from, resolving the topic pattern against the existing topics if configured.
+func readerTopics(config *ReaderConfig) ([]string, error) {
+	topics := make([]string, 0, len(config.Topics)+1)
+	addTopic := func(topic string) {
+		if topic != "" && !slices.Contains(topics, topic) {
+			topics = append(topics, topic)
+		}
+	}
+
+	addTopic(config.Conn.Topic.Name)
+	for _, topic := range config.Topics {
+		addTopic(topic)
+	}
+
+	if config.TopicPattern != "" {
+		pattern, err := regexp.Compile(config.TopicPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid topic pattern: %w", err)
+		}
+		existingTopics, err := listTopics(&config.Conn)
+		if err != nil {
+			return nil, fmt.Errorf("listing topics: %w", err)
+		}
+		for _, topic := range existingTopics {
+			if pattern.MatchString(topic) {
+				addTopic(topic)
+			}
+		}
+	}
+
+	if len(topics) == 0 {
+		return nil, errors.New("no kafka topics to read from")
+	}
+	return topics, nil
+}
+
+// listTopics returns the sorted names of the existing topics.
+func listTopics(cfg *ConnConfig) ([]string, error) {
+	topics := []string{}
+	err := withConnection(cfg, func(conn *kafka.Conn) error {
+		partitions, err := conn.ReadPartitions()
+		if err != nil {
+			return err
+		}
+		for _, partition := range partitions {
+			if !slices.Contains(topics, partition.Topic) {
+				topics = append(topics, partition.Topic)
+			}
+		}
+		return nil
+	})
+	slices.Sort(topics)
+	return topics, err
+}
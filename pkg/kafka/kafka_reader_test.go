@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	loglib "github.com/xataio/pgstream/pkg/log"
+)
+
+func TestReaderTopics(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config ReaderConfig
+
+		wantTopics []string
+		wantErr    bool
+	}{
+		{
+			name: "connection topic",
+			config: ReaderConfig{
+				Conn: ConnConfig{Topic: TopicConfig{Name: "pgstream"}},
+			},
+
+			wantTopics: []string{"pgstream"},
+		},
+		{
+			name: "additional topics",
+			config: ReaderConfig{
+				Conn:   ConnConfig{Topic: TopicConfig{Name: "pgstream"}},
+				Topics: []string{"cdc.public.users", "pgstream", "cdc.public.orders"},
+			},
+
+			wantTopics: []string{"pgstream", "cdc.public.users", "cdc.public.orders"},
+		},
+		{
+			name: "additional topics without connection topic",
+			config: ReaderConfig{
+				Topics: []string{"cdc.public.users"},
+			},
+
+			wantTopics: []string{"cdc.public.users"},
+		},
+		{
+			name:   "error - no topics",
+			config: ReaderConfig{},
+
+			wantErr: true,
+		},
+		{
+			name: "error - invalid topic pattern",
+			config: ReaderConfig{
+				Conn:         ConnConfig{Topic: TopicConfig{Name: "pgstream"}},
+				TopicPattern: "cdc\\.(",
+			},
+
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			topics, err := readerTopics(&tc.config)
+			require.Equal(t, tc.wantErr, err != nil)
+			require.Equal(t, tc.wantTopics, topics)
+		})
+	}
+}
+
+func TestReader_refreshTopics(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		resolveTopics func() ([]string, error)
+
+		wantTopics    []string
+		wantNewReader bool
+	}{
+		{
+			name:          "topics unchanged",
+			resolveTopics: func() ([]string, error) { return []string{"pgstream", "cdc.tenant_a"}, nil },
+
+			wantTopics:    []string{"pgstream", "cdc.tenant_a"},
+			wantNewReader: false,
+		},
+		{
+			name:          "new topic",
+			resolveTopics: func() ([]string, error) { return []string{"pgstream", "cdc.tenant_a", "cdc.tenant_b"}, nil },
+
+			wantTopics:    []string{"pgstream", "cdc.tenant_a", "cdc.tenant_b"},
+			wantNewReader: true,
+		},
+		{
+			name:          "error resolving topics",
+			resolveTopics: func() ([]string, error) { return nil, errors.New("oh noes") },
+
+			wantTopics:    []string{"pgstream", "cdc.tenant_a"},
+			wantNewReader: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			newReader := func(topics []string) *kafka.Reader {
+				return kafka.NewReader(kafka.ReaderConfig{
+					Brokers: []string{"localhost:9092"},
+					Topic:   topics[0],
+				})
+			}
+
+			initialTopics := []string{"pgstream", "cdc.tenant_a"}
+			initialReader := newReader(initialTopics)
+			r := &Reader{
+				reader:          initialReader,
+				logger:          loglib.NewNoopLogger(),
+				newReader:       newReader,
+				topics:          initialTopics,
+				resolveTopics:   tc.resolveTopics,
+				refreshInterval: time.Minute,
+			}
+			defer r.Close()
+
+			r.refreshTopics()
+			require.Equal(t, tc.wantTopics, r.topics)
+			require.Equal(t, tc.wantNewReader, r.reader != initialReader)
+			require.WithinDuration(t, time.Now(), r.lastRefresh, time.Second)
+		})
+	}
+}
@@ -4,7 +4,10 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/segmentio/kafka-go"
@@ -12,6 +15,11 @@ import (
 	tlslib "github.com/xataio/pgstream/pkg/tls"
 )
 
+var (
+	errUnsupportedCompression  = errors.New("unsupported compression codec")
+	errUnsupportedRequiredAcks = errors.New("unsupported required acks")
+)
+
 type MessageWriter interface {
 	WriteMessages(context.Context, ...Message) error
 	Close() error
@@ -20,19 +28,53 @@ type MessageWriter interface {
 // Writer is a wrapper around the kafkago library writer
 type Writer struct {
 	kafkaWriter *kafka.Writer
+	// default topic for the messages that don't specify one
+	topic string
+	conn  ConnConfig
+	// partitionCount returns the number of partitions of a topic, used to
+	// write the messages marked for all partitions
+	partitionCount func(ctx context.Context, topic string) (int, error)
 }
 
+// allPartitions is set as the writer data of the messages that need to be
+// written to all the partitions of their topic.
+type allPartitions struct{}
+
+// partitionAssignment is set as the writer data of the messages that are
+// written to a specific partition, bypassing the key hash.
+type partitionAssignment int
+
 // Message is a wrapper around the kafkago library message
 type Message kafka.Message
 
+// Header is an alias of the kafkago library message header
+type Header = kafka.Header
+
 // Size returns the size of the kafka message value (does not include headers or
 // other fields)
 func (m Message) Size() int {
 	return len(m.Value)
 }
 
+// IsEmpty returns true if the message has no value nor key. Messages with a
+// key but no value are tombstones.
 func (m Message) IsEmpty() bool {
-	return m.Value == nil
+	return m.Value == nil && m.Key == nil
+}
+
+// WriteToAllPartitions marks the message to be written to all the partitions
+// of its topic, instead of the one determined by its key. It can be used for
+// the messages that need to be processed before any of the following messages
+// of the topic, regardless of their partition (i.e. schema changes).
+func (m *Message) WriteToAllPartitions() {
+	m.WriterData = allPartitions{}
+}
+
+// IsForAllPartitions returns true if the message is to be written to all the
+// partitions of its topic.
+func (m Message) IsForAllPartitions() bool {
+	_, ok := m.WriterData.(allPartitions)
+	return ok
 }
 
 type WriterConfig struct {
@@ -51,62 +93,139 @@ type WriterConfig struct {
 // NewWriter returns a kafka writer that produces messages to the configured
 // topic, using the CRC32 hash function to determine which partition to route
 // messages to. This ensures that messages with the same key are routed to the
-// same partition.
+// same partition. Messages can override the configured topic by setting their
+// own.
 //
 // If the topic auto create setting is enabled in the config, it will create it.
 func NewWriter(config WriterConfig, logger loglib.Logger) (*Writer, error) {
 	logger.Info("creating kafka writer", loglib.Fields{
 		"kafka_servers": config.Conn.Servers,
 		"tls_enabled":   config.Conn.TLS.Enabled,
+		"sasl_enabled":  config.Conn.SASL != nil,
+		"compression":   config.Conn.Compression,
+		"required_acks": config.Conn.requiredAcks(),
+		"retries":       !config.Conn.DisableRetries,
 	})
 
+	compression, err := compressionCodec(config.Conn.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	requiredAcks, err := requiredAcks(&config.Conn)
+	if err != nil {
+		return nil, err
+	}
+
+	// the writer retries the failed batches by default, which can duplicate
+	// the messages that were written but not acknowledged
+	maxAttempts := 0
+	if config.Conn.DisableRetries {
+		maxAttempts = 1
+	}
+
 	if config.Conn.Topic.AutoCreate {
-		if err := createTopic(&config.Conn); err != nil {
+		if err := createTopics(&config.Conn, config.Conn.Topic); err != nil {
 			return nil, err
 		}
 	}
 
-	transport, err := buildTransport(&config.Conn.TLS)
+	transport, err := buildTransport(&config.Conn)
 	if err != nil {
 		return nil, err
 	}
 
+	client := &kafka.Client{
+		Addr:      kafka.TCP(config.Conn.Servers...),
+		Transport: transport,
+	}
+
+	// the topic is set per message, since the kafka-go writer doesn't allow
+	// messages to override it when it's configured at the writer level.
 	return &Writer{
 		kafkaWriter: &kafka.Writer{
-			Addr:         kafka.TCP(config.Conn.Servers...),
-			Topic:        config.Conn.Topic.Name,
-			RequiredAcks: kafka.RequireAll,
-			Balancer:     &kafka.CRC32Balancer{},
-			Transport:    transport,
-			Logger:       makeLogger(logger.Trace),
-			ErrorLogger:  makeErrLogger(logger.Error),
-			BatchTimeout: config.BatchTimeout,
-			BatchBytes:   config.BatchBytes,
-			BatchSize:    config.BatchSize,
+			Addr:                   kafka.TCP(config.Conn.Servers...),
+			RequiredAcks:           requiredAcks,
+			Compression:            compression,
+			MaxAttempts:            maxAttempts,
+			Balancer:               &partitionBalancer{},
+			Transport:              transport,
+			Logger:                 makeLogger(logger.Trace),
+			ErrorLogger:            makeErrLogger(logger.Error),
+			BatchTimeout:           config.BatchTimeout,
+			BatchBytes:             config.BatchBytes,
+			BatchSize:              config.BatchSize,
+			AllowAutoTopicCreation: config.Conn.Topic.AutoCreate,
+		},
+		topic: config.Conn.Topic.Name,
+		conn:  config.Conn,
+		partitionCount: func(ctx context.Context, topic string) (int, error) {
+			return readPartitionCount(ctx, client, topic)
 		},
 	}, nil
 }
 
 func (w *Writer) WriteMessages(ctx context.Context, msgs ...Message) error {
+	kafkaMsgs, err := w.toKafkaMessages(ctx, msgs)
+	if err != nil {
+		return err
+	}
+	return w.kafkaWriter.WriteMessages(ctx, kafkaMsgs...)
+}
+
+// toKafkaMessages returns the kafka-go messages for the messages on input,
+// setting the default topic when not set, and replicating the messages marked
+// for all partitions once per partition of their topic.
+func (w *Writer) toKafkaMessages(ctx context.Context, msgs []Message) ([]kafka.Message, error) {
 	kafkaMsgs := make([]kafka.Message, 0, len(msgs))
 	for _, msg := range msgs {
-		kafkaMsgs = append(kafkaMsgs, kafka.Message(msg))
+		if msg.Topic == "" {
+			msg.Topic = w.topic
+		}
+		if !msg.IsForAllPartitions() {
+			kafkaMsgs = append(kafkaMsgs, kafka.Message(msg))
+			continue
+		}
+
+		// the partitions are retrieved every time, since they can be added
+		// at any point
+		numPartitions, err := w.partitionCount(ctx, msg.Topic)
+		if err != nil {
+			return nil, fmt.Errorf("retrieving partitions of topic %s: %w", msg.Topic, err)
+		}
+		for partition := range numPartitions {
+			msg.WriterData = partitionAssignment(partition)
+			kafkaMsgs = append(kafkaMsgs, kafka.Message(msg))
+		}
 	}
-	return w.kafkaWriter.WriteMessages(ctx, kafkaMsgs...)
+	return kafkaMsgs, nil
 }
 
 func (w *Writer) Close() error {
 	return w.kafkaWriter.Close()
 }
 
-func createTopic(cfg *ConnConfig) error {
+// CreateTopic creates the topic on input if it doesn't exist yet. It can be
+// used to create the topics of the messages that override the configured one.
+func (w *Writer) CreateTopic(topic TopicConfig) error {
+	return createTopics(&w.conn, topic)
+}
+
+func createTopics(cfg *ConnConfig, topics ...TopicConfig) error {
 	return withConnection(cfg, func(conn *kafka.Conn) error {
-		topicConfigs := []kafka.TopicConfig{
-			{
-				Topic:             cfg.Topic.Name,
-				NumPartitions:     cfg.Topic.numPartitions(),
-				ReplicationFactor: cfg.Topic.replicationFactor(),
-			},
+		topicConfigs := make([]kafka.TopicConfig, 0, len(topics))
+		for _, topic := range topics {
+			topicConfig := kafka.TopicConfig{
+				Topic:             topic.Name,
+				NumPartitions:     topic.numPartitions(),
+				ReplicationFactor: topic.replicationFactor(),
+			}
+			if topic.CleanupPolicy != "" {
+				topicConfig.ConfigEntries = []kafka.ConfigEntry{
+					{ConfigName: "cleanup.policy", ConfigValue: topic.CleanupPolicy},
+				}
+			}
+			topicConfigs = append(topicConfigs, topicConfig)
 		}
 
 		err := conn.CreateTopics(topicConfigs...)
@@ -118,14 +237,87 @@ func createTopic(cfg *ConnConfig) error {
 	})
 }
 
-func buildTransport(cfg *tlslib.Config) (kafka.RoundTripper, error) {
-	if cfg.Enabled {
-		tlsConfig, err := tlslib.NewConfig(cfg)
+// partitionBalancer routes the messages assigned to a partition to it, and the
+// rest using the CRC32 hash of their key.
+type partitionBalancer struct {
+	hashBalancer kafka.CRC32Balancer
+}
+
+func (b *partitionBalancer) Balance(msg kafka.Message, partitions ...int) int {
+	if partition, ok := msg.WriterData.(partitionAssignment); ok && slices.Contains(partitions, int(partition)) {
+		return int(partition)
+	}
+	return b.hashBalancer.Balance(msg, partitions...)
+}
+
+func readPartitionCount(ctx context.Context, client *kafka.Client, topic string) (int, error) {
+	resp, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return 0, err
+	}
+	for _, t := range resp.Topics {
+		if t.Name != topic {
+			continue
+		}
+		if t.Error != nil {
+			return 0, t.Error
+		}
+		return len(t.Partitions), nil
+	}
+	return 0, fmt.Errorf("topic %s not found", topic)
+}
+
+func buildTransport(cfg *ConnConfig) (kafka.RoundTripper, error) {
+	if !cfg.TLS.Enabled && cfg.SASL == nil {
+		return kafka.DefaultTransport, nil
+	}
+
+	transport := &kafka.Transport{}
+	if cfg.TLS.Enabled {
+		tlsConfig, err := tlslib.NewConfig(&cfg.TLS)
 		if err != nil {
 			return nil, fmt.Errorf("building TLS config: %w", err)
 		}
-		return &kafka.Transport{TLS: tlsConfig}, nil
+		transport.TLS = tlsConfig
 	}
 
-	return kafka.DefaultTransport, nil
+	mechanism, err := buildSASLMechanism(cfg.SASL)
+	if err != nil {
+		return nil, err
+	}
+	transport.SASL = mechanism
+
+	return transport, nil
+}
+
+func compressionCodec(codec string) (kafka.Compression, error) {
+	switch strings.ToLower(codec) {
+	case "":
+		return 0, nil
+	case CompressionGzip:
+		return kafka.Gzip, nil
+	case CompressionSnappy:
+		return kafka.Snappy, nil
+	case CompressionLz4:
+		return kafka.Lz4, nil
+	case CompressionZstd:
+		return kafka.Zstd, nil
+	default:
+		return 0, fmt.Errorf("%w: %q, must be one of [%s, %s, %s, %s]", errUnsupportedCompression, codec,
+			CompressionGzip, CompressionSnappy, CompressionLz4, CompressionZstd)
+	}
+}
+
+func requiredAcks(cfg *ConnConfig) (kafka.RequiredAcks, error) {
+	switch cfg.requiredAcks() {
+	case RequiredAcksAll:
+		return kafka.RequireAll, nil
+	case RequiredAcksLeader:
+		return kafka.RequireOne, nil
+	case RequiredAcksNone:
+		return kafka.RequireNone, nil
+	default:
+		return 0, fmt.Errorf("%w: %q, must be one of [%s, %s, %s]", errUnsupportedRequiredAcks, cfg.RequiredAcks,
+			RequiredAcksAll, RequiredAcksLeader, RequiredAcksNone)
+	}
 }
@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter_toKafkaMessages(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("oh noes")
+	broadcastMsg := Message{Topic: "schemas", Key: []byte("public"), Value: []byte("schema")}
+	broadcastMsg.WriteToAllPartitions()
+
+	tests := []struct {
+		name           string
+		msgs           []Message
+		partitionCount func(ctx context.Context, topic string) (int, error)
+
+		wantMsgs []kafka.Message
+		wantErr  error
+	}{
+		{
+			name: "ok - default topic",
+			msgs: []Message{
+				{Key: []byte("a"), Value: []byte("1")},
+				{Topic: "other", Key: []byte("b"), Value: []byte("2")},
+			},
+			partitionCount: func(ctx context.Context, topic string) (int, error) {
+				return 0, errors.New("partitionCount: should not be called")
+			},
+
+			wantMsgs: []kafka.Message{
+				{Topic: "default", Key: []byte("a"), Value: []byte("1")},
+				{Topic: "other", Key: []byte("b"), Value: []byte("2")},
+			},
+		},
+		{
+			name: "ok - all partitions",
+			msgs: []Message{
+				{Key: []byte("a"), Value: []byte("1")},
+				broadcastMsg,
+			},
+			partitionCount: func(ctx context.Context, topic string) (int, error) {
+				require.Equal(t, "schemas", topic)
+				return 3, nil
+			},
+
+			wantMsgs: []kafka.Message{
+				{Topic: "default", Key: []byte("a"), Value: []byte("1")},
+				{Topic: "schemas", Key: []byte("public"), Value: []byte("schema"), WriterData: partitionAssignment(0)},
+				{Topic: "schemas", Key: []byte("public"), Value: []byte("schema"), WriterData: partitionAssignment(1)},
+				{Topic: "schemas", Key: []byte("public"), Value: []byte("schema"), WriterData: partitionAssignment(2)},
+			},
+		},
+		{
+			name: "error - retrieving partitions",
+			msgs: []Message{broadcastMsg},
+			partitionCount: func(ctx context.Context, topic string) (int, error) {
+				return 0, errTest
+			},
+
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := &Writer{
+				topic:          "default",
+				partitionCount: tc.partitionCount,
+			}
+			msgs, err := w.toKafkaMessages(context.Background(), tc.msgs)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantMsgs, msgs)
+		})
+	}
+}
+
+func TestPartitionBalancer_Balance(t *testing.T) {
+	t.Parallel()
+
+	b := &partitionBalancer{}
+	partitions := []int{0, 1, 2}
+
+	require.Equal(t, 2, b.Balance(kafka.Message{Key: []byte("a"), WriterData: partitionAssignment(2)}, partitions...))
+	// messages with the same key are routed to the same partition
+	hashed := b.Balance(kafka.Message{Key: []byte("a")}, partitions...)
+	require.Equal(t, hashed, b.Balance(kafka.Message{Key: []byte("a")}, partitions...))
+	// assignments to partitions that no longer exist use the key hash
+	require.Equal(t, hashed, b.Balance(kafka.Message{Key: []byte("a"), WriterData: partitionAssignment(5)}, partitions...))
+}
+
+func TestCompressionCodec(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		codec string
+
+		wantCompression kafka.Compression
+		wantErr         error
+	}{
+		{
+			name:  "ok - no compression",
+			codec: "",
+
+			wantCompression: 0,
+		},
+		{
+			name:  "ok - zstd",
+			codec: "zstd",
+
+			wantCompression: kafka.Zstd,
+		},
+		{
+			name:  "ok - case insensitive",
+			codec: "GZIP",
+
+			wantCompression: kafka.Gzip,
+		},
+		{
+			name:  "error - unsupported codec",
+			codec: "brotli",
+
+			wantErr: errUnsupportedCompression,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			compression, err := compressionCodec(tc.codec)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantCompression, compression)
+		})
+	}
+}
+
+func TestRequiredAcks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		cfg  ConnConfig
+
+		wantAcks kafka.RequiredAcks
+		wantErr  error
+	}{
+		{
+			name: "ok - default",
+			cfg:  ConnConfig{},
+
+			wantAcks: kafka.RequireAll,
+		},
+		{
+			name: "ok - leader",
+			cfg:  ConnConfig{RequiredAcks: "leader"},
+
+			wantAcks: kafka.RequireOne,
+		},
+		{
+			name: "ok - none",
+			cfg:  ConnConfig{RequiredAcks: "none"},
+
+			wantAcks: kafka.RequireNone,
+		},
+		{
+			name: "error - unsupported acks",
+			cfg:  ConnConfig{RequiredAcks: "2"},
+
+			wantErr: errUnsupportedRequiredAcks,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			acks, err := requiredAcks(&tc.cfg)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantAcks, acks)
+		})
+	}
+}
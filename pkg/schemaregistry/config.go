@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package schemaregistry
+
+import (
+	"errors"
+	"time"
+)
+
+type Config struct {
+	// URL of the Confluent compatible schema registry API.
+	URL string
+	// Username and Password are the optional basic authentication credentials
+	// for the schema registry.
+	Username string
+	Password string
+	// Timeout is the max time the client will wait for a response from the
+	// schema registry. Defaults to 10s.
+	Timeout time.Duration
+}
+
+const defaultTimeout = 10 * time.Second
+
+var errMissingURL = errors.New("missing schema registry url")
+
+func (c *Config) IsValid() error {
+	if c.URL == "" {
+		return errMissingURL
+	}
+	return nil
+}
+
+func (c *Config) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return defaultTimeout
+}
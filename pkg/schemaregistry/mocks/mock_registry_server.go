@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package mocks
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/xataio/pgstream/internal/json"
+	"github.com/xataio/pgstream/pkg/schemaregistry"
+)
+
+// RegistryServer is an in memory stand-in for the Confluent compatible schema
+// registry API, supporting the schema registration and retrieval by id.
+type RegistryServer struct {
+	server *httptest.Server
+
+	mutex    sync.Mutex
+	schemas  []schemaregistry.Schema
+	subjects map[string][]int
+	// locked contains the subjects that reject new schema versions
+	locked map[string]struct{}
+}
+
+func NewRegistryServer() *RegistryServer {
+	s := &RegistryServer{
+		subjects: map[string][]int{},
+		locked:   map[string]struct{}{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /subjects/{subject}/versions", s.registerSchema)
+	mux.HandleFunc("GET /schemas/ids/{id}", s.getSchema)
+	s.server = httptest.NewServer(mux)
+	return s
+}
+
+func (s *RegistryServer) URL() string {
+	return s.server.URL
+}
+
+func (s *RegistryServer) Close() {
+	s.server.Close()
+}
+
+// GetSubjectSchemaIDs returns the ids of the schemas registered under the
+// subject on input, in registration order.
+func (s *RegistryServer) GetSubjectSchemaIDs(subject string) []int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]int{}, s.subjects[subject]...)
+}
+
+// LockSubject makes the subject on input reject the registration of new schema
+// versions as incompatible, like the registry does when they break the subject
+// compatibility level.
+func (s *RegistryServer) LockSubject(subject string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.locked[subject] = struct{}{}
+}
+
+func (s *RegistryServer) registerSchema(w http.ResponseWriter, r *http.Request) {
+	schema := schemaregistry.Schema{}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &schema)
+	}
+	if err != nil || schema.Schema == "" {
+		writeError(w, http.StatusUnprocessableEntity, 42201, "invalid schema")
+		return
+	}
+	if schema.SchemaType == schemaregistry.SchemaTypeAvro {
+		schema.SchemaType = ""
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// schema ids are global, the same schema gets the same id for all subjects
+	id := 0
+	for i, existing := range s.schemas {
+		if existing == schema {
+			id = i + 1
+			break
+		}
+	}
+
+	subject := r.PathValue("subject")
+	if _, locked := s.locked[subject]; locked && (id == 0 || !slices.Contains(s.subjects[subject], id)) {
+		writeError(w, http.StatusConflict, 409, "Schema being registered is incompatible with an earlier schema")
+		return
+	}
+
+	if id == 0 {
+		s.schemas = append(s.schemas, schema)
+		id = len(s.schemas)
+	}
+	if !slices.Contains(s.subjects[subject], id) {
+		s.subjects[subject] = append(s.subjects[subject], id)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"id": id})
+}
+
+func (s *RegistryServer) getSchema(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err != nil || id < 1 || id > len(s.schemas) {
+		writeError(w, http.StatusNotFound, 40403, "Schema not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.schemas[id-1])
+}
+
+func writeError(w http.ResponseWriter, status, code int, msg string) {
+	writeJSON(w, status, map[string]any{"error_code": code, "message": msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	w.WriteHeader(status)
+	w.Write(b) //nolint:errcheck
+}
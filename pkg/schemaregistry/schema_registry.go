@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	httplib "github.com/xataio/pgstream/internal/http"
+	"github.com/xataio/pgstream/internal/json"
+)
+
+// Client is a client for the Confluent compatible schema registry API. The
+// registered schema ids and the retrieved schemas are cached, since they're
+// immutable.
+type Client struct {
+	client   httplib.Client
+	url      string
+	username string
+	password string
+
+	mutex       sync.RWMutex
+	registered  map[string]int
+	schemasByID map[int]*Schema
+}
+
+type SchemaType string
+
+const (
+	SchemaTypeAvro     SchemaType = "AVRO"
+	SchemaTypeProtobuf SchemaType = "PROTOBUF"
+	SchemaTypeJSON     SchemaType = "JSON"
+)
+
+type Schema struct {
+	Schema string `json:"schema"`
+	// SchemaType is omitted by the registry for avro schemas.
+	SchemaType SchemaType `json:"schemaType,omitempty"`
+}
+
+// Error is the error returned by the schema registry API.
+type Error struct {
+	StatusCode int    `json:"-"`
+	Code       int    `json:"error_code"`
+	Message    string `json:"message"`
+}
+
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+var (
+	ErrSchemaNotFound     = errors.New("schema not found")
+	ErrIncompatibleSchema = errors.New("incompatible schema")
+)
+
+func NewClient(cfg *Config) (*Client, error) {
+	if err := cfg.IsValid(); err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		client: &http.Client{
+			Timeout: cfg.timeout(),
+		},
+		url:         strings.TrimSuffix(cfg.URL, "/"),
+		username:    cfg.Username,
+		password:    cfg.Password,
+		registered:  map[string]int{},
+		schemasByID: map[int]*Schema{},
+	}, nil
+}
+
+// RegisterSchema registers the schema on input under the subject, and returns
+// its id. Registering a schema that already exists returns the existing id.
+func (c *Client) RegisterSchema(ctx context.Context, subject string, schema *Schema) (int, error) {
+	key := subject + "\x00" + schema.Schema
+	c.mutex.RLock()
+	id, found := c.registered[key]
+	c.mutex.RUnlock()
+	if found {
+		return id, nil
+	}
+
+	reqBody, err := json.Marshal(schema)
+	if err != nil {
+		return -1, fmt.Errorf("marshaling schema: %w", err)
+	}
+
+	resp := struct {
+		ID int `json:"id"`
+	}{}
+	path := fmt.Sprintf("/subjects/%s/versions", url.PathEscape(subject))
+	if err := c.do(ctx, http.MethodPost, path, reqBody, &resp); err != nil {
+		return -1, fmt.Errorf("registering schema for subject %s: %w", subject, err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.registered[key] = resp.ID
+	c.schemasByID[resp.ID] = schema
+	return resp.ID, nil
+}
+
+// GetSchemaByID returns the schema registered with the id on input.
+func (c *Client) GetSchemaByID(ctx context.Context, id int) (*Schema, error) {
+	c.mutex.RLock()
+	schema, found := c.schemasByID[id]
+	c.mutex.RUnlock()
+	if found {
+		return schema, nil
+	}
+
+	schema = &Schema{}
+	if err := c.do(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil, schema); err != nil {
+		return nil, fmt.Errorf("retrieving schema %d: %w", id, err)
+	}
+	if schema.SchemaType == "" {
+		schema.SchemaType = SchemaTypeAvro
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.schemasByID[id] = schema
+	return schema, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte, result any) error {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", contentType)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newError(resp.StatusCode, respBody)
+	}
+
+	return json.Unmarshal(respBody, result)
+}
+
+func newError(statusCode int, body []byte) error {
+	apiErr := &Error{}
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	apiErr.StatusCode = statusCode
+	return apiErr
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("schema registry error (status %d, code %d): %s", e.StatusCode, e.Code, e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrSchemaNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrIncompatibleSchema:
+		return e.StatusCode == http.StatusConflict
+	default:
+		return false
+	}
+}
@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package schemaregistry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_RegisterSchema(t *testing.T) {
+	t.Parallel()
+
+	testSchema := &Schema{Schema: `{"type":"string"}`, SchemaType: SchemaTypeAvro}
+
+	tests := []struct {
+		name    string
+		handler func(*testing.T) http.HandlerFunc
+
+		wantID  int
+		wantErr error
+	}{
+		{
+			name: "ok",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					require.Equal(t, http.MethodPost, r.Method)
+					require.Equal(t, "/subjects/pgstream.public.users/versions", r.URL.Path)
+					require.Equal(t, contentType, r.Header.Get("Content-Type"))
+					username, password, ok := r.BasicAuth()
+					require.True(t, ok)
+					require.Equal(t, "user", username)
+					require.Equal(t, "pass", password)
+					body, err := io.ReadAll(r.Body)
+					require.NoError(t, err)
+					require.JSONEq(t, `{"schema":"{\"type\":\"string\"}","schemaType":"AVRO"}`, string(body))
+					w.Write([]byte(`{"id":7}`)) //nolint:errcheck
+				}
+			},
+
+			wantID: 7,
+		},
+		{
+			name: "error - incompatible schema",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusConflict)
+					w.Write([]byte(`{"error_code":409,"message":"Schema being registered is incompatible"}`)) //nolint:errcheck
+				}
+			},
+
+			wantID:  -1,
+			wantErr: ErrIncompatibleSchema,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			calls := atomic.Int32{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				tc.handler(t)(w, r)
+			}))
+			defer server.Close()
+
+			client, err := NewClient(&Config{URL: server.URL + "/", Username: "user", Password: "pass"})
+			require.NoError(t, err)
+
+			id, err := client.RegisterSchema(context.Background(), "pgstream.public.users", testSchema)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantID, id)
+			if tc.wantErr != nil {
+				return
+			}
+
+			// the registered schemas are cached
+			id, err = client.RegisterSchema(context.Background(), "pgstream.public.users", testSchema)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantID, id)
+			schema, err := client.GetSchemaByID(context.Background(), id)
+			require.NoError(t, err)
+			require.Equal(t, testSchema, schema)
+			require.Equal(t, int32(1), calls.Load())
+		})
+	}
+}
+
+func TestClient_GetSchemaByID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		status int
+		body   string
+
+		wantSchema *Schema
+		wantErr    error
+	}{
+		{
+			name:   "ok - avro schema type omitted",
+			status: http.StatusOK,
+			body:   `{"schema":"{\"type\":\"string\"}"}`,
+
+			wantSchema: &Schema{Schema: `{"type":"string"}`, SchemaType: SchemaTypeAvro},
+		},
+		{
+			name:   "ok - protobuf",
+			status: http.StatusOK,
+			body:   `{"schema":"syntax = \"proto3\";","schemaType":"PROTOBUF"}`,
+
+			wantSchema: &Schema{Schema: `syntax = "proto3";`, SchemaType: SchemaTypeProtobuf},
+		},
+		{
+			name:   "error - not found",
+			status: http.StatusNotFound,
+			body:   `{"error_code":40403,"message":"Schema not found"}`,
+
+			wantErr: ErrSchemaNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodGet, r.Method)
+				require.Equal(t, "/schemas/ids/3", r.URL.Path)
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body)) //nolint:errcheck
+			}))
+			defer server.Close()
+
+			client, err := NewClient(&Config{URL: server.URL})
+			require.NoError(t, err)
+
+			schema, err := client.GetSchemaByID(context.Background(), 3)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantSchema, schema)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package schemaregistry
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// The schema registry wire format prefixes the serialised payload with a magic
+// byte and the 4 byte big endian id of the schema it was serialised with.
+const (
+	magicByte        = byte(0)
+	wireFormatHeader = 5
+)
+
+var ErrInvalidWireFormat = errors.New("message is not in the schema registry wire format")
+
+// EncodeWireFormat frames the payload on input with the schema id.
+func EncodeWireFormat(schemaID int, payload []byte) []byte {
+	msg := make([]byte, wireFormatHeader, wireFormatHeader+len(payload))
+	msg[0] = magicByte
+	binary.BigEndian.PutUint32(msg[1:wireFormatHeader], uint32(schemaID))
+	return append(msg, payload...)
+}
+
+// DecodeWireFormat returns the schema id and the payload of the framed message
+// on input.
+func DecodeWireFormat(msg []byte) (int, []byte, error) {
+	if !IsWireFormat(msg) {
+		return 0, nil, ErrInvalidWireFormat
+	}
+	return int(binary.BigEndian.Uint32(msg[1:wireFormatHeader])), msg[wireFormatHeader:], nil
+}
+
+// IsWireFormat returns true if the message on input is framed with a schema
+// id.
+func IsWireFormat(msg []byte) bool {
+	return len(msg) >= wireFormatHeader && msg[0] == magicByte
+}
@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package schemaregistry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWireFormat(t *testing.T) {
+	t.Parallel()
+
+	msg := EncodeWireFormat(258, []byte("payload"))
+	require.Equal(t, []byte{0, 0, 0, 1, 2, 'p', 'a', 'y', 'l', 'o', 'a', 'd'}, msg)
+	require.True(t, IsWireFormat(msg))
+
+	id, payload, err := DecodeWireFormat(msg)
+	require.NoError(t, err)
+	require.Equal(t, 258, id)
+	require.Equal(t, []byte("payload"), payload)
+
+	for _, invalid := range [][]byte{nil, {0, 0, 1}, []byte(`{"action":"I"}`)} {
+		require.False(t, IsWireFormat(invalid))
+		_, _, err := DecodeWireFormat(invalid)
+		require.ErrorIs(t, err, ErrInvalidWireFormat)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"sync"
 
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	pglib "github.com/xataio/pgstream/internal/postgres"
 	pglibinstrumentation "github.com/xataio/pgstream/internal/postgres/instrumentation"
 	"github.com/xataio/pgstream/internal/progress"
@@ -36,12 +37,18 @@ type SnapshotGenerator struct {
 	// Function called for processing produced rows.
 	rowsProcessor          snapshot.RowsProcessor
 	tableSnapshotGenerator snapshotTableFn
+	// optional filter to skip the rows that don't need to be processed
+	rowFilter RowFilter
 
 	progressTracking   bool
 	progressBars       *synclib.StringMap[progress.Bar]
 	progressBarBuilder func(totalBytes int64, description string) progress.Bar
 }
 
+// RowFilter returns true if the row values on input for the given table should
+// be processed, false otherwise.
+type RowFilter func(schema, table string, values map[string]any) (bool, error)
+
 type mapper interface {
 	TypeForOID(context.Context, uint32) (string, error)
 }
@@ -121,6 +128,13 @@ func WithInstrumentation(i *otel.Instrumentation) Option {
 	}
 }
 
+// WithRowFilter will skip the rows that don't match the row filter on input.
+func WithRowFilter(rowFilter RowFilter) Option {
+	return func(sg *SnapshotGenerator) {
+		sg.rowFilter = rowFilter
+	}
+}
+
 func WithProgressTracking() Option {
 	return func(sg *SnapshotGenerator) {
 		sg.progressTracking = true
@@ -340,6 +354,12 @@ func (sg *SnapshotGenerator) snapshotTableRange(ctx context.Context, snapshotID
 		defer rows.Close()
 
 		fieldDescriptions := rows.FieldDescriptions()
+		// the type map is used to convert the row values for the row filter,
+		// and it's not safe for concurrent use across table workers
+		var typeMap *pgtype.Map
+		if sg.rowFilter != nil {
+			typeMap = pgtype.NewMap()
+		}
 		rowCount := uint(0)
 		for rows.Next() {
 			rowCount++
@@ -357,6 +377,16 @@ func (sg *SnapshotGenerator) snapshotTableRange(ctx context.Context, snapshotID
 					continue
 				}
 
+				if sg.rowFilter != nil {
+					matches, err := sg.rowFilter(table.schema, table.name, rowValues(typeMap, fieldDescriptions, values))
+					if err != nil {
+						return fmt.Errorf("filtering snapshot row: %w", err)
+					}
+					if !matches {
+						continue
+					}
+				}
+
 				if err := sg.rowsProcessor.ProcessRow(ctx, &snapshot.Row{
 					Schema:  table.schema,
 					Table:   table.name,
@@ -401,6 +431,17 @@ func (sg *SnapshotGenerator) toSnapshotColumns(ctx context.Context, fieldDescrip
 	return columns
 }
 
+// rowValues returns the row values keyed by column name, converted to the same
+// representation as the replicated events, so that the row filter expressions
+// are evaluated consistently for both.
+func rowValues(typeMap *pgtype.Map, fieldDescriptions []pgconn.FieldDescription, values []any) map[string]any {
+	rowValues := make(map[string]any, len(values))
+	for i, value := range values {
+		rowValues[fieldDescriptions[i].Name] = pglib.ToWalValue(typeMap, fieldDescriptions[i].DataTypeOID, value)
+	}
+	return rowValues
+}
+
 func (sg *SnapshotGenerator) addProgressBar(ctx context.Context, snapshotID string, schemaTables *schemaTables) error {
 	totalBytes, err := sg.getSnapshotSchemaTotalBytes(ctx, snapshotID, schemaTables.schema, schemaTables.tables)
 	if err != nil {
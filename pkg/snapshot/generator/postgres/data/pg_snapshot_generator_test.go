@@ -106,6 +106,72 @@ func TestSnapshotGenerator_CreateSnapshot(t *testing.T) {
 		}
 	}
 
+	// querier returning a single row, used to test the row filter
+	rowFilterQuerier := func() *pgmocks.Querier {
+		return &pgmocks.Querier{
+			ExecInTxWithOptionsFn: func(_ context.Context, i uint, f func(tx pglib.Tx) error, to pglib.TxOptions) error {
+				require.Equal(t, txOptions, to)
+				switch i {
+				case 1:
+					mockTx := pgmocks.Tx{
+						QueryRowFn: func(ctx context.Context, query string, args ...any) pglib.Row {
+							require.Equal(t, exportSnapshotQuery, query)
+							return &pgmocks.Row{
+								ScanFn: func(args ...any) error {
+									require.Len(t, args, 1)
+									snapshotID, ok := args[0].(*string)
+									require.True(t, ok, fmt.Sprintf("snapshotID, expected *string, got %T", args[0]))
+									*snapshotID = testSnapshotID
+									return nil
+								},
+							}
+						},
+					}
+					return f(&mockTx)
+				case 2:
+					mockTx := pgmocks.Tx{
+						ExecFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.CommandTag, error) {
+							require.Equal(t, fmt.Sprintf("SET TRANSACTION SNAPSHOT '%s'", testSnapshotID), query)
+							require.Len(t, args, 0)
+							return pglib.CommandTag{}, nil
+						},
+						QueryRowFn: validTableInfoQueryRowFn,
+					}
+					return f(&mockTx)
+				case 3:
+					mockTx := pgmocks.Tx{
+						ExecFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.CommandTag, error) {
+							require.Equal(t, fmt.Sprintf("SET TRANSACTION SNAPSHOT '%s'", testSnapshotID), query)
+							require.Len(t, args, 0)
+							return pglib.CommandTag{}, nil
+						},
+						QueryFn: func(ctx context.Context, query string, args ...any) (pglib.Rows, error) {
+							require.Equal(t, fmt.Sprintf(pageRangeQuery, quotedSchemaTable1, 0, 1), query)
+							require.Len(t, args, 0)
+							return &pgmocks.Rows{
+								CloseFn: func() {},
+								NextFn:  func(i uint) bool { return i == 1 },
+								FieldDescriptionsFn: func() []pgconn.FieldDescription {
+									return []pgconn.FieldDescription{
+										{Name: "id", DataTypeOID: pgtype.UUIDOID},
+										{Name: "name", DataTypeOID: pgtype.TextOID},
+									}
+								},
+								ValuesFn: func() ([]any, error) {
+									return []any{testUUID, "alice"}, nil
+								},
+								ErrFn: func() error { return nil },
+							}, nil
+						},
+					}
+					return f(&mockTx)
+				default:
+					return fmt.Errorf("unexpected call to ExecInTxWithOptions: %d", i)
+				}
+			},
+		}
+	}
+
 	errTest := errors.New("oh noes")
 
 	tests := []struct {
@@ -114,6 +180,7 @@ func TestSnapshotGenerator_CreateSnapshot(t *testing.T) {
 		snapshot      *snapshot.Snapshot
 		schemaWorkers uint
 		progressBar   *progressmocks.Bar
+		rowFilter     RowFilter
 
 		wantRows []*snapshot.Row
 		wantErr  error
@@ -186,6 +253,36 @@ func TestSnapshotGenerator_CreateSnapshot(t *testing.T) {
 			wantErr:  nil,
 			wantRows: []*snapshot.Row{testRow(testTable1, testColumns)},
 		},
+		{
+			name:    "ok - with row filter",
+			querier: rowFilterQuerier(),
+			rowFilter: func(schema, table string, values map[string]any) (bool, error) {
+				require.Equal(t, testSchema, schema)
+				require.Equal(t, testTable1, table)
+				require.Equal(t, map[string]any{"id": testUUID, "name": "alice"}, values)
+				return false, nil
+			},
+
+			wantErr:  nil,
+			wantRows: []*snapshot.Row{},
+		},
+		{
+			name:    "error - filtering row",
+			querier: rowFilterQuerier(),
+			rowFilter: func(schema, table string, values map[string]any) (bool, error) {
+				return false, errTest
+			},
+
+			wantErr: snapshot.Errors{
+				testSchema: &snapshot.SchemaErrors{
+					Schema: testSchema,
+					TableErrors: map[string]string{
+						testTable1: fmt.Sprintf("filtering snapshot row: %v", errTest),
+					},
+				},
+			},
+			wantRows: []*snapshot.Row{},
+		},
 		{
 			name: "ok - with missed pages",
 			querier: &pgmocks.Querier{
@@ -1144,6 +1241,7 @@ func TestSnapshotGenerator_CreateSnapshot(t *testing.T) {
 				progressBarBuilder: func(totalBytes int64, description string) progress.Bar {
 					return tc.progressBar
 				},
+				rowFilter: tc.rowFilter,
 			}
 			sg.tableSnapshotGenerator = sg.snapshotTable
 
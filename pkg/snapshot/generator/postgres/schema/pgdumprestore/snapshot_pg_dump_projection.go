@@ -0,0 +1,207 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package pgdumprestore
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ColumnProjection reports whether the columns of a table are kept by the
+// column projection.
+type ColumnProjection interface {
+	KeepColumn(schema, table, column string) bool
+	ProjectsTable(schema, table string) bool
+}
+
+// dumpProjection removes the projected columns from the table definitions of
+// a plain text dump, along with the statements that depend on them (column
+// defaults, comments, sequence ownership, indices and constraints), so that the
+// restored tables are consistent with the projected events. The primary key
+// columns are always kept, like they are in the projected events. Other objects
+// referencing the projected columns (i.e. views, functions or triggers) are
+// not rewritten.
+type dumpProjection struct {
+	projection ColumnProjection
+	// primaryKeys contains the primary key columns of the dumped tables,
+	// indexed by schema and table
+	primaryKeys map[string]map[string][]string
+}
+
+const qualifiedIdentifierPattern = `(` + identifierPattern + `)\.(` + identifierPattern + `)`
+
+var (
+	createTableRegex = regexp.MustCompile(`(?ms)^CREATE (?:UNLOGGED )?TABLE ` + qualifiedIdentifierPattern + ` \(\n(.*?)\n\)`)
+	// statements that can reference the table columns. They're matched up to
+	// the first line ending with a semicolon, since they can span more than
+	// one line, along with the blank line that follows them.
+	columnStatementRegex = regexp.MustCompile(`(?ms)^(?:ALTER TABLE|CREATE (?:UNIQUE )?INDEX|COMMENT ON COLUMN|ALTER SEQUENCE) .*?;(?:\n|\z)\n?`)
+	alterTableRegex      = regexp.MustCompile(`^ALTER TABLE (?:IF EXISTS )?(?:ONLY )?` + qualifiedIdentifierPattern)
+	alterColumnRegex     = regexp.MustCompile(`\bALTER COLUMN (` + identifierPattern + `)`)
+	referencesRegex      = regexp.MustCompile(`\bREFERENCES ` + qualifiedIdentifierPattern + `\s*(\(.*?\))`)
+	createIndexRegex     = regexp.MustCompile(`(?s)^CREATE (?:UNIQUE )?INDEX .*? ON (?:ONLY )?` + qualifiedIdentifierPattern + `(.*)`)
+	columnCommentRegex   = regexp.MustCompile(`^COMMENT ON COLUMN ` + qualifiedIdentifierPattern + `\.(` + identifierPattern + `) IS`)
+	sequenceOwnerRegex   = regexp.MustCompile(`\bOWNED BY ` + qualifiedIdentifierPattern + `\.(` + identifierPattern + `);`)
+	identifierRegex      = regexp.MustCompile(identifierPattern)
+	primaryKeyRegex      = regexp.MustCompile(`(?m)^ALTER TABLE (?:ONLY )?` + qualifiedIdentifierPattern + `\s+ADD CONSTRAINT (?:` + identifierPattern + `) PRIMARY KEY (\(.*?\))`)
+)
+
+func newDumpProjection(projection ColumnProjection) *dumpProjection {
+	return &dumpProjection{
+		projection: projection,
+	}
+}
+
+func (p *dumpProjection) project(d []byte) []byte {
+	if len(d) == 0 {
+		return d
+	}
+
+	p.primaryKeys = map[string]map[string][]string{}
+	for _, submatches := range primaryKeyRegex.FindAllSubmatch(d, -1) {
+		schema := unquoteIdentifier(string(submatches[1]))
+		table := unquoteIdentifier(string(submatches[2]))
+		if _, found := p.primaryKeys[schema]; !found {
+			p.primaryKeys[schema] = map[string][]string{}
+		}
+		p.primaryKeys[schema][table] = parenthesisedIdentifiers(string(submatches[3]))
+	}
+
+	d = createTableRegex.ReplaceAllFunc(d, func(match []byte) []byte {
+		submatches := createTableRegex.FindSubmatch(match)
+		schema := unquoteIdentifier(string(submatches[1]))
+		table := unquoteIdentifier(string(submatches[2]))
+		if !p.projection.ProjectsTable(schema, table) {
+			return match
+		}
+		// the match ends with the table definition body and the closing
+		// parenthesis line
+		header := match[:len(match)-len(submatches[3])-len("\n)")]
+		return []byte(string(header) + p.projectTableDefinition(schema, table, string(submatches[3])) + "\n)")
+	})
+
+	return columnStatementRegex.ReplaceAllFunc(d, func(match []byte) []byte {
+		if p.referencesProjectedColumn(string(match)) {
+			return nil
+		}
+		return match
+	})
+}
+
+// projectTableDefinition removes the projected column definitions and the
+// table constraints referencing them from the body of a create table
+// statement.
+func (p *dumpProjection) projectTableDefinition(schema, table, body string) string {
+	lines := strings.Split(body, "\n")
+	projected := make([]string, 0, len(lines))
+	for _, line := range lines {
+		definition := strings.TrimSpace(line)
+		var keep bool
+		if strings.HasPrefix(definition, "CONSTRAINT ") {
+			keep = p.keepColumns(schema, table, parenthesisedIdentifiers(definition))
+		} else {
+			keep = p.keepColumn(schema, table, unquoteIdentifier(identifierRegex.FindString(definition)))
+		}
+		if keep {
+			projected = append(projected, strings.TrimSuffix(line, ","))
+		}
+	}
+	return strings.Join(projected, ",\n")
+}
+
+// referencesProjectedColumn returns true if the statement on input references
+// any of the projected columns.
+func (p *dumpProjection) referencesProjectedColumn(statement string) bool {
+	switch {
+	case strings.HasPrefix(statement, "ALTER TABLE "):
+		submatches := alterTableRegex.FindStringSubmatch(statement)
+		if submatches == nil {
+			return false
+		}
+		schema, table := unquoteIdentifier(submatches[1]), unquoteIdentifier(submatches[2])
+		if column := alterColumnRegex.FindStringSubmatch(statement); column != nil {
+			return !p.keepColumn(schema, table, unquoteIdentifier(column[1]))
+		}
+		if !strings.Contains(statement, "ADD CONSTRAINT") {
+			return false
+		}
+		constraint := statement
+		if references := referencesRegex.FindStringSubmatchIndex(statement); references != nil {
+			constraint = statement[:references[0]]
+			referencedSchema := unquoteIdentifier(statement[references[2]:references[3]])
+			referencedTable := unquoteIdentifier(statement[references[4]:references[5]])
+			if !p.keepColumns(referencedSchema, referencedTable, parenthesisedIdentifiers(statement[references[6]:references[7]])) {
+				return true
+			}
+		}
+		return !p.keepColumns(schema, table, parenthesisedIdentifiers(constraint))
+	case strings.HasPrefix(statement, "CREATE "):
+		submatches := createIndexRegex.FindStringSubmatch(statement)
+		if submatches == nil {
+			return false
+		}
+		return !p.keepColumns(unquoteIdentifier(submatches[1]), unquoteIdentifier(submatches[2]), parenthesisedIdentifiers(submatches[3]))
+	case strings.HasPrefix(statement, "COMMENT "):
+		submatches := columnCommentRegex.FindStringSubmatch(statement)
+		if submatches == nil {
+			return false
+		}
+		return !p.keepColumn(unquoteIdentifier(submatches[1]), unquoteIdentifier(submatches[2]), unquoteIdentifier(submatches[3]))
+	default:
+		submatches := sequenceOwnerRegex.FindStringSubmatch(statement)
+		if submatches == nil {
+			return false
+		}
+		return !p.keepColumn(unquoteIdentifier(submatches[1]), unquoteIdentifier(submatches[2]), unquoteIdentifier(submatches[3]))
+	}
+}
+
+// keepColumn returns true if the column on input is kept by the projection or
+// is part of the table primary key.
+func (p *dumpProjection) keepColumn(schema, table, column string) bool {
+	return p.projection.KeepColumn(schema, table, column) || slices.Contains(p.primaryKeys[schema][table], column)
+}
+
+func (p *dumpProjection) keepColumns(schema, table string, columns []string) bool {
+	for _, column := range columns {
+		if !p.keepColumn(schema, table, column) {
+			return false
+		}
+	}
+	return true
+}
+
+// parenthesisedIdentifiers returns the unquoted identifiers found between
+// parentheses in the definition on input, ignoring the string literals. They
+// include the referenced columns, as well as other identifiers such as
+// function or type names.
+func parenthesisedIdentifiers(definition string) []string {
+	var b strings.Builder
+	depth := 0
+	inLiteral := false
+	for _, r := range definition {
+		switch {
+		case r == '\'':
+			inLiteral = !inLiteral
+			r = ' '
+		case inLiteral:
+			r = ' '
+		case r == '(':
+			depth++
+			r = ' '
+		case r == ')':
+			depth--
+			r = ' '
+		case depth == 0:
+			r = ' '
+		}
+		b.WriteRune(r)
+	}
+
+	identifiers := identifierRegex.FindAllString(b.String(), -1)
+	for i, identifier := range identifiers {
+		identifiers[i] = unquoteIdentifier(identifier)
+	}
+	return identifiers
+}
@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package pgdumprestore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/wal/processor/projection"
+)
+
+func TestDumpProjection_project(t *testing.T) {
+	t.Parallel()
+
+	columnProjection, err := projection.NewColumnProjection(&projection.Config{
+		Tables: []projection.TableConfig{
+			{Table: "public.users", ExcludeColumns: []string{"id", "Email"}},
+			{Table: "sales.orders", IncludeColumns: []string{"id", "user_id", "user_email"}},
+		},
+	})
+	require.NoError(t, err)
+
+	dump := []byte(`CREATE TABLE public.users (
+    id integer NOT NULL,
+    name text,
+    "Email" text NOT NULL,
+    CONSTRAINT email_check CHECK (("Email" <> ''::text))
+);
+
+CREATE TABLE public.teams (
+    id integer NOT NULL,
+    name text
+);
+
+CREATE TABLE sales.orders (
+    id integer NOT NULL,
+    user_id integer,
+    user_email text,
+    notes text
+);
+
+ALTER SEQUENCE public.users_id_seq OWNED BY public.users.id;
+
+ALTER TABLE ONLY public.users ALTER COLUMN id SET DEFAULT nextval('public.users_id_seq'::regclass);
+
+ALTER TABLE ONLY public.teams ALTER COLUMN id SET DEFAULT nextval('public.teams_id_seq'::regclass);
+
+COMMENT ON COLUMN public.users."Email" IS 'email; address';
+
+COMMENT ON COLUMN public.users.name IS 'name';
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_name_key UNIQUE (name);
+
+ALTER TABLE ONLY sales.orders
+    ADD CONSTRAINT orders_pkey PRIMARY KEY (id);
+
+CREATE INDEX users_email_idx ON public.users USING btree (lower("Email"));
+
+CREATE UNIQUE INDEX orders_notes_idx ON sales.orders USING btree (notes) WHERE (user_id IS NOT NULL);
+
+CREATE INDEX orders_user_id_idx ON sales.orders USING btree (user_id);
+
+ALTER TABLE ONLY sales.orders
+    ADD CONSTRAINT orders_user_fk FOREIGN KEY (user_id) REFERENCES public.users(id);
+
+ALTER TABLE ONLY sales.orders
+    ADD CONSTRAINT orders_user_email_fk FOREIGN KEY (user_email) REFERENCES public.users("Email");
+
+ALTER TABLE ONLY sales.orders
+    ADD CONSTRAINT orders_team_fk FOREIGN KEY (user_id) REFERENCES public.teams(id);
+`)
+
+	// the primary key columns are kept, even if they're excluded
+	wantDump := `CREATE TABLE public.users (
+    id integer NOT NULL,
+    name text
+);
+
+CREATE TABLE public.teams (
+    id integer NOT NULL,
+    name text
+);
+
+CREATE TABLE sales.orders (
+    id integer NOT NULL,
+    user_id integer,
+    user_email text
+);
+
+ALTER SEQUENCE public.users_id_seq OWNED BY public.users.id;
+
+ALTER TABLE ONLY public.users ALTER COLUMN id SET DEFAULT nextval('public.users_id_seq'::regclass);
+
+ALTER TABLE ONLY public.teams ALTER COLUMN id SET DEFAULT nextval('public.teams_id_seq'::regclass);
+
+COMMENT ON COLUMN public.users.name IS 'name';
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_name_key UNIQUE (name);
+
+ALTER TABLE ONLY sales.orders
+    ADD CONSTRAINT orders_pkey PRIMARY KEY (id);
+
+CREATE INDEX orders_user_id_idx ON sales.orders USING btree (user_id);
+
+ALTER TABLE ONLY sales.orders
+    ADD CONSTRAINT orders_user_fk FOREIGN KEY (user_id) REFERENCES public.users(id);
+
+ALTER TABLE ONLY sales.orders
+    ADD CONSTRAINT orders_team_fk FOREIGN KEY (user_id) REFERENCES public.teams(id);
+`
+
+	p := newDumpProjection(columnProjection)
+	require.Equal(t, wantDump, string(p.project(dump)))
+}
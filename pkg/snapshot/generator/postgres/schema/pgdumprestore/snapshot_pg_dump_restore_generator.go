@@ -39,6 +39,8 @@ type SnapshotGenerator struct {
 	role                   string
 	logger                 loglib.Logger
 	generator              generator.SnapshotGenerator
+	tableRouter            TableRouter
+	columnProjection       ColumnProjection
 	dumpDebugFile          string // if set, the dump will be written to this file for debugging purposes
 }
 
@@ -112,6 +114,22 @@ func WithSnapshotGenerator(g generator.SnapshotGenerator) Option {
 	}
 }
 
+// WithTableRouter routes the dumped schemas and tables to their target names
+// before restoring them.
+func WithTableRouter(r TableRouter) Option {
+	return func(sg *SnapshotGenerator) {
+		sg.tableRouter = r
+	}
+}
+
+// WithColumnProjection removes the projected columns from the dumped tables
+// before restoring them.
+func WithColumnProjection(p ColumnProjection) Option {
+	return func(sg *SnapshotGenerator) {
+		sg.columnProjection = p
+	}
+}
+
 func WithInstrumentation(i *otel.Instrumentation) Option {
 	return func(sg *SnapshotGenerator) {
 		var err error
@@ -158,17 +176,37 @@ func (s *SnapshotGenerator) CreateSnapshot(ctx context.Context, ss *snapshot.Sna
 		return err
 	}
 
+	// the projection is applied before the routing, since it's configured
+	// with the source table names
+	if s.columnProjection != nil {
+		projection := newDumpProjection(s.columnProjection)
+		dump.full = projection.project(dump.full)
+		dump.filtered = projection.project(dump.filtered)
+		dump.indicesAndConstraints = projection.project(dump.indicesAndConstraints)
+	}
+
+	// the schemas that need to be created explicitly before the restore
+	targetSchemas := schemasToCreate(dumpSchemas)
+	if s.tableRouter != nil {
+		router := newDumpRouter(s.tableRouter, dumpSchemas, dump.full)
+		dump.full = router.route(dump.full)
+		dump.filtered = router.route(dump.filtered)
+		dump.indicesAndConstraints = router.route(dump.indicesAndConstraints)
+		sequenceDump = router.route(sequenceDump)
+		targetSchemas = router.targetSchemas
+	}
+
 	// if there's no further snapshotting happening, we can apply the full dump,
 	// no need to apply the constraints/indices separately.
 	if s.generator == nil {
-		return s.restoreDump(ctx, dumpSchemas, append(dump.full, sequenceDump...))
+		return s.restoreDump(ctx, dumpSchemas, targetSchemas, append(dump.full, sequenceDump...))
 	}
 
 	// otherwise, we need to apply the filtered schema dump first, then call the
 	// wrapped snapshot generator, and apply the indices and constraints last.
 	// This will make the data snapshot faster, since there will be no
 	// constraints to be updated/checked on each insert.
-	if err := s.restoreDump(ctx, dumpSchemas, dump.filtered); err != nil {
+	if err := s.restoreDump(ctx, dumpSchemas, targetSchemas, dump.filtered); err != nil {
 		return err
 	}
 
@@ -178,7 +216,7 @@ func (s *SnapshotGenerator) CreateSnapshot(ctx context.Context, ss *snapshot.Sna
 
 	s.logger.Info("restoring schema indices and constraints", loglib.Fields{"schemaTables": ss.SchemaTables})
 	// apply the indices and constraints when the wrapped generator has finished
-	return s.restoreDump(ctx, dumpSchemas, append(dump.indicesAndConstraints, sequenceDump...))
+	return s.restoreDump(ctx, dumpSchemas, targetSchemas, append(dump.indicesAndConstraints, sequenceDump...))
 }
 
 func (s *SnapshotGenerator) Close() error {
@@ -232,15 +270,10 @@ func (s *SnapshotGenerator) dumpSequenceValues(ctx context.Context, sequences []
 	return d, nil
 }
 
-func (s *SnapshotGenerator) restoreDump(ctx context.Context, schemaTables map[string][]string, dump []byte) error {
-	// if we use table filtering in the pg_dump command, the schema creation
-	// will not be dumped, so it needs to be created explicitly (except for
-	// public schema)
-	for schema, tables := range schemaTables {
-		if len(tables) > 0 && schema != publicSchema && schema != wildcard {
-			if err := s.createSchemaIfNotExists(ctx, schema); err != nil {
-				return err
-			}
+func (s *SnapshotGenerator) restoreDump(ctx context.Context, schemaTables map[string][]string, targetSchemas []string, dump []byte) error {
+	for _, schema := range targetSchemas {
+		if err := s.createSchemaIfNotExists(ctx, schema); err != nil {
+			return err
 		}
 	}
 
@@ -570,6 +603,19 @@ func (s *SnapshotGenerator) sequenceDumpFile() string {
 	return baseName + "-sequences" + fileExtension
 }
 
+// schemasToCreate returns the schemas that need to be created explicitly
+// before the restore. If we use table filtering in the pg_dump command, the
+// schema creation will not be dumped (except for public schema).
+func schemasToCreate(schemaTables map[string][]string) []string {
+	schemas := []string{}
+	for schema, tables := range schemaTables {
+		if len(tables) > 0 && schema != publicSchema && schema != wildcard {
+			schemas = append(schemas, schema)
+		}
+	}
+	return schemas
+}
+
 func hasWildcardTable(tables []string) bool {
 	return slices.Contains(tables, wildcard)
 }
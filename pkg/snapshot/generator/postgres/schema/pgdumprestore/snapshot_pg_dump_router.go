@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package pgdumprestore
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+)
+
+// TableRouter maps source schema and table names to their target names.
+type TableRouter interface {
+	Route(schema, table string) (string, string)
+	RouteSchema(schema string) string
+}
+
+// dumpRouter rewrites the schema qualified object names of a plain text dump
+// to their routed target names. Only the names qualified with one of the
+// dumped schemas are rewritten, so that system objects (i.e. pg_catalog
+// functions) are not affected.
+type dumpRouter struct {
+	router  TableRouter
+	schemas map[string]struct{}
+	// targetSchemas keeps track of the quoted target schemas the dump objects
+	// have been routed to
+	targetSchemas []string
+}
+
+const identifierPattern = `"(?:[^"]|"")+"|[A-Za-z_][A-Za-z0-9_$]*`
+
+var (
+	qualifiedNameRegex   = regexp.MustCompile(`(^|[^A-Za-z0-9_$."])(` + identifierPattern + `)\.(` + identifierPattern + `)`)
+	schemaStatementRegex = regexp.MustCompile(`\bSCHEMA (` + identifierPattern + `)`)
+	createSchemaRegex    = regexp.MustCompile(`(?m)^CREATE SCHEMA (` + identifierPattern + `);`)
+)
+
+func newDumpRouter(router TableRouter, schemaTables map[string][]string, d []byte) *dumpRouter {
+	schemas := map[string]struct{}{
+		publicSchema: {},
+	}
+	for schema := range schemaTables {
+		if schema != wildcard {
+			schemas[schema] = struct{}{}
+		}
+	}
+	// when using the wildcard schema, the dumped schemas are only known from
+	// the dump itself
+	for _, match := range createSchemaRegex.FindAllSubmatch(d, -1) {
+		schemas[unquoteIdentifier(string(match[1]))] = struct{}{}
+	}
+
+	return &dumpRouter{
+		router:  router,
+		schemas: schemas,
+	}
+}
+
+func (r *dumpRouter) route(d []byte) []byte {
+	if len(d) == 0 {
+		return d
+	}
+
+	d = schemaStatementRegex.ReplaceAllFunc(d, func(match []byte) []byte {
+		schema := unquoteIdentifier(string(schemaStatementRegex.FindSubmatch(match)[1]))
+		if !r.isDumpSchema(schema) {
+			return match
+		}
+		targetSchema := r.router.RouteSchema(schema)
+		r.addTargetSchema(targetSchema)
+		if targetSchema == schema {
+			return match
+		}
+		return []byte("SCHEMA " + pglib.QuoteIdentifier(targetSchema))
+	})
+
+	return qualifiedNameRegex.ReplaceAllFunc(d, func(match []byte) []byte {
+		submatches := qualifiedNameRegex.FindSubmatch(match)
+		schema := unquoteIdentifier(string(submatches[2]))
+		if !r.isDumpSchema(schema) {
+			return match
+		}
+		name := unquoteIdentifier(string(submatches[3]))
+		targetSchema, targetName := r.router.Route(schema, name)
+		r.addTargetSchema(targetSchema)
+		if targetSchema == schema && targetName == name {
+			return match
+		}
+		return []byte(string(submatches[1]) + pglib.QuoteQualifiedIdentifier(targetSchema, targetName))
+	})
+}
+
+func (r *dumpRouter) isDumpSchema(schema string) bool {
+	_, found := r.schemas[schema]
+	return found
+}
+
+func (r *dumpRouter) addTargetSchema(schema string) {
+	if schema == publicSchema {
+		return
+	}
+	if quotedSchema := pglib.QuoteIdentifier(schema); !slices.Contains(r.targetSchemas, quotedSchema) {
+		r.targetSchemas = append(r.targetSchemas, quotedSchema)
+	}
+}
+
+func unquoteIdentifier(identifier string) string {
+	if len(identifier) > 1 && strings.HasPrefix(identifier, `"`) && strings.HasSuffix(identifier, `"`) {
+		return strings.ReplaceAll(identifier[1:len(identifier)-1], `""`, `"`)
+	}
+	return identifier
+}
@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package pgdumprestore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/wal/processor/router"
+)
+
+func TestDumpRouter_route(t *testing.T) {
+	t.Parallel()
+
+	tableRouter, err := router.NewTableRouter(&router.Config{
+		Rules: []router.RuleConfig{
+			{Source: "public.users", Target: "tenant_a.accounts"},
+			{Source: "public.*", Target: "tenant_a.{table}"},
+		},
+	})
+	require.NoError(t, err)
+
+	dump := []byte(`CREATE SCHEMA sales;
+COMMENT ON SCHEMA public IS 'standard public schema';
+CREATE TABLE public.users (
+    id integer DEFAULT nextval('public.users_id_seq'::regclass) NOT NULL,
+    name text
+);
+CREATE TABLE sales."Orders" (
+    id integer NOT NULL,
+    user_id integer
+);
+SELECT pg_catalog.set_config('search_path', '', false);
+ALTER SEQUENCE public.users_id_seq OWNED BY public.users.id;
+ALTER TABLE ONLY sales."Orders"
+    ADD CONSTRAINT orders_user_fk FOREIGN KEY (user_id) REFERENCES public.users(id);
+`)
+
+	wantDump := `CREATE SCHEMA sales;
+COMMENT ON SCHEMA "tenant_a" IS 'standard public schema';
+CREATE TABLE "tenant_a"."accounts" (
+    id integer DEFAULT nextval('"tenant_a"."users_id_seq"'::regclass) NOT NULL,
+    name text
+);
+CREATE TABLE sales."Orders" (
+    id integer NOT NULL,
+    user_id integer
+);
+SELECT pg_catalog.set_config('search_path', '', false);
+ALTER SEQUENCE "tenant_a"."users_id_seq" OWNED BY "tenant_a"."accounts".id;
+ALTER TABLE ONLY sales."Orders"
+    ADD CONSTRAINT orders_user_fk FOREIGN KEY (user_id) REFERENCES "tenant_a"."accounts"(id);
+`
+
+	r := newDumpRouter(tableRouter, map[string][]string{wildcard: {wildcard}}, dump)
+	require.Equal(t, wantDump, string(r.route(dump)))
+	require.Equal(t, []string{`"sales"`, `"tenant_a"`}, r.targetSchemas)
+}
@@ -7,13 +7,27 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/xataio/pgstream/pkg/backoff"
+	"github.com/xataio/pgstream/pkg/blobstore"
 	"github.com/xataio/pgstream/pkg/kafka"
+	"github.com/xataio/pgstream/pkg/schemaregistry"
+	filecheckpoint "github.com/xataio/pgstream/pkg/wal/checkpointer/file"
 	kafkacheckpoint "github.com/xataio/pgstream/pkg/wal/checkpointer/kafka"
+	dlqfile "github.com/xataio/pgstream/pkg/wal/dlq/file"
+	dlqkafka "github.com/xataio/pgstream/pkg/wal/dlq/kafka"
+	dlqpostgres "github.com/xataio/pgstream/pkg/wal/dlq/postgres"
+	filelistener "github.com/xataio/pgstream/pkg/wal/listener/file"
 	snapshotbuilder "github.com/xataio/pgstream/pkg/wal/listener/snapshot/builder"
+	"github.com/xataio/pgstream/pkg/wal/processor/fanout"
+	fileprocessor "github.com/xataio/pgstream/pkg/wal/processor/file"
 	"github.com/xataio/pgstream/pkg/wal/processor/filter"
 	"github.com/xataio/pgstream/pkg/wal/processor/injector"
 	kafkaprocessor "github.com/xataio/pgstream/pkg/wal/processor/kafka"
+	"github.com/xataio/pgstream/pkg/wal/processor/outbox"
+	parquetprocessor "github.com/xataio/pgstream/pkg/wal/processor/parquet"
 	"github.com/xataio/pgstream/pkg/wal/processor/postgres"
+	"github.com/xataio/pgstream/pkg/wal/processor/projection"
+	"github.com/xataio/pgstream/pkg/wal/processor/router"
 	"github.com/xataio/pgstream/pkg/wal/processor/search"
 	"github.com/xataio/pgstream/pkg/wal/processor/search/store"
 	"github.com/xataio/pgstream/pkg/wal/processor/transformer"
@@ -25,11 +39,15 @@ import (
 type Config struct {
 	Listener  ListenerConfig
 	Processor ProcessorConfig
+	// DeadLetterQueue receives the events that fail processing. If not
+	// configured, the failures are logged and the events dropped.
+	DeadLetterQueue *DeadLetterQueueConfig
 }
 
 type ListenerConfig struct {
 	Postgres *PostgresListenerConfig
 	Kafka    *KafkaListenerConfig
+	File     *FileListenerConfig
 	Snapshot *snapshotbuilder.SnapshotListenerConfig
 }
 
@@ -41,6 +59,86 @@ type PostgresListenerConfig struct {
 type KafkaListenerConfig struct {
 	Reader       kafka.ReaderConfig
 	Checkpointer kafkacheckpoint.Config
+	// SchemaRegistry is required to read the messages serialised with a
+	// schema registry schema (i.e. avro).
+	SchemaRegistry *schemaregistry.Config
+	// Format of the messages that are not serialised with a schema registry
+	// schema. One of json (default), for the messages produced by the pgstream
+	// kafka processor, or debezium, for debezium change events.
+	Format string
+	// Concurrency is the number of partitions processed concurrently. The
+	// events of a partition are always processed in order. Defaults to 1.
+	Concurrency int
+	// BlobStore is required to read the messages whose value was written to
+	// a blob store by the kafka processor claim check strategy.
+	BlobStore *blobstore.Config
+	// DeleteClaimChecks deletes the blobs of the claim checked messages from
+	// the blob store once their offsets have been committed. It should only be
+	// enabled when the topics are read by a single consumer group, since the
+	// blobs are no longer available to other consumers or replays.
+	DeleteClaimChecks bool
+	// Retry of the records that fail processing. By default, they're not
+	// retried.
+	Retry KafkaListenerRetryConfig
+}
+
+type KafkaListenerRetryConfig struct {
+	// Backoff applied between the processing attempts of a failed record.
+	Backoff backoff.Config
+	// OnFailure is the policy applied to the records that still fail once
+	// the retries are exhausted. One of stop (default), which stops the
+	// listener unless a dead letter queue is configured, or error_topic,
+	// which forwards the record to the error topic.
+	OnFailure string
+	// ErrorTopic is required by the error_topic policy. It's written to
+	// using the listener kafka connection settings.
+	ErrorTopic kafka.TopicConfig
+}
+
+const (
+	KafkaListenerFormatJSON     = "json"
+	KafkaListenerFormatDebezium = "debezium"
+
+	KafkaListenerOnFailureStop       = "stop"
+	KafkaListenerOnFailureErrorTopic = "error_topic"
+)
+
+func (c *KafkaListenerConfig) IsValid() error {
+	switch c.Format {
+	case "", KafkaListenerFormatJSON, KafkaListenerFormatDebezium:
+	default:
+		return fmt.Errorf("unsupported kafka listener format: %s", c.Format)
+	}
+
+	if c.Concurrency < 0 {
+		return fmt.Errorf("invalid kafka listener concurrency: %d", c.Concurrency)
+	}
+
+	switch c.Retry.OnFailure {
+	case "", KafkaListenerOnFailureStop:
+	case KafkaListenerOnFailureErrorTopic:
+		if c.Retry.ErrorTopic.Name == "" {
+			return errors.New("kafka listener error topic name must be provided for the error_topic failure policy")
+		}
+	default:
+		return fmt.Errorf("unsupported kafka listener failure policy: %s", c.Retry.OnFailure)
+	}
+
+	if c.DeleteClaimChecks && c.BlobStore == nil {
+		return errors.New("kafka listener claim check deletion requires a blob store")
+	}
+
+	if c.BlobStore != nil {
+		return c.BlobStore.IsValid()
+	}
+	return nil
+}
+
+type FileListenerConfig struct {
+	Reader filelistener.Config
+	// Checkpointer is optional. If no path is provided, the positions are not
+	// checkpointed and the files are always read from the beginning.
+	Checkpointer filecheckpoint.Config
 }
 
 type ProcessorConfig struct {
@@ -48,29 +146,77 @@ type ProcessorConfig struct {
 	Search      *SearchProcessorConfig
 	Webhook     *WebhookProcessorConfig
 	Postgres    *PostgresProcessorConfig
+	File        *FileProcessorConfig
+	Parquet     *ParquetProcessorConfig
 	Injector    *injector.Config
 	Transformer *transformer.Config
 	Filter      *filter.Config
+	Outbox      *outbox.Config
+	Projection  *projection.Config
+	Routing     *router.Config
 }
 
 type KafkaProcessorConfig struct {
 	Writer *kafkaprocessor.Config
+	Target TargetConfig
 }
 
 type SearchProcessorConfig struct {
 	Indexer search.IndexerConfig
 	Store   store.Config
 	Retrier search.StoreRetryConfig
+	Target  TargetConfig
 }
 
 type WebhookProcessorConfig struct {
 	Notifier           notifier.Config
 	SubscriptionServer server.Config
 	SubscriptionStore  WebhookSubscriptionStoreConfig
+	Target             TargetConfig
 }
 
 type PostgresProcessorConfig struct {
 	BatchWriter postgres.Config
+	Target      TargetConfig
+}
+
+type FileProcessorConfig struct {
+	Writer fileprocessor.Config
+	Target TargetConfig
+}
+
+type ParquetProcessorConfig struct {
+	Writer parquetprocessor.Config
+	Target TargetConfig
+}
+
+// TargetConfig contains the settings that apply to an individual target.
+type TargetConfig struct {
+	// Transformer is applied only to the events sent to this target, after
+	// the shared transformer modifier.
+	Transformer *transformer.Config
+	// Fanout contains the target settings used when the events are fanned out
+	// to multiple targets.
+	Fanout fanout.TargetConfig
+}
+
+type targetType string
+
+const (
+	targetKafka    targetType = "kafka"
+	targetPostgres targetType = "postgres"
+	targetSearch   targetType = "search"
+	targetWebhook  targetType = "webhook"
+	targetFile     targetType = "file"
+	targetParquet  targetType = "parquet"
+)
+
+// DeadLetterQueueConfig contains the configuration of the dead letter queue.
+// Only one of the sinks can be configured.
+type DeadLetterQueueConfig struct {
+	Postgres *dlqpostgres.Config
+	Kafka    *dlqkafka.Config
+	File     *dlqfile.Config
 }
 
 type WebhookSubscriptionStoreConfig struct {
@@ -84,7 +230,87 @@ func (c *Config) IsValid() error {
 		return err
 	}
 
-	return c.Processor.IsValid()
+	if err := c.Processor.IsValid(); err != nil {
+		return err
+	}
+
+	if c.DeadLetterQueue != nil {
+		if err := c.DeadLetterQueue.IsValid(); err != nil {
+			return err
+		}
+	}
+
+	// exactly once delivery relies on the postgres commit positions
+	if c.Processor.Postgres != nil && c.Processor.Postgres.BatchWriter.ExactlyOnce.Enabled {
+		if c.Listener.Kafka != nil {
+			return errors.New("postgres exactly once delivery is not supported with a kafka listener")
+		}
+		if c.Listener.File != nil {
+			return errors.New("postgres exactly once delivery is not supported with a file listener")
+		}
+	}
+
+	// transaction consistency relies on the events being processed in the
+	// order they were produced
+	if c.Listener.Kafka != nil && c.Listener.Kafka.Concurrency > 1 &&
+		c.Processor.Postgres != nil && c.Processor.Postgres.BatchWriter.TransactionConsistent {
+		return errors.New("postgres transaction consistency is not supported with a concurrent kafka listener")
+	}
+
+	if c.Processor.Routing != nil && c.Processor.Search != nil {
+		// the search indices are per schema, so all the tables of a schema
+		// need to be routed to the same target schema
+		tableRouter, err := router.NewTableRouter(c.Processor.Routing)
+		if err != nil {
+			return fmt.Errorf("invalid routing configuration: %w", err)
+		}
+		if tableRouter.SplitsSchemas() {
+			return errors.New("search processor doesn't support routing tables of the same schema to different schemas")
+		}
+	}
+
+	// the row filter emits the updates of rows without old values as inserts,
+	// which the postgres target can only apply as upserts
+	if c.Processor.Filter != nil && len(c.Processor.Filter.RowFilters) > 0 && c.Listener.Snapshot == nil &&
+		c.Processor.Postgres != nil && c.Processor.Postgres.BatchWriter.OnConflictAction != "update" {
+		return errors.New("row filters with a postgres target require the update on conflict action")
+	}
+
+	if c.Processor.Outbox != nil {
+		// the outbox events are routed using kafka message properties
+		if c.Processor.Kafka == nil {
+			return errors.New("outbox modifier is only supported with a kafka processor")
+		}
+		// processed rows are deleted from the source database once their
+		// replication positions have been checkpointed
+		if c.Processor.Outbox.DeleteProcessedRows && c.Listener.Postgres == nil {
+			return errors.New("outbox processed rows deletion requires a postgres listener")
+		}
+	}
+
+	return nil
+}
+
+func (c *DeadLetterQueueConfig) IsValid() error {
+	sinkCount := 0
+	if c.Postgres != nil {
+		sinkCount++
+	}
+	if c.Kafka != nil {
+		sinkCount++
+	}
+	if c.File != nil {
+		sinkCount++
+	}
+
+	switch sinkCount {
+	case 0:
+		return errors.New("need at least one dead letter queue sink configured")
+	case 1:
+		return nil
+	default:
+		return fmt.Errorf("only one dead letter queue sink can be configured at a time, found %d", sinkCount)
+	}
 }
 
 func (c *ListenerConfig) IsValid() error {
@@ -95,10 +321,19 @@ func (c *ListenerConfig) IsValid() error {
 	if c.Postgres != nil {
 		listenerCount++
 	}
+	if c.File != nil {
+		listenerCount++
+	}
 	if c.Snapshot != nil {
 		listenerCount++
 	}
 
+	if c.Kafka != nil {
+		if err := c.Kafka.IsValid(); err != nil {
+			return err
+		}
+	}
+
 	switch listenerCount {
 	case 0:
 		return errors.New("need at least one listener configured")
@@ -112,29 +347,53 @@ func (c *ListenerConfig) IsValid() error {
 }
 
 func (c *ProcessorConfig) IsValid() error {
-	processorCount := 0
+	if len(c.targets()) == 0 {
+		return errors.New("need at least one processor configured")
+	}
+	return nil
+}
+
+// targets returns the configured targets. When more than one target is
+// configured, the events are fanned out to all of them.
+func (c *ProcessorConfig) targets() []targetType {
+	targets := []targetType{}
 	if c.Kafka != nil {
-		processorCount++
+		targets = append(targets, targetKafka)
 	}
 	if c.Postgres != nil {
-		processorCount++
+		targets = append(targets, targetPostgres)
 	}
 	if c.Search != nil {
-		processorCount++
+		targets = append(targets, targetSearch)
 	}
 	if c.Webhook != nil {
-		processorCount++
+		targets = append(targets, targetWebhook)
+	}
+	if c.File != nil {
+		targets = append(targets, targetFile)
 	}
+	if c.Parquet != nil {
+		targets = append(targets, targetParquet)
+	}
+	return targets
+}
 
-	switch processorCount {
-	case 0:
-		return errors.New("need at least one processor configured")
-	case 1:
-		// Only one processor is configured, do nothing
-		return nil
+func (c *ProcessorConfig) targetConfig(target targetType) TargetConfig {
+	switch target {
+	case targetKafka:
+		return c.Kafka.Target
+	case targetPostgres:
+		return c.Postgres.Target
+	case targetSearch:
+		return c.Search.Target
+	case targetWebhook:
+		return c.Webhook.Target
+	case targetFile:
+		return c.File.Target
+	case targetParquet:
+		return c.Parquet.Target
 	default:
-		// More than one processor is configured, return an error
-		return fmt.Errorf("only one processor can be configured at a time, found %d", processorCount)
+		return TargetConfig{}
 	}
 }
 
@@ -148,6 +407,23 @@ func (c *Config) SourcePostgresURL() string {
 	return ""
 }
 
+// snapshotListenerConfig returns the snapshot listener configuration on input
+// with the processor row filters, routing and column projection, so that the
+// snapshot is consistent with the streamed events.
+func (c *Config) snapshotListenerConfig(snapshotCfg *snapshotbuilder.SnapshotListenerConfig) *snapshotbuilder.SnapshotListenerConfig {
+	hasRowFilters := c.Processor.Filter != nil && len(c.Processor.Filter.RowFilters) > 0
+	if !hasRowFilters && c.Processor.Routing == nil && c.Processor.Projection == nil {
+		return snapshotCfg
+	}
+	cfg := *snapshotCfg
+	if hasRowFilters {
+		cfg.RowFilters = c.Processor.Filter.RowFilters
+	}
+	cfg.Routing = c.Processor.Routing
+	cfg.Projection = c.Processor.Projection
+	return &cfg
+}
+
 func (c *Config) PostgresReplicationSlot() string {
 	if c.Listener.Postgres != nil {
 		return c.Listener.Postgres.Replication.ReplicationSlotName
@@ -155,6 +431,24 @@ func (c *Config) PostgresReplicationSlot() string {
 	return ""
 }
 
+// PostgresReplicationPlugin returns the logical decoding output plugin
+// configured for the replication slot, defaulting to wal2json.
+func (c *Config) PostgresReplicationPlugin() string {
+	if c.Listener.Postgres != nil {
+		return c.Listener.Postgres.Replication.GetPlugin()
+	}
+	return pgreplication.PluginWal2json
+}
+
+// PostgresPublication returns the publication name used by the pgoutput
+// plugin.
+func (c *Config) PostgresPublication() string {
+	if c.Listener.Postgres != nil {
+		return c.Listener.Postgres.Replication.GetPublicationName()
+	}
+	return pgreplication.DefaultPublicationName
+}
+
 func (c *Config) RequiredTables() []string {
 	requiredTables := []string{}
 	if c.Listener.Snapshot != nil {
@@ -88,6 +88,9 @@ func Test_PostgresToKafka(t *testing.T) {
 					return
 				case event := <-mockProcessor.eventChan:
 					require.NotNil(t, event.Data)
+					if event.Data.IsTransactionBoundary() {
+						continue
+					}
 					require.Equal(t, tc.wantEvent.Data.Action, event.Data.Action)
 					require.Equal(t, tc.wantEvent.Data.Schema, event.Data.Schema)
 					require.Equal(t, tc.wantEvent.Data.Table, event.Data.Table)
@@ -7,17 +7,33 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/xataio/pgstream/pkg/blobstore"
+	"github.com/xataio/pgstream/pkg/kafka"
 	loglib "github.com/xataio/pgstream/pkg/log"
 	"github.com/xataio/pgstream/pkg/otel"
 	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/schemaregistry"
 	"github.com/xataio/pgstream/pkg/transformers/builder"
+	"github.com/xataio/pgstream/pkg/wal/avro"
 	"github.com/xataio/pgstream/pkg/wal/checkpointer"
+	"github.com/xataio/pgstream/pkg/wal/debezium"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
+	dlqfile "github.com/xataio/pgstream/pkg/wal/dlq/file"
+	dlqkafka "github.com/xataio/pgstream/pkg/wal/dlq/kafka"
+	dlqpostgres "github.com/xataio/pgstream/pkg/wal/dlq/postgres"
+	kafkalistener "github.com/xataio/pgstream/pkg/wal/listener/kafka"
 	"github.com/xataio/pgstream/pkg/wal/processor"
+	"github.com/xataio/pgstream/pkg/wal/processor/fanout"
+	fileprocessor "github.com/xataio/pgstream/pkg/wal/processor/file"
 	"github.com/xataio/pgstream/pkg/wal/processor/filter"
 	"github.com/xataio/pgstream/pkg/wal/processor/injector"
 	processinstrumentation "github.com/xataio/pgstream/pkg/wal/processor/instrumentation"
 	kafkaprocessor "github.com/xataio/pgstream/pkg/wal/processor/kafka"
+	"github.com/xataio/pgstream/pkg/wal/processor/outbox"
+	parquetprocessor "github.com/xataio/pgstream/pkg/wal/processor/parquet"
 	pgwriter "github.com/xataio/pgstream/pkg/wal/processor/postgres"
+	"github.com/xataio/pgstream/pkg/wal/processor/projection"
+	"github.com/xataio/pgstream/pkg/wal/processor/router"
 	"github.com/xataio/pgstream/pkg/wal/processor/search"
 	searchinstrumentation "github.com/xataio/pgstream/pkg/wal/processor/search/instrumentation"
 	"github.com/xataio/pgstream/pkg/wal/processor/search/store"
@@ -39,10 +55,60 @@ const (
 	processorTypeSnapshot
 )
 
-func buildProcessor(ctx context.Context, logger loglib.Logger, config *ProcessorConfig, checkpoint checkpointer.Checkpoint, processorType processorType, instrumentation *otel.Instrumentation) (processor.Processor, error) {
+// buildProcessor builds the configured targets, along with their target
+// specific modifiers. When more than one target is configured, the events are
+// fanned out to all of them, and the source is only checkpointed up to the
+// positions acknowledged by all targets.
+func buildProcessor(ctx context.Context, logger loglib.Logger, config *Config, checkpoint checkpointer.Checkpoint, processorType processorType, instrumentation *otel.Instrumentation, outboxCleaner *outbox.Cleaner, deadLetterQueue dlq.Queue) (processor.Processor, closerFn, error) {
+	targets := config.Processor.targets()
+	if len(targets) == 1 {
+		return buildTarget(ctx, logger, config, targets[0], checkpoint, processorType, instrumentation, outboxCleaner, deadLetterQueue)
+	}
+
+	logger.Info("fan out to multiple targets configured", loglib.Fields{"targets": targets})
+	closerAgg := &closerAggregator{}
+	tracker := fanout.NewPositionTracker(checkpoint)
+	fanoutTargets := make([]fanout.Target, 0, len(targets))
+	for _, target := range targets {
+		targetProcessor, closer, err := buildTarget(ctx, logger, config, target, tracker.TargetCheckpoint(string(target)), processorType, instrumentation, outboxCleaner, deadLetterQueue)
+		if err != nil {
+			for _, t := range fanoutTargets {
+				t.Processor.Close()
+			}
+			closerAgg.close()
+			return nil, noopCloser, fmt.Errorf("building %s target: %w", target, err)
+		}
+		closerAgg.addCloserFn(closer)
+		fanoutTargets = append(fanoutTargets, fanout.Target{
+			Name:      string(target),
+			Processor: targetProcessor,
+			Config:    config.Processor.targetConfig(target).Fanout,
+		})
+	}
+
+	return fanout.New(ctx, fanoutTargets, tracker, fanout.WithLogger(logger)), closerAgg.close, nil
+}
+
+// buildTarget builds the target processor on input, wrapped with the target
+// specific modifiers.
+func buildTarget(ctx context.Context, logger loglib.Logger, config *Config, target targetType, checkpoint checkpointer.Checkpoint, processorType processorType, instrumentation *otel.Instrumentation, outboxCleaner *outbox.Cleaner, deadLetterQueue dlq.Queue) (processor.Processor, closerFn, error) {
+	processor, err := buildTargetProcessor(ctx, logger, &config.Processor, target, checkpoint, processorType, instrumentation, deadLetterQueue)
+	if err != nil {
+		return nil, noopCloser, err
+	}
+
+	targetProcessor, closer, err := addTargetModifiers(ctx, config, logger, target, processor, instrumentation, outboxCleaner, deadLetterQueue)
+	if err != nil {
+		processor.Close()
+		return nil, noopCloser, err
+	}
+	return targetProcessor, closer, nil
+}
+
+func buildTargetProcessor(ctx context.Context, logger loglib.Logger, config *ProcessorConfig, target targetType, checkpoint checkpointer.Checkpoint, processorType processorType, instrumentation *otel.Instrumentation, deadLetterQueue dlq.Queue) (processor.Processor, error) {
 	var processor processor.Processor
-	switch {
-	case config.Kafka != nil:
+	switch target {
+	case targetKafka:
 		logger.Info("kafka processor configured")
 		opts := []kafkaprocessor.Option{
 			kafkaprocessor.WithCheckpoint(checkpoint),
@@ -56,15 +122,29 @@ func buildProcessor(ctx context.Context, logger loglib.Logger, config *Processor
 			return nil, err
 		}
 		processor = kafkaWriter
-	case config.Search != nil:
+	case targetSearch:
 		logger.Info("search processor configured")
 		var searchStore search.Store
 		var err error
-		searchStore, err = store.NewStore(config.Search.Store, store.WithLogger(logger))
+		storeOpts := []store.Option{store.WithLogger(logger)}
+		if config.Routing != nil {
+			// the schema log entries keep the source schema names, so the
+			// routing needs to be applied to the schema indices
+			schemaRouter, err := router.NewTableRouter(config.Routing)
+			if err != nil {
+				return nil, err
+			}
+			storeOpts = append(storeOpts, store.WithSchemaRouter(schemaRouter))
+		}
+		searchStore, err = store.NewStore(config.Search.Store, storeOpts...)
 		if err != nil {
 			return nil, err
 		}
-		searchStore = search.NewStoreRetrier(searchStore, config.Search.Retrier, search.WithStoreLogger(logger))
+		retrierOpts := []search.StoreOption{search.WithStoreLogger(logger)}
+		if deadLetterQueue != nil {
+			retrierOpts = append(retrierOpts, search.WithStoreDeadLetterQueue(deadLetterQueue))
+		}
+		searchStore = search.NewStoreRetrier(searchStore, config.Search.Retrier, retrierOpts...)
 		if instrumentation.IsEnabled() {
 			searchStore, err = searchinstrumentation.NewStore(searchStore, instrumentation)
 			if err != nil {
@@ -84,7 +164,7 @@ func buildProcessor(ctx context.Context, logger loglib.Logger, config *Processor
 		}
 		processor = searchIndexer
 
-	case config.Webhook != nil:
+	case targetWebhook:
 		logger.Info("webhook processor configured")
 
 		var subscriptionStore webhookstore.Store
@@ -109,11 +189,17 @@ func buildProcessor(ctx context.Context, logger loglib.Logger, config *Processor
 			}
 		}
 
+		notifierOpts := []webhooknotifier.Option{
+			webhooknotifier.WithLogger(logger),
+			webhooknotifier.WithCheckpoint(checkpoint),
+		}
+		if deadLetterQueue != nil {
+			notifierOpts = append(notifierOpts, webhooknotifier.WithDeadLetterQueue(deadLetterQueue))
+		}
 		notifier := webhooknotifier.New(
 			&config.Webhook.Notifier,
 			subscriptionStore,
-			webhooknotifier.WithLogger(logger),
-			webhooknotifier.WithCheckpoint(checkpoint))
+			notifierOpts...)
 		processor = notifier
 
 		subscriptionServer := subscriptionserver.New(
@@ -139,7 +225,7 @@ func buildProcessor(ctx context.Context, logger loglib.Logger, config *Processor
 			}
 		}()
 
-	case config.Postgres != nil:
+	case targetPostgres:
 		logger.Info("postgres processor configured")
 
 		opts := []pgwriter.WriterOption{
@@ -149,16 +235,24 @@ func buildProcessor(ctx context.Context, logger loglib.Logger, config *Processor
 			opts = append(opts, pgwriter.WithInstrumentation(instrumentation))
 		}
 
-		if processorType == processorTypeSnapshot && config.Postgres.BatchWriter.BulkIngestEnabled {
+		// the schema log entries processed by the writer keep the source table
+		// names, so the routing needs to be applied by the writer
+		writerCfg := config.Postgres.BatchWriter
+		writerCfg.TableRouting = config.Routing
+
+		if processorType == processorTypeSnapshot && writerCfg.BulkIngestEnabled {
 			logger.Info("postgres bulk ingest writer enabled")
-			bulkIngestWriter, err := pgwriter.NewBulkIngestWriter(ctx, &config.Postgres.BatchWriter, opts...)
+			bulkIngestWriter, err := pgwriter.NewBulkIngestWriter(ctx, &writerCfg, opts...)
 			if err != nil {
 				return nil, err
 			}
 			processor = bulkIngestWriter
 		} else {
 			opts := append(opts, pgwriter.WithCheckpoint(checkpoint))
-			pgBatchWriter, err := pgwriter.NewBatchWriter(ctx, &config.Postgres.BatchWriter, opts...)
+			// the schema log entries retrieved by the writer need to be
+			// consistent with the projected schema log events
+			writerCfg.ColumnProjection = config.Projection
+			pgBatchWriter, err := pgwriter.NewBatchWriter(ctx, &writerCfg, opts...)
 			if err != nil {
 				return nil, err
 			}
@@ -166,6 +260,26 @@ func buildProcessor(ctx context.Context, logger loglib.Logger, config *Processor
 			processor = pgBatchWriter
 		}
 
+	case targetFile:
+		logger.Info("file processor configured")
+		fileWriter, err := fileprocessor.NewBatchWriter(ctx, &config.File.Writer,
+			fileprocessor.WithCheckpoint(checkpoint),
+			fileprocessor.WithLogger(logger))
+		if err != nil {
+			return nil, err
+		}
+		processor = fileWriter
+
+	case targetParquet:
+		logger.Info("parquet processor configured")
+		parquetWriter, err := parquetprocessor.NewBatchWriter(ctx, &config.Parquet.Writer,
+			parquetprocessor.WithCheckpoint(checkpoint),
+			parquetprocessor.WithLogger(logger))
+		if err != nil {
+			return nil, err
+		}
+		processor = parquetWriter
+
 	default:
 		return nil, errors.New("no supported processor found")
 	}
@@ -173,41 +287,68 @@ func buildProcessor(ctx context.Context, logger loglib.Logger, config *Processor
 	return processor, nil
 }
 
-func addProcessorModifiers(ctx context.Context, config *Config, logger loglib.Logger, processor processor.Processor, instrumentation *otel.Instrumentation) (processor.Processor, closerFn, error) {
+// addTargetModifiers wraps the target processor on input with the modifiers
+// that apply to each target individually.
+func addTargetModifiers(ctx context.Context, config *Config, logger loglib.Logger, target targetType, processor processor.Processor, instrumentation *otel.Instrumentation, outboxCleaner *outbox.Cleaner, deadLetterQueue dlq.Queue) (processor.Processor, closerFn, error) {
 	closerAgg := &closerAggregator{}
 	var err error
-	if config.Processor.Transformer != nil {
-		logger.Info("adding transformation layer to processor...")
-		builderOpts := []builder.Option{}
-		if instrumentation.IsEnabled() {
-			builderOpts = append(builderOpts, builder.WithInstrumentation(instrumentation))
+	// the routing layer is applied first so that the rest of modifiers work
+	// with the source schema and table names
+	if config.Processor.Routing != nil {
+		logger.Info("adding routing to processor...")
+		processor, err = router.New(processor, config.Processor.Routing, router.WithLogger(logger))
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating processor routing layer: %w", err)
 		}
-		transformerBuilder := builder.NewTransformerBuilder(builderOpts...)
+	}
 
-		opts := []transformer.Option{transformer.WithLogger(logger)}
-		// if a source pg url is provided, use it to validate the transformer
-		pgURL := ""
-		switch {
-		case config.Listener.Postgres != nil:
-			pgURL = config.Listener.Postgres.Replication.PostgresURL
-		case config.Listener.Snapshot != nil:
-			pgURL = config.Listener.Snapshot.Generator.URL
+	// the outbox layer is applied before the transformers so that the routed
+	// payload contains the transformed values
+	if config.Processor.Outbox != nil && target == targetKafka {
+		logger.Info("adding outbox routing to processor...")
+		opts := []outbox.Option{outbox.WithLogger(logger)}
+		if outboxCleaner != nil {
+			opts = append(opts, outbox.WithCleaner(outboxCleaner))
 		}
-		if pgURL != "" {
-			pgParser, err := transformer.NewPostgresTransformerParser(ctx, pgURL, transformerBuilder, config.RequiredTables())
-			if err != nil {
-				return nil, nil, fmt.Errorf("creating transformer validator: %w", err)
-			}
-			closerAgg.addCloserFn(pgParser.Close)
-			opts = append(opts, transformer.WithParser(pgParser.ParseAndValidate))
+		processor, err = outbox.New(processor, config.Processor.Outbox, opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating processor outbox layer: %w", err)
 		}
-		processor, err = transformer.New(ctx, config.Processor.Transformer, processor, transformerBuilder, opts...)
+	}
+
+	if transformerCfg := config.Processor.targetConfig(target).Transformer; transformerCfg != nil {
+		logger.Info("adding target transformation layer to processor...", loglib.Fields{"target": target})
+		processor, err = newTransformer(ctx, config, transformerCfg, logger, processor, instrumentation, deadLetterQueue, closerAgg)
 		if err != nil {
-			logger.Error(err, "creating transformer layer")
 			return nil, nil, err
 		}
 	}
 
+	return processor, closerAgg.close, nil
+}
+
+func addProcessorModifiers(ctx context.Context, config *Config, logger loglib.Logger, processor processor.Processor, instrumentation *otel.Instrumentation, deadLetterQueue dlq.Queue) (processor.Processor, closerFn, error) {
+	closerAgg := &closerAggregator{}
+	var err error
+	if config.Processor.Transformer != nil {
+		logger.Info("adding transformation layer to processor...")
+		processor, err = newTransformer(ctx, config, config.Processor.Transformer, logger, processor, instrumentation, deadLetterQueue, closerAgg)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// the projection layer is applied after the filtering, so that the row
+	// filters can reference the projected columns, and after the injection,
+	// so that it can keep the identity columns identified by the metadata
+	if config.Processor.Projection != nil {
+		logger.Info("adding column projection to processor...")
+		processor, err = projection.New(processor, config.Processor.Projection, projection.WithLogger(logger))
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating processor projection layer: %w", err)
+		}
+	}
+
 	if config.Processor.Injector != nil {
 		logger.Info("adding injection to processor...")
 		opts := []injector.Option{
@@ -247,6 +388,122 @@ func addProcessorModifiers(ctx context.Context, config *Config, logger loglib.Lo
 	return processor, closerAgg.close, nil
 }
 
+func newTransformer(ctx context.Context, config *Config, transformerCfg *transformer.Config, logger loglib.Logger, processor processor.Processor, instrumentation *otel.Instrumentation, deadLetterQueue dlq.Queue, closerAgg *closerAggregator) (processor.Processor, error) {
+	builderOpts := []builder.Option{}
+	if instrumentation.IsEnabled() {
+		builderOpts = append(builderOpts, builder.WithInstrumentation(instrumentation))
+	}
+	transformerBuilder := builder.NewTransformerBuilder(builderOpts...)
+
+	opts := []transformer.Option{transformer.WithLogger(logger)}
+	if deadLetterQueue != nil {
+		opts = append(opts, transformer.WithDeadLetterQueue(deadLetterQueue))
+	}
+	// if a source pg url is provided, use it to validate the transformer
+	pgURL := ""
+	switch {
+	case config.Listener.Postgres != nil:
+		pgURL = config.Listener.Postgres.Replication.PostgresURL
+	case config.Listener.Snapshot != nil:
+		pgURL = config.Listener.Snapshot.Generator.URL
+	}
+	if pgURL != "" {
+		pgParser, err := transformer.NewPostgresTransformerParser(ctx, pgURL, transformerBuilder, config.RequiredTables())
+		if err != nil {
+			return nil, fmt.Errorf("creating transformer validator: %w", err)
+		}
+		closerAgg.addCloserFn(pgParser.Close)
+		opts = append(opts, transformer.WithParser(pgParser.ParseAndValidate))
+	}
+	processor, err := transformer.New(ctx, transformerCfg, processor, transformerBuilder, opts...)
+	if err != nil {
+		logger.Error(err, "creating transformer layer")
+		return nil, err
+	}
+	return processor, nil
+}
+
+// newDeadLetterQueue returns the configured dead letter queue, or nil if none
+// is configured.
+func newDeadLetterQueue(ctx context.Context, config *DeadLetterQueueConfig, logger loglib.Logger) (dlq.Queue, error) {
+	switch {
+	case config == nil:
+		return nil, nil
+	case config.Postgres != nil:
+		logger.Info("postgres dead letter queue configured")
+		q, err := dlqpostgres.New(ctx, *config.Postgres)
+		if err != nil {
+			return nil, fmt.Errorf("creating postgres dead letter queue: %w", err)
+		}
+		return q, nil
+	case config.Kafka != nil:
+		logger.Info("kafka dead letter queue configured")
+		q, err := dlqkafka.New(*config.Kafka)
+		if err != nil {
+			return nil, fmt.Errorf("creating kafka dead letter queue: %w", err)
+		}
+		return q, nil
+	case config.File != nil:
+		logger.Info("file dead letter queue configured")
+		q, err := dlqfile.New(*config.File)
+		if err != nil {
+			return nil, fmt.Errorf("creating file dead letter queue: %w", err)
+		}
+		return q, nil
+	default:
+		return nil, nil
+	}
+}
+
+// newKafkaListenerOptions returns the kafka listener options for the
+// configuration on input.
+func newKafkaListenerOptions(config *KafkaListenerConfig, logger loglib.Logger, deadLetterQueue dlq.Queue) ([]kafkalistener.Option, error) {
+	opts := []kafkalistener.Option{
+		kafkalistener.WithLogger(logger),
+	}
+	if deadLetterQueue != nil {
+		opts = append(opts, kafkalistener.WithDeadLetterQueue(deadLetterQueue))
+	}
+	if config.SchemaRegistry != nil {
+		registry, err := schemaregistry.NewClient(config.SchemaRegistry)
+		if err != nil {
+			return nil, fmt.Errorf("creating schema registry client: %w", err)
+		}
+		opts = append(opts, kafkalistener.WithDeserialiser(avro.NewDeserialiser(registry)))
+	}
+	if config.Format == KafkaListenerFormatDebezium {
+		opts = append(opts, kafkalistener.WithDecoder(debezium.NewDeserialiser()))
+	}
+	if config.BlobStore != nil {
+		store, err := blobstore.NewStore(config.BlobStore)
+		if err != nil {
+			return nil, fmt.Errorf("creating blob store: %w", err)
+		}
+		opts = append(opts, kafkalistener.WithBlobStore(store))
+	}
+	if config.Concurrency > 1 {
+		opts = append(opts, kafkalistener.WithConcurrency(config.Concurrency))
+	}
+	if config.Retry.Backoff.Exponential != nil || config.Retry.Backoff.Constant != nil {
+		opts = append(opts, kafkalistener.WithRetry(&config.Retry.Backoff))
+	}
+	if config.Retry.OnFailure == KafkaListenerOnFailureErrorTopic {
+		conn := config.Reader.Conn
+		conn.Topic = config.Retry.ErrorTopic
+		writer, err := kafka.NewWriter(kafka.WriterConfig{
+			Conn: conn,
+			// the failed messages are written one at a time, so they're
+			// flushed straight away instead of waiting for the batch timeout
+			BatchSize: 1,
+		}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("creating kafka error topic writer: %w", err)
+		}
+		opts = append(opts, kafkalistener.WithErrorTopic(writer, config.Retry.ErrorTopic.Name))
+	}
+	return opts, nil
+}
+
 type closerAggregator struct {
 	closers []closerFn
 }
@@ -9,6 +9,7 @@ import (
 
 	pglib "github.com/xataio/pgstream/internal/postgres"
 	pgmigrations "github.com/xataio/pgstream/migrations/postgres"
+	pgreplication "github.com/xataio/pgstream/pkg/wal/replication/postgres"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -24,13 +25,33 @@ const (
 
 var errMissingPostgresURL = errors.New("postgres URL is required")
 
+// InitOption allows to configure the pgstream initialisation.
+type InitOption func(*initConfig)
+
+type initConfig struct {
+	replication pgreplication.Config
+}
+
+// WithReplicationPlugin configures the logical decoding output plugin used by
+// the replication slot. When the plugin is pgoutput, the publication provided
+// will be created/removed along with the slot.
+func WithReplicationPlugin(plugin, publicationName string) InitOption {
+	return func(c *initConfig) {
+		c.replication.Plugin = plugin
+		c.replication.PublicationName = publicationName
+	}
+}
+
 // Init initialises the pgstream state in the postgres database provided, along
-// with creating the relevant replication slot.
-func Init(ctx context.Context, pgURL, replicationSlotName string) error {
+// with creating the relevant replication slot (and publication when using the
+// pgoutput plugin).
+func Init(ctx context.Context, pgURL, replicationSlotName string, opts ...InitOption) error {
 	if pgURL == "" {
 		return errMissingPostgresURL
 	}
 
+	cfg := newInitConfig(opts...)
+
 	conn, err := newPGConn(ctx, pgURL)
 	if err != nil {
 		return err
@@ -59,7 +80,14 @@ func Init(ctx context.Context, pgURL, replicationSlotName string) error {
 		}
 	}
 
-	if err := createReplicationSlot(ctx, conn, replicationSlotName); err != nil {
+	plugin := cfg.replication.GetPlugin()
+	if plugin == pgreplication.PluginPgoutput {
+		if err := createPublication(ctx, conn, cfg.replication.GetPublicationName()); err != nil {
+			return fmt.Errorf("failed to create publication: %w", err)
+		}
+	}
+
+	if err := createReplicationSlot(ctx, conn, replicationSlotName, plugin); err != nil {
 		return fmt.Errorf("failed to create replication slot: %w", err)
 	}
 
@@ -67,12 +95,15 @@ func Init(ctx context.Context, pgURL, replicationSlotName string) error {
 }
 
 // TearDown removes the pgstream state from the postgres database provided,
-// as well as removing the replication slot.
-func TearDown(ctx context.Context, pgURL, replicationSlotName string) error {
+// as well as removing the replication slot (and publication when using the
+// pgoutput plugin).
+func TearDown(ctx context.Context, pgURL, replicationSlotName string, opts ...InitOption) error {
 	if pgURL == "" {
 		return errMissingPostgresURL
 	}
 
+	cfg := newInitConfig(opts...)
+
 	conn, err := newPGConn(ctx, pgURL)
 	if err != nil {
 		return err
@@ -90,6 +121,12 @@ func TearDown(ctx context.Context, pgURL, replicationSlotName string) error {
 		return err
 	}
 
+	if cfg.replication.GetPlugin() == pgreplication.PluginPgoutput {
+		if err := dropPublication(ctx, conn, cfg.replication.GetPublicationName()); err != nil {
+			return fmt.Errorf("failed to drop publication: %w", err)
+		}
+	}
+
 	migrator, err := newPGMigrator(pgURL)
 	if err != nil {
 		return fmt.Errorf("error creating postgres migrator: %w", err)
@@ -123,8 +160,16 @@ func dropPGStreamSchema(ctx context.Context, conn *pgx.Conn) error {
 	return nil
 }
 
-func createReplicationSlot(ctx context.Context, conn *pgx.Conn, slotName string) error {
-	_, err := conn.Exec(ctx, fmt.Sprintf(`SELECT 'init' FROM pg_create_logical_replication_slot ('%s', 'wal2json')`, slotName))
+func newInitConfig(opts ...InitOption) *initConfig {
+	cfg := &initConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+func createReplicationSlot(ctx context.Context, conn *pgx.Conn, slotName, plugin string) error {
+	_, err := conn.Exec(ctx, fmt.Sprintf(`SELECT 'init' FROM pg_create_logical_replication_slot ('%s', '%s')`, slotName, plugin))
 	if err != nil && !isDuplicateObject(err) {
 		return err
 	}
@@ -136,6 +181,19 @@ func dropReplicationSlot(ctx context.Context, conn *pgx.Conn, slotName string) e
 	return err
 }
 
+func createPublication(ctx context.Context, conn *pgx.Conn, publicationName string) error {
+	_, err := conn.Exec(ctx, fmt.Sprintf(`CREATE PUBLICATION %s FOR ALL TABLES`, pgx.Identifier{publicationName}.Sanitize()))
+	if err != nil && !isDuplicateObject(err) {
+		return err
+	}
+	return nil
+}
+
+func dropPublication(ctx context.Context, conn *pgx.Conn, publicationName string) error {
+	_, err := conn.Exec(ctx, fmt.Sprintf(`DROP PUBLICATION IF EXISTS %s`, pgx.Identifier{publicationName}.Sanitize()))
+	return err
+}
+
 func newPGConn(ctx context.Context, pgURL string) (*pgx.Conn, error) {
 	pgCfg, err := pgx.ParseConfig(pgURL)
 	if err != nil {
@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/xataio/pgstream/pkg/kafka"
+	kafkainstrumentation "github.com/xataio/pgstream/pkg/kafka/instrumentation"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/otel"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal"
+	kafkalistener "github.com/xataio/pgstream/pkg/wal/listener/kafka"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+	"github.com/xataio/pgstream/pkg/wal/processor/filter"
+	"golang.org/x/sync/errgroup"
+)
+
+// ReplayConfig contains the settings to replay the events stored in the kafka
+// topics of the listener.
+type ReplayConfig struct {
+	// Range is the offset range replayed from all the topic partitions when no
+	// partitions are provided. Defaults to all the messages in the topics.
+	Range kafka.OffsetRange
+	// Partitions limits the replay to the partitions provided, using their own
+	// offset range. They apply to the matching partition of every topic.
+	Partitions []kafka.PartitionRange
+	// Tables limits the replay to the events of the tables provided. Tables
+	// should be schema qualified. If no schema is provided, the public schema
+	// will be assumed. Wildcards "*" are supported.
+	Tables []string
+	// Actions limits the replay to the events with the actions provided. One
+	// of insert, update, delete, truncate.
+	Actions []string
+}
+
+// replayActions maps the supported replay actions to the WAL data actions
+var replayActions = map[string]string{
+	"insert":   "I",
+	"update":   "U",
+	"delete":   "D",
+	"truncate": "T",
+}
+
+var errUnsupportedReplayAction = errors.New("unsupported replay action")
+
+// Replay will read the configured range of the kafka listener topics and send
+// the events through the configured processors. It doesn't commit the offsets
+// of the listener consumer group, so that the targets can be backfilled
+// without affecting the running pgstream consumers. This call is blocking
+// until the end of the range is reached.
+func Replay(ctx context.Context, logger loglib.Logger, config *Config, replayConfig *ReplayConfig, instrumentation *otel.Instrumentation) error {
+	if config.Listener.Kafka == nil {
+		return errors.New("replay requires a kafka source")
+	}
+
+	if err := config.IsValid(); err != nil {
+		return fmt.Errorf("incompatible configuration: %w", err)
+	}
+
+	if err := replayConfig.IsValid(); err != nil {
+		return fmt.Errorf("invalid replay configuration: %w", err)
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	var kafkaReader kafka.MessageReader
+	kafkaReader, err := kafka.NewRangeReader(ctx, kafka.RangeReaderConfig{
+		Conn:         config.Listener.Kafka.Reader.Conn,
+		Topics:       config.Listener.Kafka.Reader.Topics,
+		TopicPattern: config.Listener.Kafka.Reader.TopicPattern,
+		Range:        replayConfig.Range,
+		Partitions:   replayConfig.Partitions,
+	}, logger)
+	if err != nil {
+		return fmt.Errorf("error setting up kafka range reader: %w", err)
+	}
+	defer kafkaReader.Close()
+
+	if instrumentation.IsEnabled() {
+		kafkaReader, err = kafkainstrumentation.NewReader(kafkaReader, instrumentation)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Dead letter queue
+
+	deadLetterQueue, err := newDeadLetterQueue(ctx, config.DeadLetterQueue, logger)
+	if err != nil {
+		return err
+	}
+	if deadLetterQueue != nil {
+		defer deadLetterQueue.Close()
+	}
+
+	// Processor
+
+	// no checkpoint is provided, since the replayed offsets must not be
+	// committed
+	processor, closer, err := newProcessor(ctx, logger, config, nil, processorTypeReplication, instrumentation, deadLetterQueue)
+	defer closer()
+	if err != nil {
+		return err
+	}
+
+	processor, err = addReplayFilters(processor, replayConfig, logger)
+	if err != nil {
+		return err
+	}
+
+	// Listener
+
+	opts, err := newKafkaListenerOptions(config.Listener.Kafka, logger, deadLetterQueue)
+	if err != nil {
+		return err
+	}
+	listener, err := kafkalistener.NewWALReader(kafkaReader, processor.ProcessWALEvent, opts...)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	eg.Go(func() error {
+		defer logger.Info("stopping replay listener...")
+		logger.Info("starting replay listener...")
+		return listener.Listen(ctx)
+	})
+
+	if err := eg.Wait(); err != nil {
+		if !errors.Is(err, context.Canceled) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *ReplayConfig) IsValid() error {
+	for _, action := range c.Actions {
+		if _, found := replayActions[action]; !found {
+			return fmt.Errorf("%w: %s", errUnsupportedReplayAction, action)
+		}
+	}
+
+	if err := c.Range.IsValid(); err != nil {
+		return err
+	}
+	for _, p := range c.Partitions {
+		if err := p.Range.IsValid(); err != nil {
+			return fmt.Errorf("partition %d: %w", p.Partition, err)
+		}
+	}
+	return nil
+}
+
+func addReplayFilters(p processor.Processor, replayConfig *ReplayConfig, logger loglib.Logger) (processor.Processor, error) {
+	if len(replayConfig.Actions) > 0 {
+		p = newActionFilter(p, replayConfig.Actions)
+	}
+
+	if len(replayConfig.Tables) > 0 {
+		var err error
+		p, err = filter.New(p, &filter.Config{IncludeTables: replayConfig.Tables},
+			filter.WithLogger(logger),
+			// the schema log events are required to replay the DDL changes
+			filter.WithDefaultIncludeTables([]string{schemalog.SchemaName + "." + schemalog.TableName}))
+		if err != nil {
+			return nil, fmt.Errorf("error creating replay table filter: %w", err)
+		}
+	}
+
+	return p, nil
+}
+
+// actionFilter is a processor wrapper that skips the row events with actions
+// other than the ones provided. Transaction boundaries, logical messages and
+// schema log events are always processed.
+type actionFilter struct {
+	processor.Processor
+	actions map[string]struct{}
+}
+
+func newActionFilter(p processor.Processor, actions []string) *actionFilter {
+	f := &actionFilter{
+		Processor: p,
+		actions:   make(map[string]struct{}, len(actions)),
+	}
+	for _, action := range actions {
+		f.actions[replayActions[action]] = struct{}{}
+	}
+	return f
+}
+
+func (f *actionFilter) ProcessWALEvent(ctx context.Context, event *wal.Event) error {
+	if event.Data != nil && !event.Data.IsTransactionBoundary() && !event.Data.IsLogicalMessage() && !processor.IsSchemaLogEvent(event.Data) {
+		if _, found := f.actions[event.Data.Action]; !found {
+			return nil
+		}
+	}
+	return f.Processor.ProcessWALEvent(ctx, event)
+}
@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/kafka"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/mocks"
+)
+
+func TestReplayConfig_IsValid(t *testing.T) {
+	t.Parallel()
+
+	offset := int64(1)
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		config ReplayConfig
+
+		wantErr error
+	}{
+		{
+			name: "ok",
+			config: ReplayConfig{
+				Range: kafka.OffsetRange{
+					Start: kafka.RangeBound{Offset: &offset},
+					End:   kafka.RangeBound{Timestamp: &now},
+				},
+				Actions: []string{"insert", "delete"},
+			},
+
+			wantErr: nil,
+		},
+		{
+			name: "error - unsupported action",
+			config: ReplayConfig{
+				Actions: []string{"insert", "I"},
+			},
+
+			wantErr: errUnsupportedReplayAction,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.config.IsValid()
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestAddReplayFilters(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testEvent := func(schema, table, action string) *wal.Event {
+		return &wal.Event{
+			Data: &wal.Data{
+				Action: action,
+				Schema: schema,
+				Table:  table,
+			},
+		}
+	}
+
+	events := []*wal.Event{
+		testEvent("", "", "B"),
+		testEvent("public", "users", "I"),
+		testEvent("public", "users", "D"),
+		testEvent("public", "orders", "D"),
+		testEvent(schemalog.SchemaName, schemalog.TableName, "I"),
+		testEvent("", "", "C"),
+	}
+
+	tests := []struct {
+		name         string
+		replayConfig *ReplayConfig
+
+		wantEvents []*wal.Event
+	}{
+		{
+			name:         "no filters",
+			replayConfig: &ReplayConfig{},
+
+			wantEvents: events,
+		},
+		{
+			name: "tables and actions",
+			replayConfig: &ReplayConfig{
+				Tables:  []string{"users"},
+				Actions: []string{"delete"},
+			},
+
+			wantEvents: []*wal.Event{
+				testEvent("", "", "B"),
+				testEvent("public", "users", "D"),
+				testEvent(schemalog.SchemaName, schemalog.TableName, "I"),
+				testEvent("", "", "C"),
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			processedEvents := []*wal.Event{}
+			processor := &mocks.Processor{
+				ProcessWALEventFn: func(ctx context.Context, event *wal.Event) error {
+					processedEvents = append(processedEvents, event)
+					return nil
+				},
+			}
+
+			p, err := addReplayFilters(processor, tc.replayConfig, loglib.NewNoopLogger())
+			require.NoError(t, err)
+
+			for _, event := range events {
+				require.NoError(t, p.ProcessWALEvent(ctx, event))
+			}
+			require.Equal(t, tc.wantEvents, processedEvents)
+		})
+	}
+}
@@ -7,18 +7,23 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/xataio/pgstream/pkg/blobstore"
 	"github.com/xataio/pgstream/pkg/kafka"
 	kafkainstrumentation "github.com/xataio/pgstream/pkg/kafka/instrumentation"
 	loglib "github.com/xataio/pgstream/pkg/log"
 	"github.com/xataio/pgstream/pkg/otel"
 	"github.com/xataio/pgstream/pkg/wal/checkpointer"
+	filecheckpoint "github.com/xataio/pgstream/pkg/wal/checkpointer/file"
 	kafkacheckpoint "github.com/xataio/pgstream/pkg/wal/checkpointer/kafka"
 	pgcheckpoint "github.com/xataio/pgstream/pkg/wal/checkpointer/postgres"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
 	"github.com/xataio/pgstream/pkg/wal/listener"
+	filelistener "github.com/xataio/pgstream/pkg/wal/listener/file"
 	kafkalistener "github.com/xataio/pgstream/pkg/wal/listener/kafka"
 	pglistener "github.com/xataio/pgstream/pkg/wal/listener/postgres"
 	snapshotbuilder "github.com/xataio/pgstream/pkg/wal/listener/snapshot/builder"
 	"github.com/xataio/pgstream/pkg/wal/processor"
+	"github.com/xataio/pgstream/pkg/wal/processor/outbox"
 	"github.com/xataio/pgstream/pkg/wal/replication"
 	replicationinstrumentation "github.com/xataio/pgstream/pkg/wal/replication/instrumentation"
 	pgreplication "github.com/xataio/pgstream/pkg/wal/replication/postgres"
@@ -75,6 +80,8 @@ func Run(ctx context.Context, logger loglib.Logger, config *Config, instrumentat
 	// Checkpointer
 
 	var checkpoint checkpointer.Checkpoint
+	var fileStartPosition *filelistener.Position
+	var claimCheckCleaner *kafkalistener.ClaimCheckCleaner
 	switch {
 	case config.Listener.Kafka != nil:
 		kafkaCheckpointer, err := kafkacheckpoint.New(ctx,
@@ -87,15 +94,51 @@ func Run(ctx context.Context, logger loglib.Logger, config *Config, instrumentat
 		defer kafkaCheckpointer.Close()
 		checkpoint = kafkaCheckpointer.CommitOffsets
 
+		// the claim check blobs can only be deleted once the offsets of their
+		// messages have been committed
+		if config.Listener.Kafka.DeleteClaimChecks {
+			store, err := blobstore.NewStore(config.Listener.Kafka.BlobStore)
+			if err != nil {
+				return fmt.Errorf("error setting up claim check cleaner blob store: %w", err)
+			}
+			claimCheckCleaner = kafkalistener.NewClaimCheckCleaner(store, kafkalistener.WithClaimCheckCleanerLogger(logger))
+			checkpoint = claimCheckCleaner.WrapCheckpoint(checkpoint)
+		}
+
 	case config.Listener.Postgres != nil:
 		pgCheckpointer := pgcheckpoint.New(replicationHandler)
 		defer pgCheckpointer.Close()
 		checkpoint = pgCheckpointer.SyncLSN
+
+	case config.Listener.File != nil && config.Listener.File.Checkpointer.Path != "":
+		fileCheckpointer, err := filecheckpoint.New(
+			config.Listener.File.Checkpointer,
+			filecheckpoint.WithLogger(logger))
+		if err != nil {
+			return fmt.Errorf("error setting up file checkpointer: %w", err)
+		}
+		defer fileCheckpointer.Close()
+		checkpoint = fileCheckpointer.CommitPositions
+
+		fileStartPosition, err = fileCheckpointer.LastPosition()
+		if err != nil {
+			return fmt.Errorf("error retrieving file checkpoint: %w", err)
+		}
+	}
+
+	// Dead letter queue
+
+	deadLetterQueue, err := newDeadLetterQueue(ctx, config.DeadLetterQueue, logger)
+	if err != nil {
+		return err
+	}
+	if deadLetterQueue != nil {
+		defer deadLetterQueue.Close()
 	}
 
 	// Processor
 
-	processor, closer, err := newProcessor(ctx, logger, config, checkpoint, processorTypeReplication, instrumentation)
+	processor, closer, err := newProcessor(ctx, logger, config, checkpoint, processorTypeReplication, instrumentation, deadLetterQueue)
 	defer closer()
 	if err != nil {
 		return err
@@ -115,7 +158,7 @@ func Run(ctx context.Context, logger loglib.Logger, config *Config, instrumentat
 			// use a dedicated processor for the snapshot phase, to be able to
 			// close it and make sure the snapshot is complete before starting
 			// to process the WAL replication events.
-			snapshotProcessor, snapshotCloser, err := newProcessor(ctx, logger, config, checkpoint, processorTypeSnapshot, instrumentation)
+			snapshotProcessor, snapshotCloser, err := newProcessor(ctx, logger, config, checkpoint, processorTypeSnapshot, instrumentation, deadLetterQueue)
 			defer snapshotCloser()
 			if err != nil {
 				return fmt.Errorf("error creating snapshot processor: %w", err)
@@ -123,7 +166,7 @@ func Run(ctx context.Context, logger loglib.Logger, config *Config, instrumentat
 
 			snapshotGenerator, err := snapshotbuilder.NewSnapshotGenerator(
 				ctx,
-				config.Listener.Postgres.Snapshot,
+				config.snapshotListenerConfig(config.Listener.Postgres.Snapshot),
 				snapshotProcessor,
 				logger,
 				instrumentation)
@@ -140,10 +183,35 @@ func Run(ctx context.Context, logger loglib.Logger, config *Config, instrumentat
 			opts...)
 	case config.Listener.Kafka != nil:
 		logger.Info("kafka listener configured")
+		opts, err := newKafkaListenerOptions(config.Listener.Kafka, logger, deadLetterQueue)
+		if err != nil {
+			return err
+		}
+		if claimCheckCleaner != nil {
+			opts = append(opts, kafkalistener.WithClaimCheckCleaner(claimCheckCleaner))
+		}
 		listener, err = kafkalistener.NewWALReader(
 			kafkaReader,
 			processor.ProcessWALEvent,
-			kafkalistener.WithLogger(logger))
+			opts...)
+		if err != nil {
+			return err
+		}
+	case config.Listener.File != nil:
+		logger.Info("file listener configured")
+		opts := []filelistener.Option{
+			filelistener.WithLogger(logger),
+		}
+		if fileStartPosition != nil {
+			opts = append(opts, filelistener.WithStartPosition(fileStartPosition))
+		}
+		if deadLetterQueue != nil {
+			opts = append(opts, filelistener.WithDeadLetterQueue(deadLetterQueue))
+		}
+		listener, err = filelistener.NewReader(
+			&config.Listener.File.Reader,
+			processor.ProcessWALEvent,
+			opts...)
 		if err != nil {
 			return err
 		}
@@ -171,20 +239,45 @@ var noopCloser func() error = func() error {
 	return nil
 }
 
-func newProcessor(ctx context.Context, logger loglib.Logger, config *Config, checkpoint checkpointer.Checkpoint, processorType processorType, instrumentation *otel.Instrumentation) (processor.Processor, closerFn, error) {
-	processor, err := buildProcessor(ctx, logger, &config.Processor, checkpoint, processorType, instrumentation)
+func newProcessor(ctx context.Context, logger loglib.Logger, config *Config, checkpoint checkpointer.Checkpoint, processorType processorType, instrumentation *otel.Instrumentation, deadLetterQueue dlq.Queue) (processor.Processor, closerFn, error) {
+	// the outbox rows can only be deleted once their replication events have
+	// been checkpointed
+	var outboxCleaner *outbox.Cleaner
+	if config.Processor.Outbox != nil && config.Processor.Outbox.DeleteProcessedRows && processorType == processorTypeReplication {
+		var err error
+		outboxCleaner, err = outbox.NewCleaner(ctx, config.SourcePostgresURL(), outbox.WithCleanerLogger(logger))
+		if err != nil {
+			return nil, noopCloser, err
+		}
+		checkpoint = outboxCleaner.WrapCheckpoint(checkpoint)
+	}
+	closeOutboxCleaner := func() error {
+		if outboxCleaner == nil {
+			return nil
+		}
+		return outboxCleaner.Close()
+	}
+
+	processor, targetsCloser, err := buildProcessor(ctx, logger, config, checkpoint, processorType, instrumentation, outboxCleaner, deadLetterQueue)
 	if err != nil {
+		closeOutboxCleaner()
 		return nil, noopCloser, err
 	}
 	var closerAgg closerAggregator
 	var closer closerFn
-	processor, closer, err = addProcessorModifiers(ctx, config, logger, processor, instrumentation)
+	processor, closer, err = addProcessorModifiers(ctx, config, logger, processor, instrumentation, deadLetterQueue)
 	if err != nil {
+		processor.Close()
+		targetsCloser()
+		closeOutboxCleaner()
 		return nil, noopCloser, err
 	}
 
 	closerAgg.addCloserFn(closer)
 	closerAgg.addCloserFn(processor.Close)
+	closerAgg.addCloserFn(targetsCloser)
+	// closed last, since closing the processor can trigger a final checkpoint
+	closerAgg.addCloserFn(closeOutboxCleaner)
 
 	return processor, closerAgg.close, nil
 }
@@ -25,16 +25,27 @@ func Snapshot(ctx context.Context, logger loglib.Logger, config *Config, instrum
 
 	eg, ctx := errgroup.WithContext(ctx)
 
+	// Dead letter queue
+
+	deadLetterQueue, err := newDeadLetterQueue(ctx, config.DeadLetterQueue, logger)
+	if err != nil {
+		return err
+	}
+	if deadLetterQueue != nil {
+		defer deadLetterQueue.Close()
+	}
+
 	// Processor
 
-	processor, err := buildProcessor(ctx, logger, &config.Processor, nil, processorTypeSnapshot, instrumentation)
+	processor, targetsCloser, err := buildProcessor(ctx, logger, config, nil, processorTypeSnapshot, instrumentation, nil, deadLetterQueue)
 	if err != nil {
 		return err
 	}
+	defer targetsCloser()
 	defer processor.Close()
 
 	var closer closerFn
-	processor, closer, err = addProcessorModifiers(ctx, config, logger, processor, instrumentation)
+	processor, closer, err = addProcessorModifiers(ctx, config, logger, processor, instrumentation, deadLetterQueue)
 	if err != nil {
 		return err
 	}
@@ -44,7 +55,7 @@ func Snapshot(ctx context.Context, logger loglib.Logger, config *Config, instrum
 
 	snapshotGenerator, err := snapshotbuilder.NewSnapshotGenerator(
 		ctx,
-		config.Listener.Snapshot,
+		config.snapshotListenerConfig(config.Listener.Snapshot),
 		processor,
 		logger,
 		instrumentation)
@@ -36,8 +36,6 @@ type migrator interface {
 	Close() (error, error)
 }
 
-const wal2jsonPlugin = "wal2json"
-
 const (
 	noPgstreamSchemaErrMsg         = "pgstream schema does not exist in the configured postgres database"
 	noPgstreamSchemaLogTableErrMsg = "pgstream schema_log table does not exist in the configured postgres database"
@@ -72,7 +70,7 @@ func (s *StatusChecker) Status(ctx context.Context, config *Config) (*Status, er
 		return nil, fmt.Errorf("checking source status: %w", err)
 	}
 
-	initStatus, err := s.initStatus(ctx, config.SourcePostgresURL(), config.PostgresReplicationSlot())
+	initStatus, err := s.initStatus(ctx, config.SourcePostgresURL(), config.PostgresReplicationSlot(), config.PostgresReplicationPlugin())
 	if err != nil {
 		return nil, fmt.Errorf("checking init status: %w", err)
 	}
@@ -140,7 +138,7 @@ func (s *StatusChecker) sourceStatus(ctx context.Context, config *Config) (*Sour
 // initStatus checks the initialisation status of pgstream in the provided
 // postgres database. If the replicationSlotName is empty, it will check against
 // the default (pgstream_<database>_slot).
-func (s *StatusChecker) initStatus(ctx context.Context, pgURL, replicationSlotName string) (*InitStatus, error) {
+func (s *StatusChecker) initStatus(ctx context.Context, pgURL, replicationSlotName, replicationPlugin string) (*InitStatus, error) {
 	if pgURL == "" {
 		return nil, errMissingPostgresURL
 	}
@@ -158,7 +156,7 @@ func (s *StatusChecker) initStatus(ctx context.Context, pgURL, replicationSlotNa
 		return nil, fmt.Errorf("validating pgstream migrations: %w", err)
 	}
 
-	initStatus.ReplicationSlot, err = s.validateReplicationSlotStatus(ctx, pgURL, replicationSlotName)
+	initStatus.ReplicationSlot, err = s.validateReplicationSlotStatus(ctx, pgURL, replicationSlotName, replicationPlugin)
 	if err != nil {
 		return nil, fmt.Errorf("validating replication slot: %w", err)
 	}
@@ -302,8 +300,9 @@ func (s *StatusChecker) validateSchemaStatus(ctx context.Context, pgURL string)
 }
 
 // validateReplicationSlotStatus checks if the replication slot exists in the
-// configured postgres database, and it's correctly configured.
-func (s *StatusChecker) validateReplicationSlotStatus(ctx context.Context, pgURL, replicationSlotName string) (*ReplicationSlotStatus, error) {
+// configured postgres database, and it's correctly configured with the expected
+// output plugin.
+func (s *StatusChecker) validateReplicationSlotStatus(ctx context.Context, pgURL, replicationSlotName, replicationPlugin string) (*ReplicationSlotStatus, error) {
 	if replicationSlotName == "" {
 		var err error
 		replicationSlotName, err = getReplicationSlotName(pgURL)
@@ -345,8 +344,8 @@ func (s *StatusChecker) validateReplicationSlotStatus(ctx context.Context, pgURL
 			return []string{fmt.Sprintf("replication slot %s does not exist in the configured database", replicationSlotName)}
 		}
 
-		if plugin != wal2jsonPlugin {
-			return []string{fmt.Sprintf("replication slot %s is not using the wal2json plugin", replicationSlotName)}
+		if plugin != replicationPlugin {
+			return []string{fmt.Sprintf("replication slot %s is not using the %s plugin", replicationSlotName, replicationPlugin)}
 		}
 
 		return nil
@@ -0,0 +1,255 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package avro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	avrolib "github.com/hamba/avro/v2"
+	"github.com/rs/xid"
+	"github.com/xataio/pgstream/internal/json"
+	"github.com/xataio/pgstream/pkg/schemaregistry"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// Deserialiser deserialises the wal data serialised by the avro Serialiser,
+// retrieving the schemas from the schema registry using the id they're framed
+// with.
+type Deserialiser struct {
+	registry schemaGetter
+
+	mutex   sync.RWMutex
+	schemas map[int]*recordSchema
+}
+
+type schemaGetter interface {
+	GetSchemaByID(ctx context.Context, id int) (*schemaregistry.Schema, error)
+}
+
+var (
+	errUnsupportedSchemaType = errors.New("unsupported schema type")
+	errInvalidRecord         = errors.New("invalid avro record")
+)
+
+func NewDeserialiser(registry schemaGetter) *Deserialiser {
+	return &Deserialiser{
+		registry: registry,
+		schemas:  map[int]*recordSchema{},
+	}
+}
+
+// Deserialise returns the wal data for the avro message on input, in the
+// schema registry wire format. It can be called concurrently.
+func (d *Deserialiser) Deserialise(ctx context.Context, msg []byte) (*wal.Data, error) {
+	id, payload, err := schemaregistry.DecodeWireFormat(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := d.getSchema(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	record := map[string]any{}
+	if err := avrolib.Unmarshal(schema.schema, payload, &record); err != nil {
+		return nil, fmt.Errorf("avro deserialising %s event: %w", schema.subject, err)
+	}
+
+	if schema.isEvent {
+		return decodeEvent(record)
+	}
+	return schema.decodeRow(record)
+}
+
+func (d *Deserialiser) getSchema(ctx context.Context, id int) (*recordSchema, error) {
+	d.mutex.RLock()
+	schema, found := d.schemas[id]
+	d.mutex.RUnlock()
+	if found {
+		return schema, nil
+	}
+
+	registrySchema, err := d.registry.GetSchemaByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if registrySchema.SchemaType != schemaregistry.SchemaTypeAvro {
+		return nil, fmt.Errorf("%w: schema %d is %s", errUnsupportedSchemaType, id, registrySchema.SchemaType)
+	}
+	if schema, err = newRecordSchema(registrySchema.Schema); err != nil {
+		return nil, fmt.Errorf("schema %d: %w", id, err)
+	}
+	schema.id = id
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.schemas[id] = schema
+	return schema, nil
+}
+
+// decodeRow returns the wal data for the table row avro record on input.
+func (s *recordSchema) decodeRow(record map[string]any) (*wal.Data, error) {
+	data, err := decodeHeader(record)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Columns, err = s.decodeRowColumns(record["columns"], record["omitted_columns"]); err != nil {
+		return nil, err
+	}
+	if data.Identity, err = s.decodeRowColumns(record["identity"], record["omitted_identity"]); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (s *recordSchema) decodeRowColumns(value, omittedValue any) ([]wal.Column, error) {
+	if value == nil {
+		return nil, nil
+	}
+	row, ok := unionValue(value, s.rowName).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected row type %T", errInvalidRecord, value)
+	}
+
+	omitted := map[string]struct{}{}
+	for _, name := range toStrings(omittedValue) {
+		omitted[name] = struct{}{}
+	}
+
+	columns := make([]wal.Column, 0, len(s.columns))
+	for _, col := range s.columns {
+		if _, found := omitted[col.name]; found {
+			continue
+		}
+		columns = append(columns, wal.Column{
+			ID:    col.id,
+			Name:  col.name,
+			Type:  col.pgType,
+			Value: fromAvroValue(row[col.field]),
+		})
+	}
+	return columns, nil
+}
+
+// decodeEvent returns the wal data for the generic event avro record on input.
+func decodeEvent(record map[string]any) (*wal.Data, error) {
+	data, err := decodeHeader(record)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Columns, err = decodeEventColumns(record["columns"]); err != nil {
+		return nil, err
+	}
+	if data.Identity, err = decodeEventColumns(record["identity"]); err != nil {
+		return nil, err
+	}
+	data.Transactional, _ = record["transactional"].(bool)
+	data.Prefix, _ = record["prefix"].(string)
+	data.Content, _ = record["content"].(string)
+
+	return data, nil
+}
+
+func decodeEventColumns(value any) ([]wal.Column, error) {
+	if value == nil {
+		return nil, nil
+	}
+	items, ok := unionValue(value, "array").([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected columns type %T", errInvalidRecord, value)
+	}
+
+	columns := make([]wal.Column, 0, len(items))
+	for _, item := range items {
+		col, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: unexpected column type %T", errInvalidRecord, item)
+		}
+		walColumn := wal.Column{}
+		walColumn.ID, _ = col["id"].(string)
+		walColumn.Name, _ = col["name"].(string)
+		walColumn.Type, _ = col["type"].(string)
+		if value, ok := col["value"].(string); ok {
+			if err := json.Unmarshal([]byte(value), &walColumn.Value); err != nil {
+				return nil, fmt.Errorf("unmarshaling column %s value: %w", walColumn.Name, err)
+			}
+		}
+		columns = append(columns, walColumn)
+	}
+	return columns, nil
+}
+
+func decodeHeader(record map[string]any) (*wal.Data, error) {
+	data := &wal.Data{}
+	data.Action, _ = record["action"].(string)
+	data.Timestamp, _ = record["timestamp"].(string)
+	data.LSN, _ = record["lsn"].(string)
+	data.Schema, _ = record["schema"].(string)
+	data.Table, _ = record["table"].(string)
+	if txID, ok := record["xid"].(int64); ok {
+		data.XID = uint32(txID)
+	}
+
+	if metadata, ok := record["metadata"].(map[string]any); ok {
+		if schemaID, _ := metadata["schema_id"].(string); schemaID != "" {
+			id, err := xid.FromString(schemaID)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid schema id %q: %w", errInvalidRecord, schemaID, err)
+			}
+			data.Metadata.SchemaID = id
+		}
+		data.Metadata.TablePgstreamID, _ = metadata["table_pgstream_id"].(string)
+		data.Metadata.InternalColIDs = toStrings(metadata["id_col_pgstream_id"])
+		data.Metadata.InternalColVersion, _ = metadata["version_col_pgstream_id"].(string)
+	}
+
+	if record["transaction"] != nil {
+		tx, ok := unionValue(record["transaction"], namespace+".transaction").(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: unexpected transaction type %T", errInvalidRecord, record["transaction"])
+		}
+		data.Transaction = &wal.Transaction{}
+		if txID, ok := tx["xid"].(int64); ok {
+			data.Transaction.XID = uint32(txID)
+		}
+		data.Transaction.CommitLSN, _ = tx["commit_lsn"].(string)
+		data.Transaction.CommitTimestamp, _ = tx["commit_timestamp"].(string)
+		if sequence, ok := tx["sequence"].(int64); ok {
+			data.Transaction.Sequence = uint64(sequence)
+		}
+	}
+
+	return data, nil
+}
+
+// unionValue returns the value of the union type on input. Non null union
+// values are decoded as a map with the type name as key.
+func unionValue(value any, typeName string) any {
+	if union, ok := value.(map[string]any); ok {
+		if v, found := union[typeName]; found {
+			return v
+		}
+	}
+	return nil
+}
+
+func toStrings(value any) []string {
+	items, _ := value.([]any)
+	if len(items) == 0 {
+		return nil
+	}
+	strs := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
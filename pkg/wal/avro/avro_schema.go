@@ -0,0 +1,374 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package avro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	avrolib "github.com/hamba/avro/v2"
+)
+
+// recordSchema is an avro record schema used to serialise wal data. Table
+// schemas contain a typed field per table column, while the generic event
+// schema is used for the events that don't relate to a known table (schema
+// log, transaction boundaries, logical decoding messages...), with the column
+// values serialised as JSON.
+type recordSchema struct {
+	// id is the schema registry id of the schema
+	id         int
+	definition string
+	schema     *avrolib.RecordSchema
+	// subject is the schema registry subject the schema is registered under,
+	// following the record name strategy.
+	subject string
+	isEvent bool
+
+	// rowName is the full name of the table row record
+	rowName       string
+	columns       []rowColumn
+	columnsByName map[string]int
+}
+
+// rowColumn maps a table column to its row record field. The column name,
+// type and pgstream id are stored as custom field properties, so that the
+// events can be deserialised from the registered schema.
+type rowColumn struct {
+	field    string
+	name     string
+	pgType   string
+	id       string
+	avroType avrolib.Type
+}
+
+// columnDefinition describes a table column for the schema generation.
+type columnDefinition struct {
+	name   string
+	pgType string
+	id     string
+}
+
+const (
+	namespace       = "pgstream"
+	eventSchemaName = namespace + ".event"
+	rowRecordName   = "row"
+
+	propColumnName = "pgstream.name"
+	propColumnType = "pgstream.type"
+	propColumnID   = "pgstream.id"
+)
+
+var (
+	errUnsupportedSchema = errors.New("unsupported avro schema")
+	invalidNameChars     = regexp.MustCompile(`[^A-Za-z0-9_]`)
+	typeModifiers        = regexp.MustCompile(`\(.*?\)`)
+)
+
+// avro schema definition types, used to generate the schemas with their
+// custom properties, which are not kept by the parsed schemas String method.
+type recordDefinition struct {
+	Type      string            `json:"type"`
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace,omitempty"`
+	Fields    []fieldDefinition `json:"fields"`
+}
+
+type fieldDefinition struct {
+	Name    string          `json:"name"`
+	Type    any             `json:"type"`
+	Default json.RawMessage `json:"default,omitempty"`
+
+	ColumnName string `json:"pgstream.name,omitempty"`
+	ColumnType string `json:"pgstream.type,omitempty"`
+	ColumnID   string `json:"pgstream.id,omitempty"`
+}
+
+type arrayDefinition struct {
+	Type  string `json:"type"`
+	Items any    `json:"items"`
+}
+
+var (
+	defaultNull        = json.RawMessage("null")
+	defaultZero        = json.RawMessage("0")
+	defaultEmptyString = json.RawMessage(`""`)
+	defaultEmptyArray  = json.RawMessage("[]")
+	defaultFalse       = json.RawMessage("false")
+)
+
+// tableSchemaDefinition returns the avro schema definition for the table on
+// input. The record is named after the schema and table, and the row columns
+// are all optional, since the wal events might not contain all of them (i.e.
+// unchanged toasted values or replica identity).
+func tableSchemaDefinition(schemaName, tableName string, columns []columnDefinition) (string, error) {
+	recordNamespace := namespace + "." + avroName(schemaName)
+	recordName := avroName(tableName)
+
+	rowFields := make([]fieldDefinition, 0, len(columns))
+	fieldNames := make(map[string]struct{}, len(columns))
+	for _, col := range columns {
+		field := uniqueName(avroName(col.name), fieldNames)
+		rowFields = append(rowFields, fieldDefinition{
+			Name:       field,
+			Type:       []any{"null", pgTypeToAvro(col.pgType)},
+			Default:    defaultNull,
+			ColumnName: col.name,
+			ColumnType: col.pgType,
+			ColumnID:   col.id,
+		})
+	}
+
+	row := recordDefinition{
+		Type:      "record",
+		Name:      rowRecordName,
+		Namespace: recordNamespace + "." + recordName,
+		Fields:    rowFields,
+	}
+
+	fields := headerFields()
+	fields = append(fields,
+		fieldDefinition{Name: "columns", Type: []any{"null", row}, Default: defaultNull},
+		fieldDefinition{Name: "identity", Type: []any{"null", row.Namespace + "." + row.Name}, Default: defaultNull},
+		fieldDefinition{Name: "omitted_columns", Type: arrayDefinition{Type: "array", Items: "string"}, Default: defaultEmptyArray},
+		fieldDefinition{Name: "omitted_identity", Type: arrayDefinition{Type: "array", Items: "string"}, Default: defaultEmptyArray},
+	)
+	fields = append(fields, metadataFields()...)
+
+	return marshalDefinition(recordDefinition{
+		Type:      "record",
+		Name:      recordName,
+		Namespace: recordNamespace,
+		Fields:    fields,
+	})
+}
+
+// eventSchemaDefinition returns the avro schema definition for the generic wal
+// event, which supports any wal data.
+func eventSchemaDefinition() (string, error) {
+	column := recordDefinition{
+		Type: "record",
+		Name: "column",
+		Fields: []fieldDefinition{
+			{Name: "id", Type: "string", Default: defaultEmptyString},
+			{Name: "name", Type: "string"},
+			{Name: "type", Type: "string", Default: defaultEmptyString},
+			// JSON serialised column value
+			{Name: "value", Type: []any{"null", "string"}, Default: defaultNull},
+		},
+	}
+
+	fields := headerFields()
+	fields = append(fields,
+		fieldDefinition{Name: "columns", Type: []any{"null", arrayDefinition{Type: "array", Items: column}}, Default: defaultNull},
+		fieldDefinition{Name: "identity", Type: []any{"null", arrayDefinition{Type: "array", Items: column.Name}}, Default: defaultNull},
+	)
+	fields = append(fields, metadataFields()...)
+	fields = append(fields,
+		fieldDefinition{Name: "transactional", Type: "boolean", Default: defaultFalse},
+		fieldDefinition{Name: "prefix", Type: "string", Default: defaultEmptyString},
+		fieldDefinition{Name: "content", Type: "string", Default: defaultEmptyString},
+	)
+
+	return marshalDefinition(recordDefinition{
+		Type:      "record",
+		Name:      "event",
+		Namespace: namespace,
+		Fields:    fields,
+	})
+}
+
+func headerFields() []fieldDefinition {
+	return []fieldDefinition{
+		{Name: "action", Type: "string"},
+		{Name: "timestamp", Type: "string", Default: defaultEmptyString},
+		{Name: "lsn", Type: "string", Default: defaultEmptyString},
+		{Name: "xid", Type: "long", Default: defaultZero},
+		{Name: "schema", Type: "string", Default: defaultEmptyString},
+		{Name: "table", Type: "string", Default: defaultEmptyString},
+	}
+}
+
+func metadataFields() []fieldDefinition {
+	metadata := recordDefinition{
+		Type:      "record",
+		Name:      "metadata",
+		Namespace: namespace,
+		Fields: []fieldDefinition{
+			{Name: "schema_id", Type: "string", Default: defaultEmptyString},
+			{Name: "table_pgstream_id", Type: "string", Default: defaultEmptyString},
+			{Name: "id_col_pgstream_id", Type: arrayDefinition{Type: "array", Items: "string"}, Default: defaultEmptyArray},
+			{Name: "version_col_pgstream_id", Type: "string", Default: defaultEmptyString},
+		},
+	}
+	transaction := recordDefinition{
+		Type:      "record",
+		Name:      "transaction",
+		Namespace: namespace,
+		Fields: []fieldDefinition{
+			{Name: "xid", Type: "long", Default: defaultZero},
+			{Name: "commit_lsn", Type: "string", Default: defaultEmptyString},
+			{Name: "commit_timestamp", Type: "string", Default: defaultEmptyString},
+			{Name: "sequence", Type: "long", Default: defaultZero},
+		},
+	}
+	return []fieldDefinition{
+		{Name: "metadata", Type: metadata},
+		{Name: "transaction", Type: []any{"null", transaction}, Default: defaultNull},
+	}
+}
+
+func marshalDefinition(def recordDefinition) (string, error) {
+	b, err := json.Marshal(def)
+	if err != nil {
+		return "", fmt.Errorf("marshaling avro schema definition: %w", err)
+	}
+	return string(b), nil
+}
+
+// newRecordSchema parses the avro schema definition on input. The table
+// columns are retrieved from the row record field properties.
+func newRecordSchema(definition string) (*recordSchema, error) {
+	// use a dedicated cache, since the named types of different versions of
+	// the same table schema would otherwise clash
+	parsed, err := avrolib.ParseWithCache(definition, "", &avrolib.SchemaCache{})
+	if err != nil {
+		return nil, fmt.Errorf("parsing avro schema: %w", err)
+	}
+	record, ok := parsed.(*avrolib.RecordSchema)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not a record", errUnsupportedSchema, parsed.Type())
+	}
+
+	s := &recordSchema{
+		definition:    definition,
+		schema:        record,
+		subject:       record.FullName(),
+		isEvent:       record.FullName() == eventSchemaName,
+		columnsByName: map[string]int{},
+	}
+	if s.isEvent {
+		return s, nil
+	}
+
+	row := rowRecord(record)
+	if row == nil {
+		return nil, fmt.Errorf("%w: %s has no row record", errUnsupportedSchema, record.FullName())
+	}
+	s.rowName = row.FullName()
+	for _, field := range row.Fields() {
+		col := rowColumn{
+			field:    field.Name(),
+			name:     stringProp(field, propColumnName),
+			pgType:   stringProp(field, propColumnType),
+			id:       stringProp(field, propColumnID),
+			avroType: nonNullType(field.Type()).Type(),
+		}
+		if col.name == "" {
+			col.name = col.field
+		}
+		s.columnsByName[col.name] = len(s.columns)
+		s.columns = append(s.columns, col)
+	}
+
+	return s, nil
+}
+
+// hasColumns returns true if all the columns on input are part of the table
+// schema.
+func (s *recordSchema) hasColumns(columns []columnDefinition) bool {
+	for _, col := range columns {
+		if _, found := s.columnsByName[col.name]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+// columnDefinitions returns the definitions of the table schema columns.
+func (s *recordSchema) columnDefinitions() []columnDefinition {
+	defs := make([]columnDefinition, 0, len(s.columns))
+	for _, col := range s.columns {
+		defs = append(defs, columnDefinition{name: col.name, pgType: col.pgType, id: col.id})
+	}
+	return defs
+}
+
+func rowRecord(record *avrolib.RecordSchema) *avrolib.RecordSchema {
+	for _, field := range record.Fields() {
+		if field.Name() != "columns" {
+			continue
+		}
+		row, ok := nonNullType(field.Type()).(*avrolib.RecordSchema)
+		if !ok {
+			return nil
+		}
+		return row
+	}
+	return nil
+}
+
+func nonNullType(schema avrolib.Schema) avrolib.Schema {
+	union, ok := schema.(*avrolib.UnionSchema)
+	if !ok {
+		return schema
+	}
+	for _, t := range union.Types() {
+		if t.Type() != avrolib.Null {
+			return t
+		}
+	}
+	return schema
+}
+
+func stringProp(field *avrolib.Field, name string) string {
+	v, _ := field.Prop(name).(string)
+	return v
+}
+
+// pgTypeToAvro returns the avro type for the postgres type on input. Types
+// without a lossless avro equivalent are represented as strings.
+func pgTypeToAvro(pgType string) avrolib.Type {
+	if strings.HasSuffix(pgType, "]") {
+		return avrolib.String
+	}
+
+	switch strings.TrimSpace(typeModifiers.ReplaceAllString(strings.ToLower(pgType), "")) {
+	case "boolean", "bool":
+		return avrolib.Boolean
+	case "smallint", "int2", "integer", "int", "int4", "smallserial", "serial", "serial2", "serial4":
+		return avrolib.Int
+	case "bigint", "int8", "bigserial", "serial8":
+		return avrolib.Long
+	case "real", "float4":
+		return avrolib.Float
+	case "double precision", "float8":
+		return avrolib.Double
+	default:
+		return avrolib.String
+	}
+}
+
+// avroName returns a valid avro name for the name on input.
+func avroName(name string) string {
+	name = invalidNameChars.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+func uniqueName(name string, names map[string]struct{}) string {
+	unique := name
+	for i := 2; ; i++ {
+		if _, found := names[unique]; !found {
+			break
+		}
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	names[unique] = struct{}{}
+	return unique
+}
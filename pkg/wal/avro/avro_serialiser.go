@@ -0,0 +1,392 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package avro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	avrolib "github.com/hamba/avro/v2"
+	"github.com/xataio/pgstream/internal/json"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/schemaregistry"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+)
+
+// Serialiser serialises wal data into avro, framed with the id of the schema
+// registry schema it was serialised with. Row events are serialised with a
+// schema per table, generated from the schema log and registered whenever it
+// changes. If the table schema log is not known, the schema is derived from
+// the event columns. The rest of events use a generic event schema, as do the
+// row events of the tables whose schema is rejected by the registry as
+// incompatible with the previous versions.
+type Serialiser struct {
+	registry schemaRegistry
+	logger   loglib.Logger
+
+	mutex sync.Mutex
+	// tables contains the table schemas indexed by schema qualified table name.
+	// Tables whose schema was rejected by the registry have a nil schema.
+	tables      map[string]*recordSchema
+	eventSchema *recordSchema
+}
+
+type schemaRegistry interface {
+	RegisterSchema(ctx context.Context, subject string, schema *schemaregistry.Schema) (int, error)
+}
+
+type Option func(*Serialiser)
+
+func NewSerialiser(registry schemaRegistry, opts ...Option) (*Serialiser, error) {
+	definition, err := eventSchemaDefinition()
+	if err != nil {
+		return nil, err
+	}
+	eventSchema, err := newRecordSchema(definition)
+	if err != nil {
+		return nil, err
+	}
+	eventSchema.id = -1
+
+	s := &Serialiser{
+		registry:    registry,
+		logger:      loglib.NewNoopLogger(),
+		tables:      map[string]*recordSchema{},
+		eventSchema: eventSchema,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+func WithLogger(l loglib.Logger) Option {
+	return func(s *Serialiser) {
+		s.logger = loglib.NewLogger(l).WithFields(loglib.Fields{
+			loglib.ModuleField: "avro_serialiser",
+		})
+	}
+}
+
+// Serialise returns the avro serialised wal data on input, in the schema
+// registry wire format. It can be called concurrently.
+func (s *Serialiser) Serialise(ctx context.Context, data *wal.Data) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if processor.IsSchemaLogEvent(data) && data.IsInsert() {
+		if err := s.updateTableSchemas(ctx, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if isRowEvent(data) {
+		msg, err := s.serialiseRow(ctx, data)
+		if err == nil {
+			return msg, nil
+		}
+		if !errors.Is(err, errIncompatibleValue) && !errors.Is(err, schemaregistry.ErrIncompatibleSchema) {
+			return nil, err
+		}
+		s.logger.Warn(err, "event not compatible with the table avro schema, using the generic event schema", loglib.Fields{
+			"schema": data.Schema,
+			"table":  data.Table,
+		})
+	}
+
+	return s.serialise(ctx, s.eventSchema, data)
+}
+
+func (s *Serialiser) serialiseRow(ctx context.Context, data *wal.Data) ([]byte, error) {
+	key := tableKey(data.Schema, data.Table)
+	eventColumns := columnDefinitionsFromEvent(data)
+	table, found := s.tables[key]
+	if found && table == nil {
+		return nil, fmt.Errorf("%w: table %s schema rejected by the registry", schemaregistry.ErrIncompatibleSchema, key)
+	}
+	if !found || !table.hasColumns(eventColumns) {
+		// derive the schema from the event columns, keeping the known ones so
+		// that the new schema remains compatible
+		columns := eventColumns
+		if found {
+			columns = mergeColumnDefinitions(table.columnDefinitions(), eventColumns)
+		}
+		var err error
+		if table, err = s.registerTableSchema(ctx, data.Schema, data.Table, columns); err != nil {
+			if errors.Is(err, schemaregistry.ErrIncompatibleSchema) {
+				s.tables[key] = nil
+			}
+			return nil, err
+		}
+		s.tables[key] = table
+		s.logger.Debug("table avro schema derived from event columns", loglib.Fields{
+			"schema":    data.Schema,
+			"table":     data.Table,
+			"schema_id": table.id,
+		})
+	}
+
+	return s.serialise(ctx, table, data)
+}
+
+func (s *Serialiser) serialise(ctx context.Context, schema *recordSchema, data *wal.Data) ([]byte, error) {
+	if schema.id < 0 {
+		if err := s.register(ctx, schema); err != nil {
+			return nil, err
+		}
+	}
+
+	var record map[string]any
+	var err error
+	if schema.isEvent {
+		record, err = encodeEvent(data)
+	} else {
+		record, err = schema.encodeRow(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := avrolib.Marshal(schema.schema, record)
+	if err != nil {
+		return nil, fmt.Errorf("avro serialising %s event: %w", schema.subject, err)
+	}
+	return schemaregistry.EncodeWireFormat(schema.id, payload), nil
+}
+
+// updateTableSchemas registers the schemas of the tables in the schema log
+// entry on input. Tables whose schema is rejected by the registry as
+// incompatible use the generic event schema until their next schema change.
+func (s *Serialiser) updateTableSchemas(ctx context.Context, data *wal.Data) error {
+	logEntry, err := processor.WalDataToLogEntry(data)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range logEntry.Schema.Tables {
+		columns := make([]columnDefinition, 0, len(table.Columns))
+		for _, col := range table.Columns {
+			columns = append(columns, columnDefinition{name: col.Name, pgType: col.DataType, id: col.PgstreamID})
+		}
+		schema, err := s.registerTableSchema(ctx, logEntry.SchemaName, table.Name, columns)
+		if err != nil && !errors.Is(err, schemaregistry.ErrIncompatibleSchema) {
+			return err
+		}
+		if err != nil {
+			s.logger.Warn(err, "table avro schema rejected by the registry, using the generic event schema", loglib.Fields{
+				"schema": logEntry.SchemaName,
+				"table":  table.Name,
+			})
+		}
+		s.tables[tableKey(logEntry.SchemaName, table.Name)] = schema
+	}
+
+	s.logger.Debug("table avro schemas updated", loglib.Fields{
+		"schema":         logEntry.SchemaName,
+		"schema_version": logEntry.Version,
+	})
+	return nil
+}
+
+func (s *Serialiser) registerTableSchema(ctx context.Context, schemaName, tableName string, columns []columnDefinition) (*recordSchema, error) {
+	definition, err := tableSchemaDefinition(schemaName, tableName, columns)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := newRecordSchema(definition)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.register(ctx, schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (s *Serialiser) register(ctx context.Context, schema *recordSchema) error {
+	id, err := s.registry.RegisterSchema(ctx, schema.subject, &schemaregistry.Schema{
+		Schema:     schema.definition,
+		SchemaType: schemaregistry.SchemaTypeAvro,
+	})
+	if err != nil {
+		return err
+	}
+	schema.id = id
+	return nil
+}
+
+// encodeRow returns the avro record for the table row event on input.
+func (s *recordSchema) encodeRow(data *wal.Data) (map[string]any, error) {
+	record := encodeHeader(data)
+
+	var err error
+	if record["columns"], record["omitted_columns"], err = s.encodeRowColumns(data.Columns); err != nil {
+		return nil, err
+	}
+	if record["identity"], record["omitted_identity"], err = s.encodeRowColumns(data.Identity); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// encodeRowColumns returns the row record for the columns on input, along with
+// the names of the table columns not present in them.
+func (s *recordSchema) encodeRowColumns(columns []wal.Column) (any, []string, error) {
+	if columns == nil {
+		return nil, []string{}, nil
+	}
+
+	row := make(map[string]any, len(s.columns))
+	present := make([]bool, len(s.columns))
+	for _, col := range columns {
+		i, found := s.columnsByName[col.Name]
+		if !found {
+			return nil, nil, fmt.Errorf("%w: column %s not found in schema %s", errIncompatibleValue, col.Name, s.subject)
+		}
+		value, err := toAvroValue(s.columns[i].avroType, col.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		row[s.columns[i].field] = value
+		present[i] = true
+	}
+
+	omitted := []string{}
+	for i, col := range s.columns {
+		if !present[i] {
+			row[col.field] = nil
+			omitted = append(omitted, col.name)
+		}
+	}
+
+	// records within unions are identified by their full name
+	return map[string]any{s.rowName: row}, omitted, nil
+}
+
+// encodeEvent returns the generic event avro record for the wal data on input.
+func encodeEvent(data *wal.Data) (map[string]any, error) {
+	record := encodeHeader(data)
+
+	var err error
+	if record["columns"], err = encodeEventColumns(data.Columns); err != nil {
+		return nil, err
+	}
+	if record["identity"], err = encodeEventColumns(data.Identity); err != nil {
+		return nil, err
+	}
+	record["transactional"] = data.Transactional
+	record["prefix"] = data.Prefix
+	record["content"] = data.Content
+
+	return record, nil
+}
+
+func encodeEventColumns(columns []wal.Column) (any, error) {
+	if columns == nil {
+		return nil, nil
+	}
+
+	items := make([]any, 0, len(columns))
+	for _, col := range columns {
+		var value any
+		if col.Value != nil {
+			b, err := json.Marshal(col.Value)
+			if err != nil {
+				return nil, fmt.Errorf("marshaling column %s value: %w", col.Name, err)
+			}
+			value = string(b)
+		}
+		items = append(items, map[string]any{
+			"id":    col.ID,
+			"name":  col.Name,
+			"type":  col.Type,
+			"value": value,
+		})
+	}
+	return map[string]any{"array": items}, nil
+}
+
+func encodeHeader(data *wal.Data) map[string]any {
+	schemaID := ""
+	if !data.Metadata.SchemaID.IsNil() {
+		schemaID = data.Metadata.SchemaID.String()
+	}
+	idColumns := data.Metadata.InternalColIDs
+	if idColumns == nil {
+		idColumns = []string{}
+	}
+
+	record := map[string]any{
+		"action":    data.Action,
+		"timestamp": data.Timestamp,
+		"lsn":       data.LSN,
+		"xid":       int64(data.XID),
+		"schema":    data.Schema,
+		"table":     data.Table,
+		"metadata": map[string]any{
+			"schema_id":               schemaID,
+			"table_pgstream_id":       data.Metadata.TablePgstreamID,
+			"id_col_pgstream_id":      idColumns,
+			"version_col_pgstream_id": data.Metadata.InternalColVersion,
+		},
+		"transaction": nil,
+	}
+	if tx := data.Transaction; tx != nil {
+		record["transaction"] = map[string]any{
+			namespace + ".transaction": map[string]any{
+				"xid":              int64(tx.XID),
+				"commit_lsn":       tx.CommitLSN,
+				"commit_timestamp": tx.CommitTimestamp,
+				"sequence":         int64(tx.Sequence),
+			},
+		}
+	}
+	return record
+}
+
+func isRowEvent(data *wal.Data) bool {
+	switch data.Action {
+	case "I", "U", "D":
+		return !processor.IsSchemaLogEvent(data)
+	default:
+		return false
+	}
+}
+
+func columnDefinitionsFromEvent(data *wal.Data) []columnDefinition {
+	columns := make([]columnDefinition, 0, len(data.Columns)+len(data.Identity))
+	for _, cols := range [][]wal.Column{data.Columns, data.Identity} {
+		for _, col := range cols {
+			columns = mergeColumnDefinitions(columns, []columnDefinition{{name: col.Name, pgType: col.Type, id: col.ID}})
+		}
+	}
+	return columns
+}
+
+// mergeColumnDefinitions appends the new columns not already part of the
+// existing ones.
+func mergeColumnDefinitions(existing, columns []columnDefinition) []columnDefinition {
+	for _, col := range columns {
+		found := false
+		for _, e := range existing {
+			if e.name == col.name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			existing = append(existing, col)
+		}
+	}
+	return existing
+}
+
+func tableKey(schemaName, tableName string) string {
+	return schemaName + "." + tableName
+}
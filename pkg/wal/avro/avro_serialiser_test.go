@@ -0,0 +1,291 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package avro
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/internal/json"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/schemaregistry"
+	schemaregistrymocks "github.com/xataio/pgstream/pkg/schemaregistry/mocks"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+const usersSubject = "pgstream.public.users"
+
+func TestSerialiser_roundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	registryServer := schemaregistrymocks.NewRegistryServer()
+	defer registryServer.Close()
+	registry, err := schemaregistry.NewClient(&schemaregistry.Config{URL: registryServer.URL()})
+	require.NoError(t, err)
+
+	serialiser, err := NewSerialiser(registry)
+	require.NoError(t, err)
+	// use a separate registry client to make sure the schemas are retrieved
+	// from the registry
+	deserialiserRegistry, err := schemaregistry.NewClient(&schemaregistry.Config{URL: registryServer.URL()})
+	require.NoError(t, err)
+	deserialiser := NewDeserialiser(deserialiserRegistry)
+
+	schemaID := xid.New()
+	testMetadata := wal.Metadata{
+		SchemaID:           schemaID,
+		TablePgstreamID:    "t1",
+		InternalColIDs:     []string{"t1-1"},
+		InternalColVersion: "t1-3",
+	}
+
+	roundTrip := func(t *testing.T, data *wal.Data) *wal.Data {
+		msg, err := serialiser.Serialise(ctx, data)
+		require.NoError(t, err)
+		require.True(t, schemaregistry.IsWireFormat(msg))
+
+		got, err := deserialiser.Deserialise(ctx, msg)
+		require.NoError(t, err)
+		return got
+	}
+
+	// events for unknown tables derive the schema from the event columns
+	insert := &wal.Data{
+		Action:    "I",
+		Timestamp: "2024-01-02 03:04:05.000000+00",
+		LSN:       "0/1",
+		XID:       42,
+		Schema:    "public",
+		Table:     "users",
+		Columns: []wal.Column{
+			{ID: "t1-1", Name: "id", Type: "bigint", Value: float64(1)},
+			{ID: "t1-2", Name: "name", Type: "text", Value: "alice"},
+		},
+		Metadata: testMetadata,
+		Transaction: &wal.Transaction{
+			XID:             42,
+			CommitLSN:       "0/2",
+			CommitTimestamp: "2024-01-02 03:04:05.000000+00",
+			Sequence:        1,
+		},
+	}
+	got := roundTrip(t, insert)
+	want := *insert
+	want.Columns = []wal.Column{
+		{ID: "t1-1", Name: "id", Type: "bigint", Value: int64(1)},
+		{ID: "t1-2", Name: "name", Type: "text", Value: "alice"},
+	}
+	require.Equal(t, &want, got)
+	require.Len(t, registryServer.GetSubjectSchemaIDs(usersSubject), 1)
+
+	// schema log events update the table schemas
+	schemaLogEvent := newTestSchemaLogEvent(t, schemaID,
+		schemalog.Column{Name: "id", DataType: "bigint", PgstreamID: "t1-1"},
+		schemalog.Column{Name: "name", DataType: "text", PgstreamID: "t1-2"},
+		schemalog.Column{Name: "version", DataType: "integer", PgstreamID: "t1-3"},
+		schemalog.Column{Name: "score", DataType: "real", PgstreamID: "t1-4"},
+		schemalog.Column{Name: "active", DataType: "boolean", PgstreamID: "t1-5"},
+		schemalog.Column{Name: "created at", DataType: "timestamp with time zone", PgstreamID: "t1-6"},
+	)
+	got = roundTrip(t, schemaLogEvent)
+	require.Equal(t, schemaLogEvent.Columns, got.Columns)
+	require.Len(t, registryServer.GetSubjectSchemaIDs(usersSubject), 2)
+	require.Len(t, registryServer.GetSubjectSchemaIDs(eventSchemaName), 1)
+
+	// omitted columns are kept omitted
+	update := &wal.Data{
+		Action: "U",
+		LSN:    "0/3",
+		Schema: "public",
+		Table:  "users",
+		Columns: []wal.Column{
+			{ID: "t1-1", Name: "id", Type: "bigint", Value: float64(1)},
+			{ID: "t1-3", Name: "version", Type: "integer", Value: float64(2)},
+			{ID: "t1-4", Name: "score", Type: "real", Value: 0.1},
+			{ID: "t1-5", Name: "active", Type: "boolean", Value: true},
+			{ID: "t1-6", Name: "created at", Type: "timestamp with time zone", Value: "2024-01-02 03:04:05+00"},
+		},
+		Identity: []wal.Column{
+			{ID: "t1-1", Name: "id", Type: "bigint", Value: float64(1)},
+		},
+		Metadata: testMetadata,
+	}
+	got = roundTrip(t, update)
+	want = *update
+	want.Columns = []wal.Column{
+		{ID: "t1-1", Name: "id", Type: "bigint", Value: int64(1)},
+		{ID: "t1-3", Name: "version", Type: "integer", Value: int64(2)},
+		{ID: "t1-4", Name: "score", Type: "real", Value: 0.1},
+		{ID: "t1-5", Name: "active", Type: "boolean", Value: true},
+		{ID: "t1-6", Name: "created at", Type: "timestamp with time zone", Value: "2024-01-02 03:04:05+00"},
+	}
+	want.Identity = []wal.Column{
+		{ID: "t1-1", Name: "id", Type: "bigint", Value: int64(1)},
+	}
+	require.Equal(t, &want, got)
+	require.Len(t, registryServer.GetSubjectSchemaIDs(usersSubject), 2)
+
+	// values not compatible with the table schema use the generic schema
+	incompatible := &wal.Data{
+		Action:   "I",
+		Schema:   "public",
+		Table:    "users",
+		Columns:  []wal.Column{{ID: "t1-1", Name: "id", Type: "bigint", Value: "not a number"}},
+		Metadata: testMetadata,
+	}
+	require.Equal(t, incompatible, roundTrip(t, incompatible))
+
+	// non row events use the generic schema
+	message := &wal.Data{
+		Action:        "M",
+		LSN:           "0/4",
+		Transactional: true,
+		Prefix:        "audit",
+		Content:       `{"user":"alice"}`,
+	}
+	require.Equal(t, message, roundTrip(t, message))
+	commit := &wal.Data{
+		Action:      "C",
+		LSN:         "0/5",
+		Transaction: &wal.Transaction{XID: 42, CommitLSN: "0/5", Sequence: 3},
+	}
+	require.Equal(t, commit, roundTrip(t, commit))
+	require.Len(t, registryServer.GetSubjectSchemaIDs(eventSchemaName), 1)
+}
+
+func TestSerialiser_incompatibleSchema(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	registryServer := schemaregistrymocks.NewRegistryServer()
+	defer registryServer.Close()
+	registry, err := schemaregistry.NewClient(&schemaregistry.Config{URL: registryServer.URL()})
+	require.NoError(t, err)
+
+	serialiser, err := NewSerialiser(registry)
+	require.NoError(t, err)
+	deserialiser := NewDeserialiser(registry)
+
+	roundTrip := func(t *testing.T, data *wal.Data) *wal.Data {
+		msg, err := serialiser.Serialise(ctx, data)
+		require.NoError(t, err)
+
+		got, err := deserialiser.Deserialise(ctx, msg)
+		require.NoError(t, err)
+		return got
+	}
+
+	schemaID := xid.New()
+	roundTrip(t, newTestSchemaLogEvent(t, schemaID,
+		schemalog.Column{Name: "id", DataType: "bigint", PgstreamID: "t1-1"},
+	))
+	require.Len(t, registryServer.GetSubjectSchemaIDs(usersSubject), 1)
+
+	// the registry rejects the new versions of the table schema, so the
+	// schema log event is still serialised, and the table row events use the
+	// generic schema
+	registryServer.LockSubject(usersSubject)
+	roundTrip(t, newTestSchemaLogEvent(t, schemaID,
+		schemalog.Column{Name: "id", DataType: "bigint", PgstreamID: "t1-1"},
+		schemalog.Column{Name: "name", DataType: "text", PgstreamID: "t1-2"},
+	))
+	require.Len(t, registryServer.GetSubjectSchemaIDs(usersSubject), 1)
+
+	insert := &wal.Data{
+		Action: "I",
+		Schema: "public",
+		Table:  "users",
+		Columns: []wal.Column{
+			{ID: "t1-1", Name: "id", Type: "bigint", Value: float64(1)},
+			{ID: "t1-2", Name: "name", Type: "text", Value: "alice"},
+		},
+	}
+	require.Equal(t, insert, roundTrip(t, insert))
+
+	// the schemas derived from the event columns fall back to the generic
+	// schema too
+	orders := &wal.Data{
+		Action:  "I",
+		Schema:  "public",
+		Table:   "orders",
+		Columns: []wal.Column{{ID: "t2-1", Name: "id", Type: "bigint", Value: float64(1)}},
+	}
+	registryServer.LockSubject("pgstream.public.orders")
+	require.Equal(t, orders, roundTrip(t, orders))
+	require.Empty(t, registryServer.GetSubjectSchemaIDs("pgstream.public.orders"))
+	require.Len(t, registryServer.GetSubjectSchemaIDs(usersSubject), 1)
+}
+
+func TestDeserialiser_errors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	registryServer := schemaregistrymocks.NewRegistryServer()
+	defer registryServer.Close()
+	registry, err := schemaregistry.NewClient(&schemaregistry.Config{URL: registryServer.URL()})
+	require.NoError(t, err)
+
+	deserialiser := NewDeserialiser(registry)
+
+	_, err = deserialiser.Deserialise(ctx, []byte(`{"action":"I"}`))
+	require.ErrorIs(t, err, schemaregistry.ErrInvalidWireFormat)
+
+	_, err = deserialiser.Deserialise(ctx, schemaregistry.EncodeWireFormat(10, []byte{}))
+	require.ErrorIs(t, err, schemaregistry.ErrSchemaNotFound)
+
+	id, err := registry.RegisterSchema(ctx, "test", &schemaregistry.Schema{Schema: `syntax = "proto3";`, SchemaType: schemaregistry.SchemaTypeProtobuf})
+	require.NoError(t, err)
+	_, err = deserialiser.Deserialise(ctx, schemaregistry.EncodeWireFormat(id, []byte{}))
+	require.ErrorIs(t, err, errUnsupportedSchemaType)
+}
+
+func TestTableSchemaDefinition(t *testing.T) {
+	t.Parallel()
+
+	definition, err := tableSchemaDefinition("my-schema", "1users", []columnDefinition{
+		{name: "user id", pgType: "integer", id: "1"},
+		{name: "user_id", pgType: "bigint", id: "2"},
+		{name: "tags", pgType: "text[]", id: "3"},
+		{name: "amount", pgType: "numeric(10,2)", id: "4"},
+		{name: "ratio", pgType: "double precision", id: "5"},
+	})
+	require.NoError(t, err)
+
+	schema, err := newRecordSchema(definition)
+	require.NoError(t, err)
+	require.Equal(t, "pgstream.my_schema._1users", schema.subject)
+	require.Equal(t, "pgstream.my_schema._1users.row", schema.rowName)
+	require.False(t, schema.isEvent)
+	require.Equal(t, []rowColumn{
+		{field: "user_id", name: "user id", pgType: "integer", id: "1", avroType: "int"},
+		{field: "user_id_2", name: "user_id", pgType: "bigint", id: "2", avroType: "long"},
+		{field: "tags", name: "tags", pgType: "text[]", id: "3", avroType: "string"},
+		{field: "amount", name: "amount", pgType: "numeric(10,2)", id: "4", avroType: "string"},
+		{field: "ratio", name: "ratio", pgType: "double precision", id: "5", avroType: "double"},
+	}, schema.columns)
+}
+
+func newTestSchemaLogEvent(t *testing.T, id xid.ID, columns ...schemalog.Column) *wal.Data {
+	schema, err := json.Marshal(schemalog.Schema{
+		Tables: []schemalog.Table{
+			{Name: "users", PgstreamID: "t1", Columns: columns},
+		},
+	})
+	require.NoError(t, err)
+
+	return &wal.Data{
+		Action: "I",
+		Schema: schemalog.SchemaName,
+		Table:  schemalog.TableName,
+		Columns: []wal.Column{
+			{Name: "id", Type: "text", Value: id.String()},
+			{Name: "version", Type: "bigint", Value: float64(1)},
+			{Name: "schema_name", Type: "text", Value: "public"},
+			{Name: "created_at", Type: "timestamp", Value: "2024-01-02 03:04:05"},
+			{Name: "schema", Type: "jsonb", Value: string(schema)},
+		},
+	}
+}
@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package avro
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	avrolib "github.com/hamba/avro/v2"
+	"github.com/xataio/pgstream/internal/json"
+)
+
+var errIncompatibleValue = errors.New("value not compatible with avro type")
+
+// toAvroValue converts the wal column value on input into the go type expected
+// by the avro type. The values can be the ones decoded from the replication
+// plugins (bool, float64 or string) or the native types of the snapshots.
+func toAvroValue(avroType avrolib.Type, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch avroType {
+	case avrolib.Boolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b, nil
+			}
+		}
+	case avrolib.Int:
+		if i, ok := toInt64(value); ok && i >= math.MinInt32 && i <= math.MaxInt32 {
+			return int32(i), nil
+		}
+	case avrolib.Long:
+		if i, ok := toInt64(value); ok {
+			return i, nil
+		}
+	case avrolib.Float:
+		if f, ok := toFloat64(value); ok {
+			return float32(f), nil
+		}
+	case avrolib.Double:
+		if f, ok := toFloat64(value); ok {
+			return f, nil
+		}
+	case avrolib.String:
+		return toString(value)
+	}
+
+	return nil, fmt.Errorf("%w: %v (%T) as %s", errIncompatibleValue, value, value, avroType)
+}
+
+// fromAvroValue converts the decoded avro value on input into the go type
+// used for the wal column values.
+func fromAvroValue(value any) any {
+	switch v := value.(type) {
+	case int32:
+		return int64(v)
+	case int:
+		return int64(v)
+	case float32:
+		// avoid float32 to float64 conversion artifacts
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+		return f
+	default:
+		return v
+	}
+}
+
+func toInt64(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case float32:
+		return toInt64(float64(v))
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	case string:
+		i, err := strconv.ParseInt(v, 10, 64)
+		return i, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toFloat64(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		if i, ok := toInt64(value); ok {
+			return float64(i), true
+		}
+		return 0, false
+	}
+}
+
+// toString returns the string representation of the value on input, using
+// its JSON representation for non string types.
+func toString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errIncompatibleValue, err)
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return "", fmt.Errorf("%w: %w", errIncompatibleValue, err)
+		}
+		return s, nil
+	}
+	return string(b), nil
+}
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	filelistener "github.com/xataio/pgstream/pkg/wal/listener/file"
+)
+
+// Checkpointer is a file implementation of the wal checkpointer. It stores the
+// last processed position of the file listener in a local file, so that the
+// reading can be resumed from it.
+type Checkpointer struct {
+	logger loglib.Logger
+	path   string
+	mutex  sync.Mutex
+}
+
+type Config struct {
+	// Path of the file where the last processed position is stored. It will
+	// be created if it doesn't exist.
+	Path string
+}
+
+type Option func(c *Checkpointer)
+
+var errMissingPath = errors.New("missing file checkpointer path")
+
+// New returns a file checkpointer that stores the positions in the configured
+// file.
+func New(cfg Config, opts ...Option) (*Checkpointer, error) {
+	if cfg.Path == "" {
+		return nil, errMissingPath
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating checkpoint directory: %w", err)
+	}
+
+	c := &Checkpointer{
+		logger: loglib.NewNoopLogger(),
+		path:   cfg.Path,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+func WithLogger(l loglib.Logger) Option {
+	return func(c *Checkpointer) {
+		c.logger = loglib.NewLogger(l).WithFields(loglib.Fields{
+			loglib.ModuleField: "wal_file_checkpointer",
+		})
+	}
+}
+
+// CommitPositions stores the last of the positions on input. The positions
+// are provided in the order the events were read, so the last one is the most
+// recent.
+func (c *Checkpointer) CommitPositions(ctx context.Context, positions []wal.CommitPosition) error {
+	if len(positions) == 0 {
+		return nil
+	}
+
+	last := positions[len(positions)-1]
+	position, err := filelistener.ParsePosition(string(last))
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// write to a temporary file and rename it, so that the checkpoint file is
+	// never left incomplete
+	tmpPath := c.path + ".tmp"
+	if err := writeFileSync(tmpPath, []byte(position.String()+"\n")); err != nil {
+		return fmt.Errorf("writing checkpoint file: %w", err)
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		return fmt.Errorf("renaming checkpoint file: %w", err)
+	}
+
+	c.logger.Trace("committed", loglib.Fields{"position": position.String()})
+	return nil
+}
+
+// LastPosition returns the last stored position, or nil if there's none.
+func (c *Checkpointer) LastPosition() (*filelistener.Position, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading checkpoint file: %w", err)
+	}
+
+	return filelistener.ParsePosition(strings.TrimSpace(string(b)))
+}
+
+func (c *Checkpointer) Close() error {
+	return nil
+}
+
+func writeFileSync(path string, data []byte) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
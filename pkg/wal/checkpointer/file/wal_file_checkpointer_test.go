@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/wal"
+	filelistener "github.com/xataio/pgstream/pkg/wal/listener/file"
+)
+
+func TestCheckpointer_CommitPositions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "checkpoints", "position")
+
+	_, err := New(Config{})
+	require.ErrorIs(t, err, errMissingPath)
+
+	c, err := New(Config{Path: path})
+	require.NoError(t, err)
+	defer c.Close()
+
+	// no checkpoint stored yet
+	position, err := c.LastPosition()
+	require.NoError(t, err)
+	require.Nil(t, position)
+
+	require.NoError(t, c.CommitPositions(ctx, nil))
+	require.NoFileExists(t, path)
+
+	require.NoError(t, c.CommitPositions(ctx, []wal.CommitPosition{"/data/a.ndjson:10", "/data/b.ndjson:20"}))
+	position, err = c.LastPosition()
+	require.NoError(t, err)
+	require.Equal(t, &filelistener.Position{File: "/data/b.ndjson", Offset: 20}, position)
+
+	require.ErrorIs(t, c.CommitPositions(ctx, []wal.CommitPosition{"0/1"}), filelistener.ErrInvalidPosition)
+	position, err = c.LastPosition()
+	require.NoError(t, err)
+	require.Equal(t, &filelistener.Position{File: "/data/b.ndjson", Offset: 20}, position)
+
+	require.NoError(t, os.WriteFile(path, []byte("invalid"), 0o644))
+	_, err = c.LastPosition()
+	require.ErrorIs(t, err, filelistener.ErrInvalidPosition)
+}
@@ -0,0 +1,476 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package debezium
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/rs/xid"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// Deserialiser converts debezium change events into wal data, so that
+// debezium topics can be used as a pgstream source. Change event envelopes are
+// converted into row events, transaction metadata events into transaction
+// boundaries, and schema change events into schema log entries. The envelopes
+// can be produced with or without schemas. The values of the debezium logical
+// types (i.e. temporal, decimal and binary values) can only be decoded when
+// the schemas are enabled, otherwise they're kept as produced by the
+// connector.
+type Deserialiser struct {
+	mutex sync.Mutex
+	// schemas contains the schema log entry per schema name, built from the
+	// schema change events. It's used to compute the next schema log entry, as
+	// well as to populate the pgstream metadata of the row events.
+	schemas map[string]*schemalog.LogEntry
+}
+
+// rawEnvelope is used to decode the change event envelopes, keeping the row
+// values raw so that the column order is preserved.
+type rawEnvelope struct {
+	Before      json.RawMessage `json:"before"`
+	After       json.RawMessage `json:"after"`
+	Source      Source          `json:"source"`
+	Op          string          `json:"op"`
+	TsMs        int64           `json:"ts_ms"`
+	Transaction *Transaction    `json:"transaction"`
+	Message     *Message        `json:"message"`
+}
+
+var (
+	errUnsupportedEvent   = errors.New("unsupported debezium event")
+	errUnsupportedOp      = errors.New("unsupported debezium operation")
+	errInvalidTableID     = errors.New("invalid debezium table id")
+	errMultipleSchemas    = errors.New("debezium schema change event spans multiple schemas")
+	errInvalidTransaction = errors.New("invalid debezium transaction id")
+)
+
+func NewDeserialiser() *Deserialiser {
+	return &Deserialiser{
+		schemas: map[string]*schemalog.LogEntry{},
+	}
+}
+
+// Deserialise returns the wal data for the debezium event on input. Tombstones
+// (empty values) return nil, since the delete has already been processed with
+// the event preceding them. It can be called concurrently.
+func (d *Deserialiser) Deserialise(_ context.Context, msg []byte) (*wal.Data, error) {
+	fields, schema, err := unwrapPayload(msg)
+	if err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		return nil, nil
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	switch {
+	case fields["op"] != nil:
+		return d.decodeEnvelope(fields, schema)
+	case fields["status"] != nil:
+		return decodeTransactionEvent(fields)
+	case fields["tableChanges"] != nil:
+		return d.decodeSchemaChange(fields)
+	default:
+		return nil, errUnsupportedEvent
+	}
+}
+
+// unwrapPayload returns the fields of the debezium event on input, removing
+// the schema wrapper added when the JSON converter has schemas enabled, along
+// with its schema, if any.
+func unwrapPayload(msg []byte) (map[string]json.RawMessage, *connectSchema, error) {
+	if isNull(msg) {
+		return nil, nil, nil
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(msg, &fields); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", errUnsupportedEvent, err)
+	}
+	payload, found := fields["payload"]
+	if !found || fields["op"] != nil {
+		return fields, nil, nil
+	}
+	if isNull(payload) {
+		return nil, nil, nil
+	}
+
+	var schema *connectSchema
+	if rawSchema := fields["schema"]; !isNull(rawSchema) {
+		schema = &connectSchema{}
+		if err := json.Unmarshal(rawSchema, schema); err != nil {
+			return nil, nil, fmt.Errorf("%w: schema: %w", errUnsupportedEvent, err)
+		}
+	}
+	fields = map[string]json.RawMessage{}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", errUnsupportedEvent, err)
+	}
+	return fields, schema, nil
+}
+
+func (d *Deserialiser) decodeEnvelope(fields map[string]json.RawMessage, schema *connectSchema) (*wal.Data, error) {
+	envelope := &rawEnvelope{}
+	if err := unmarshalFields(fields, envelope); err != nil {
+		return nil, err
+	}
+
+	data := &wal.Data{
+		Timestamp: formatMillis(envelope.Source.TsMs),
+		Schema:    envelope.Source.Schema,
+		Table:     envelope.Source.Table,
+	}
+	if envelope.Source.TxID != nil {
+		data.XID = uint32(*envelope.Source.TxID)
+	}
+	if envelope.Source.LSN != nil {
+		data.LSN = pglogrepl.LSN(*envelope.Source.LSN).String()
+	}
+	if envelope.Transaction != nil {
+		txID, err := parseTransactionID(envelope.Transaction.ID)
+		if err != nil {
+			return nil, err
+		}
+		data.Transaction = &wal.Transaction{
+			XID:             txID,
+			CommitTimestamp: data.Timestamp,
+			Sequence:        uint64(envelope.Transaction.TotalOrder),
+		}
+	}
+
+	var err error
+	switch envelope.Op {
+	case OpCreate, OpRead:
+		data.Action = "I"
+		data.Columns, err = decodeColumns(envelope.After, schema.field("after"))
+	case OpUpdate:
+		data.Action = "U"
+		if data.Columns, err = decodeColumns(envelope.After, schema.field("after")); err == nil {
+			data.Identity, err = decodeColumns(envelope.Before, schema.field("before"))
+		}
+	case OpDelete:
+		data.Action = "D"
+		data.Identity, err = decodeColumns(envelope.Before, schema.field("before"))
+	case OpTruncate:
+		data.Action = "T"
+	case OpMessage:
+		data.Action = "M"
+		data.Transactional = envelope.Transaction != nil
+		if envelope.Message != nil {
+			data.Prefix = envelope.Message.Prefix
+			data.Content = string(envelope.Message.Content)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnsupportedOp, envelope.Op)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d.addMetadata(data)
+	return data, nil
+}
+
+// addMetadata populates the pgstream metadata and the column ids and types of
+// the row event on input, if the table is known from the schema change events.
+func (d *Deserialiser) addMetadata(data *wal.Data) {
+	logEntry, found := d.schemas[data.Schema]
+	if !found {
+		return
+	}
+	table, found := logEntry.GetTableByName(data.Table)
+	if !found {
+		return
+	}
+
+	columns := make(map[string]*schemalog.Column, len(table.Columns))
+	for i := range table.Columns {
+		columns[table.Columns[i].Name] = &table.Columns[i]
+	}
+	for _, cols := range [][]wal.Column{data.Columns, data.Identity} {
+		for i := range cols {
+			if col, found := columns[cols[i].Name]; found {
+				cols[i].ID = col.PgstreamID
+				cols[i].Type = col.DataType
+			}
+		}
+	}
+
+	data.Metadata.SchemaID = logEntry.ID
+	data.Metadata.TablePgstreamID = table.PgstreamID
+	for _, pk := range table.PrimaryKeyColumns {
+		if col, found := columns[pk]; found {
+			data.Metadata.InternalColIDs = append(data.Metadata.InternalColIDs, col.PgstreamID)
+		}
+	}
+}
+
+func decodeTransactionEvent(fields map[string]json.RawMessage) (*wal.Data, error) {
+	event := &TransactionEvent{}
+	if err := unmarshalFields(fields, event); err != nil {
+		return nil, err
+	}
+
+	txID, err := parseTransactionID(event.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &wal.Data{
+		Timestamp: formatMillis(event.TsMs),
+		XID:       txID,
+		Transaction: &wal.Transaction{
+			XID: txID,
+		},
+	}
+	switch event.Status {
+	case TransactionStatusBegin:
+		data.Action = "B"
+	case TransactionStatusEnd:
+		data.Action = "C"
+		data.Transaction.CommitTimestamp = data.Timestamp
+		// the commit event is always the last one in the transaction
+		if event.EventCount != nil {
+			data.Transaction.Sequence = uint64(*event.EventCount) + 1
+		}
+	default:
+		return nil, fmt.Errorf("%w: transaction status %q", errUnsupportedEvent, event.Status)
+	}
+	return data, nil
+}
+
+// decodeSchemaChange returns the schema log entry wal data for the schema
+// change event on input. The table changes are applied to the previous schema
+// log entry of the schema, and the resulting entry is produced with the next
+// version. Since debezium doesn't provide stable table and column ids, the
+// pgstream ids are derived from their names, which means renames are handled
+// as a drop and create.
+func (d *Deserialiser) decodeSchemaChange(fields map[string]json.RawMessage) (*wal.Data, error) {
+	change := &SchemaChange{}
+	if err := unmarshalFields(fields, change); err != nil {
+		return nil, err
+	}
+
+	schemaName := change.SchemaName
+	tables := make([]TableChange, 0, len(change.TableChanges))
+	tableNames := make([]string, 0, len(change.TableChanges))
+	for _, tableChange := range change.TableChanges {
+		tableSchema, tableName, err := parseTableID(tableChange.ID)
+		if err != nil {
+			return nil, err
+		}
+		if tableSchema == "" {
+			tableSchema = schemaName
+		}
+		if schemaName == "" {
+			schemaName = tableSchema
+		}
+		if tableSchema != schemaName {
+			return nil, fmt.Errorf("%w: %s and %s", errMultipleSchemas, schemaName, tableSchema)
+		}
+		tables = append(tables, tableChange)
+		tableNames = append(tableNames, tableName)
+	}
+	if schemaName == "" {
+		schemaName = change.DatabaseName
+	}
+
+	logEntry := &schemalog.LogEntry{
+		ID:         xid.New(),
+		Version:    0,
+		SchemaName: schemaName,
+		CreatedAt:  schemalog.NewSchemaCreatedAtTimestamp(time.UnixMilli(change.Source.TsMs)),
+	}
+	if previous, found := d.schemas[schemaName]; found {
+		logEntry.Version = previous.Version + 1
+		logEntry.Schema.Tables = append(logEntry.Schema.Tables, previous.Schema.Tables...)
+	}
+
+	for i, tableChange := range tables {
+		logEntry.Schema.Tables = applyTableChange(logEntry.Schema.Tables, schemaName, tableNames[i], &tableChange)
+	}
+	d.schemas[schemaName] = logEntry
+
+	return logEntryToWalData(logEntry, change.Source.TsMs)
+}
+
+func applyTableChange(tables []schemalog.Table, schemaName, tableName string, change *TableChange) []schemalog.Table {
+	remaining := make([]schemalog.Table, 0, len(tables)+1)
+	for _, table := range tables {
+		if table.Name != tableName {
+			remaining = append(remaining, table)
+		}
+	}
+
+	if change.Type == TableChangeDrop || change.Table == nil {
+		return remaining
+	}
+
+	tableID := schemaName + "." + tableName
+	table := schemalog.Table{
+		Name:              tableName,
+		PgstreamID:        tableID,
+		PrimaryKeyColumns: change.Table.PrimaryKeyColumnNames,
+		Columns:           make([]schemalog.Column, 0, len(change.Table.Columns)),
+	}
+	for _, col := range change.Table.Columns {
+		dataType := col.TypeExpression
+		if dataType == "" {
+			dataType = col.TypeName
+		}
+		table.Columns = append(table.Columns, schemalog.Column{
+			Name:         col.Name,
+			DataType:     dataType,
+			DefaultValue: col.DefaultValueExpression,
+			Nullable:     col.Optional,
+			Generated:    col.Generated,
+			PgstreamID:   tableID + "." + col.Name,
+		})
+	}
+	return append(remaining, table)
+}
+
+// logEntryToWalData returns the wal data for the schema log entry on input,
+// as produced by the postgres listener for the schema log table inserts.
+func logEntryToWalData(logEntry *schemalog.LogEntry, tsMs int64) (*wal.Data, error) {
+	schema, err := json.Marshal(logEntry.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling schema log entry: %w", err)
+	}
+	createdAt, err := logEntry.CreatedAt.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return &wal.Data{
+		Action:    "I",
+		Timestamp: formatMillis(tsMs),
+		Schema:    schemalog.SchemaName,
+		Table:     schemalog.TableName,
+		Columns: []wal.Column{
+			{Name: "id", Type: "text", Value: logEntry.ID.String()},
+			{Name: "version", Type: "bigint", Value: float64(logEntry.Version)},
+			{Name: "schema_name", Type: "text", Value: logEntry.SchemaName},
+			{Name: "schema", Type: "jsonb", Value: string(schema)},
+			{Name: "created_at", Type: "timestamp", Value: strings.Trim(string(createdAt), `"`)},
+			{Name: "acked", Type: "boolean", Value: false},
+		},
+	}, nil
+}
+
+// decodeColumns returns the wal columns for the debezium row on input, keeping
+// the order of the fields. The row schema is used to decode the values of the
+// logical types, if provided.
+func decodeColumns(row json.RawMessage, schema *connectSchema) ([]wal.Column, error) {
+	if isNull(row) {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(row))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("%w: row is not an object", errUnsupportedEvent)
+	}
+
+	columns := []wal.Column{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("decoding debezium row: %w", err)
+		}
+		name, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: unexpected row field %v", errUnsupportedEvent, token)
+		}
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("decoding debezium row field %s: %w", name, err)
+		}
+		value, err := decodeValue(raw, schema.field(name))
+		if err != nil {
+			return nil, fmt.Errorf("decoding debezium row field %s: %w", name, err)
+		}
+		columns = append(columns, wal.Column{Name: name, Value: value})
+	}
+	return columns, nil
+}
+
+func unmarshalFields(fields map[string]json.RawMessage, v any) error {
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%w: %w", errUnsupportedEvent, err)
+	}
+	return nil
+}
+
+// parseTableID returns the schema and table names of the debezium table id on
+// input, with the format "schema"."table" or "database"."schema"."table". The
+// schema is empty for ids with no schema (i.e. "table").
+func parseTableID(id string) (string, string, error) {
+	parts := []string{}
+	for rest := id; rest != ""; {
+		var part string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				return "", "", fmt.Errorf("%w: %s", errInvalidTableID, id)
+			}
+			part, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			part, rest, _ = strings.Cut(rest, ".")
+			rest = "." + rest
+			if rest == "." {
+				rest = ""
+			}
+		}
+		parts = append(parts, part)
+		rest = strings.TrimPrefix(rest, ".")
+	}
+
+	switch len(parts) {
+	case 1:
+		return "", parts[0], nil
+	case 2, 3:
+		return parts[len(parts)-2], parts[len(parts)-1], nil
+	default:
+		return "", "", fmt.Errorf("%w: %s", errInvalidTableID, id)
+	}
+}
+
+// parseTransactionID returns the source transaction id for the debezium
+// transaction id on input, with the format "txId" or "txId:LSN".
+func parseTransactionID(id string) (uint32, error) {
+	txID, _, _ := strings.Cut(id, ":")
+	xid, err := strconv.ParseUint(txID, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", errInvalidTransaction, id)
+	}
+	return uint32(xid), nil
+}
+
+func formatMillis(ms int64) string {
+	if ms == 0 {
+		return ""
+	}
+	return time.UnixMilli(ms).UTC().Format(time.RFC3339Nano)
+}
+
+func isNull(b []byte) bool {
+	b = bytes.TrimSpace(b)
+	return len(b) == 0 || bytes.Equal(b, []byte("null"))
+}
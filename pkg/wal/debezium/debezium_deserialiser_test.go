@@ -0,0 +1,361 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package debezium
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+)
+
+func TestDeserialiser_Deserialise(t *testing.T) {
+	t.Parallel()
+
+	testSource := `"source":{"version":"2.7.0.Final","connector":"postgresql","name":"dbserver1","ts_ms":1704164645000,"snapshot":"false","db":"postgres","schema":"public","table":"users","txId":42,"lsn":23803720}`
+	testTransaction := &wal.Transaction{XID: 42, CommitTimestamp: "2024-01-02T03:04:05Z", Sequence: 2}
+	testTimestamp := "2024-01-02T03:04:05Z"
+
+	tests := []struct {
+		name string
+		msg  string
+
+		wantData *wal.Data
+		wantErr  error
+	}{
+		{
+			name: "create",
+			msg:  `{"before":null,"after":{"name":"alice","id":1},` + testSource + `,"op":"c","ts_ms":1704164646000,"transaction":{"id":"42:23803720","total_order":2,"data_collection_order":1}}`,
+
+			wantData: &wal.Data{
+				Action:    "I",
+				Timestamp: testTimestamp,
+				LSN:       "0/16B3748",
+				XID:       42,
+				Schema:    "public",
+				Table:     "users",
+				Columns: []wal.Column{
+					{Name: "name", Value: "alice"},
+					{Name: "id", Value: float64(1)},
+				},
+				Transaction: testTransaction,
+			},
+		},
+		{
+			name: "snapshot read with schema wrapper",
+			msg:  `{"schema":{"type":"struct"},"payload":{"before":null,"after":{"id":1},` + testSource + `,"op":"r","ts_ms":1704164646000,"transaction":null}}`,
+
+			wantData: &wal.Data{
+				Action:    "I",
+				Timestamp: testTimestamp,
+				LSN:       "0/16B3748",
+				XID:       42,
+				Schema:    "public",
+				Table:     "users",
+				Columns:   []wal.Column{{Name: "id", Value: float64(1)}},
+			},
+		},
+		{
+			name: "create with logical types schema",
+			msg: `{"schema":{"type":"struct","fields":[{"type":"struct","optional":true,"field":"before","fields":[]},{"type":"struct","optional":true,"field":"after","fields":[` +
+				`{"type":"int32","optional":false,"field":"id"},` +
+				`{"type":"int32","optional":true,"name":"io.debezium.time.Date","version":1,"field":"birth_date"},` +
+				`{"type":"int64","optional":true,"name":"io.debezium.time.MicroTimestamp","version":1,"field":"created_at"},` +
+				`{"type":"string","optional":true,"name":"io.debezium.time.ZonedTimestamp","version":1,"field":"updated_at"},` +
+				`{"type":"bytes","optional":true,"name":"org.apache.kafka.connect.data.Decimal","version":1,"parameters":{"scale":"2","connect.decimal.precision":"10"},"field":"balance"},` +
+				`{"type":"bytes","optional":true,"field":"avatar"}]}]},` +
+				`"payload":{"before":null,"after":{"id":1,"birth_date":19724,"created_at":1704164645123456,"updated_at":"2024-01-02T03:04:05Z","balance":"EtaH","avatar":"AQL/"},` + testSource + `,"op":"c","ts_ms":1704164646000,"transaction":null}}`,
+
+			wantData: &wal.Data{
+				Action:    "I",
+				Timestamp: testTimestamp,
+				LSN:       "0/16B3748",
+				XID:       42,
+				Schema:    "public",
+				Table:     "users",
+				Columns: []wal.Column{
+					{Name: "id", Value: float64(1)},
+					{Name: "birth_date", Value: "2024-01-02"},
+					{Name: "created_at", Value: "2024-01-02 03:04:05.123456"},
+					{Name: "updated_at", Value: "2024-01-02T03:04:05Z"},
+					{Name: "balance", Value: "12345.67"},
+					{Name: "avatar", Value: `\x0102ff`},
+				},
+			},
+		},
+		{
+			name: "update",
+			msg:  `{"before":{"id":1},"after":{"id":1,"name":"bob"},` + testSource + `,"op":"u","ts_ms":1704164646000,"transaction":{"id":"42","total_order":2,"data_collection_order":1}}`,
+
+			wantData: &wal.Data{
+				Action:    "U",
+				Timestamp: testTimestamp,
+				LSN:       "0/16B3748",
+				XID:       42,
+				Schema:    "public",
+				Table:     "users",
+				Columns: []wal.Column{
+					{Name: "id", Value: float64(1)},
+					{Name: "name", Value: "bob"},
+				},
+				Identity:    []wal.Column{{Name: "id", Value: float64(1)}},
+				Transaction: testTransaction,
+			},
+		},
+		{
+			name: "delete",
+			msg:  `{"before":{"id":1},"after":null,` + testSource + `,"op":"d","ts_ms":1704164646000}`,
+
+			wantData: &wal.Data{
+				Action:    "D",
+				Timestamp: testTimestamp,
+				LSN:       "0/16B3748",
+				XID:       42,
+				Schema:    "public",
+				Table:     "users",
+				Identity:  []wal.Column{{Name: "id", Value: float64(1)}},
+			},
+		},
+		{
+			name: "truncate",
+			msg:  `{"before":null,"after":null,` + testSource + `,"op":"t","ts_ms":1704164646000}`,
+
+			wantData: &wal.Data{
+				Action:    "T",
+				Timestamp: testTimestamp,
+				LSN:       "0/16B3748",
+				XID:       42,
+				Schema:    "public",
+				Table:     "users",
+			},
+		},
+		{
+			name: "logical message",
+			msg:  `{"op":"m",` + testSource + `,"ts_ms":1704164646000,"message":{"prefix":"audit","content":"aGVsbG8="}}`,
+
+			wantData: &wal.Data{
+				Action:    "M",
+				Timestamp: testTimestamp,
+				LSN:       "0/16B3748",
+				XID:       42,
+				Schema:    "public",
+				Table:     "users",
+				Prefix:    "audit",
+				Content:   "hello",
+			},
+		},
+		{
+			name: "transaction begin",
+			msg:  `{"status":"BEGIN","id":"42:23803720","event_count":null,"data_collections":null,"ts_ms":1704164645000}`,
+
+			wantData: &wal.Data{
+				Action:      "B",
+				Timestamp:   testTimestamp,
+				XID:         42,
+				Transaction: &wal.Transaction{XID: 42},
+			},
+		},
+		{
+			name: "transaction end",
+			msg:  `{"status":"END","id":"42:23803720","event_count":2,"data_collections":[{"data_collection":"public.users","event_count":2}],"ts_ms":1704164645000}`,
+
+			wantData: &wal.Data{
+				Action:      "C",
+				Timestamp:   testTimestamp,
+				XID:         42,
+				Transaction: &wal.Transaction{XID: 42, CommitTimestamp: testTimestamp, Sequence: 3},
+			},
+		},
+		{
+			name: "tombstone",
+			msg:  ``,
+
+			wantData: nil,
+		},
+		{
+			name: "tombstone with schema wrapper",
+			msg:  `{"schema":null,"payload":null}`,
+
+			wantData: nil,
+		},
+		{
+			name: "error - unsupported event",
+			msg:  `{"id":1}`,
+
+			wantErr: errUnsupportedEvent,
+		},
+		{
+			name: "error - invalid json",
+			msg:  `{"op":`,
+
+			wantErr: errUnsupportedEvent,
+		},
+		{
+			name: "error - unsupported operation",
+			msg:  `{"op":"x",` + testSource + `}`,
+
+			wantErr: errUnsupportedOp,
+		},
+		{
+			name: "error - invalid transaction id",
+			msg:  `{"status":"BEGIN","id":"abc","ts_ms":1704164645000}`,
+
+			wantErr: errInvalidTransaction,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := NewDeserialiser()
+			data, err := d.Deserialise(context.Background(), []byte(tc.msg))
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantData, data)
+		})
+	}
+}
+
+func TestDeserialiser_Deserialise_schemaChange(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	d := NewDeserialiser()
+
+	createMsg := `{"source":{"ts_ms":1704164645000,"db":"postgres","schema":"public"},"ts_ms":1704164646000,"databaseName":"postgres","schemaName":"public","ddl":"",
+	"tableChanges":[
+		{"type":"CREATE","id":"\"public\".\"users\"","table":{"primaryKeyColumnNames":["id"],"columns":[
+			{"name":"id","typeName":"int8","typeExpression":"bigint","optional":false,"position":1},
+			{"name":"name","typeName":"text","optional":true,"position":2}]}},
+		{"type":"CREATE","id":"public.orders","table":{"primaryKeyColumnNames":[],"columns":[
+			{"name":"total","typeName":"numeric","optional":true,"position":1}]}}]}`
+
+	data, err := d.Deserialise(ctx, []byte(createMsg))
+	require.NoError(t, err)
+	require.True(t, processor.IsSchemaLogEvent(data))
+	logEntry, err := processor.WalDataToLogEntry(data)
+	require.NoError(t, err)
+	require.Equal(t, "public", logEntry.SchemaName)
+	require.Equal(t, int64(0), logEntry.Version)
+	require.Equal(t, []schemalog.Table{
+		{
+			Name:              "users",
+			PgstreamID:        "public.users",
+			PrimaryKeyColumns: []string{"id"},
+			Columns: []schemalog.Column{
+				{Name: "id", DataType: "bigint", PgstreamID: "public.users.id"},
+				{Name: "name", DataType: "text", Nullable: true, PgstreamID: "public.users.name"},
+			},
+		},
+		{
+			Name:              "orders",
+			PgstreamID:        "public.orders",
+			PrimaryKeyColumns: []string{},
+			Columns: []schemalog.Column{
+				{Name: "total", DataType: "numeric", Nullable: true, PgstreamID: "public.orders.total"},
+			},
+		},
+	}, logEntry.Schema.Tables)
+
+	// row events for known tables are populated with the pgstream metadata
+	data, err = d.Deserialise(ctx, []byte(`{"before":{"id":1},"after":{"id":1,"name":"bob"},"source":{"schema":"public","table":"users"},"op":"u"}`))
+	require.NoError(t, err)
+	require.Equal(t, &wal.Data{
+		Action: "U",
+		Schema: "public",
+		Table:  "users",
+		Columns: []wal.Column{
+			{ID: "public.users.id", Name: "id", Type: "bigint", Value: float64(1)},
+			{ID: "public.users.name", Name: "name", Type: "text", Value: "bob"},
+		},
+		Identity: []wal.Column{
+			{ID: "public.users.id", Name: "id", Type: "bigint", Value: float64(1)},
+		},
+		Metadata: wal.Metadata{
+			SchemaID:        logEntry.ID,
+			TablePgstreamID: "public.users",
+			InternalColIDs:  []string{"public.users.id"},
+		},
+	}, data)
+
+	// following schema changes are applied to the previous schema
+	dropMsg := `{"source":{"ts_ms":1704164647000},"schemaName":"public","tableChanges":[{"type":"DROP","id":"\"public\".\"orders\"","table":null}]}`
+	data, err = d.Deserialise(ctx, []byte(dropMsg))
+	require.NoError(t, err)
+	nextLogEntry, err := processor.WalDataToLogEntry(data)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), nextLogEntry.Version)
+	require.Len(t, nextLogEntry.Schema.Tables, 1)
+	require.Equal(t, "users", nextLogEntry.Schema.Tables[0].Name)
+
+	diff := schemalog.ComputeSchemaDiff(logEntry, nextLogEntry)
+	require.Len(t, diff.TablesRemoved, 1)
+	require.Equal(t, "orders", diff.TablesRemoved[0].Name)
+
+	// changes spanning multiple schemas are not supported
+	_, err = d.Deserialise(ctx, []byte(`{"schemaName":"public","tableChanges":[{"type":"DROP","id":"other.orders"}]}`))
+	require.ErrorIs(t, err, errMultipleSchemas)
+}
+
+func TestDeserialiser_roundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	serialiser := NewSerialiser()
+	deserialiser := NewDeserialiser()
+
+	insert := &wal.Data{
+		Action:    "I",
+		Timestamp: "2024-01-02T03:04:05Z",
+		LSN:       "0/16B3748",
+		XID:       42,
+		Schema:    "public",
+		Table:     "users",
+		Columns: []wal.Column{
+			{Name: "id", Value: float64(1)},
+			{Name: "name", Value: "alice"},
+		},
+	}
+	msg, err := serialiser.Serialise(ctx, insert)
+	require.NoError(t, err)
+	got, err := deserialiser.Deserialise(ctx, msg)
+	require.NoError(t, err)
+	// the envelope rows are serialised as maps, so the column order is not
+	// guaranteed
+	require.ElementsMatch(t, insert.Columns, got.Columns)
+	got.Columns = insert.Columns
+	require.Equal(t, insert, got)
+}
+
+func TestParseTableID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		id string
+
+		wantSchema string
+		wantTable  string
+		wantErr    error
+	}{
+		{id: `"public"."users"`, wantSchema: "public", wantTable: "users"},
+		{id: `"my.schema"."my table"`, wantSchema: "my.schema", wantTable: "my table"},
+		{id: `postgres.public.users`, wantSchema: "public", wantTable: "users"},
+		{id: `users`, wantTable: "users"},
+		{id: `"public"."users`, wantErr: errInvalidTableID},
+		{id: `a.b.c.d`, wantErr: errInvalidTableID},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.id, func(t *testing.T) {
+			t.Parallel()
+
+			schema, table, err := parseTableID(tc.id)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantSchema, schema)
+			require.Equal(t, tc.wantTable, table)
+		})
+	}
+}
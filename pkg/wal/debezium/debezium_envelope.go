@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package debezium
+
+// Envelope is the value of a debezium change event, as produced by the
+// debezium postgres connector with the JSON converter and schemas disabled.
+type Envelope struct {
+	// Before contains the row state before the change. Only populated for
+	// updates and deletes, with the replica identity columns.
+	Before map[string]any `json:"before"`
+	// After contains the row state after the change. Only populated for
+	// creates, updates and snapshot reads.
+	After  map[string]any `json:"after"`
+	Source Source         `json:"source"`
+	// Op is the type of operation. One of c (create), u (update), d (delete),
+	// r (snapshot read), t (truncate) or m (logical decoding message).
+	Op string `json:"op"`
+	// TsMs is the time at which the event was processed, in milliseconds
+	// since epoch.
+	TsMs        int64        `json:"ts_ms"`
+	Transaction *Transaction `json:"transaction"`
+	// Message is only populated for logical decoding messages.
+	Message *Message `json:"message,omitempty"`
+}
+
+// Source contains the metadata of the source of the change event.
+type Source struct {
+	Version   string `json:"version"`
+	Connector string `json:"connector"`
+	Name      string `json:"name"`
+	// TsMs is the time at which the change was committed in the source
+	// database, in milliseconds since epoch.
+	TsMs     int64  `json:"ts_ms"`
+	Snapshot string `json:"snapshot"`
+	DB       string `json:"db"`
+	Schema   string `json:"schema"`
+	Table    string `json:"table"`
+	TxID     *int64 `json:"txId"`
+	LSN      *int64 `json:"lsn"`
+}
+
+// Transaction contains the position of the change event within its source
+// transaction.
+type Transaction struct {
+	ID                  string `json:"id"`
+	TotalOrder          int64  `json:"total_order"`
+	DataCollectionOrder int64  `json:"data_collection_order"`
+}
+
+// Message contains the logical decoding message emitted with
+// pg_logical_emit_message. The content is base64 encoded in JSON.
+type Message struct {
+	Prefix  string `json:"prefix"`
+	Content []byte `json:"content"`
+}
+
+// TransactionEvent is the debezium transaction metadata event, produced for
+// the begin and commit of the source transactions.
+type TransactionEvent struct {
+	// Status is one of BEGIN or END.
+	Status string `json:"status"`
+	ID     string `json:"id"`
+	// EventCount and DataCollections are only populated for END events.
+	EventCount      *int64           `json:"event_count"`
+	DataCollections []DataCollection `json:"data_collections"`
+	TsMs            int64            `json:"ts_ms"`
+}
+
+// DataCollection contains the number of events of a table within a
+// transaction.
+type DataCollection struct {
+	DataCollection string `json:"data_collection"`
+	EventCount     int64  `json:"event_count"`
+}
+
+// SchemaChange is the debezium schema change event, produced for the pgstream
+// schema log entries.
+type SchemaChange struct {
+	Source       Source        `json:"source"`
+	TsMs         int64         `json:"ts_ms"`
+	DatabaseName string        `json:"databaseName"`
+	SchemaName   string        `json:"schemaName"`
+	DDL          string        `json:"ddl"`
+	TableChanges []TableChange `json:"tableChanges"`
+}
+
+// TableChange describes the change of a table. The table is not populated for
+// dropped tables.
+type TableChange struct {
+	// Type is one of CREATE, ALTER or DROP.
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Table *Table `json:"table"`
+}
+
+type Table struct {
+	PrimaryKeyColumnNames []string `json:"primaryKeyColumnNames"`
+	Columns               []Column `json:"columns"`
+}
+
+type Column struct {
+	Name                   string  `json:"name"`
+	TypeName               string  `json:"typeName"`
+	TypeExpression         string  `json:"typeExpression"`
+	Optional               bool    `json:"optional"`
+	Position               int     `json:"position"`
+	DefaultValueExpression *string `json:"defaultValueExpression"`
+	Generated              bool    `json:"generated"`
+}
+
+const (
+	OpCreate   = "c"
+	OpUpdate   = "u"
+	OpDelete   = "d"
+	OpRead     = "r"
+	OpTruncate = "t"
+	OpMessage  = "m"
+
+	TransactionStatusBegin = "BEGIN"
+	TransactionStatusEnd   = "END"
+
+	TableChangeCreate = "CREATE"
+	TableChangeAlter  = "ALTER"
+	TableChangeDrop   = "DROP"
+)
@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package debezium
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// connectSchema is the kafka connect schema added to the debezium events when
+// the JSON converter has schemas enabled. It's used to decode the values of
+// the debezium logical types.
+type connectSchema struct {
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	Field      string            `json:"field"`
+	Fields     []connectSchema   `json:"fields"`
+	Parameters map[string]string `json:"parameters"`
+}
+
+const (
+	logicalTypeDate                 = "io.debezium.time.Date"
+	logicalTypeTime                 = "io.debezium.time.Time"
+	logicalTypeMicroTime            = "io.debezium.time.MicroTime"
+	logicalTypeNanoTime             = "io.debezium.time.NanoTime"
+	logicalTypeTimestamp            = "io.debezium.time.Timestamp"
+	logicalTypeMicroTimestamp       = "io.debezium.time.MicroTimestamp"
+	logicalTypeNanoTimestamp        = "io.debezium.time.NanoTimestamp"
+	logicalTypeVariableScaleDecimal = "io.debezium.data.VariableScaleDecimal"
+	logicalTypeConnectDate          = "org.apache.kafka.connect.data.Date"
+	logicalTypeConnectTime          = "org.apache.kafka.connect.data.Time"
+	logicalTypeConnectTimestamp     = "org.apache.kafka.connect.data.Timestamp"
+	logicalTypeConnectDecimal       = "org.apache.kafka.connect.data.Decimal"
+
+	// the values are formatted like the postgres text output, as produced by
+	// the postgres listener
+	dateFormat      = "2006-01-02"
+	timeFormat      = "15:04:05.999999999"
+	timestampFormat = "2006-01-02 15:04:05.999999999"
+)
+
+// field returns the schema of the field with the name on input, if any.
+func (s *connectSchema) field(name string) *connectSchema {
+	if s == nil {
+		return nil
+	}
+	for i := range s.Fields {
+		if s.Fields[i].Field == name {
+			return &s.Fields[i]
+		}
+	}
+	return nil
+}
+
+// decodeValue returns the value of the raw debezium field on input. Values
+// with a logical type or with the bytes type are decoded using their schema,
+// while the rest are decoded as plain JSON. Temporal values are returned in
+// the postgres text format, decimals as strings to keep their precision, and
+// bytes as hex encoded bytea strings.
+func decodeValue(raw json.RawMessage, schema *connectSchema) (any, error) {
+	if schema == nil || isNull(raw) {
+		return unmarshalValue(raw)
+	}
+
+	switch schema.Name {
+	case logicalTypeDate, logicalTypeConnectDate:
+		days, err := unmarshalInt(raw)
+		if err != nil {
+			return nil, err
+		}
+		return time.Unix(days*int64(24*time.Hour/time.Second), 0).UTC().Format(dateFormat), nil
+	case logicalTypeTime, logicalTypeConnectTime:
+		return decodeTime(raw, time.Millisecond)
+	case logicalTypeMicroTime:
+		return decodeTime(raw, time.Microsecond)
+	case logicalTypeNanoTime:
+		return decodeTime(raw, time.Nanosecond)
+	case logicalTypeTimestamp, logicalTypeConnectTimestamp:
+		return decodeTimestamp(raw, time.UnixMilli)
+	case logicalTypeMicroTimestamp:
+		return decodeTimestamp(raw, time.UnixMicro)
+	case logicalTypeNanoTimestamp:
+		return decodeTimestamp(raw, func(ns int64) time.Time { return time.Unix(0, ns) })
+	case logicalTypeConnectDecimal:
+		scale, err := strconv.Atoi(schema.Parameters["scale"])
+		if err != nil {
+			return nil, fmt.Errorf("%w: decimal scale: %w", errUnsupportedEvent, err)
+		}
+		return decodeDecimal(raw, scale)
+	case logicalTypeVariableScaleDecimal:
+		value := struct {
+			Scale int             `json:"scale"`
+			Value json.RawMessage `json:"value"`
+		}{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("%w: variable scale decimal: %w", errUnsupportedEvent, err)
+		}
+		return decodeDecimal(value.Value, value.Scale)
+	}
+
+	if schema.Type == "bytes" {
+		b, err := unmarshalBytes(raw)
+		if err != nil {
+			return nil, err
+		}
+		return `\x` + hex.EncodeToString(b), nil
+	}
+
+	return unmarshalValue(raw)
+}
+
+func decodeTime(raw json.RawMessage, unit time.Duration) (any, error) {
+	sinceMidnight, err := unmarshalInt(raw)
+	if err != nil {
+		return nil, err
+	}
+	return time.Unix(0, 0).UTC().Add(time.Duration(sinceMidnight) * unit).Format(timeFormat), nil
+}
+
+func decodeTimestamp(raw json.RawMessage, fromEpoch func(int64) time.Time) (any, error) {
+	sinceEpoch, err := unmarshalInt(raw)
+	if err != nil {
+		return nil, err
+	}
+	return fromEpoch(sinceEpoch).UTC().Format(timestampFormat), nil
+}
+
+// decodeDecimal returns the decimal string for the base64 encoded, big endian
+// two's complement unscaled value on input.
+func decodeDecimal(raw json.RawMessage, scale int) (any, error) {
+	if scale < 0 {
+		return nil, fmt.Errorf("%w: negative decimal scale %d", errUnsupportedEvent, scale)
+	}
+	b, err := unmarshalBytes(raw)
+	if err != nil {
+		return nil, err
+	}
+	unscaled := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	return new(big.Rat).SetFrac(unscaled, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)).FloatString(scale), nil
+}
+
+func unmarshalValue(raw json.RawMessage) (any, error) {
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func unmarshalInt(raw json.RawMessage) (int64, error) {
+	var value int64
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return 0, fmt.Errorf("%w: %w", errUnsupportedEvent, err)
+	}
+	return value, nil
+}
+
+func unmarshalBytes(raw json.RawMessage) ([]byte, error) {
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, fmt.Errorf("%w: %w", errUnsupportedEvent, err)
+	}
+	b, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errUnsupportedEvent, err)
+	}
+	return b, nil
+}
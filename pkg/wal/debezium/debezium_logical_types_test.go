@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package debezium
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		raw    string
+		schema *connectSchema
+
+		wantValue any
+		wantErr   error
+	}{
+		{
+			name:   "no schema",
+			raw:    `1`,
+			schema: nil,
+
+			wantValue: float64(1),
+		},
+		{
+			name:   "null",
+			raw:    `null`,
+			schema: &connectSchema{Type: "int32", Name: logicalTypeDate},
+
+			wantValue: nil,
+		},
+		{
+			name:   "date",
+			raw:    `19724`,
+			schema: &connectSchema{Type: "int32", Name: logicalTypeDate},
+
+			wantValue: "2024-01-02",
+		},
+		{
+			name:   "connect date",
+			raw:    `-1`,
+			schema: &connectSchema{Type: "int32", Name: logicalTypeConnectDate},
+
+			wantValue: "1969-12-31",
+		},
+		{
+			name:   "time",
+			raw:    `11045123`,
+			schema: &connectSchema{Type: "int32", Name: logicalTypeTime},
+
+			wantValue: "03:04:05.123",
+		},
+		{
+			name:   "micro time",
+			raw:    `11045123456`,
+			schema: &connectSchema{Type: "int64", Name: logicalTypeMicroTime},
+
+			wantValue: "03:04:05.123456",
+		},
+		{
+			name:   "timestamp",
+			raw:    `1704164645123`,
+			schema: &connectSchema{Type: "int64", Name: logicalTypeTimestamp},
+
+			wantValue: "2024-01-02 03:04:05.123",
+		},
+		{
+			name:   "micro timestamp",
+			raw:    `1704164645123456`,
+			schema: &connectSchema{Type: "int64", Name: logicalTypeMicroTimestamp},
+
+			wantValue: "2024-01-02 03:04:05.123456",
+		},
+		{
+			name:   "nano timestamp",
+			raw:    `1704164645123456789`,
+			schema: &connectSchema{Type: "int64", Name: logicalTypeNanoTimestamp},
+
+			wantValue: "2024-01-02 03:04:05.123456789",
+		},
+		{
+			name:   "zoned timestamp",
+			raw:    `"2024-01-02T03:04:05.123456Z"`,
+			schema: &connectSchema{Type: "string", Name: "io.debezium.time.ZonedTimestamp"},
+
+			wantValue: "2024-01-02T03:04:05.123456Z",
+		},
+		{
+			name:   "decimal",
+			raw:    `"EtaH"`,
+			schema: &connectSchema{Type: "bytes", Name: logicalTypeConnectDecimal, Parameters: map[string]string{"scale": "2"}},
+
+			wantValue: "12345.67",
+		},
+		{
+			name:   "negative decimal",
+			raw:    `"/2o="`,
+			schema: &connectSchema{Type: "bytes", Name: logicalTypeConnectDecimal, Parameters: map[string]string{"scale": "2"}},
+
+			wantValue: "-1.50",
+		},
+		{
+			name:   "variable scale decimal",
+			raw:    `{"scale":3,"value":"EtaH"}`,
+			schema: &connectSchema{Type: "struct", Name: logicalTypeVariableScaleDecimal},
+
+			wantValue: "1234.567",
+		},
+		{
+			name:   "bytes",
+			raw:    `"AQL/"`,
+			schema: &connectSchema{Type: "bytes"},
+
+			wantValue: `\x0102ff`,
+		},
+		{
+			name:   "error - decimal without scale",
+			raw:    `"EtaH"`,
+			schema: &connectSchema{Type: "bytes", Name: logicalTypeConnectDecimal},
+
+			wantErr: errUnsupportedEvent,
+		},
+		{
+			name:   "error - invalid base64",
+			raw:    `"not base64"`,
+			schema: &connectSchema{Type: "bytes"},
+
+			wantErr: errUnsupportedEvent,
+		},
+		{
+			name:   "error - date with unexpected type",
+			raw:    `"2024-01-02"`,
+			schema: &connectSchema{Type: "int32", Name: logicalTypeDate},
+
+			wantErr: errUnsupportedEvent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			value, err := decodeValue(json.RawMessage(tc.raw), tc.schema)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantValue, value)
+		})
+	}
+}
@@ -0,0 +1,408 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package debezium
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/jonboulle/clockwork"
+	pgstreamjson "github.com/xataio/pgstream/internal/json"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+)
+
+// Serialiser serialises wal data into debezium change events, so that
+// consumers built for the debezium postgres connector can consume pgstream
+// events. Row events are serialised as change event envelopes, transaction
+// boundaries as transaction metadata events, and schema log entries as schema
+// change events. The row values are not converted into the debezium logical
+// types, they keep the representation of the pgstream events.
+type Serialiser struct {
+	clock clockwork.Clock
+
+	mutex sync.Mutex
+	// schemas contains the last schema log entry per schema name, used to
+	// compute the table changes of the schema change events
+	schemas map[string]*schemalog.LogEntry
+	// tx keeps track of the events of the ongoing source transaction
+	tx *transactionState
+}
+
+type transactionState struct {
+	xid    uint32
+	tables []string
+	events map[string]int64
+}
+
+type Option func(*Serialiser)
+
+const (
+	// sourceVersion and sourceConnector identify pgstream as the producer of
+	// the change events
+	sourceVersion   = "pgstream"
+	sourceConnector = "postgresql"
+	sourceName      = "pgstream"
+
+	// snapshot events are produced with a zero LSN
+	zeroLSN = "0/0"
+)
+
+func NewSerialiser(opts ...Option) *Serialiser {
+	s := &Serialiser{
+		clock:   clockwork.NewRealClock(),
+		schemas: map[string]*schemalog.LogEntry{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func WithClock(c clockwork.Clock) Option {
+	return func(s *Serialiser) {
+		s.clock = c
+	}
+}
+
+// Serialise returns the debezium change event for the wal data on input. It
+// returns nil if the event has no debezium equivalent (i.e. schema log
+// updates). It can be called concurrently.
+func (s *Serialiser) Serialise(_ context.Context, data *wal.Data) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var event any
+	var err error
+	switch {
+	case processor.IsSchemaLogEvent(data):
+		if !data.IsInsert() {
+			return nil, nil
+		}
+		if event, err = s.schemaChange(data); err != nil {
+			return nil, err
+		}
+	case data.IsBegin(), data.IsCommit():
+		event = s.transactionEvent(data)
+	case data.IsLogicalMessage():
+		event = s.messageEnvelope(data)
+	case data.Action == "I", data.Action == "U", data.Action == "D", data.Action == "T":
+		event = s.rowEnvelope(data)
+	default:
+		return nil, nil
+	}
+
+	b, err := pgstreamjson.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling debezium event: %w", err)
+	}
+	return b, nil
+}
+
+// SerialiseKey returns the debezium key for the wal data on input, a JSON
+// object with the primary key column values of the row. The primary key
+// columns are identified by the pgstream metadata, falling back to the replica
+// identity columns. It returns nil if the key columns can't be identified.
+func (s *Serialiser) SerialiseKey(data *wal.Data) ([]byte, error) {
+	switch data.Action {
+	case "I", "U", "D":
+		if processor.IsSchemaLogEvent(data) {
+			return nil, nil
+		}
+	default:
+		return nil, nil
+	}
+
+	key := keyColumns(data)
+	if len(key) == 0 {
+		return nil, nil
+	}
+
+	// use the standard library marshaling, which sorts the map keys, so that
+	// the key is deterministic
+	b, err := json.Marshal(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling debezium key: %w", err)
+	}
+	return b, nil
+}
+
+func (s *Serialiser) rowEnvelope(data *wal.Data) *Envelope {
+	envelope := &Envelope{
+		Source: s.source(data),
+		TsMs:   s.clock.Now().UnixMilli(),
+	}
+
+	switch data.Action {
+	case "I":
+		envelope.Op = OpCreate
+		if isSnapshot(data) {
+			envelope.Op = OpRead
+		}
+		envelope.After = columnValues(data.Columns)
+	case "U":
+		envelope.Op = OpUpdate
+		envelope.Before = columnValues(data.Identity)
+		envelope.After = columnValues(data.Columns)
+	case "D":
+		envelope.Op = OpDelete
+		envelope.Before = columnValues(data.Identity)
+	case "T":
+		envelope.Op = OpTruncate
+	}
+
+	if data.Transaction != nil && !isSnapshot(data) {
+		envelope.Transaction = &Transaction{
+			ID:                  transactionID(data.Transaction.XID),
+			TotalOrder:          int64(data.Transaction.Sequence),
+			DataCollectionOrder: s.trackTransactionEvent(data),
+		}
+	}
+
+	return envelope
+}
+
+func (s *Serialiser) messageEnvelope(data *wal.Data) *Envelope {
+	envelope := &Envelope{
+		Op:     OpMessage,
+		Source: s.source(data),
+		TsMs:   s.clock.Now().UnixMilli(),
+		Message: &Message{
+			Prefix:  data.Prefix,
+			Content: []byte(data.Content),
+		},
+	}
+	if data.Transactional && data.Transaction != nil {
+		envelope.Transaction = &Transaction{
+			ID:         transactionID(data.Transaction.XID),
+			TotalOrder: int64(data.Transaction.Sequence),
+		}
+	}
+	return envelope
+}
+
+// transactionEvent returns the debezium transaction metadata event for the
+// begin or commit event on input. The commit events include the number of
+// events per table within the transaction.
+func (s *Serialiser) transactionEvent(data *wal.Data) *TransactionEvent {
+	xid := data.XID
+	if data.Transaction != nil {
+		xid = data.Transaction.XID
+	}
+	event := &TransactionEvent{
+		Status: TransactionStatusBegin,
+		ID:     transactionID(xid),
+		TsMs:   s.source(data).TsMs,
+	}
+
+	if data.IsBegin() {
+		s.tx = &transactionState{xid: xid, events: map[string]int64{}}
+		return event
+	}
+
+	event.Status = TransactionStatusEnd
+	total := int64(0)
+	event.DataCollections = []DataCollection{}
+	if s.tx != nil && s.tx.xid == xid {
+		for _, table := range s.tx.tables {
+			event.DataCollections = append(event.DataCollections, DataCollection{
+				DataCollection: table,
+				EventCount:     s.tx.events[table],
+			})
+			total += s.tx.events[table]
+		}
+	}
+	event.EventCount = &total
+	s.tx = nil
+	return event
+}
+
+// trackTransactionEvent records the row event on input as part of the ongoing
+// transaction, and returns its order within the events of the same table.
+func (s *Serialiser) trackTransactionEvent(data *wal.Data) int64 {
+	if s.tx == nil || s.tx.xid != data.Transaction.XID {
+		s.tx = &transactionState{xid: data.Transaction.XID, events: map[string]int64{}}
+	}
+	table := data.Schema + "." + data.Table
+	if _, found := s.tx.events[table]; !found {
+		s.tx.tables = append(s.tx.tables, table)
+	}
+	s.tx.events[table]++
+	return s.tx.events[table]
+}
+
+// schemaChange returns the debezium schema change event for the schema log
+// entry on input. The table changes are computed against the previous schema
+// log entry for the same schema. If there's none, all the tables are reported
+// as created.
+func (s *Serialiser) schemaChange(data *wal.Data) (*SchemaChange, error) {
+	logEntry, err := processor.WalDataToLogEntry(data)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := s.schemas[logEntry.SchemaName]
+	s.schemas[logEntry.SchemaName] = logEntry
+
+	diff := schemalog.ComputeSchemaDiff(previous, logEntry)
+	changes := make([]TableChange, 0, len(diff.TablesAdded)+len(diff.TablesChanged)+len(diff.TablesRemoved))
+	for _, table := range diff.TablesAdded {
+		changes = append(changes, TableChange{
+			Type:  TableChangeCreate,
+			ID:    tableID(logEntry.SchemaName, table.Name),
+			Table: newTable(&table),
+		})
+	}
+	for _, tableDiff := range diff.TablesChanged {
+		table, found := logEntry.GetTableByName(tableDiff.TableName)
+		if !found {
+			continue
+		}
+		changes = append(changes, TableChange{
+			Type:  TableChangeAlter,
+			ID:    tableID(logEntry.SchemaName, table.Name),
+			Table: newTable(&table),
+		})
+	}
+	for _, table := range diff.TablesRemoved {
+		changes = append(changes, TableChange{
+			Type: TableChangeDrop,
+			ID:   tableID(logEntry.SchemaName, table.Name),
+		})
+	}
+	// the schema diff doesn't keep the order of the tables
+	slices.SortStableFunc(changes, func(a, b TableChange) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	source := s.source(data)
+	source.Schema = logEntry.SchemaName
+	source.Table = ""
+	if !logEntry.CreatedAt.IsZero() {
+		source.TsMs = logEntry.CreatedAt.UnixMilli()
+	}
+
+	return &SchemaChange{
+		Source:       source,
+		TsMs:         s.clock.Now().UnixMilli(),
+		SchemaName:   logEntry.SchemaName,
+		TableChanges: changes,
+	}, nil
+}
+
+func (s *Serialiser) source(data *wal.Data) Source {
+	source := Source{
+		Version:   sourceVersion,
+		Connector: sourceConnector,
+		Name:      sourceName,
+		Snapshot:  "false",
+		Schema:    data.Schema,
+		Table:     data.Table,
+	}
+
+	if isSnapshot(data) {
+		source.Snapshot = "true"
+	}
+
+	// use the commit timestamp when available, since that's when the change
+	// was made visible in the source database
+	timestamp := data.Timestamp
+	if data.Transaction != nil && data.Transaction.CommitTimestamp != "" {
+		timestamp = data.Transaction.CommitTimestamp
+	}
+	if ts, err := (&wal.Data{Timestamp: timestamp}).GetTimestamp(); err == nil {
+		source.TsMs = ts.UnixMilli()
+	}
+
+	if data.XID != 0 {
+		txID := int64(data.XID)
+		source.TxID = &txID
+	}
+
+	if data.LSN != "" && !isSnapshot(data) {
+		if lsn, err := pglogrepl.ParseLSN(data.LSN); err == nil {
+			lsnValue := int64(lsn)
+			source.LSN = &lsnValue
+		}
+	}
+
+	return source
+}
+
+func keyColumns(data *wal.Data) map[string]any {
+	key := map[string]any{}
+	if len(data.Metadata.InternalColIDs) > 0 {
+		// the new values take precedence over the identity, since the primary
+		// key might have been updated
+		for _, cols := range [][]wal.Column{data.Identity, data.Columns} {
+			for _, col := range cols {
+				if data.Metadata.IsIDColumn(col.ID) {
+					key[col.Name] = col.Value
+				}
+			}
+		}
+		return key
+	}
+
+	for _, col := range data.Identity {
+		key[col.Name] = col.Value
+	}
+	return key
+}
+
+func newTable(table *schemalog.Table) *Table {
+	t := &Table{
+		PrimaryKeyColumnNames: table.PrimaryKeyColumns,
+		Columns:               make([]Column, 0, len(table.Columns)),
+	}
+	if t.PrimaryKeyColumnNames == nil {
+		t.PrimaryKeyColumnNames = []string{}
+	}
+	for i, col := range table.Columns {
+		t.Columns = append(t.Columns, Column{
+			Name:                   col.Name,
+			TypeName:               col.DataType,
+			TypeExpression:         col.DataType,
+			Optional:               col.Nullable,
+			Position:               i + 1,
+			DefaultValueExpression: col.DefaultValue,
+			Generated:              col.Generated,
+		})
+	}
+	return t
+}
+
+// columnValues returns the row values for the columns on input, as they are.
+// Temporal values are kept as postgres text strings, numeric values as JSON
+// numbers and bytea values as hex encoded strings.
+func columnValues(columns []wal.Column) map[string]any {
+	if len(columns) == 0 {
+		return nil
+	}
+	values := make(map[string]any, len(columns))
+	for _, col := range columns {
+		values[col.Name] = col.Value
+	}
+	return values
+}
+
+func isSnapshot(data *wal.Data) bool {
+	return data.LSN == zeroLSN
+}
+
+func transactionID(xid uint32) string {
+	return strconv.FormatUint(uint64(xid), 10)
+}
+
+func tableID(schemaName, tableName string) string {
+	return strconv.Quote(schemaName) + "." + strconv.Quote(tableName)
+}
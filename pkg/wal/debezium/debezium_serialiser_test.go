@@ -0,0 +1,417 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package debezium
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/internal/json"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+var (
+	testNow       = time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC)
+	testTimestamp = "2024-01-02 03:04:05.000000+00"
+	testTsMs      = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixMilli()
+)
+
+func TestSerialiser_Serialise(t *testing.T) {
+	t.Parallel()
+
+	txID := int64(42)
+	lsn := int64(0x16B3748)
+	testSource := Source{
+		Version:   sourceVersion,
+		Connector: sourceConnector,
+		Name:      sourceName,
+		TsMs:      testTsMs,
+		Snapshot:  "false",
+		Schema:    "public",
+		Table:     "users",
+		TxID:      &txID,
+		LSN:       &lsn,
+	}
+	testTransaction := &wal.Transaction{XID: 42, Sequence: 2}
+	testColumns := []wal.Column{
+		{ID: "t1-1", Name: "id", Type: "bigint", Value: float64(1)},
+		{ID: "t1-2", Name: "name", Type: "text", Value: "alice"},
+	}
+	testIdentity := []wal.Column{
+		{ID: "t1-1", Name: "id", Type: "bigint", Value: float64(1)},
+	}
+
+	newData := func(action string, columns, identity []wal.Column) *wal.Data {
+		return &wal.Data{
+			Action:      action,
+			Timestamp:   testTimestamp,
+			LSN:         "0/16B3748",
+			XID:         42,
+			Schema:      "public",
+			Table:       "users",
+			Columns:     columns,
+			Identity:    identity,
+			Transaction: testTransaction,
+		}
+	}
+
+	tests := []struct {
+		name string
+		data *wal.Data
+
+		wantEnvelope *Envelope
+	}{
+		{
+			name: "insert",
+			data: newData("I", testColumns, nil),
+
+			wantEnvelope: &Envelope{
+				After:       map[string]any{"id": float64(1), "name": "alice"},
+				Source:      testSource,
+				Op:          OpCreate,
+				TsMs:        testNow.UnixMilli(),
+				Transaction: &Transaction{ID: "42", TotalOrder: 2, DataCollectionOrder: 1},
+			},
+		},
+		{
+			name: "update",
+			data: newData("U", testColumns, testIdentity),
+
+			wantEnvelope: &Envelope{
+				Before:      map[string]any{"id": float64(1)},
+				After:       map[string]any{"id": float64(1), "name": "alice"},
+				Source:      testSource,
+				Op:          OpUpdate,
+				TsMs:        testNow.UnixMilli(),
+				Transaction: &Transaction{ID: "42", TotalOrder: 2, DataCollectionOrder: 1},
+			},
+		},
+		{
+			name: "delete",
+			data: newData("D", nil, testIdentity),
+
+			wantEnvelope: &Envelope{
+				Before:      map[string]any{"id": float64(1)},
+				Source:      testSource,
+				Op:          OpDelete,
+				TsMs:        testNow.UnixMilli(),
+				Transaction: &Transaction{ID: "42", TotalOrder: 2, DataCollectionOrder: 1},
+			},
+		},
+		{
+			name: "truncate",
+			data: newData("T", nil, nil),
+
+			wantEnvelope: &Envelope{
+				Source:      testSource,
+				Op:          OpTruncate,
+				TsMs:        testNow.UnixMilli(),
+				Transaction: &Transaction{ID: "42", TotalOrder: 2, DataCollectionOrder: 1},
+			},
+		},
+		{
+			name: "snapshot read",
+			data: &wal.Data{
+				Action:    "I",
+				Timestamp: "2024-01-02T03:04:05Z",
+				LSN:       zeroLSN,
+				Schema:    "public",
+				Table:     "users",
+				Columns:   testColumns,
+			},
+
+			wantEnvelope: &Envelope{
+				After: map[string]any{"id": float64(1), "name": "alice"},
+				Source: Source{
+					Version:   sourceVersion,
+					Connector: sourceConnector,
+					Name:      sourceName,
+					TsMs:      testTsMs,
+					Snapshot:  "true",
+					Schema:    "public",
+					Table:     "users",
+				},
+				Op:   OpRead,
+				TsMs: testNow.UnixMilli(),
+			},
+		},
+		{
+			name: "logical message",
+			data: &wal.Data{
+				Action:        "M",
+				Timestamp:     testTimestamp,
+				LSN:           "0/16B3748",
+				XID:           42,
+				Transactional: true,
+				Prefix:        "audit",
+				Content:       "hello",
+				Transaction:   testTransaction,
+			},
+
+			wantEnvelope: &Envelope{
+				Source: Source{
+					Version:   sourceVersion,
+					Connector: sourceConnector,
+					Name:      sourceName,
+					TsMs:      testTsMs,
+					Snapshot:  "false",
+					TxID:      &txID,
+					LSN:       &lsn,
+				},
+				Op:          OpMessage,
+				TsMs:        testNow.UnixMilli(),
+				Transaction: &Transaction{ID: "42", TotalOrder: 2},
+				Message:     &Message{Prefix: "audit", Content: []byte("hello")},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := NewSerialiser(WithClock(clockwork.NewFakeClockAt(testNow)))
+			b, err := s.Serialise(context.Background(), tc.data)
+			require.NoError(t, err)
+
+			envelope := &Envelope{}
+			require.NoError(t, json.Unmarshal(b, envelope))
+			require.Equal(t, tc.wantEnvelope, envelope)
+		})
+	}
+}
+
+func TestSerialiser_Serialise_transaction(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s := NewSerialiser(WithClock(clockwork.NewFakeClockAt(testNow)))
+
+	newTxData := func(action, table string, sequence uint64) *wal.Data {
+		return &wal.Data{
+			Action:      action,
+			Timestamp:   testTimestamp,
+			LSN:         "0/16B3748",
+			XID:         42,
+			Schema:      "public",
+			Table:       table,
+			Columns:     []wal.Column{{Name: "id", Value: float64(sequence)}},
+			Transaction: &wal.Transaction{XID: 42, Sequence: sequence, CommitTimestamp: testTimestamp},
+		}
+	}
+
+	b, err := s.Serialise(ctx, newTxData("B", "", 0))
+	require.NoError(t, err)
+	begin := &TransactionEvent{}
+	require.NoError(t, json.Unmarshal(b, begin))
+	require.Equal(t, &TransactionEvent{
+		Status: TransactionStatusBegin,
+		ID:     "42",
+		TsMs:   testTsMs,
+	}, begin)
+
+	wantOrders := []int64{1, 1, 2}
+	for i, table := range []string{"users", "orders", "users"} {
+		b, err := s.Serialise(ctx, newTxData("I", table, uint64(i+1)))
+		require.NoError(t, err)
+		envelope := &Envelope{}
+		require.NoError(t, json.Unmarshal(b, envelope))
+		require.Equal(t, &Transaction{ID: "42", TotalOrder: int64(i + 1), DataCollectionOrder: wantOrders[i]}, envelope.Transaction)
+	}
+
+	b, err = s.Serialise(ctx, newTxData("C", "", 4))
+	require.NoError(t, err)
+	end := &TransactionEvent{}
+	require.NoError(t, json.Unmarshal(b, end))
+	eventCount := int64(3)
+	require.Equal(t, &TransactionEvent{
+		Status:     TransactionStatusEnd,
+		ID:         "42",
+		EventCount: &eventCount,
+		DataCollections: []DataCollection{
+			{DataCollection: "public.users", EventCount: 2},
+			{DataCollection: "public.orders", EventCount: 1},
+		},
+		TsMs: testTsMs,
+	}, end)
+}
+
+func TestSerialiser_Serialise_schemaChange(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s := NewSerialiser(WithClock(clockwork.NewFakeClockAt(testNow)))
+
+	defaultValue := "0"
+	usersTable := schemalog.Table{
+		Name:              "users",
+		PgstreamID:        "t1",
+		PrimaryKeyColumns: []string{"id"},
+		Columns: []schemalog.Column{
+			{Name: "id", DataType: "bigint", PgstreamID: "t1-1"},
+		},
+	}
+	ordersTable := schemalog.Table{
+		Name:       "orders",
+		PgstreamID: "t2",
+		Columns: []schemalog.Column{
+			{Name: "total", DataType: "numeric", Nullable: true, DefaultValue: &defaultValue, PgstreamID: "t2-1"},
+		},
+	}
+
+	b, err := s.Serialise(ctx, newTestSchemaLogEvent(t, usersTable, ordersTable))
+	require.NoError(t, err)
+	change := &SchemaChange{}
+	require.NoError(t, json.Unmarshal(b, change))
+	require.Equal(t, "public", change.SchemaName)
+	require.Equal(t, "public", change.Source.Schema)
+	require.Equal(t, testNow.UnixMilli(), change.TsMs)
+	require.Equal(t, []TableChange{
+		{
+			Type: TableChangeCreate,
+			ID:   `"public"."orders"`,
+			Table: &Table{
+				PrimaryKeyColumnNames: []string{},
+				Columns: []Column{
+					{Name: "total", TypeName: "numeric", TypeExpression: "numeric", Optional: true, Position: 1, DefaultValueExpression: &defaultValue},
+				},
+			},
+		},
+		{
+			Type: TableChangeCreate,
+			ID:   `"public"."users"`,
+			Table: &Table{
+				PrimaryKeyColumnNames: []string{"id"},
+				Columns: []Column{
+					{Name: "id", TypeName: "bigint", TypeExpression: "bigint", Position: 1},
+				},
+			},
+		},
+	}, change.TableChanges)
+
+	// alter the users table and drop the orders table
+	usersTable.Columns = append(usersTable.Columns, schemalog.Column{Name: "name", DataType: "text", Nullable: true, PgstreamID: "t1-2"})
+	b, err = s.Serialise(ctx, newTestSchemaLogEvent(t, usersTable))
+	require.NoError(t, err)
+	change = &SchemaChange{}
+	require.NoError(t, json.Unmarshal(b, change))
+	require.Equal(t, []TableChange{
+		{
+			Type: TableChangeDrop,
+			ID:   `"public"."orders"`,
+		},
+		{
+			Type: TableChangeAlter,
+			ID:   `"public"."users"`,
+			Table: &Table{
+				PrimaryKeyColumnNames: []string{"id"},
+				Columns: []Column{
+					{Name: "id", TypeName: "bigint", TypeExpression: "bigint", Position: 1},
+					{Name: "name", TypeName: "text", TypeExpression: "text", Optional: true, Position: 2},
+				},
+			},
+		},
+	}, change.TableChanges)
+
+	// schema log updates are skipped
+	update := newTestSchemaLogEvent(t, usersTable)
+	update.Action = "U"
+	b, err = s.Serialise(ctx, update)
+	require.NoError(t, err)
+	require.Nil(t, b)
+}
+
+func TestSerialiser_SerialiseKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data *wal.Data
+
+		wantKey []byte
+	}{
+		{
+			name: "pgstream metadata id columns",
+			data: &wal.Data{
+				Action: "I",
+				Schema: "public",
+				Table:  "users",
+				Columns: []wal.Column{
+					{ID: "t1-2", Name: "tenant", Value: "a"},
+					{ID: "t1-3", Name: "name", Value: "alice"},
+					{ID: "t1-1", Name: "id", Value: float64(1)},
+				},
+				Metadata: wal.Metadata{InternalColIDs: []string{"t1-1", "t1-2"}},
+			},
+
+			wantKey: []byte(`{"id":1,"tenant":"a"}`),
+		},
+		{
+			name: "identity columns",
+			data: &wal.Data{
+				Action:   "D",
+				Schema:   "public",
+				Table:    "users",
+				Identity: []wal.Column{{Name: "id", Value: float64(1)}},
+			},
+
+			wantKey: []byte(`{"id":1}`),
+		},
+		{
+			name: "no key columns",
+			data: &wal.Data{
+				Action:  "I",
+				Schema:  "public",
+				Table:   "users",
+				Columns: []wal.Column{{Name: "id", Value: float64(1)}},
+			},
+
+			wantKey: nil,
+		},
+		{
+			name: "non row event",
+			data: &wal.Data{
+				Action: "T",
+				Schema: "public",
+				Table:  "users",
+			},
+
+			wantKey: nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			key, err := NewSerialiser().SerialiseKey(tc.data)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantKey, key)
+		})
+	}
+}
+
+func newTestSchemaLogEvent(t *testing.T, tables ...schemalog.Table) *wal.Data {
+	schema, err := json.Marshal(schemalog.Schema{Tables: tables})
+	require.NoError(t, err)
+
+	return &wal.Data{
+		Action:    "I",
+		Timestamp: testTimestamp,
+		LSN:       "0/16B3748",
+		Schema:    schemalog.SchemaName,
+		Table:     schemalog.TableName,
+		Columns: []wal.Column{
+			{Name: "id", Type: "text", Value: xid.New().String()},
+			{Name: "version", Type: "bigint", Value: float64(1)},
+			{Name: "schema_name", Type: "text", Value: "public"},
+			{Name: "created_at", Type: "timestamp", Value: "2024-01-02 03:04:05"},
+			{Name: "schema", Type: "jsonb", Value: string(schema)},
+		},
+	}
+}
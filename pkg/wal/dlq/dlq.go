@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package dlq
+
+import (
+	"context"
+	"time"
+
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// Queue is a dead letter queue, where the WAL events that fail processing are
+// sent instead of being dropped, so that they can be inspected and replayed.
+type Queue interface {
+	Send(context.Context, *Entry) error
+	Close() error
+}
+
+// Entry is a dead letter queue record. It contains the original WAL event,
+// along with the details of the processing failure.
+type Entry struct {
+	Event *wal.Event `json:"event"`
+	// Processor is the name of the component that failed to process the event
+	Processor string    `json:"processor"`
+	Error     string    `json:"error"`
+	Attempts  int       `json:"attempts"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NewEntry returns a dead letter queue entry for the event that failed to be
+// processed by the processor on input after the given number of attempts.
+func NewEntry(event *wal.Event, processor string, err error, attempts int) *Entry {
+	entry := &Entry{
+		Event:     event,
+		Processor: processor,
+		Attempts:  attempts,
+		Timestamp: time.Now().UTC(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return entry
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/xataio/pgstream/internal/json"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
+)
+
+// Queue is a local file implementation of a dead letter queue. The entries are
+// appended to the configured file in NDJSON format (one JSON entry per line).
+type Queue struct {
+	mu        sync.Mutex
+	file      *os.File
+	marshaler func(any) ([]byte, error)
+}
+
+type Config struct {
+	Path string
+}
+
+// New returns a file dead letter queue that appends the entries to the
+// configured file, creating it if it doesn't exist.
+func New(cfg Config) (*Queue, error) {
+	f, err := os.OpenFile(cfg.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening dead letter queue file: %w", err)
+	}
+
+	return &Queue{
+		file:      f,
+		marshaler: json.Marshal,
+	}, nil
+}
+
+// Send appends the entry on input to the dead letter queue file. The file is
+// synced before returning, so that the entry is not lost if the process
+// crashes afterwards.
+func (q *Queue) Send(_ context.Context, entry *dlq.Entry) error {
+	entryBytes, err := q.marshaler(entry)
+	if err != nil {
+		return fmt.Errorf("marshaling dead letter queue entry: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, err := q.file.Write(append(entryBytes, '\n')); err != nil {
+		return fmt.Errorf("writing dead letter queue entry: %w", err)
+	}
+	if err := q.file.Sync(); err != nil {
+		return fmt.Errorf("syncing dead letter queue file: %w", err)
+	}
+	return nil
+}
+
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.file.Close()
+}
@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/internal/json"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
+)
+
+func TestQueue_Send(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "dlq.ndjson")
+
+	testEntry := func(position wal.CommitPosition) *dlq.Entry {
+		return &dlq.Entry{
+			Event: &wal.Event{
+				Data:           &wal.Data{Action: "I", Schema: "public", Table: "users"},
+				CommitPosition: position,
+			},
+			Processor: "webhook_notifier",
+			Error:     "oh noes",
+			Attempts:  1,
+			Timestamp: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+
+	q, err := New(Config{Path: path})
+	require.NoError(t, err)
+	require.NoError(t, q.Send(context.Background(), testEntry("0/1")))
+	require.NoError(t, q.Close())
+
+	// entries are appended to existing files
+	q, err = New(Config{Path: path})
+	require.NoError(t, err)
+	require.NoError(t, q.Send(context.Background(), testEntry("0/2")))
+	require.NoError(t, q.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	entries := []*dlq.Entry{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		entry := &dlq.Entry{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), entry))
+		entries = append(entries, entry)
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, []*dlq.Entry{testEntry("0/1"), testEntry("0/2")}, entries)
+}
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xataio/pgstream/internal/json"
+	"github.com/xataio/pgstream/pkg/kafka"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
+)
+
+// Queue is a kafka implementation of a dead letter queue. The entries are
+// produced as JSON messages to the configured topic, keyed by processor name.
+type Queue struct {
+	writer    kafka.MessageWriter
+	marshaler func(any) ([]byte, error)
+}
+
+type Config struct {
+	Conn kafka.ConnConfig
+}
+
+type Option func(*Queue)
+
+// New returns a kafka dead letter queue that writes the entries to the
+// configured topic.
+func New(cfg Config, opts ...Option) (*Queue, error) {
+	q := &Queue{
+		marshaler: json.Marshal,
+	}
+
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	if q.writer == nil {
+		var err error
+		q.writer, err = kafka.NewWriter(kafka.WriterConfig{
+			Conn: cfg.Conn,
+			// entries are sent one at a time, don't wait for a batch to fill up
+			BatchSize: 1,
+		}, loglib.NewNoopLogger())
+		if err != nil {
+			return nil, fmt.Errorf("creating kafka writer: %w", err)
+		}
+	}
+
+	return q, nil
+}
+
+func WithWriter(w kafka.MessageWriter) Option {
+	return func(q *Queue) {
+		q.writer = w
+	}
+}
+
+// Send produces the entry on input to the dead letter queue topic.
+func (q *Queue) Send(ctx context.Context, entry *dlq.Entry) error {
+	entryBytes, err := q.marshaler(entry)
+	if err != nil {
+		return fmt.Errorf("marshaling dead letter queue entry: %w", err)
+	}
+
+	if err := q.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(entry.Processor),
+		Value: entryBytes,
+	}); err != nil {
+		return fmt.Errorf("writing dead letter queue entry to kafka: %w", err)
+	}
+	return nil
+}
+
+func (q *Queue) Close() error {
+	return q.writer.Close()
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/internal/json"
+	"github.com/xataio/pgstream/pkg/kafka"
+	kafkamocks "github.com/xataio/pgstream/pkg/kafka/mocks"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
+)
+
+func TestQueue_Send(t *testing.T) {
+	t.Parallel()
+
+	testEntry := &dlq.Entry{
+		Event: &wal.Event{
+			Data:           &wal.Data{Action: "I", Schema: "public", Table: "users"},
+			CommitPosition: "0/1",
+		},
+		Processor: "wal_kafka_reader",
+		Error:     "oh noes",
+		Attempts:  1,
+		Timestamp: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	testEntryBytes, err := json.Marshal(testEntry)
+	require.NoError(t, err)
+
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name   string
+		writer *kafkamocks.Writer
+
+		wantErr error
+	}{
+		{
+			name: "ok",
+			writer: &kafkamocks.Writer{
+				WriteMessagesFn: func(ctx context.Context, _ uint64, msgs ...kafka.Message) error {
+					require.Equal(t, []kafka.Message{
+						{Key: []byte("wal_kafka_reader"), Value: testEntryBytes},
+					}, msgs)
+					return nil
+				},
+			},
+
+			wantErr: nil,
+		},
+		{
+			name: "error - writing message",
+			writer: &kafkamocks.Writer{
+				WriteMessagesFn: func(ctx context.Context, _ uint64, msgs ...kafka.Message) error {
+					return errTest
+				},
+			},
+
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			q, err := New(Config{}, WithWriter(tc.writer))
+			require.NoError(t, err)
+			defer q.Close()
+
+			err = q.Send(context.Background(), testEntry)
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package mocks
+
+import (
+	"context"
+	"sync"
+
+	"github.com/xataio/pgstream/pkg/wal/dlq"
+)
+
+type Queue struct {
+	SendFn  func(context.Context, *dlq.Entry) error
+	CloseFn func() error
+
+	mu      sync.Mutex
+	entries []*dlq.Entry
+}
+
+func (m *Queue) Send(ctx context.Context, entry *dlq.Entry) error {
+	m.mu.Lock()
+	m.entries = append(m.entries, entry)
+	m.mu.Unlock()
+
+	if m.SendFn != nil {
+		return m.SendFn(ctx, entry)
+	}
+	return nil
+}
+
+func (m *Queue) Close() error {
+	if m.CloseFn != nil {
+		return m.CloseFn()
+	}
+	return nil
+}
+
+// GetEntries returns the entries sent to the queue.
+func (m *Queue) GetEntries() []*dlq.Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*dlq.Entry{}, m.entries...)
+}
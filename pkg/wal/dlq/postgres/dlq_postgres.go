@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xataio/pgstream/internal/json"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
+)
+
+// Queue is a postgres implementation of a dead letter queue. The entries are
+// stored in the pgstream.dead_letter_queue table of the configured database.
+type Queue struct {
+	querier   pglib.Querier
+	marshaler func(any) ([]byte, error)
+}
+
+type Config struct {
+	URL string
+}
+
+const tableName = "dead_letter_queue"
+
+// New returns a postgres dead letter queue, creating the table where the
+// entries are stored if it doesn't exist.
+func New(ctx context.Context, cfg Config) (*Queue, error) {
+	pool, err := pglib.NewConnPool(ctx, cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("creating postgres connection pool: %w", err)
+	}
+
+	q, err := newQueue(ctx, pool)
+	if err != nil {
+		pool.Close(ctx)
+		return nil, err
+	}
+	return q, nil
+}
+
+func newQueue(ctx context.Context, querier pglib.Querier) (*Queue, error) {
+	q := &Queue{
+		querier:   querier,
+		marshaler: json.Marshal,
+	}
+
+	if err := q.createTable(ctx); err != nil {
+		return nil, fmt.Errorf("creating dead letter queue table: %w", err)
+	}
+
+	return q, nil
+}
+
+// Send stores the entry on input in the dead letter queue table.
+func (q *Queue) Send(ctx context.Context, entry *dlq.Entry) error {
+	eventBytes, err := q.marshaler(entry.Event)
+	if err != nil {
+		return fmt.Errorf("marshaling dead letter queue event: %w", err)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s(processor, error, attempts, event, created_at) VALUES($1, $2, $3, $4, $5)`, table())
+	if _, err := q.querier.Exec(ctx, query, entry.Processor, entry.Error, entry.Attempts, eventBytes, entry.Timestamp); err != nil {
+		return fmt.Errorf("inserting dead letter queue entry: %w", err)
+	}
+	return nil
+}
+
+func (q *Queue) Close() error {
+	return q.querier.Close(context.Background())
+}
+
+func (q *Queue) createTable(ctx context.Context) error {
+	createSchemaQuery := fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", pglib.QuoteIdentifier(schemalog.SchemaName))
+	if _, err := q.querier.Exec(ctx, createSchemaQuery); err != nil {
+		return fmt.Errorf("creating pgstream schema: %w", err)
+	}
+
+	createTableQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s(
+	id BIGSERIAL PRIMARY KEY,
+	processor TEXT NOT NULL,
+	error TEXT NOT NULL,
+	attempts INTEGER NOT NULL,
+	event JSONB,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL)`, table())
+	if _, err := q.querier.Exec(ctx, createTableQuery); err != nil {
+		return fmt.Errorf("creating dead letter queue postgres table: %w", err)
+	}
+
+	return nil
+}
+
+func table() string {
+	return pglib.QuoteQualifiedIdentifier(schemalog.SchemaName, tableName)
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
+)
+
+func TestQueue_Send(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	testEntry := &dlq.Entry{
+		Event: &wal.Event{
+			Data:           &wal.Data{Action: "I", Schema: "public", Table: "users"},
+			CommitPosition: "0/1",
+		},
+		Processor: "wal_transformer",
+		Error:     "oh noes",
+		Attempts:  1,
+		Timestamp: now,
+	}
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name      string
+		querier   *pgmocks.Querier
+		marshaler func(any) ([]byte, error)
+
+		wantErr error
+	}{
+		{
+			name: "ok",
+			querier: &pgmocks.Querier{
+				ExecFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.CommandTag, error) {
+					require.Equal(t, `INSERT INTO "pgstream"."dead_letter_queue"(processor, error, attempts, event, created_at) VALUES($1, $2, $3, $4, $5)`, query)
+					require.Equal(t, []any{"wal_transformer", "oh noes", 1, []byte("event"), now}, args)
+					return pglib.CommandTag{}, nil
+				},
+			},
+			marshaler: func(a any) ([]byte, error) {
+				require.Equal(t, testEntry.Event, a)
+				return []byte("event"), nil
+			},
+
+			wantErr: nil,
+		},
+		{
+			name: "error - marshaling event",
+			querier: &pgmocks.Querier{
+				ExecFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.CommandTag, error) {
+					return pglib.CommandTag{}, errors.New("unexpected call to ExecFn")
+				},
+			},
+			marshaler: func(a any) ([]byte, error) { return nil, errTest },
+
+			wantErr: errTest,
+		},
+		{
+			name: "error - inserting entry",
+			querier: &pgmocks.Querier{
+				ExecFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.CommandTag, error) {
+					return pglib.CommandTag{}, errTest
+				},
+			},
+			marshaler: func(a any) ([]byte, error) { return []byte("event"), nil },
+
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			q := &Queue{
+				querier:   tc.querier,
+				marshaler: tc.marshaler,
+			}
+
+			err := q.Send(context.Background(), testEntry)
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,20 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import "errors"
+
+type Config struct {
+	// Paths of the NDJSON files to read, in order. Glob patterns are
+	// supported, in which case the matching files are read sorted by name.
+	Paths []string
+}
+
+var errMissingPaths = errors.New("missing file listener paths")
+
+func (c *Config) IsValid() error {
+	if len(c.Paths) == 0 {
+		return errMissingPaths
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Position identifies a line in the recorded event files. The offset is the
+// byte offset where the line following the event starts, so that reading can
+// be resumed from it.
+type Position struct {
+	File   string
+	Offset int64
+}
+
+var ErrInvalidPosition = errors.New("invalid file position")
+
+// String returns the commit position representation of the position, with
+// format <file>:<offset>.
+func (p *Position) String() string {
+	return fmt.Sprintf("%s:%d", p.File, p.Offset)
+}
+
+// ParsePosition parses the commit position on input, with format
+// <file>:<offset>.
+func ParsePosition(s string) (*Position, error) {
+	i := strings.LastIndex(s, ":")
+	if i <= 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPosition, s)
+	}
+
+	offset, err := strconv.ParseInt(s[i+1:], 10, 64)
+	if err != nil || offset < 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPosition, s)
+	}
+
+	return &Position{
+		File:   s[:i],
+		Offset: offset,
+	}, nil
+}
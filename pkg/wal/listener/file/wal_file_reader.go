@@ -0,0 +1,240 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/xataio/pgstream/internal/json"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
+)
+
+// Reader is a listener that reads recorded wal events from NDJSON files. Each
+// line contains a serialised wal event. Lines containing only the event data,
+// as written by the file target, are also supported. The commit position of
+// each event is derived from its file and offset, so that the processing can
+// be checkpointed and resumed.
+type Reader struct {
+	paths       []string
+	logger      loglib.Logger
+	unmarshaler func([]byte, any) error
+	// startPosition is the position the reading is resumed from. If not set,
+	// all the files are read from the beginning.
+	startPosition *Position
+	// deadLetterQueue receives the events that fail processing. If not set,
+	// the failures are logged and the events dropped.
+	deadLetterQueue dlq.Queue
+
+	// processEvent is called for each event read.
+	processEvent payloadProcessor
+}
+
+type payloadProcessor func(context.Context, *wal.Event) error
+
+type Option func(*Reader)
+
+var errStartFileNotFound = errors.New("start position file is not part of the listener files")
+
+// NewReader returns a listener that reads the wal events from the configured
+// files and calls the processor on input for each of them.
+func NewReader(cfg *Config, processEvent payloadProcessor, opts ...Option) (*Reader, error) {
+	if err := cfg.IsValid(); err != nil {
+		return nil, err
+	}
+
+	r := &Reader{
+		paths:        cfg.Paths,
+		logger:       loglib.NewNoopLogger(),
+		unmarshaler:  json.Unmarshal,
+		processEvent: processEvent,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r, nil
+}
+
+func WithLogger(logger loglib.Logger) Option {
+	return func(r *Reader) {
+		r.logger = loglib.NewLogger(logger).WithFields(loglib.Fields{
+			loglib.ModuleField: "wal_file_reader",
+		})
+	}
+}
+
+func WithDeadLetterQueue(q dlq.Queue) Option {
+	return func(r *Reader) {
+		r.deadLetterQueue = q
+	}
+}
+
+// WithStartPosition resumes the reading from the position on input, usually
+// the last checkpointed position.
+func WithStartPosition(p *Position) Option {
+	return func(r *Reader) {
+		r.startPosition = p
+	}
+}
+
+// Listen reads the events of all the files in order, and returns once the end
+// of the last file is reached.
+func (r *Reader) Listen(ctx context.Context) error {
+	files, err := r.files()
+	if err != nil {
+		return err
+	}
+
+	startOffset := int64(0)
+	if r.startPosition != nil {
+		i := slices.Index(files, r.startPosition.File)
+		if i < 0 {
+			return fmt.Errorf("%w: %s", errStartFileNotFound, r.startPosition.File)
+		}
+		r.logger.Info("resuming from position", loglib.Fields{"position": r.startPosition.String()})
+		files = files[i:]
+		startOffset = r.startPosition.Offset
+	}
+
+	for i, file := range files {
+		offset := int64(0)
+		if i == 0 {
+			offset = startOffset
+		}
+		if err := r.readFile(ctx, file, offset); err != nil {
+			return err
+		}
+	}
+
+	r.logger.Info("end of files reached")
+	return nil
+}
+
+func (r *Reader) Close() error {
+	return nil
+}
+
+// files returns the list of files to read, expanding the glob patterns.
+func (r *Reader) files() ([]string, error) {
+	files := []string{}
+	for _, path := range r.paths {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file listener path %s: %w", path, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files found for file listener path %s", path)
+		}
+		slices.Sort(matches)
+		for _, match := range matches {
+			if !slices.Contains(files, match) {
+				files = append(files, match)
+			}
+		}
+	}
+	return files, nil
+}
+
+func (r *Reader) readFile(ctx context.Context, path string, offset int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("seeking %s to offset %d: %w", path, offset, err)
+	}
+
+	r.logger.Debug("reading file", loglib.Fields{"file": path, "offset": offset})
+
+	reader := bufio.NewReader(f)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return fmt.Errorf("reading %s: %w", path, readErr)
+		}
+
+		offset += int64(len(line))
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			position := &Position{File: path, Offset: offset}
+			if err := r.processLine(ctx, line, position); err != nil {
+				return err
+			}
+		}
+
+		if errors.Is(readErr, io.EOF) {
+			return nil
+		}
+	}
+}
+
+func (r *Reader) processLine(ctx context.Context, line []byte, position *Position) error {
+	r.logger.Trace("received", loglib.Fields{
+		"position": position.String(),
+		"wal_data": line,
+	})
+
+	data, err := r.decodeEventData(line)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling line at %s into wal event: %w", position.String(), err)
+	}
+
+	event := &wal.Event{
+		Data:           data,
+		CommitPosition: wal.CommitPosition(position.String()),
+	}
+	if err := r.processEvent(ctx, event); err != nil {
+		if errors.Is(err, context.Canceled) {
+			return fmt.Errorf("canceled: %w", err)
+		}
+
+		if r.deadLetterQueue != nil {
+			r.logger.Error(err, "processing file event, sending to dead letter queue", loglib.Fields{
+				"wal_data": line,
+			})
+			if err := r.deadLetterQueue.Send(ctx, dlq.NewEntry(event, "wal_file_reader", err, 1)); err != nil {
+				return fmt.Errorf("sending file event to dead letter queue: %w", err)
+			}
+			return nil
+		}
+
+		return fmt.Errorf("processing file event at %s: %w", position.String(), err)
+	}
+	return nil
+}
+
+// decodeEventData returns the wal event data of the line on input. The
+// recorded commit position is replaced by the file position.
+func (r *Reader) decodeEventData(line []byte) (*wal.Data, error) {
+	event := &wal.Event{}
+	if err := r.unmarshaler(line, event); err != nil {
+		return nil, err
+	}
+	if event.Data != nil {
+		return event.Data, nil
+	}
+
+	data := &wal.Data{}
+	if err := r.unmarshaler(line, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,201 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/wal"
+	dlqmocks "github.com/xataio/pgstream/pkg/wal/dlq/mocks"
+)
+
+var errTest = errors.New("oh noes")
+
+func TestReader_Listen(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file1 := filepath.Join(dir, "events-1.ndjson")
+	file2 := filepath.Join(dir, "events-2.ndjson")
+	eventLine := `{"Data":{"action":"I","schema":"public","table":"a","lsn":"0/1"},"CommitPosition":"0/1"}` + "\n"
+	dataLine := `{"action":"D","schema":"public","table":"b","lsn":"0/2"}` + "\n"
+	require.NoError(t, os.WriteFile(file1, []byte(eventLine+"\n"), 0o644))
+	require.NoError(t, os.WriteFile(file2, []byte(dataLine), 0o644))
+
+	pos := func(file string, offset int) wal.CommitPosition {
+		return wal.CommitPosition((&Position{File: file, Offset: int64(offset)}).String())
+	}
+
+	tests := []struct {
+		name          string
+		cfg           *Config
+		startPosition *Position
+		processErr    error
+		withDLQ       bool
+
+		wantPositions []wal.CommitPosition
+		wantTables    []string
+		wantDLQ       int
+		wantErr       error
+	}{
+		{
+			name: "ok - glob",
+			cfg:  &Config{Paths: []string{filepath.Join(dir, "*.ndjson")}},
+
+			wantPositions: []wal.CommitPosition{pos(file1, len(eventLine)), pos(file2, len(dataLine))},
+			wantTables:    []string{"a", "b"},
+		},
+		{
+			name: "ok - duplicated paths are read once",
+			cfg:  &Config{Paths: []string{file2, file1, file2}},
+
+			wantPositions: []wal.CommitPosition{pos(file2, len(dataLine)), pos(file1, len(eventLine))},
+			wantTables:    []string{"b", "a"},
+		},
+		{
+			name:          "ok - resume from start position",
+			cfg:           &Config{Paths: []string{filepath.Join(dir, "*.ndjson")}},
+			startPosition: &Position{File: file1, Offset: int64(len(eventLine))},
+
+			wantPositions: []wal.CommitPosition{pos(file2, len(dataLine))},
+			wantTables:    []string{"b"},
+		},
+		{
+			name:       "ok - processing error sent to dead letter queue",
+			cfg:        &Config{Paths: []string{file2}},
+			processErr: errTest,
+			withDLQ:    true,
+
+			wantPositions: []wal.CommitPosition{pos(file2, len(dataLine))},
+			wantTables:    []string{"b"},
+			wantDLQ:       1,
+		},
+		{
+			name:       "error - processing event without dead letter queue",
+			cfg:        &Config{Paths: []string{filepath.Join(dir, "*.ndjson")}},
+			processErr: errTest,
+
+			wantPositions: []wal.CommitPosition{pos(file1, len(eventLine))},
+			wantTables:    []string{"a"},
+			wantErr:       errTest,
+		},
+		{
+			name:       "error - processing canceled",
+			cfg:        &Config{Paths: []string{file2}},
+			processErr: context.Canceled,
+
+			wantPositions: []wal.CommitPosition{pos(file2, len(dataLine))},
+			wantTables:    []string{"b"},
+			wantErr:       context.Canceled,
+		},
+		{
+			name:          "error - start file not found",
+			cfg:           &Config{Paths: []string{file2}},
+			startPosition: &Position{File: file1, Offset: 0},
+
+			wantPositions: []wal.CommitPosition{},
+			wantTables:    []string{},
+			wantErr:       errStartFileNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			positions := []wal.CommitPosition{}
+			tables := []string{}
+			processEvent := func(_ context.Context, e *wal.Event) error {
+				positions = append(positions, e.CommitPosition)
+				tables = append(tables, e.Data.Table)
+				return tc.processErr
+			}
+
+			opts := []Option{}
+			if tc.startPosition != nil {
+				opts = append(opts, WithStartPosition(tc.startPosition))
+			}
+			dlqQueue := &dlqmocks.Queue{}
+			if tc.withDLQ {
+				opts = append(opts, WithDeadLetterQueue(dlqQueue))
+			}
+
+			r, err := NewReader(tc.cfg, processEvent, opts...)
+			require.NoError(t, err)
+			defer r.Close()
+
+			err = r.Listen(context.Background())
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantPositions, positions)
+			require.Equal(t, tc.wantTables, tables)
+			require.Len(t, dlqQueue.GetEntries(), tc.wantDLQ)
+		})
+	}
+}
+
+func TestReader_Listen_errors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.ndjson")
+	require.NoError(t, os.WriteFile(invalid, []byte("not json\n"), 0o644))
+
+	noopProcessor := func(context.Context, *wal.Event) error { return nil }
+
+	_, err := NewReader(&Config{}, noopProcessor)
+	require.ErrorIs(t, err, errMissingPaths)
+
+	r, err := NewReader(&Config{Paths: []string{filepath.Join(dir, "*.json")}}, noopProcessor)
+	require.NoError(t, err)
+	require.Error(t, r.Listen(context.Background()))
+
+	r, err = NewReader(&Config{Paths: []string{invalid}}, noopProcessor)
+	require.NoError(t, err)
+	require.Error(t, r.Listen(context.Background()))
+}
+
+func TestParsePosition(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		position string
+
+		wantPosition *Position
+		wantErr      error
+	}{
+		{
+			name:         "ok",
+			position:     "/data/events:1.ndjson:42",
+			wantPosition: &Position{File: "/data/events:1.ndjson", Offset: 42},
+		},
+		{
+			name:     "error - missing offset",
+			position: "/data/events.ndjson",
+			wantErr:  ErrInvalidPosition,
+		},
+		{
+			name:     "error - negative offset",
+			position: "/data/events.ndjson:-1",
+			wantErr:  ErrInvalidPosition,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			position, err := ParsePosition(tc.position)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantPosition, position)
+			if tc.wantPosition != nil {
+				require.Equal(t, tc.position, position.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"sync"
+
+	"github.com/xataio/pgstream/pkg/blobstore"
+	"github.com/xataio/pgstream/pkg/kafka"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/checkpointer"
+)
+
+// ClaimCheckCleaner deletes the blobs of the claim checked messages from the
+// blob store once their offsets have been committed, which guarantees they
+// won't be read again by the consumer group.
+type ClaimCheckCleaner struct {
+	store        blobstore.Store
+	offsetParser kafka.OffsetParser
+	logger       loglib.Logger
+
+	mutex   sync.Mutex
+	pending map[topicPartition][]*claimCheck
+}
+
+type claimCheck struct {
+	offset  int64
+	blobKey string
+}
+
+type ClaimCheckCleanerOption func(*ClaimCheckCleaner)
+
+// NewClaimCheckCleaner returns a cleaner that deletes the blobs of the
+// processed claim checked messages from the blob store on input.
+func NewClaimCheckCleaner(store blobstore.Store, opts ...ClaimCheckCleanerOption) *ClaimCheckCleaner {
+	c := &ClaimCheckCleaner{
+		store:        store,
+		offsetParser: kafka.NewOffsetParser(),
+		logger:       loglib.NewNoopLogger(),
+		pending:      map[topicPartition][]*claimCheck{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func WithClaimCheckCleanerLogger(logger loglib.Logger) ClaimCheckCleanerOption {
+	return func(c *ClaimCheckCleaner) {
+		c.logger = loglib.NewLogger(logger).WithFields(loglib.Fields{
+			loglib.ModuleField: "wal_kafka_claim_check_cleaner",
+		})
+	}
+}
+
+// WrapCheckpoint returns a checkpoint that calls the checkpoint on input and,
+// once it succeeds, deletes the blobs of the messages covered by the committed
+// offsets.
+func (c *ClaimCheckCleaner) WrapCheckpoint(checkpoint checkpointer.Checkpoint) checkpointer.Checkpoint {
+	return func(ctx context.Context, positions []wal.CommitPosition) error {
+		if checkpoint != nil {
+			if err := checkpoint(ctx, positions); err != nil {
+				return err
+			}
+		}
+		c.deleteCommittedBlobs(ctx, positions)
+		return nil
+	}
+}
+
+// track registers the blob of the claim checked message on input, so that
+// it's deleted once the message offset has been committed. Messages without a
+// claim check are ignored.
+func (c *ClaimCheckCleaner) track(msg *kafka.Message) {
+	blobKey, found := msg.Header(kafka.HeaderClaimCheck)
+	if !found {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	tp := topicPartition{topic: msg.Topic, partition: msg.Partition}
+	c.pending[tp] = append(c.pending[tp], &claimCheck{
+		offset:  msg.Offset,
+		blobKey: string(blobKey),
+	})
+}
+
+// deleteCommittedBlobs deletes the pending blobs of the messages with an offset
+// lower or equal to the committed offset of their partition. Failures are
+// logged and the blobs are kept pending, so that the deletion is retried on
+// the next checkpoint.
+func (c *ClaimCheckCleaner) deleteCommittedBlobs(ctx context.Context, positions []wal.CommitPosition) {
+	committed := make(map[topicPartition]int64, len(positions))
+	for _, position := range positions {
+		offset, err := c.offsetParser.FromString(string(position))
+		if err != nil {
+			c.logger.Error(err, "claim check cleaner: parsing committed positions")
+			return
+		}
+		tp := topicPartition{topic: offset.Topic, partition: offset.Partition}
+		if lastOffset, found := committed[tp]; !found || offset.Offset > lastOffset {
+			committed[tp] = offset.Offset
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	deleted := 0
+	for tp, lastOffset := range committed {
+		pending := []*claimCheck{}
+		for _, cc := range c.pending[tp] {
+			if cc.offset > lastOffset {
+				pending = append(pending, cc)
+				continue
+			}
+			if err := c.store.Delete(ctx, cc.blobKey); err != nil {
+				c.logger.Error(err, "claim check cleaner: deleting blob", loglib.Fields{
+					"blob_key":  cc.blobKey,
+					"topic":     tp.topic,
+					"partition": tp.partition,
+					"offset":    cc.offset,
+				})
+				pending = append(pending, cc)
+				continue
+			}
+			deleted++
+		}
+
+		if len(pending) == 0 {
+			delete(c.pending, tp)
+			continue
+		}
+		c.pending[tp] = pending
+	}
+
+	if deleted > 0 {
+		c.logger.Debug("claim check cleaner: deleted committed blobs", loglib.Fields{"blobs": deleted})
+	}
+}
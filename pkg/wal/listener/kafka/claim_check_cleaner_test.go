@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	blobmocks "github.com/xataio/pgstream/pkg/blobstore/mocks"
+	"github.com/xataio/pgstream/pkg/kafka"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func TestClaimCheckCleaner_WrapCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("oh noes")
+	offsetParser := kafka.NewOffsetParser()
+
+	testMsg := func(partition int, offset int64, blobKey string) *kafka.Message {
+		msg := &kafka.Message{Topic: "test-topic", Partition: partition, Offset: offset}
+		if blobKey != "" {
+			msg.Headers = []kafka.Header{{Key: kafka.HeaderClaimCheck, Value: []byte(blobKey)}}
+		}
+		return msg
+	}
+	testPosition := func(partition int, offset int64) wal.CommitPosition {
+		return wal.CommitPosition(offsetParser.ToString(&kafka.Offset{Topic: "test-topic", Partition: partition, Offset: offset}))
+	}
+
+	tests := []struct {
+		name       string
+		checkpoint func(context.Context, []wal.CommitPosition) error
+		deleteErr  error
+
+		wantDeleted []string
+		wantPending map[topicPartition][]*claimCheck
+		wantErr     error
+	}{
+		{
+			name: "ok",
+
+			wantDeleted: []string{"blob-1", "blob-2"},
+			wantPending: map[topicPartition][]*claimCheck{
+				{topic: "test-topic", partition: 0}: {{offset: 3, blobKey: "blob-3"}},
+				{topic: "test-topic", partition: 1}: {{offset: 1, blobKey: "blob-4"}},
+			},
+		},
+		{
+			name: "error - checkpoint",
+			checkpoint: func(context.Context, []wal.CommitPosition) error {
+				return errTest
+			},
+
+			wantDeleted: []string{},
+			wantPending: map[topicPartition][]*claimCheck{
+				{topic: "test-topic", partition: 0}: {
+					{offset: 1, blobKey: "blob-1"},
+					{offset: 2, blobKey: "blob-2"},
+					{offset: 3, blobKey: "blob-3"},
+				},
+				{topic: "test-topic", partition: 1}: {{offset: 1, blobKey: "blob-4"}},
+			},
+			wantErr: errTest,
+		},
+		{
+			name:      "error - deleting blobs",
+			deleteErr: errTest,
+
+			wantDeleted: []string{},
+			wantPending: map[topicPartition][]*claimCheck{
+				{topic: "test-topic", partition: 0}: {
+					{offset: 1, blobKey: "blob-1"},
+					{offset: 2, blobKey: "blob-2"},
+					{offset: 3, blobKey: "blob-3"},
+				},
+				{topic: "test-topic", partition: 1}: {{offset: 1, blobKey: "blob-4"}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			deleted := []string{}
+			store := &blobmocks.Store{
+				DeleteFn: func(ctx context.Context, key string) error {
+					if tc.deleteErr != nil {
+						return tc.deleteErr
+					}
+					deleted = append(deleted, key)
+					return nil
+				},
+			}
+
+			cleaner := NewClaimCheckCleaner(store)
+			cleaner.track(testMsg(0, 1, "blob-1"))
+			cleaner.track(testMsg(0, 2, "blob-2"))
+			// messages without a claim check are ignored
+			cleaner.track(testMsg(0, 2, ""))
+			cleaner.track(testMsg(0, 3, "blob-3"))
+			cleaner.track(testMsg(1, 1, "blob-4"))
+
+			checkpoint := cleaner.WrapCheckpoint(tc.checkpoint)
+			err := checkpoint(context.Background(), []wal.CommitPosition{testPosition(0, 1), testPosition(0, 2)})
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantDeleted, deleted)
+			require.Equal(t, tc.wantPending, cleaner.pending)
+		})
+	}
+}
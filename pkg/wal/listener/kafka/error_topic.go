@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/xataio/pgstream/pkg/kafka"
+)
+
+// errorTopic forwards the messages that fail processing to a kafka topic, so
+// that they can be inspected and replayed without blocking the listener.
+type errorTopic struct {
+	writer kafka.MessageWriter
+	topic  string
+}
+
+// send writes the message on input to the error topic, keeping its key, value
+// and headers, and adding the error and the source position as headers.
+func (e *errorTopic) send(ctx context.Context, msg *kafka.Message, processErr error, attempts int) error {
+	errMsg := kafka.Message{
+		Topic:   e.topic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: make([]kafka.Header, 0, len(msg.Headers)+5),
+	}
+	errMsg.Headers = append(errMsg.Headers, msg.Headers...)
+	errMsg.SetHeader(kafka.HeaderError, []byte(processErr.Error()))
+	errMsg.SetHeader(kafka.HeaderErrorAttempts, []byte(strconv.Itoa(attempts)))
+	errMsg.SetHeader(kafka.HeaderSourceTopic, []byte(msg.Topic))
+	errMsg.SetHeader(kafka.HeaderSourcePartition, []byte(strconv.Itoa(msg.Partition)))
+	errMsg.SetHeader(kafka.HeaderSourceOffset, []byte(strconv.FormatInt(msg.Offset, 10)))
+
+	return e.writer.WriteMessages(ctx, errMsg)
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/xataio/pgstream/pkg/kafka"
+)
+
+// chunkAssembler reassembles the values of the messages split across multiple
+// chunks. The chunks of a message are written consecutively to the same
+// partition, so there can only be one message being assembled per partition.
+type chunkAssembler struct {
+	pending map[topicPartition]*chunkedMessage
+	// started contains the partitions for which a message or the first chunk
+	// of a message has been read
+	started map[topicPartition]struct{}
+}
+
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+type chunkedMessage struct {
+	id     string
+	count  int
+	chunks [][]byte
+}
+
+var (
+	errInvalidChunk       = errors.New("invalid kafka message chunk")
+	errMissingBlobStore   = errors.New("message value in blob store, but no blob store configured")
+	errIncompleteChunkSet = errors.New("incomplete kafka message chunks")
+	errLeadingChunk       = errors.New("kafka message chunk of a message started before the reading")
+)
+
+// add adds the message on input to the message being assembled for its
+// partition. It returns the complete value once the last chunk is added, and
+// whether the value is complete. Messages that are not chunked are returned
+// as they are. Chunks read before the first message of their partition (i.e.
+// when the reading starts in the middle of a set) return errLeadingChunk,
+// while the rest of chunks that don't belong to a complete set return
+// errIncompleteChunkSet.
+func (a *chunkAssembler) add(msg *kafka.Message) ([]byte, bool, error) {
+	if a.started == nil {
+		a.started = map[topicPartition]struct{}{}
+	}
+	tp := topicPartition{topic: msg.Topic, partition: msg.Partition}
+
+	id, found := msg.Header(kafka.HeaderChunkID)
+	if !found {
+		a.started[tp] = struct{}{}
+		return msg.Value, true, nil
+	}
+	index, err := chunkHeaderInt(msg, kafka.HeaderChunkIndex)
+	if err != nil {
+		return nil, false, err
+	}
+	count, err := chunkHeaderInt(msg, kafka.HeaderChunkCount)
+	if err != nil {
+		return nil, false, err
+	}
+	if index < 0 || count <= 0 || index >= count {
+		return nil, false, fmt.Errorf("%w: chunk %d of %d", errInvalidChunk, index, count)
+	}
+
+	if _, started := a.started[tp]; !started && index != 0 {
+		return nil, false, fmt.Errorf("%w: chunk %d of %d of message %s", errLeadingChunk, index, count, id)
+	}
+	a.started[tp] = struct{}{}
+
+	if a.pending == nil {
+		a.pending = map[topicPartition]*chunkedMessage{}
+	}
+	pending, found := a.pending[tp]
+	if index == 0 {
+		// any message being assembled for the partition won't be completed
+		pending = &chunkedMessage{
+			id:     string(id),
+			count:  count,
+			chunks: make([][]byte, 0, count),
+		}
+		a.pending[tp] = pending
+	}
+	if !found && index != 0 || pending.id != string(id) || len(pending.chunks) != index {
+		delete(a.pending, tp)
+		return nil, false, fmt.Errorf("%w: chunk %d of %d of message %s", errIncompleteChunkSet, index, count, id)
+	}
+
+	pending.chunks = append(pending.chunks, msg.Value)
+	if len(pending.chunks) < pending.count {
+		return nil, false, nil
+	}
+
+	delete(a.pending, tp)
+	size := 0
+	for _, chunk := range pending.chunks {
+		size += len(chunk)
+	}
+	value := make([]byte, 0, size)
+	for _, chunk := range pending.chunks {
+		value = append(value, chunk...)
+	}
+	return value, true, nil
+}
+
+// assembledMessage returns a copy of the last chunk of a message, with the
+// assembled value on input and without the chunk headers.
+func assembledMessage(lastChunk *kafka.Message, value []byte) *kafka.Message {
+	assembled := *lastChunk
+	assembled.Value = value
+	assembled.Headers = make([]kafka.Header, 0, len(lastChunk.Headers))
+	for _, h := range lastChunk.Headers {
+		switch h.Key {
+		case kafka.HeaderChunkID, kafka.HeaderChunkIndex, kafka.HeaderChunkCount:
+		default:
+			assembled.Headers = append(assembled.Headers, h)
+		}
+	}
+	return &assembled
+}
+
+func chunkHeaderInt(msg *kafka.Message, key string) (int, error) {
+	value, found := msg.Header(key)
+	if !found {
+		return 0, fmt.Errorf("%w: missing %s header", errInvalidChunk, key)
+	}
+	n, err := strconv.Atoi(string(value))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s header: %w", errInvalidChunk, key, err)
+	}
+	return n, nil
+}
+
+// resolveClaimCheck returns the value of the message on input, retrieving it
+// from the blob store if the message carries a claim check.
+func (r *Reader) resolveClaimCheck(ctx context.Context, msg *kafka.Message) ([]byte, error) {
+	blobKey, found := msg.Header(kafka.HeaderClaimCheck)
+	if !found {
+		return msg.Value, nil
+	}
+	if r.blobStore == nil {
+		return nil, errMissingBlobStore
+	}
+	value, err := r.blobStore.Get(ctx, string(blobKey))
+	if err != nil {
+		return nil, fmt.Errorf("retrieving message value from blob store: %w", err)
+	}
+	return value, nil
+}
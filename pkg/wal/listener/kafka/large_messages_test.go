@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	blobstoremocks "github.com/xataio/pgstream/pkg/blobstore/mocks"
+	"github.com/xataio/pgstream/pkg/kafka"
+)
+
+func TestChunkAssembler_add(t *testing.T) {
+	t.Parallel()
+
+	chunk := func(partition int, id, index, count, value string) *kafka.Message {
+		return &kafka.Message{
+			Topic:     "topic",
+			Partition: partition,
+			Value:     []byte(value),
+			Headers: []kafka.Header{
+				{Key: kafka.HeaderChunkID, Value: []byte(id)},
+				{Key: kafka.HeaderChunkIndex, Value: []byte(index)},
+				{Key: kafka.HeaderChunkCount, Value: []byte(count)},
+			},
+		}
+	}
+
+	type result struct {
+		value    string
+		complete bool
+		err      error
+	}
+
+	tests := []struct {
+		name string
+		msgs []*kafka.Message
+
+		wantResults []result
+	}{
+		{
+			name: "ok - not chunked",
+			msgs: []*kafka.Message{{Value: []byte("abc")}},
+
+			wantResults: []result{{value: "abc", complete: true}},
+		},
+		{
+			name: "ok - interleaved partitions",
+			msgs: []*kafka.Message{
+				chunk(0, "a", "0", "2", "ab"),
+				chunk(1, "b", "0", "2", "12"),
+				chunk(0, "a", "1", "2", "c"),
+				chunk(1, "b", "1", "2", "3"),
+			},
+
+			wantResults: []result{
+				{}, {},
+				{value: "abc", complete: true},
+				{value: "123", complete: true},
+			},
+		},
+		{
+			name: "error - reading from the middle of a message",
+			msgs: []*kafka.Message{
+				chunk(0, "a", "1", "3", "c"),
+				chunk(0, "a", "2", "3", "d"),
+				chunk(0, "b", "0", "2", "ab"),
+				chunk(0, "b", "1", "2", "c"),
+			},
+
+			wantResults: []result{
+				{err: errLeadingChunk},
+				{err: errLeadingChunk},
+				{},
+				{value: "abc", complete: true},
+			},
+		},
+		{
+			name: "error - chunk without first chunk after a message",
+			msgs: []*kafka.Message{
+				{Topic: "topic", Value: []byte("abc")},
+				chunk(0, "a", "1", "2", "c"),
+			},
+
+			wantResults: []result{
+				{value: "abc", complete: true},
+				{err: errIncompleteChunkSet},
+			},
+		},
+		{
+			name: "error - missing chunk",
+			msgs: []*kafka.Message{
+				chunk(0, "a", "0", "3", "ab"),
+				chunk(0, "a", "2", "3", "c"),
+			},
+
+			wantResults: []result{
+				{},
+				{err: errIncompleteChunkSet},
+			},
+		},
+		{
+			name: "error - invalid headers",
+			msgs: []*kafka.Message{
+				chunk(0, "a", "2", "2", "ab"),
+				chunk(0, "a", "x", "2", "ab"),
+			},
+
+			wantResults: []result{
+				{err: errInvalidChunk},
+				{err: errInvalidChunk},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := &chunkAssembler{}
+			for i, msg := range tc.msgs {
+				value, complete, err := a.add(msg)
+				require.ErrorIs(t, err, tc.wantResults[i].err)
+				require.Equal(t, tc.wantResults[i].complete, complete)
+				if complete {
+					require.Equal(t, tc.wantResults[i].value, string(value))
+				}
+			}
+		})
+	}
+}
+
+func TestReader_resolveClaimCheck(t *testing.T) {
+	t.Parallel()
+
+	store := &blobstoremocks.Store{}
+	require.NoError(t, store.Put(context.Background(), "blob-key", []byte("value")))
+
+	claimCheckMsg := &kafka.Message{
+		Value:   []byte("blob-key"),
+		Headers: []kafka.Header{{Key: kafka.HeaderClaimCheck, Value: []byte("blob-key")}},
+	}
+
+	r := &Reader{blobStore: store}
+	value, err := r.resolveClaimCheck(context.Background(), claimCheckMsg)
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+
+	value, err = r.resolveClaimCheck(context.Background(), &kafka.Message{Value: []byte("inline")})
+	require.NoError(t, err)
+	require.Equal(t, []byte("inline"), value)
+
+	_, err = (&Reader{}).resolveClaimCheck(context.Background(), claimCheckMsg)
+	require.ErrorIs(t, err, errMissingBlobStore)
+}
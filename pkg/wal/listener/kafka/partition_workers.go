@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+	"sync"
+)
+
+// partitionWorkers processes the kafka messages concurrently, while keeping
+// the order within each partition. The messages of a partition are always
+// processed by the same worker, sequentially. Since kafka routes the messages
+// with the same key to the same partition, this preserves the per key order.
+type partitionWorkers struct {
+	queues []chan task
+	// inflight keeps track of the tasks dispatched but not yet processed
+	inflight sync.WaitGroup
+	workers  sync.WaitGroup
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	errMutex sync.Mutex
+	err      error
+}
+
+type task func(context.Context) error
+
+// workerQueueSize is the number of tasks that can be queued per worker before
+// the dispatch blocks
+const workerQueueSize = 100
+
+// newPartitionWorkers starts the number of workers on input. The context on
+// input is canceled as soon as one of the tasks fails, so that the caller can
+// stop dispatching new ones.
+func newPartitionWorkers(ctx context.Context, cancel context.CancelFunc, concurrency int) *partitionWorkers {
+	w := &partitionWorkers{
+		queues: make([]chan task, concurrency),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	for i := range w.queues {
+		w.queues[i] = make(chan task, workerQueueSize)
+		w.workers.Add(1)
+		go w.work(w.queues[i])
+	}
+
+	return w
+}
+
+// dispatch queues the task on input to the worker of the topic partition. It
+// blocks if the worker queue is full, and returns an error if any of the
+// previous tasks has failed.
+func (w *partitionWorkers) dispatch(topic string, partition int, t task) error {
+	if err := w.getErr(); err != nil {
+		return err
+	}
+
+	w.inflight.Add(1)
+	select {
+	case w.queues[w.workerIndex(topic, partition)] <- t:
+		return nil
+	case <-w.ctx.Done():
+		w.inflight.Done()
+		if err := w.getErr(); err != nil {
+			return err
+		}
+		return w.ctx.Err()
+	}
+}
+
+// wait blocks until all the dispatched tasks have been processed, and returns
+// the error of the first failed task, if any.
+func (w *partitionWorkers) wait() error {
+	w.inflight.Wait()
+	return w.getErr()
+}
+
+// close stops the workers once the queued tasks have been processed. No tasks
+// can be dispatched after calling close.
+func (w *partitionWorkers) close() {
+	for _, queue := range w.queues {
+		close(queue)
+	}
+	w.workers.Wait()
+}
+
+func (w *partitionWorkers) getErr() error {
+	w.errMutex.Lock()
+	defer w.errMutex.Unlock()
+	return w.err
+}
+
+func (w *partitionWorkers) work(queue chan task) {
+	defer w.workers.Done()
+	for t := range queue {
+		// once a task has failed, the rest are skipped
+		if w.getErr() == nil {
+			if err := t(w.ctx); err != nil {
+				w.setErr(err)
+			}
+		}
+		w.inflight.Done()
+	}
+}
+
+func (w *partitionWorkers) setErr(err error) {
+	w.errMutex.Lock()
+	defer w.errMutex.Unlock()
+	if w.err == nil {
+		w.err = err
+		w.cancel()
+	}
+}
+
+func (w *partitionWorkers) workerIndex(topic string, partition int) int {
+	h := fnv.New32a()
+	h.Write([]byte(topic))
+	h.Write([]byte(strconv.Itoa(partition)))
+	return int(h.Sum32() % uint32(len(w.queues)))
+}
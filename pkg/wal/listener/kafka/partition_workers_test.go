@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionWorkers(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("oh noes")
+
+	t.Run("ok - per partition order", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		workers := newPartitionWorkers(ctx, cancel, 3)
+		defer workers.close()
+
+		var mutex sync.Mutex
+		processed := map[int][]int{}
+		for i := 0; i < 100; i++ {
+			partition, offset := i%4, i
+			err := workers.dispatch("topic", partition, func(ctx context.Context) error {
+				mutex.Lock()
+				defer mutex.Unlock()
+				processed[partition] = append(processed[partition], offset)
+				return nil
+			})
+			require.NoError(t, err)
+		}
+		require.NoError(t, workers.wait())
+
+		require.Len(t, processed, 4)
+		for partition, offsets := range processed {
+			require.Len(t, offsets, 25)
+			for i, offset := range offsets {
+				require.Equal(t, partition+i*4, offset)
+			}
+		}
+	})
+
+	t.Run("error - task failure", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		workers := newPartitionWorkers(ctx, cancel, 2)
+		defer workers.close()
+
+		err := workers.dispatch("topic", 0, func(ctx context.Context) error {
+			return errTest
+		})
+		require.NoError(t, err)
+		require.ErrorIs(t, workers.wait(), errTest)
+		// the context is canceled and no more tasks are accepted
+		require.ErrorIs(t, ctx.Err(), context.Canceled)
+		err = workers.dispatch("topic", 1, func(ctx context.Context) error {
+			return errors.New("task: should not be called")
+		})
+		require.ErrorIs(t, err, errTest)
+	})
+}
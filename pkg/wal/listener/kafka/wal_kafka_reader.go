@@ -6,11 +6,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/xataio/pgstream/internal/json"
+	"github.com/xataio/pgstream/pkg/backoff"
+	"github.com/xataio/pgstream/pkg/blobstore"
 	"github.com/xataio/pgstream/pkg/kafka"
 	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/schemaregistry"
 	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+	kafkaprocessor "github.com/xataio/pgstream/pkg/wal/processor/kafka"
 )
 
 // Reader is a kafka reader that listens to wal events.
@@ -19,6 +26,39 @@ type Reader struct {
 	unmarshaler  func([]byte, any) error
 	logger       loglib.Logger
 	offsetParser kafka.OffsetParser
+	// deadLetterQueue receives the events that fail processing once the
+	// retries are exhausted, when no error topic is configured.
+	deadLetterQueue dlq.Queue
+	// errorTopic receives the messages that fail processing once the retries
+	// are exhausted. If neither the error topic nor the dead letter queue are
+	// set, the listener stops instead.
+	errorTopic *errorTopic
+	// backoffProvider is applied to retry the processing of the failed
+	// records. Defaults to no retries.
+	backoffProvider backoff.Provider
+	// deserialiser decodes the messages framed with a schema registry schema
+	// id (i.e. avro). If not set, only JSON messages are supported.
+	deserialiser dataDeserialiser
+	// decoder decodes the messages that are not in the schema registry wire
+	// format (i.e. debezium change events). If not set, the messages are
+	// expected to be JSON serialised wal data.
+	decoder dataDeserialiser
+
+	// concurrency is the number of partitions processed concurrently. The
+	// events of a partition are always processed in order.
+	concurrency int
+	// blobStore is where the values of the claim checked messages are
+	// retrieved from. If not set, claim checked messages are not supported.
+	blobStore blobstore.Store
+	// claimCheckCleaner deletes the blobs of the processed claim checked
+	// messages once their offsets are committed. If not set, the blobs are
+	// kept.
+	claimCheckCleaner *ClaimCheckCleaner
+	// chunks reassembles the values split across multiple messages.
+	chunks chunkAssembler
+	// schemaLogIDs keeps track of the schema log events already processed,
+	// to skip the copies written to other partitions.
+	schemaLogIDs *schemaLogIDSet
 
 	// processRecord is called for a new record.
 	processRecord payloadProcessor
@@ -28,10 +68,16 @@ type kafkaReader interface {
 	FetchMessage(context.Context) (*kafka.Message, error)
 }
 
+type dataDeserialiser interface {
+	Deserialise(ctx context.Context, msg []byte) (*wal.Data, error)
+}
+
 type payloadProcessor func(context.Context, *wal.Event) error
 
 type Option func(*Reader)
 
+var errMissingDeserialiser = errors.New("message in schema registry wire format, but no schema registry configured")
+
 // NewReader returns a kafka reader that listens to wal events and calls the
 // processor on input.
 func NewWALReader(kafkaReader kafkaReader, processRecord payloadProcessor, opts ...Option) (*Reader, error) {
@@ -41,6 +87,10 @@ func NewWALReader(kafkaReader kafkaReader, processRecord payloadProcessor, opts
 		unmarshaler:   json.Unmarshal,
 		offsetParser:  kafka.NewOffsetParser(),
 		reader:        kafkaReader,
+		schemaLogIDs:  newSchemaLogIDSet(),
+		backoffProvider: func(ctx context.Context) backoff.Backoff {
+			return backoff.NewStopBackoff()
+		},
 	}
 
 	for _, opt := range opts {
@@ -58,13 +108,116 @@ func WithLogger(logger loglib.Logger) Option {
 	}
 }
 
+func WithDeadLetterQueue(q dlq.Queue) Option {
+	return func(r *Reader) {
+		r.deadLetterQueue = q
+	}
+}
+
+// WithDeserialiser sets the deserialiser used for the messages in the schema
+// registry wire format.
+func WithDeserialiser(d dataDeserialiser) Option {
+	return func(r *Reader) {
+		r.deserialiser = d
+	}
+}
+
+// WithDecoder sets the decoder used for the messages that are not in the
+// schema registry wire format, instead of the default JSON unmarshaling.
+func WithDecoder(d dataDeserialiser) Option {
+	return func(r *Reader) {
+		r.decoder = d
+	}
+}
+
+// WithConcurrency sets the number of partitions processed concurrently. The
+// events of a partition are always processed in order. Defaults to 1.
+func WithConcurrency(n int) Option {
+	return func(r *Reader) {
+		r.concurrency = n
+	}
+}
+
+// WithBlobStore sets the blob store the values of the claim checked messages
+// are retrieved from.
+func WithBlobStore(store blobstore.Store) Option {
+	return func(r *Reader) {
+		r.blobStore = store
+	}
+}
+
+// WithClaimCheckCleaner sets the cleaner the processed claim checked messages
+// are registered with, so that their blobs are deleted once committed. The
+// messages forwarded to the error topic keep their blobs.
+func WithClaimCheckCleaner(cleaner *ClaimCheckCleaner) Option {
+	return func(r *Reader) {
+		r.claimCheckCleaner = cleaner
+	}
+}
+
+// WithRetry sets the backoff policy applied to retry the processing of the
+// failed records. Defaults to no retries.
+func WithRetry(cfg *backoff.Config) Option {
+	return func(r *Reader) {
+		r.backoffProvider = backoff.NewProvider(cfg)
+	}
+}
+
+// WithErrorTopic sets the kafka writer the messages that fail processing are
+// forwarded to once their retries are exhausted, along with the error topic.
+// The writer is closed when the reader is closed.
+func WithErrorTopic(writer kafka.MessageWriter, topic string) Option {
+	return func(r *Reader) {
+		r.errorTopic = &errorTopic{
+			writer: writer,
+			topic:  topic,
+		}
+	}
+}
+
 func (r *Reader) Listen(ctx context.Context) error {
+	if r.concurrency <= 1 {
+		return r.listen(ctx, r.processEvent)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	workers := newPartitionWorkers(ctx, cancel, r.concurrency)
+	defer workers.close()
+
+	err := r.listen(ctx, func(ctx context.Context, msg *kafka.Message, event *wal.Event) error {
+		// schema log events are processed once all previous events have been
+		// processed, so that the schema changes are applied in order with the
+		// data events of all partitions
+		if event.Data != nil && processor.IsSchemaLogEvent(event.Data) {
+			if err := workers.wait(); err != nil {
+				return err
+			}
+			return r.processEvent(ctx, msg, event)
+		}
+		return workers.dispatch(msg.Topic, msg.Partition, func(ctx context.Context) error {
+			return r.processEvent(ctx, msg, event)
+		})
+	})
+	// a failed worker cancels the context, so its error takes precedence
+	if workersErr := workers.wait(); workersErr != nil {
+		return workersErr
+	}
+	return err
+}
+
+func (r *Reader) listen(ctx context.Context, process func(context.Context, *kafka.Message, *wal.Event) error) error {
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 			msg, err := r.reader.FetchMessage(ctx)
+			if errors.Is(err, kafka.ErrEndOfRange) {
+				// bounded readers stop once they reach the end of their range
+				r.logger.Info("end of kafka range reached")
+				return nil
+			}
 			if err != nil {
 				return fmt.Errorf("reading from kafka: %w", err)
 			}
@@ -74,37 +227,259 @@ func (r *Reader) Listen(ctx context.Context) error {
 				"partition": msg.Partition,
 				"offset":    msg.Offset,
 				"key":       msg.Key,
+				"headers":   msg.Headers,
 				"wal_data":  msg.Value,
 			})
 
-			offset := &kafka.Offset{
-				Topic:     msg.Topic,
-				Partition: msg.Partition,
-				Offset:    msg.Offset,
+			// the chunks of a message are only processed once complete. Their
+			// offsets are not committed until then, so that the message is
+			// read from its first chunk if the reading is resumed.
+			value, complete, err := r.chunks.add(msg)
+			switch {
+			case errors.Is(err, errLeadingChunk):
+				// the reading started in the middle of a message, whose
+				// chunks have been processed before
+				r.logger.Debug("skipping leading kafka message chunk", loglib.Fields{
+					"topic":     msg.Topic,
+					"partition": msg.Partition,
+					"offset":    msg.Offset,
+				})
+				continue
+			case errors.Is(err, errIncompleteChunkSet):
+				if err := r.handleFailure(ctx, msg, r.newEvent(msg), err, 1); err != nil {
+					return err
+				}
+				continue
+			case err != nil:
+				return err
+			case !complete:
+				continue
 			}
-
-			event := &wal.Event{
-				CommitPosition: wal.CommitPosition(r.offsetParser.ToString(offset)),
+			if _, chunked := msg.Header(kafka.HeaderChunkID); chunked {
+				msg = assembledMessage(msg, value)
 			}
-			event.Data = &wal.Data{}
-			if err := r.unmarshaler(msg.Value, event.Data); err != nil {
+
+			event := r.newEvent(msg)
+			if event.Data, err = r.decodeData(ctx, msg); err != nil {
 				return fmt.Errorf("error unmarshaling message value into wal data: %w", err)
 			}
 
-			if err = r.processRecord(ctx, event); err != nil {
-				if errors.Is(err, context.Canceled) {
-					return fmt.Errorf("canceled: %w", err)
-				}
+			// schema log events can be written to all the partitions of a
+			// topic. Only the first copy is processed, the rest are kept as
+			// keep alive events so that their offset is still committed.
+			if r.schemaLogIDs.isDuplicate(event.Data) {
+				event.Data = nil
+			}
 
-				r.logger.Error(err, "processing kafka msg", loglib.Fields{
-					"severity": "DATALOSS",
-					"wal_data": msg.Value,
-				})
+			if err := process(ctx, msg, event); err != nil {
+				return err
 			}
 		}
 	}
 }
 
+// processEvent calls the record processor for the event on input, retrying
+// with the configured backoff policy. Once the retries are exhausted, the
+// message is forwarded to the error topic or the event sent to the dead letter
+// queue, if configured. Otherwise, the error is returned and the listener
+// stops, so that no offset is committed past the failed message.
+func (r *Reader) processEvent(ctx context.Context, msg *kafka.Message, event *wal.Event) error {
+	attempts := 0
+	err := r.backoffProvider(ctx).RetryNotify(
+		func() error {
+			attempts++
+			err := r.processRecord(ctx, event)
+			if errors.Is(err, context.Canceled) {
+				return fmt.Errorf("%w: %w", err, backoff.ErrPermanent)
+			}
+			return err
+		},
+		func(err error, duration time.Duration) {
+			r.logger.Warn(err, "processing kafka msg, retrying", loglib.Fields{
+				"topic":     msg.Topic,
+				"partition": msg.Partition,
+				"offset":    msg.Offset,
+				"attempt":   attempts,
+				"backoff":   duration,
+			})
+		})
+	if err == nil {
+		r.trackClaimCheck(msg)
+		return nil
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("canceled: %w", err)
+	}
+
+	return r.handleFailure(ctx, msg, event, err, attempts)
+}
+
+// handleFailure forwards the failed message to the error topic or sends the
+// event to the dead letter queue, if configured. Otherwise, the error is
+// returned.
+func (r *Reader) handleFailure(ctx context.Context, msg *kafka.Message, event *wal.Event, err error, attempts int) error {
+	fields := loglib.Fields{
+		"topic":     msg.Topic,
+		"partition": msg.Partition,
+		"offset":    msg.Offset,
+		"attempts":  attempts,
+		"wal_data":  msg.Value,
+	}
+
+	if r.errorTopic != nil {
+		r.logger.Error(err, "processing kafka msg, forwarding to error topic", fields)
+		if err := r.errorTopic.send(ctx, msg, err, attempts); err != nil {
+			return fmt.Errorf("forwarding kafka msg to error topic: %w", err)
+		}
+		return nil
+	}
+
+	if r.deadLetterQueue != nil {
+		r.logger.Error(err, "processing kafka msg, sending to dead letter queue", fields)
+		if err := r.deadLetterQueue.Send(ctx, dlq.NewEntry(event, "wal_kafka_reader", err, attempts)); err != nil {
+			return fmt.Errorf("sending kafka msg to dead letter queue: %w", err)
+		}
+		// the dead letter queue entry contains the resolved event, so the
+		// blob is no longer needed
+		r.trackClaimCheck(msg)
+		return nil
+	}
+
+	return fmt.Errorf("processing kafka msg (topic %s, partition %d, offset %d) after %d attempts: %w",
+		msg.Topic, msg.Partition, msg.Offset, attempts, err)
+}
+
+// newEvent returns the event for the message on input, without its data.
+func (r *Reader) newEvent(msg *kafka.Message) *wal.Event {
+	offset := &kafka.Offset{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+	}
+	return &wal.Event{
+		CommitPosition: wal.CommitPosition(r.offsetParser.ToString(offset)),
+		Headers:        msg.HeadersMap(),
+	}
+}
+
+func (r *Reader) trackClaimCheck(msg *kafka.Message) {
+	if r.claimCheckCleaner != nil {
+		r.claimCheckCleaner.track(msg)
+	}
+}
+
 func (r *Reader) Close() error {
+	if r.errorTopic != nil {
+		return r.errorTopic.writer.Close()
+	}
 	return nil
 }
+
+// decodeData returns the wal data of the message on input. Values in the
+// schema registry wire format are decoded with the deserialiser, and the rest
+// with the decoder, if configured, or as JSON otherwise. Tombstones are decoded
+// by the decoder if configured, or from their key otherwise. The values of the
+// claim checked messages are retrieved from the blob store.
+func (r *Reader) decodeData(ctx context.Context, msg *kafka.Message) (*wal.Data, error) {
+	if len(msg.Value) == 0 && r.decoder == nil {
+		return r.decodeTombstone(msg), nil
+	}
+
+	value, err := r.resolveClaimCheck(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if schemaregistry.IsWireFormat(value) {
+		if r.deserialiser == nil {
+			return nil, errMissingDeserialiser
+		}
+		return r.deserialiser.Deserialise(ctx, value)
+	}
+
+	if r.decoder != nil {
+		return r.decoder.Deserialise(ctx, value)
+	}
+
+	data := &wal.Data{}
+	if err := r.unmarshaler(value, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// decodeTombstone returns the delete event of the row identified by the
+// tombstone key, for the tombstones written to compacted topics. The position
+// of the delete is retrieved from the message headers. Tombstones with a
+// different key are processed as keep alive events.
+func (r *Reader) decodeTombstone(msg *kafka.Message) *wal.Data {
+	key, err := kafkaprocessor.DecodeCompactionKey(msg.Key)
+	if err != nil {
+		r.logger.Debug("skipping tombstone without compaction key", loglib.Fields{
+			"topic":     msg.Topic,
+			"partition": msg.Partition,
+			"offset":    msg.Offset,
+		})
+		return nil
+	}
+
+	data := key.ToDeleteData()
+	if lsn, found := msg.Header(kafka.HeaderLSN); found {
+		data.LSN = string(lsn)
+	}
+	if timestamp, found := msg.Header(kafka.HeaderCommitTimestamp); found {
+		data.Timestamp = string(timestamp)
+	}
+	if tableID, found := msg.Header(kafka.HeaderTableID); found {
+		data.Metadata.TablePgstreamID = string(tableID)
+	}
+	return data
+}
+
+// schemaLogIDSet keeps track of the most recent schema log event ids. The
+// number of ids is bounded, since the copies of a schema log event are written
+// together, so they are expected to be received close to each other.
+type schemaLogIDSet struct {
+	ids   map[string]struct{}
+	order []string
+}
+
+const maxSchemaLogIDs = 1000
+
+func newSchemaLogIDSet() *schemaLogIDSet {
+	return &schemaLogIDSet{
+		ids: make(map[string]struct{}, maxSchemaLogIDs),
+	}
+}
+
+// isDuplicate returns true if the schema log event on input has already been
+// seen. Events that are not schema log events are never duplicates.
+func (s *schemaLogIDSet) isDuplicate(d *wal.Data) bool {
+	if s == nil || d == nil || !processor.IsSchemaLogEvent(d) {
+		return false
+	}
+
+	var id string
+	for _, col := range d.Columns {
+		if col.Name == "id" {
+			id = fmt.Sprint(col.Value)
+			break
+		}
+	}
+	if id == "" {
+		return false
+	}
+
+	if _, found := s.ids[id]; found {
+		return true
+	}
+
+	if len(s.order) == maxSchemaLogIDs {
+		delete(s.ids, s.order[0])
+		s.order = s.order[1:]
+	}
+	s.ids[id] = struct{}{}
+	s.order = append(s.order, id)
+	return false
+}
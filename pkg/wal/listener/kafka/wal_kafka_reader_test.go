@@ -11,12 +11,29 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/backoff"
 	"github.com/xataio/pgstream/pkg/kafka"
 	kafkamocks "github.com/xataio/pgstream/pkg/kafka/mocks"
 	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/schemaregistry"
 	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
+	dlqmocks "github.com/xataio/pgstream/pkg/wal/dlq/mocks"
 )
 
+func testChunk(id string, index, count int, value string) *kafka.Message {
+	return &kafka.Message{
+		Topic: "test-topic",
+		Value: []byte(value),
+		Headers: []kafka.Header{
+			{Key: kafka.HeaderChunkID, Value: []byte(id)},
+			{Key: kafka.HeaderChunkIndex, Value: []byte(fmt.Sprint(index))},
+			{Key: kafka.HeaderChunkCount, Value: []byte(fmt.Sprint(count))},
+		},
+	}
+}
+
 func TestReader_Listen(t *testing.T) {
 	t.Parallel()
 
@@ -38,6 +55,26 @@ func TestReader_Listen(t *testing.T) {
 		CommitPosition: wal.CommitPosition(testOffsetStr),
 	}
 
+	testSchemaLogEvent := wal.Event{
+		Data: &wal.Data{
+			Action: "I",
+			Schema: schemalog.SchemaName,
+			Table:  schemalog.TableName,
+			Columns: []wal.Column{
+				{Name: "id", Value: "cq7rhbs7fh5e0e1es0ig"},
+			},
+		},
+		CommitPosition: wal.CommitPosition(testOffsetStr),
+	}
+
+	testWireFormatMessage := &kafka.Message{
+		Topic:     "test-topic",
+		Partition: 0,
+		Offset:    1,
+		Key:       []byte("test-key"),
+		Value:     schemaregistry.EncodeWireFormat(1, []byte("test-value")),
+	}
+
 	errTest := errors.New("oh noes")
 
 	testUnmarshaler := func(b []byte, a any) error {
@@ -49,10 +86,16 @@ func TestReader_Listen(t *testing.T) {
 	}
 
 	tests := []struct {
-		name          string
-		reader        func(doneChan chan struct{}) *kafkamocks.Reader
-		processRecord payloadProcessor
-		unmarshaler   func(b []byte, a any) error
+		name            string
+		reader          func(doneChan chan struct{}) *kafkamocks.Reader
+		processRecord   payloadProcessor
+		unmarshaler     func(b []byte, a any) error
+		deserialiser    dataDeserialiser
+		decoder         dataDeserialiser
+		deadLetterQueue *dlqmocks.Queue
+		errorTopic      *kafkamocks.Writer
+		retry           *backoff.Config
+		concurrency     int
 
 		wantErr error
 	}{
@@ -74,6 +117,348 @@ func TestReader_Listen(t *testing.T) {
 
 			wantErr: context.Canceled,
 		},
+		{
+			name: "ok - end of range",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				calls := 0
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						calls++
+						if calls == 1 {
+							return testMessage, nil
+						}
+						defer func() { doneChan <- struct{}{} }()
+						return nil, kafka.ErrEndOfRange
+					},
+				}
+			},
+			processRecord: func(ctx context.Context, d *wal.Event) error {
+				require.Equal(t, &testWalEvent, d)
+				return nil
+			},
+
+			wantErr: nil,
+		},
+		{
+			name: "ok - schema registry wire format",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				calls := 0
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						calls++
+						if calls == 1 {
+							return testWireFormatMessage, nil
+						}
+						defer func() { doneChan <- struct{}{} }()
+						return nil, kafka.ErrEndOfRange
+					},
+				}
+			},
+			processRecord: func(ctx context.Context, d *wal.Event) error {
+				require.Equal(t, &testWalEvent, d)
+				return nil
+			},
+			unmarshaler: func(b []byte, a any) error { return errors.New("unmarshaler: should not be called") },
+			deserialiser: mockDataDeserialiser(func(ctx context.Context, msg []byte) (*wal.Data, error) {
+				require.Equal(t, testWireFormatMessage.Value, msg)
+				return testWalEvent.Data, nil
+			}),
+
+			wantErr: nil,
+		},
+		{
+			name: "ok - decoder",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				calls := 0
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						calls++
+						if calls == 1 {
+							return testMessage, nil
+						}
+						defer func() { doneChan <- struct{}{} }()
+						return nil, kafka.ErrEndOfRange
+					},
+				}
+			},
+			processRecord: func(ctx context.Context, d *wal.Event) error {
+				require.Equal(t, &testWalEvent, d)
+				return nil
+			},
+			unmarshaler: func(b []byte, a any) error { return errors.New("unmarshaler: should not be called") },
+			decoder: mockDataDeserialiser(func(ctx context.Context, msg []byte) (*wal.Data, error) {
+				require.Equal(t, testMessage.Value, msg)
+				return testWalEvent.Data, nil
+			}),
+
+			wantErr: nil,
+		},
+		{
+			name: "ok - headers",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				calls := 0
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						calls++
+						if calls == 1 {
+							msg := *testMessage
+							msg.Headers = []kafka.Header{
+								{Key: kafka.HeaderTable, Value: []byte("test_table")},
+							}
+							return &msg, nil
+						}
+						defer func() { doneChan <- struct{}{} }()
+						return nil, kafka.ErrEndOfRange
+					},
+				}
+			},
+			processRecord: func(ctx context.Context, d *wal.Event) error {
+				wantEvent := testWalEvent
+				wantEvent.Headers = map[string]string{kafka.HeaderTable: "test_table"}
+				require.Equal(t, &wantEvent, d)
+				return nil
+			},
+
+			wantErr: nil,
+		},
+		{
+			name: "ok - compaction tombstone",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				calls := 0
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						calls++
+						if calls == 1 {
+							return &kafka.Message{
+								Topic: "test-topic",
+								Key:   []byte(`{"schema":"test_schema","table":"test_table","columns":[{"id":"c1","name":"id","value":"a"}]}`),
+								Headers: []kafka.Header{
+									{Key: kafka.HeaderLSN, Value: []byte("0/1")},
+								},
+							}, nil
+						}
+						defer func() { doneChan <- struct{}{} }()
+						return nil, kafka.ErrEndOfRange
+					},
+				}
+			},
+			processRecord: func(ctx context.Context, d *wal.Event) error {
+				require.Equal(t, &wal.Event{
+					Data: &wal.Data{
+						Action: "D",
+						LSN:    "0/1",
+						Schema: "test_schema",
+						Table:  "test_table",
+						Identity: []wal.Column{
+							{ID: "c1", Name: "id", Value: "a"},
+						},
+						Metadata: wal.Metadata{InternalColIDs: []string{"c1"}},
+					},
+					CommitPosition: wal.CommitPosition(testOffsetStr),
+					Headers:        map[string]string{kafka.HeaderLSN: "0/1"},
+				}, d)
+				return nil
+			},
+			unmarshaler: func(b []byte, a any) error { return errors.New("unmarshaler: should not be called") },
+
+			wantErr: nil,
+		},
+		{
+			name: "ok - tombstone without compaction key",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				calls := 0
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						calls++
+						if calls == 1 {
+							return &kafka.Message{Topic: "test-topic", Key: []byte("test-key")}, nil
+						}
+						defer func() { doneChan <- struct{}{} }()
+						return nil, kafka.ErrEndOfRange
+					},
+				}
+			},
+			processRecord: func(ctx context.Context, d *wal.Event) error {
+				require.Equal(t, &wal.Event{CommitPosition: wal.CommitPosition(testOffsetStr)}, d)
+				return nil
+			},
+			unmarshaler: func(b []byte, a any) error { return errors.New("unmarshaler: should not be called") },
+
+			wantErr: nil,
+		},
+		{
+			name: "ok - chunked message",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				chunks := []string{"test-", "value"}
+				calls := 0
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						calls++
+						if calls <= len(chunks) {
+							return &kafka.Message{
+								Topic: "test-topic",
+								Value: []byte(chunks[calls-1]),
+								Headers: []kafka.Header{
+									{Key: kafka.HeaderChunkID, Value: []byte("id")},
+									{Key: kafka.HeaderChunkIndex, Value: []byte(fmt.Sprint(calls - 1))},
+									{Key: kafka.HeaderChunkCount, Value: []byte("2")},
+								},
+							}, nil
+						}
+						defer func() { doneChan <- struct{}{} }()
+						return nil, kafka.ErrEndOfRange
+					},
+				}
+			},
+			processRecord: func() payloadProcessor {
+				calls := 0
+				return func(ctx context.Context, d *wal.Event) error {
+					calls++
+					require.Equal(t, 1, calls)
+					require.Equal(t, testWalEvent.Data, d.Data)
+					return nil
+				}
+			}(),
+
+			wantErr: nil,
+		},
+		{
+			name: "ok - leading chunk skipped",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				chunks := []*kafka.Message{
+					testChunk("a", 1, 2, "value"),
+					testChunk("b", 0, 2, "test-"),
+					testChunk("b", 1, 2, "value"),
+				}
+				calls := 0
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						calls++
+						if calls <= len(chunks) {
+							return chunks[calls-1], nil
+						}
+						defer func() { doneChan <- struct{}{} }()
+						return nil, kafka.ErrEndOfRange
+					},
+				}
+			},
+			processRecord: func() payloadProcessor {
+				calls := 0
+				return func(ctx context.Context, d *wal.Event) error {
+					calls++
+					require.Equal(t, 1, calls)
+					require.Equal(t, testWalEvent.Data, d.Data)
+					return nil
+				}
+			}(),
+
+			wantErr: nil,
+		},
+		{
+			name: "ok - incomplete chunk set sent to dead letter queue",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				chunks := []*kafka.Message{
+					testChunk("a", 0, 3, "test-"),
+					testChunk("a", 2, 3, "value"),
+				}
+				calls := 0
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						calls++
+						if calls <= len(chunks) {
+							return chunks[calls-1], nil
+						}
+						defer func() { doneChan <- struct{}{} }()
+						return nil, kafka.ErrEndOfRange
+					},
+				}
+			},
+			processRecord: func(ctx context.Context, d *wal.Event) error {
+				return errors.New("processRecord: should not be called")
+			},
+			deadLetterQueue: &dlqmocks.Queue{
+				SendFn: func(ctx context.Context, entry *dlq.Entry) error {
+					require.Equal(t, wal.CommitPosition(testOffsetStr), entry.Event.CommitPosition)
+					require.Nil(t, entry.Event.Data)
+					require.Contains(t, entry.Error, errIncompleteChunkSet.Error())
+					return nil
+				},
+			},
+
+			wantErr: nil,
+		},
+		{
+			name: "ok - concurrency",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				calls := 0
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						calls++
+						if calls <= 3 {
+							return &kafka.Message{
+								Topic:     "test-topic",
+								Partition: calls,
+								Offset:    1,
+								Value:     []byte("test-value"),
+							}, nil
+						}
+						defer func() { doneChan <- struct{}{} }()
+						return nil, kafka.ErrEndOfRange
+					},
+				}
+			},
+			processRecord: func(ctx context.Context, d *wal.Event) error {
+				require.Equal(t, &testWalEvent, d)
+				return nil
+			},
+			concurrency: 2,
+
+			wantErr: nil,
+		},
+		{
+			name: "ok - duplicate schema log events",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				calls := 0
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						calls++
+						if calls <= 2 {
+							return &kafka.Message{
+								Topic:     "test-topic",
+								Partition: calls,
+								Offset:    1,
+								Value:     []byte("test-value"),
+							}, nil
+						}
+						defer func() { doneChan <- struct{}{} }()
+						return nil, kafka.ErrEndOfRange
+					},
+				}
+			},
+			processRecord: func() payloadProcessor {
+				var mutex sync.Mutex
+				calls := 0
+				return func(ctx context.Context, d *wal.Event) error {
+					mutex.Lock()
+					defer mutex.Unlock()
+					calls++
+					switch calls {
+					case 1:
+						require.Equal(t, &testSchemaLogEvent, d)
+					default:
+						// copies are processed as keep alive events
+						require.Equal(t, &wal.Event{CommitPosition: wal.CommitPosition(testOffsetStr)}, d)
+					}
+					return nil
+				}
+			}(),
+			decoder: mockDataDeserialiser(func(ctx context.Context, msg []byte) (*wal.Data, error) {
+				return testSchemaLogEvent.Data, nil
+			}),
+			concurrency: 2,
+
+			wantErr: nil,
+		},
 		{
 			name: "error - fetching message",
 			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
@@ -91,6 +476,37 @@ func TestReader_Listen(t *testing.T) {
 
 			wantErr: errTest,
 		},
+		{
+			name: "ok - processing message retried",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				var once sync.Once
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						defer once.Do(func() { doneChan <- struct{}{} })
+						return testMessage, nil
+					},
+				}
+			},
+			processRecord: func() payloadProcessor {
+				calls := 0
+				// the first attempt of every message fails
+				return func(ctx context.Context, d *wal.Event) error {
+					calls++
+					if calls%2 == 1 {
+						return errTest
+					}
+					return nil
+				}
+			}(),
+			retry: &backoff.Config{
+				Constant: &backoff.ConstantConfig{
+					Interval:   time.Millisecond,
+					MaxRetries: 1,
+				},
+			},
+
+			wantErr: context.Canceled,
+		},
 		{
 			name: "error - processing message",
 			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
@@ -106,8 +522,155 @@ func TestReader_Listen(t *testing.T) {
 				return errTest
 			},
 
+			wantErr: errTest,
+		},
+		{
+			name: "error - processing message retries exhausted",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				var once sync.Once
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						defer once.Do(func() { doneChan <- struct{}{} })
+						return testMessage, nil
+					},
+				}
+			},
+			processRecord: func() payloadProcessor {
+				calls := 0
+				return func(ctx context.Context, d *wal.Event) error {
+					calls++
+					require.LessOrEqual(t, calls, 3)
+					return errTest
+				}
+			}(),
+			retry: &backoff.Config{
+				Constant: &backoff.ConstantConfig{
+					Interval:   time.Millisecond,
+					MaxRetries: 2,
+				},
+			},
+
+			wantErr: errTest,
+		},
+		{
+			name: "error - processing message forwarded to error topic",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				var once sync.Once
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						defer once.Do(func() { doneChan <- struct{}{} })
+						return &kafka.Message{
+							Topic:     "test-topic",
+							Partition: 2,
+							Offset:    1,
+							Key:       []byte("test-key"),
+							Value:     []byte("test-value"),
+							Headers:   []kafka.Header{{Key: "source", Value: []byte("pgstream")}},
+						}, nil
+					},
+				}
+			},
+			processRecord: func(ctx context.Context, d *wal.Event) error {
+				return errTest
+			},
+			errorTopic: &kafkamocks.Writer{
+				WriteMessagesFn: func(ctx context.Context, i uint64, msgs ...kafka.Message) error {
+					require.Equal(t, []kafka.Message{
+						{
+							Topic: "test-topic.errors",
+							Key:   []byte("test-key"),
+							Value: []byte("test-value"),
+							Headers: []kafka.Header{
+								{Key: "source", Value: []byte("pgstream")},
+								{Key: kafka.HeaderError, Value: []byte(errTest.Error())},
+								{Key: kafka.HeaderErrorAttempts, Value: []byte("1")},
+								{Key: kafka.HeaderSourceTopic, Value: []byte("test-topic")},
+								{Key: kafka.HeaderSourcePartition, Value: []byte("2")},
+								{Key: kafka.HeaderSourceOffset, Value: []byte("1")},
+							},
+						},
+					}, msgs)
+					return nil
+				},
+			},
+			deadLetterQueue: &dlqmocks.Queue{
+				SendFn: func(ctx context.Context, entry *dlq.Entry) error {
+					return errors.New("SendFn: should not be called")
+				},
+			},
+
+			wantErr: context.Canceled,
+		},
+		{
+			name: "error - forwarding message to error topic",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				var once sync.Once
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						defer once.Do(func() { doneChan <- struct{}{} })
+						return testMessage, nil
+					},
+				}
+			},
+			processRecord: func(ctx context.Context, d *wal.Event) error {
+				return errTest
+			},
+			errorTopic: &kafkamocks.Writer{
+				WriteMessagesFn: func(ctx context.Context, i uint64, msgs ...kafka.Message) error {
+					return errTest
+				},
+			},
+
+			wantErr: errTest,
+		},
+		{
+			name: "error - processing message sent to dead letter queue",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				var once sync.Once
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						defer once.Do(func() { doneChan <- struct{}{} })
+						return testMessage, nil
+					},
+				}
+			},
+			processRecord: func(ctx context.Context, d *wal.Event) error {
+				return errTest
+			},
+			deadLetterQueue: &dlqmocks.Queue{
+				SendFn: func(ctx context.Context, entry *dlq.Entry) error {
+					require.Equal(t, &testWalEvent, entry.Event)
+					require.Equal(t, "wal_kafka_reader", entry.Processor)
+					require.Equal(t, errTest.Error(), entry.Error)
+					require.Equal(t, 1, entry.Attempts)
+					return nil
+				},
+			},
+
 			wantErr: context.Canceled,
 		},
+		{
+			name: "error - sending message to dead letter queue",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				var once sync.Once
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						defer once.Do(func() { doneChan <- struct{}{} })
+						return testMessage, nil
+					},
+				}
+			},
+			processRecord: func(ctx context.Context, d *wal.Event) error {
+				return errTest
+			},
+			deadLetterQueue: &dlqmocks.Queue{
+				SendFn: func(ctx context.Context, entry *dlq.Entry) error {
+					return errTest
+				},
+			},
+
+			wantErr: errTest,
+		},
 		{
 			name: "error - processing message context canceled",
 			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
@@ -125,6 +688,30 @@ func TestReader_Listen(t *testing.T) {
 
 			wantErr: context.Canceled,
 		},
+		{
+			name: "error - incomplete chunk set",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				chunks := []*kafka.Message{
+					testChunk("a", 0, 3, "test-"),
+					testChunk("a", 2, 3, "value"),
+				}
+				calls := 0
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						calls++
+						if calls == len(chunks) {
+							defer func() { doneChan <- struct{}{} }()
+						}
+						return chunks[calls-1], nil
+					},
+				}
+			},
+			processRecord: func(ctx context.Context, d *wal.Event) error {
+				return errors.New("processRecord: should not be called")
+			},
+
+			wantErr: errIncompleteChunkSet,
+		},
 		{
 			name: "error - unmarshaling message",
 			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
@@ -141,6 +728,43 @@ func TestReader_Listen(t *testing.T) {
 			},
 			unmarshaler: func(b []byte, a any) error { return errTest },
 
+			wantErr: errTest,
+		},
+		{
+			name: "error - schema registry wire format without deserialiser",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				var once sync.Once
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						defer once.Do(func() { doneChan <- struct{}{} })
+						return testWireFormatMessage, nil
+					},
+				}
+			},
+			processRecord: func(ctx context.Context, d *wal.Event) error {
+				return errors.New("processRecord: should not be called")
+			},
+
+			wantErr: errMissingDeserialiser,
+		},
+		{
+			name: "error - deserialising message",
+			reader: func(doneChan chan struct{}) *kafkamocks.Reader {
+				var once sync.Once
+				return &kafkamocks.Reader{
+					FetchMessageFn: func(ctx context.Context) (*kafka.Message, error) {
+						defer once.Do(func() { doneChan <- struct{}{} })
+						return testWireFormatMessage, nil
+					},
+				}
+			},
+			processRecord: func(ctx context.Context, d *wal.Event) error {
+				return errors.New("processRecord: should not be called")
+			},
+			deserialiser: mockDataDeserialiser(func(ctx context.Context, msg []byte) (*wal.Data, error) {
+				return nil, errTest
+			}),
+
 			wantErr: errTest,
 		},
 	}
@@ -157,6 +781,11 @@ func TestReader_Listen(t *testing.T) {
 				reader:        tc.reader(doneChan),
 				processRecord: tc.processRecord,
 				unmarshaler:   testUnmarshaler,
+				concurrency:   tc.concurrency,
+				schemaLogIDs:  newSchemaLogIDSet(),
+				backoffProvider: func(ctx context.Context) backoff.Backoff {
+					return backoff.NewStopBackoff()
+				},
 				offsetParser: &kafkamocks.OffsetParser{
 					ToStringFn: func(o *kafka.Offset) string { return testOffsetStr },
 				},
@@ -165,6 +794,21 @@ func TestReader_Listen(t *testing.T) {
 			if tc.unmarshaler != nil {
 				r.unmarshaler = tc.unmarshaler
 			}
+			if tc.deadLetterQueue != nil {
+				r.deadLetterQueue = tc.deadLetterQueue
+			}
+			if tc.deserialiser != nil {
+				r.deserialiser = tc.deserialiser
+			}
+			if tc.decoder != nil {
+				r.decoder = tc.decoder
+			}
+			if tc.errorTopic != nil {
+				r.errorTopic = &errorTopic{writer: tc.errorTopic, topic: "test-topic.errors"}
+			}
+			if tc.retry != nil {
+				r.backoffProvider = backoff.NewProvider(tc.retry)
+			}
 
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
@@ -190,3 +834,9 @@ func TestReader_Listen(t *testing.T) {
 		})
 	}
 }
+
+type mockDataDeserialiser func(ctx context.Context, msg []byte) (*wal.Data, error)
+
+func (m mockDataDeserialiser) Deserialise(ctx context.Context, msg []byte) (*wal.Data, error) {
+	return m(ctx, msg)
+}
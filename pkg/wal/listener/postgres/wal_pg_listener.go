@@ -25,6 +25,10 @@ type Listener struct {
 	processEvent listenerProcessWalEvent
 
 	walDataDeserialiser func([]byte, any) error
+
+	// currentTx keeps track of the transaction being received, to populate
+	// the transaction metadata of its events.
+	currentTx *wal.Transaction
 }
 
 type replicationHandler interface {
@@ -157,8 +161,40 @@ func (l *Listener) processWALEvent(ctx context.Context, msg *replication.Message
 		if err := l.walDataDeserialiser(msg.Data, event.Data); err != nil {
 			return fmt.Errorf("error unmarshaling wal data: %w", err)
 		}
+		l.addTransactionMetadata(event.Data)
 	}
 	event.CommitPosition = wal.CommitPosition(l.lsnParser.ToString(msg.LSN))
 
 	return l.processEvent(ctx, event)
 }
+
+// addTransactionMetadata populates the transaction metadata of the wal data on
+// input, keeping track of the transaction boundaries.
+func (l *Listener) addTransactionMetadata(data *wal.Data) {
+	switch {
+	case data.IsBegin():
+		l.currentTx = &wal.Transaction{
+			XID:             data.XID,
+			CommitLSN:       data.LSN,
+			CommitTimestamp: data.Timestamp,
+		}
+	case l.currentTx == nil, data.IsLogicalMessage() && !data.Transactional:
+		// events received outside of a transaction (if the plugin doesn't
+		// send transaction boundaries) and non transactional logical messages
+		// only contain the transaction id if any.
+		if data.XID != 0 {
+			data.Transaction = &wal.Transaction{XID: data.XID}
+		}
+		return
+	default:
+		l.currentTx.Sequence++
+	}
+
+	tx := *l.currentTx
+	if data.IsCommit() {
+		// the commit LSN is always available on the commit event
+		tx.CommitLSN = data.LSN
+		l.currentTx = nil
+	}
+	data.Transaction = &tx
+}
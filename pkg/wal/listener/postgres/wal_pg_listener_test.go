@@ -385,3 +385,47 @@ func TestListener_Listen(t *testing.T) {
 		})
 	}
 }
+
+func TestListener_addTransactionMetadata(t *testing.T) {
+	t.Parallel()
+
+	const (
+		testXID         = uint32(42)
+		testCommitLSN   = "1/CF54A100"
+		testCommitTime  = "2024-05-02 10:30:15.123456+00"
+		testInsertLSN   = "1/CF54A050"
+		testUpdateLSN   = "1/CF54A060"
+		testNoTxDataLSN = "1/CF54A200"
+	)
+
+	l := New(newMockReplicationHandler(), nil)
+
+	events := []*wal.Data{
+		{Action: "B", XID: testXID, Timestamp: testCommitTime},
+		{Action: "I", XID: testXID, LSN: testInsertLSN, Timestamp: testCommitTime},
+		{Action: "U", XID: testXID, LSN: testUpdateLSN, Timestamp: testCommitTime},
+		{Action: "M", XID: testXID, LSN: testUpdateLSN, Timestamp: testCommitTime, Transactional: true, Prefix: "test"},
+		{Action: "C", XID: testXID, LSN: testCommitLSN, Timestamp: testCommitTime},
+		{Action: "I", XID: testXID + 1, LSN: testNoTxDataLSN},
+		{Action: "M", LSN: testNoTxDataLSN, Prefix: "test"},
+	}
+	for _, e := range events {
+		l.addTransactionMetadata(e)
+	}
+
+	wantTransactions := []*wal.Transaction{
+		{XID: testXID, CommitTimestamp: testCommitTime, Sequence: 0},
+		{XID: testXID, CommitTimestamp: testCommitTime, Sequence: 1},
+		{XID: testXID, CommitTimestamp: testCommitTime, Sequence: 2},
+		{XID: testXID, CommitTimestamp: testCommitTime, Sequence: 3},
+		{XID: testXID, CommitTimestamp: testCommitTime, CommitLSN: testCommitLSN, Sequence: 4},
+		// events received outside of a transaction only get the xid
+		{XID: testXID + 1},
+		// non transactional messages don't belong to any transaction
+		nil,
+	}
+	for i, e := range events {
+		require.Equal(t, wantTransactions[i], e.Transaction, "event %d", i)
+	}
+	require.Nil(t, l.currentTx)
+}
@@ -7,6 +7,8 @@ import (
 	pgsnapshotgenerator "github.com/xataio/pgstream/pkg/snapshot/generator/postgres/data"
 	"github.com/xataio/pgstream/pkg/snapshot/generator/postgres/schema/pgdumprestore"
 	"github.com/xataio/pgstream/pkg/wal/listener/snapshot/adapter"
+	"github.com/xataio/pgstream/pkg/wal/processor/projection"
+	"github.com/xataio/pgstream/pkg/wal/processor/router"
 )
 
 type SnapshotListenerConfig struct {
@@ -14,6 +16,18 @@ type SnapshotListenerConfig struct {
 	Adapter   adapter.SnapshotConfig
 	Recorder  *SnapshotRecorderConfig
 	Schema    SchemaSnapshotConfig
+	// RowFilters maps schema qualified table names to row filter expressions.
+	// Only the rows matching the expression of their table will be included in
+	// the data snapshot.
+	RowFilters map[string]string
+	// Routing contains the table routing rules applied to the schema
+	// snapshot, so that the restored schema is consistent with the routed
+	// events.
+	Routing *router.Config
+	// Projection contains the column projection applied to the schema
+	// snapshot, so that the restored tables are consistent with the projected
+	// events.
+	Projection *projection.Config
 }
 
 type SchemaSnapshotConfig struct {
@@ -25,6 +25,9 @@ import (
 	"github.com/xataio/pgstream/pkg/wal/listener"
 	listenersnapshot "github.com/xataio/pgstream/pkg/wal/listener/snapshot"
 	"github.com/xataio/pgstream/pkg/wal/listener/snapshot/adapter"
+	"github.com/xataio/pgstream/pkg/wal/processor/filter"
+	"github.com/xataio/pgstream/pkg/wal/processor/projection"
+	"github.com/xataio/pgstream/pkg/wal/processor/router"
 )
 
 var errSchemaSnapshotNotConfigured = errors.New("no schema snapshot has been configured")
@@ -62,6 +65,13 @@ func NewSnapshotGenerator(ctx context.Context, cfg *SnapshotListenerConfig, p li
 	if instrumentation.IsEnabled() {
 		opts = append(opts, pgsnapshotgenerator.WithInstrumentation(instrumentation))
 	}
+	if len(cfg.RowFilters) > 0 {
+		rowFilter, err := filter.NewRowFilter(cfg.RowFilters)
+		if err != nil {
+			return nil, fmt.Errorf("creating snapshot row filter: %w", err)
+		}
+		opts = append(opts, pgsnapshotgenerator.WithRowFilter(rowFilter.Matches))
+	}
 	g, err = pgsnapshotgenerator.NewSnapshotGenerator(ctx, &cfg.Generator, rowsProcessor, opts...)
 	if err != nil {
 		return nil, err
@@ -79,7 +89,21 @@ func NewSnapshotGenerator(ctx context.Context, cfg *SnapshotListenerConfig, p li
 	}
 
 	// postgres schema snapshot generator layer
-	g, err = newSchemaSnapshotGenerator(ctx, &cfg.Schema, g, rowsProcessor.ProcessRow, logger, instrumentation)
+	var tableRouter *router.TableRouter
+	if cfg.Routing != nil {
+		tableRouter, err = router.NewTableRouter(cfg.Routing)
+		if err != nil {
+			return nil, fmt.Errorf("creating snapshot table router: %w", err)
+		}
+	}
+	var columnProjection *projection.ColumnProjection
+	if cfg.Projection != nil {
+		columnProjection, err = projection.NewColumnProjection(cfg.Projection)
+		if err != nil {
+			return nil, fmt.Errorf("creating snapshot column projection: %w", err)
+		}
+	}
+	g, err = newSchemaSnapshotGenerator(ctx, &cfg.Schema, g, rowsProcessor.ProcessRow, tableRouter, columnProjection, logger, instrumentation)
 	if err != nil {
 		return nil, err
 	}
@@ -107,7 +131,7 @@ func NewSnapshotGenerator(ctx context.Context, cfg *SnapshotListenerConfig, p li
 	return adapter.NewSnapshotGeneratorAdapter(&cfg.Adapter, g, adapter.WithLogger(logger)), nil
 }
 
-func newSchemaSnapshotGenerator(ctx context.Context, cfg *SchemaSnapshotConfig, g generator.SnapshotGenerator, processRow snapshot.RowProcessor, logger loglib.Logger, instrumentation *otel.Instrumentation) (generator.SnapshotGenerator, error) {
+func newSchemaSnapshotGenerator(ctx context.Context, cfg *SchemaSnapshotConfig, g generator.SnapshotGenerator, processRow snapshot.RowProcessor, tableRouter *router.TableRouter, columnProjection *projection.ColumnProjection, logger loglib.Logger, instrumentation *otel.Instrumentation) (generator.SnapshotGenerator, error) {
 	switch {
 	case cfg.SchemaLogStore != nil:
 		// postgres schemalog schema snapshot generator
@@ -135,6 +159,12 @@ func newSchemaSnapshotGenerator(ctx context.Context, cfg *SchemaSnapshotConfig,
 		if instrumentation.IsEnabled() {
 			opts = append(opts, pgdumprestoregenerator.WithInstrumentation(instrumentation))
 		}
+		if tableRouter != nil {
+			opts = append(opts, pgdumprestoregenerator.WithTableRouter(tableRouter))
+		}
+		if columnProjection != nil {
+			opts = append(opts, pgdumprestoregenerator.WithColumnProjection(columnProjection))
+		}
 		return pgdumprestoregenerator.NewSnapshotGenerator(ctx, cfg.DumpRestore, opts...)
 	default:
 		return nil, errSchemaSnapshotNotConfigured
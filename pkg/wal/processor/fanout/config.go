@@ -0,0 +1,20 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package fanout
+
+type TargetConfig struct {
+	// MaxLag is the maximum number of events the target can fall behind
+	// before the fan out blocks. It isolates the targets from each other, so
+	// that a slow target doesn't block the rest until it exceeds its lag.
+	// Defaults to 1000.
+	MaxLag int
+}
+
+const defaultMaxLag = 1000
+
+func (c *TargetConfig) GetMaxLag() int {
+	if c.MaxLag > 0 {
+		return c.MaxLag
+	}
+	return defaultMaxLag
+}
@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package fanout
+
+import (
+	"context"
+	"sync"
+
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/checkpointer"
+)
+
+// PositionTracker keeps track of the positions acknowledged by each of the
+// fan out targets, and checkpoints the source only up to the minimum position
+// acknowledged by all of them.
+type PositionTracker struct {
+	mu         sync.Mutex
+	checkpoint checkpointer.Checkpoint
+	// lastSeq is the sequence number of the last tracked position. Sequence
+	// numbers follow the order in which the events are received, which allows
+	// to compare positions regardless of their format.
+	lastSeq uint64
+	// pending contains the positions that have not been checkpointed yet, in
+	// the order they were tracked
+	pending []trackedPosition
+	// seqs maps the pending positions to the sequence numbers of each of their
+	// occurrences, in the order they were tracked. Positions can be repeated
+	// (i.e. multi table truncates sharing the same LSN), and each occurrence
+	// needs to be acknowledged separately.
+	seqs map[wal.CommitPosition][]uint64
+	// acked contains the last sequence number acknowledged by each target
+	acked map[string]uint64
+	// checkpointedSeq is the last sequence number checkpointed on the source
+	checkpointedSeq uint64
+}
+
+type trackedPosition struct {
+	seq      uint64
+	position wal.CommitPosition
+}
+
+// NewPositionTracker returns a position tracker that will call the checkpoint
+// on input with the positions acknowledged by all the registered targets.
+func NewPositionTracker(checkpoint checkpointer.Checkpoint) *PositionTracker {
+	return &PositionTracker{
+		checkpoint: checkpoint,
+		seqs:       map[wal.CommitPosition][]uint64{},
+		acked:      map[string]uint64{},
+	}
+}
+
+// TargetCheckpoint registers the target on input and returns the checkpoint
+// it should use to acknowledge its processed positions. It returns a nil
+// checkpoint if the tracker has no source checkpoint.
+func (t *PositionTracker) TargetCheckpoint(target string) checkpointer.Checkpoint {
+	if t.checkpoint == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	t.acked[target] = 0
+	t.mu.Unlock()
+
+	return func(ctx context.Context, positions []wal.CommitPosition) error {
+		return t.ack(ctx, target, positions)
+	}
+}
+
+// Track registers the position on input, which will be checkpointed once all
+// targets have acknowledged it.
+func (t *PositionTracker) Track(position wal.CommitPosition) {
+	if t.checkpoint == nil || position == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.lastSeq++
+	t.pending = append(t.pending, trackedPosition{seq: t.lastSeq, position: position})
+	t.seqs[position] = append(t.seqs[position], t.lastSeq)
+}
+
+func (t *PositionTracker) ack(ctx context.Context, target string, positions []wal.CommitPosition) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, position := range positions {
+		// each acknowledgement applies to the first occurrence of the position
+		// not yet acknowledged by the target. Positions that are no longer
+		// pending have already been checkpointed.
+		for _, seq := range t.seqs[position] {
+			if seq > t.acked[target] {
+				t.acked[target] = seq
+				break
+			}
+		}
+	}
+
+	minSeq := t.minAckedSeq()
+	if minSeq <= t.checkpointedSeq {
+		return nil
+	}
+
+	checkpointPositions := []wal.CommitPosition{}
+	i := 0
+	for ; i < len(t.pending) && t.pending[i].seq <= minSeq; i++ {
+		// duplicated positions are only checkpointed once all their tracked
+		// occurrences have been acknowledged
+		position := t.pending[i].position
+		t.seqs[position] = t.seqs[position][1:]
+		if len(t.seqs[position]) == 0 {
+			delete(t.seqs, position)
+			checkpointPositions = append(checkpointPositions, position)
+		}
+	}
+	t.pending = t.pending[i:]
+	t.checkpointedSeq = minSeq
+
+	if len(checkpointPositions) == 0 {
+		return nil
+	}
+	return t.checkpoint(ctx, checkpointPositions)
+}
+
+func (t *PositionTracker) minAckedSeq() uint64 {
+	first := true
+	var minSeq uint64
+	for _, seq := range t.acked {
+		if first || seq < minSeq {
+			minSeq = seq
+			first = false
+		}
+	}
+	return minSeq
+}
@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package fanout
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func TestPositionTracker(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	checkpoints := [][]wal.CommitPosition{}
+	tracker := NewPositionTracker(func(_ context.Context, positions []wal.CommitPosition) error {
+		checkpoints = append(checkpoints, positions)
+		return nil
+	})
+
+	postgresCheckpoint := tracker.TargetCheckpoint("postgres")
+	webhookCheckpoint := tracker.TargetCheckpoint("webhook")
+
+	for _, position := range []wal.CommitPosition{"1", "2", "2", "3", "4"} {
+		tracker.Track(position)
+	}
+
+	// the source is not checkpointed until all targets acknowledge positions
+	require.NoError(t, postgresCheckpoint(ctx, []wal.CommitPosition{"1", "3"}))
+	require.Empty(t, checkpoints)
+
+	require.NoError(t, webhookCheckpoint(ctx, []wal.CommitPosition{"1"}))
+	require.Equal(t, [][]wal.CommitPosition{{"1"}}, checkpoints)
+
+	// duplicated positions are only checkpointed once all their occurrences
+	// have been acknowledged
+	require.NoError(t, webhookCheckpoint(ctx, []wal.CommitPosition{"2"}))
+	require.Equal(t, [][]wal.CommitPosition{{"1"}}, checkpoints)
+	require.NoError(t, webhookCheckpoint(ctx, []wal.CommitPosition{"2"}))
+	require.Equal(t, [][]wal.CommitPosition{{"1"}, {"2"}}, checkpoints)
+
+	// the checkpoint only advances to the minimum acknowledged position
+	require.NoError(t, webhookCheckpoint(ctx, []wal.CommitPosition{"4"}))
+	require.Equal(t, [][]wal.CommitPosition{{"1"}, {"2"}, {"3"}}, checkpoints)
+
+	// already checkpointed positions are ignored
+	require.NoError(t, postgresCheckpoint(ctx, []wal.CommitPosition{"2", "4"}))
+	require.Equal(t, [][]wal.CommitPosition{{"1"}, {"2"}, {"3"}, {"4"}}, checkpoints)
+	require.Empty(t, tracker.pending)
+	require.Empty(t, tracker.seqs)
+}
+
+func TestPositionTracker_duplicatedPositions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	checkpoints := [][]wal.CommitPosition{}
+	tracker := NewPositionTracker(func(_ context.Context, positions []wal.CommitPosition) error {
+		checkpoints = append(checkpoints, positions)
+		return nil
+	})
+
+	postgresCheckpoint := tracker.TargetCheckpoint("postgres")
+	kafkaCheckpoint := tracker.TargetCheckpoint("kafka")
+
+	// multi table truncates share the same position
+	for _, position := range []wal.CommitPosition{"1", "1", "1", "2"} {
+		tracker.Track(position)
+	}
+
+	require.NoError(t, postgresCheckpoint(ctx, []wal.CommitPosition{"1", "1", "1", "2"}))
+	require.Empty(t, checkpoints)
+
+	// acknowledging the first occurrence doesn't acknowledge the following
+	// ones
+	require.NoError(t, kafkaCheckpoint(ctx, []wal.CommitPosition{"1"}))
+	require.Empty(t, checkpoints)
+	require.Equal(t, uint64(1), tracker.acked["kafka"])
+
+	require.NoError(t, kafkaCheckpoint(ctx, []wal.CommitPosition{"1"}))
+	require.Empty(t, checkpoints)
+	require.Equal(t, uint64(2), tracker.acked["kafka"])
+
+	require.NoError(t, kafkaCheckpoint(ctx, []wal.CommitPosition{"1", "2"}))
+	require.Equal(t, [][]wal.CommitPosition{{"1", "2"}}, checkpoints)
+	require.Empty(t, tracker.pending)
+	require.Empty(t, tracker.seqs)
+}
+
+func TestPositionTracker_noCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	tracker := NewPositionTracker(nil)
+	require.Nil(t, tracker.TargetCheckpoint("postgres"))
+
+	tracker.Track("1")
+	require.Empty(t, tracker.pending)
+}
@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+)
+
+// FanOut is a processor that sends the WAL events to multiple targets. Each
+// target processes the events in its own goroutine, with a queue of up to its
+// configured max lag events, so that a slow target doesn't block the rest
+// until it falls too far behind. The positions of the events are tracked so
+// that the source is only checkpointed once all targets have acknowledged
+// them.
+type FanOut struct {
+	targets []*target
+	tracker *PositionTracker
+	logger  loglib.Logger
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// Target is a processor the events are fanned out to.
+type Target struct {
+	Name      string
+	Processor processor.Processor
+	Config    TargetConfig
+}
+
+type target struct {
+	name      string
+	processor processor.Processor
+	events    chan *wal.Event
+	// done is closed when the target stops processing events, with err
+	// containing the reason
+	done chan struct{}
+	err  error
+}
+
+type Option func(*FanOut)
+
+var errTargetStopped = errors.New("fan out target stopped")
+
+// New returns a fan out processor that will send the WAL events to the
+// targets on input. The targets are expected to use the tracker target
+// checkpoints to acknowledge the processed positions.
+func New(ctx context.Context, targets []Target, tracker *PositionTracker, opts ...Option) *FanOut {
+	f := &FanOut{
+		targets: make([]*target, 0, len(targets)),
+		tracker: tracker,
+		logger:  loglib.NewNoopLogger(),
+	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	for _, t := range targets {
+		target := &target{
+			name:      t.Name,
+			processor: t.Processor,
+			events:    make(chan *wal.Event, t.Config.GetMaxLag()),
+			done:      make(chan struct{}),
+		}
+		f.targets = append(f.targets, target)
+
+		f.wg.Add(1)
+		go func() {
+			defer f.wg.Done()
+			f.process(ctx, target)
+		}()
+	}
+
+	return f
+}
+
+func WithLogger(logger loglib.Logger) Option {
+	return func(f *FanOut) {
+		f.logger = loglib.NewLogger(logger).WithFields(loglib.Fields{
+			loglib.ModuleField: "wal_fanout",
+		})
+	}
+}
+
+// ProcessWALEvent sends a copy of the event on input to each of the targets.
+// It will block if any of the targets has reached its max lag.
+func (f *FanOut) ProcessWALEvent(ctx context.Context, event *wal.Event) error {
+	f.tracker.Track(event.CommitPosition)
+
+	for _, t := range f.targets {
+		select {
+		case <-t.done:
+			return t.stoppedErr()
+		default:
+		}
+
+		eventCopy := copyEvent(event)
+		select {
+		case t.events <- eventCopy:
+			continue
+		default:
+		}
+
+		f.logger.Debug("fan out target reached max lag, waiting", loglib.Fields{"target": t.name})
+		select {
+		case t.events <- eventCopy:
+		case <-t.done:
+			return t.stoppedErr()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (f *FanOut) Name() string {
+	return "fanout"
+}
+
+// Close stops sending events to the targets, waiting for them to process the
+// queued events before closing them.
+func (f *FanOut) Close() error {
+	var errs error
+	f.once.Do(func() {
+		for _, t := range f.targets {
+			close(t.events)
+		}
+		f.wg.Wait()
+
+		for _, t := range f.targets {
+			if err := t.processor.Close(); err != nil {
+				errs = errors.Join(errs, fmt.Errorf("closing target %s: %w", t.name, err))
+			}
+		}
+	})
+	return errs
+}
+
+func (f *FanOut) process(ctx context.Context, t *target) {
+	defer close(t.done)
+	for event := range t.events {
+		if err := t.processor.ProcessWALEvent(ctx, event); err != nil {
+			f.logger.Error(err, "fan out target stopped processing events", loglib.Fields{"target": t.name})
+			t.err = err
+			return
+		}
+	}
+	t.err = errTargetStopped
+}
+
+func (t *target) stoppedErr() error {
+	if errors.Is(t.err, errTargetStopped) {
+		return t.err
+	}
+	return fmt.Errorf("%w: %s: %w", errTargetStopped, t.name, t.err)
+}
+
+// copyEvent returns a copy of the event on input, so that the targets (and
+// their modifiers) can update it independently.
+func copyEvent(event *wal.Event) *wal.Event {
+	eventCopy := *event
+	if event.Data != nil {
+		dataCopy := *event.Data
+		dataCopy.Columns = slices.Clone(event.Data.Columns)
+		dataCopy.Identity = slices.Clone(event.Data.Identity)
+		dataCopy.Metadata.InternalColIDs = slices.Clone(event.Data.Metadata.InternalColIDs)
+		if event.Data.Transaction != nil {
+			transactionCopy := *event.Data.Transaction
+			dataCopy.Transaction = &transactionCopy
+		}
+		eventCopy.Data = &dataCopy
+	}
+	if event.Route != nil {
+		routeCopy := *event.Route
+		routeCopy.Headers = maps.Clone(event.Route.Headers)
+		eventCopy.Route = &routeCopy
+	}
+	return &eventCopy
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package fanout
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/mocks"
+)
+
+var errTest = errors.New("oh noes")
+
+func TestFanOut_ProcessWALEvent(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testEvent := func(position wal.CommitPosition) *wal.Event {
+		return &wal.Event{
+			Data: &wal.Data{
+				Action:  "I",
+				Schema:  "public",
+				Table:   "users",
+				Columns: []wal.Column{{Name: "id", Value: 1}},
+			},
+			CommitPosition: position,
+		}
+	}
+
+	var mu sync.Mutex
+	checkpoints := []wal.CommitPosition{}
+	tracker := NewPositionTracker(func(_ context.Context, positions []wal.CommitPosition) error {
+		mu.Lock()
+		defer mu.Unlock()
+		checkpoints = append(checkpoints, positions...)
+		return nil
+	})
+
+	// the postgres target updates the event, which must not affect the event
+	// received by the webhook target
+	postgresCheckpoint := tracker.TargetCheckpoint("postgres")
+	postgresCalls := atomic.Int32{}
+	postgresTarget := &mocks.Processor{
+		ProcessWALEventFn: func(ctx context.Context, event *wal.Event) error {
+			postgresCalls.Add(1)
+			event.Data.Schema = "tenant_a"
+			event.Data.Columns[0].Value = 2
+			return postgresCheckpoint(ctx, []wal.CommitPosition{event.CommitPosition})
+		},
+	}
+
+	// the webhook target is blocked until released
+	release := make(chan struct{})
+	webhookCheckpoint := tracker.TargetCheckpoint("webhook")
+	webhookEvents := make(chan *wal.Event, 3)
+	webhookTarget := &mocks.Processor{
+		ProcessWALEventFn: func(ctx context.Context, event *wal.Event) error {
+			<-release
+			webhookEvents <- event
+			return webhookCheckpoint(ctx, []wal.CommitPosition{event.CommitPosition})
+		},
+	}
+
+	f := New(ctx, []Target{
+		{Name: "postgres", Processor: postgresTarget},
+		{Name: "webhook", Processor: webhookTarget, Config: TargetConfig{MaxLag: 1}},
+	}, tracker)
+
+	// the first event is processed by the webhook target (blocked), the second
+	// one is queued, and the third one exceeds the webhook max lag
+	require.NoError(t, f.ProcessWALEvent(ctx, testEvent("1")))
+	require.NoError(t, f.ProcessWALEvent(ctx, testEvent("2")))
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	err := f.ProcessWALEvent(timeoutCtx, testEvent("3"))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the postgres target is not blocked by the webhook target lag
+	require.Eventually(t, func() bool { return postgresCalls.Load() == 3 }, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	require.Empty(t, checkpoints)
+	mu.Unlock()
+
+	close(release)
+	require.NoError(t, f.Close())
+
+	require.Equal(t, testEvent("1"), <-webhookEvents)
+	require.Equal(t, testEvent("2"), <-webhookEvents)
+	require.Equal(t, []wal.CommitPosition{"1", "2"}, checkpoints)
+}
+
+func TestFanOut_ProcessWALEvent_targetError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tracker := NewPositionTracker(nil)
+
+	f := New(ctx, []Target{
+		{
+			Name: "postgres",
+			Processor: &mocks.Processor{
+				ProcessWALEventFn: func(ctx context.Context, event *wal.Event) error { return nil },
+			},
+		},
+		{
+			Name: "webhook",
+			Processor: &mocks.Processor{
+				ProcessWALEventFn: func(ctx context.Context, event *wal.Event) error { return errTest },
+			},
+		},
+	}, tracker)
+	defer f.Close()
+
+	require.NoError(t, f.ProcessWALEvent(ctx, &wal.Event{CommitPosition: "1"}))
+	require.Eventually(t, func() bool {
+		err := f.ProcessWALEvent(ctx, &wal.Event{CommitPosition: "2"})
+		return errors.Is(err, errTest) && errors.Is(err, errTargetStopped)
+	}, time.Second, 10*time.Millisecond)
+}
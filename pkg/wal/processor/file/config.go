@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+)
+
+type Config struct {
+	// Dir is the directory where the files are written. It will be created if
+	// it doesn't exist.
+	Dir string
+	// Format of the files. One of ndjson, csv. Defaults to ndjson. The ndjson
+	// format writes all the events to the same file, while the csv format
+	// writes a file per table.
+	Format Format
+	// Compression of the files. One of none, gzip, zstd. Defaults to none.
+	Compression Compression
+	// MaxFileBytes is the size at which a file is finalised and a new one is
+	// started. Defaults to 100MiB.
+	MaxFileBytes int64
+	// RotationInterval is the time after which a file is finalised and a new
+	// one is started. Defaults to 1h.
+	RotationInterval time.Duration
+	Batch            batch.Config
+}
+
+type Format string
+
+const (
+	FormatNDJSON Format = "ndjson"
+	FormatCSV    Format = "csv"
+)
+
+type Compression string
+
+const (
+	CompressionNone Compression = "none"
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+const (
+	defaultMaxFileBytes     = int64(100 * 1024 * 1024) // 100MiB
+	defaultRotationInterval = time.Hour
+)
+
+func (c *Config) GetFormat() Format {
+	if c.Format != "" {
+		return c.Format
+	}
+	return FormatNDJSON
+}
+
+func (c *Config) GetCompression() Compression {
+	if c.Compression != "" {
+		return c.Compression
+	}
+	return CompressionNone
+}
+
+func (c *Config) GetMaxFileBytes() int64 {
+	if c.MaxFileBytes > 0 {
+		return c.MaxFileBytes
+	}
+	return defaultMaxFileBytes
+}
+
+func (c *Config) GetRotationInterval() time.Duration {
+	if c.RotationInterval > 0 {
+		return c.RotationInterval
+	}
+	return defaultRotationInterval
+}
+
+func (c *Config) IsValid() error {
+	if c.Dir == "" {
+		return errMissingDir
+	}
+
+	switch c.GetFormat() {
+	case FormatNDJSON, FormatCSV:
+	default:
+		return fmt.Errorf("%w: %s", errUnsupportedFormat, c.Format)
+	}
+
+	switch c.GetCompression() {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return fmt.Errorf("%w: %s", errUnsupportedCompression, c.Compression)
+	}
+
+	return nil
+}
@@ -0,0 +1,371 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	loglib "github.com/xataio/pgstream/pkg/log"
+)
+
+// fileRotator manages the files being written, rotating them when they reach
+// the configured size or age. The files are written with an in progress
+// suffix, and renamed once they're finalised, so that readers only ever see
+// complete files.
+type fileRotator struct {
+	logger           loglib.Logger
+	dir              string
+	extension        string
+	compressor       func([]byte) ([]byte, error)
+	maxFileBytes     int64
+	rotationInterval time.Duration
+	files            map[string]*rotatingFile
+	now              func() time.Time
+}
+
+type rotatingFile struct {
+	name     string
+	path     string
+	file     *os.File
+	openedAt time.Time
+	// size is the number of bytes written to disk
+	size int64
+	// header is the list of columns of the file, if any
+	header []string
+	// buffer contains the data pending to be written to disk
+	buffer bytes.Buffer
+}
+
+const inProgressSuffix = ".tmp"
+
+func newFileRotator(cfg *Config, logger loglib.Logger) (*fileRotator, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating directory %s: %w", cfg.Dir, err)
+	}
+
+	r := &fileRotator{
+		logger:           logger,
+		dir:              cfg.Dir,
+		extension:        "." + string(cfg.GetFormat()),
+		maxFileBytes:     cfg.GetMaxFileBytes(),
+		rotationInterval: cfg.GetRotationInterval(),
+		files:            map[string]*rotatingFile{},
+		now:              time.Now,
+	}
+
+	switch cfg.GetCompression() {
+	case CompressionGzip:
+		r.extension += ".gz"
+		r.compressor = gzipCompress
+	case CompressionZstd:
+		r.extension += ".zst"
+		encoder, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating zstd encoder: %w", err)
+		}
+		r.compressor = func(b []byte) ([]byte, error) {
+			return encoder.EncodeAll(b, nil), nil
+		}
+	}
+
+	if err := r.recover(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// get returns the open file for the name on input, opening a new one with the
+// header provided if there isn't one.
+func (r *fileRotator) get(name string, header []string) (*rotatingFile, error) {
+	if f, found := r.files[name]; found {
+		return f, nil
+	}
+
+	openedAt := r.now().UTC()
+	path := filepath.Join(r.dir, fmt.Sprintf("%s-%s%s%s", name, openedAt.Format("20060102T150405.000000000Z"), r.extension, inProgressSuffix))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("creating file: %w", err)
+	}
+
+	f := &rotatingFile{
+		name:     name,
+		path:     path,
+		file:     file,
+		openedAt: openedAt,
+		header:   header,
+	}
+	r.files[name] = f
+	return f, nil
+}
+
+// rotate finalises the open file for the name on input, if any.
+func (r *fileRotator) rotate(name string) error {
+	f, found := r.files[name]
+	if !found {
+		return nil
+	}
+	if err := r.write(f); err != nil {
+		return err
+	}
+	return r.finalise(f)
+}
+
+// flush writes the pending data of all the files to disk, making sure it's
+// synced before returning. Files that have reached the configured size or age
+// are finalised.
+func (r *fileRotator) flush() error {
+	for _, f := range r.files {
+		if err := r.write(f); err != nil {
+			return err
+		}
+
+		if f.size >= r.maxFileBytes || r.now().Sub(f.openedAt) >= r.rotationInterval {
+			if err := r.finalise(f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// close flushes and finalises all the open files.
+func (r *fileRotator) close() error {
+	var errs error
+	for _, f := range r.files {
+		if err := r.write(f); err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		if err := r.finalise(f); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	return errs
+}
+
+// write writes the pending data of the file to disk. When compression is
+// enabled, the data is written as an independent compressed member, so that
+// the file is always readable up to the last synced write.
+func (r *fileRotator) write(f *rotatingFile) error {
+	if f.buffer.Len() == 0 {
+		return nil
+	}
+
+	data := f.buffer.Bytes()
+	if r.compressor != nil {
+		var err error
+		data, err = r.compressor(data)
+		if err != nil {
+			return fmt.Errorf("compressing data for %s: %w", f.path, err)
+		}
+	}
+
+	n, err := f.file.Write(data)
+	if err != nil {
+		return fmt.Errorf("writing to %s: %w", f.path, err)
+	}
+	if err := f.file.Sync(); err != nil {
+		return fmt.Errorf("syncing %s: %w", f.path, err)
+	}
+
+	f.size += int64(n)
+	f.buffer.Reset()
+	return nil
+}
+
+func (r *fileRotator) finalise(f *rotatingFile) error {
+	delete(r.files, f.name)
+
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("closing %s: %w", f.path, err)
+	}
+	if err := r.rename(f.path); err != nil {
+		return err
+	}
+
+	r.logger.Debug("file finalised", loglib.Fields{"file": strings.TrimSuffix(f.path, inProgressSuffix), "size": f.size})
+	return nil
+}
+
+// rename removes the in progress suffix from the file on input, syncing the
+// directory so that the rename is durable.
+func (r *fileRotator) rename(path string) error {
+	if err := os.Rename(path, strings.TrimSuffix(path, inProgressSuffix)); err != nil {
+		return fmt.Errorf("renaming %s: %w", path, err)
+	}
+
+	dir, err := os.Open(r.dir)
+	if err != nil {
+		return fmt.Errorf("opening directory %s: %w", r.dir, err)
+	}
+	defer dir.Close()
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("syncing directory %s: %w", r.dir, err)
+	}
+	return nil
+}
+
+// recover finalises the in progress files left behind by a previous run that
+// didn't shut down cleanly. Their content was synced to disk before the
+// positions of its events were checkpointed, but the events written after the
+// last checkpoint will be written again once the replication resumes from it,
+// so the finalised files can contain duplicates. The last write might be
+// incomplete, in which case it's truncated, and files without any complete
+// write are removed.
+func (r *fileRotator) recover() error {
+	paths, err := filepath.Glob(filepath.Join(r.dir, "*"+inProgressSuffix))
+	if err != nil {
+		return fmt.Errorf("listing in progress files: %w", err)
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		length, err := completeWritesLength(strings.TrimSuffix(path, inProgressSuffix), data)
+		if err != nil {
+			return fmt.Errorf("recovering %s: %w", path, err)
+		}
+
+		if length == 0 {
+			r.logger.Warn(nil, "removing in progress file from previous run without complete writes", loglib.Fields{"file": path})
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("removing %s: %w", path, err)
+			}
+			continue
+		}
+
+		if length < len(data) {
+			r.logger.Warn(nil, "truncating incomplete last write of in progress file from previous run", loglib.Fields{
+				"file":            path,
+				"size":            len(data),
+				"truncated_bytes": len(data) - length,
+			})
+			if err := truncate(path, int64(length)); err != nil {
+				return err
+			}
+		}
+
+		r.logger.Warn(nil, "finalising in progress file from previous run", loglib.Fields{"file": path})
+		if err := r.rename(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// completeWritesLength returns the length of the data on input made of
+// complete writes, based on the format and compression of the file name
+// provided. Compressed writes are independent members, so the data is
+// truncated at the start of the last member that can't be decompressed.
+// Uncompressed writes always end with a complete record.
+func completeWritesLength(name string, data []byte) (int, error) {
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		return compressedWritesLength(data, gzipMagic, func(b []byte) error {
+			reader, err := gzip.NewReader(bytes.NewReader(b))
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(io.Discard, reader)
+			return err
+		}), nil
+	case strings.HasSuffix(name, ".zst"):
+		decoder, err := zstd.NewReader(nil)
+		if err != nil {
+			return 0, fmt.Errorf("creating zstd decoder: %w", err)
+		}
+		defer decoder.Close()
+		return compressedWritesLength(data, zstdMagic, func(b []byte) error {
+			_, err := decoder.DecodeAll(b, nil)
+			return err
+		}), nil
+	case strings.HasSuffix(name, "."+string(FormatCSV)):
+		return csvRecordsLength(data), nil
+	default:
+		return bytes.LastIndexByte(data, '\n') + 1, nil
+	}
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// compressedWritesLength returns the length of the longest prefix of the data
+// on input that can be decompressed. The candidate prefixes end where a
+// compressed member starts, identified by its magic number.
+func compressedWritesLength(data, magic []byte, decompress func([]byte) error) int {
+	if len(data) == 0 || decompress(data) == nil {
+		return len(data)
+	}
+	for end := len(data); end > 0; {
+		end = bytes.LastIndex(data[:end], magic)
+		if end <= 0 {
+			return 0
+		}
+		if decompress(data[:end]) == nil {
+			return end
+		}
+	}
+	return 0
+}
+
+// csvRecordsLength returns the length of the data on input up to the end of
+// the last complete record.
+func csvRecordsLength(data []byte) int {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	length := 0
+	for {
+		if _, err := reader.Read(); err != nil {
+			return length
+		}
+		// the last record might not be terminated
+		if offset := int(reader.InputOffset()); data[offset-1] == '\n' {
+			length = offset
+		}
+	}
+}
+
+// truncate truncates the file on input to the size provided, syncing it so
+// that the truncation is durable.
+func truncate(path string, size int64) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close()
+	if err := f.Truncate(size); err != nil {
+		return fmt.Errorf("truncating %s: %w", path, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("syncing %s: %w", path, err)
+	}
+	return nil
+}
+
+func gzipCompress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
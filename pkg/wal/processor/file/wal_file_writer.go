@@ -0,0 +1,301 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"slices"
+
+	"github.com/xataio/pgstream/internal/json"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/checkpointer"
+	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+)
+
+// BatchWriter is a processor that writes the WAL events to local files, using
+// batches. The files are synced to disk before the batch positions are
+// checkpointed.
+type BatchWriter struct {
+	logger      loglib.Logger
+	batchSender batchSender
+	format      Format
+	files       *fileRotator
+
+	// optional checkpointer callback to mark what was safely processed
+	checkpointer checkpointer.Checkpoint
+
+	serialiser func(any) ([]byte, error)
+}
+
+type Option func(*BatchWriter)
+
+type batchSender interface {
+	SendMessage(context.Context, *batch.WALMessage[*message]) error
+	Close()
+}
+
+// message is the batch message for a WAL event. The serialised event is used
+// for the ndjson format, and to account for the message size in the batch.
+type message struct {
+	data  *wal.Data
+	value []byte
+}
+
+var (
+	errMissingDir             = errors.New("missing file target directory")
+	errUnsupportedFormat      = errors.New("unsupported file format")
+	errUnsupportedCompression = errors.New("unsupported file compression")
+)
+
+const (
+	// ndjsonFileName is the name of the file all the events are written to
+	// when using the ndjson format
+	ndjsonFileName = "events"
+
+	csvActionColumn    = "_action"
+	csvTimestampColumn = "_timestamp"
+	csvLSNColumn       = "_lsn"
+)
+
+var csvMetadataColumns = []string{csvActionColumn, csvTimestampColumn, csvLSNColumn}
+
+// NewBatchWriter returns a processor that writes the WAL events to files in
+// the configured directory. In progress files left behind by a previous run
+// are finalised on creation.
+func NewBatchWriter(ctx context.Context, config *Config, opts ...Option) (*BatchWriter, error) {
+	if err := config.IsValid(); err != nil {
+		return nil, err
+	}
+
+	w := &BatchWriter{
+		logger:     loglib.NewNoopLogger(),
+		format:     config.GetFormat(),
+		serialiser: json.Marshal,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	var err error
+	w.files, err = newFileRotator(config, w.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	w.batchSender, err = batch.NewSender(ctx, &config.Batch, w.sendBatch, w.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func WithLogger(l loglib.Logger) Option {
+	return func(w *BatchWriter) {
+		w.logger = loglib.NewLogger(l).WithFields(loglib.Fields{
+			loglib.ModuleField: "file_batch_writer",
+		})
+	}
+}
+
+func WithCheckpoint(c checkpointer.Checkpoint) Option {
+	return func(w *BatchWriter) {
+		w.checkpointer = c
+	}
+}
+
+// ProcessWALEvent is called on every new message from the wal. It can be called
+// concurrently.
+func (w *BatchWriter) ProcessWALEvent(ctx context.Context, walEvent *wal.Event) (retErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			w.logger.Panic("[PANIC] Panic while processing replication event", loglib.Fields{
+				"wal_data":    walEvent,
+				"panic":       r,
+				"stack_trace": debug.Stack(),
+			})
+
+			retErr = fmt.Errorf("file batch writer: understanding event: %v", r)
+		}
+	}()
+
+	// events that are not written don't produce any message. They're not
+	// treated as keep alive messages to prevent every transaction from
+	// triggering a batch send.
+	if walEvent.Data != nil && !w.isWritable(walEvent.Data) {
+		return nil
+	}
+
+	msg := &message{}
+	if walEvent.Data != nil {
+		value, err := w.serialiser(walEvent.Data)
+		if err != nil {
+			return fmt.Errorf("marshalling event: %w", err)
+		}
+		msg = &message{
+			data:  walEvent.Data,
+			value: value,
+		}
+	}
+
+	return w.batchSender.SendMessage(ctx, batch.NewWALMessage(msg, walEvent.CommitPosition))
+}
+
+func (w *BatchWriter) Name() string {
+	return "file-batch-writer"
+}
+
+func (w *BatchWriter) Close() error {
+	w.batchSender.Close()
+	return w.files.close()
+}
+
+func (w *BatchWriter) sendBatch(ctx context.Context, batch *batch.Batch[*message]) error {
+	messages := batch.GetMessages()
+	w.logger.Debug("file batch writer: writing message batch", loglib.Fields{
+		"batch_size":             len(messages),
+		"batch_commit_positions": len(batch.GetCommitPositions()),
+	})
+
+	for _, msg := range messages {
+		var err error
+		switch w.format {
+		case FormatCSV:
+			err = w.writeCSV(msg.data)
+		default:
+			err = w.writeNDJSON(msg.value)
+		}
+		if err != nil {
+			return fmt.Errorf("file batch writer: %w", err)
+		}
+	}
+
+	// the positions can only be checkpointed once the data is synced to disk
+	if err := w.files.flush(); err != nil {
+		return fmt.Errorf("file batch writer: %w", err)
+	}
+
+	positions := batch.GetCommitPositions()
+	if w.checkpointer != nil && len(positions) > 0 {
+		if err := w.checkpointer(ctx, positions); err != nil {
+			w.logger.Warn(err, "file batch writer: error updating commit position")
+		}
+	}
+
+	return nil
+}
+
+func (w *BatchWriter) writeNDJSON(value []byte) error {
+	f, err := w.files.get(ndjsonFileName, nil)
+	if err != nil {
+		return err
+	}
+	f.buffer.Write(value)
+	f.buffer.WriteByte('\n')
+	return nil
+}
+
+// writeCSV writes the row event to the file of its table. The file header
+// contains the metadata columns followed by the table columns. When the table
+// columns change, the file is rotated so that each file has a consistent
+// header.
+func (w *BatchWriter) writeCSV(data *wal.Data) error {
+	name := data.Schema + "." + data.Table
+	columns := data.Columns
+	if data.Action == "D" {
+		columns = data.Identity
+	}
+
+	header := slices.Clone(csvMetadataColumns)
+	for _, c := range columns {
+		header = append(header, c.Name)
+	}
+
+	f, found := w.files.files[name]
+	switch {
+	case found && (data.Action == "D" || data.Action == "T"):
+		// deletes and truncates don't contain all the table columns, use the
+		// current header
+		header = f.header
+	case found && !slices.Equal(f.header, header):
+		if err := w.files.rotate(name); err != nil {
+			return err
+		}
+	}
+
+	f, err := w.files.get(name, header)
+	if err != nil {
+		return err
+	}
+
+	csvWriter := csv.NewWriter(&f.buffer)
+	if f.size == 0 && f.buffer.Len() == 0 {
+		if err := csvWriter.Write(header); err != nil {
+			return fmt.Errorf("writing csv header: %w", err)
+		}
+	}
+
+	values := make(map[string]string, len(columns))
+	for _, c := range columns {
+		value, err := w.csvValue(c.Value)
+		if err != nil {
+			return fmt.Errorf("formatting column %s: %w", c.Name, err)
+		}
+		values[c.Name] = value
+	}
+
+	record := make([]string, 0, len(header))
+	record = append(record, data.Action, data.Timestamp, data.LSN)
+	for _, column := range header[len(csvMetadataColumns):] {
+		record = append(record, values[column])
+	}
+	if err := csvWriter.Write(record); err != nil {
+		return fmt.Errorf("writing csv record: %w", err)
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func (w *BatchWriter) csvValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		b, err := w.serialiser(v)
+		if err != nil {
+			return "", err
+		}
+		return string(bytes.Trim(b, `"`)), nil
+	}
+}
+
+// isWritable returns whether the data on input is written to the files. The
+// csv format only writes row events.
+func (w *BatchWriter) isWritable(data *wal.Data) bool {
+	if w.format != FormatCSV {
+		return true
+	}
+	switch data.Action {
+	case "I", "U", "D", "T":
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *message) Size() int {
+	return len(m.value)
+}
+
+func (m *message) IsEmpty() bool {
+	return m == nil || m.data == nil
+}
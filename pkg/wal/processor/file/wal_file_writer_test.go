@@ -0,0 +1,428 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/internal/json"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+	batchmocks "github.com/xataio/pgstream/pkg/wal/processor/batch/mocks"
+)
+
+var errTest = errors.New("oh noes")
+
+func TestBatchWriter_ProcessWALEvent(t *testing.T) {
+	t.Parallel()
+
+	testData := &wal.Data{
+		Action: "I",
+		Schema: "public",
+		Table:  "users",
+	}
+	testBytes := []byte("test")
+
+	tests := []struct {
+		name       string
+		format     Format
+		walEvent   *wal.Event
+		serialiser func(any) ([]byte, error)
+
+		wantMsgs []*batch.WALMessage[*message]
+		wantErr  error
+	}{
+		{
+			name:     "ok",
+			walEvent: &wal.Event{Data: testData, CommitPosition: "1"},
+
+			wantMsgs: []*batch.WALMessage[*message]{
+				batch.NewWALMessage(&message{data: testData, value: testBytes}, "1"),
+			},
+		},
+		{
+			name:     "ok - keep alive",
+			walEvent: &wal.Event{CommitPosition: "1"},
+
+			wantMsgs: []*batch.WALMessage[*message]{
+				batch.NewWALMessage(&message{}, "1"),
+			},
+		},
+		{
+			name:   "ok - csv skips non row events",
+			format: FormatCSV,
+			walEvent: &wal.Event{
+				Data:           &wal.Data{Action: "B"},
+				CommitPosition: "1",
+			},
+
+			wantMsgs: []*batch.WALMessage[*message]{},
+		},
+		{
+			name:     "error - serialising event",
+			walEvent: &wal.Event{Data: testData, CommitPosition: "1"},
+			serialiser: func(any) ([]byte, error) {
+				return nil, errTest
+			},
+
+			wantMsgs: []*batch.WALMessage[*message]{},
+			wantErr:  errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			batchSender := batchmocks.NewBatchSender[*message]()
+			w := &BatchWriter{
+				logger:      loglib.NewNoopLogger(),
+				batchSender: batchSender,
+				format:      tc.format,
+				serialiser:  func(any) ([]byte, error) { return testBytes, nil },
+			}
+			if tc.serialiser != nil {
+				w.serialiser = tc.serialiser
+			}
+
+			go func() {
+				defer batchSender.Close()
+				err := w.ProcessWALEvent(context.Background(), tc.walEvent)
+				require.ErrorIs(t, err, tc.wantErr)
+			}()
+
+			require.Equal(t, tc.wantMsgs, batchSender.GetWALMessages())
+		})
+	}
+}
+
+func TestBatchWriter_sendBatch(t *testing.T) {
+	t.Parallel()
+
+	insertEvent := func(id int, columns ...string) *wal.Data {
+		data := &wal.Data{
+			Action:    "I",
+			Timestamp: "2024-01-02 03:04:05.000000+00",
+			LSN:       "0/1",
+			Schema:    "public",
+			Table:     "users",
+			Columns:   []wal.Column{{Name: "id", Value: id}},
+		}
+		for _, c := range columns {
+			data.Columns = append(data.Columns, wal.Column{Name: c, Value: "a,b"})
+		}
+		return data
+	}
+	deleteEvent := &wal.Data{
+		Action:   "D",
+		Schema:   "public",
+		Table:    "users",
+		Identity: []wal.Column{{Name: "id", Value: 1}},
+	}
+
+	tests := []struct {
+		name        string
+		format      Format
+		compression Compression
+		batches     [][]*wal.Data
+
+		wantFiles []string
+	}{
+		{
+			name:    "ndjson",
+			batches: [][]*wal.Data{{insertEvent(1), insertEvent(2)}, {deleteEvent}},
+
+			wantFiles: []string{
+				`{"action":"I","timestamp":"2024-01-02 03:04:05.000000+00","lsn":"0/1","schema":"public","table":"users","columns":[{"id":"","name":"id","type":"","value":1}],"identity":null,"metadata":{"schema_id":null,"table_pgstream_id":"","id_col_pgstream_id":null,"version_col_pgstream_id":""}}
+{"action":"I","timestamp":"2024-01-02 03:04:05.000000+00","lsn":"0/1","schema":"public","table":"users","columns":[{"id":"","name":"id","type":"","value":2}],"identity":null,"metadata":{"schema_id":null,"table_pgstream_id":"","id_col_pgstream_id":null,"version_col_pgstream_id":""}}
+{"action":"D","timestamp":"","lsn":"","schema":"public","table":"users","columns":null,"identity":[{"id":"","name":"id","type":"","value":1}],"metadata":{"schema_id":null,"table_pgstream_id":"","id_col_pgstream_id":null,"version_col_pgstream_id":""}}
+`,
+			},
+		},
+		{
+			name:    "csv with column changes",
+			format:  FormatCSV,
+			batches: [][]*wal.Data{{insertEvent(1), deleteEvent}, {insertEvent(2, "name")}},
+
+			wantFiles: []string{
+				"_action,_timestamp,_lsn,id\nI,2024-01-02 03:04:05.000000+00,0/1,1\nD,,,1\n",
+				"_action,_timestamp,_lsn,id,name\nI,2024-01-02 03:04:05.000000+00,0/1,2,\"a,b\"\n",
+			},
+		},
+		{
+			name:        "gzip",
+			format:      FormatCSV,
+			compression: CompressionGzip,
+			batches:     [][]*wal.Data{{insertEvent(1)}, {insertEvent(2)}},
+
+			wantFiles: []string{
+				"_action,_timestamp,_lsn,id\nI,2024-01-02 03:04:05.000000+00,0/1,1\nI,2024-01-02 03:04:05.000000+00,0/1,2\n",
+			},
+		},
+		{
+			name:        "zstd",
+			format:      FormatCSV,
+			compression: CompressionZstd,
+			batches:     [][]*wal.Data{{insertEvent(1)}, {insertEvent(2)}},
+
+			wantFiles: []string{
+				"_action,_timestamp,_lsn,id\nI,2024-01-02 03:04:05.000000+00,0/1,1\nI,2024-01-02 03:04:05.000000+00,0/1,2\n",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			dir := t.TempDir()
+			cfg := &Config{
+				Dir:         dir,
+				Format:      tc.format,
+				Compression: tc.compression,
+			}
+
+			checkpoints := []wal.CommitPosition{}
+			w := newTestWriter(t, cfg)
+			w.checkpointer = func(_ context.Context, positions []wal.CommitPosition) error {
+				// the data must be synced to disk before checkpointing
+				require.Len(t, inProgressFiles(t, dir), 1)
+				checkpoints = append(checkpoints, positions...)
+				return nil
+			}
+			now := time.Now()
+			w.files.now = func() time.Time {
+				// make sure the rotated files have different names
+				now = now.Add(time.Second)
+				return now
+			}
+
+			wantCheckpoints := []wal.CommitPosition{}
+			for i, events := range tc.batches {
+				msgs := []*message{}
+				for _, data := range events {
+					value, err := w.serialiser(data)
+					require.NoError(t, err)
+					msgs = append(msgs, &message{data: data, value: value})
+				}
+				position := wal.CommitPosition(strings.Repeat("1", i+1))
+				wantCheckpoints = append(wantCheckpoints, position)
+				require.NoError(t, w.sendBatch(ctx, batch.NewBatch(msgs, []wal.CommitPosition{position})))
+			}
+			require.Equal(t, wantCheckpoints, checkpoints)
+
+			require.NoError(t, w.files.close())
+			require.Empty(t, inProgressFiles(t, dir))
+
+			paths, err := filepath.Glob(filepath.Join(dir, "*"))
+			require.NoError(t, err)
+			require.Len(t, paths, len(tc.wantFiles))
+			for i, path := range paths {
+				require.Equal(t, tc.wantFiles[i], readFile(t, path, tc.compression))
+			}
+		})
+	}
+}
+
+func TestBatchWriter_rotation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// leftover file from a previous run
+	leftover := filepath.Join(dir, "events-20240101T000000.000000000Z.ndjson"+inProgressSuffix)
+	require.NoError(t, os.WriteFile(leftover, []byte("{}\n"), 0o644))
+
+	w := newTestWriter(t, &Config{
+		Dir:              dir,
+		MaxFileBytes:     10,
+		RotationInterval: time.Minute,
+	})
+	defer w.files.close()
+	require.Empty(t, inProgressFiles(t, dir))
+
+	now := time.Now()
+	w.files.now = func() time.Time { return now }
+
+	// the file is rotated once it reaches the max size
+	require.NoError(t, w.sendBatch(ctx, batch.NewBatch([]*message{{data: &wal.Data{}, value: []byte("0123456789")}}, nil)))
+	require.Empty(t, inProgressFiles(t, dir))
+
+	// the file is rotated once it reaches the rotation interval
+	now = now.Add(time.Second)
+	require.NoError(t, w.sendBatch(ctx, batch.NewBatch([]*message{{data: &wal.Data{}, value: []byte("0")}}, nil)))
+	require.Len(t, inProgressFiles(t, dir), 1)
+	now = now.Add(time.Minute)
+	require.NoError(t, w.sendBatch(ctx, batch.NewBatch([]*message{}, nil)))
+	require.Empty(t, inProgressFiles(t, dir))
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.ndjson"))
+	require.NoError(t, err)
+	require.Len(t, paths, 3)
+}
+
+func TestFileRotator_recover(t *testing.T) {
+	t.Parallel()
+
+	gzipWrites := func(writes ...string) []byte {
+		data := []byte{}
+		for _, w := range writes {
+			b, err := gzipCompress([]byte(w))
+			require.NoError(t, err)
+			data = append(data, b...)
+		}
+		return data
+	}
+	zstdWrites := func(writes ...string) []byte {
+		encoder, err := zstd.NewWriter(nil)
+		require.NoError(t, err)
+		defer encoder.Close()
+		data := []byte{}
+		for _, w := range writes {
+			data = encoder.EncodeAll([]byte(w), data)
+		}
+		return data
+	}
+	torn := func(b []byte) []byte {
+		return b[:len(b)-3]
+	}
+
+	tests := []struct {
+		name        string
+		extension   string
+		compression Compression
+		data        []byte
+
+		wantData string
+	}{
+		{
+			name:      "ndjson complete writes",
+			extension: ".ndjson",
+			data:      []byte("{\"a\":1}\n{\"a\":2}\n"),
+
+			wantData: "{\"a\":1}\n{\"a\":2}\n",
+		},
+		{
+			name:      "ndjson incomplete write",
+			extension: ".ndjson",
+			data:      []byte("{\"a\":1}\n{\"a\":"),
+
+			wantData: "{\"a\":1}\n",
+		},
+		{
+			name:      "csv incomplete write within a quoted field",
+			extension: ".csv",
+			data:      []byte("id,name\n1,\"a\nb\"\n2,\"c\n"),
+
+			wantData: "id,name\n1,\"a\nb\"\n",
+		},
+		{
+			name:      "csv incomplete write without line end",
+			extension: ".csv",
+			data:      []byte("id,name\n1,a\n2,b"),
+
+			wantData: "id,name\n1,a\n",
+		},
+		{
+			name:        "gzip incomplete write",
+			extension:   ".ndjson.gz",
+			compression: CompressionGzip,
+			data:        append(gzipWrites("{}\n", "{}\n"), torn(gzipWrites("{\"a\":1}\n"))...),
+
+			wantData: "{}\n{}\n",
+		},
+		{
+			name:        "zstd incomplete write",
+			extension:   ".csv.zst",
+			compression: CompressionZstd,
+			data:        append(zstdWrites("id\n1\n", "2\n"), torn(zstdWrites("3\n"))...),
+
+			wantData: "id\n1\n2\n",
+		},
+		{
+			name:      "no complete writes",
+			extension: ".ndjson",
+			data:      []byte("{\"a\":"),
+		},
+		{
+			name:        "no complete compressed writes",
+			extension:   ".ndjson.gz",
+			compression: CompressionGzip,
+			data:        torn(gzipWrites("{}\n")),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			path := filepath.Join(dir, "events-20240101T000000.000000000Z"+tc.extension)
+			require.NoError(t, os.WriteFile(path+inProgressSuffix, tc.data, 0o644))
+
+			r := &fileRotator{logger: loglib.NewNoopLogger(), dir: dir}
+			require.NoError(t, r.recover())
+			require.Empty(t, inProgressFiles(t, dir))
+
+			if tc.wantData == "" {
+				require.NoFileExists(t, path)
+				return
+			}
+			require.Equal(t, tc.wantData, readFile(t, path, tc.compression))
+		})
+	}
+}
+
+func newTestWriter(t *testing.T, cfg *Config) *BatchWriter {
+	files, err := newFileRotator(cfg, loglib.NewNoopLogger())
+	require.NoError(t, err)
+
+	return &BatchWriter{
+		logger:     loglib.NewNoopLogger(),
+		format:     cfg.GetFormat(),
+		files:      files,
+		serialiser: json.Marshal,
+	}
+}
+
+func inProgressFiles(t *testing.T, dir string) []string {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+inProgressSuffix))
+	require.NoError(t, err)
+	return paths
+}
+
+func readFile(t *testing.T, path string, compression Compression) string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var reader io.Reader = f
+	switch compression {
+	case CompressionGzip:
+		gzipReader, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		reader = gzipReader
+	case CompressionZstd:
+		zstdReader, err := zstd.NewReader(f)
+		require.NoError(t, err)
+		defer zstdReader.Close()
+		reader = zstdReader
+	}
+
+	b, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(b)
+}
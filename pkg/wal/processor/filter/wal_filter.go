@@ -13,11 +13,12 @@ import (
 )
 
 // Filter is a processor wrapper that filter table WAL events based on the
-// configured table include/exclude lists.
+// configured table include/exclude lists and row filter expressions.
 type Filter struct {
 	processor       processor.Processor
 	includeTableMap schemaTableMap
 	excludeTableMap schemaTableMap
+	rowFilter       *RowFilter
 	logger          loglib.Logger
 }
 
@@ -30,6 +31,10 @@ type Config struct {
 	// is provided, the public schema will be assumed. Wildcards "*" are
 	// supported.
 	ExcludeTables []string
+	// Map of schema qualified table names to row filter expressions. Only the
+	// rows matching the expression of their table will be allowed. If no schema
+	// is provided, the public schema will be assumed.
+	RowFilters map[string]string
 }
 
 type schemaTableMap map[string]map[string]struct{}
@@ -54,7 +59,7 @@ func New(processor processor.Processor, cfg *Config, opts ...Option) (*Filter, e
 	if len(cfg.ExcludeTables) > 0 && len(cfg.IncludeTables) > 0 {
 		return nil, errIncludeExcludeList
 	}
-	if len(cfg.ExcludeTables) == 0 && len(cfg.IncludeTables) == 0 {
+	if len(cfg.ExcludeTables) == 0 && len(cfg.IncludeTables) == 0 && len(cfg.RowFilters) == 0 {
 		return nil, errMissingFilteringConfig
 	}
 
@@ -78,6 +83,13 @@ func New(processor processor.Processor, cfg *Config, opts ...Option) (*Filter, e
 		}
 	}
 
+	if len(cfg.RowFilters) > 0 {
+		f.rowFilter, err = NewRowFilter(cfg.RowFilters)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	for _, opt := range opts {
 		opt(f)
 	}
@@ -99,7 +111,8 @@ func WithLogger(logger loglib.Logger) Option {
 // since only one can be provided.
 func WithDefaultIncludeTables(tables []string) Option {
 	return func(f *Filter) {
-		if len(f.excludeTableMap) > 0 {
+		// the default include tables only apply when filtering by table
+		if len(f.excludeTableMap) > 0 || len(f.includeTableMap) == 0 {
 			return
 		}
 		for _, table := range tables {
@@ -119,6 +132,15 @@ func (f *Filter) ProcessWALEvent(ctx context.Context, event *wal.Event) error {
 		return nil
 	}
 
+	keep, err := f.applyRowFilter(event)
+	if err != nil {
+		return err
+	}
+	if !keep {
+		f.logger.Trace("skipping row event", loglib.Fields{"schema": event.Data.Schema, "table": event.Data.Table})
+		return nil
+	}
+
 	return f.processor.ProcessWALEvent(ctx, event)
 }
 
@@ -132,7 +154,10 @@ func (f *Filter) Close() error {
 
 // skip event for table if it's not in the include table list or if it's in the exclude one
 func (f *Filter) skipEvent(event *wal.Event) bool {
-	if event == nil || event.Data == nil {
+	// transaction boundaries and logical messages are not table specific, keep
+	// them so that the downstream processors can reconstruct transactions and
+	// route the messages
+	if event == nil || event.Data == nil || event.Data.IsTransactionBoundary() || event.Data.IsLogicalMessage() {
 		return false
 	}
 
@@ -146,6 +171,82 @@ func (f *Filter) skipEvent(event *wal.Event) bool {
 	return false
 }
 
+// applyRowFilter evaluates the row filter of the event table, if any, and
+// returns whether the event should be kept. Updates are evaluated against both
+// the old (identity) and the new values, so that rows moving into the filter
+// are emitted as inserts, and rows moving out of it as deletes. When the old
+// values required by the expression are not available (the table doesn't use
+// REPLICA IDENTITY FULL), updates that match are emitted as inserts, since the
+// row might not have previously matched, and updates that don't match are
+// emitted as deletes, since the row could have previously matched.
+func (f *Filter) applyRowFilter(event *wal.Event) (bool, error) {
+	if event == nil || event.Data == nil {
+		return true, nil
+	}
+	data := event.Data
+	predicate := f.rowFilter.get(data.Schema, data.Table)
+	if predicate == nil {
+		return true, nil
+	}
+
+	switch data.Action {
+	case "I":
+		return predicate.evaluate(columnValues(data.Columns))
+	case "D":
+		oldValues := columnValues(data.Identity)
+		// without the old values we can't know if the row matched, so we keep
+		// the delete
+		if !predicate.covers(oldValues) {
+			return true, nil
+		}
+		return predicate.evaluate(oldValues)
+	case "U":
+		newMatch, err := predicate.evaluate(columnValues(data.Columns))
+		if err != nil {
+			return false, err
+		}
+
+		oldValues := columnValues(data.Identity)
+		if !predicate.covers(oldValues) {
+			// without the old values we can't know if the row matched, so the
+			// row is emitted as an insert if it matches, since the target
+			// might not have it yet, and skipped otherwise, since the target
+			// is not expected to have it
+			if !newMatch {
+				return false, nil
+			}
+			data.Action = "I"
+			data.Identity = nil
+			return true, nil
+		}
+
+		oldMatch, err := predicate.evaluate(oldValues)
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case oldMatch && newMatch:
+			return true, nil
+		case newMatch:
+			// the row moved into the filter
+			data.Action = "I"
+			data.Identity = nil
+			return true, nil
+		case oldMatch:
+			// the row moved out of the filter. The new values are removed,
+			// since they don't match the filter and must not be exposed.
+			data.Action = "D"
+			data.Columns = nil
+			return true, nil
+		default:
+			return false, nil
+		}
+	default:
+		return true, nil
+	}
+}
+
 func (t schemaTableMap) containsSchemaTable(schema, table string) bool {
 	if len(t) == 0 {
 		return false
@@ -5,8 +5,10 @@ package filter
 import (
 	"context"
 	"errors"
+	"math/big"
 	"testing"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 	"github.com/xataio/pgstream/pkg/log"
 	"github.com/xataio/pgstream/pkg/wal"
@@ -82,6 +84,22 @@ func Test_New(t *testing.T) {
 			},
 			wantErr: errInvalidTableName,
 		},
+		{
+			name: "valid row filters configuration",
+			config: &Config{
+				RowFilters: map[string]string{"public.users": "tenant_id == 42"},
+			},
+			wantIncluded: nil,
+			wantExcluded: nil,
+			wantErr:      nil,
+		},
+		{
+			name: "invalid row filter expression",
+			config: &Config{
+				RowFilters: map[string]string{"public.users": "tenant_id =="},
+			},
+			wantErr: errInvalidRowFilter,
+		},
 		{
 			name:    "empty configuration",
 			config:  &Config{},
@@ -226,6 +244,44 @@ func TestFilter_ProcessWALEvent(t *testing.T) {
 			wantProcessCalls: 0,
 			wantErr:          nil,
 		},
+		{
+			name:  "transaction boundary event does not match included",
+			event: &wal.Event{Data: &wal.Data{Action: "C", XID: 1}},
+			processor: &mocks.Processor{
+				ProcessWALEventFn: func(ctx context.Context, walEvent *wal.Event) error {
+					require.Equal(t, &wal.Event{Data: &wal.Data{Action: "C", XID: 1}}, walEvent)
+					return nil
+				},
+			},
+			included: schemaTableMap{
+				"public": {
+					"orders": struct{}{},
+				},
+			},
+			excluded: nil,
+
+			wantProcessCalls: 1,
+			wantErr:          nil,
+		},
+		{
+			name:  "logical message event does not match included",
+			event: &wal.Event{Data: &wal.Data{Action: "M", Prefix: "test"}},
+			processor: &mocks.Processor{
+				ProcessWALEventFn: func(ctx context.Context, walEvent *wal.Event) error {
+					require.Equal(t, &wal.Event{Data: &wal.Data{Action: "M", Prefix: "test"}}, walEvent)
+					return nil
+				},
+			},
+			included: schemaTableMap{
+				"public": {
+					"orders": struct{}{},
+				},
+			},
+			excluded: nil,
+
+			wantProcessCalls: 1,
+			wantErr:          nil,
+		},
 		{
 			name:  "event matches excluded",
 			event: testEvent,
@@ -377,3 +433,169 @@ func TestSchemaTableMap_containsSchemaTable(t *testing.T) {
 		})
 	}
 }
+
+func TestFilter_ProcessWALEvent_rowFilter(t *testing.T) {
+	t.Parallel()
+
+	testSchema := "test_schema"
+	testTable := "test_table"
+
+	newTestEvent := func(action string, columns, identity []wal.Column) *wal.Event {
+		return &wal.Event{
+			Data: &wal.Data{
+				Action:   action,
+				Schema:   testSchema,
+				Table:    testTable,
+				Columns:  columns,
+				Identity: identity,
+			},
+		}
+	}
+
+	matchingCols := []wal.Column{
+		{Name: "id", Value: float64(1)},
+		{Name: "tenant_id", Value: float64(42)},
+		{Name: "status", Value: "published"},
+	}
+	nonMatchingCols := []wal.Column{
+		{Name: "id", Value: float64(1)},
+		{Name: "tenant_id", Value: float64(42)},
+		{Name: "status", Value: "draft"},
+	}
+	keyCols := []wal.Column{
+		{Name: "id", Value: float64(1)},
+	}
+
+	tests := []struct {
+		name  string
+		event *wal.Event
+
+		wantEvent *wal.Event
+		wantErr   error
+	}{
+		{
+			name:      "insert matches",
+			event:     newTestEvent("I", matchingCols, nil),
+			wantEvent: newTestEvent("I", matchingCols, nil),
+		},
+		{
+			name:      "insert doesn't match",
+			event:     newTestEvent("I", nonMatchingCols, nil),
+			wantEvent: nil,
+		},
+		{
+			name:      "delete matches",
+			event:     newTestEvent("D", nil, matchingCols),
+			wantEvent: newTestEvent("D", nil, matchingCols),
+		},
+		{
+			name:      "delete doesn't match",
+			event:     newTestEvent("D", nil, nonMatchingCols),
+			wantEvent: nil,
+		},
+		{
+			name:      "delete without old values",
+			event:     newTestEvent("D", nil, keyCols),
+			wantEvent: newTestEvent("D", nil, keyCols),
+		},
+		{
+			name:      "update matches old and new values",
+			event:     newTestEvent("U", matchingCols, matchingCols),
+			wantEvent: newTestEvent("U", matchingCols, matchingCols),
+		},
+		{
+			name:      "update moves row into the filter",
+			event:     newTestEvent("U", matchingCols, nonMatchingCols),
+			wantEvent: newTestEvent("I", matchingCols, nil),
+		},
+		{
+			name:      "update moves row out of the filter",
+			event:     newTestEvent("U", nonMatchingCols, matchingCols),
+			wantEvent: newTestEvent("D", nil, matchingCols),
+		},
+		{
+			name:      "update doesn't match old and new values",
+			event:     newTestEvent("U", nonMatchingCols, nonMatchingCols),
+			wantEvent: nil,
+		},
+		{
+			name:      "update without old values matches",
+			event:     newTestEvent("U", matchingCols, nil),
+			wantEvent: newTestEvent("I", matchingCols, nil),
+		},
+		{
+			name:      "update with partial old values matches",
+			event:     newTestEvent("U", matchingCols, keyCols),
+			wantEvent: newTestEvent("I", matchingCols, nil),
+		},
+		{
+			name:      "update without old values doesn't match",
+			event:     newTestEvent("U", nonMatchingCols, keyCols),
+			wantEvent: nil,
+		},
+		{
+			name: "snapshot insert matches",
+			event: newTestEvent("I", []wal.Column{
+				{Name: "id", Type: "int8", Value: int64(1)},
+				{Name: "tenant_id", Type: "numeric", Value: pgtype.Numeric{Int: big.NewInt(42), Valid: true}},
+				{Name: "status", Type: "text", Value: "published"},
+			}, nil),
+			wantEvent: newTestEvent("I", []wal.Column{
+				{Name: "id", Type: "int8", Value: int64(1)},
+				{Name: "tenant_id", Type: "numeric", Value: pgtype.Numeric{Int: big.NewInt(42), Valid: true}},
+				{Name: "status", Type: "text", Value: "published"},
+			}, nil),
+		},
+		{
+			name: "snapshot insert doesn't match",
+			event: newTestEvent("I", []wal.Column{
+				{Name: "id", Type: "int8", Value: int64(1)},
+				{Name: "tenant_id", Type: "int4", Value: int32(7)},
+				{Name: "status", Type: "text", Value: "published"},
+			}, nil),
+			wantEvent: nil,
+		},
+		{
+			name: "table without row filter",
+			event: &wal.Event{
+				Data: &wal.Data{Action: "I", Schema: testSchema, Table: "another_table", Columns: nonMatchingCols},
+			},
+			wantEvent: &wal.Event{
+				Data: &wal.Data{Action: "I", Schema: testSchema, Table: "another_table", Columns: nonMatchingCols},
+			},
+		},
+		{
+			name:      "error - evaluating expression",
+			event:     newTestEvent("I", []wal.Column{{Name: "tenant_id", Value: "42"}, {Name: "status", Value: float64(1)}}, nil),
+			wantEvent: nil,
+			wantErr:   errors.New("evaluating row filter expression"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotEvent *wal.Event
+			f, err := New(&mocks.Processor{
+				ProcessWALEventFn: func(ctx context.Context, walEvent *wal.Event) error {
+					gotEvent = walEvent
+					return nil
+				},
+			}, &Config{
+				RowFilters: map[string]string{
+					testSchema + "." + testTable: `tenant_id > 40 && status != "draft"`,
+				},
+			}, WithDefaultIncludeTables([]string{"pgstream.schema_log"}))
+			require.NoError(t, err)
+
+			err = f.ProcessWALEvent(context.Background(), tc.event)
+			if tc.wantErr != nil {
+				require.ErrorContains(t, err, tc.wantErr.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantEvent, gotEvent)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/vm"
+	"github.com/jackc/pgx/v5/pgtype"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// RowFilter evaluates the configured per table predicate expressions against
+// the column values of a row. Expressions use the expr language syntax
+// (https://expr-lang.org), and reference the row columns by name (i.e.
+// `tenant_id == 42 && status != "draft"`).
+type RowFilter struct {
+	predicates map[string]map[string]*rowPredicate
+}
+
+type rowPredicate struct {
+	expression string
+	program    *vm.Program
+	// columns referenced by the expression
+	columns []string
+}
+
+var errInvalidRowFilter = errors.New("invalid row filter expression")
+
+// NewRowFilter returns a row filter for the expressions on input, indexed by
+// schema qualified table name. If no schema is provided, the public schema
+// will be assumed.
+func NewRowFilter(rowFilters map[string]string) (*RowFilter, error) {
+	rf := &RowFilter{
+		predicates: make(map[string]map[string]*rowPredicate, len(rowFilters)),
+	}
+	for qualifiedTable, expression := range rowFilters {
+		schema, table, err := parseTableName(qualifiedTable)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, qualifiedTable)
+		}
+		predicate, err := newRowPredicate(expression)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", qualifiedTable, err)
+		}
+		if _, found := rf.predicates[schema]; !found {
+			rf.predicates[schema] = map[string]*rowPredicate{}
+		}
+		rf.predicates[schema][table] = predicate
+	}
+	return rf, nil
+}
+
+// Matches returns true if the row values on input match the predicate of the
+// table, or if the table has no predicate configured.
+func (rf *RowFilter) Matches(schema, table string, values map[string]any) (bool, error) {
+	predicate := rf.get(schema, table)
+	if predicate == nil {
+		return true, nil
+	}
+	return predicate.evaluate(values)
+}
+
+func (rf *RowFilter) get(schema, table string) *rowPredicate {
+	if rf == nil {
+		return nil
+	}
+	return rf.predicates[schema][table]
+}
+
+func newRowPredicate(expression string) (*rowPredicate, error) {
+	// undefined variables are allowed since the expression can only be
+	// validated against the table columns at evaluation time
+	program, err := expr.Compile(expression, expr.AllowUndefinedVariables(), expr.AsBool())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidRowFilter, err)
+	}
+
+	collector := &identifierCollector{}
+	node := program.Node()
+	ast.Walk(&node, collector)
+
+	return &rowPredicate{
+		expression: expression,
+		program:    program,
+		columns:    collector.identifiers,
+	}, nil
+}
+
+func (p *rowPredicate) evaluate(values map[string]any) (bool, error) {
+	result, err := expr.Run(p.program, values)
+	if err != nil {
+		return false, fmt.Errorf("evaluating row filter expression %q: %w", p.expression, err)
+	}
+	matches, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("evaluating row filter expression %q: unexpected result type %T", p.expression, result)
+	}
+	return matches, nil
+}
+
+// covers returns true if the values on input contain all the columns
+// referenced by the predicate expression.
+func (p *rowPredicate) covers(values map[string]any) bool {
+	for _, col := range p.columns {
+		if _, found := values[col]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+type identifierCollector struct {
+	identifiers []string
+}
+
+func (c *identifierCollector) Visit(node *ast.Node) {
+	if identifier, ok := (*node).(*ast.IdentifierNode); ok {
+		c.identifiers = append(c.identifiers, identifier.Value)
+	}
+}
+
+// typeMaps pools the type maps used to convert the column values, since they're
+// not safe for concurrent use.
+var typeMaps = sync.Pool{
+	New: func() any { return pgtype.NewMap() },
+}
+
+// columnValues returns the column values keyed by name. The values of the
+// snapshot events, decoded by pgx, are converted to the same representation as
+// the replicated events (wal2json), so that the expressions are evaluated
+// consistently for both.
+func columnValues(columns []wal.Column) map[string]any {
+	values := make(map[string]any, len(columns))
+	var typeMap *pgtype.Map
+	for _, col := range columns {
+		values[col.Name] = col.Value
+		if isWalValue(col.Value) {
+			continue
+		}
+		if typeMap == nil {
+			typeMap = typeMaps.Get().(*pgtype.Map)
+		}
+		if dataType, found := typeMap.TypeForName(col.Type); found {
+			values[col.Name] = pglib.ToWalValue(typeMap, dataType.OID, col.Value)
+		}
+	}
+	if typeMap != nil {
+		typeMaps.Put(typeMap)
+	}
+	return values
+}
+
+// isWalValue returns true if the value on input uses one of the types of the
+// wal2json column values.
+func isWalValue(value any) bool {
+	switch value.(type) {
+	case nil, string, float64, bool:
+		return true
+	default:
+		return false
+	}
+}
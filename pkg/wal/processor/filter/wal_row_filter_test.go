@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowFilter_Matches(t *testing.T) {
+	t.Parallel()
+
+	rowFilter, err := NewRowFilter(map[string]string{
+		"users":              `tenant_id == 42 && status != "draft"`,
+		"test_schema.orders": `total > 100 || "vip" in tags`,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		schema string
+		table  string
+		values map[string]any
+
+		wantMatch bool
+		wantErr   bool
+	}{
+		{
+			name:   "public schema table matches",
+			schema: "public",
+			table:  "users",
+			values: map[string]any{"tenant_id": float64(42), "status": "published"},
+
+			wantMatch: true,
+		},
+		{
+			name:   "public schema table doesn't match",
+			schema: "public",
+			table:  "users",
+			values: map[string]any{"tenant_id": int64(42), "status": "draft"},
+
+			wantMatch: false,
+		},
+		{
+			name:   "schema table matches",
+			schema: "test_schema",
+			table:  "orders",
+			values: map[string]any{"total": int32(10), "tags": []any{"vip"}},
+
+			wantMatch: true,
+		},
+		{
+			name:   "missing column",
+			schema: "public",
+			table:  "users",
+			values: map[string]any{"status": "published"},
+
+			wantMatch: false,
+		},
+		{
+			name:   "table without row filter",
+			schema: "public",
+			table:  "orders",
+			values: map[string]any{},
+
+			wantMatch: true,
+		},
+		{
+			name:   "error - invalid operation",
+			schema: "test_schema",
+			table:  "orders",
+			values: map[string]any{"total": "a", "tags": []any{}},
+
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			match, err := rowFilter.Matches(tc.schema, tc.table, tc.values)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantMatch, match)
+		})
+	}
+}
+
+func TestNewRowFilter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		rowFilters map[string]string
+
+		wantColumns []string
+		wantErr     error
+	}{
+		{
+			name:       "ok",
+			rowFilters: map[string]string{"public.users": `tenant_id == 42 && status != "draft"`},
+
+			wantColumns: []string{"tenant_id", "status"},
+		},
+		{
+			name:       "error - invalid table name",
+			rowFilters: map[string]string{"a.b.c": `tenant_id == 42`},
+
+			wantErr: errInvalidTableName,
+		},
+		{
+			name:       "error - invalid expression",
+			rowFilters: map[string]string{"users": `tenant_id ==`},
+
+			wantErr: errInvalidRowFilter,
+		},
+		{
+			name:       "error - non boolean expression",
+			rowFilters: map[string]string{"users": `"a"`},
+
+			wantErr: errInvalidRowFilter,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rowFilter, err := NewRowFilter(tc.rowFilters)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantErr != nil {
+				return
+			}
+			require.ElementsMatch(t, tc.wantColumns, rowFilter.get("public", "users").columns)
+		})
+	}
+}
@@ -104,7 +104,9 @@ func WithInstrumentation(instr *otel.Instrumentation) Option {
 // ProcessWALEvent populates the metadata of the wal event on input, before
 // passing it over to the configured wal processor.
 func (in *Injector) ProcessWALEvent(ctx context.Context, event *wal.Event) error {
-	if event.Data == nil {
+	// transaction boundaries and logical messages don't relate to any schema,
+	// there's no metadata to inject
+	if event.Data == nil || event.Data.IsTransactionBoundary() || event.Data.IsLogicalMessage() {
 		return in.processor.ProcessWALEvent(ctx, event)
 	}
 
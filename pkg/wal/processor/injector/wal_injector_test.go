@@ -4,6 +4,7 @@ package injector
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -100,6 +101,40 @@ func TestInjector_ProcessWALEvent(t *testing.T) {
 
 			wantErr: nil,
 		},
+		{
+			name:  "ok - transaction boundary event",
+			event: &wal.Event{Data: &wal.Data{Action: "B", XID: 1}},
+			store: &schemalogmocks.Store{
+				FetchLastFn: func(ctx context.Context, schemaName string, ackedOnly bool) (*schemalog.LogEntry, error) {
+					return nil, errors.New("FetchLastFn: should not be called")
+				},
+			},
+			processor: &mocks.Processor{
+				ProcessWALEventFn: func(ctx context.Context, walEvent *wal.Event) error {
+					require.Equal(t, &wal.Event{Data: &wal.Data{Action: "B", XID: 1}}, walEvent)
+					return nil
+				},
+			},
+
+			wantErr: nil,
+		},
+		{
+			name:  "ok - logical message event",
+			event: &wal.Event{Data: &wal.Data{Action: "M", Prefix: "test", Content: "content"}},
+			store: &schemalogmocks.Store{
+				FetchLastFn: func(ctx context.Context, schemaName string, ackedOnly bool) (*schemalog.LogEntry, error) {
+					return nil, errors.New("FetchLastFn: should not be called")
+				},
+			},
+			processor: &mocks.Processor{
+				ProcessWALEventFn: func(ctx context.Context, walEvent *wal.Event) error {
+					require.Equal(t, &wal.Event{Data: &wal.Data{Action: "M", Prefix: "test", Content: "content"}}, walEvent)
+					return nil
+				},
+			},
+
+			wantErr: nil,
+		},
 		{
 			name:  "ok - fail to inject data event",
 			event: newTestDataEvent("I"),
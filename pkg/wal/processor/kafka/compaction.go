@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xataio/pgstream/internal/json"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// CompactionKey is the message key of the row events written to compacted
+// topics. It identifies the row by its table and primary key, so that kafka
+// only retains the latest message of each row, and deletes can be recovered
+// from their tombstones.
+type CompactionKey struct {
+	Schema  string             `json:"schema"`
+	Table   string             `json:"table"`
+	Columns []CompactionColumn `json:"columns"`
+}
+
+type CompactionColumn struct {
+	ID    string `json:"id,omitempty"`
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+}
+
+var errInvalidCompactionKey = errors.New("invalid compaction key")
+
+// DecodeCompactionKey returns the compaction key encoded in the message key on
+// input. It returns an error if the key is not a compaction key.
+func DecodeCompactionKey(key []byte) (*CompactionKey, error) {
+	if len(key) == 0 {
+		return nil, errInvalidCompactionKey
+	}
+	compactionKey := &CompactionKey{}
+	if err := json.Unmarshal(key, compactionKey); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidCompactionKey, err)
+	}
+	if compactionKey.Schema == "" || compactionKey.Table == "" || len(compactionKey.Columns) == 0 {
+		return nil, errInvalidCompactionKey
+	}
+	return compactionKey, nil
+}
+
+// ToDeleteData returns the delete event of the row identified by the
+// compaction key.
+func (k *CompactionKey) ToDeleteData() *wal.Data {
+	data := &wal.Data{
+		Action:   "D",
+		Schema:   k.Schema,
+		Table:    k.Table,
+		Identity: make([]wal.Column, 0, len(k.Columns)),
+	}
+	for _, col := range k.Columns {
+		data.Identity = append(data.Identity, wal.Column{
+			ID:    col.ID,
+			Name:  col.Name,
+			Value: col.Value,
+		})
+		if col.ID != "" {
+			data.Metadata.InternalColIDs = append(data.Metadata.InternalColIDs, col.ID)
+		}
+	}
+	return data
+}
+
+// compactionKeys returns the compaction key of the row of the event on input,
+// and the key it had before the event, if different (i.e. updates of the
+// primary key). The primary key is identified by the pgstream metadata, and
+// the key is nil if it can't be found in the event columns.
+func compactionKeys(walData *wal.Data) (key, previousKey []byte) {
+	switch walData.Action {
+	case "D":
+		// deletes only have the identity columns
+		return encodeCompactionKey(walData, walData.Identity, walData.Columns), nil
+	case "U":
+		key = encodeCompactionKey(walData, walData.Columns)
+		// the identity is only set for updates when the primary key changes
+		// or the table has a full replica identity
+		previousKey = encodeCompactionKey(walData, walData.Identity)
+		if previousKey == nil || string(previousKey) == string(key) {
+			return key, nil
+		}
+		return key, previousKey
+	default:
+		return encodeCompactionKey(walData, walData.Columns), nil
+	}
+}
+
+// encodeCompactionKey returns the compaction key of the event on input, using
+// the primary key values of the first list of columns containing them all.
+func encodeCompactionKey(walData *wal.Data, columnLists ...[]wal.Column) []byte {
+	if len(walData.Metadata.InternalColIDs) == 0 {
+		return nil
+	}
+
+	for _, columns := range columnLists {
+		key := CompactionKey{
+			Schema:  walData.Schema,
+			Table:   walData.Table,
+			Columns: make([]CompactionColumn, 0, len(walData.Metadata.InternalColIDs)),
+		}
+		for _, id := range walData.Metadata.InternalColIDs {
+			for _, col := range columns {
+				if col.ID == id {
+					key.Columns = append(key.Columns, CompactionColumn{
+						ID:    col.ID,
+						Name:  col.Name,
+						Value: col.Value,
+					})
+					break
+				}
+			}
+		}
+		if len(key.Columns) != len(walData.Metadata.InternalColIDs) {
+			continue
+		}
+
+		keyBytes, err := json.Marshal(key)
+		if err != nil {
+			return nil
+		}
+		return keyBytes
+	}
+	return nil
+}
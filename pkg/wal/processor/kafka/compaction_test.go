@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func TestDecodeCompactionKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		key  []byte
+
+		wantData *wal.Data
+		wantErr  error
+	}{
+		{
+			name: "ok",
+			key:  []byte(`{"schema":"public","table":"users","columns":[{"id":"c1","name":"id","value":1},{"id":"c2","name":"tenant","value":"a"}]}`),
+
+			wantData: &wal.Data{
+				Action: "D",
+				Schema: "public",
+				Table:  "users",
+				Identity: []wal.Column{
+					{ID: "c1", Name: "id", Value: float64(1)},
+					{ID: "c2", Name: "tenant", Value: "a"},
+				},
+				Metadata: wal.Metadata{InternalColIDs: []string{"c1", "c2"}},
+			},
+		},
+		{
+			name: "error - empty key",
+			key:  nil,
+
+			wantErr: errInvalidCompactionKey,
+		},
+		{
+			name: "error - not json",
+			key:  []byte("public"),
+
+			wantErr: errInvalidCompactionKey,
+		},
+		{
+			name: "error - not a compaction key",
+			key:  []byte(`{"id":1}`),
+
+			wantErr: errInvalidCompactionKey,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			key, err := DecodeCompactionKey(tc.key)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantErr != nil {
+				return
+			}
+			require.Equal(t, tc.wantData, key.ToDeleteData())
+		})
+	}
+}
@@ -3,11 +3,215 @@
 package kafka
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/xataio/pgstream/pkg/blobstore"
 	"github.com/xataio/pgstream/pkg/kafka"
+	"github.com/xataio/pgstream/pkg/schemaregistry"
 	"github.com/xataio/pgstream/pkg/wal/processor/batch"
 )
 
 type Config struct {
 	Kafka kafka.ConnConfig
 	Batch batch.Config
+	// Format is the serialisation format of the kafka message values. One of
+	// json, avro or debezium. Defaults to json.
+	Format string
+	// SchemaRegistry is the schema registry the avro schemas are registered
+	// against. Required for the avro format.
+	SchemaRegistry *schemaregistry.Config
+	// TopicRouting configures the topics the table events are written to. If
+	// not set, all the events are written to the configured kafka topic.
+	TopicRouting *TopicRoutingConfig
+	// KeyStrategy defines the message key of the table events, which
+	// determines their partition, and therefore their ordering. One of schema,
+	// table, primary_key or columns. Defaults to schema.
+	KeyStrategy string
+	// KeyColumns is the list of columns used as message key by the columns
+	// key strategy. Events missing any of the columns fall back to the primary
+	// key.
+	KeyColumns []string
+	// Headers are the user defined headers added to every message, on top of
+	// the pgstream metadata headers.
+	Headers []HeaderConfig
+	// Compaction enables writing the row events to log compacted topics. The
+	// row events are keyed by their primary key, and deletes are written as
+	// tombstones. The auto created topics use the compact cleanup policy.
+	// Requires the primary key strategy, which is the default when enabled.
+	Compaction bool
+	// LargeMessages configures how the events too large to be written in a
+	// single message are handled. If not set, they are skipped.
+	LargeMessages *LargeMessagesConfig
+}
+
+type LargeMessagesConfig struct {
+	// Strategy for the events too large to be written in a single message.
+	// One of claim_check, where the value is written to the blob store and
+	// the message carries its key, or chunking, where the value is split
+	// across multiple messages.
+	Strategy string
+	// BlobStore where the values are written. Required by the claim_check
+	// strategy.
+	BlobStore *blobstore.Config
+}
+
+type HeaderConfig struct {
+	Key string
+	// Value is the header value template, supporting the {schema}, {table},
+	// {action} and {lsn} placeholders.
+	Value string
+}
+
+type TopicRoutingConfig struct {
+	// Template is the topic name template for the tables not matching any
+	// rule. The {schema} and {table} placeholders are replaced with the source
+	// schema and table names (i.e. "cdc.{schema}.{table}"). If not set, the
+	// tables not matching any rule are written to the configured kafka topic.
+	Template string
+	// Rules contains the ordered list of topic routing rules. The first rule
+	// matching a table is applied.
+	Rules []TopicRuleConfig
+}
+
+type TopicRuleConfig struct {
+	// Source is the schema qualified table pattern the rule applies to. Both
+	// the schema and the table support glob patterns (i.e. "public.*",
+	// "tenant_*.users"). If no schema is provided, the public schema will be
+	// assumed.
+	Source string
+	// Topic is the topic name template, supporting the {schema} and {table}
+	// placeholders.
+	Topic string
+	// NumPartitions and ReplicationFactor are used when the topic is auto
+	// created. They default to the configured kafka topic settings.
+	NumPartitions     int
+	ReplicationFactor int
+}
+
+const (
+	FormatJSON     = "json"
+	FormatAvro     = "avro"
+	FormatDebezium = "debezium"
+
+	KeyStrategySchema     = "schema"
+	KeyStrategyTable      = "table"
+	KeyStrategyPrimaryKey = "primary_key"
+	KeyStrategyColumns    = "columns"
+
+	LargeMessagesClaimCheck = "claim_check"
+	LargeMessagesChunking   = "chunking"
+)
+
+var (
+	errUnsupportedFormat      = errors.New("unsupported kafka message format")
+	errMissingSchemaRegistry  = errors.New("avro format requires a schema registry")
+	errUnsupportedKeyStrategy = errors.New("unsupported kafka key strategy")
+	errMissingKeyColumns      = errors.New("columns key strategy requires key columns")
+	errCompactionKeyStrategy  = errors.New("compaction requires the primary key strategy")
+	errCompactionFormat       = errors.New("compaction is not supported with the debezium format")
+	errUnsupportedLargeMsgs   = errors.New("unsupported kafka large messages strategy")
+	errMissingBlobStore       = errors.New("claim check strategy requires a blob store")
+)
+
+func (c *Config) GetFormat() string {
+	if c.Format != "" {
+		return c.Format
+	}
+	return FormatJSON
+}
+
+func (c *Config) GetKeyStrategy() string {
+	if c.KeyStrategy != "" {
+		return c.KeyStrategy
+	}
+	if c.Compaction {
+		return KeyStrategyPrimaryKey
+	}
+	return KeyStrategySchema
+}
+
+func (c *Config) IsValid() error {
+	switch c.GetKeyStrategy() {
+	case KeyStrategySchema, KeyStrategyTable, KeyStrategyPrimaryKey:
+	case KeyStrategyColumns:
+		if len(c.KeyColumns) == 0 {
+			return errMissingKeyColumns
+		}
+	default:
+		return fmt.Errorf("%w: %s", errUnsupportedKeyStrategy, c.KeyStrategy)
+	}
+
+	if c.Compaction {
+		if c.GetKeyStrategy() != KeyStrategyPrimaryKey {
+			return errCompactionKeyStrategy
+		}
+		// debezium events are already keyed by primary key, with a tombstone
+		// following every delete
+		if c.GetFormat() == FormatDebezium {
+			return errCompactionFormat
+		}
+	}
+
+	if c.TopicRouting != nil {
+		if _, err := newTopicRouter(c.TopicRouting); err != nil {
+			return err
+		}
+	}
+
+	if c.LargeMessages != nil {
+		if err := c.LargeMessages.IsValid(); err != nil {
+			return err
+		}
+	}
+
+	for _, header := range c.Headers {
+		if err := header.IsValid(); err != nil {
+			return err
+		}
+	}
+
+	switch c.GetFormat() {
+	case FormatJSON, FormatDebezium:
+		return nil
+	case FormatAvro:
+		if c.SchemaRegistry == nil {
+			return errMissingSchemaRegistry
+		}
+		return c.SchemaRegistry.IsValid()
+	default:
+		return fmt.Errorf("%w: %s", errUnsupportedFormat, c.Format)
+	}
+}
+
+func (c *HeaderConfig) IsValid() error {
+	if c.Key == "" {
+		return fmt.Errorf("%w: empty key", errInvalidHeader)
+	}
+	if slices.Contains(reservedHeaders, c.Key) {
+		return fmt.Errorf("%w: %s", errReservedHeader, c.Key)
+	}
+	for _, placeholder := range placeholderRegex.FindAllString(c.Value, -1) {
+		switch placeholder {
+		case schemaPlaceholder, tablePlaceholder, actionPlaceholder, lsnPlaceholder:
+		default:
+			return fmt.Errorf("%w %s: unsupported placeholder %s", errInvalidHeader, c.Key, placeholder)
+		}
+	}
+	return nil
+}
+
+func (c *LargeMessagesConfig) IsValid() error {
+	switch c.Strategy {
+	case LargeMessagesClaimCheck:
+		if c.BlobStore == nil {
+			return errMissingBlobStore
+		}
+		return c.BlobStore.IsValid()
+	case LargeMessagesChunking:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", errUnsupportedLargeMsgs, c.Strategy)
+	}
 }
@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/rs/xid"
+	"github.com/xataio/pgstream/pkg/kafka"
+)
+
+// handleLargeMessages applies the large messages strategy to the messages on
+// input whose value exceeds the max message size. With the claim check
+// strategy, their value is written to the blob store and replaced with the
+// blob key, and with the chunking strategy, they're split into as many
+// messages as needed.
+func (w *BatchWriter) handleLargeMessages(ctx context.Context, msgs []kafka.Message) ([]kafka.Message, error) {
+	maxMessageBytes := w.maxMessageBytes()
+	result := make([]kafka.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		if len(msg.Value) <= maxMessageBytes {
+			result = append(result, msg)
+			continue
+		}
+
+		switch {
+		case w.blobStore != nil:
+			blobKey := xid.New().String()
+			if err := w.blobStore.Put(ctx, blobKey, msg.Value); err != nil {
+				return nil, fmt.Errorf("writing message value to blob store: %w", err)
+			}
+			msg.Value = []byte(blobKey)
+			msg.SetHeader(kafka.HeaderClaimCheck, []byte(blobKey))
+			result = append(result, msg)
+		case w.chunking:
+			result = append(result, chunkMessage(msg, maxMessageBytes)...)
+		default:
+			// should not happen, since large events are skipped when no
+			// strategy is configured
+			return nil, errRecordTooLarge
+		}
+	}
+	return result, nil
+}
+
+// maxMessageBytes returns the max size of the message values, 95% of the
+// Kafka accepted max message size to allow for some buffer for the rest of the
+// message.
+func (w *BatchWriter) maxMessageBytes() int {
+	return int(0.95 * float64(w.maxBatchBytes))
+}
+
+// chunkMessage splits the message value into chunks of the given size, each
+// written in its own message, with the same topic, key and headers as the
+// original message, so that they're written to the same partition in order.
+func chunkMessage(msg kafka.Message, chunkSize int) []kafka.Message {
+	chunkID := []byte(xid.New().String())
+	count := (len(msg.Value) + chunkSize - 1) / chunkSize
+	chunks := make([]kafka.Message, 0, count)
+	for i := range count {
+		chunk := msg
+		chunk.Value = msg.Value[i*chunkSize : min((i+1)*chunkSize, len(msg.Value))]
+		chunk.Headers = slices.Clone(msg.Headers)
+		chunk.SetHeader(kafka.HeaderChunkID, chunkID)
+		chunk.SetHeader(kafka.HeaderChunkIndex, []byte(strconv.Itoa(i)))
+		chunk.SetHeader(kafka.HeaderChunkCount, []byte(strconv.Itoa(count)))
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	blobstoremocks "github.com/xataio/pgstream/pkg/blobstore/mocks"
+	"github.com/xataio/pgstream/pkg/kafka"
+)
+
+func TestBatchKafkaWriter_handleLargeMessages(t *testing.T) {
+	t.Parallel()
+
+	largeValue := bytes.Repeat([]byte("a"), 250)
+	smallMsg := kafka.Message{Key: []byte("key"), Value: []byte("small")}
+	largeMsg := kafka.Message{
+		Topic:   "topic",
+		Key:     []byte("key"),
+		Value:   largeValue,
+		Headers: []kafka.Header{{Key: kafka.HeaderTable, Value: []byte("users")}},
+	}
+	errTest := errors.New("oh noes")
+
+	t.Run("ok - claim check", func(t *testing.T) {
+		t.Parallel()
+
+		store := &blobstoremocks.Store{}
+		w := &BatchWriter{maxBatchBytes: 100, blobStore: store}
+
+		msgs, err := w.handleLargeMessages(context.Background(), []kafka.Message{smallMsg, largeMsg})
+		require.NoError(t, err)
+		require.Len(t, msgs, 2)
+		require.Equal(t, smallMsg, msgs[0])
+
+		blobKey, found := msgs[1].Header(kafka.HeaderClaimCheck)
+		require.True(t, found)
+		require.Equal(t, blobKey, msgs[1].Value)
+		require.Equal(t, largeMsg.Key, msgs[1].Key)
+		require.Equal(t, largeMsg.Topic, msgs[1].Topic)
+		require.Equal(t, map[string][]byte{string(blobKey): largeValue}, store.GetBlobs())
+	})
+
+	t.Run("ok - chunking", func(t *testing.T) {
+		t.Parallel()
+
+		w := &BatchWriter{maxBatchBytes: 100, chunking: true}
+
+		msgs, err := w.handleLargeMessages(context.Background(), []kafka.Message{largeMsg, smallMsg})
+		require.NoError(t, err)
+		// 250 bytes in chunks of 95
+		require.Len(t, msgs, 4)
+		require.Equal(t, smallMsg, msgs[3])
+
+		chunkID, _ := msgs[0].Header(kafka.HeaderChunkID)
+		value := []byte{}
+		for i, msg := range msgs[:3] {
+			require.Equal(t, largeMsg.Key, msg.Key)
+			require.Equal(t, largeMsg.Topic, msg.Topic)
+			table, _ := msg.Header(kafka.HeaderTable)
+			require.Equal(t, []byte("users"), table)
+			id, _ := msg.Header(kafka.HeaderChunkID)
+			require.Equal(t, chunkID, id)
+			index, _ := msg.Header(kafka.HeaderChunkIndex)
+			require.Equal(t, []byte{byte('0' + i)}, index)
+			count, _ := msg.Header(kafka.HeaderChunkCount)
+			require.Equal(t, []byte("3"), count)
+			value = append(value, msg.Value...)
+		}
+		require.Equal(t, largeValue, value)
+		// the original message headers are not modified
+		require.Len(t, largeMsg.Headers, 1)
+	})
+
+	t.Run("error - writing to blob store", func(t *testing.T) {
+		t.Parallel()
+
+		w := &BatchWriter{
+			maxBatchBytes: 100,
+			blobStore: &blobstoremocks.Store{
+				PutFn: func(ctx context.Context, key string, data []byte) error { return errTest },
+			},
+		}
+
+		_, err := w.handleLargeMessages(context.Background(), []kafka.Message{largeMsg})
+		require.ErrorIs(t, err, errTest)
+	})
+}
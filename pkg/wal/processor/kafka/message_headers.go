@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/xataio/pgstream/pkg/kafka"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+)
+
+// messageHeaders builds the headers of the kafka messages, made of the CDC
+// metadata of the event and the user defined headers.
+type messageHeaders struct {
+	// contentType of the message values. Not set for the events with a
+	// routing payload, since their content is unknown.
+	contentType string
+	custom      []headerTemplate
+
+	// schemaVersions keeps track of the latest schema log entry seen per
+	// schema, so that the table events stamped with it can be tagged with its
+	// version.
+	versionsMutex  sync.RWMutex
+	schemaVersions map[string]schemaVersion
+}
+
+type headerTemplate struct {
+	key   string
+	value string
+}
+
+type schemaVersion struct {
+	id      string
+	version string
+}
+
+const (
+	actionPlaceholder = "{action}"
+	lsnPlaceholder    = "{lsn}"
+)
+
+var (
+	errInvalidHeader  = errors.New("invalid kafka header")
+	errReservedHeader = errors.New("kafka header is reserved for the pgstream metadata")
+)
+
+var reservedHeaders = []string{
+	kafka.HeaderSchema,
+	kafka.HeaderTable,
+	kafka.HeaderAction,
+	kafka.HeaderLSN,
+	kafka.HeaderCommitTimestamp,
+	kafka.HeaderSchemaVersion,
+	kafka.HeaderTableID,
+	kafka.HeaderContentType,
+	kafka.HeaderClaimCheck,
+	kafka.HeaderChunkID,
+	kafka.HeaderChunkIndex,
+	kafka.HeaderChunkCount,
+	kafka.HeaderError,
+	kafka.HeaderErrorAttempts,
+	kafka.HeaderSourceTopic,
+	kafka.HeaderSourcePartition,
+	kafka.HeaderSourceOffset,
+}
+
+func newMessageHeaders(format string, headers []HeaderConfig) (*messageHeaders, error) {
+	h := &messageHeaders{
+		contentType:    contentType(format),
+		custom:         make([]headerTemplate, 0, len(headers)),
+		schemaVersions: map[string]schemaVersion{},
+	}
+	for _, header := range headers {
+		if err := header.IsValid(); err != nil {
+			return nil, err
+		}
+		h.custom = append(h.custom, headerTemplate{key: header.Key, value: header.Value})
+	}
+	return h, nil
+}
+
+// build returns the headers for the kafka message of the wal event on input.
+// The user defined headers that render to an empty value are skipped.
+func (h *messageHeaders) build(walEvent *wal.Event) []kafka.Header {
+	data := walEvent.Data
+	headers := make([]kafka.Header, 0, len(reservedHeaders)+len(h.custom))
+	add := func(key, value string) {
+		if value != "" {
+			headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+		}
+	}
+
+	add(kafka.HeaderSchema, data.Schema)
+	add(kafka.HeaderTable, data.Table)
+	add(kafka.HeaderAction, data.Action)
+	add(kafka.HeaderLSN, data.LSN)
+	add(kafka.HeaderCommitTimestamp, data.Timestamp)
+	add(kafka.HeaderSchemaVersion, h.schemaVersion(data))
+	add(kafka.HeaderTableID, data.Metadata.TablePgstreamID)
+	if walEvent.Route == nil || walEvent.Route.Payload == nil {
+		add(kafka.HeaderContentType, h.contentType)
+	}
+
+	for _, header := range h.custom {
+		add(header.key, renderHeader(header.value, data))
+	}
+	return headers
+}
+
+// schemaVersion returns the schema log version of the event on input. For
+// schema log events it's their own version, and for table events, the version
+// of the schema log entry they were stamped with, if it has been seen.
+func (h *messageHeaders) schemaVersion(data *wal.Data) string {
+	if processor.IsSchemaLogEvent(data) {
+		var schemaName string
+		var version schemaVersion
+		for _, col := range data.Columns {
+			switch col.Name {
+			case "id":
+				version.id = fmt.Sprint(col.Value)
+			case "version":
+				version.version = fmt.Sprint(col.Value)
+			case "schema_name":
+				schemaName = fmt.Sprint(col.Value)
+			}
+		}
+		if schemaName != "" && version.id != "" {
+			h.versionsMutex.Lock()
+			h.schemaVersions[schemaName] = version
+			h.versionsMutex.Unlock()
+		}
+		return version.version
+	}
+
+	if data.Metadata.SchemaID.IsNil() {
+		return ""
+	}
+	h.versionsMutex.RLock()
+	defer h.versionsMutex.RUnlock()
+	version, found := h.schemaVersions[data.Schema]
+	if !found || version.id != data.Metadata.SchemaID.String() {
+		return ""
+	}
+	return version.version
+}
+
+func renderHeader(template string, data *wal.Data) string {
+	return strings.NewReplacer(
+		schemaPlaceholder, data.Schema,
+		tablePlaceholder, data.Table,
+		actionPlaceholder, data.Action,
+		lsnPlaceholder, data.LSN,
+	).Replace(template)
+}
+
+func contentType(format string) string {
+	switch format {
+	case FormatAvro:
+		return "application/avro"
+	default:
+		return "application/json"
+	}
+}
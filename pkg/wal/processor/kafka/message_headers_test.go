@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/kafka"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func TestMessageHeaders_build(t *testing.T) {
+	t.Parallel()
+
+	schemaID := xid.New()
+	schemaLogEvent := &wal.Event{
+		Data: &wal.Data{
+			Action: "I",
+			Schema: schemalog.SchemaName,
+			Table:  schemalog.TableName,
+			Columns: []wal.Column{
+				{Name: "id", Value: schemaID.String()},
+				{Name: "version", Value: float64(3)},
+				{Name: "schema_name", Value: "public"},
+			},
+		},
+	}
+
+	tableEvent := func(schemaID xid.ID) *wal.Event {
+		return &wal.Event{
+			Data: &wal.Data{
+				Action:   "U",
+				Schema:   "public",
+				Table:    "users",
+				Metadata: wal.Metadata{SchemaID: schemaID},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		events []*wal.Event
+
+		wantHeaders []kafka.Header
+	}{
+		{
+			name:   "ok - schema log event",
+			format: FormatAvro,
+			events: []*wal.Event{schemaLogEvent},
+
+			wantHeaders: []kafka.Header{
+				{Key: kafka.HeaderSchema, Value: []byte(schemalog.SchemaName)},
+				{Key: kafka.HeaderTable, Value: []byte(schemalog.TableName)},
+				{Key: kafka.HeaderAction, Value: []byte("I")},
+				{Key: kafka.HeaderSchemaVersion, Value: []byte("3")},
+				{Key: kafka.HeaderContentType, Value: []byte("application/avro")},
+			},
+		},
+		{
+			name:   "ok - table event stamped with a known schema log entry",
+			format: FormatJSON,
+			events: []*wal.Event{schemaLogEvent, tableEvent(schemaID)},
+
+			wantHeaders: []kafka.Header{
+				{Key: kafka.HeaderSchema, Value: []byte("public")},
+				{Key: kafka.HeaderTable, Value: []byte("users")},
+				{Key: kafka.HeaderAction, Value: []byte("U")},
+				{Key: kafka.HeaderSchemaVersion, Value: []byte("3")},
+				{Key: kafka.HeaderContentType, Value: []byte("application/json")},
+			},
+		},
+		{
+			name:   "ok - table event stamped with an unknown schema log entry",
+			format: FormatJSON,
+			events: []*wal.Event{schemaLogEvent, tableEvent(xid.New())},
+
+			wantHeaders: []kafka.Header{
+				{Key: kafka.HeaderSchema, Value: []byte("public")},
+				{Key: kafka.HeaderTable, Value: []byte("users")},
+				{Key: kafka.HeaderAction, Value: []byte("U")},
+				{Key: kafka.HeaderContentType, Value: []byte("application/json")},
+			},
+		},
+		{
+			name:   "ok - routing payload",
+			format: FormatJSON,
+			events: []*wal.Event{
+				{
+					Data:  &wal.Data{Action: "I", Schema: "public", Table: "outbox"},
+					Route: &wal.Route{Payload: []byte("payload")},
+				},
+			},
+
+			wantHeaders: []kafka.Header{
+				{Key: kafka.HeaderSchema, Value: []byte("public")},
+				{Key: kafka.HeaderTable, Value: []byte("outbox")},
+				{Key: kafka.HeaderAction, Value: []byte("I")},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			h, err := newMessageHeaders(tc.format, nil)
+			require.NoError(t, err)
+
+			var headers []kafka.Header
+			for _, event := range tc.events {
+				headers = h.build(event)
+			}
+			require.Equal(t, tc.wantHeaders, headers)
+		})
+	}
+}
+
+func TestHeaderConfig_IsValid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		header HeaderConfig
+
+		wantErr error
+	}{
+		{
+			name:   "ok - static",
+			header: HeaderConfig{Key: "source", Value: "pgstream"},
+		},
+		{
+			name:   "ok - template",
+			header: HeaderConfig{Key: "event", Value: "{schema}.{table}.{action}@{lsn}"},
+		},
+		{
+			name:   "error - empty key",
+			header: HeaderConfig{Value: "pgstream"},
+
+			wantErr: errInvalidHeader,
+		},
+		{
+			name:   "error - reserved key",
+			header: HeaderConfig{Key: kafka.HeaderTable, Value: "users"},
+
+			wantErr: errReservedHeader,
+		},
+		{
+			name:   "error - unsupported placeholder",
+			header: HeaderConfig{Key: "event", Value: "{column}"},
+
+			wantErr: errInvalidHeader,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.header.IsValid()
+			require.True(t, errors.Is(err, tc.wantErr), err)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/xataio/pgstream/pkg/kafka"
+)
+
+// topicRouter maps source schema and table names to the kafka topic their
+// events are written to, as defined by the topic routing configuration.
+type topicRouter struct {
+	rules []*topicRule
+	// defaultRule is applied to the tables not matching any rule. Optional.
+	defaultRule *topicRule
+}
+
+type topicRule struct {
+	// glob patterns
+	sourceSchema string
+	sourceTable  string
+	// template
+	topic             string
+	numPartitions     int
+	replicationFactor int
+}
+
+var (
+	errInvalidTableName     = errors.New("invalid table name format")
+	errInvalidTablePattern  = errors.New("invalid topic routing source pattern")
+	errInvalidTopicTemplate = errors.New("invalid topic routing template")
+)
+
+const (
+	publicSchema = "public"
+	wildcard     = "*"
+
+	schemaPlaceholder = "{schema}"
+	tablePlaceholder  = "{table}"
+
+	// maxTopicNameLength is the maximum length of a kafka topic name
+	maxTopicNameLength = 249
+)
+
+var (
+	placeholderRegex = regexp.MustCompile(`\{[^{}]*\}`)
+	// invalidTopicCharsRegex matches the characters not allowed in kafka topic
+	// names
+	invalidTopicCharsRegex = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+)
+
+func newTopicRouter(cfg *TopicRoutingConfig) (*topicRouter, error) {
+	r := &topicRouter{
+		rules: make([]*topicRule, 0, len(cfg.Rules)),
+	}
+	for _, ruleCfg := range cfg.Rules {
+		rule, err := newTopicRule(ruleCfg)
+		if err != nil {
+			return nil, fmt.Errorf("topic routing rule %s -> %s: %w", ruleCfg.Source, ruleCfg.Topic, err)
+		}
+		r.rules = append(r.rules, rule)
+	}
+
+	if cfg.Template != "" {
+		var err error
+		r.defaultRule, err = newTopicRule(TopicRuleConfig{
+			Source: wildcard + "." + wildcard,
+			Topic:  cfg.Template,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("topic routing template %s: %w", cfg.Template, err)
+		}
+	}
+
+	return r, nil
+}
+
+// route returns the topic configuration for the source table on input. The
+// topic name is empty for tables not matching any rule, meaning the events are
+// written to the default topic. The topic partitions and replication factor
+// are only set when configured for the matching rule.
+func (r *topicRouter) route(schema, table string) kafka.TopicConfig {
+	for _, rule := range r.rules {
+		if rule.matches(schema, table) {
+			return rule.render(schema, table)
+		}
+	}
+	if r.defaultRule != nil {
+		return r.defaultRule.render(schema, table)
+	}
+	return kafka.TopicConfig{}
+}
+
+func newTopicRule(cfg TopicRuleConfig) (*topicRule, error) {
+	sourceSchema, sourceTable, err := parseTableName(cfg.Source)
+	if err != nil {
+		return nil, err
+	}
+	for _, pattern := range []string{sourceSchema, sourceTable} {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return nil, fmt.Errorf("%w: %q", errInvalidTablePattern, pattern)
+		}
+	}
+
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("%w: empty topic", errInvalidTopicTemplate)
+	}
+	for _, placeholder := range placeholderRegex.FindAllString(cfg.Topic, -1) {
+		if placeholder != schemaPlaceholder && placeholder != tablePlaceholder {
+			return nil, fmt.Errorf("%w: unsupported placeholder %s", errInvalidTopicTemplate, placeholder)
+		}
+	}
+
+	return &topicRule{
+		sourceSchema:      sourceSchema,
+		sourceTable:       sourceTable,
+		topic:             cfg.Topic,
+		numPartitions:     cfg.NumPartitions,
+		replicationFactor: cfg.ReplicationFactor,
+	}, nil
+}
+
+func (r *topicRule) matches(schema, table string) bool {
+	return matchPattern(r.sourceSchema, schema) && matchPattern(r.sourceTable, table)
+}
+
+// render returns the topic configuration for the source table on input. The
+// characters not supported by kafka in the topic names are replaced with
+// underscores.
+func (r *topicRule) render(schema, table string) kafka.TopicConfig {
+	topic := strings.NewReplacer(schemaPlaceholder, schema, tablePlaceholder, table).Replace(r.topic)
+	topic = invalidTopicCharsRegex.ReplaceAllString(topic, "_")
+	if len(topic) > maxTopicNameLength {
+		topic = topic[:maxTopicNameLength]
+	}
+	return kafka.TopicConfig{
+		Name:              topic,
+		NumPartitions:     r.numPartitions,
+		ReplicationFactor: r.replicationFactor,
+	}
+}
+
+func matchPattern(pattern, name string) bool {
+	// patterns are validated on creation
+	matched, _ := path.Match(pattern, name)
+	return matched
+}
+
+func parseTableName(qualifiedTableName string) (string, string, error) {
+	parts := strings.Split(qualifiedTableName, ".")
+	switch len(parts) {
+	case 1:
+		return publicSchema, parts[0], nil
+	case 2:
+		return parts[0], parts[1], nil
+	default:
+		return "", "", errInvalidTableName
+	}
+}
@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/kafka"
+)
+
+func TestTopicRouter_route(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config *TopicRoutingConfig
+		schema string
+		table  string
+
+		wantTopic kafka.TopicConfig
+	}{
+		{
+			name:   "template",
+			config: &TopicRoutingConfig{Template: "cdc.{schema}.{table}"},
+			schema: "public",
+			table:  "users",
+
+			wantTopic: kafka.TopicConfig{Name: "cdc.public.users"},
+		},
+		{
+			name: "first matching rule",
+			config: &TopicRoutingConfig{
+				Template: "cdc.{schema}.{table}",
+				Rules: []TopicRuleConfig{
+					{Source: "users", Topic: "users", NumPartitions: 6, ReplicationFactor: 3},
+					{Source: "public.*", Topic: "public"},
+				},
+			},
+			schema: "public",
+			table:  "users",
+
+			wantTopic: kafka.TopicConfig{Name: "users", NumPartitions: 6, ReplicationFactor: 3},
+		},
+		{
+			name: "glob rule",
+			config: &TopicRoutingConfig{
+				Rules: []TopicRuleConfig{
+					{Source: "tenant_*.*", Topic: "tenants.{table}"},
+				},
+			},
+			schema: "tenant_a",
+			table:  "orders",
+
+			wantTopic: kafka.TopicConfig{Name: "tenants.orders"},
+		},
+		{
+			name: "no matching rule nor template",
+			config: &TopicRoutingConfig{
+				Rules: []TopicRuleConfig{
+					{Source: "tenant_*.*", Topic: "tenants.{table}"},
+				},
+			},
+			schema: "public",
+			table:  "orders",
+
+			wantTopic: kafka.TopicConfig{},
+		},
+		{
+			name:   "invalid characters",
+			config: &TopicRoutingConfig{Template: "cdc.{schema}.{table}"},
+			schema: "my schema",
+			table:  "user$",
+
+			wantTopic: kafka.TopicConfig{Name: "cdc.my_schema.user_"},
+		},
+		{
+			name:   "name too long",
+			config: &TopicRoutingConfig{Template: "{table}"},
+			schema: "public",
+			table:  strings.Repeat("a", 300),
+
+			wantTopic: kafka.TopicConfig{Name: strings.Repeat("a", maxTopicNameLength)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			router, err := newTopicRouter(tc.config)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantTopic, router.route(tc.schema, tc.table))
+		})
+	}
+}
+
+func TestNewTopicRouter_errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config *TopicRoutingConfig
+
+		wantErr error
+	}{
+		{
+			name:   "invalid template placeholder",
+			config: &TopicRoutingConfig{Template: "cdc.{database}.{table}"},
+
+			wantErr: errInvalidTopicTemplate,
+		},
+		{
+			name:   "empty rule topic",
+			config: &TopicRoutingConfig{Rules: []TopicRuleConfig{{Source: "public.users"}}},
+
+			wantErr: errInvalidTopicTemplate,
+		},
+		{
+			name:   "invalid source pattern",
+			config: &TopicRoutingConfig{Rules: []TopicRuleConfig{{Source: "public.[", Topic: "users"}}},
+
+			wantErr: errInvalidTablePattern,
+		},
+		{
+			name:   "invalid table name",
+			config: &TopicRoutingConfig{Rules: []TopicRuleConfig{{Source: "a.b.c", Topic: "users"}}},
+
+			wantErr: errInvalidTableName,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := newTopicRouter(tc.config)
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
@@ -7,15 +7,21 @@ import (
 	"errors"
 	"fmt"
 	"runtime/debug"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/xataio/pgstream/internal/json"
+	"github.com/xataio/pgstream/pkg/blobstore"
 	"github.com/xataio/pgstream/pkg/kafka"
 	kafkainstrumentation "github.com/xataio/pgstream/pkg/kafka/instrumentation"
 	loglib "github.com/xataio/pgstream/pkg/log"
 	"github.com/xataio/pgstream/pkg/otel"
+	"github.com/xataio/pgstream/pkg/schemaregistry"
 	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/avro"
 	"github.com/xataio/pgstream/pkg/wal/checkpointer"
+	"github.com/xataio/pgstream/pkg/wal/debezium"
 	"github.com/xataio/pgstream/pkg/wal/processor"
 	"github.com/xataio/pgstream/pkg/wal/processor/batch"
 )
@@ -32,6 +38,52 @@ type BatchWriter struct {
 	checkpointer checkpointer.Checkpoint
 
 	serialiser func(any) ([]byte, error)
+	// dataSerialiser is an optional serialiser for the wal event data, used
+	// instead of the default JSON serialiser (i.e. avro). Events it serialises
+	// as nil are skipped.
+	dataSerialiser dataSerialiser
+	// keySerialiser is an optional serialiser for the message keys, used
+	// instead of the default key when it returns one (i.e. debezium).
+	keySerialiser keySerialiser
+	// deleteTombstones enables sending a tombstone (a message with the same
+	// key and no value) after every delete event.
+	deleteTombstones bool
+	// compaction enables keying the row events by their compaction key, and
+	// replacing the deletes with tombstones.
+	compaction bool
+	// blobStore is where the values too large to be written inline are
+	// written to, when the claim check strategy is configured. Otherwise, if
+	// chunking is enabled, they're split across multiple messages. If neither
+	// is configured, the events are skipped.
+	blobStore blobstore.Store
+	chunking  bool
+
+	// headers builds the message headers. If not set, the messages are written
+	// without headers, other than the routing ones.
+	headers *messageHeaders
+
+	// keyStrategy defines the message key of the table events, and keyColumns
+	// the columns used by the columns key strategy
+	keyStrategy string
+	keyColumns  []string
+
+	// topicRouter routes the table events to their own topic. If not set, all
+	// the events are written to the default topic.
+	topicRouter *topicRouter
+	// topicCreator creates the routed topics before their first message is
+	// written. Only set when the topic auto creation is enabled.
+	topicCreator  func(kafka.TopicConfig) error
+	defaultTopic  kafka.TopicConfig
+	topicsMutex   sync.Mutex
+	createdTopics map[string]struct{}
+}
+
+type dataSerialiser interface {
+	Serialise(ctx context.Context, data *wal.Data) ([]byte, error)
+}
+
+type keySerialiser interface {
+	SerialiseKey(data *wal.Data) ([]byte, error)
 }
 
 type Option func(*BatchWriter)
@@ -44,10 +96,37 @@ type batchSender interface {
 var errRecordTooLarge = errors.New("record too large")
 
 func NewBatchWriter(ctx context.Context, config *Config, opts ...Option) (*BatchWriter, error) {
+	if err := config.IsValid(); err != nil {
+		return nil, err
+	}
+
+	var err error
 	w := &BatchWriter{
 		serialiser:    json.Marshal,
 		logger:        loglib.NewNoopLogger(),
 		maxBatchBytes: config.Batch.GetMaxBatchBytes(),
+		defaultTopic:  config.Kafka.Topic,
+		createdTopics: map[string]struct{}{},
+		keyStrategy:   config.GetKeyStrategy(),
+		keyColumns:    config.KeyColumns,
+		compaction:    config.Compaction,
+	}
+
+	if config.LargeMessages != nil {
+		switch config.LargeMessages.Strategy {
+		case LargeMessagesClaimCheck:
+			if w.blobStore, err = blobstore.NewStore(config.LargeMessages.BlobStore); err != nil {
+				return nil, err
+			}
+		case LargeMessagesChunking:
+			w.chunking = true
+		}
+	}
+
+	kafkaConn := config.Kafka
+	if config.Compaction {
+		kafkaConn.Topic.CleanupPolicy = kafka.CleanupPolicyCompact
+		w.defaultTopic.CleanupPolicy = kafka.CleanupPolicyCompact
 	}
 
 	// Since the batch kafka writer handles the batching, we don't want to have
@@ -61,9 +140,8 @@ func NewBatchWriter(ctx context.Context, config *Config, opts ...Option) (*Batch
 	// additional features (automatic retries, reconnection, distribution of
 	// messages across partitions,etc) which we want to benefit from.
 	const kafkaBatchTimeout = 10 * time.Millisecond
-	var err error
-	w.writer, err = kafka.NewWriter(kafka.WriterConfig{
-		Conn:         config.Kafka,
+	kafkaWriter, err := kafka.NewWriter(kafka.WriterConfig{
+		Conn:         kafkaConn,
 		BatchTimeout: kafkaBatchTimeout,
 		BatchSize:    int(config.Batch.GetMaxBatchSize()),
 		BatchBytes:   config.Batch.GetMaxBatchBytes(),
@@ -71,11 +149,41 @@ func NewBatchWriter(ctx context.Context, config *Config, opts ...Option) (*Batch
 	if err != nil {
 		return nil, err
 	}
+	w.writer = kafkaWriter
+
+	if w.headers, err = newMessageHeaders(config.GetFormat(), config.Headers); err != nil {
+		return nil, err
+	}
+
+	if config.TopicRouting != nil {
+		if w.topicRouter, err = newTopicRouter(config.TopicRouting); err != nil {
+			return nil, err
+		}
+		if config.Kafka.Topic.AutoCreate {
+			w.topicCreator = kafkaWriter.CreateTopic
+		}
+	}
 
 	for _, opt := range opts {
 		opt(w)
 	}
 
+	switch config.GetFormat() {
+	case FormatAvro:
+		registry, err := schemaregistry.NewClient(config.SchemaRegistry)
+		if err != nil {
+			return nil, err
+		}
+		if w.dataSerialiser, err = avro.NewSerialiser(registry, avro.WithLogger(w.logger)); err != nil {
+			return nil, err
+		}
+	case FormatDebezium:
+		serialiser := debezium.NewSerialiser()
+		w.dataSerialiser = serialiser
+		w.keySerialiser = serialiser
+		w.deleteTombstones = true
+	}
+
 	w.batchSender, err = batch.NewSender(ctx, &config.Batch, w.sendBatch, w.logger)
 	if err != nil {
 		return nil, err
@@ -124,15 +232,15 @@ func (w *BatchWriter) ProcessWALEvent(ctx context.Context, walEvent *wal.Event)
 		}
 	}()
 
-	kafkaMsg := kafka.Message{}
+	msgs := []kafka.Message{{}}
 	if walEvent.Data != nil {
-		walDataBytes, err := w.serialiser(walEvent.Data)
+		walDataBytes, err := w.getMessageValue(ctx, walEvent)
 		if err != nil {
-			return fmt.Errorf("marshalling event: %w", err)
+			return err
 		}
-		// check if walEventBytes is larger than 95% of the Kafka accepted max
-		// message size to allow for some buffer for the rest of the message
-		if len(walDataBytes) > int(0.95*float64(w.maxBatchBytes)) {
+		// events larger than the max message size are skipped, unless a large
+		// messages strategy is configured
+		if len(walDataBytes) > w.maxMessageBytes() && w.blobStore == nil && !w.chunking {
 			w.logger.Warn(errRecordTooLarge,
 				"kafka batch writer: wal event is larger than 95% of max bytes allowed",
 				loglib.Fields{
@@ -144,14 +252,102 @@ func (w *BatchWriter) ProcessWALEvent(ctx context.Context, walEvent *wal.Event)
 			return nil
 		}
 
-		kafkaMsg = kafka.Message{
-			Key:   w.getMessageKey(walEvent.Data),
-			Value: walDataBytes,
+		// events serialised as nil are skipped, but their commit position is
+		// still sent
+		if walDataBytes != nil {
+			if msgs, err = w.buildMessages(walEvent, walDataBytes); err != nil {
+				return err
+			}
+			if msgs, err = w.handleLargeMessages(ctx, msgs); err != nil {
+				return err
+			}
+		}
+	}
+
+	// the commit position is sent with the last message, so that it's only
+	// checkpointed once all the messages of the event have been written
+	for i, msg := range msgs {
+		var position wal.CommitPosition
+		if i == len(msgs)-1 {
+			position = walEvent.CommitPosition
+		}
+		if err := w.batchSender.SendMessage(ctx, batch.NewWALMessage(msg, position)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildMessages returns the kafka messages for the wal event on input. Most
+// events translate to a single message, but deletes can be followed by a
+// tombstone, and with compaction enabled, primary key updates are preceded by
+// the tombstone of the previous key. Rows whose primary key can't be
+// identified are skipped when compaction is enabled, since their messages
+// would never be compacted.
+func (w *BatchWriter) buildMessages(walEvent *wal.Event, value []byte) ([]kafka.Message, error) {
+	key, err := w.getKey(walEvent.Data)
+	if err != nil {
+		return nil, err
+	}
+	msg := kafka.Message{
+		Key:   key,
+		Value: value,
+	}
+	if w.headers != nil {
+		msg.Headers = w.headers.build(walEvent)
+	}
+	if err := w.routeTopic(&msg, walEvent.Data); err != nil {
+		return nil, err
+	}
+	if w.writeToAllPartitions(walEvent.Data) {
+		msg.WriteToAllPartitions()
+	}
+
+	compaction := w.compaction && isRowEvent(walEvent.Data)
+	var previousKey []byte
+	if compaction {
+		msg.Key, previousKey = compactionKeys(walEvent.Data)
+		if msg.Key == nil {
+			w.logger.Warn(nil, "kafka batch writer: skipping row event without primary key in compaction mode", loglib.Fields{
+				"schema": walEvent.Data.Schema,
+				"table":  walEvent.Data.Table,
+			})
+			return []kafka.Message{{}}, nil
+		}
+	}
+
+	applyRoute(&msg, walEvent.Route)
+
+	msgs := make([]kafka.Message, 0, 2)
+	if compaction {
+		if previousKey != nil {
+			msgs = append(msgs, tombstoneMessage(msg, previousKey))
+		}
+		if walEvent.Data.Action == "D" {
+			msg = tombstoneMessage(msg, msg.Key)
 		}
 	}
+	msgs = append(msgs, msg)
 
-	msg := batch.NewWALMessage(kafkaMsg, walEvent.CommitPosition)
-	return w.batchSender.SendMessage(ctx, msg)
+	// the schema log events are also written to the routed topics of their
+	// schema tables, so that the consumers of those topics see the schema
+	// changes before the table events that depend on them
+	if processor.IsSchemaLogEvent(walEvent.Data) && walEvent.Route == nil {
+		topics, err := w.schemaLogTopics(walEvent.Data)
+		if err != nil {
+			return nil, err
+		}
+		for _, topic := range topics {
+			routedMsg := msg
+			routedMsg.Topic = topic
+			msgs = append(msgs, routedMsg)
+		}
+	}
+
+	if w.deleteTombstones && walEvent.Data.Action == "D" {
+		msgs = append(msgs, tombstoneMessage(msg, msg.Key))
+	}
+	return msgs, nil
 }
 
 func (w *BatchWriter) Name() string {
@@ -196,37 +392,305 @@ func (w *BatchWriter) sendBatch(ctx context.Context, batch *batch.Batch[kafka.Me
 	return nil
 }
 
+// getMessageValue returns the value to be used in a kafka message for the wal
+// event on input. Events with a routing payload use it as is, otherwise the wal
+// event data is serialised.
+func (w *BatchWriter) getMessageValue(ctx context.Context, walEvent *wal.Event) ([]byte, error) {
+	if walEvent.Route != nil && walEvent.Route.Payload != nil {
+		return walEvent.Route.Payload, nil
+	}
+	if w.dataSerialiser != nil {
+		walDataBytes, err := w.dataSerialiser.Serialise(ctx, walEvent.Data)
+		if err != nil {
+			return nil, fmt.Errorf("serialising event: %w", err)
+		}
+		return walDataBytes, nil
+	}
+	walDataBytes, err := w.serialiser(walEvent.Data)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling event: %w", err)
+	}
+	return walDataBytes, nil
+}
+
+// getKey returns the key of the kafka message for the wal data on input, using
+// the key serialiser when configured, and falling back to the default key.
+func (w *BatchWriter) getKey(walData *wal.Data) ([]byte, error) {
+	if w.keySerialiser != nil {
+		key, err := w.keySerialiser.SerialiseKey(walData)
+		if err != nil {
+			return nil, fmt.Errorf("serialising key: %w", err)
+		}
+		if key != nil {
+			return key, nil
+		}
+	}
+	return w.getMessageKey(walData), nil
+}
+
+// routeTopic sets the topic of the kafka message for the wal data on input, as
+// defined by the topic routing. Events that don't belong to a table (schema
+// log entries, transaction boundaries and logical messages) are written to the
+// default topic, and the schema log entries to the routed topics of their
+// tables as well.
+func (w *BatchWriter) routeTopic(msg *kafka.Message, walData *wal.Data) error {
+	if w.topicRouter == nil || !isTableEvent(walData) {
+		return nil
+	}
+
+	topic := w.topicRouter.route(walData.Schema, walData.Table)
+	if topic.Name == "" {
+		return nil
+	}
+	if err := w.createTopic(topic); err != nil {
+		return err
+	}
+	msg.Topic = topic.Name
+	return nil
+}
+
+// schemaLogTopics returns the routed topics of the tables of the schema log
+// event on input, other than the default topic. The topics are created if
+// needed. It returns no topics if the topic routing is not configured.
+func (w *BatchWriter) schemaLogTopics(walData *wal.Data) ([]string, error) {
+	if w.topicRouter == nil {
+		return nil, nil
+	}
+
+	logEntry, err := processor.WalDataToLogEntry(walData)
+	if err != nil {
+		return nil, err
+	}
+
+	topics := []string{}
+	for _, table := range logEntry.Schema.Tables {
+		topic := w.topicRouter.route(logEntry.SchemaName, table.Name)
+		if topic.Name == "" || topic.Name == w.defaultTopic.Name || slices.Contains(topics, topic.Name) {
+			continue
+		}
+		if err := w.createTopic(topic); err != nil {
+			return nil, err
+		}
+		topics = append(topics, topic.Name)
+	}
+	return topics, nil
+}
+
+// createTopic creates the routed topic on input the first time it's seen, if
+// the topic auto creation is enabled. The partitions and replication factor
+// default to the ones of the default topic.
+func (w *BatchWriter) createTopic(topic kafka.TopicConfig) error {
+	if w.topicCreator == nil {
+		return nil
+	}
+
+	w.topicsMutex.Lock()
+	defer w.topicsMutex.Unlock()
+	if _, found := w.createdTopics[topic.Name]; found {
+		return nil
+	}
+
+	if topic.NumPartitions == 0 {
+		topic.NumPartitions = w.defaultTopic.NumPartitions
+	}
+	if topic.ReplicationFactor == 0 {
+		topic.ReplicationFactor = w.defaultTopic.ReplicationFactor
+	}
+	topic.CleanupPolicy = w.defaultTopic.CleanupPolicy
+	if err := w.topicCreator(topic); err != nil {
+		return fmt.Errorf("creating topic %s: %w", topic.Name, err)
+	}
+	w.createdTopics[topic.Name] = struct{}{}
+	return nil
+}
+
+// isRowEvent returns true if the wal data is a row change of a table (insert,
+// update or delete).
+func isRowEvent(walData *wal.Data) bool {
+	return walData.Action != "T" && isTableEvent(walData)
+}
+
+// tombstoneMessage returns a message with the key on input and no value, with
+// the same topic and headers as the message on input.
+func tombstoneMessage(msg kafka.Message, key []byte) kafka.Message {
+	return kafka.Message{
+		Topic:   msg.Topic,
+		Key:     key,
+		Headers: msg.Headers,
+	}
+}
+
+func isTableEvent(walData *wal.Data) bool {
+	switch walData.Action {
+	case "I", "U", "D", "T":
+		return !processor.IsSchemaLogEvent(walData)
+	default:
+		return false
+	}
+}
+
+// applyRoute overrides the kafka message topic, key and headers with the
+// routing information of the wal event, if any. The routing headers replace the
+// message headers with the same key.
+func applyRoute(msg *kafka.Message, route *wal.Route) {
+	if route == nil {
+		return
+	}
+	if route.Topic != "" {
+		msg.Topic = route.Topic
+	}
+	if route.Key != nil {
+		msg.Key = route.Key
+	}
+	if len(route.Headers) > 0 {
+		// keep the headers order deterministic
+		keys := make([]string, 0, len(route.Headers))
+		for k := range route.Headers {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			msg.SetHeader(k, []byte(route.Headers[k]))
+		}
+	}
+}
+
 // getMessageKey returns the key to be used in a kafka message for the wal event
 // on input. The message key determines which partition the event is routed to,
-// and therefore which order the events will be executed in. For schema logs,
-// the event schema is that of the pgstream schema, so we extract the underlying
-// user schema they're linked to, to make sure they're routed to the same
-// partition as their writes. This gives us ordering per schema.
-func (w BatchWriter) getMessageKey(walData *wal.Data) []byte {
-	eventKey := walData.Schema
+// and therefore which order the events will be executed in. The table events
+// are keyed as defined by the key strategy, which defaults to their schema,
+// giving us ordering per schema. For schema logs, the event schema is that of
+// the pgstream schema, so we extract the underlying user schema they're linked
+// to, to make sure they're routed to the same partition as their writes when
+// keyed by schema. Logical decoding messages use their prefix, which gives us
+// ordering per prefix.
+func (w *BatchWriter) getMessageKey(walData *wal.Data) []byte {
+	if walData.IsLogicalMessage() {
+		return []byte(walData.Prefix)
+	}
+
 	if processor.IsSchemaLogEvent(walData) {
-		var schemaName string
-		var found bool
-		for _, col := range walData.Columns {
-			if col.Name == "schema_name" {
-				var ok bool
-				if schemaName, ok = col.Value.(string); !ok {
-					// We've got schema name, but it's not a string. This would mean the schema_log has changed and
-					// this code has not been updated.
-					panic(fmt.Sprintf("schema_log schema_name received is not a string: %T", col.Value))
-				}
-				found = true
-				break
+		return []byte(schemaLogSchemaName(walData))
+	}
+
+	if !isTableEvent(walData) {
+		return []byte(walData.Schema)
+	}
+
+	switch w.keyStrategy {
+	case KeyStrategyTable:
+		return tableKey(walData)
+	case KeyStrategyPrimaryKey:
+		return primaryKey(walData)
+	case KeyStrategyColumns:
+		if values, found := columnValues(walData, w.keyColumns); found {
+			return rowKey(walData, values)
+		}
+		return primaryKey(walData)
+	default:
+		return []byte(walData.Schema)
+	}
+}
+
+// writeToAllPartitions returns true if the event needs to be written to all
+// the partitions of its topic, so that it's processed before any of the
+// following events, regardless of their key. That's the case for the schema
+// changes when the table events are not keyed by schema, and for the truncates
+// when they're not keyed by table.
+func (w *BatchWriter) writeToAllPartitions(walData *wal.Data) bool {
+	switch {
+	case processor.IsSchemaLogEvent(walData):
+		switch w.keyStrategy {
+		case KeyStrategyTable, KeyStrategyPrimaryKey, KeyStrategyColumns:
+			return true
+		}
+	case walData.Action == "T":
+		switch w.keyStrategy {
+		case KeyStrategyPrimaryKey, KeyStrategyColumns:
+			return true
+		}
+	}
+	return false
+}
+
+func schemaLogSchemaName(walData *wal.Data) string {
+	for _, col := range walData.Columns {
+		if col.Name == "schema_name" {
+			schemaName, ok := col.Value.(string)
+			if !ok {
+				// We've got schema name, but it's not a string. This would mean the schema_log has changed and
+				// this code has not been updated.
+				panic(fmt.Sprintf("schema_log schema_name received is not a string: %T", col.Value))
 			}
+			return schemaName
+		}
+	}
+	// this means the schema name has not been found in the columns written. This would mean that we've
+	// received a schema_log event, but without enough columns to act on it. This indicates a schema
+	// change that we've not handled.
+	panic("schema_log schema_name not found in columns")
+}
+
+func tableKey(walData *wal.Data) []byte {
+	return []byte(walData.Schema + "." + walData.Table)
+}
+
+// primaryKey returns the key of the row of the event on input, made of its
+// primary key values, as identified by the pgstream metadata. It falls back to
+// the table key if the primary key can't be identified, so that all the events
+// of a table without primary key keep their order.
+func primaryKey(walData *wal.Data) []byte {
+	if len(walData.Metadata.InternalColIDs) == 0 {
+		return tableKey(walData)
+	}
+
+	values := make([]any, 0, len(walData.Metadata.InternalColIDs))
+	for _, id := range walData.Metadata.InternalColIDs {
+		col, found := findColumn(walData, func(col *wal.Column) bool { return col.ID == id })
+		if !found {
+			return tableKey(walData)
 		}
+		values = append(values, col.Value)
+	}
+	return rowKey(walData, values)
+}
+
+// columnValues returns the values of the columns on input for the event row,
+// and whether all of them were found.
+func columnValues(walData *wal.Data, columns []string) ([]any, bool) {
+	values := make([]any, 0, len(columns))
+	for _, name := range columns {
+		col, found := findColumn(walData, func(col *wal.Column) bool { return col.Name == name })
 		if !found {
-			// this means the schema name has not been found in the columns written. This would mean that we've
-			// received a schema_log event, but without enough columns to act on it. This indicates a schema
-			// change that we've not handled.
-			panic("schema_log schema_name not found in columns")
+			return nil, false
 		}
-		eventKey = schemaName
+		values = append(values, col.Value)
 	}
+	return values, true
+}
 
-	return []byte(eventKey)
+// findColumn returns the first column matching the function on input, looking
+// at the new values first, since the key might have been updated, and the
+// identity after (i.e. deletes).
+func findColumn(walData *wal.Data, match func(*wal.Column) bool) (*wal.Column, bool) {
+	for _, cols := range [][]wal.Column{walData.Columns, walData.Identity} {
+		for i := range cols {
+			if match(&cols[i]) {
+				return &cols[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// rowKey returns the key of a row, made of its table and JSON encoded key
+// values.
+func rowKey(walData *wal.Data, values []any) []byte {
+	key, err := json.Marshal(values)
+	if err != nil {
+		// should never happen, since the values have been unmarshaled from
+		// JSON
+		return tableKey(walData)
+	}
+	return append(append(tableKey(walData), '/'), key...)
 }
@@ -46,10 +46,26 @@ func TestBatchKafkaWriter_ProcessWALEvent(t *testing.T) {
 	testBytes := []byte("test")
 	mockMarshaler := func(any) ([]byte, error) { return testBytes, nil }
 
+	testTopicRouter, err := newTopicRouter(&TopicRoutingConfig{
+		Template: "cdc.{schema}.{table}",
+		Rules: []TopicRuleConfig{
+			{Source: testSchema + ".orders", Topic: "orders", NumPartitions: 3},
+		},
+	})
+	require.NoError(t, err)
+
 	tests := []struct {
 		name            string
 		walEvent        *wal.Event
 		eventSerialiser func(any) ([]byte, error)
+		dataSerialiser  dataSerialiser
+		keySerialiser   keySerialiser
+		tombstones      bool
+		compaction      bool
+		topicRouter     *topicRouter
+		topicCreator    func(kafka.TopicConfig) error
+		keyStrategy     string
+		headers         []HeaderConfig
 		batchSender     *batchmocks.BatchSender[kafka.Message]
 
 		wantMsgs []*batch.WALMessage[kafka.Message]
@@ -68,6 +84,49 @@ func TestBatchKafkaWriter_ProcessWALEvent(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name: "ok - with headers",
+			walEvent: &wal.Event{
+				Data: &wal.Data{
+					Action:    "I",
+					LSN:       testLSNStr,
+					Timestamp: "2024-01-01 00:00:00.000000",
+					Schema:    testSchema,
+					Table:     testTable,
+					Metadata:  wal.Metadata{TablePgstreamID: "t1"},
+				},
+				CommitPosition: testCommitPosition,
+				Route: &wal.Route{
+					Headers: map[string]string{"tenant": "routed"},
+				},
+			},
+			headers: []HeaderConfig{
+				{Key: "source", Value: "pgstream"},
+				{Key: "tenant", Value: "{schema}"},
+				{Key: "event", Value: "{table}.{action}"},
+			},
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{
+				batch.NewWALMessage(kafka.Message{
+					Key:   []byte(testSchema),
+					Value: testBytes,
+					Headers: []kafka.Header{
+						{Key: kafka.HeaderSchema, Value: []byte(testSchema)},
+						{Key: kafka.HeaderTable, Value: []byte(testTable)},
+						{Key: kafka.HeaderAction, Value: []byte("I")},
+						{Key: kafka.HeaderLSN, Value: []byte(testLSNStr)},
+						{Key: kafka.HeaderCommitTimestamp, Value: []byte("2024-01-01 00:00:00.000000")},
+						{Key: kafka.HeaderTableID, Value: []byte("t1")},
+						{Key: kafka.HeaderContentType, Value: []byte("application/json")},
+						{Key: "source", Value: []byte("pgstream")},
+						{Key: "event", Value: []byte(testTable + ".I")},
+						{Key: "tenant", Value: []byte("routed")},
+					},
+				}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
 		{
 			name: "ok - keep alive",
 			walEvent: &wal.Event{
@@ -104,6 +163,98 @@ func TestBatchKafkaWriter_ProcessWALEvent(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name: "ok - pgstream schema event with primary key strategy",
+			walEvent: &wal.Event{
+				Data: &wal.Data{
+					Action: "I",
+					LSN:    testLSNStr,
+					Schema: schemalog.SchemaName,
+					Table:  schemalog.TableName,
+					Columns: []wal.Column{
+						{Name: "schema_name", Value: testSchema},
+					},
+				},
+				CommitPosition: testCommitPosition,
+			},
+			keyStrategy: KeyStrategyPrimaryKey,
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: func() []*batch.WALMessage[kafka.Message] {
+				msg := kafka.Message{
+					Key:   []byte(testSchema),
+					Value: testBytes,
+				}
+				msg.WriteToAllPartitions()
+				return []*batch.WALMessage[kafka.Message]{batch.NewWALMessage(msg, testCommitPosition)}
+			}(),
+			wantErr: nil,
+		},
+		{
+			name: "ok - logical message event",
+			walEvent: &wal.Event{
+				Data: &wal.Data{
+					Action:  "M",
+					LSN:     testLSNStr,
+					Prefix:  "outbox",
+					Content: "test",
+				},
+				CommitPosition: testCommitPosition,
+			},
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{
+				batch.NewWALMessage(kafka.Message{
+					Key:   []byte("outbox"),
+					Value: testBytes,
+				}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name: "ok - routed event",
+			walEvent: &wal.Event{
+				Data:           testWalEvent.Data,
+				CommitPosition: testCommitPosition,
+				Route: &wal.Route{
+					Topic: "orders",
+					Key:   []byte("order-1"),
+					Headers: map[string]string{
+						"event_type":   "order_created",
+						"aggregate_id": "order-1",
+					},
+					Payload: []byte(`{"id":1}`),
+				},
+			},
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{
+				batch.NewWALMessage(kafka.Message{
+					Topic: "orders",
+					Key:   []byte("order-1"),
+					Value: []byte(`{"id":1}`),
+					Headers: []kafka.Header{
+						{Key: "aggregate_id", Value: []byte("order-1")},
+						{Key: "event_type", Value: []byte("order_created")},
+					},
+				}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name: "ok - routed event payload too large, message dropped",
+			walEvent: &wal.Event{
+				Data:           testWalEvent.Data,
+				CommitPosition: testCommitPosition,
+				Route: &wal.Route{
+					Payload: []byte(strings.Repeat("a", 101)),
+				},
+			},
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{},
+			wantErr:  nil,
+		},
 		{
 			name:            "ok - wal event too large, message dropped",
 			walEvent:        testWalEvent,
@@ -113,6 +264,360 @@ func TestBatchKafkaWriter_ProcessWALEvent(t *testing.T) {
 			wantMsgs: []*batch.WALMessage[kafka.Message]{},
 			wantErr:  nil,
 		},
+		{
+			name:     "ok - data serialiser",
+			walEvent: testWalEvent,
+			dataSerialiser: mockDataSerialiser(func(_ context.Context, data *wal.Data) ([]byte, error) {
+				require.Equal(t, testWalEvent.Data, data)
+				return []byte("avro"), nil
+			}),
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{
+				batch.NewWALMessage(kafka.Message{
+					Key:   []byte(testSchema),
+					Value: []byte("avro"),
+				}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "ok - data serialiser skipped event",
+			walEvent: testWalEvent,
+			dataSerialiser: mockDataSerialiser(func(context.Context, *wal.Data) ([]byte, error) {
+				return nil, nil
+			}),
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{
+				batch.NewWALMessage(kafka.Message{}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "ok - key serialiser",
+			walEvent: testWalEvent,
+			keySerialiser: mockKeySerialiser(func(data *wal.Data) ([]byte, error) {
+				require.Equal(t, testWalEvent.Data, data)
+				return []byte(`{"id":1}`), nil
+			}),
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{
+				batch.NewWALMessage(kafka.Message{
+					Key:   []byte(`{"id":1}`),
+					Value: testBytes,
+				}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "ok - key serialiser without key",
+			walEvent: testWalEvent,
+			keySerialiser: mockKeySerialiser(func(*wal.Data) ([]byte, error) {
+				return nil, nil
+			}),
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{
+				batch.NewWALMessage(kafka.Message{
+					Key:   []byte(testSchema),
+					Value: testBytes,
+				}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name: "ok - delete tombstone",
+			walEvent: &wal.Event{
+				Data: &wal.Data{
+					Action: "D",
+					LSN:    testLSNStr,
+					Schema: testSchema,
+					Table:  testTable,
+				},
+				CommitPosition: testCommitPosition,
+			},
+			keySerialiser: mockKeySerialiser(func(*wal.Data) ([]byte, error) {
+				return []byte(`{"id":1}`), nil
+			}),
+			tombstones:  true,
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{
+				batch.NewWALMessage(kafka.Message{
+					Key:   []byte(`{"id":1}`),
+					Value: testBytes,
+				}, ""),
+				batch.NewWALMessage(kafka.Message{
+					Key: []byte(`{"id":1}`),
+				}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name: "ok - compaction insert",
+			walEvent: &wal.Event{
+				Data: &wal.Data{
+					Action: "I",
+					LSN:    testLSNStr,
+					Schema: testSchema,
+					Table:  testTable,
+					Columns: []wal.Column{
+						{ID: "c1", Name: "id", Value: 1},
+						{ID: "c2", Name: "name", Value: "a"},
+					},
+					Metadata: wal.Metadata{InternalColIDs: []string{"c1"}},
+				},
+				CommitPosition: testCommitPosition,
+			},
+			compaction:  true,
+			keyStrategy: KeyStrategyPrimaryKey,
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{
+				batch.NewWALMessage(kafka.Message{
+					Key:   []byte(`{"schema":"test_schema","table":"test_table","columns":[{"id":"c1","name":"id","value":1}]}`),
+					Value: testBytes,
+				}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name: "ok - compaction primary key update",
+			walEvent: &wal.Event{
+				Data: &wal.Data{
+					Action: "U",
+					LSN:    testLSNStr,
+					Schema: testSchema,
+					Table:  testTable,
+					Columns: []wal.Column{
+						{ID: "c1", Name: "id", Value: 2},
+						{ID: "c2", Name: "name", Value: "a"},
+					},
+					Identity: []wal.Column{
+						{ID: "c1", Name: "id", Value: 1},
+					},
+					Metadata: wal.Metadata{InternalColIDs: []string{"c1"}},
+				},
+				CommitPosition: testCommitPosition,
+			},
+			compaction:  true,
+			keyStrategy: KeyStrategyPrimaryKey,
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{
+				batch.NewWALMessage(kafka.Message{
+					Key: []byte(`{"schema":"test_schema","table":"test_table","columns":[{"id":"c1","name":"id","value":1}]}`),
+				}, ""),
+				batch.NewWALMessage(kafka.Message{
+					Key:   []byte(`{"schema":"test_schema","table":"test_table","columns":[{"id":"c1","name":"id","value":2}]}`),
+					Value: testBytes,
+				}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name: "ok - compaction delete",
+			walEvent: &wal.Event{
+				Data: &wal.Data{
+					Action: "D",
+					LSN:    testLSNStr,
+					Schema: testSchema,
+					Table:  testTable,
+					Identity: []wal.Column{
+						{ID: "c1", Name: "id", Value: 1},
+					},
+					Metadata: wal.Metadata{InternalColIDs: []string{"c1"}},
+				},
+				CommitPosition: testCommitPosition,
+			},
+			compaction:  true,
+			keyStrategy: KeyStrategyPrimaryKey,
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{
+				batch.NewWALMessage(kafka.Message{
+					Key: []byte(`{"schema":"test_schema","table":"test_table","columns":[{"id":"c1","name":"id","value":1}]}`),
+				}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name:        "ok - compaction without primary key",
+			walEvent:    testWalEvent,
+			compaction:  true,
+			keyStrategy: KeyStrategyPrimaryKey,
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{
+				batch.NewWALMessage(kafka.Message{}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name:        "ok - topic routing",
+			walEvent:    testWalEvent,
+			topicRouter: testTopicRouter,
+			topicCreator: func(topic kafka.TopicConfig) error {
+				require.Equal(t, kafka.TopicConfig{Name: "cdc.test_schema.test_table", NumPartitions: 2, ReplicationFactor: 1}, topic)
+				return nil
+			},
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{
+				batch.NewWALMessage(kafka.Message{
+					Topic: "cdc.test_schema.test_table",
+					Key:   []byte(testSchema),
+					Value: testBytes,
+				}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name: "ok - topic routing rule",
+			walEvent: &wal.Event{
+				Data: &wal.Data{
+					Action: "D",
+					LSN:    testLSNStr,
+					Schema: testSchema,
+					Table:  "orders",
+				},
+				CommitPosition: testCommitPosition,
+			},
+			topicRouter: testTopicRouter,
+			topicCreator: func(topic kafka.TopicConfig) error {
+				require.Equal(t, kafka.TopicConfig{Name: "orders", NumPartitions: 3, ReplicationFactor: 1}, topic)
+				return nil
+			},
+			tombstones:  true,
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{
+				batch.NewWALMessage(kafka.Message{
+					Topic: "orders",
+					Key:   []byte(testSchema),
+					Value: testBytes,
+				}, ""),
+				batch.NewWALMessage(kafka.Message{
+					Topic: "orders",
+					Key:   []byte(testSchema),
+				}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name: "ok - topic routing overridden by event route",
+			walEvent: &wal.Event{
+				Data:           testWalEvent.Data,
+				CommitPosition: testCommitPosition,
+				Route:          &wal.Route{Topic: "outbox"},
+			},
+			topicRouter: testTopicRouter,
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{
+				batch.NewWALMessage(kafka.Message{
+					Topic: "outbox",
+					Key:   []byte(testSchema),
+					Value: testBytes,
+				}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name: "ok - topic routing non table event",
+			walEvent: &wal.Event{
+				Data: &wal.Data{
+					Action: "C",
+					LSN:    testLSNStr,
+				},
+				CommitPosition: testCommitPosition,
+			},
+			topicRouter: testTopicRouter,
+			topicCreator: func(topic kafka.TopicConfig) error {
+				return errors.New("topicCreator: should not be called")
+			},
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{
+				batch.NewWALMessage(kafka.Message{
+					Key:   []byte(""),
+					Value: testBytes,
+				}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name: "ok - topic routing schema log event",
+			walEvent: &wal.Event{
+				Data: &wal.Data{
+					Action: "I",
+					LSN:    testLSNStr,
+					Schema: schemalog.SchemaName,
+					Table:  schemalog.TableName,
+					Columns: []wal.Column{
+						{Name: "schema_name", Value: testSchema},
+						{Name: "schema", Value: `{"tables":[{"name":"test_table"},{"name":"orders"},{"name":"test_table"}]}`},
+					},
+				},
+				CommitPosition: testCommitPosition,
+			},
+			topicRouter: testTopicRouter,
+			keyStrategy: KeyStrategyTable,
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: func() []*batch.WALMessage[kafka.Message] {
+				msgs := []*batch.WALMessage[kafka.Message]{}
+				for i, topic := range []string{"", "cdc.test_schema.test_table", "orders"} {
+					msg := kafka.Message{
+						Topic: topic,
+						Key:   []byte(testSchema),
+						Value: testBytes,
+					}
+					msg.WriteToAllPartitions()
+					var position wal.CommitPosition
+					if i == 2 {
+						position = testCommitPosition
+					}
+					msgs = append(msgs, batch.NewWALMessage(msg, position))
+				}
+				return msgs
+			}(),
+			wantErr: nil,
+		},
+		{
+			name:         "error - creating topic",
+			walEvent:     testWalEvent,
+			topicRouter:  testTopicRouter,
+			topicCreator: func(topic kafka.TopicConfig) error { return errTest },
+			batchSender:  batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{},
+			wantErr:  errTest,
+		},
+		{
+			name:     "error - key serialiser",
+			walEvent: testWalEvent,
+			keySerialiser: mockKeySerialiser(func(*wal.Data) ([]byte, error) {
+				return nil, errTest
+			}),
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{},
+			wantErr:  errTest,
+		},
+		{
+			name:     "error - data serialiser",
+			walEvent: testWalEvent,
+			dataSerialiser: mockDataSerialiser(func(context.Context, *wal.Data) ([]byte, error) {
+				return nil, errTest
+			}),
+			batchSender: batchmocks.NewBatchSender[kafka.Message](),
+
+			wantMsgs: []*batch.WALMessage[kafka.Message]{},
+			wantErr:  errTest,
+		},
 		{
 			name:            "error - marshaling event",
 			walEvent:        testWalEvent,
@@ -168,11 +673,29 @@ func TestBatchKafkaWriter_ProcessWALEvent(t *testing.T) {
 				maxBatchBytes: 100,
 				serialiser:    mockMarshaler,
 				batchSender:   tc.batchSender,
+				topicRouter:   tc.topicRouter,
+				topicCreator:  tc.topicCreator,
+				defaultTopic:  kafka.TopicConfig{Name: "pgstream", NumPartitions: 2, ReplicationFactor: 1},
+				createdTopics: map[string]struct{}{},
+				keyStrategy:   tc.keyStrategy,
 			}
 
 			if tc.eventSerialiser != nil {
 				writer.serialiser = tc.eventSerialiser
 			}
+			if tc.dataSerialiser != nil {
+				writer.dataSerialiser = tc.dataSerialiser
+			}
+			if tc.keySerialiser != nil {
+				writer.keySerialiser = tc.keySerialiser
+			}
+			writer.deleteTombstones = tc.tombstones
+			writer.compaction = tc.compaction
+			if tc.headers != nil {
+				var err error
+				writer.headers, err = newMessageHeaders(FormatJSON, tc.headers)
+				require.NoError(t, err)
+			}
 
 			go func() {
 				defer tc.batchSender.Close()
@@ -291,3 +814,139 @@ func TestBatchKafkaWriter_sendBatch(t *testing.T) {
 		})
 	}
 }
+
+func TestBatchKafkaWriter_getMessageKey(t *testing.T) {
+	t.Parallel()
+
+	testMetadata := wal.Metadata{InternalColIDs: []string{"t1-1", "t1-2"}}
+	testColumns := []wal.Column{
+		{ID: "t1-1", Name: "tenant_id", Value: "a"},
+		{ID: "t1-2", Name: "id", Value: float64(1)},
+		{ID: "t1-3", Name: "name", Value: "alice"},
+	}
+	newData := func(action string, columns, identity []wal.Column, metadata wal.Metadata) *wal.Data {
+		return &wal.Data{
+			Action:   action,
+			Schema:   testSchema,
+			Table:    testTable,
+			Columns:  columns,
+			Identity: identity,
+			Metadata: metadata,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		keyStrategy string
+		keyColumns  []string
+		data        *wal.Data
+
+		wantKey           string
+		wantAllPartitions bool
+	}{
+		{
+			name: "default strategy",
+			data: newData("I", testColumns, nil, testMetadata),
+
+			wantKey: testSchema,
+		},
+		{
+			name:        "table strategy",
+			keyStrategy: KeyStrategyTable,
+			data:        newData("I", testColumns, nil, testMetadata),
+
+			wantKey: "test_schema.test_table",
+		},
+		{
+			name:        "primary key strategy",
+			keyStrategy: KeyStrategyPrimaryKey,
+			data:        newData("U", testColumns, nil, testMetadata),
+
+			wantKey: `test_schema.test_table/["a",1]`,
+		},
+		{
+			name:        "primary key strategy - delete identity",
+			keyStrategy: KeyStrategyPrimaryKey,
+			data:        newData("D", nil, testColumns[:2], testMetadata),
+
+			wantKey: `test_schema.test_table/["a",1]`,
+		},
+		{
+			name:        "primary key strategy - no primary key",
+			keyStrategy: KeyStrategyPrimaryKey,
+			data:        newData("I", testColumns, nil, wal.Metadata{}),
+
+			wantKey: "test_schema.test_table",
+		},
+		{
+			name:        "primary key strategy - missing primary key column",
+			keyStrategy: KeyStrategyPrimaryKey,
+			data:        newData("I", testColumns[1:], nil, testMetadata),
+
+			wantKey: "test_schema.test_table",
+		},
+		{
+			name:        "primary key strategy - truncate",
+			keyStrategy: KeyStrategyPrimaryKey,
+			data:        newData("T", nil, nil, testMetadata),
+
+			wantKey:           "test_schema.test_table",
+			wantAllPartitions: true,
+		},
+		{
+			name:        "primary key strategy - transaction boundary",
+			keyStrategy: KeyStrategyPrimaryKey,
+			data:        &wal.Data{Action: "C"},
+
+			wantKey: "",
+		},
+		{
+			name:        "columns strategy",
+			keyStrategy: KeyStrategyColumns,
+			keyColumns:  []string{"tenant_id"},
+			data:        newData("I", testColumns, nil, testMetadata),
+
+			wantKey: `test_schema.test_table/["a"]`,
+		},
+		{
+			name:        "columns strategy - missing column",
+			keyStrategy: KeyStrategyColumns,
+			keyColumns:  []string{"tenant_id", "region"},
+			data:        newData("I", testColumns, nil, testMetadata),
+
+			wantKey: `test_schema.test_table/["a",1]`,
+		},
+		{
+			name:        "table strategy - truncate",
+			keyStrategy: KeyStrategyTable,
+			data:        newData("T", nil, nil, testMetadata),
+
+			wantKey: "test_schema.test_table",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := &BatchWriter{
+				keyStrategy: tc.keyStrategy,
+				keyColumns:  tc.keyColumns,
+			}
+			require.Equal(t, tc.wantKey, string(writer.getMessageKey(tc.data)))
+			require.Equal(t, tc.wantAllPartitions, writer.writeToAllPartitions(tc.data))
+		})
+	}
+}
+
+type mockDataSerialiser func(context.Context, *wal.Data) ([]byte, error)
+
+func (m mockDataSerialiser) Serialise(ctx context.Context, data *wal.Data) ([]byte, error) {
+	return m(ctx, data)
+}
+
+type mockKeySerialiser func(*wal.Data) ([]byte, error)
+
+func (m mockKeySerialiser) SerialiseKey(data *wal.Data) ([]byte, error) {
+	return m(data)
+}
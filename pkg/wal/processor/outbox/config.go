@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package outbox
+
+type Config struct {
+	// Tables contains the outbox tables configuration.
+	Tables []TableConfig
+	// DeleteProcessedRows enables the deletion of the outbox rows from the
+	// source database once their events have been checkpointed. Defaults to
+	// false.
+	DeleteProcessedRows bool
+}
+
+type TableConfig struct {
+	// Table is the outbox table name. It should be schema qualified. If no
+	// schema is provided, the public schema will be assumed.
+	Table string
+	// IDColumn is the column that uniquely identifies an outbox row. It's used
+	// to delete the processed rows. Defaults to "id".
+	IDColumn string
+	// PayloadColumn is the column containing the message payload. Defaults to
+	// "payload".
+	PayloadColumn string
+	// AggregateIDColumn is the column used as the message key. Defaults to
+	// "aggregate_id".
+	AggregateIDColumn string
+	// EventTypeColumn is the column containing the event type, sent as a
+	// message header. Defaults to "event_type".
+	EventTypeColumn string
+	// Topic is the template of the destination topic. Column values can be
+	// referenced with {column_name} placeholders (i.e. "outbox.{aggregate_type}").
+	// Defaults to the target topic.
+	Topic string
+	// HeaderColumns are additional columns sent as message headers.
+	HeaderColumns []string
+}
+
+const (
+	defaultIDColumn          = "id"
+	defaultPayloadColumn     = "payload"
+	defaultAggregateIDColumn = "aggregate_id"
+	defaultEventTypeColumn   = "event_type"
+)
+
+func (c *TableConfig) GetIDColumn() string {
+	if c.IDColumn != "" {
+		return c.IDColumn
+	}
+	return defaultIDColumn
+}
+
+func (c *TableConfig) GetPayloadColumn() string {
+	if c.PayloadColumn != "" {
+		return c.PayloadColumn
+	}
+	return defaultPayloadColumn
+}
+
+func (c *TableConfig) GetAggregateIDColumn() string {
+	if c.AggregateIDColumn != "" {
+		return c.AggregateIDColumn
+	}
+	return defaultAggregateIDColumn
+}
+
+func (c *TableConfig) GetEventTypeColumn() string {
+	if c.EventTypeColumn != "" {
+		return c.EventTypeColumn
+	}
+	return defaultEventTypeColumn
+}
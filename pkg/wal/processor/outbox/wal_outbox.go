@@ -0,0 +1,289 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+)
+
+// Outbox is a processor wrapper that implements the transactional outbox
+// pattern. Inserts on the configured outbox tables are turned into routed
+// events, where the payload, key, topic and headers of the delivered message
+// are extracted from the row columns. Any other operation on the outbox tables
+// is skipped, and events for non outbox tables are passed through unchanged.
+type Outbox struct {
+	processor processor.Processor
+	tables    map[string]*outboxTable
+	cleaner   *Cleaner
+	logger    loglib.Logger
+}
+
+type outboxTable struct {
+	schema string
+	name   string
+	config TableConfig
+}
+
+type Option func(*Outbox)
+
+var (
+	errMissingTables     = errors.New("missing outbox tables configuration")
+	errInvalidTableName  = errors.New("invalid table name format")
+	errDuplicateTable    = errors.New("outbox table configured more than once")
+	errMissingColumn     = errors.New("outbox column not found")
+	errUnsupportedColumn = errors.New("unsupported outbox column value")
+)
+
+const publicSchema = "public"
+
+// topicPlaceholderRegex matches the {column_name} placeholders in the topic
+// templates.
+var topicPlaceholderRegex = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// New will return an outbox processor wrapper that will route the inserts on
+// the configured outbox tables.
+func New(processor processor.Processor, cfg *Config, opts ...Option) (*Outbox, error) {
+	if len(cfg.Tables) == 0 {
+		return nil, errMissingTables
+	}
+
+	o := &Outbox{
+		processor: processor,
+		tables:    make(map[string]*outboxTable, len(cfg.Tables)),
+		logger:    loglib.NewNoopLogger(),
+	}
+
+	for _, tableCfg := range cfg.Tables {
+		schema, table, err := parseTableName(tableCfg.Table)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, tableCfg.Table)
+		}
+		key := tableKey(schema, table)
+		if _, found := o.tables[key]; found {
+			return nil, fmt.Errorf("%w: %s", errDuplicateTable, tableCfg.Table)
+		}
+		o.tables[key] = &outboxTable{
+			schema: schema,
+			name:   table,
+			config: tableCfg,
+		}
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o, nil
+}
+
+func WithLogger(logger loglib.Logger) Option {
+	return func(o *Outbox) {
+		o.logger = loglib.NewLogger(logger).WithFields(loglib.Fields{
+			loglib.ModuleField: "wal_outbox",
+		})
+	}
+}
+
+// WithCleaner will register the routed outbox rows with the cleaner on input,
+// so that they can be deleted from the source database once their events have
+// been checkpointed.
+func WithCleaner(cleaner *Cleaner) Option {
+	return func(o *Outbox) {
+		o.cleaner = cleaner
+	}
+}
+
+func (o *Outbox) ProcessWALEvent(ctx context.Context, event *wal.Event) error {
+	table := o.getOutboxTable(event)
+	if table == nil {
+		return o.processor.ProcessWALEvent(ctx, event)
+	}
+
+	// only inserts are relevant for the outbox pattern, updates and deletes of
+	// the outbox rows (including the ones performed by the cleaner) are skipped
+	if event.Data.Action != "I" {
+		o.logger.Trace("skipping outbox event", loglib.Fields{"schema": event.Data.Schema, "table": event.Data.Table, "action": event.Data.Action})
+		return nil
+	}
+
+	route, err := table.route(event.Data)
+	if err != nil {
+		return fmt.Errorf("outbox table %s.%s: %w", table.schema, table.name, err)
+	}
+	event.Route = route
+
+	if o.cleaner != nil && event.CommitPosition != "" {
+		id, err := table.columnValue(event.Data, table.config.GetIDColumn())
+		if err != nil {
+			return fmt.Errorf("outbox table %s.%s: %w", table.schema, table.name, err)
+		}
+		if err := o.cleaner.track(event.CommitPosition, table, id); err != nil {
+			return err
+		}
+	}
+
+	return o.processor.ProcessWALEvent(ctx, event)
+}
+
+func (o *Outbox) Name() string {
+	return o.processor.Name()
+}
+
+func (o *Outbox) Close() error {
+	return o.processor.Close()
+}
+
+func (o *Outbox) getOutboxTable(event *wal.Event) *outboxTable {
+	if event == nil || event.Data == nil || event.Data.IsTransactionBoundary() || event.Data.IsLogicalMessage() {
+		return nil
+	}
+	return o.tables[tableKey(event.Data.Schema, event.Data.Table)]
+}
+
+// route extracts the routing information for the outbox row on input.
+func (t *outboxTable) route(data *wal.Data) (*wal.Route, error) {
+	payloadValue, err := t.columnValue(data, t.config.GetPayloadColumn())
+	if err != nil {
+		return nil, err
+	}
+	payload, err := payloadBytes(payloadValue)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregateID, err := t.columnStringValue(data, t.config.GetAggregateIDColumn())
+	if err != nil {
+		return nil, err
+	}
+
+	eventType, err := t.columnStringValue(data, t.config.GetEventTypeColumn())
+	if err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{
+		t.config.GetAggregateIDColumn(): aggregateID,
+		t.config.GetEventTypeColumn():   eventType,
+	}
+	for _, col := range t.config.HeaderColumns {
+		value, err := t.columnStringValue(data, col)
+		if err != nil {
+			return nil, err
+		}
+		headers[col] = value
+	}
+
+	topic, err := t.topic(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &wal.Route{
+		Topic:   topic,
+		Key:     []byte(aggregateID),
+		Headers: headers,
+		Payload: payload,
+	}, nil
+}
+
+// topic resolves the topic template placeholders with the values of the
+// referenced columns.
+func (t *outboxTable) topic(data *wal.Data) (string, error) {
+	var err error
+	topic := topicPlaceholderRegex.ReplaceAllStringFunc(t.config.Topic, func(placeholder string) string {
+		value, colErr := t.columnStringValue(data, strings.Trim(placeholder, "{}"))
+		if colErr != nil {
+			err = colErr
+			return ""
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return topic, nil
+}
+
+func (t *outboxTable) columnValue(data *wal.Data, name string) (any, error) {
+	for _, col := range data.Columns {
+		if col.Name == name {
+			return col.Value, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errMissingColumn, name)
+}
+
+func (t *outboxTable) columnStringValue(data *wal.Data, name string) (string, error) {
+	value, err := t.columnValue(data, name)
+	if err != nil {
+		return "", err
+	}
+	str, err := valueToString(value)
+	if err != nil {
+		return "", fmt.Errorf("column %s: %w", name, err)
+	}
+	return str, nil
+}
+
+// payloadBytes returns the message payload for the column value on input.
+// Text values (including json/jsonb columns) are used as is, while any other
+// value is json encoded.
+func payloadBytes(value any) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		payload, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("encoding payload: %w", err)
+		}
+		return payload, nil
+	}
+}
+
+func valueToString(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, bool:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("%w: %T", errUnsupportedColumn, value)
+	}
+}
+
+func parseTableName(qualifiedTableName string) (string, string, error) {
+	parts := strings.Split(qualifiedTableName, ".")
+	switch len(parts) {
+	case 1:
+		return publicSchema, parts[0], nil
+	case 2:
+		return parts[0], parts[1], nil
+	default:
+		return "", "", errInvalidTableName
+	}
+}
+
+func tableKey(schema, table string) string {
+	return schema + "." + table
+}
@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/checkpointer"
+	"github.com/xataio/pgstream/pkg/wal/replication"
+	pgreplication "github.com/xataio/pgstream/pkg/wal/replication/postgres"
+)
+
+// Cleaner deletes the outbox rows from the source database once the events
+// they produced have been checkpointed, which guarantees they've been
+// delivered to the target.
+type Cleaner struct {
+	querier   pglib.Querier
+	lsnParser replication.LSNParser
+	logger    loglib.Logger
+
+	mutex   sync.Mutex
+	pending []*processedRow
+	// failedDeletes is the number of consecutive checkpoints for which the
+	// processed rows couldn't be deleted
+	failedDeletes int
+}
+
+type processedRow struct {
+	position replication.LSN
+	table    *outboxTable
+	id       any
+}
+
+type CleanerOption func(*Cleaner)
+
+// maxFailedDeletes is the number of consecutive checkpoints for which the
+// deletion of the processed rows can fail before the error is returned, so
+// that the pending rows don't grow without bound.
+const maxFailedDeletes = 10
+
+var errDeletingRows = errors.New("deleting processed outbox rows")
+
+// NewCleaner returns a cleaner that deletes the processed outbox rows from the
+// postgres database on input.
+func NewCleaner(ctx context.Context, pgURL string, opts ...CleanerOption) (*Cleaner, error) {
+	pool, err := pglib.NewConnPool(ctx, pgURL)
+	if err != nil {
+		return nil, fmt.Errorf("creating outbox cleaner connection pool: %w", err)
+	}
+
+	c := &Cleaner{
+		querier:   pool,
+		lsnParser: pgreplication.NewLSNParser(),
+		logger:    loglib.NewNoopLogger(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+func WithCleanerLogger(logger loglib.Logger) CleanerOption {
+	return func(c *Cleaner) {
+		c.logger = loglib.NewLogger(logger).WithFields(loglib.Fields{
+			loglib.ModuleField: "wal_outbox_cleaner",
+		})
+	}
+}
+
+// WrapCheckpoint returns a checkpoint that calls the checkpoint on input and,
+// once it succeeds, deletes the outbox rows whose events are covered by the
+// checkpointed positions. An error is returned if the deletion keeps failing.
+func (c *Cleaner) WrapCheckpoint(checkpoint checkpointer.Checkpoint) checkpointer.Checkpoint {
+	return func(ctx context.Context, positions []wal.CommitPosition) error {
+		if checkpoint != nil {
+			if err := checkpoint(ctx, positions); err != nil {
+				return err
+			}
+		}
+		return c.deleteProcessedRows(ctx, positions)
+	}
+}
+
+func (c *Cleaner) Close() error {
+	return c.querier.Close(context.Background())
+}
+
+// track registers the outbox row on input, so that it's deleted once its
+// position has been checkpointed.
+func (c *Cleaner) track(position wal.CommitPosition, table *outboxTable, id any) error {
+	lsn, err := c.lsnParser.FromString(string(position))
+	if err != nil {
+		return fmt.Errorf("parsing outbox row position: %w", err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.pending = append(c.pending, &processedRow{
+		position: lsn,
+		table:    table,
+		id:       id,
+	})
+	return nil
+}
+
+// deleteProcessedRows deletes the pending outbox rows with a position lower or
+// equal to the checkpointed ones. Failures are logged and the rows are kept
+// pending, so that the deletion is retried on the next checkpoint. Once the
+// deletion has failed for maxFailedDeletes consecutive checkpoints, the error
+// is returned.
+func (c *Cleaner) deleteProcessedRows(ctx context.Context, positions []wal.CommitPosition) error {
+	maxLSN, err := c.maxPosition(positions)
+	if err != nil {
+		c.logger.Error(err, "outbox cleaner: parsing checkpointed positions")
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	processed := []*processedRow{}
+	pending := []*processedRow{}
+	for _, row := range c.pending {
+		if row.position <= maxLSN {
+			processed = append(processed, row)
+			continue
+		}
+		pending = append(pending, row)
+	}
+	if len(processed) == 0 {
+		return nil
+	}
+
+	err = c.querier.ExecInTx(ctx, func(tx pglib.Tx) error {
+		for _, row := range processed {
+			query := fmt.Sprintf("DELETE FROM %s WHERE %s = $1",
+				pglib.QuoteQualifiedIdentifier(row.table.schema, row.table.name),
+				pglib.QuoteIdentifier(row.table.config.GetIDColumn()))
+			if _, err := tx.Exec(ctx, query, row.id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.failedDeletes++
+		if c.failedDeletes >= maxFailedDeletes {
+			return fmt.Errorf("%w: %d rows pending after %d attempts: %w", errDeletingRows, len(c.pending), c.failedDeletes, err)
+		}
+		c.logger.Error(err, "outbox cleaner: deleting processed outbox rows", loglib.Fields{
+			"rows":     len(processed),
+			"attempts": c.failedDeletes,
+		})
+		return nil
+	}
+
+	c.logger.Debug("outbox cleaner: deleted processed outbox rows", loglib.Fields{"rows": len(processed)})
+	c.pending = pending
+	c.failedDeletes = 0
+	return nil
+}
+
+func (c *Cleaner) maxPosition(positions []wal.CommitPosition) (replication.LSN, error) {
+	var maxLSN replication.LSN
+	for _, position := range positions {
+		lsn, err := c.lsnParser.FromString(string(position))
+		if err != nil {
+			return 0, err
+		}
+		if lsn > maxLSN {
+			maxLSN = lsn
+		}
+	}
+	return maxLSN, nil
+}
@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package outbox
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	pgreplication "github.com/xataio/pgstream/pkg/wal/replication/postgres"
+)
+
+func newTestCleaner(querier pglib.Querier) *Cleaner {
+	return &Cleaner{
+		querier:   querier,
+		lsnParser: pgreplication.NewLSNParser(),
+		logger:    loglib.NewNoopLogger(),
+	}
+}
+
+func TestCleaner_WrapCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	testTable := &outboxTable{
+		schema: "public",
+		name:   "outbox",
+		config: TableConfig{Table: "outbox", IDColumn: "event_id"},
+	}
+
+	testPending := func() []*processedRow {
+		return []*processedRow{
+			{position: 1, table: testTable, id: float64(1)},
+			{position: 2, table: testTable, id: float64(2)},
+			{position: 3, table: testTable, id: float64(3)},
+		}
+	}
+
+	testPositions := []wal.CommitPosition{"0/1", "0/2"}
+
+	errDeleting := func() *pgmocks.Querier {
+		return &pgmocks.Querier{
+			ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+				return f(&pgmocks.Tx{
+					ExecFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+						return pglib.CommandTag{}, errTest
+					},
+				})
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		checkpoint    func(context.Context, []wal.CommitPosition) error
+		querier       *pgmocks.Querier
+		failedDeletes int
+
+		wantPending       []*processedRow
+		wantFailedDeletes int
+		wantErr           error
+	}{
+		{
+			name: "ok",
+			querier: &pgmocks.Querier{
+				ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+					return f(&pgmocks.Tx{
+						ExecFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+							require.Equal(t, `DELETE FROM "public"."outbox" WHERE "event_id" = $1`, query)
+							require.Equal(t, []any{float64(i)}, args)
+							return pglib.CommandTag{}, nil
+						},
+					})
+				},
+			},
+
+			failedDeletes: 2,
+
+			wantPending: testPending()[2:],
+			wantErr:     nil,
+		},
+		{
+			name:    "ok - error deleting rows, kept pending",
+			querier: errDeleting(),
+
+			wantPending:       testPending(),
+			wantFailedDeletes: 1,
+			wantErr:           nil,
+		},
+		{
+			name:          "error - deleting rows, max attempts reached",
+			querier:       errDeleting(),
+			failedDeletes: maxFailedDeletes - 1,
+
+			wantPending:       testPending(),
+			wantFailedDeletes: maxFailedDeletes,
+			wantErr:           errTest,
+		},
+		{
+			name: "error - checkpointing",
+			checkpoint: func(ctx context.Context, positions []wal.CommitPosition) error {
+				return errTest
+			},
+			querier: &pgmocks.Querier{
+				ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+					return errTest
+				},
+			},
+
+			wantPending: testPending(),
+			wantErr:     errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := newTestCleaner(tc.querier)
+			c.pending = testPending()
+			c.failedDeletes = tc.failedDeletes
+
+			checkpoint := tc.checkpoint
+			if checkpoint == nil {
+				checkpoint = func(ctx context.Context, positions []wal.CommitPosition) error {
+					require.Equal(t, testPositions, positions)
+					return nil
+				}
+			}
+
+			err := c.WrapCheckpoint(checkpoint)(context.Background(), testPositions)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantPending, c.pending)
+			require.Equal(t, tc.wantFailedDeletes, c.failedDeletes)
+		})
+	}
+}
@@ -0,0 +1,318 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/mocks"
+	"github.com/xataio/pgstream/pkg/wal/replication"
+)
+
+const testLSNStr = "0/15D6A28"
+
+var errTest = errors.New("oh noes")
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config *Config
+
+		wantTables map[string]*outboxTable
+		wantErr    error
+	}{
+		{
+			name: "ok",
+			config: &Config{
+				Tables: []TableConfig{
+					{Table: "outbox"},
+					{Table: "orders.outbox", Topic: "orders"},
+				},
+			},
+
+			wantTables: map[string]*outboxTable{
+				"public.outbox": {schema: "public", name: "outbox", config: TableConfig{Table: "outbox"}},
+				"orders.outbox": {schema: "orders", name: "outbox", config: TableConfig{Table: "orders.outbox", Topic: "orders"}},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "error - missing tables",
+			config: &Config{},
+
+			wantErr: errMissingTables,
+		},
+		{
+			name: "error - invalid table name",
+			config: &Config{
+				Tables: []TableConfig{{Table: "a.b.c"}},
+			},
+
+			wantErr: errInvalidTableName,
+		},
+		{
+			name: "error - duplicate table",
+			config: &Config{
+				Tables: []TableConfig{{Table: "outbox"}, {Table: "public.outbox"}},
+			},
+
+			wantErr: errDuplicateTable,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			o, err := New(&mocks.Processor{}, tc.config)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantErr != nil {
+				return
+			}
+			require.Equal(t, tc.wantTables, o.tables)
+		})
+	}
+}
+
+func TestOutbox_ProcessWALEvent(t *testing.T) {
+	t.Parallel()
+
+	testOutboxTable := &outboxTable{
+		schema: "public",
+		name:   "outbox",
+		config: TableConfig{
+			Table:         "outbox",
+			Topic:         "outbox.{aggregate_type}",
+			HeaderColumns: []string{"trace_id"},
+		},
+	}
+
+	testColumns := func() []wal.Column {
+		return []wal.Column{
+			{Name: "id", Type: "bigint", Value: float64(1)},
+			{Name: "aggregate_type", Type: "text", Value: "order"},
+			{Name: "aggregate_id", Type: "text", Value: "order-1"},
+			{Name: "event_type", Type: "text", Value: "order_created"},
+			{Name: "trace_id", Type: "bigint", Value: float64(1234567)},
+			{Name: "payload", Type: "jsonb", Value: `{"total":10}`},
+		}
+	}
+
+	testEvent := func(action string, columns []wal.Column) *wal.Event {
+		return &wal.Event{
+			Data: &wal.Data{
+				Action:  action,
+				LSN:     testLSNStr,
+				Schema:  "public",
+				Table:   "outbox",
+				Columns: columns,
+			},
+			CommitPosition: wal.CommitPosition(testLSNStr),
+		}
+	}
+
+	testRoute := &wal.Route{
+		Topic: "outbox.order",
+		Key:   []byte("order-1"),
+		Headers: map[string]string{
+			"aggregate_id": "order-1",
+			"event_type":   "order_created",
+			"trace_id":     "1234567",
+		},
+		Payload: []byte(`{"total":10}`),
+	}
+
+	tests := []struct {
+		name     string
+		event    *wal.Event
+		cleaner  *Cleaner
+		procErr  error
+		wantErr  error
+		wantCall bool
+
+		wantEvent   *wal.Event
+		wantPending []*processedRow
+	}{
+		{
+			name:     "ok - outbox insert",
+			event:    testEvent("I", testColumns()),
+			wantCall: true,
+
+			wantEvent: func() *wal.Event {
+				e := testEvent("I", testColumns())
+				e.Route = testRoute
+				return e
+			}(),
+		},
+		{
+			name:     "ok - outbox insert with cleaner",
+			event:    testEvent("I", testColumns()),
+			cleaner:  newTestCleaner(nil),
+			wantCall: true,
+
+			wantEvent: func() *wal.Event {
+				e := testEvent("I", testColumns())
+				e.Route = testRoute
+				return e
+			}(),
+			wantPending: []*processedRow{
+				{position: replication.LSN(22899240), table: testOutboxTable, id: float64(1)},
+			},
+		},
+		{
+			name: "ok - outbox insert with null payload and numeric aggregate id",
+			event: testEvent("I", []wal.Column{
+				{Name: "id", Value: float64(1)},
+				{Name: "aggregate_id", Value: float64(2)},
+				{Name: "event_type", Value: "deleted"},
+				{Name: "payload", Value: nil},
+				{Name: "aggregate_type", Value: "order"},
+				{Name: "trace_id", Value: nil},
+			}),
+			wantCall: true,
+
+			wantEvent: func() *wal.Event {
+				e := testEvent("I", []wal.Column{
+					{Name: "id", Value: float64(1)},
+					{Name: "aggregate_id", Value: float64(2)},
+					{Name: "event_type", Value: "deleted"},
+					{Name: "payload", Value: nil},
+					{Name: "aggregate_type", Value: "order"},
+					{Name: "trace_id", Value: nil},
+				})
+				e.Route = &wal.Route{
+					Topic: "outbox.order",
+					Key:   []byte("2"),
+					Headers: map[string]string{
+						"aggregate_id": "2",
+						"event_type":   "deleted",
+						"trace_id":     "",
+					},
+					Payload: []byte("null"),
+				}
+				return e
+			}(),
+		},
+		{
+			name:     "ok - outbox update skipped",
+			event:    testEvent("U", testColumns()),
+			wantCall: false,
+		},
+		{
+			name:     "ok - outbox delete skipped",
+			event:    testEvent("D", testColumns()),
+			wantCall: false,
+		},
+		{
+			name: "ok - non outbox table",
+			event: &wal.Event{
+				Data: &wal.Data{Action: "I", Schema: "public", Table: "orders"},
+			},
+			wantCall: true,
+
+			wantEvent: &wal.Event{
+				Data: &wal.Data{Action: "I", Schema: "public", Table: "orders"},
+			},
+		},
+		{
+			name: "ok - transaction boundary",
+			event: &wal.Event{
+				Data: &wal.Data{Action: "B"},
+			},
+			wantCall: true,
+
+			wantEvent: &wal.Event{
+				Data: &wal.Data{Action: "B"},
+			},
+		},
+		{
+			name: "ok - keep alive",
+			event: &wal.Event{
+				CommitPosition: wal.CommitPosition(testLSNStr),
+			},
+			wantCall: true,
+
+			wantEvent: &wal.Event{
+				CommitPosition: wal.CommitPosition(testLSNStr),
+			},
+		},
+		{
+			name:     "error - processing event",
+			event:    testEvent("I", testColumns()),
+			procErr:  errTest,
+			wantCall: true,
+
+			wantEvent: func() *wal.Event {
+				e := testEvent("I", testColumns())
+				e.Route = testRoute
+				return e
+			}(),
+			wantErr: errTest,
+		},
+		{
+			name: "error - missing payload column",
+			event: testEvent("I", []wal.Column{
+				{Name: "aggregate_id", Value: "order-1"},
+			}),
+			wantCall: false,
+			wantErr:  errMissingColumn,
+		},
+		{
+			name: "error - missing topic column",
+			event: testEvent("I", []wal.Column{
+				{Name: "id", Value: float64(1)},
+				{Name: "aggregate_id", Value: "order-1"},
+				{Name: "event_type", Value: "order_created"},
+				{Name: "trace_id", Value: "a"},
+				{Name: "payload", Value: `{}`},
+			}),
+			wantCall: false,
+			wantErr:  errMissingColumn,
+		},
+		{
+			name: "error - unsupported aggregate id value",
+			event: testEvent("I", []wal.Column{
+				{Name: "aggregate_id", Value: []any{"a"}},
+				{Name: "payload", Value: `{}`},
+			}),
+			wantCall: false,
+			wantErr:  errUnsupportedColumn,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			called := false
+			o := &Outbox{
+				processor: &mocks.Processor{
+					ProcessWALEventFn: func(ctx context.Context, event *wal.Event) error {
+						called = true
+						require.Equal(t, tc.wantEvent, event)
+						return tc.procErr
+					},
+				},
+				tables: map[string]*outboxTable{
+					"public.outbox": testOutboxTable,
+				},
+				cleaner: tc.cleaner,
+				logger:  loglib.NewNoopLogger(),
+			}
+
+			err := o.ProcessWALEvent(context.Background(), tc.event)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantCall, called)
+			if tc.cleaner != nil {
+				require.Equal(t, tc.wantPending, tc.cleaner.pending)
+			}
+		})
+	}
+}
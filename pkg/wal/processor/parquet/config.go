@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package parquet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+)
+
+type Config struct {
+	// Dir is the root directory where the parquet files are written. Files
+	// are partitioned by schema, table and date. It will be created if it
+	// doesn't exist.
+	Dir string
+	// Compression codec of the parquet files. One of none, snappy, gzip,
+	// zstd. Defaults to snappy.
+	Compression Compression
+	// MaxFileRows is the number of rows at which a file is finalised and a new
+	// one is started. Defaults to 1000000.
+	MaxFileRows int64
+	// RowGroupSize is the max number of rows in a parquet row group. Defaults
+	// to 100000.
+	RowGroupSize int64
+	// RotationInterval is the time after which all the open files are
+	// finalised. The positions of the events are only checkpointed once all
+	// the files containing them have been finalised, so this also bounds the
+	// replication lag. Defaults to 15m.
+	RotationInterval time.Duration
+	Batch            batch.Config
+}
+
+type Compression string
+
+const (
+	CompressionNone   Compression = "none"
+	CompressionSnappy Compression = "snappy"
+	CompressionGzip   Compression = "gzip"
+	CompressionZstd   Compression = "zstd"
+)
+
+const (
+	defaultMaxFileRows      = int64(1000000)
+	defaultRowGroupSize     = int64(100000)
+	defaultRotationInterval = 15 * time.Minute
+)
+
+func (c *Config) GetCompression() Compression {
+	if c.Compression != "" {
+		return c.Compression
+	}
+	return CompressionSnappy
+}
+
+func (c *Config) GetMaxFileRows() int64 {
+	if c.MaxFileRows > 0 {
+		return c.MaxFileRows
+	}
+	return defaultMaxFileRows
+}
+
+func (c *Config) GetRowGroupSize() int64 {
+	if c.RowGroupSize > 0 {
+		return c.RowGroupSize
+	}
+	return defaultRowGroupSize
+}
+
+func (c *Config) GetRotationInterval() time.Duration {
+	if c.RotationInterval > 0 {
+		return c.RotationInterval
+	}
+	return defaultRotationInterval
+}
+
+func (c *Config) IsValid() error {
+	if c.Dir == "" {
+		return errMissingDir
+	}
+
+	switch c.GetCompression() {
+	case CompressionNone, CompressionSnappy, CompressionGzip, CompressionZstd:
+	default:
+		return fmt.Errorf("%w: %s", errUnsupportedCompression, c.Compression)
+	}
+
+	return nil
+}
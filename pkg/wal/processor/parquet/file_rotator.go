@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package parquet
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+	loglib "github.com/xataio/pgstream/pkg/log"
+)
+
+// fileRotator manages the parquet files being written, one per table. Parquet
+// files are only readable once their footer has been written, so the files are
+// written with an in progress suffix and renamed once they're finalised.
+type fileRotator struct {
+	logger           loglib.Logger
+	dir              string
+	codec            compress.Codec
+	maxFileRows      int64
+	rowGroupSize     int64
+	rotationInterval time.Duration
+	files            map[string]*tableFile
+	now              func() time.Time
+}
+
+type tableFile struct {
+	// key is the schema qualified table name
+	key      string
+	table    *tableSchema
+	date     string
+	path     string
+	file     *os.File
+	writer   *parquet.Writer
+	openedAt time.Time
+	rows     int64
+}
+
+const (
+	fileExtension    = ".parquet"
+	inProgressSuffix = ".tmp"
+	datePartition    = "date="
+)
+
+func newFileRotator(cfg *Config, logger loglib.Logger) (*fileRotator, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating directory %s: %w", cfg.Dir, err)
+	}
+
+	r := &fileRotator{
+		logger:           logger,
+		dir:              cfg.Dir,
+		maxFileRows:      cfg.GetMaxFileRows(),
+		rowGroupSize:     cfg.GetRowGroupSize(),
+		rotationInterval: cfg.GetRotationInterval(),
+		files:            map[string]*tableFile{},
+		now:              time.Now,
+	}
+
+	switch cfg.GetCompression() {
+	case CompressionNone:
+		r.codec = &parquet.Uncompressed
+	case CompressionGzip:
+		r.codec = &parquet.Gzip
+	case CompressionZstd:
+		r.codec = &parquet.Zstd
+	default:
+		r.codec = &parquet.Snappy
+	}
+
+	if err := r.recover(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// get returns the open file for the table on input. A new file is started if
+// there isn't one, or if the open file was written with a different table
+// schema or for a different date.
+func (r *fileRotator) get(schema, table string, tableSchema *tableSchema, date string) (*tableFile, error) {
+	key := schema + "." + table
+	if f, found := r.files[key]; found {
+		if f.table == tableSchema && f.date == date {
+			return f, nil
+		}
+		if err := r.finalise(f); err != nil {
+			return nil, err
+		}
+	}
+
+	partitionDir := filepath.Join(r.dir, schema, table, datePartition+date)
+	if err := os.MkdirAll(partitionDir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating directory %s: %w", partitionDir, err)
+	}
+
+	openedAt := r.now().UTC()
+	fileName := fmt.Sprintf("%s-v%d-%s%s%s", table, tableSchema.version, openedAt.Format("20060102T150405.000000000Z"), fileExtension, inProgressSuffix)
+	path := filepath.Join(partitionDir, fileName)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("creating file: %w", err)
+	}
+
+	f := &tableFile{
+		key:   key,
+		table: tableSchema,
+		date:  date,
+		path:  path,
+		file:  file,
+		writer: parquet.NewWriter(file,
+			tableSchema.schema,
+			parquet.Compression(r.codec),
+			parquet.MaxRowsPerRowGroup(r.rowGroupSize)),
+		openedAt: openedAt,
+	}
+	r.files[key] = f
+	return f, nil
+}
+
+// write writes the row on input to the file. The file is finalised once it
+// reaches the configured max rows.
+func (r *fileRotator) write(f *tableFile, row parquet.Row) error {
+	if _, err := f.writer.WriteRows([]parquet.Row{row}); err != nil {
+		return fmt.Errorf("writing to %s: %w", f.path, err)
+	}
+	f.rows++
+
+	if f.rows >= r.maxFileRows {
+		return r.finalise(f)
+	}
+	return nil
+}
+
+// rotateExpired finalises all the open files once the oldest of them has
+// reached the configured rotation interval. All files are finalised together
+// so that the processed positions can be checkpointed.
+func (r *fileRotator) rotateExpired() error {
+	for _, f := range r.files {
+		if r.now().Sub(f.openedAt) >= r.rotationInterval {
+			return r.close()
+		}
+	}
+	return nil
+}
+
+// close finalises all the open files.
+func (r *fileRotator) close() error {
+	var errs error
+	for _, f := range r.files {
+		if err := r.finalise(f); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	return errs
+}
+
+// hasOpenFiles returns true if there are files that haven't been finalised.
+func (r *fileRotator) hasOpenFiles() bool {
+	return len(r.files) > 0
+}
+
+// finalise writes the parquet footer and syncs the file before removing the
+// in progress suffix.
+func (r *fileRotator) finalise(f *tableFile) error {
+	delete(r.files, f.key)
+
+	if err := f.writer.Close(); err != nil {
+		f.file.Close()
+		return fmt.Errorf("writing parquet footer to %s: %w", f.path, err)
+	}
+	if err := f.file.Sync(); err != nil {
+		f.file.Close()
+		return fmt.Errorf("syncing %s: %w", f.path, err)
+	}
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("closing %s: %w", f.path, err)
+	}
+
+	finalPath := strings.TrimSuffix(f.path, inProgressSuffix)
+	if err := os.Rename(f.path, finalPath); err != nil {
+		return fmt.Errorf("renaming %s: %w", f.path, err)
+	}
+	if err := syncDir(filepath.Dir(f.path)); err != nil {
+		return err
+	}
+
+	r.logger.Debug("parquet file finalised", loglib.Fields{"file": finalPath, "rows": f.rows})
+	return nil
+}
+
+// recover removes the in progress files left behind by a previous run that
+// didn't shut down cleanly. They can't be read without a footer, and their
+// events haven't been checkpointed, so they will be processed again.
+func (r *fileRotator) recover() error {
+	return filepath.WalkDir(r.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, fileExtension+inProgressSuffix) {
+			return nil
+		}
+		r.logger.Warn(nil, "removing incomplete parquet file from previous run", loglib.Fields{"file": path})
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("removing %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening directory %s: %w", path, err)
+	}
+	defer dir.Close()
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("syncing directory %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,386 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package parquet
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/xataio/pgstream/internal/json"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// tableSchema is the parquet schema of a table. It's derived from the table
+// definition in the schema log, or from the event columns when the schema log
+// entry is not known.
+type tableSchema struct {
+	schema *parquet.Schema
+	// version is the schema log version the table definition belongs to, 0
+	// when it's derived from the event columns
+	version int64
+	// columns maps the column names to their position in the parquet rows
+	columns    map[string]int
+	converters []valueConverter
+}
+
+type valueConverter func(any) (parquet.Value, error)
+
+const (
+	actionColumn    = "_action"
+	timestampColumn = "_timestamp"
+	lsnColumn       = "_lsn"
+)
+
+var (
+	errUnsupportedValue = errors.New("unsupported value")
+
+	// typeModifierRegex matches the type modifiers, i.e. the length in
+	// varchar(255) or the precision in timestamp(3) without time zone
+	typeModifierRegex = regexp.MustCompile(`\(\d+(,\s*\d+)?\)`)
+
+	timestampFormats = []string{
+		"2006-01-02 15:04:05.999999999Z07:00",
+		"2006-01-02 15:04:05.999999999Z07",
+		"2006-01-02 15:04:05.999999999",
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05.999999999",
+	}
+)
+
+// newTableSchema returns the parquet schema for the table columns on input.
+// The pgstream metadata columns (action, commit timestamp and LSN) are added to
+// every table. All columns are optional, since delete events only contain the
+// identity columns and truncate events don't contain any.
+func newTableSchema(name string, version int64, columns []schemalog.Column) *tableSchema {
+	group := parquet.Group{
+		actionColumn:    parquet.Optional(parquet.String()),
+		timestampColumn: parquet.Optional(parquet.Timestamp(parquet.Microsecond)),
+		lsnColumn:       parquet.Optional(parquet.String()),
+	}
+	converters := map[string]valueConverter{
+		actionColumn:    toText,
+		timestampColumn: toTimestamp,
+		lsnColumn:       toText,
+	}
+	for _, c := range columns {
+		if _, found := group[c.Name]; found {
+			continue
+		}
+		node, converter := pgTypeToParquet(c.DataType)
+		group[c.Name] = parquet.Optional(node)
+		converters[c.Name] = converter
+	}
+
+	s := &tableSchema{
+		schema:  parquet.NewSchema(name, group),
+		version: version,
+		columns: make(map[string]int, len(group)),
+	}
+	// the group fields are sorted by name
+	for i, field := range s.schema.Fields() {
+		s.columns[field.Name()] = i
+		s.converters = append(s.converters, converters[field.Name()])
+	}
+	return s
+}
+
+// newTableSchemaFromEvent returns the parquet schema derived from the types of
+// the event columns.
+func newTableSchemaFromEvent(name string, data *wal.Data) *tableSchema {
+	columns := make([]schemalog.Column, 0, len(data.Columns))
+	for _, c := range data.Columns {
+		columns = append(columns, schemalog.Column{Name: c.Name, DataType: c.Type, Nullable: true})
+	}
+	return newTableSchema(name, 0, columns)
+}
+
+// containsColumns returns true if all the columns on input are part of the
+// table schema.
+func (s *tableSchema) containsColumns(columns []wal.Column) bool {
+	for _, c := range columns {
+		if _, found := s.columns[c.Name]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+// row returns the parquet row for the event on input. Columns that are not
+// part of the schema are ignored. Values that can't be converted to the column
+// type are set to null, and the conversion errors are returned.
+func (s *tableSchema) row(data *wal.Data) (parquet.Row, error) {
+	row := make(parquet.Row, len(s.converters))
+	for i := range row {
+		row[i] = parquet.NullValue().Level(0, 0, i)
+	}
+
+	var errs error
+	setValue := func(name string, value any) {
+		i, found := s.columns[name]
+		if !found || value == nil {
+			return
+		}
+		v, err := s.converters[i](value)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("column %s: %w", name, err))
+			return
+		}
+		row[i] = v.Level(0, 1, i)
+	}
+
+	setValue(actionColumn, data.Action)
+	setValue(lsnColumn, data.LSN)
+	if data.Timestamp != "" {
+		setValue(timestampColumn, data.Timestamp)
+	}
+
+	columns := data.Columns
+	if data.Action == "D" && len(data.Identity) > 0 {
+		columns = data.Identity
+	}
+	for _, c := range columns {
+		setValue(c.Name, c.Value)
+	}
+
+	return row, errs
+}
+
+// pgTypeToParquet returns the parquet node and value converter for the
+// postgres type on input. Numeric values are kept as strings to avoid losing
+// precision, and arrays are stored using their textual representation. Types
+// without a parquet equivalent are stored as strings.
+func pgTypeToParquet(pgType string) (parquet.Node, valueConverter) {
+	pgType = strings.ToLower(strings.TrimSpace(pgType))
+	if strings.HasSuffix(pgType, "[]") {
+		return parquet.String(), toText
+	}
+
+	switch typeModifierRegex.ReplaceAllString(pgType, "") {
+	case "boolean", "bool":
+		return parquet.Leaf(parquet.BooleanType), toBoolean
+	case "smallint", "int2", "integer", "int", "int4", "smallserial", "serial":
+		return parquet.Int(32), toInt32
+	case "bigint", "int8", "bigserial":
+		return parquet.Int(64), toInt64
+	case "real", "float4":
+		return parquet.Leaf(parquet.FloatType), toFloat
+	case "double precision", "float8":
+		return parquet.Leaf(parquet.DoubleType), toDouble
+	case "date":
+		return parquet.Date(), toDate
+	case "timestamp", "timestamp without time zone", "timestamptz", "timestamp with time zone":
+		return parquet.Timestamp(parquet.Microsecond), toTimestamp
+	case "json", "jsonb":
+		return parquet.JSON(), toJSON
+	case "bytea":
+		return parquet.Leaf(parquet.ByteArrayType), toBytes
+	default:
+		return parquet.String(), toText
+	}
+}
+
+func toBoolean(v any) (parquet.Value, error) {
+	switch b := v.(type) {
+	case bool:
+		return parquet.BooleanValue(b), nil
+	case string:
+		parsed, err := strconv.ParseBool(b)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.BooleanValue(parsed), nil
+	default:
+		return parquet.Value{}, fmt.Errorf("%w: %T for boolean", errUnsupportedValue, v)
+	}
+}
+
+func toInt32(v any) (parquet.Value, error) {
+	i, err := toInteger(v)
+	if err != nil {
+		return parquet.Value{}, err
+	}
+	if i < math.MinInt32 || i > math.MaxInt32 {
+		return parquet.Value{}, fmt.Errorf("%w: %d out of range for int32", errUnsupportedValue, i)
+	}
+	return parquet.Int32Value(int32(i)), nil
+}
+
+func toInt64(v any) (parquet.Value, error) {
+	i, err := toInteger(v)
+	if err != nil {
+		return parquet.Value{}, err
+	}
+	return parquet.Int64Value(i), nil
+}
+
+func toInteger(v any) (int64, error) {
+	switch i := v.(type) {
+	case int:
+		return int64(i), nil
+	case int8:
+		return int64(i), nil
+	case int16:
+		return int64(i), nil
+	case int32:
+		return int64(i), nil
+	case int64:
+		return i, nil
+	case uint8:
+		return int64(i), nil
+	case uint16:
+		return int64(i), nil
+	case uint32:
+		return int64(i), nil
+	case float64:
+		// numbers decoded from json are float64
+		if i != math.Trunc(i) {
+			return 0, fmt.Errorf("%w: %v for integer", errUnsupportedValue, i)
+		}
+		return int64(i), nil
+	case string:
+		return strconv.ParseInt(i, 10, 64)
+	default:
+		return 0, fmt.Errorf("%w: %T for integer", errUnsupportedValue, v)
+	}
+}
+
+func toFloat(v any) (parquet.Value, error) {
+	f, err := toFloatingPoint(v)
+	if err != nil {
+		return parquet.Value{}, err
+	}
+	return parquet.FloatValue(float32(f)), nil
+}
+
+func toDouble(v any) (parquet.Value, error) {
+	f, err := toFloatingPoint(v)
+	if err != nil {
+		return parquet.Value{}, err
+	}
+	return parquet.DoubleValue(f), nil
+}
+
+func toFloatingPoint(v any) (float64, error) {
+	switch f := v.(type) {
+	case float32:
+		return float64(f), nil
+	case float64:
+		return f, nil
+	case string:
+		return strconv.ParseFloat(f, 64)
+	default:
+		i, err := toInteger(v)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %T for float", errUnsupportedValue, v)
+		}
+		return float64(i), nil
+	}
+}
+
+func toDate(v any) (parquet.Value, error) {
+	var t time.Time
+	switch d := v.(type) {
+	case time.Time:
+		t = d
+	case string:
+		var err error
+		if t, err = time.Parse(time.DateOnly, d); err != nil {
+			return parquet.Value{}, err
+		}
+	default:
+		return parquet.Value{}, fmt.Errorf("%w: %T for date", errUnsupportedValue, v)
+	}
+	// dates are stored as the number of days since the unix epoch
+	days := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60)
+	return parquet.Int32Value(int32(days)), nil
+}
+
+func toTimestamp(v any) (parquet.Value, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return parquet.Value{}, err
+	}
+	return parquet.Int64Value(t.UnixMicro()), nil
+}
+
+func toTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		for _, format := range timestampFormats {
+			if parsed, err := time.Parse(format, t); err == nil {
+				return parsed, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%w: unrecognised timestamp format %q", errUnsupportedValue, t)
+	default:
+		return time.Time{}, fmt.Errorf("%w: %T for timestamp", errUnsupportedValue, v)
+	}
+}
+
+func toJSON(v any) (parquet.Value, error) {
+	switch j := v.(type) {
+	case string:
+		return parquet.ByteArrayValue([]byte(j)), nil
+	case []byte:
+		return parquet.ByteArrayValue(j), nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.ByteArrayValue(b), nil
+	}
+}
+
+func toBytes(v any) (parquet.Value, error) {
+	switch b := v.(type) {
+	case []byte:
+		return parquet.ByteArrayValue(b), nil
+	case string:
+		// bytea values are sent using the postgres hex format
+		if hexValue, found := strings.CutPrefix(b, `\x`); found {
+			decoded, err := hex.DecodeString(hexValue)
+			if err != nil {
+				return parquet.Value{}, err
+			}
+			return parquet.ByteArrayValue(decoded), nil
+		}
+		return parquet.ByteArrayValue([]byte(b)), nil
+	default:
+		return parquet.Value{}, fmt.Errorf("%w: %T for bytea", errUnsupportedValue, v)
+	}
+}
+
+func toText(v any) (parquet.Value, error) {
+	switch s := v.(type) {
+	case string:
+		return parquet.ByteArrayValue([]byte(s)), nil
+	case []byte:
+		return parquet.ByteArrayValue(s), nil
+	case float64:
+		return parquet.ByteArrayValue([]byte(strconv.FormatFloat(s, 'f', -1, 64))), nil
+	case fmt.Stringer:
+		return parquet.ByteArrayValue([]byte(s.String())), nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		// keep plain strings unquoted
+		var str string
+		if err := json.Unmarshal(b, &str); err == nil {
+			return parquet.ByteArrayValue([]byte(str)), nil
+		}
+		return parquet.ByteArrayValue(b), nil
+	}
+}
@@ -0,0 +1,177 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package parquet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func TestPgTypeToParquet(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		pgType string
+		value  any
+
+		wantType  parquet.Type
+		wantValue parquet.Value
+		wantErr   error
+	}{
+		{
+			name:      "boolean",
+			pgType:    "boolean",
+			value:     true,
+			wantType:  parquet.BooleanType,
+			wantValue: parquet.BooleanValue(true),
+		},
+		{
+			name:      "integer from json number",
+			pgType:    "integer",
+			value:     float64(42),
+			wantType:  parquet.Int(32).Type(),
+			wantValue: parquet.Int32Value(42),
+		},
+		{
+			name:      "bigint",
+			pgType:    "bigint",
+			value:     int64(1) << 40,
+			wantType:  parquet.Int(64).Type(),
+			wantValue: parquet.Int64Value(1 << 40),
+		},
+		{
+			name:      "double precision from string",
+			pgType:    "double precision",
+			value:     "1.5",
+			wantType:  parquet.DoubleType,
+			wantValue: parquet.DoubleValue(1.5),
+		},
+		{
+			name:      "numeric kept as string",
+			pgType:    "numeric(10,2)",
+			value:     float64(12.5),
+			wantType:  parquet.String().Type(),
+			wantValue: parquet.ByteArrayValue([]byte("12.5")),
+		},
+		{
+			name:      "varchar with modifier",
+			pgType:    "character varying(255)",
+			value:     "alice",
+			wantType:  parquet.String().Type(),
+			wantValue: parquet.ByteArrayValue([]byte("alice")),
+		},
+		{
+			name:      "timestamp with time zone",
+			pgType:    "timestamp(3) with time zone",
+			value:     "2024-01-02 03:04:05.123+01",
+			wantType:  parquet.Timestamp(parquet.Microsecond).Type(),
+			wantValue: parquet.Int64Value(time.Date(2024, 1, 2, 2, 4, 5, 123000000, time.UTC).UnixMicro()),
+		},
+		{
+			name:      "date",
+			pgType:    "date",
+			value:     "1970-01-11",
+			wantType:  parquet.Date().Type(),
+			wantValue: parquet.Int32Value(10),
+		},
+		{
+			name:      "jsonb",
+			pgType:    "jsonb",
+			value:     map[string]any{"a": 1},
+			wantType:  parquet.JSON().Type(),
+			wantValue: parquet.ByteArrayValue([]byte(`{"a":1}`)),
+		},
+		{
+			name:      "bytea",
+			pgType:    "bytea",
+			value:     `\x0102`,
+			wantType:  parquet.ByteArrayType,
+			wantValue: parquet.ByteArrayValue([]byte{1, 2}),
+		},
+		{
+			name:      "array",
+			pgType:    "integer[]",
+			value:     "{1,2}",
+			wantType:  parquet.String().Type(),
+			wantValue: parquet.ByteArrayValue([]byte("{1,2}")),
+		},
+		{
+			name:     "error - integer out of range",
+			pgType:   "integer",
+			value:    float64(1 << 40),
+			wantType: parquet.Int(32).Type(),
+			wantErr:  errUnsupportedValue,
+		},
+		{
+			name:     "error - unsupported boolean value",
+			pgType:   "boolean",
+			value:    1,
+			wantType: parquet.BooleanType,
+			wantErr:  errUnsupportedValue,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			node, converter := pgTypeToParquet(tc.pgType)
+			require.Equal(t, tc.wantType.String(), node.Type().String())
+
+			value, err := converter(tc.value)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantErr != nil {
+				return
+			}
+			require.True(t, parquet.Equal(tc.wantValue, value))
+		})
+	}
+}
+
+func TestTableSchema_row(t *testing.T) {
+	t.Parallel()
+
+	s := newTableSchema("users", 1, []schemalog.Column{
+		{Name: "id", DataType: "integer"},
+		{Name: "age", DataType: "integer"},
+	})
+
+	row, err := s.row(&wal.Data{
+		Action: "D",
+		LSN:    "0/1",
+		Columns: []wal.Column{
+			{Name: "id", Value: float64(1)},
+			{Name: "age", Value: float64(30)},
+		},
+		Identity: []wal.Column{
+			{Name: "id", Value: float64(1)},
+			{Name: "unknown", Value: "ignored"},
+		},
+	})
+	require.NoError(t, err)
+
+	// columns are sorted by name: _action, _lsn, _timestamp, age, id
+	require.Len(t, row, 5)
+	require.Equal(t, "D", row[0].String())
+	require.Equal(t, "0/1", row[1].String())
+	require.True(t, row[2].IsNull())
+	require.True(t, row[3].IsNull())
+	require.Equal(t, int32(1), row[4].Int32())
+	for i, v := range row {
+		require.Equal(t, i, v.Column())
+	}
+
+	// values not compatible with the column type are set to null
+	row, err = s.row(&wal.Data{
+		Action:  "I",
+		Columns: []wal.Column{{Name: "id", Value: "not a number"}},
+	})
+	require.Error(t, err)
+	require.True(t, row[4].IsNull())
+}
@@ -0,0 +1,307 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package parquet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/xataio/pgstream/internal/json"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/checkpointer"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+)
+
+// BatchWriter is a processor that writes the row events to parquet files, one
+// per table, partitioned by date. The parquet schema of each table is derived
+// from its schema log definition, and a new file is started whenever the
+// schema version changes.
+type BatchWriter struct {
+	logger      loglib.Logger
+	batchSender batchSender
+	files       *fileRotator
+
+	// optional checkpointer callback to mark what was safely processed
+	checkpointer checkpointer.Checkpoint
+	// pendingPositions contains the positions of the processed events that
+	// can't be checkpointed until the files containing them are finalised
+	pendingPositions []wal.CommitPosition
+
+	// tablesByID and tablesByName contain the table schemas of the latest
+	// schema log entries, indexed by the table pgstream id and by the schema
+	// qualified table name respectively
+	tablesByID   map[string]*tableSchema
+	tablesByName map[string]*tableSchema
+
+	serialiser func(any) ([]byte, error)
+}
+
+type Option func(*BatchWriter)
+
+type batchSender interface {
+	SendMessage(context.Context, *batch.WALMessage[*message]) error
+	Close()
+}
+
+// message is the batch message for a WAL event. The serialised event size is
+// used to account for the message size in the batch.
+type message struct {
+	data *wal.Data
+	size int
+}
+
+var (
+	errMissingDir             = errors.New("missing parquet target directory")
+	errUnsupportedCompression = errors.New("unsupported parquet compression")
+)
+
+// NewBatchWriter returns a processor that writes the row events to parquet
+// files in the configured directory. Incomplete files left behind by a
+// previous run are removed on creation.
+func NewBatchWriter(ctx context.Context, config *Config, opts ...Option) (*BatchWriter, error) {
+	if err := config.IsValid(); err != nil {
+		return nil, err
+	}
+
+	w := &BatchWriter{
+		logger:       loglib.NewNoopLogger(),
+		tablesByID:   map[string]*tableSchema{},
+		tablesByName: map[string]*tableSchema{},
+		serialiser:   json.Marshal,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	var err error
+	w.files, err = newFileRotator(config, w.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	w.batchSender, err = batch.NewSender(ctx, &config.Batch, w.sendBatch, w.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func WithLogger(l loglib.Logger) Option {
+	return func(w *BatchWriter) {
+		w.logger = loglib.NewLogger(l).WithFields(loglib.Fields{
+			loglib.ModuleField: "parquet_batch_writer",
+		})
+	}
+}
+
+func WithCheckpoint(c checkpointer.Checkpoint) Option {
+	return func(w *BatchWriter) {
+		w.checkpointer = c
+	}
+}
+
+// ProcessWALEvent is called on every new message from the wal. It can be called
+// concurrently.
+func (w *BatchWriter) ProcessWALEvent(ctx context.Context, walEvent *wal.Event) (retErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			w.logger.Panic("[PANIC] Panic while processing replication event", loglib.Fields{
+				"wal_data":    walEvent,
+				"panic":       r,
+				"stack_trace": debug.Stack(),
+			})
+
+			retErr = fmt.Errorf("parquet batch writer: understanding event: %v", r)
+		}
+	}()
+
+	msg := &message{}
+	if walEvent.Data != nil && isWritable(walEvent.Data) {
+		value, err := w.serialiser(walEvent.Data)
+		if err != nil {
+			return fmt.Errorf("marshalling event: %w", err)
+		}
+		msg = &message{
+			data: walEvent.Data,
+			size: len(value),
+		}
+	}
+
+	return w.batchSender.SendMessage(ctx, batch.NewWALMessage(msg, walEvent.CommitPosition))
+}
+
+func (w *BatchWriter) Name() string {
+	return "parquet-batch-writer"
+}
+
+func (w *BatchWriter) Close() error {
+	w.batchSender.Close()
+	return w.files.close()
+}
+
+func (w *BatchWriter) sendBatch(ctx context.Context, batch *batch.Batch[*message]) error {
+	messages := batch.GetMessages()
+	w.logger.Debug("parquet batch writer: writing message batch", loglib.Fields{
+		"batch_size":             len(messages),
+		"batch_commit_positions": len(batch.GetCommitPositions()),
+	})
+
+	for _, msg := range messages {
+		if processor.IsSchemaLogEvent(msg.data) {
+			if err := w.updateTableSchemas(msg.data); err != nil {
+				return fmt.Errorf("parquet batch writer: %w", err)
+			}
+			continue
+		}
+
+		if err := w.writeRow(msg.data); err != nil {
+			return fmt.Errorf("parquet batch writer: %w", err)
+		}
+	}
+
+	if err := w.files.rotateExpired(); err != nil {
+		return fmt.Errorf("parquet batch writer: %w", err)
+	}
+
+	// the positions can only be checkpointed once all the files containing
+	// the events have been finalised
+	w.pendingPositions = append(w.pendingPositions, batch.GetCommitPositions()...)
+	if w.files.hasOpenFiles() {
+		return nil
+	}
+
+	if w.checkpointer != nil && len(w.pendingPositions) > 0 {
+		if err := w.checkpointer(ctx, w.pendingPositions); err != nil {
+			w.logger.Warn(err, "parquet batch writer: error updating commit position")
+		}
+	}
+	w.pendingPositions = nil
+
+	return nil
+}
+
+// updateTableSchemas replaces the table schemas of the schema log entry
+// schema. Files written with a previous version will be finalised when the
+// next row for the table is written.
+func (w *BatchWriter) updateTableSchemas(data *wal.Data) error {
+	logEntry, err := processor.WalDataToLogEntry(data)
+	if err != nil {
+		return err
+	}
+
+	schemaPrefix := logEntry.SchemaName + "."
+	for key, table := range w.tablesByName {
+		if strings.HasPrefix(key, schemaPrefix) {
+			delete(w.tablesByName, key)
+			for id, t := range w.tablesByID {
+				if t == table {
+					delete(w.tablesByID, id)
+				}
+			}
+		}
+	}
+
+	if logEntry.Schema.Dropped {
+		return nil
+	}
+
+	for _, table := range logEntry.Schema.Tables {
+		tableSchema := newTableSchema(table.Name, logEntry.Version, table.Columns)
+		w.tablesByName[schemaPrefix+table.Name] = tableSchema
+		if table.PgstreamID != "" {
+			w.tablesByID[table.PgstreamID] = tableSchema
+		}
+	}
+
+	w.logger.Debug("parquet batch writer: updated table schemas", loglib.Fields{
+		"schema":  logEntry.SchemaName,
+		"version": logEntry.Version,
+	})
+	return nil
+}
+
+func (w *BatchWriter) writeRow(data *wal.Data) error {
+	tableSchema := w.tableSchema(data)
+
+	row, err := tableSchema.row(data)
+	if err != nil {
+		w.logger.Warn(err, "parquet batch writer: values not compatible with column types written as null", loglib.Fields{
+			"schema": data.Schema,
+			"table":  data.Table,
+			"lsn":    data.LSN,
+		})
+	}
+
+	f, err := w.files.get(data.Schema, data.Table, tableSchema, partitionDate(data))
+	if err != nil {
+		return err
+	}
+	return w.files.write(f, row)
+}
+
+// tableSchema returns the parquet schema for the event table. The table is
+// looked up by its pgstream id when available, since the schema log entries
+// keep the source table names. If the table is not part of the known schema
+// log entries (i.e. events processed after a restart, before any schema
+// change), the schema is derived from the event columns.
+func (w *BatchWriter) tableSchema(data *wal.Data) *tableSchema {
+	if id := data.Metadata.TablePgstreamID; id != "" {
+		if t, found := w.tablesByID[id]; found {
+			return t
+		}
+	}
+
+	key := data.Schema + "." + data.Table
+	t, found := w.tablesByName[key]
+	if found && (t.version > 0 || t.containsColumns(data.Columns)) {
+		return t
+	}
+
+	w.logger.Debug("parquet batch writer: no schema log entry found for table, deriving schema from event columns", loglib.Fields{
+		"schema": data.Schema,
+		"table":  data.Table,
+	})
+	t = newTableSchemaFromEvent(data.Table, data)
+	w.tablesByName[key] = t
+	return t
+}
+
+// partitionDate returns the date partition of the event, based on its commit
+// timestamp. The current date is used if the event has no timestamp.
+func partitionDate(data *wal.Data) string {
+	t, err := toTime(data.Timestamp)
+	if err != nil {
+		t = time.Now()
+	}
+	return t.UTC().Format(time.DateOnly)
+}
+
+// isWritable returns whether the data on input is processed by the writer.
+// Only row events and schema log entries are processed.
+func isWritable(data *wal.Data) bool {
+	if processor.IsSchemaLogEvent(data) {
+		return data.IsInsert()
+	}
+	switch data.Action {
+	case "I", "U", "D", "T":
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *message) Size() int {
+	return m.size
+}
+
+func (m *message) IsEmpty() bool {
+	return m == nil || m.data == nil
+}
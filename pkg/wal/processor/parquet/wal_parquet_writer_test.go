@@ -0,0 +1,337 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package parquet
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/internal/json"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+	batchmocks "github.com/xataio/pgstream/pkg/wal/processor/batch/mocks"
+)
+
+var errTest = errors.New("oh noes")
+
+const (
+	testTimestamp = "2024-01-02 03:04:05.000000+00"
+	testTableID   = "table-1"
+)
+
+func TestBatchWriter_ProcessWALEvent(t *testing.T) {
+	t.Parallel()
+
+	testData := &wal.Data{
+		Action: "I",
+		Schema: "public",
+		Table:  "users",
+	}
+	testBytes := []byte("test")
+
+	tests := []struct {
+		name       string
+		walEvent   *wal.Event
+		serialiser func(any) ([]byte, error)
+
+		wantMsgs []*batch.WALMessage[*message]
+		wantErr  error
+	}{
+		{
+			name:     "ok",
+			walEvent: &wal.Event{Data: testData, CommitPosition: "1"},
+
+			wantMsgs: []*batch.WALMessage[*message]{
+				batch.NewWALMessage(&message{data: testData, size: len(testBytes)}, "1"),
+			},
+		},
+		{
+			name:     "ok - keep alive",
+			walEvent: &wal.Event{CommitPosition: "1"},
+
+			wantMsgs: []*batch.WALMessage[*message]{
+				batch.NewWALMessage(&message{}, "1"),
+			},
+		},
+		{
+			name: "ok - skips non row events",
+			walEvent: &wal.Event{
+				Data:           &wal.Data{Action: "B"},
+				CommitPosition: "1",
+			},
+
+			wantMsgs: []*batch.WALMessage[*message]{
+				batch.NewWALMessage(&message{}, "1"),
+			},
+		},
+		{
+			name:     "error - serialising event",
+			walEvent: &wal.Event{Data: testData, CommitPosition: "1"},
+			serialiser: func(any) ([]byte, error) {
+				return nil, errTest
+			},
+
+			wantMsgs: []*batch.WALMessage[*message]{},
+			wantErr:  errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			batchSender := batchmocks.NewBatchSender[*message]()
+			w := &BatchWriter{
+				logger:      loglib.NewNoopLogger(),
+				batchSender: batchSender,
+				serialiser:  func(any) ([]byte, error) { return testBytes, nil },
+			}
+			if tc.serialiser != nil {
+				w.serialiser = tc.serialiser
+			}
+
+			go func() {
+				defer batchSender.Close()
+				err := w.ProcessWALEvent(context.Background(), tc.walEvent)
+				require.ErrorIs(t, err, tc.wantErr)
+			}()
+
+			require.Equal(t, tc.wantMsgs, batchSender.GetWALMessages())
+		})
+	}
+}
+
+func TestBatchWriter_sendBatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	checkpoints := []wal.CommitPosition{}
+	w := newTestWriter(t, &Config{Dir: dir, RotationInterval: time.Minute})
+	w.checkpointer = func(_ context.Context, positions []wal.CommitPosition) error {
+		// the files must be finalised before checkpointing
+		require.Empty(t, inProgressFiles(t, dir))
+		checkpoints = append(checkpoints, positions...)
+		return nil
+	}
+	now := time.Now()
+	w.files.now = func() time.Time { return now }
+
+	schemaV1 := newTestSchemaLogEvent(t, 1, schemalog.Column{Name: "id", DataType: "bigint"})
+	schemaV2 := newTestSchemaLogEvent(t, 2,
+		schemalog.Column{Name: "id", DataType: "bigint"},
+		schemalog.Column{Name: "name", DataType: "character varying(255)"})
+
+	// the files are not finalised, positions can't be checkpointed yet
+	require.NoError(t, w.sendBatch(ctx, newTestBatch("1",
+		schemaV1,
+		newTestRowEvent("I", wal.Column{Name: "id", Value: float64(1)}),
+		newTestRowEvent("D", wal.Column{Name: "id", Value: float64(1)}),
+	)))
+	require.Len(t, inProgressFiles(t, dir), 1)
+	require.Empty(t, checkpoints)
+
+	// a new schema version starts a new file
+	now = now.Add(time.Second)
+	require.NoError(t, w.sendBatch(ctx, newTestBatch("2",
+		schemaV2,
+		newTestRowEvent("I", wal.Column{Name: "id", Value: float64(2)}, wal.Column{Name: "name", Value: "alice"}),
+	)))
+	require.Len(t, inProgressFiles(t, dir), 1)
+	require.Empty(t, checkpoints)
+
+	// once the rotation interval is reached all the files are finalised and
+	// the positions checkpointed
+	now = now.Add(time.Minute)
+	require.NoError(t, w.sendBatch(ctx, newTestBatch("3")))
+	require.Empty(t, inProgressFiles(t, dir))
+	require.Equal(t, []wal.CommitPosition{"1", "2", "3"}, checkpoints)
+
+	paths, err := filepath.Glob(filepath.Join(dir, "public", "users", "date=2024-01-02", "*"+fileExtension))
+	require.NoError(t, err)
+	require.Len(t, paths, 2)
+	require.True(t, strings.HasPrefix(filepath.Base(paths[0]), "users-v1-"))
+	require.True(t, strings.HasPrefix(filepath.Base(paths[1]), "users-v2-"))
+
+	wantTimestamp := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixMicro()
+	require.Equal(t, []map[string]any{
+		{"_action": "I", "_lsn": "0/1", "_timestamp": wantTimestamp, "id": int64(1)},
+		{"_action": "D", "_lsn": "0/1", "_timestamp": wantTimestamp, "id": int64(1)},
+	}, readParquetFile(t, paths[0]))
+	require.Equal(t, []map[string]any{
+		{"_action": "I", "_lsn": "0/1", "_timestamp": wantTimestamp, "id": int64(2), "name": "alice"},
+	}, readParquetFile(t, paths[1]))
+}
+
+func TestBatchWriter_tableSchema(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// leftover file from a previous run
+	leftoverDir := filepath.Join(dir, "public", "users", "date=2024-01-01")
+	require.NoError(t, os.MkdirAll(leftoverDir, 0o755))
+	leftover := filepath.Join(leftoverDir, "users-v1-20240101T000000.000000000Z"+fileExtension+inProgressSuffix)
+	require.NoError(t, os.WriteFile(leftover, []byte("PAR1"), 0o644))
+
+	w := newTestWriter(t, &Config{Dir: dir, MaxFileRows: 1})
+	require.NoFileExists(t, leftover)
+
+	now := time.Now()
+	w.files.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	// no schema log entry is known, the schema is derived from the events
+	require.NoError(t, w.sendBatch(ctx, newTestBatch("1",
+		newTestRowEvent("I", wal.Column{Name: "id", Type: "integer", Value: float64(1)}),
+	)))
+	// the table is routed, but it's found using its pgstream id
+	schemaEvent := newTestSchemaLogEvent(t, 1, schemalog.Column{Name: "id", DataType: "text"})
+	routedEvent := newTestRowEvent("I", wal.Column{Name: "id", Type: "text", Value: "a"})
+	routedEvent.Schema = "routed"
+	routedEvent.Metadata.TablePgstreamID = testTableID
+	require.NoError(t, w.sendBatch(ctx, newTestBatch("2", schemaEvent, routedEvent)))
+	require.Empty(t, inProgressFiles(t, dir))
+
+	paths, err := filepath.Glob(filepath.Join(dir, "public", "users", "date=2024-01-02", "*"+fileExtension))
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	require.True(t, strings.HasPrefix(filepath.Base(paths[0]), "users-v0-"))
+	require.Equal(t, []map[string]any{
+		{"_action": "I", "_lsn": "0/1", "_timestamp": time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixMicro(), "id": int32(1)},
+	}, readParquetFile(t, paths[0]))
+
+	paths, err = filepath.Glob(filepath.Join(dir, "routed", "users", "date=2024-01-02", "*"+fileExtension))
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	require.True(t, strings.HasPrefix(filepath.Base(paths[0]), "users-v1-"))
+	require.Equal(t, []map[string]any{
+		{"_action": "I", "_lsn": "0/1", "_timestamp": time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixMicro(), "id": "a"},
+	}, readParquetFile(t, paths[0]))
+}
+
+func newTestWriter(t *testing.T, cfg *Config) *BatchWriter {
+	files, err := newFileRotator(cfg, loglib.NewNoopLogger())
+	require.NoError(t, err)
+
+	return &BatchWriter{
+		logger:       loglib.NewNoopLogger(),
+		files:        files,
+		tablesByID:   map[string]*tableSchema{},
+		tablesByName: map[string]*tableSchema{},
+		serialiser:   json.Marshal,
+	}
+}
+
+func newTestBatch(position wal.CommitPosition, events ...*wal.Data) *batch.Batch[*message] {
+	msgs := make([]*message, 0, len(events))
+	for _, data := range events {
+		msgs = append(msgs, &message{data: data})
+	}
+	return batch.NewBatch(msgs, []wal.CommitPosition{position})
+}
+
+func newTestRowEvent(action string, columns ...wal.Column) *wal.Data {
+	data := &wal.Data{
+		Action:    action,
+		Timestamp: testTimestamp,
+		LSN:       "0/1",
+		Schema:    "public",
+		Table:     "users",
+	}
+	if action == "D" {
+		data.Identity = columns
+	} else {
+		data.Columns = columns
+	}
+	return data
+}
+
+func newTestSchemaLogEvent(t *testing.T, version int64, columns ...schemalog.Column) *wal.Data {
+	schema, err := json.Marshal(schemalog.Schema{
+		Tables: []schemalog.Table{
+			{Name: "users", PgstreamID: testTableID, Columns: columns},
+		},
+	})
+	require.NoError(t, err)
+
+	return &wal.Data{
+		Action: "I",
+		Schema: schemalog.SchemaName,
+		Table:  schemalog.TableName,
+		Columns: []wal.Column{
+			{Name: "id", Value: "cjv1g1hu2tq6n6dhnrpg"},
+			{Name: "version", Value: version},
+			{Name: "schema_name", Value: "public"},
+			{Name: "created_at", Value: "2024-01-02 03:04:05"},
+			{Name: "schema", Value: string(schema)},
+		},
+	}
+}
+
+func inProgressFiles(t *testing.T, dir string) []string {
+	paths := []string{}
+	err := filepath.WalkDir(dir, func(path string, _ os.DirEntry, err error) error {
+		if strings.HasSuffix(path, inProgressSuffix) {
+			paths = append(paths, path)
+		}
+		return err
+	})
+	require.NoError(t, err)
+	return paths
+}
+
+// readParquetFile returns the rows of the parquet file on input, with the null
+// values omitted.
+func readParquetFile(t *testing.T, path string) []map[string]any {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	reader := parquet.NewReader(f)
+	defer reader.Close()
+	fields := reader.Schema().Fields()
+
+	rows := []map[string]any{}
+	for {
+		row := make([]parquet.Row, 1)
+		n, err := reader.ReadRows(row)
+		if n == 0 && errors.Is(err, io.EOF) {
+			return rows
+		}
+		require.Equal(t, 1, n)
+
+		values := map[string]any{}
+		for _, v := range row[0] {
+			if v.IsNull() {
+				continue
+			}
+			name := fields[v.Column()].Name()
+			switch v.Kind() {
+			case parquet.Int32:
+				values[name] = v.Int32()
+			case parquet.Int64:
+				values[name] = v.Int64()
+			case parquet.ByteArray:
+				values[name] = v.String()
+			default:
+				values[name] = v.String()
+			}
+		}
+		rows = append(rows, values)
+	}
+}
@@ -5,6 +5,8 @@ package postgres
 import (
 	schemalogpg "github.com/xataio/pgstream/pkg/schemalog/postgres"
 	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+	"github.com/xataio/pgstream/pkg/wal/processor/projection"
+	"github.com/xataio/pgstream/pkg/wal/processor/router"
 )
 
 type Config struct {
@@ -14,4 +16,45 @@ type Config struct {
 	DisableTriggers   bool
 	OnConflictAction  string
 	BulkIngestEnabled bool
+	// TransactionConsistent enables the transaction consistent apply mode,
+	// where batches are only cut at source transaction boundaries, and each
+	// batch is applied in a single target transaction. This guarantees the
+	// target is always at a state the source database had.
+	TransactionConsistent bool
+	// ExactlyOnce configures the tracking of the applied commit positions on
+	// the target database. It implies the transaction consistent mode.
+	ExactlyOnce ExactlyOnceConfig
+	// ColumnProjection is the column projection applied to the replicated
+	// events. It's used to project the schema log entries retrieved from the
+	// store, so that the schema diffs don't include the projected columns.
+	ColumnProjection *projection.Config
+	// TableRouting is the table routing applied to the replicated events. It's
+	// used to route the tables of the schema log entries, which keep the
+	// source names.
+	TableRouting *router.Config
+}
+
+type ExactlyOnceConfig struct {
+	// Enabled makes the writer persist the last applied commit position on the
+	// target database in the same transaction as the data, and skip any
+	// transactions already applied when they're replayed.
+	Enabled bool
+	// StreamID identifies the stream the applied positions belong to, allowing
+	// multiple streams to write to the same target database. Defaults to
+	// pgstream.
+	StreamID string
+}
+
+func (c *ExactlyOnceConfig) GetStreamID() string {
+	if c.StreamID != "" {
+		return c.StreamID
+	}
+	return DefaultStreamID
+}
+
+func (c *Config) tableRouter() (*router.TableRouter, error) {
+	if c.TableRouting == nil {
+		return nil, nil
+	}
+	return router.NewTableRouter(c.TableRouting)
 }
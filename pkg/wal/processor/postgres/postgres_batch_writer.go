@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"runtime/debug"
+	"sync"
 
 	pglib "github.com/xataio/pgstream/internal/postgres"
 	loglib "github.com/xataio/pgstream/pkg/log"
@@ -15,6 +16,9 @@ import (
 	"github.com/xataio/pgstream/pkg/wal"
 	"github.com/xataio/pgstream/pkg/wal/processor"
 	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+	"github.com/xataio/pgstream/pkg/wal/processor/projection"
+	"github.com/xataio/pgstream/pkg/wal/replication"
+	pgreplication "github.com/xataio/pgstream/pkg/wal/replication/postgres"
 )
 
 // BatchWriter is a WAL processor implementation that batches and writes wal
@@ -23,10 +27,30 @@ type BatchWriter struct {
 	*Writer
 
 	batchSender queryBatchSender
+
+	// transaction consistent mode fields. The transaction batch sender is used
+	// instead of the query batch sender, and the queries of the source
+	// transaction in progress are kept until its commit is received.
+	txBatchSender txBatchSender
+	txMutex       *sync.Mutex
+	currentTx     *transaction
+	maxTxBytes    int64
+
+	// exactly once fields. The last applied position is retrieved from the
+	// target database on startup, and used to skip any transactions that have
+	// already been applied.
+	positionStore *positionStore
+	lsnParser     replication.LSNParser
+	appliedLSN    replication.LSN
 }
 
 const batchWriter = "postgres_batch_writer"
 
+var (
+	errTransactionTooLarge    = errors.New("transaction size exceeds the max queue bytes")
+	errTransactionBatchFailed = errors.New("transaction batch failed, no changes applied")
+)
+
 // NewBatchWriter returns a postgres processor that batches and writes data to
 // the configured postgres instance.
 func NewBatchWriter(ctx context.Context, config *Config, opts ...WriterOption) (*BatchWriter, error) {
@@ -38,9 +62,20 @@ func NewBatchWriter(ctx context.Context, config *Config, opts ...WriterOption) (
 			return nil, fmt.Errorf("create schema log postgres store: %w", err)
 		}
 		schemaLogStore = schemalog.NewStoreCache(schemaLogStore)
+		if config.ColumnProjection != nil {
+			schemaLogStore, err = projection.NewSchemaLogStore(schemaLogStore, config.ColumnProjection)
+			if err != nil {
+				return nil, fmt.Errorf("create schema log projection store: %w", err)
+			}
+		}
 	}
 
-	adapter, err := newAdapter(ctx, schemaLogStore, config.URL, config.OnConflictAction)
+	tableRouter, err := config.tableRouter()
+	if err != nil {
+		return nil, fmt.Errorf("create table router: %w", err)
+	}
+
+	adapter, err := newAdapter(ctx, schemaLogStore, tableRouter, config.URL, config.OnConflictAction)
 	if err != nil {
 		return nil, err
 	}
@@ -54,6 +89,29 @@ func NewBatchWriter(ctx context.Context, config *Config, opts ...WriterOption) (
 		Writer: w,
 	}
 
+	if config.ExactlyOnce.Enabled {
+		if err := bw.initExactlyOnce(ctx, config.ExactlyOnce.GetStreamID()); err != nil {
+			return nil, err
+		}
+	}
+
+	// exactly once relies on the source transaction commit positions, so it
+	// requires the transaction consistent mode
+	if config.TransactionConsistent || config.ExactlyOnce.Enabled {
+		// a transaction is never split across batches, so it needs to fit in
+		// the batch sender queue
+		bw.maxTxBytes, err = config.BatchConfig.GetMaxQueueBytes()
+		if err != nil {
+			return nil, err
+		}
+		bw.txMutex = &sync.Mutex{}
+		bw.txBatchSender, err = batch.NewSender(ctx, &config.BatchConfig, bw.sendTxBatch, w.logger)
+		if err != nil {
+			return nil, err
+		}
+		return bw, nil
+	}
+
 	bw.batchSender, err = batch.NewSender(ctx, &config.BatchConfig, bw.sendBatch, w.logger)
 	if err != nil {
 		return nil, err
@@ -77,6 +135,10 @@ func (w *BatchWriter) ProcessWALEvent(ctx context.Context, walEvent *wal.Event)
 		}
 	}()
 
+	if w.txBatchSender != nil {
+		return w.processTxEvent(ctx, walEvent)
+	}
+
 	queries, err := w.adapter.walEventToQueries(ctx, walEvent)
 	if err != nil {
 		return err
@@ -99,11 +161,77 @@ func (w *BatchWriter) Name() string {
 
 func (w *BatchWriter) Close() error {
 	w.logger.Debug("closing batch writer")
-	w.batchSender.Close()
+	if w.txBatchSender != nil {
+		w.txBatchSender.Close()
+	} else {
+		w.batchSender.Close()
+	}
 
 	return w.close()
 }
 
+// processTxEvent keeps the queries for the events of a source transaction
+// until the transaction commit is received, at which point they are sent as a
+// single message to the batch sender, so that a transaction is never split
+// across batches. Events received outside of a transaction (snapshot, keep
+// alive) are sent straight away.
+func (w *BatchWriter) processTxEvent(ctx context.Context, walEvent *wal.Event) error {
+	w.txMutex.Lock()
+	defer w.txMutex.Unlock()
+
+	switch {
+	case walEvent.Data != nil && walEvent.Data.IsBegin():
+		if w.currentTx != nil {
+			// logical decoding only streams committed transactions, so this
+			// should never happen
+			w.logger.Warn(nil, "transaction begin received before previous transaction commit, discarding in flight transaction", loglib.Fields{
+				"xid":      walEvent.Data.XID,
+				"severity": "DATALOSS",
+			})
+		}
+		w.currentTx = &transaction{}
+		return nil
+	case walEvent.Data != nil && walEvent.Data.IsCommit():
+		tx := w.currentTx
+		w.currentTx = nil
+		applied, err := w.isApplied(walEvent.CommitPosition)
+		if err != nil {
+			return err
+		}
+		if applied {
+			w.logger.Debug("skipping transaction already applied", loglib.Fields{"xid": walEvent.Data.XID, "commit_position": walEvent.CommitPosition})
+			return nil
+		}
+		// transactions without queries are not sent to prevent the batch
+		// sender from treating them as keep alive messages. Their position is
+		// not checkpointed, but since the positions are cumulative, it's
+		// covered by the next position checkpointed (either from a following
+		// transaction or keep alive message).
+		if tx.IsEmpty() {
+			return nil
+		}
+		w.logger.Debug("batching transaction", loglib.Fields{"queries": len(tx.queries), "commit_position": walEvent.CommitPosition})
+		return w.txBatchSender.SendMessage(ctx, batch.NewWALMessage(tx, walEvent.CommitPosition))
+	}
+
+	queries, err := w.adapter.walEventToQueries(ctx, walEvent)
+	if err != nil {
+		return err
+	}
+
+	if w.currentTx == nil {
+		tx := &transaction{}
+		tx.add(queries...)
+		return w.txBatchSender.SendMessage(ctx, batch.NewWALMessage(tx, walEvent.CommitPosition))
+	}
+
+	w.currentTx.add(queries...)
+	if txSize := int64(w.currentTx.Size()); txSize > w.maxTxBytes {
+		return fmt.Errorf("transaction size %d bytes, max queue bytes %d: %w", txSize, w.maxTxBytes, errTransactionTooLarge)
+	}
+	return nil
+}
+
 func (w *BatchWriter) sendBatch(ctx context.Context, batch *batch.Batch[*query]) error {
 	queries := batch.GetMessages()
 	if len(queries) > 0 {
@@ -139,10 +267,100 @@ func (w *BatchWriter) sendBatch(ctx context.Context, batch *batch.Batch[*query])
 		}
 	}
 
-	if w.checkpointer != nil && len(batch.GetCommitPositions()) > 0 {
-		return w.checkpointer(ctx, batch.GetCommitPositions())
+	return w.checkpoint(ctx, batch.GetCommitPositions())
+}
+
+// sendTxBatch applies all the queries in the batch of source transactions,
+// DDL included, in a single target transaction.
+func (w *BatchWriter) sendTxBatch(ctx context.Context, batch *batch.Batch[*transaction]) error {
+	txs := batch.GetMessages()
+	if len(txs) > 0 {
+		queries := []*query{}
+		for _, tx := range txs {
+			queries = append(queries, tx.queries...)
+		}
+
+		// record the batch position in the same target transaction as the data
+		var positionQuery *query
+		if w.positionStore != nil {
+			position, err := w.maxPosition(batch.GetCommitPositions())
+			if err != nil {
+				return err
+			}
+			if position != "" {
+				positionQuery = w.positionStore.updatePositionQuery(position)
+			}
+		}
+
+		w.logger.Debug("sending transaction batch", loglib.Fields{"batch_size": len(txs), "queries": len(queries)})
+		if err := w.execTxQueries(ctx, queries, positionQuery); err != nil {
+			w.logger.Error(err, "applying transaction batch")
+			return err
+		}
+	}
+
+	return w.checkpoint(ctx, batch.GetCommitPositions())
+}
+
+func (w *BatchWriter) initExactlyOnce(ctx context.Context, streamID string) error {
+	var err error
+	w.positionStore, err = newPositionStore(ctx, w.pgConn, streamID)
+	if err != nil {
+		return err
+	}
+
+	w.lsnParser = pgreplication.NewLSNParser()
+	appliedPosition, err := w.positionStore.getAppliedPosition(ctx)
+	if err != nil {
+		return err
+	}
+	if appliedPosition != "" {
+		w.appliedLSN, err = w.lsnParser.FromString(string(appliedPosition))
+		if err != nil {
+			return fmt.Errorf("parsing applied position %s: %w", appliedPosition, err)
+		}
+	}
+
+	w.logger.Info("exactly once enabled", loglib.Fields{"stream_id": streamID, "applied_position": appliedPosition})
+	return nil
+}
+
+// isApplied returns true if the commit position on input has already been
+// applied to the target database, according to the position retrieved on
+// startup. It always returns false when exactly once is not enabled.
+func (w *BatchWriter) isApplied(position wal.CommitPosition) (bool, error) {
+	if w.positionStore == nil || w.appliedLSN == 0 || position == "" {
+		return false, nil
+	}
+
+	lsn, err := w.lsnParser.FromString(string(position))
+	if err != nil {
+		return false, fmt.Errorf("parsing commit position %s: %w", position, err)
+	}
+	return lsn <= w.appliedLSN, nil
+}
+
+func (w *BatchWriter) maxPosition(positions []wal.CommitPosition) (wal.CommitPosition, error) {
+	var max replication.LSN
+	for _, position := range positions {
+		lsn, err := w.lsnParser.FromString(string(position))
+		if err != nil {
+			return "", fmt.Errorf("parsing commit position %s: %w", position, err)
+		}
+		if lsn > max {
+			max = lsn
+		}
+	}
+	if max == 0 {
+		return "", nil
 	}
+	return wal.CommitPosition(w.lsnParser.ToString(max)), nil
+}
 
+func (w *BatchWriter) checkpoint(ctx context.Context, positions []wal.CommitPosition) error {
+	if w.checkpointer != nil && len(positions) > 0 {
+		return w.checkpointer(ctx, positions)
+	}
 	return nil
 }
 
@@ -199,6 +417,37 @@ func (w *BatchWriter) execQueries(ctx context.Context, queries []*query) ([]*que
 	return retryQueries, nil
 }
 
+// execTxQueries runs all the queries on input in a single target transaction,
+// followed by the position query, if any, once all of them have succeeded.
+// Unlike execQueries, failed queries are not dropped and the rest retried,
+// since that would apply a partial source transaction, and record its position
+// as applied. Any failure aborts the whole transaction and is returned.
+func (w *BatchWriter) execTxQueries(ctx context.Context, queries []*query, positionQuery *query) error {
+	return w.pgConn.ExecInTx(ctx, func(tx pglib.Tx) error {
+		if err := w.setReplicationRoleToReplica(ctx, tx); err != nil {
+			return err
+		}
+
+		for _, q := range queries {
+			if _, err := tx.Exec(ctx, q.sql, q.args...); err != nil {
+				w.logger.Error(err, "executing sql query, aborting transaction batch", loglib.Fields{
+					"sql":  q.sql,
+					"args": q.args,
+				})
+				return fmt.Errorf("%w: %w", errTransactionBatchFailed, err)
+			}
+		}
+
+		if positionQuery != nil {
+			if _, err := tx.Exec(ctx, positionQuery.sql, positionQuery.args...); err != nil {
+				return fmt.Errorf("updating applied position: %w", err)
+			}
+		}
+
+		return w.resetReplicationRole(ctx, tx)
+	})
+}
+
 func (w *BatchWriter) isInternalError(err error) bool {
 	var errRelationDoesNotExist *pglib.ErrRelationDoesNotExist
 	var errConstraintViolation *pglib.ErrConstraintViolation
@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -18,6 +19,8 @@ import (
 	"github.com/xataio/pgstream/pkg/wal/processor"
 	"github.com/xataio/pgstream/pkg/wal/processor/batch"
 	batchmocks "github.com/xataio/pgstream/pkg/wal/processor/batch/mocks"
+	"github.com/xataio/pgstream/pkg/wal/replication"
+	pgreplication "github.com/xataio/pgstream/pkg/wal/replication/postgres"
 )
 
 var (
@@ -245,6 +248,384 @@ func TestBatchWriter_sendBatch(t *testing.T) {
 	}
 }
 
+func TestBatchWriter_ProcessWALEvent_transactionConsistent(t *testing.T) {
+	t.Parallel()
+
+	beginEvent := &wal.Event{
+		Data: &wal.Data{Action: "B", LSN: "1/CF54A000", XID: 1},
+	}
+	commitEvent := &wal.Event{
+		Data:           &wal.Data{Action: "C", LSN: testLSNStr, XID: 1},
+		CommitPosition: testCommitPosition,
+	}
+	rowEvent := func(action string) *wal.Event {
+		return &wal.Event{
+			Data: &wal.Data{
+				Action: action,
+				LSN:    "1/CF54A010",
+				Schema: testSchema,
+				Table:  testTable,
+				XID:    1,
+			},
+			CommitPosition: wal.CommitPosition("1/CF54A010"),
+		}
+	}
+
+	testInsertQuery := &query{
+		sql:  "INSERT INTO test(id, name) VALUES($1, $2) ON CONFLICT (id) DO NOTHING",
+		args: []any{1, "alice"},
+	}
+	testUpdateQuery := &query{
+		sql:  "UPDATE test SET name = $1 WHERE id = $2",
+		args: []any{"bob", 1},
+	}
+
+	testAdapter := &mockAdapter{
+		walEventToQueriesFn: func(e *wal.Event) ([]*query, error) {
+			switch {
+			case e.Data == nil:
+				return []*query{{}}, nil
+			case e.Data.IsInsert():
+				return []*query{testInsertQuery}, nil
+			case e.Data.IsUpdate():
+				return []*query{testUpdateQuery}, nil
+			default:
+				return nil, fmt.Errorf("unexpected event: %v", e.Data.Action)
+			}
+		},
+	}
+
+	tests := []struct {
+		name       string
+		walEvents  []*wal.Event
+		adapter    walAdapter
+		maxTxBytes int64
+		appliedLSN replication.LSN
+
+		wantMsgs []*batch.WALMessage[*transaction]
+		wantErr  error
+	}{
+		{
+			name:       "ok - transaction sent on commit",
+			walEvents:  []*wal.Event{beginEvent, rowEvent("I"), rowEvent("U"), commitEvent},
+			adapter:    testAdapter,
+			maxTxBytes: 1024,
+
+			wantMsgs: []*batch.WALMessage[*transaction]{
+				batch.NewWALMessage(&transaction{queries: []*query{testInsertQuery, testUpdateQuery}}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name:       "ok - transaction already applied",
+			walEvents:  []*wal.Event{beginEvent, rowEvent("I"), rowEvent("U"), commitEvent},
+			adapter:    testAdapter,
+			maxTxBytes: 1024,
+			appliedLSN: func() replication.LSN {
+				lsn, err := pgreplication.NewLSNParser().FromString(testLSNStr)
+				require.NoError(t, err)
+				return lsn
+			}(),
+
+			wantMsgs: []*batch.WALMessage[*transaction]{},
+			wantErr:  nil,
+		},
+		{
+			name:       "ok - empty transaction",
+			walEvents:  []*wal.Event{beginEvent, commitEvent},
+			adapter:    testAdapter,
+			maxTxBytes: 1024,
+
+			wantMsgs: []*batch.WALMessage[*transaction]{},
+			wantErr:  nil,
+		},
+		{
+			name:       "ok - events outside of a transaction",
+			walEvents:  []*wal.Event{rowEvent("I"), {CommitPosition: testCommitPosition}},
+			adapter:    testAdapter,
+			maxTxBytes: 1024,
+
+			wantMsgs: []*batch.WALMessage[*transaction]{
+				batch.NewWALMessage(&transaction{queries: []*query{testInsertQuery}}, wal.CommitPosition("1/CF54A010")),
+				batch.NewWALMessage(&transaction{}, testCommitPosition),
+			},
+			wantErr: nil,
+		},
+		{
+			name:       "error - transaction too large",
+			walEvents:  []*wal.Event{beginEvent, rowEvent("I"), rowEvent("U"), commitEvent},
+			adapter:    testAdapter,
+			maxTxBytes: 10,
+
+			wantMsgs: []*batch.WALMessage[*transaction]{},
+			wantErr:  errTransactionTooLarge,
+		},
+		{
+			name:      "error - event to query",
+			walEvents: []*wal.Event{beginEvent, rowEvent("I")},
+			adapter: &mockAdapter{
+				walEventToQueriesFn: func(e *wal.Event) ([]*query, error) {
+					return nil, errTest
+				},
+			},
+			maxTxBytes: 1024,
+
+			wantMsgs: []*batch.WALMessage[*transaction]{},
+			wantErr:  errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			txBatchSender := batchmocks.NewBatchSender[*transaction]()
+			writer := &BatchWriter{
+				Writer: &Writer{
+					logger:  loglib.NewNoopLogger(),
+					adapter: tc.adapter,
+				},
+				txBatchSender: txBatchSender,
+				txMutex:       &sync.Mutex{},
+				maxTxBytes:    tc.maxTxBytes,
+			}
+			if tc.appliedLSN != 0 {
+				writer.positionStore = &positionStore{streamID: DefaultStreamID}
+				writer.lsnParser = pgreplication.NewLSNParser()
+				writer.appliedLSN = tc.appliedLSN
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			go func() {
+				defer txBatchSender.Close()
+				var err error
+				for _, event := range tc.walEvents {
+					if err = writer.ProcessWALEvent(ctx, event); err != nil {
+						break
+					}
+				}
+				require.ErrorIs(t, err, tc.wantErr)
+			}()
+
+			msgs := txBatchSender.GetWALMessages()
+			require.Equal(t, tc.wantMsgs, msgs)
+		})
+	}
+}
+
+func TestBatchWriter_sendTxBatch(t *testing.T) {
+	t.Parallel()
+
+	testDDLQuery := &query{
+		sql:   "ALTER TABLE test ADD COLUMN age int",
+		isDDL: true,
+	}
+	testQuery := &query{
+		sql:  "INSERT INTO test(id, name) VALUES($1, $2)",
+		args: []any{1, "alice"},
+	}
+
+	testBatch := batch.NewBatch(
+		[]*transaction{
+			{queries: []*query{testDDLQuery}},
+			{queries: []*query{testQuery}},
+		},
+		[]wal.CommitPosition{testCommitPosition})
+
+	tests := []struct {
+		name          string
+		pgconn        func(execInTxCalls *uint) *pgmocks.Querier
+		checkpointer  checkpointer.Checkpoint
+		positionStore *positionStore
+
+		wantExecInTxCalls uint
+		wantErr           error
+	}{
+		{
+			name: "ok - all queries applied in one transaction",
+			pgconn: func(execInTxCalls *uint) *pgmocks.Querier {
+				return &pgmocks.Querier{
+					ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+						*execInTxCalls++
+						mockTx := pgmocks.Tx{
+							ExecFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+								switch i {
+								case 1:
+									require.Equal(t, testDDLQuery.sql, query)
+								case 2:
+									require.Equal(t, testQuery.sql, query)
+									require.Equal(t, testQuery.args, args)
+								default:
+									return pglib.CommandTag{}, fmt.Errorf("unexpected call to tx ExecFn: %v", query)
+								}
+								return pglib.CommandTag{}, nil
+							},
+						}
+						return f(&mockTx)
+					},
+					ExecFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.CommandTag, error) {
+						return pglib.CommandTag{}, errors.New("ExecFn: should not be called")
+					},
+				}
+			},
+			checkpointer: func(ctx context.Context, positions []wal.CommitPosition) error {
+				require.Equal(t, []wal.CommitPosition{testCommitPosition}, positions)
+				return nil
+			},
+
+			wantExecInTxCalls: 1,
+			wantErr:           nil,
+		},
+		{
+			name: "ok - with applied position",
+			pgconn: func(execInTxCalls *uint) *pgmocks.Querier {
+				return &pgmocks.Querier{
+					ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+						*execInTxCalls++
+						mockTx := pgmocks.Tx{
+							ExecFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+								switch i {
+								case 1:
+									require.Equal(t, testDDLQuery.sql, query)
+								case 2:
+									require.Equal(t, testQuery.sql, query)
+								case 3:
+									require.Contains(t, query, `INSERT INTO "pgstream"."applied_positions"`)
+									require.Equal(t, []any{"test-stream", testLSNStr}, args)
+								default:
+									return pglib.CommandTag{}, fmt.Errorf("unexpected call to tx ExecFn: %v", query)
+								}
+								return pglib.CommandTag{}, nil
+							},
+						}
+						return f(&mockTx)
+					},
+				}
+			},
+			positionStore: &positionStore{streamID: "test-stream"},
+
+			wantExecInTxCalls: 1,
+			wantErr:           nil,
+		},
+		{
+			name: "error - data query fails, position not updated",
+			pgconn: func(execInTxCalls *uint) *pgmocks.Querier {
+				return &pgmocks.Querier{
+					ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+						*execInTxCalls++
+						mockTx := pgmocks.Tx{
+							ExecFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+								require.NotContains(t, query, `"pgstream"."applied_positions"`)
+								if i == 2 {
+									return pglib.CommandTag{}, &pglib.ErrDataException{}
+								}
+								return pglib.CommandTag{}, nil
+							},
+						}
+						return f(&mockTx)
+					},
+				}
+			},
+			checkpointer: func(ctx context.Context, positions []wal.CommitPosition) error {
+				return errors.New("checkpointer: should not be called")
+			},
+			positionStore: &positionStore{streamID: "test-stream"},
+
+			wantExecInTxCalls: 1,
+			wantErr:           errTransactionBatchFailed,
+		},
+		{
+			name: "error - executing transaction",
+			pgconn: func(execInTxCalls *uint) *pgmocks.Querier {
+				return &pgmocks.Querier{
+					ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+						*execInTxCalls++
+						return errTest
+					},
+				}
+			},
+			checkpointer: func(ctx context.Context, positions []wal.CommitPosition) error {
+				return errors.New("checkpointer: should not be called")
+			},
+
+			wantExecInTxCalls: 1,
+			wantErr:           errTest,
+		},
+		{
+			name: "error - query of a multi statement transaction fails",
+			pgconn: func(execInTxCalls *uint) *pgmocks.Querier {
+				return &pgmocks.Querier{
+					ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+						*execInTxCalls++
+						mockTx := pgmocks.Tx{
+							ExecFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+								switch i {
+								case 1:
+									require.Equal(t, testDDLQuery.sql, query)
+									return pglib.CommandTag{}, nil
+								case 2:
+									require.Equal(t, testQuery.sql, query)
+									return pglib.CommandTag{}, &pglib.ErrConstraintViolation{}
+								default:
+									return pglib.CommandTag{}, fmt.Errorf("unexpected call to tx ExecFn: %v", query)
+								}
+							},
+						}
+						// the failed query must abort the whole transaction
+						return f(&mockTx)
+					},
+				}
+			},
+			checkpointer: func(ctx context.Context, positions []wal.CommitPosition) error {
+				return errors.New("checkpointer: should not be called")
+			},
+
+			// the rest of the transaction is not retried without the failed
+			// query
+			wantExecInTxCalls: 1,
+			wantErr:           errTransactionBatchFailed,
+		},
+		{
+			name: "error - checkpointing",
+			pgconn: func(execInTxCalls *uint) *pgmocks.Querier {
+				return &pgmocks.Querier{
+					ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+						*execInTxCalls++
+						return nil
+					},
+				}
+			},
+			checkpointer: func(ctx context.Context, positions []wal.CommitPosition) error { return errTest },
+
+			wantExecInTxCalls: 1,
+			wantErr:           errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			execInTxCalls := uint(0)
+			writer := &BatchWriter{
+				Writer: &Writer{
+					logger:       loglib.NewNoopLogger(),
+					pgConn:       tc.pgconn(&execInTxCalls),
+					checkpointer: tc.checkpointer,
+				},
+				positionStore: tc.positionStore,
+				lsnParser:     pgreplication.NewLSNParser(),
+			}
+
+			err := writer.sendTxBatch(context.Background(), testBatch)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantExecInTxCalls, execInTxCalls)
+		})
+	}
+}
+
 func TestBatchWriter_flushQueries(t *testing.T) {
 	t.Parallel()
 
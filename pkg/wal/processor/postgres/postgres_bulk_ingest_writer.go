@@ -37,7 +37,12 @@ var errUnexpectedCopiedRows = errors.New("number of rows copied doesn't match th
 func NewBulkIngestWriter(ctx context.Context, config *Config, opts ...WriterOption) (*BulkIngestWriter, error) {
 	// the bulk ingest writer only processes insert events, so we don't need a
 	// DDL adapter
-	adapter, err := newAdapter(ctx, nil, config.URL, config.OnConflictAction)
+	tableRouter, err := config.tableRouter()
+	if err != nil {
+		return nil, fmt.Errorf("create table router: %w", err)
+	}
+
+	adapter, err := newAdapter(ctx, nil, tableRouter, config.URL, config.OnConflictAction)
 	if err != nil {
 		return nil, err
 	}
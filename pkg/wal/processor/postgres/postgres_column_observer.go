@@ -9,6 +9,7 @@ import (
 	pglib "github.com/xataio/pgstream/internal/postgres"
 	synclib "github.com/xataio/pgstream/internal/sync"
 	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal/processor/router"
 )
 
 // pgColumnObserver keeps track of column names for tables. It uses a cache to
@@ -17,12 +18,14 @@ import (
 type pgColumnObserver struct {
 	pgConn                pglib.Querier
 	generatedTableColumns *synclib.StringMap[[]string]
+	// tableRouter maps the schema log tables to the target tables
+	tableRouter *router.TableRouter
 }
 
 // newPGColumnObserver returns a postgres that checks column names for tables.
 // It keeps a cache to reduce the number of calls to postgres, and it updates
 // the state whenever a DDL event is received through the WAL.
-func newPGColumnObserver(ctx context.Context, pgURL string) (*pgColumnObserver, error) {
+func newPGColumnObserver(ctx context.Context, pgURL string, tableRouter *router.TableRouter) (*pgColumnObserver, error) {
 	pgConn, err := pglib.NewConnPool(ctx, pgURL)
 	if err != nil {
 		return nil, err
@@ -30,6 +33,7 @@ func newPGColumnObserver(ctx context.Context, pgURL string) (*pgColumnObserver,
 	return &pgColumnObserver{
 		pgConn:                pgConn,
 		generatedTableColumns: synclib.NewStringMap[[]string](),
+		tableRouter:           tableRouter,
 	}, nil
 }
 
@@ -58,7 +62,7 @@ func (o *pgColumnObserver) getGeneratedColumnNames(ctx context.Context, schema,
 // columns for the schema log on input.
 func (o *pgColumnObserver) updateGeneratedColumnNames(logEntry *schemalog.LogEntry) {
 	for _, table := range logEntry.Schema.Tables {
-		key := pglib.QuoteQualifiedIdentifier(logEntry.SchemaName, table.Name)
+		key := pglib.QuoteQualifiedIdentifier(o.tableRouter.Route(logEntry.SchemaName, table.Name))
 		generatedColumns := make([]string, 0, len(table.Columns))
 		for _, c := range table.Columns {
 			if c.Generated {
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// positionStore keeps track of the last source commit position applied to the
+// target database for a stream. The position is updated in the same
+// transaction as the data it belongs to, so that the target always knows
+// exactly which changes have already been applied.
+type positionStore struct {
+	querier  pglib.Querier
+	streamID string
+}
+
+const (
+	positionsTableName = "applied_positions"
+	// DefaultStreamID is the stream identifier used to track the applied
+	// positions when none is configured.
+	DefaultStreamID = "pgstream"
+)
+
+func newPositionStore(ctx context.Context, querier pglib.Querier, streamID string) (*positionStore, error) {
+	s := &positionStore{
+		querier:  querier,
+		streamID: streamID,
+	}
+
+	if err := s.createTable(ctx); err != nil {
+		return nil, fmt.Errorf("creating applied positions table: %w", err)
+	}
+
+	return s, nil
+}
+
+// getAppliedPosition returns the last commit position applied to the target
+// database for the stream. It returns an empty position if none has been
+// applied yet.
+func (s *positionStore) getAppliedPosition(ctx context.Context) (wal.CommitPosition, error) {
+	query := fmt.Sprintf("SELECT commit_position FROM %s WHERE stream_id = $1", positionsTable())
+	var position string
+	if err := s.querier.QueryRow(ctx, query, s.streamID).Scan(&position); err != nil {
+		if errors.Is(err, pglib.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("retrieving applied position for stream %s: %w", s.streamID, err)
+	}
+	return wal.CommitPosition(position), nil
+}
+
+// updatePositionQuery returns the query that records the commit position on
+// input as the last one applied for the stream. It's meant to be run within
+// the same transaction as the data it belongs to.
+func (s *positionStore) updatePositionQuery(position wal.CommitPosition) *query {
+	return &query{
+		schema: schemalog.SchemaName,
+		table:  positionsTableName,
+		sql: fmt.Sprintf(`INSERT INTO %s(stream_id, commit_position, updated_at) VALUES($1, $2, now())
+	ON CONFLICT (stream_id) DO UPDATE SET commit_position = EXCLUDED.commit_position, updated_at = EXCLUDED.updated_at`, positionsTable()),
+		args: []any{s.streamID, string(position)},
+	}
+}
+
+func (s *positionStore) createTable(ctx context.Context) error {
+	createSchemaQuery := fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", pglib.QuoteIdentifier(schemalog.SchemaName))
+	if _, err := s.querier.Exec(ctx, createSchemaQuery); err != nil {
+		return fmt.Errorf("creating pgstream schema: %w", err)
+	}
+
+	createTableQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s(
+	stream_id TEXT PRIMARY KEY,
+	commit_position TEXT NOT NULL,
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL)`, positionsTable())
+	if _, err := s.querier.Exec(ctx, createTableQuery); err != nil {
+		return fmt.Errorf("creating applied positions postgres table: %w", err)
+	}
+
+	return nil
+}
+
+func positionsTable() string {
+	return pglib.QuoteQualifiedIdentifier(schemalog.SchemaName, positionsTableName)
+}
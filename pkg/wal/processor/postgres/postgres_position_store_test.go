@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func TestPositionStore_getAppliedPosition(t *testing.T) {
+	t.Parallel()
+
+	testStreamID := "test-stream"
+
+	tests := []struct {
+		name    string
+		querier *pgmocks.Querier
+
+		wantPosition wal.CommitPosition
+		wantErr      error
+	}{
+		{
+			name: "ok",
+			querier: &pgmocks.Querier{
+				QueryRowFn: func(ctx context.Context, query string, args ...any) pglib.Row {
+					require.Equal(t, `SELECT commit_position FROM "pgstream"."applied_positions" WHERE stream_id = $1`, query)
+					require.Equal(t, []any{testStreamID}, args)
+					return &pgmocks.Row{
+						ScanFn: func(args ...any) error {
+							require.Len(t, args, 1)
+							position, ok := args[0].(*string)
+							require.True(t, ok)
+							*position = testLSNStr
+							return nil
+						},
+					}
+				},
+			},
+
+			wantPosition: testCommitPosition,
+			wantErr:      nil,
+		},
+		{
+			name: "ok - no applied position",
+			querier: &pgmocks.Querier{
+				QueryRowFn: func(ctx context.Context, query string, args ...any) pglib.Row {
+					return &pgmocks.Row{
+						ScanFn: func(args ...any) error { return pglib.ErrNoRows },
+					}
+				},
+			},
+
+			wantPosition: "",
+			wantErr:      nil,
+		},
+		{
+			name: "error - retrieving applied position",
+			querier: &pgmocks.Querier{
+				QueryRowFn: func(ctx context.Context, query string, args ...any) pglib.Row {
+					return &pgmocks.Row{
+						ScanFn: func(args ...any) error { return errTest },
+					}
+				},
+			},
+
+			wantPosition: "",
+			wantErr:      errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := &positionStore{
+				querier:  tc.querier,
+				streamID: testStreamID,
+			}
+
+			position, err := store.getAppliedPosition(context.Background())
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantPosition, position)
+		})
+	}
+}
+
+func TestPositionStore_updatePositionQuery(t *testing.T) {
+	t.Parallel()
+
+	store := &positionStore{
+		streamID: "test-stream",
+	}
+
+	q := store.updatePositionQuery(testCommitPosition)
+	require.Equal(t, &query{
+		schema: "pgstream",
+		table:  "applied_positions",
+		sql: `INSERT INTO "pgstream"."applied_positions"(stream_id, commit_position, updated_at) VALUES($1, $2, now())
+	ON CONFLICT (stream_id) DO UPDATE SET commit_position = EXCLUDED.commit_position, updated_at = EXCLUDED.updated_at`,
+		args: []any{"test-stream", testLSNStr},
+	}, q)
+}
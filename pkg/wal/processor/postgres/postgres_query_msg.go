@@ -34,3 +34,34 @@ func (m *query) getArgs() []any {
 	}
 	return m.args
 }
+
+// transaction groups the queries generated by the events of a source
+// transaction, so that they are batched and applied together.
+type transaction struct {
+	queries []*query
+}
+
+// Size returns the aggregated size of the transaction queries
+func (t *transaction) Size() int {
+	if t == nil {
+		return 0
+	}
+	size := 0
+	for _, q := range t.queries {
+		size += q.Size()
+	}
+	return size
+}
+
+func (t *transaction) IsEmpty() bool {
+	return t == nil || len(t.queries) == 0
+}
+
+func (t *transaction) add(queries ...*query) {
+	for _, q := range queries {
+		if q.IsEmpty() {
+			continue
+		}
+		t.queries = append(t.queries, q)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/xataio/pgstream/pkg/schemalog"
 	"github.com/xataio/pgstream/pkg/wal"
 	"github.com/xataio/pgstream/pkg/wal/processor"
+	"github.com/xataio/pgstream/pkg/wal/processor/router"
 )
 
 type walAdapter interface {
@@ -29,8 +30,8 @@ type adapter struct {
 	columnObserver columnObserver
 }
 
-func newAdapter(ctx context.Context, schemaQuerier schemalogQuerier, pgURL string, onConflictAction string) (*adapter, error) {
-	columnObserver, err := newPGColumnObserver(ctx, pgURL)
+func newAdapter(ctx context.Context, schemaQuerier schemalogQuerier, tableRouter *router.TableRouter, pgURL string, onConflictAction string) (*adapter, error) {
+	columnObserver, err := newPGColumnObserver(ctx, pgURL, tableRouter)
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +43,7 @@ func newAdapter(ctx context.Context, schemaQuerier schemalogQuerier, pgURL strin
 
 	var ddl *ddlAdapter
 	if schemaQuerier != nil {
-		ddl = newDDLAdapter(schemaQuerier)
+		ddl = newDDLAdapter(schemaQuerier, tableRouter)
 	}
 	return &adapter{
 		dmlAdapter:      dmlAdapter,
@@ -57,6 +58,13 @@ func (a *adapter) walEventToQueries(ctx context.Context, e *wal.Event) ([]*query
 		return []*query{{}}, nil
 	}
 
+	// transaction boundaries and logical messages don't produce any queries.
+	// They're not treated as keep alive messages to prevent every transaction
+	// from triggering a batch send.
+	if e.Data.IsTransactionBoundary() || e.Data.IsLogicalMessage() {
+		return nil, nil
+	}
+
 	if processor.IsSchemaLogEvent(e.Data) {
 		schemaLog, err := a.logEntryAdapter(e.Data)
 		if err != nil {
@@ -12,11 +12,16 @@ import (
 	pglib "github.com/xataio/pgstream/internal/postgres"
 	"github.com/xataio/pgstream/pkg/schemalog"
 	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/router"
 )
 
 type ddlAdapter struct {
 	schemalogQuerier schemalogQuerier
 	schemaDiffer     schemaDiffer
+	// tableRouter maps the source schema log tables to the target tables. The
+	// schema log entries are kept with the source names, so that their
+	// versions can be retrieved from the source schema log store.
+	tableRouter *router.TableRouter
 }
 
 type schemalogQuerier interface {
@@ -27,10 +32,11 @@ type schemaDiffer func(old, new *schemalog.LogEntry) *schemalog.Diff
 
 type logEntryAdapter func(*wal.Data) (*schemalog.LogEntry, error)
 
-func newDDLAdapter(querier schemalogQuerier) *ddlAdapter {
+func newDDLAdapter(querier schemalogQuerier, tableRouter *router.TableRouter) *ddlAdapter {
 	return &ddlAdapter{
 		schemalogQuerier: querier,
 		schemaDiffer:     schemalog.ComputeSchemaDiff,
+		tableRouter:      tableRouter,
 	}
 }
 
@@ -46,8 +52,9 @@ func (a *ddlAdapter) schemaLogToQueries(ctx context.Context, schemaLog *schemalo
 
 	diff := a.schemaDiffer(previousSchemaLog, schemaLog)
 
-	queries := []*query{
-		a.createSchemaIfNotExists(schemaLog.SchemaName),
+	queries := []*query{}
+	for _, targetSchema := range a.targetSchemas(schemaLog) {
+		queries = append(queries, a.createSchemaIfNotExists(targetSchema))
 	}
 
 	schemaQueries, err := a.schemaDiffToQueries(schemaLog.SchemaName, diff)
@@ -72,7 +79,7 @@ func (a *ddlAdapter) schemaDiffToQueries(schemaName string, diff *schemalog.Diff
 
 	queries := []*query{}
 	for _, table := range diff.TablesRemoved {
-		dropQuery := fmt.Sprintf("DROP TABLE IF EXISTS %s", quotedTableName(schemaName, table.Name))
+		dropQuery := fmt.Sprintf("DROP TABLE IF EXISTS %s", a.quotedTargetTableName(schemaName, table.Name))
 		queries = append(queries, a.newDDLQuery(schemaName, table.Name, dropQuery))
 	}
 
@@ -88,7 +95,7 @@ func (a *ddlAdapter) schemaDiffToQueries(schemaName string, diff *schemalog.Diff
 }
 
 func (a *ddlAdapter) buildCreateTableQuery(schemaName string, table schemalog.Table) *query {
-	createQuery := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (", quotedTableName(schemaName, table.Name))
+	createQuery := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (", a.quotedTargetTableName(schemaName, table.Name))
 	uniqueConstraints := make([]string, 0, len(table.Columns))
 	columnDefinitions := make([]string, 0, len(table.Columns))
 	for _, col := range table.Columns {
@@ -148,20 +155,30 @@ func (a *ddlAdapter) buildAlterTableQueries(schemaName string, tableDiff schemal
 
 	queries := []*query{}
 	if tableDiff.TableNameChange != nil {
+		oldSchema, oldTable := a.tableRouter.Route(schemaName, tableDiff.TableNameChange.Old)
+		newSchema, newTable := a.tableRouter.Route(schemaName, tableDiff.TableNameChange.New)
 		alterQuery := fmt.Sprintf("ALTER TABLE %s RENAME TO %s",
-			quotedTableName(schemaName, tableDiff.TableNameChange.Old),
-			tableDiff.TableNameChange.New,
+			quotedTableName(oldSchema, oldTable),
+			newTable,
 		)
 		queries = append(queries, a.newDDLQuery(schemaName, tableDiff.TableName, alterQuery))
+		// the renamed table can be routed to a different target schema
+		if newSchema != oldSchema {
+			alterQuery := fmt.Sprintf("ALTER TABLE %s SET SCHEMA %s",
+				quotedTableName(oldSchema, newTable),
+				pglib.QuoteIdentifier(newSchema),
+			)
+			queries = append(queries, a.newDDLQuery(schemaName, tableDiff.TableName, alterQuery))
+		}
 	}
 
 	for _, col := range tableDiff.ColumnsRemoved {
-		alterQuery := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quotedTableName(schemaName, tableDiff.TableName), pglib.QuoteIdentifier(col.Name))
+		alterQuery := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", a.quotedTargetTableName(schemaName, tableDiff.TableName), pglib.QuoteIdentifier(col.Name))
 		queries = append(queries, a.newDDLQuery(schemaName, tableDiff.TableName, alterQuery))
 	}
 
 	for _, col := range tableDiff.ColumnsAdded {
-		alterQuery := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quotedTableName(schemaName, tableDiff.TableName), a.buildColumnDefinition(&col))
+		alterQuery := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", a.quotedTargetTableName(schemaName, tableDiff.TableName), a.buildColumnDefinition(&col))
 		queries = append(queries, a.newDDLQuery(schemaName, tableDiff.TableName, alterQuery))
 	}
 
@@ -181,7 +198,7 @@ func (a *ddlAdapter) buildAlterColumnQueries(schemaName, tableName string, colum
 	queries := []*query{}
 	if columnDiff.NameChange != nil {
 		alterQuery := fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s",
-			quotedTableName(schemaName, tableName),
+			a.quotedTargetTableName(schemaName, tableName),
 			pglib.QuoteIdentifier(columnDiff.NameChange.Old),
 			pglib.QuoteIdentifier(columnDiff.NameChange.New),
 		)
@@ -190,7 +207,7 @@ func (a *ddlAdapter) buildAlterColumnQueries(schemaName, tableName string, colum
 
 	if columnDiff.TypeChange != nil {
 		alterQuery := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s",
-			quotedTableName(schemaName, tableName),
+			a.quotedTargetTableName(schemaName, tableName),
 			pglib.QuoteIdentifier(columnDiff.ColumnName),
 			columnDiff.TypeChange.New,
 		)
@@ -203,13 +220,13 @@ func (a *ddlAdapter) buildAlterColumnQueries(schemaName, tableName string, colum
 		// from not nullable to nullable
 		case columnDiff.NullChange.New:
 			alterQuery = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL",
-				quotedTableName(schemaName, tableName),
+				a.quotedTargetTableName(schemaName, tableName),
 				pglib.QuoteIdentifier(columnDiff.ColumnName),
 			)
 		default:
 			// from nullable to not nullable
 			alterQuery = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL",
-				quotedTableName(schemaName, tableName),
+				a.quotedTargetTableName(schemaName, tableName),
 				pglib.QuoteIdentifier(columnDiff.ColumnName),
 			)
 		}
@@ -222,7 +239,7 @@ func (a *ddlAdapter) buildAlterColumnQueries(schemaName, tableName string, colum
 		// removing the default
 		case nil:
 			alterQuery = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT",
-				quotedTableName(schemaName, tableName),
+				a.quotedTargetTableName(schemaName, tableName),
 				pglib.QuoteIdentifier(columnDiff.ColumnName),
 			)
 		default:
@@ -230,7 +247,7 @@ func (a *ddlAdapter) buildAlterColumnQueries(schemaName, tableName string, colum
 			// source/target. Keep source database as source of truth.
 			if !strings.Contains(*columnDiff.DefaultChange.New, "seq") {
 				alterQuery = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s",
-					quotedTableName(schemaName, tableName),
+					a.quotedTargetTableName(schemaName, tableName),
 					pglib.QuoteIdentifier(columnDiff.ColumnName),
 					*columnDiff.DefaultChange.New,
 				)
@@ -244,7 +261,30 @@ func (a *ddlAdapter) buildAlterColumnQueries(schemaName, tableName string, colum
 	return queries
 }
 
+// targetSchemas returns the target schemas for the schema log on input,
+// including the ones its tables are routed to.
+func (a *ddlAdapter) targetSchemas(schemaLog *schemalog.LogEntry) []string {
+	schemas := []string{a.tableRouter.RouteSchema(schemaLog.SchemaName)}
+	for _, table := range schemaLog.Schema.Tables {
+		schema, _ := a.tableRouter.Route(schemaLog.SchemaName, table.Name)
+		if !slices.Contains(schemas, schema) {
+			schemas = append(schemas, schema)
+		}
+	}
+	return schemas
+}
+
+func (a *ddlAdapter) quotedTargetTableName(schemaName, tableName string) string {
+	return quotedTableName(a.tableRouter.Route(schemaName, tableName))
+}
+
+// newDDLQuery returns a ddl query for the source table on input, which is
+// routed to its target table. Schema level queries are expected to be built
+// for the target schema.
 func (a *ddlAdapter) newDDLQuery(schema, table, sql string) *query {
+	if table != "" {
+		schema, table = a.tableRouter.Route(schema, table)
+	}
 	return &query{
 		schema: schema,
 		table:  table,
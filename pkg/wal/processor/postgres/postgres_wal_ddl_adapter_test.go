@@ -14,6 +14,7 @@ import (
 	pglib "github.com/xataio/pgstream/internal/postgres"
 	"github.com/xataio/pgstream/pkg/schemalog"
 	schemalogmocks "github.com/xataio/pgstream/pkg/schemalog/mocks"
+	"github.com/xataio/pgstream/pkg/wal/processor/router"
 )
 
 func TestDDLAdapter_walDataToQueries(t *testing.T) {
@@ -471,7 +472,7 @@ func TestDDLAdapter_schemaDiffToQueries(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			ddlAdapter := newDDLAdapter(nil)
+			ddlAdapter := newDDLAdapter(nil, nil)
 
 			queries, err := ddlAdapter.schemaDiffToQueries(testSchema, tc.diff)
 			require.ErrorIs(t, err, tc.wantErr)
@@ -479,3 +480,77 @@ func TestDDLAdapter_schemaDiffToQueries(t *testing.T) {
 		})
 	}
 }
+
+func TestDDLAdapter_schemaLogToQueries_withRouting(t *testing.T) {
+	t.Parallel()
+
+	tableRouter, err := router.NewTableRouter(&router.Config{
+		Rules: []router.RuleConfig{
+			{Source: "public.users", Target: "tenant_a.accounts"},
+			{Source: "public.*", Target: "tenant_a.{table}"},
+		},
+	})
+	require.NoError(t, err)
+
+	logEntry := &schemalog.LogEntry{
+		Version:    1,
+		SchemaName: "public",
+		Schema: schemalog.Schema{
+			Tables: []schemalog.Table{
+				{Name: "users", Columns: []schemalog.Column{{Name: "id", DataType: "integer"}}},
+				{Name: "orders", Columns: []schemalog.Column{{Name: "id", DataType: "integer"}}},
+			},
+		},
+	}
+
+	a := newDDLAdapter(&schemalogmocks.Store{
+		FetchFn: func(ctx context.Context, schemaName string, version int) (*schemalog.LogEntry, error) {
+			require.Equal(t, "public", schemaName)
+			require.Equal(t, 0, version)
+			return &schemalog.LogEntry{
+				SchemaName: "public",
+				Schema: schemalog.Schema{
+					Tables: []schemalog.Table{
+						{Name: "users", Columns: []schemalog.Column{{Name: "id", DataType: "integer"}}},
+						{Name: "items", Columns: []schemalog.Column{{Name: "id", DataType: "integer"}}},
+					},
+				},
+			}, nil
+		},
+	}, tableRouter)
+	a.schemaDiffer = func(old, new *schemalog.LogEntry) *schemalog.Diff {
+		return &schemalog.Diff{
+			TablesRemoved: []schemalog.Table{{Name: "items"}},
+			TablesChanged: []schemalog.TableDiff{
+				{
+					TableName: "users",
+					ColumnsAdded: []schemalog.Column{
+						{Name: "name", DataType: "text", Nullable: true},
+					},
+				},
+			},
+		}
+	}
+
+	queries, err := a.schemaLogToQueries(context.Background(), logEntry)
+	require.NoError(t, err)
+	require.Equal(t, []*query{
+		{
+			schema: "tenant_a",
+			sql:    fmt.Sprintf(createSchemaIfNotExistsQuery, pglib.QuoteIdentifier("tenant_a")),
+			isDDL:  true,
+		},
+		{
+			schema: "tenant_a",
+			table:  "items",
+			sql:    fmt.Sprintf("DROP TABLE IF EXISTS %s", quotedTableName("tenant_a", "items")),
+			isDDL:  true,
+		},
+		{
+			schema: "tenant_a",
+			table:  "accounts",
+			sql:    fmt.Sprintf("ALTER TABLE %s ADD COLUMN \"name\" text", quotedTableName("tenant_a", "accounts")),
+			isDDL:  true,
+		},
+	}, queries)
+}
@@ -27,6 +27,11 @@ type queryBatchSender interface {
 	Close()
 }
 
+type txBatchSender interface {
+	SendMessage(context.Context, *batch.WALMessage[*transaction]) error
+	Close()
+}
+
 type WriterOption func(*Writer)
 
 func newWriter(ctx context.Context, config *Config, adapter walAdapter, writerType string, opts ...WriterOption) (*Writer, error) {
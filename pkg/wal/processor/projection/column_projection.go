@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package projection
+
+// ColumnProjection reports which columns are kept by the projection
+// configuration, so that the components that don't process WAL events (i.e.
+// the pg_dump schema snapshot) can apply the same projection.
+type ColumnProjection struct {
+	tables tableProjections
+}
+
+// NewColumnProjection returns a column projection for the configuration on
+// input.
+func NewColumnProjection(cfg *Config) (*ColumnProjection, error) {
+	tables, err := newTableProjections(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &ColumnProjection{tables: tables}, nil
+}
+
+// KeepColumn returns true if the column of the table on input is kept by the
+// projection. All the columns of the tables without projection are kept.
+func (p *ColumnProjection) KeepColumn(schema, table, column string) bool {
+	projection := p.tables.get(schema, table)
+	return projection == nil || projection.keep(column)
+}
+
+// ProjectsTable returns true if the table on input has a column projection
+// configured.
+func (p *ColumnProjection) ProjectsTable(schema, table string) bool {
+	return p.tables.get(schema, table) != nil
+}
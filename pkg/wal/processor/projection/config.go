@@ -0,0 +1,20 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package projection
+
+type Config struct {
+	// Tables contains the column projection configuration per table.
+	Tables []TableConfig
+}
+
+type TableConfig struct {
+	// Table is the table name. It should be schema qualified. If no schema is
+	// provided, the public schema will be assumed.
+	Table string
+	// IncludeColumns is the list of columns to keep. Any other column of the
+	// table will be removed.
+	IncludeColumns []string
+	// ExcludeColumns is the list of columns to remove. Any other column of the
+	// table will be kept.
+	ExcludeColumns []string
+}
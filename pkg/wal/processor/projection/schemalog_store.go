@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package projection
+
+import (
+	"context"
+	"slices"
+
+	"github.com/xataio/pgstream/pkg/schemalog"
+)
+
+// SchemaLogStore is a schema log store wrapper that applies the column
+// projection to the log entries it returns. It keeps the schema log entries
+// read from the store consistent with the projected schema log events, which
+// prevents the processors that compute schema diffs from seeing the projected
+// columns as removed.
+type SchemaLogStore struct {
+	schemalog.Store
+	tables tableProjections
+}
+
+// NewSchemaLogStore returns a schema log store wrapper that will remove the
+// projected columns from the log entries returned by the store on input.
+func NewSchemaLogStore(store schemalog.Store, cfg *Config) (*SchemaLogStore, error) {
+	tables, err := newTableProjections(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &SchemaLogStore{
+		Store:  store,
+		tables: tables,
+	}, nil
+}
+
+func (s *SchemaLogStore) Insert(ctx context.Context, schemaName string) (*schemalog.LogEntry, error) {
+	logEntry, err := s.Store.Insert(ctx, schemaName)
+	return s.project(logEntry), err
+}
+
+func (s *SchemaLogStore) FetchLast(ctx context.Context, schemaName string, ackedOnly bool) (*schemalog.LogEntry, error) {
+	logEntry, err := s.Store.FetchLast(ctx, schemaName, ackedOnly)
+	return s.project(logEntry), err
+}
+
+func (s *SchemaLogStore) Fetch(ctx context.Context, schemaName string, version int) (*schemalog.LogEntry, error) {
+	logEntry, err := s.Store.Fetch(ctx, schemaName, version)
+	return s.project(logEntry), err
+}
+
+// project returns a projected copy of the log entry on input, since the
+// wrapped store might be caching the original entry.
+func (s *SchemaLogStore) project(logEntry *schemalog.LogEntry) *schemalog.LogEntry {
+	if logEntry == nil || len(s.tables[logEntry.SchemaName]) == 0 {
+		return logEntry
+	}
+	projected := *logEntry
+	projected.Schema.Tables = slices.Clone(logEntry.Schema.Tables)
+	s.tables.projectSchema(projected.SchemaName, &projected.Schema)
+	return &projected
+}
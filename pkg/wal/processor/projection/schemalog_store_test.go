@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package projection
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	schemalogmocks "github.com/xataio/pgstream/pkg/schemalog/mocks"
+)
+
+func TestSchemaLogStore_Fetch(t *testing.T) {
+	t.Parallel()
+
+	testLogEntry := func(schemaName string) *schemalog.LogEntry {
+		return &schemalog.LogEntry{
+			SchemaName: schemaName,
+			Version:    1,
+			Schema: schemalog.Schema{
+				Tables: []schemalog.Table{
+					{
+						Name: "users",
+						Columns: []schemalog.Column{
+							{Name: "id", DataType: "bigint"},
+							{Name: "password", DataType: "text"},
+						},
+						PrimaryKeyColumns: []string{"id"},
+					},
+					{
+						Name:    "orders",
+						Columns: []schemalog.Column{{Name: "password", DataType: "text"}},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		logEntry *schemalog.LogEntry
+		fetchErr error
+
+		wantLogEntry *schemalog.LogEntry
+		wantErr      error
+	}{
+		{
+			name:     "ok",
+			logEntry: testLogEntry("public"),
+
+			wantLogEntry: &schemalog.LogEntry{
+				SchemaName: "public",
+				Version:    1,
+				Schema: schemalog.Schema{
+					Tables: []schemalog.Table{
+						{
+							Name:              "users",
+							Columns:           []schemalog.Column{{Name: "id", DataType: "bigint"}},
+							PrimaryKeyColumns: []string{"id"},
+						},
+						{
+							Name:    "orders",
+							Columns: []schemalog.Column{{Name: "password", DataType: "text"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "ok - schema without projection",
+			logEntry: testLogEntry("other"),
+
+			wantLogEntry: testLogEntry("other"),
+		},
+		{
+			name:     "error - fetching log entry",
+			fetchErr: schemalog.ErrNoRows,
+
+			wantErr: schemalog.ErrNoRows,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// keep a pristine copy to validate the wrapped store entry is not
+			// modified
+			var original *schemalog.LogEntry
+			if tc.logEntry != nil {
+				original = testLogEntry(tc.logEntry.SchemaName)
+			}
+
+			store, err := NewSchemaLogStore(&schemalogmocks.Store{
+				FetchFn: func(ctx context.Context, schemaName string, version int) (*schemalog.LogEntry, error) {
+					return tc.logEntry, tc.fetchErr
+				},
+			}, &Config{
+				Tables: []TableConfig{{Table: "users", ExcludeColumns: []string{"password"}}},
+			})
+			require.NoError(t, err)
+
+			logEntry, err := store.Fetch(context.Background(), "public", 1)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantLogEntry, logEntry)
+			require.Equal(t, original, tc.logEntry)
+		})
+	}
+}
@@ -0,0 +1,276 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package projection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+)
+
+// Projection is a processor wrapper that removes columns from the table WAL
+// events based on the configured per table include/exclude column lists. The
+// schema log events are projected as well, so that the processors that
+// replicate the schema (DDL, search mappings) don't create the removed columns.
+type Projection struct {
+	processor processor.Processor
+	tables    tableProjections
+	logger    loglib.Logger
+}
+
+// tableProjections indexes the table projections by schema and table name.
+type tableProjections map[string]map[string]*tableProjection
+
+type tableProjection struct {
+	includeColumns map[string]struct{}
+	excludeColumns map[string]struct{}
+}
+
+type Option func(*Projection)
+
+var (
+	errMissingTables        = errors.New("missing projection tables configuration")
+	errInvalidTableName     = errors.New("invalid table name format")
+	errDuplicateTable       = errors.New("projection table configured more than once")
+	errIncludeExcludeList   = errors.New("cannot use both include and exclude column lists for the same table")
+	errMissingColumns       = errors.New("missing include or exclude column list")
+	errUnexpectedSchemaType = errors.New("unexpected schema log schema value type")
+)
+
+const (
+	publicSchema = "public"
+
+	schemaLogSchemaNameColumn = "schema_name"
+	schemaLogSchemaColumn     = "schema"
+)
+
+// New will return a projection processor wrapper that will remove columns from
+// the WAL events as per the configuration provided. Only one of include or
+// exclude column list can be provided per table (not both).
+func New(processor processor.Processor, cfg *Config, opts ...Option) (*Projection, error) {
+	tables, err := newTableProjections(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Projection{
+		processor: processor,
+		tables:    tables,
+		logger:    loglib.NewNoopLogger(),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p, nil
+}
+
+func WithLogger(logger loglib.Logger) Option {
+	return func(p *Projection) {
+		p.logger = loglib.NewLogger(logger).WithFields(loglib.Fields{
+			loglib.ModuleField: "wal_projection",
+		})
+	}
+}
+
+func (p *Projection) ProcessWALEvent(ctx context.Context, event *wal.Event) error {
+	if event.Data == nil || event.Data.IsTransactionBoundary() || event.Data.IsLogicalMessage() {
+		return p.processor.ProcessWALEvent(ctx, event)
+	}
+
+	if processor.IsSchemaLogEvent(event.Data) {
+		if err := p.projectSchemaLog(event.Data); err != nil {
+			return fmt.Errorf("projecting schema log event: %w", err)
+		}
+		return p.processor.ProcessWALEvent(ctx, event)
+	}
+
+	if projection := p.tables.get(event.Data.Schema, event.Data.Table); projection != nil {
+		// the identity columns are always kept, so that the targets can
+		// identify the rows
+		keyColIDs := event.Data.Metadata.InternalColIDs
+		event.Data.Columns = projection.projectColumns(event.Data.Columns, keyColIDs)
+		event.Data.Identity = projection.projectColumns(event.Data.Identity, keyColIDs)
+	}
+
+	return p.processor.ProcessWALEvent(ctx, event)
+}
+
+func (p *Projection) Name() string {
+	return p.processor.Name()
+}
+
+func (p *Projection) Close() error {
+	return p.processor.Close()
+}
+
+// projectSchemaLog removes the projected columns from the tables of the schema
+// log entry contained in the wal data on input, so that they're not part of
+// the replicated schema.
+func (p *Projection) projectSchemaLog(data *wal.Data) error {
+	schemaName, ok := columnValue(data.Columns, schemaLogSchemaNameColumn).(string)
+	if !ok || len(p.tables[schemaName]) == 0 {
+		return nil
+	}
+
+	for i, col := range data.Columns {
+		if col.Name != schemaLogSchemaColumn || col.Value == nil {
+			continue
+		}
+
+		schemaStr, ok := col.Value.(string)
+		if !ok {
+			return fmt.Errorf("%w: %T", errUnexpectedSchemaType, col.Value)
+		}
+
+		schema := schemalog.Schema{}
+		if err := json.Unmarshal([]byte(schemaStr), &schema); err != nil {
+			return fmt.Errorf("unmarshaling schema: %w", err)
+		}
+
+		p.tables.projectSchema(schemaName, &schema)
+
+		// the schema log stores the schema as a json string, so the schema
+		// custom json marshaling is not used
+		type schemaAlias schemalog.Schema
+		projectedSchema, err := json.Marshal(schemaAlias(schema))
+		if err != nil {
+			return fmt.Errorf("marshaling schema: %w", err)
+		}
+		data.Columns[i].Value = string(projectedSchema)
+	}
+
+	return nil
+}
+
+func newTableProjections(cfg *Config) (tableProjections, error) {
+	if len(cfg.Tables) == 0 {
+		return nil, errMissingTables
+	}
+
+	tables := make(tableProjections, len(cfg.Tables))
+	for _, tableCfg := range cfg.Tables {
+		schema, table, err := parseTableName(tableCfg.Table)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, tableCfg.Table)
+		}
+		if tables.get(schema, table) != nil {
+			return nil, fmt.Errorf("%w: %s", errDuplicateTable, tableCfg.Table)
+		}
+		projection, err := newTableProjection(tableCfg)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", tableCfg.Table, err)
+		}
+		if _, found := tables[schema]; !found {
+			tables[schema] = map[string]*tableProjection{}
+		}
+		tables[schema][table] = projection
+	}
+	return tables, nil
+}
+
+func (tp tableProjections) get(schema, table string) *tableProjection {
+	return tp[schema][table]
+}
+
+// projectSchema removes the projected columns from the tables of the schema on
+// input. The table column slices are replaced, not modified in place.
+func (tp tableProjections) projectSchema(schemaName string, schema *schemalog.Schema) {
+	for i := range schema.Tables {
+		if projection := tp.get(schemaName, schema.Tables[i].Name); projection != nil {
+			projection.projectTable(&schema.Tables[i])
+		}
+	}
+}
+
+func newTableProjection(cfg TableConfig) (*tableProjection, error) {
+	switch {
+	case len(cfg.IncludeColumns) > 0 && len(cfg.ExcludeColumns) > 0:
+		return nil, errIncludeExcludeList
+	case len(cfg.IncludeColumns) == 0 && len(cfg.ExcludeColumns) == 0:
+		return nil, errMissingColumns
+	}
+
+	return &tableProjection{
+		includeColumns: columnSet(cfg.IncludeColumns),
+		excludeColumns: columnSet(cfg.ExcludeColumns),
+	}, nil
+}
+
+func (t *tableProjection) keep(column string) bool {
+	if t.includeColumns != nil {
+		_, found := t.includeColumns[column]
+		return found
+	}
+	_, found := t.excludeColumns[column]
+	return !found
+}
+
+// projectColumns removes the projected columns from the columns on input,
+// keeping the ones with the key column ids provided.
+func (t *tableProjection) projectColumns(columns []wal.Column, keyColIDs []string) []wal.Column {
+	if columns == nil {
+		return nil
+	}
+	projected := make([]wal.Column, 0, len(columns))
+	for _, col := range columns {
+		if t.keep(col.Name) || (col.ID != "" && slices.Contains(keyColIDs, col.ID)) {
+			projected = append(projected, col)
+		}
+	}
+	return projected
+}
+
+// projectTable removes the projected columns from the table on input. The
+// primary key columns are always kept, so that the targets can identify the
+// rows.
+func (t *tableProjection) projectTable(table *schemalog.Table) {
+	columns := make([]schemalog.Column, 0, len(table.Columns))
+	for _, col := range table.Columns {
+		if t.keep(col.Name) || slices.Contains(table.PrimaryKeyColumns, col.Name) {
+			columns = append(columns, col)
+		}
+	}
+	table.Columns = columns
+}
+
+func columnSet(columns []string) map[string]struct{} {
+	if len(columns) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(columns))
+	for _, col := range columns {
+		set[col] = struct{}{}
+	}
+	return set
+}
+
+func columnValue(columns []wal.Column, name string) any {
+	for _, col := range columns {
+		if col.Name == name {
+			return col.Value
+		}
+	}
+	return nil
+}
+
+func parseTableName(qualifiedTableName string) (string, string, error) {
+	parts := strings.Split(qualifiedTableName, ".")
+	switch len(parts) {
+	case 1:
+		return publicSchema, parts[0], nil
+	case 2:
+		return parts[0], parts[1], nil
+	default:
+		return "", "", errInvalidTableName
+	}
+}
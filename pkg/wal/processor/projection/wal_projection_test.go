@@ -0,0 +1,339 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package projection
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/mocks"
+)
+
+var errTest = errors.New("oh noes")
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config *Config
+
+		wantTables tableProjections
+		wantErr    error
+	}{
+		{
+			name: "ok",
+			config: &Config{
+				Tables: []TableConfig{
+					{Table: "users", ExcludeColumns: []string{"password"}},
+					{Table: "billing.invoices", IncludeColumns: []string{"id", "total"}},
+				},
+			},
+
+			wantTables: tableProjections{
+				"public": {
+					"users": {excludeColumns: map[string]struct{}{"password": {}}},
+				},
+				"billing": {
+					"invoices": {includeColumns: map[string]struct{}{"id": {}, "total": {}}},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "error - missing tables",
+			config: &Config{},
+
+			wantErr: errMissingTables,
+		},
+		{
+			name: "error - invalid table name",
+			config: &Config{
+				Tables: []TableConfig{{Table: "a.b.c", ExcludeColumns: []string{"a"}}},
+			},
+
+			wantErr: errInvalidTableName,
+		},
+		{
+			name: "error - duplicate table",
+			config: &Config{
+				Tables: []TableConfig{
+					{Table: "users", ExcludeColumns: []string{"a"}},
+					{Table: "public.users", ExcludeColumns: []string{"b"}},
+				},
+			},
+
+			wantErr: errDuplicateTable,
+		},
+		{
+			name: "error - include and exclude columns",
+			config: &Config{
+				Tables: []TableConfig{
+					{Table: "users", IncludeColumns: []string{"a"}, ExcludeColumns: []string{"b"}},
+				},
+			},
+
+			wantErr: errIncludeExcludeList,
+		},
+		{
+			name: "error - missing columns",
+			config: &Config{
+				Tables: []TableConfig{{Table: "users"}},
+			},
+
+			wantErr: errMissingColumns,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := New(&mocks.Processor{}, tc.config)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantErr != nil {
+				return
+			}
+			require.Equal(t, tc.wantTables, p.tables)
+		})
+	}
+}
+
+func TestProjection_ProcessWALEvent(t *testing.T) {
+	t.Parallel()
+
+	testTables := tableProjections{
+		"public": {
+			"users": {excludeColumns: map[string]struct{}{"password": {}}},
+		},
+		"billing": {
+			"invoices": {includeColumns: map[string]struct{}{"id": {}, "total": {}}},
+		},
+	}
+
+	testSchemaLogEvent := func(schemaName, schema string) *wal.Event {
+		return &wal.Event{
+			Data: &wal.Data{
+				Action: "I",
+				Schema: schemalog.SchemaName,
+				Table:  schemalog.TableName,
+				Columns: []wal.Column{
+					{Name: "id", Value: "cjohn8pmhvfs72p2osqg"},
+					{Name: "schema_name", Value: schemaName},
+					{Name: "schema", Value: schema},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		event   *wal.Event
+		procErr error
+
+		wantEvent *wal.Event
+		wantErr   error
+	}{
+		{
+			name: "ok - exclude columns",
+			event: &wal.Event{
+				Data: &wal.Data{
+					Action: "U",
+					Schema: "public",
+					Table:  "users",
+					Columns: []wal.Column{
+						{Name: "id", Value: float64(1)},
+						{Name: "email", Value: "a@b.c"},
+						{Name: "password", Value: "secret"},
+					},
+					Identity: []wal.Column{
+						{Name: "id", Value: float64(1)},
+						{Name: "password", Value: "old-secret"},
+					},
+				},
+			},
+
+			wantEvent: &wal.Event{
+				Data: &wal.Data{
+					Action: "U",
+					Schema: "public",
+					Table:  "users",
+					Columns: []wal.Column{
+						{Name: "id", Value: float64(1)},
+						{Name: "email", Value: "a@b.c"},
+					},
+					Identity: []wal.Column{
+						{Name: "id", Value: float64(1)},
+					},
+				},
+			},
+		},
+		{
+			name: "ok - include columns",
+			event: &wal.Event{
+				Data: &wal.Data{
+					Action: "I",
+					Schema: "billing",
+					Table:  "invoices",
+					Columns: []wal.Column{
+						{Name: "id", Value: float64(1)},
+						{Name: "total", Value: float64(10)},
+						{Name: "notes", Value: "private"},
+					},
+				},
+			},
+
+			wantEvent: &wal.Event{
+				Data: &wal.Data{
+					Action: "I",
+					Schema: "billing",
+					Table:  "invoices",
+					Columns: []wal.Column{
+						{Name: "id", Value: float64(1)},
+						{Name: "total", Value: float64(10)},
+					},
+				},
+			},
+		},
+		{
+			name: "ok - identity columns are kept",
+			event: &wal.Event{
+				Data: &wal.Data{
+					Action: "U",
+					Schema: "billing",
+					Table:  "invoices",
+					Columns: []wal.Column{
+						{ID: "1_1", Name: "number", Value: float64(1)},
+						{ID: "1_2", Name: "total", Value: float64(10)},
+						{ID: "1_3", Name: "notes", Value: "private"},
+					},
+					Identity: []wal.Column{
+						{ID: "1_1", Name: "number", Value: float64(1)},
+					},
+					Metadata: wal.Metadata{InternalColIDs: []string{"1_1"}},
+				},
+			},
+
+			wantEvent: &wal.Event{
+				Data: &wal.Data{
+					Action: "U",
+					Schema: "billing",
+					Table:  "invoices",
+					Columns: []wal.Column{
+						{ID: "1_1", Name: "number", Value: float64(1)},
+						{ID: "1_2", Name: "total", Value: float64(10)},
+					},
+					Identity: []wal.Column{
+						{ID: "1_1", Name: "number", Value: float64(1)},
+					},
+					Metadata: wal.Metadata{InternalColIDs: []string{"1_1"}},
+				},
+			},
+		},
+		{
+			name: "ok - table without projection",
+			event: &wal.Event{
+				Data: &wal.Data{
+					Action:  "I",
+					Schema:  "public",
+					Table:   "orders",
+					Columns: []wal.Column{{Name: "password", Value: "a"}},
+				},
+			},
+
+			wantEvent: &wal.Event{
+				Data: &wal.Data{
+					Action:  "I",
+					Schema:  "public",
+					Table:   "orders",
+					Columns: []wal.Column{{Name: "password", Value: "a"}},
+				},
+			},
+		},
+		{
+			name: "ok - keep alive",
+			event: &wal.Event{
+				CommitPosition: wal.CommitPosition("0/15D6A28"),
+			},
+
+			wantEvent: &wal.Event{
+				CommitPosition: wal.CommitPosition("0/15D6A28"),
+			},
+		},
+		{
+			name: "ok - schema log event",
+			event: testSchemaLogEvent("public",
+				`{"tables":[{"oid":"1","name":"users","columns":[{"name":"id","type":"bigint","nullable":false,"generated":false,"unique":true,"metadata":null,"pgstream_id":"1_1"},{"name":"password","type":"text","nullable":true,"generated":false,"unique":false,"metadata":null,"pgstream_id":"1_2"}],"primary_key_columns":["id"],"pgstream_id":"1"},{"oid":"2","name":"orders","columns":[{"name":"password","type":"text","nullable":true,"generated":false,"unique":false,"metadata":null,"pgstream_id":"2_1"}],"primary_key_columns":null,"pgstream_id":"2"}]}`),
+
+			wantEvent: testSchemaLogEvent("public",
+				`{"tables":[{"oid":"1","name":"users","columns":[{"name":"id","type":"bigint","nullable":false,"generated":false,"unique":true,"metadata":null,"pgstream_id":"1_1"}],"primary_key_columns":["id"],"pgstream_id":"1"},{"oid":"2","name":"orders","columns":[{"name":"password","type":"text","nullable":true,"generated":false,"unique":false,"metadata":null,"pgstream_id":"2_1"}],"primary_key_columns":null,"pgstream_id":"2"}]}`),
+		},
+		{
+			name: "ok - schema log event with projected primary key",
+			event: testSchemaLogEvent("billing",
+				`{"tables":[{"oid":"1","name":"invoices","columns":[{"name":"number","type":"bigint","nullable":false,"generated":false,"unique":true,"metadata":null,"pgstream_id":"1_1"},{"name":"total","type":"numeric","nullable":true,"generated":false,"unique":false,"metadata":null,"pgstream_id":"1_2"},{"name":"notes","type":"text","nullable":true,"generated":false,"unique":false,"metadata":null,"pgstream_id":"1_3"}],"primary_key_columns":["number"],"pgstream_id":"1"}]}`),
+
+			wantEvent: testSchemaLogEvent("billing",
+				`{"tables":[{"oid":"1","name":"invoices","columns":[{"name":"number","type":"bigint","nullable":false,"generated":false,"unique":true,"metadata":null,"pgstream_id":"1_1"},{"name":"total","type":"numeric","nullable":true,"generated":false,"unique":false,"metadata":null,"pgstream_id":"1_2"}],"primary_key_columns":["number"],"pgstream_id":"1"}]}`),
+		},
+		{
+			name:  "ok - schema log event for schema without projection",
+			event: testSchemaLogEvent("other", `invalid`),
+
+			wantEvent: testSchemaLogEvent("other", `invalid`),
+		},
+		{
+			name: "error - processing event",
+			event: &wal.Event{
+				Data: &wal.Data{Action: "I", Schema: "public", Table: "orders"},
+			},
+			procErr: errTest,
+
+			wantEvent: &wal.Event{
+				Data: &wal.Data{Action: "I", Schema: "public", Table: "orders"},
+			},
+			wantErr: errTest,
+		},
+		{
+			name: "error - unexpected schema log schema type",
+			event: &wal.Event{
+				Data: &wal.Data{
+					Action: "I",
+					Schema: schemalog.SchemaName,
+					Table:  schemalog.TableName,
+					Columns: []wal.Column{
+						{Name: "schema_name", Value: "public"},
+						{Name: "schema", Value: float64(1)},
+					},
+				},
+			},
+
+			wantErr: errUnexpectedSchemaType,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &Projection{
+				processor: &mocks.Processor{
+					ProcessWALEventFn: func(ctx context.Context, event *wal.Event) error {
+						require.Equal(t, tc.wantEvent, event)
+						return tc.procErr
+					},
+				},
+				tables: testTables,
+				logger: loglib.NewNoopLogger(),
+			}
+
+			err := p.ProcessWALEvent(context.Background(), tc.event)
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
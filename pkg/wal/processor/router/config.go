@@ -0,0 +1,22 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+type Config struct {
+	// Rules contains the ordered list of routing rules. The first rule
+	// matching a table is applied, and tables not matching any rule keep their
+	// original schema and table names.
+	Rules []RuleConfig
+}
+
+type RuleConfig struct {
+	// Source is the schema qualified table pattern the rule applies to. Both
+	// the schema and the table support glob patterns (i.e. "public.*",
+	// "tenant_*.users"). If no schema is provided, the public schema will be
+	// assumed.
+	Source string
+	// Target is the schema qualified template of the routed table name. The
+	// {schema} and {table} placeholders are replaced with the source schema
+	// and table names (i.e. "tenant_a.{table}", "{schema}_archive.{table}").
+	Target string
+}
@@ -0,0 +1,199 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// TableRouter maps source schema and table names to their target names, as
+// defined by the configured routing rules.
+type TableRouter struct {
+	rules []*rule
+}
+
+type rule struct {
+	// glob patterns
+	sourceSchema string
+	sourceTable  string
+	// templates
+	targetSchema string
+	targetTable  string
+}
+
+var (
+	errMissingRules     = errors.New("missing routing rules")
+	errInvalidTableName = errors.New("invalid table name format")
+	errInvalidPattern   = errors.New("invalid routing rule source pattern")
+	errInvalidTemplate  = errors.New("invalid routing rule target template")
+)
+
+const (
+	publicSchema = "public"
+	wildcard     = "*"
+
+	schemaPlaceholder = "{schema}"
+	tablePlaceholder  = "{table}"
+)
+
+var placeholderRegex = regexp.MustCompile(`\{[^{}]*\}`)
+
+// NewTableRouter returns a table router for the routing rules on input.
+func NewTableRouter(cfg *Config) (*TableRouter, error) {
+	if len(cfg.Rules) == 0 {
+		return nil, errMissingRules
+	}
+
+	r := &TableRouter{
+		rules: make([]*rule, 0, len(cfg.Rules)),
+	}
+	for _, ruleCfg := range cfg.Rules {
+		rule, err := newRule(ruleCfg)
+		if err != nil {
+			return nil, fmt.Errorf("routing rule %s -> %s: %w", ruleCfg.Source, ruleCfg.Target, err)
+		}
+		r.rules = append(r.rules, rule)
+	}
+
+	return r, nil
+}
+
+// Route returns the target schema and table names for the source table on
+// input. Tables not matching any rule keep their names.
+func (r *TableRouter) Route(schema, table string) (string, string) {
+	if r == nil {
+		return schema, table
+	}
+	for _, rule := range r.rules {
+		if rule.matches(schema, table) {
+			return rule.render(rule.targetSchema, schema, table), rule.render(rule.targetTable, schema, table)
+		}
+	}
+	return schema, table
+}
+
+// RouteSchema returns the target schema for the source schema on input, as
+// defined by the first rule that applies to all the tables of the schema.
+// Table specific rules are not taken into account. Schemas not matching any
+// rule keep their name.
+func (r *TableRouter) RouteSchema(schema string) string {
+	if r == nil {
+		return schema
+	}
+	for _, rule := range r.rules {
+		if rule.isSchemaRule() && rule.matchesSchema(schema) {
+			return rule.render(rule.targetSchema, schema, "")
+		}
+	}
+	return schema
+}
+
+// SplitsSchemas returns true if the tables of a source schema can be routed to
+// different target schemas. When that's the case, the schema level routing
+// (RouteSchema) is not consistent with the table level routing (Route).
+func (r *TableRouter) SplitsSchemas() bool {
+	for _, rule := range r.rules {
+		switch {
+		case strings.Contains(rule.targetSchema, tablePlaceholder):
+			return true
+		case rule.isSchemaRule():
+			continue
+		case !isLiteral(rule.sourceSchema):
+			if rule.targetSchema != schemaPlaceholder || r.hasSchemaRules() {
+				return true
+			}
+		default:
+			if rule.render(rule.targetSchema, rule.sourceSchema, "") != r.RouteSchema(rule.sourceSchema) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *TableRouter) hasSchemaRules() bool {
+	for _, rule := range r.rules {
+		if rule.isSchemaRule() {
+			return true
+		}
+	}
+	return false
+}
+
+func newRule(cfg RuleConfig) (*rule, error) {
+	sourceSchema, sourceTable, err := parseTableName(cfg.Source)
+	if err != nil {
+		return nil, err
+	}
+	for _, pattern := range []string{sourceSchema, sourceTable} {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return nil, fmt.Errorf("%w: %q", errInvalidPattern, pattern)
+		}
+	}
+
+	targetParts := strings.Split(cfg.Target, ".")
+	if len(targetParts) != 2 {
+		return nil, fmt.Errorf("%w: target must be schema qualified", errInvalidTemplate)
+	}
+	for _, template := range targetParts {
+		if template == "" {
+			return nil, fmt.Errorf("%w: empty name", errInvalidTemplate)
+		}
+		for _, placeholder := range placeholderRegex.FindAllString(template, -1) {
+			if placeholder != schemaPlaceholder && placeholder != tablePlaceholder {
+				return nil, fmt.Errorf("%w: unsupported placeholder %s", errInvalidTemplate, placeholder)
+			}
+		}
+	}
+
+	return &rule{
+		sourceSchema: sourceSchema,
+		sourceTable:  sourceTable,
+		targetSchema: targetParts[0],
+		targetTable:  targetParts[1],
+	}, nil
+}
+
+func (r *rule) matches(schema, table string) bool {
+	return r.matchesSchema(schema) && matchPattern(r.sourceTable, table)
+}
+
+func (r *rule) matchesSchema(schema string) bool {
+	return matchPattern(r.sourceSchema, schema)
+}
+
+// isSchemaRule returns true if the rule applies to all the tables of the
+// matching schemas, and routes them to the same target schema.
+func (r *rule) isSchemaRule() bool {
+	return r.sourceTable == wildcard && !strings.Contains(r.targetSchema, tablePlaceholder)
+}
+
+func (r *rule) render(template, schema, table string) string {
+	return strings.NewReplacer(schemaPlaceholder, schema, tablePlaceholder, table).Replace(template)
+}
+
+func matchPattern(pattern, name string) bool {
+	// patterns are validated on creation
+	matched, _ := path.Match(pattern, name)
+	return matched
+}
+
+func isLiteral(pattern string) bool {
+	return !strings.ContainsAny(pattern, `*?[\`)
+}
+
+func parseTableName(qualifiedTableName string) (string, string, error) {
+	parts := strings.Split(qualifiedTableName, ".")
+	switch len(parts) {
+	case 1:
+		return publicSchema, parts[0], nil
+	case 2:
+		return parts[0], parts[1], nil
+	default:
+		return "", "", errInvalidTableName
+	}
+}
@@ -0,0 +1,243 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTableRouter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config *Config
+
+		wantRules []*rule
+		wantErr   error
+	}{
+		{
+			name: "ok",
+			config: &Config{
+				Rules: []RuleConfig{
+					{Source: "users", Target: "tenant_a.users"},
+					{Source: "tenant_*.*", Target: "{schema}_archive.{table}"},
+				},
+			},
+
+			wantRules: []*rule{
+				{sourceSchema: "public", sourceTable: "users", targetSchema: "tenant_a", targetTable: "users"},
+				{sourceSchema: "tenant_*", sourceTable: "*", targetSchema: "{schema}_archive", targetTable: "{table}"},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "error - missing rules",
+			config: &Config{},
+
+			wantErr: errMissingRules,
+		},
+		{
+			name: "error - invalid source table name",
+			config: &Config{
+				Rules: []RuleConfig{{Source: "a.b.c", Target: "a.b"}},
+			},
+
+			wantErr: errInvalidTableName,
+		},
+		{
+			name: "error - invalid source pattern",
+			config: &Config{
+				Rules: []RuleConfig{{Source: "public.[a", Target: "a.b"}},
+			},
+
+			wantErr: errInvalidPattern,
+		},
+		{
+			name: "error - unqualified target",
+			config: &Config{
+				Rules: []RuleConfig{{Source: "public.users", Target: "users"}},
+			},
+
+			wantErr: errInvalidTemplate,
+		},
+		{
+			name: "error - unsupported target placeholder",
+			config: &Config{
+				Rules: []RuleConfig{{Source: "public.users", Target: "{database}.{table}"}},
+			},
+
+			wantErr: errInvalidTemplate,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := NewTableRouter(tc.config)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantErr != nil {
+				return
+			}
+			require.Equal(t, tc.wantRules, r.rules)
+		})
+	}
+}
+
+func TestTableRouter_Route(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewTableRouter(&Config{
+		Rules: []RuleConfig{
+			{Source: "public.users", Target: "tenant_a.accounts"},
+			{Source: "public.*", Target: "tenant_a.{table}"},
+			{Source: "tenant_*.*", Target: "{schema}_archive.{schema}_{table}"},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		schema string
+		table  string
+
+		wantSchema string
+		wantTable  string
+	}{
+		{
+			name:   "table rule",
+			schema: "public",
+			table:  "users",
+
+			wantSchema: "tenant_a",
+			wantTable:  "accounts",
+		},
+		{
+			name:   "schema rule",
+			schema: "public",
+			table:  "orders",
+
+			wantSchema: "tenant_a",
+			wantTable:  "orders",
+		},
+		{
+			name:   "pattern rule",
+			schema: "tenant_b",
+			table:  "orders",
+
+			wantSchema: "tenant_b_archive",
+			wantTable:  "tenant_b_orders",
+		},
+		{
+			name:   "no matching rule",
+			schema: "other",
+			table:  "orders",
+
+			wantSchema: "other",
+			wantTable:  "orders",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			schema, table := r.Route(tc.schema, tc.table)
+			require.Equal(t, tc.wantSchema, schema)
+			require.Equal(t, tc.wantTable, table)
+		})
+	}
+}
+
+func TestTableRouter_RouteSchema(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewTableRouter(&Config{
+		Rules: []RuleConfig{
+			{Source: "public.users", Target: "tenant_b.users"},
+			{Source: "public.*", Target: "tenant_a.{table}"},
+			{Source: "sales.*", Target: "{table}.data"},
+		},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "tenant_a", r.RouteSchema("public"))
+	require.Equal(t, "sales", r.RouteSchema("sales"))
+	require.Equal(t, "other", r.RouteSchema("other"))
+}
+
+func TestTableRouter_SplitsSchemas(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		rules []RuleConfig
+
+		wantSplits bool
+	}{
+		{
+			name: "schema rules",
+			rules: []RuleConfig{
+				{Source: "public.*", Target: "tenant_a.{table}"},
+				{Source: "*.*", Target: "source_a_{schema}.{schema}_{table}"},
+			},
+
+			wantSplits: false,
+		},
+		{
+			name: "table rule consistent with schema rule",
+			rules: []RuleConfig{
+				{Source: "public.users", Target: "tenant_a.accounts"},
+				{Source: "public.*", Target: "tenant_a.{table}"},
+			},
+
+			wantSplits: false,
+		},
+		{
+			name: "table rename within schema",
+			rules: []RuleConfig{
+				{Source: "*.users", Target: "{schema}.accounts"},
+			},
+
+			wantSplits: false,
+		},
+		{
+			name: "table routed to a different schema",
+			rules: []RuleConfig{
+				{Source: "public.users", Target: "tenant_a.users"},
+			},
+
+			wantSplits: true,
+		},
+		{
+			name: "pattern table rule with schema rules",
+			rules: []RuleConfig{
+				{Source: "*.users", Target: "{schema}.accounts"},
+				{Source: "public.*", Target: "tenant_a.{table}"},
+			},
+
+			wantSplits: true,
+		},
+		{
+			name: "target schema depends on table",
+			rules: []RuleConfig{
+				{Source: "public.*", Target: "{table}.data"},
+			},
+
+			wantSplits: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := NewTableRouter(&Config{Rules: tc.rules})
+			require.NoError(t, err)
+			require.Equal(t, tc.wantSplits, r.SplitsSchemas())
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"context"
+
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+)
+
+// Router is a processor wrapper that rewrites the schema and table names of
+// the table WAL events as per the configured routing rules. Schema log events
+// are passed through unchanged, since the schema log versions are tracked per
+// source schema. The processors that replicate the schema are expected to
+// apply the same routing to them.
+type Router struct {
+	processor   processor.Processor
+	tableRouter *TableRouter
+	logger      loglib.Logger
+}
+
+type Option func(*Router)
+
+// New will return a router processor wrapper that will rewrite the WAL events
+// schema and table names as per the configuration provided.
+func New(processor processor.Processor, cfg *Config, opts ...Option) (*Router, error) {
+	tableRouter, err := NewTableRouter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Router{
+		processor:   processor,
+		tableRouter: tableRouter,
+		logger:      loglib.NewNoopLogger(),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r, nil
+}
+
+func WithLogger(logger loglib.Logger) Option {
+	return func(r *Router) {
+		r.logger = loglib.NewLogger(logger).WithFields(loglib.Fields{
+			loglib.ModuleField: "wal_router",
+		})
+	}
+}
+
+func (r *Router) ProcessWALEvent(ctx context.Context, event *wal.Event) error {
+	if event.Data == nil || event.Data.IsTransactionBoundary() || event.Data.IsLogicalMessage() || processor.IsSchemaLogEvent(event.Data) {
+		return r.processor.ProcessWALEvent(ctx, event)
+	}
+
+	schema, table := r.tableRouter.Route(event.Data.Schema, event.Data.Table)
+	if schema != event.Data.Schema || table != event.Data.Table {
+		r.logger.Trace("routing event", loglib.Fields{
+			"schema": event.Data.Schema, "table": event.Data.Table,
+			"target_schema": schema, "target_table": table,
+		})
+		event.Data.Schema = schema
+		event.Data.Table = table
+	}
+
+	return r.processor.ProcessWALEvent(ctx, event)
+}
+
+func (r *Router) Name() string {
+	return r.processor.Name()
+}
+
+func (r *Router) Close() error {
+	return r.processor.Close()
+}
@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/mocks"
+)
+
+var errTest = errors.New("oh noes")
+
+func TestRouter_ProcessWALEvent(t *testing.T) {
+	t.Parallel()
+
+	testTableRouter, err := NewTableRouter(&Config{
+		Rules: []RuleConfig{
+			{Source: "*.*", Target: "tenant_a_{schema}.{table}"},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		event   *wal.Event
+		procErr error
+
+		wantEvent *wal.Event
+		wantErr   error
+	}{
+		{
+			name: "ok - routed event",
+			event: &wal.Event{
+				Data: &wal.Data{
+					Action:  "I",
+					Schema:  "public",
+					Table:   "users",
+					Columns: []wal.Column{{Name: "id", Value: float64(1)}},
+				},
+			},
+
+			wantEvent: &wal.Event{
+				Data: &wal.Data{
+					Action:  "I",
+					Schema:  "tenant_a_public",
+					Table:   "users",
+					Columns: []wal.Column{{Name: "id", Value: float64(1)}},
+				},
+			},
+		},
+		{
+			name: "ok - schema log event",
+			event: &wal.Event{
+				Data: &wal.Data{Action: "I", Schema: schemalog.SchemaName, Table: schemalog.TableName},
+			},
+
+			wantEvent: &wal.Event{
+				Data: &wal.Data{Action: "I", Schema: schemalog.SchemaName, Table: schemalog.TableName},
+			},
+		},
+		{
+			name: "ok - logical message",
+			event: &wal.Event{
+				Data: &wal.Data{Action: "M", Prefix: "app"},
+			},
+
+			wantEvent: &wal.Event{
+				Data: &wal.Data{Action: "M", Prefix: "app"},
+			},
+		},
+		{
+			name: "ok - keep alive",
+			event: &wal.Event{
+				CommitPosition: wal.CommitPosition("0/15D6A28"),
+			},
+
+			wantEvent: &wal.Event{
+				CommitPosition: wal.CommitPosition("0/15D6A28"),
+			},
+		},
+		{
+			name: "error - processing event",
+			event: &wal.Event{
+				Data: &wal.Data{Action: "D", Schema: "public", Table: "users"},
+			},
+			procErr: errTest,
+
+			wantEvent: &wal.Event{
+				Data: &wal.Data{Action: "D", Schema: "tenant_a_public", Table: "users"},
+			},
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &Router{
+				processor: &mocks.Processor{
+					ProcessWALEventFn: func(ctx context.Context, event *wal.Event) error {
+						require.Equal(t, tc.wantEvent, event)
+						return tc.procErr
+					},
+				},
+				tableRouter: testTableRouter,
+				logger:      loglib.NewNoopLogger(),
+			}
+
+			err := r.ProcessWALEvent(context.Background(), tc.event)
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
@@ -61,6 +61,12 @@ func (a *adapter) walEventToMsg(e *wal.Event) (*msg, error) {
 		}, nil
 	}
 
+	// transaction boundaries and logical messages are not linked to any
+	// table, there's nothing to index
+	if e.Data.IsTransactionBoundary() || e.Data.IsLogicalMessage() {
+		return nil, nil
+	}
+
 	if e.Data.Metadata.IsEmpty() {
 		return nil, errMetadataMissing
 	}
@@ -71,6 +77,7 @@ func (a *adapter) walEventToMsg(e *wal.Event) (*msg, error) {
 		if err != nil {
 			return nil, err
 		}
+		doc.Event = e
 		size, err := a.documentSize(doc)
 		if err != nil {
 			return nil, fmt.Errorf("calculating document size: %w", err)
@@ -124,7 +131,13 @@ func (a *adapter) walDataToDocument(data *wal.Data) (*Document, error) {
 	var err error
 	switch data.Action {
 	case "D":
-		doc, err = a.parseColumns(data.Identity, data.Metadata, data.LSN)
+		// deletes produced from updates (i.e. rows moving out of a row filter)
+		// might not have identity values, use the row columns instead
+		identity := data.Identity
+		if len(identity) == 0 {
+			identity = data.Columns
+		}
+		doc, err = a.parseColumns(identity, data.Metadata, data.LSN)
 		if err != nil {
 			return nil, err
 		}
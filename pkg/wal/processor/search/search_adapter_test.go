@@ -76,7 +76,11 @@ func TestAdapter_walEventToMsg(t *testing.T) {
 			marshaler: func(a any) ([]byte, error) { return testDocBytes, nil },
 
 			wantMsg: &msg{
-				write:     newTestDocument(),
+				write: func() *Document {
+					doc := newTestDocument()
+					doc.Event = newTestDataEvent("I")
+					return doc
+				}(),
 				bytesSize: len(testDocBytes),
 			},
 			wantErr: nil,
@@ -101,6 +105,20 @@ func TestAdapter_walEventToMsg(t *testing.T) {
 			wantMsg: nil,
 			wantErr: nil,
 		},
+		{
+			name:  "ok - transaction boundary events without metadata",
+			event: &wal.Event{Data: &wal.Data{Action: "C", XID: 1}},
+
+			wantMsg: nil,
+			wantErr: nil,
+		},
+		{
+			name:  "ok - logical message events",
+			event: &wal.Event{Data: &wal.Data{Action: "M", Prefix: "test"}},
+
+			wantMsg: nil,
+			wantErr: nil,
+		},
 		{
 			name:      "error - data event document size",
 			event:     newTestDataEvent("I"),
@@ -338,6 +356,28 @@ func TestAdapter_walDataToDocument(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name:   "ok - delete event without identity",
+			mapper: noopMapper,
+			data: func() *wal.Data {
+				d := newTestDataEvent("D").Data
+				d.Columns = d.Identity
+				d.Identity = nil
+				return d
+			}(),
+
+			wantDoc: &Document{
+				ID:      fmt.Sprintf("%s_id-1", testTableID),
+				Version: 1,
+				Schema:  testSchema,
+				Data: map[string]any{
+					"col-3":  "a",
+					"_table": testTableID,
+				},
+				Delete: true,
+			},
+			wantErr: nil,
+		},
 		{
 			name:   "error - parsing columns with insert event",
 			mapper: noopMapper,
@@ -12,6 +12,7 @@ import (
 	"github.com/xataio/pgstream/pkg/backoff"
 	loglib "github.com/xataio/pgstream/pkg/log"
 	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
 )
 
 // StoreRetrier applies a retry strategy to failed search store operations.
@@ -20,6 +21,9 @@ type StoreRetrier struct {
 	logger          loglib.Logger
 	backoffProvider backoff.Provider
 	marshaler       func(any) ([]byte, error)
+	// deadLetterQueue receives the events of the documents that fail to be
+	// sent after all retries. If not set, the documents are dropped.
+	deadLetterQueue dlq.Queue
 }
 
 type StoreRetryConfig struct {
@@ -59,6 +63,12 @@ func WithStoreLogger(logger loglib.Logger) StoreOption {
 	}
 }
 
+func WithStoreDeadLetterQueue(q dlq.Queue) StoreOption {
+	return func(sr *StoreRetrier) {
+		sr.deadLetterQueue = q
+	}
+}
+
 func (s *StoreRetrier) GetMapper() Mapper {
 	return s.inner.GetMapper()
 }
@@ -95,8 +105,11 @@ func (s *StoreRetrier) SendDocuments(ctx context.Context, docs []Document) ([]Do
 	docsToSend := docs
 	failedDocs := []DocumentError{}
 	docsDropped := []DocumentError{}
+	deadLetters := []*dlq.Entry{}
+	attempts := 0
 	send := func(ctx context.Context) error {
 		total := len(docsToSend)
+		attempts++
 		var err error
 		failedDocs, err = s.inner.SendDocuments(ctx, docsToSend)
 		if err != nil {
@@ -106,6 +119,7 @@ func (s *StoreRetrier) SendDocuments(ctx context.Context, docs []Document) ([]Do
 		var dropped []DocumentError
 		docsToSend, dropped = s.getRetriableDocs(failedDocs)
 		docsDropped = append(docsDropped, dropped...)
+		deadLetters = append(deadLetters, s.deadLetterEntries(dropped, attempts)...)
 		// nothing to retry
 		if len(docsToSend) == 0 {
 			return nil
@@ -133,6 +147,18 @@ func (s *StoreRetrier) SendDocuments(ctx context.Context, docs []Document) ([]Do
 	if err != nil {
 		// some documents failed to send - return back whatever failed
 		if errors.Is(err, errPartialDocumentSend) {
+			// the retriable documents that are still failing after all the
+			// retries are dropped too
+			retriableFailedDocs := make([]DocumentError, 0, len(failedDocs))
+			for _, f := range failedDocs {
+				if f.Severity == SeverityRetriable {
+					retriableFailedDocs = append(retriableFailedDocs, f)
+				}
+			}
+			deadLetters = append(deadLetters, s.deadLetterEntries(retriableFailedDocs, attempts)...)
+			if err := s.sendToDeadLetterQueue(ctx, deadLetters); err != nil {
+				return append(failedDocs, docsDropped...), err
+			}
 			return append(failedDocs, docsDropped...), nil
 		}
 		// internal search store error points to something wrong, return the error
@@ -140,9 +166,37 @@ func (s *StoreRetrier) SendDocuments(ctx context.Context, docs []Document) ([]Do
 		return append(failedDocs, docsDropped...), err
 	}
 
+	if err := s.sendToDeadLetterQueue(ctx, deadLetters); err != nil {
+		return docsDropped, err
+	}
+
 	return docsDropped, nil
 }
 
+// deadLetterEntries returns the dead letter queue entries for the failed
+// documents on input. It returns no entries if there's no dead letter queue
+// configured.
+func (s *StoreRetrier) deadLetterEntries(docErrs []DocumentError, attempts int) []*dlq.Entry {
+	if s.deadLetterQueue == nil || len(docErrs) == 0 {
+		return nil
+	}
+
+	entries := make([]*dlq.Entry, 0, len(docErrs))
+	for _, docErr := range docErrs {
+		entries = append(entries, dlq.NewEntry(docErr.Document.Event, "search_store_retrier", errors.New(docErr.Error), attempts))
+	}
+	return entries
+}
+
+func (s *StoreRetrier) sendToDeadLetterQueue(ctx context.Context, entries []*dlq.Entry) error {
+	for _, entry := range entries {
+		if err := s.deadLetterQueue.Send(ctx, entry); err != nil {
+			return fmt.Errorf("sending failed document to dead letter queue: %w", err)
+		}
+	}
+	return nil
+}
+
 func (s *StoreRetrier) getRetriableDocs(failedDocs []DocumentError) ([]Document, []DocumentError) {
 	if len(failedDocs) == 0 {
 		return nil, nil
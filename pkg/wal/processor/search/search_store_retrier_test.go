@@ -11,6 +11,9 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/xataio/pgstream/pkg/backoff"
 	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
+	dlqmocks "github.com/xataio/pgstream/pkg/wal/dlq/mocks"
 )
 
 func TestStoreRetrier_DeleteSchema(t *testing.T) {
@@ -238,6 +241,93 @@ func TestStoreRetrier_SendDocuments(t *testing.T) {
 	}
 }
 
+func TestStoreRetrier_SendDocuments_deadLetterQueue(t *testing.T) {
+	t.Parallel()
+
+	testEvent := func(id string) *wal.Event {
+		return &wal.Event{
+			Data:           &wal.Data{Action: "I", Schema: testSchemaName, Table: testTableName, Columns: []wal.Column{{Name: "id", Value: id}}},
+			CommitPosition: wal.CommitPosition(id),
+		}
+	}
+	testDocument := func(id string) Document {
+		doc := newTestDocument(withID(id))
+		doc.Event = testEvent(id)
+		return *doc
+	}
+	testDocs := []Document{testDocument("1"), testDocument("2")}
+
+	sendDocumentsFn := func(ctx context.Context, i uint, docs []Document) ([]DocumentError, error) {
+		switch i {
+		case 1:
+			require.Equal(t, testDocs, docs)
+			return []DocumentError{
+				{Document: testDocument("1"), Severity: SeverityRetriable, Error: "retriable"},
+				{Document: testDocument("2"), Severity: SeverityDataLoss, Error: "dataloss"},
+			}, nil
+		case 2, 3:
+			require.Equal(t, []Document{testDocument("1")}, docs)
+			return []DocumentError{
+				{Document: testDocument("1"), Severity: SeverityRetriable, Error: "retriable"},
+			}, nil
+		default:
+			return nil, fmt.Errorf("sendDocumentsFn: unexpected call %d", i)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		sendErr error
+
+		wantErr error
+	}{
+		{
+			name: "ok",
+		},
+		{
+			name:    "error - sending to dead letter queue",
+			sendErr: errTest,
+
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			queue := &dlqmocks.Queue{
+				SendFn: func(ctx context.Context, e *dlq.Entry) error { return tc.sendErr },
+			}
+			retrier := StoreRetrier{
+				inner:           &mockStore{sendDocumentsFn: sendDocumentsFn},
+				logger:          loglib.NewNoopLogger(),
+				backoffProvider: newMockBackoffProvider(),
+				marshaler:       json.Marshal,
+				deadLetterQueue: queue,
+			}
+
+			_, err := retrier.SendDocuments(context.Background(), testDocs)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantErr != nil {
+				return
+			}
+
+			entries := queue.GetEntries()
+			require.Len(t, entries, 2)
+			// the data loss document is dropped on the first attempt, while the
+			// retriable one is dropped once the retries are exhausted
+			require.Equal(t, testEvent("2"), entries[0].Event)
+			require.Equal(t, "search_store_retrier", entries[0].Processor)
+			require.Equal(t, "dataloss", entries[0].Error)
+			require.Equal(t, 1, entries[0].Attempts)
+			require.Equal(t, testEvent("1"), entries[1].Event)
+			require.Equal(t, "retriable", entries[1].Error)
+			require.Equal(t, 3, entries[1].Attempts)
+		})
+	}
+}
+
 // mock backoff provider runs the operation for up to 2 times until it succeeds
 // or returns error
 func newMockBackoffProvider() backoff.Provider {
@@ -6,6 +6,7 @@ import (
 	"context"
 
 	"github.com/xataio/pgstream/pkg/schemalog"
+	"github.com/xataio/pgstream/pkg/wal"
 )
 
 type Store interface {
@@ -29,6 +30,9 @@ type Document struct {
 	Data    map[string]any
 	Version int
 	Delete  bool
+	// Event is the WAL event the document was generated from, reported when
+	// the document fails to be indexed.
+	Event *wal.Event
 }
 
 type DocumentError struct {
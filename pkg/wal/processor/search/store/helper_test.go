@@ -35,3 +35,11 @@ func (m *mockAdapter) SearchDocToBulkItem(docs search.Document) searchstore.Bulk
 func (m *mockAdapter) BulkItemsToSearchDocErrs(items []searchstore.BulkItem) []search.DocumentError {
 	return m.bulkItemsToSearchDocErrsFn(items)
 }
+
+type mockSchemaRouter struct {
+	routeSchemaFn func(schemaName string) string
+}
+
+func (m *mockSchemaRouter) RouteSchema(schemaName string) string {
+	return m.routeSchemaFn(schemaName)
+}
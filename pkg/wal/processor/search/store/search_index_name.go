@@ -64,3 +64,40 @@ func (i *defaultIndexName) Name() string {
 func (i *defaultIndexName) Version() int {
 	return i.version
 }
+
+// SchemaRouter maps source schema names to their target schema names.
+type SchemaRouter interface {
+	RouteSchema(schemaName string) string
+}
+
+// routedIndexNameAdapter is an index name adapter for source schema names,
+// which are routed to their target schema before being mapped to the index.
+// The index name keeps the source schema name, so that it can be matched
+// with the schema log entries.
+type routedIndexNameAdapter struct {
+	IndexNameAdapter
+	router SchemaRouter
+}
+
+func newRoutedIndexNameAdapter(adapter IndexNameAdapter, router SchemaRouter) IndexNameAdapter {
+	return &routedIndexNameAdapter{
+		IndexNameAdapter: adapter,
+		router:           router,
+	}
+}
+
+func (i *routedIndexNameAdapter) SchemaNameToIndex(schemaName string) IndexName {
+	return &routedIndexName{
+		IndexName:  i.IndexNameAdapter.SchemaNameToIndex(i.router.RouteSchema(schemaName)),
+		schemaName: schemaName,
+	}
+}
+
+type routedIndexName struct {
+	IndexName
+	schemaName string
+}
+
+func (i *routedIndexName) SchemaName() string {
+	return i.schemaName
+}
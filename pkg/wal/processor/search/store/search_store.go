@@ -23,6 +23,7 @@ type Store struct {
 	mapper               search.Mapper
 	adapter              SearchAdapter
 	indexNameAdapter     IndexNameAdapter
+	schemaRouter         SchemaRouter
 	marshaler            func(any) ([]byte, error)
 	defaultIndexSettings map[string]any
 }
@@ -101,6 +102,15 @@ func WithIndexNameAdapter(a IndexNameAdapter) Option {
 	}
 }
 
+// WithSchemaRouter routes the schema log schemas to their target indices. The
+// documents are expected to be routed already, so their index naming is not
+// affected.
+func WithSchemaRouter(r SchemaRouter) Option {
+	return func(s *Store) {
+		s.schemaRouter = r
+	}
+}
+
 func (s *Store) GetMapper() search.Mapper {
 	return s.mapper
 }
@@ -183,7 +193,7 @@ func (s *Store) SendDocuments(ctx context.Context, docs []search.Document) ([]se
 }
 
 func (s *Store) DeleteSchema(ctx context.Context, schemaName string) error {
-	index := s.indexNameAdapter.SchemaNameToIndex(schemaName)
+	index := s.schemaIndexName(schemaName)
 	exists, err := s.client.IndexExists(ctx, index.NameWithVersion())
 	if err != nil {
 		return mapError(err)
@@ -270,7 +280,7 @@ func (s *Store) getLastSchemaLogEntry(ctx context.Context, schemaName string) (*
 }
 
 func (s *Store) schemaExists(ctx context.Context, schemaName string) (bool, error) {
-	indexName := s.indexNameAdapter.SchemaNameToIndex(schemaName)
+	indexName := s.schemaIndexName(schemaName)
 	exists, err := s.client.IndexExists(ctx, indexName.NameWithVersion())
 	if err != nil {
 		return false, mapError(err)
@@ -279,7 +289,7 @@ func (s *Store) schemaExists(ctx context.Context, schemaName string) (bool, erro
 }
 
 func (s *Store) createSchema(ctx context.Context, schemaName string) error {
-	index := s.indexNameAdapter.SchemaNameToIndex(schemaName)
+	index := s.schemaIndexName(schemaName)
 	err := s.client.CreateIndex(ctx, index.NameWithVersion(), map[string]any{
 		"mappings": map[string]any{
 			"dynamic": "strict",
@@ -307,7 +317,7 @@ func (s *Store) createSchema(ctx context.Context, schemaName string) error {
 }
 
 func (s *Store) updateMapping(ctx context.Context, schemaName string, logEntry *schemalog.LogEntry, diff *schemalog.Diff) error {
-	index := s.indexNameAdapter.SchemaNameToIndex(schemaName)
+	index := s.schemaIndexName(schemaName)
 	if diff != nil && !diff.IsEmpty() {
 		if err := s.updateMappingAddNewColumns(ctx, index, s.getAllNewColumns(diff)); err != nil {
 			return fmt.Errorf("failed to add new columns: %w", mapError(err))
@@ -331,6 +341,14 @@ func (s *Store) updateMapping(ctx context.Context, schemaName string, logEntry *
 	return nil
 }
 
+// schemaIndexName returns the index name for the schema log schema on input.
+func (s *Store) schemaIndexName(schemaName string) IndexName {
+	if s.schemaRouter == nil {
+		return s.indexNameAdapter.SchemaNameToIndex(schemaName)
+	}
+	return newRoutedIndexNameAdapter(s.indexNameAdapter, s.schemaRouter).SchemaNameToIndex(schemaName)
+}
+
 func (s *Store) deleteTableDocuments(ctx context.Context, index IndexName, tableIDs []string) error {
 	if len(tableIDs) == 0 {
 		return nil
@@ -458,6 +458,44 @@ func TestStore_DeleteSchema(t *testing.T) {
 	}
 }
 
+func TestStore_DeleteSchema_withSchemaRouter(t *testing.T) {
+	t.Parallel()
+
+	s := NewStoreWithClient(&searchstoremocks.Client{
+		GetMapperFn: func() searchstore.Mapper {
+			return &searchstoremocks.Mapper{}
+		},
+		IndexExistsFn: func(ctx context.Context, index string) (bool, error) {
+			require.Equal(t, "tenant_a-1", index)
+			return true, nil
+		},
+		DeleteIndexFn: func(ctx context.Context, index []string) error {
+			require.Equal(t, []string{"tenant_a-1"}, index)
+			return nil
+		},
+		DeleteByQueryFn: func(ctx context.Context, req *searchstore.DeleteByQueryRequest) error {
+			// the schema log entries keep the source schema name
+			require.Equal(t, map[string]any{
+				"query": map[string]any{
+					"term": map[string]any{
+						"schema_name": "public",
+					},
+				},
+			}, req.Query)
+			return nil
+		},
+	})
+	WithSchemaRouter(&mockSchemaRouter{
+		routeSchemaFn: func(schemaName string) string {
+			require.Equal(t, "public", schemaName)
+			return "tenant_a"
+		},
+	})(s)
+
+	err := s.DeleteSchema(context.Background(), "public")
+	require.NoError(t, err)
+}
+
 func TestStore_DeleteTableDocuments(t *testing.T) {
 	t.Parallel()
 
@@ -5,11 +5,14 @@ package transformer
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
 
 	pglib "github.com/xataio/pgstream/internal/postgres"
 	loglib "github.com/xataio/pgstream/pkg/log"
 	"github.com/xataio/pgstream/pkg/transformers"
 	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
 	"github.com/xataio/pgstream/pkg/wal/processor"
 )
 
@@ -20,6 +23,9 @@ type Transformer struct {
 	processor      processor.Processor
 	transformerMap map[string]ColumnTransformers
 	parser         ParseFn
+	// deadLetterQueue receives the events that fail to be transformed. If not
+	// set, the failed column values are set to nil.
+	deadLetterQueue dlq.Queue
 }
 
 type ParseFn func(ctx context.Context, rules Rules) (map[string]ColumnTransformers, error)
@@ -77,11 +83,23 @@ func WithParser(parser ParseFn) Option {
 	}
 }
 
+// WithDeadLetterQueue sends the events that fail to be transformed to the dead
+// letter queue on input, instead of processing them with nil values for the
+// failed columns.
+func WithDeadLetterQueue(q dlq.Queue) Option {
+	return func(in *Transformer) {
+		in.deadLetterQueue = q
+	}
+}
+
 func (t *Transformer) ProcessWALEvent(ctx context.Context, event *wal.Event) error {
-	err := t.applyTransformations(ctx, event)
+	skip, err := t.applyTransformations(ctx, event)
 	if err != nil {
 		return err
 	}
+	if skip {
+		return nil
+	}
 
 	return t.processor.ProcessWALEvent(ctx, event)
 }
@@ -94,17 +112,21 @@ func (t *Transformer) Close() error {
 	return t.processor.Close()
 }
 
-func (t *Transformer) applyTransformations(ctx context.Context, event *wal.Event) error {
+// applyTransformations transforms the event column values. It returns true if
+// the event has been sent to the dead letter queue and should be skipped.
+func (t *Transformer) applyTransformations(ctx context.Context, event *wal.Event) (bool, error) {
 	if event.Data == nil || len(t.transformerMap) == 0 {
-		return nil
+		return false, nil
 	}
 
 	columnTransformers, found := t.transformerMap[schemaTableKey(event.Data.Schema, event.Data.Table)]
 	if !found || len(columnTransformers) == 0 {
-		return nil
+		return false, nil
 	}
 
-	columns := event.Data.Columns
+	// the transformations are applied to a copy of the columns, so that the
+	// original event can be sent to the dead letter queue on failure
+	columns := slices.Clone(event.Data.Columns)
 	for i, col := range columns {
 		// do not transform nil column values for now
 		if col.Value == nil {
@@ -116,8 +138,21 @@ func (t *Transformer) applyTransformations(ctx context.Context, event *wal.Event
 			continue
 		}
 
-		newValue, err := columnTransformer.Transform(ctx, t.getTransformValue(&col, event.Data.Columns))
+		newValue, err := columnTransformer.Transform(ctx, t.getTransformValue(&col, columns))
 		if err != nil {
+			if t.deadLetterQueue != nil {
+				t.logger.Error(err, "transforming column, sending event to dead letter queue", loglib.Fields{
+					"column_name": col.Name,
+					"schema":      event.Data.Schema,
+					"table":       event.Data.Table,
+				})
+				entry := dlq.NewEntry(event, "wal_transformer", fmt.Errorf("transforming column %s: %w", col.Name, err), 1)
+				if err := t.deadLetterQueue.Send(ctx, entry); err != nil {
+					return false, fmt.Errorf("sending event to dead letter queue: %w", err)
+				}
+				return true, nil
+			}
+
 			t.logger.Error(err, "transforming column", loglib.Fields{
 				"severity":         "DATALOSS",
 				"column_name":      col.Name,
@@ -131,8 +166,9 @@ func (t *Transformer) applyTransformations(ctx context.Context, event *wal.Event
 		t.logger.Trace("applying column transformation", loglib.Fields{"column_name": col.Name, "column_value": col.Value, "column_type": col.Type, "new_column_value": newValue})
 		columns[i].Value = newValue
 	}
+	event.Data.Columns = columns
 
-	return nil
+	return false, nil
 }
 
 func (t *Transformer) getTransformValue(column *wal.Column, columns []wal.Column) transformers.Value {
@@ -12,6 +12,8 @@ import (
 	"github.com/xataio/pgstream/pkg/transformers"
 	transformermocks "github.com/xataio/pgstream/pkg/transformers/mocks"
 	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
+	dlqmocks "github.com/xataio/pgstream/pkg/wal/dlq/mocks"
 	"github.com/xataio/pgstream/pkg/wal/processor"
 	"github.com/xataio/pgstream/pkg/wal/processor/mocks"
 )
@@ -107,10 +109,11 @@ func TestTransformer_ProcessWALEvent(t *testing.T) {
 	}
 
 	tests := []struct {
-		name           string
-		event          *wal.Event
-		processor      processor.Processor
-		transformerMap map[string]ColumnTransformers
+		name            string
+		event           *wal.Event
+		processor       processor.Processor
+		transformerMap  map[string]ColumnTransformers
+		deadLetterQueue *dlqmocks.Queue
 
 		wantErr error
 	}{
@@ -229,6 +232,74 @@ func TestTransformer_ProcessWALEvent(t *testing.T) {
 
 			wantErr: nil,
 		},
+		{
+			name: "error - transforming, sent to dead letter queue",
+			event: newTestEvent([]wal.Column{
+				{Name: "column_1", Type: "text", Value: "one"},
+				{Name: "column_2", Type: "int", Value: 1},
+			}),
+			processor: &mocks.Processor{
+				ProcessWALEventFn: func(ctx context.Context, walEvent *wal.Event) error {
+					return errors.New("ProcessWALEventFn: should not be called")
+				},
+			},
+			transformerMap: map[string]ColumnTransformers{
+				testKey: {
+					"column_1": &transformermocks.Transformer{
+						TransformFn: func(a transformers.Value) (any, error) {
+							return "two", nil
+						},
+					},
+					"column_2": &transformermocks.Transformer{
+						TransformFn: func(a transformers.Value) (any, error) {
+							return nil, errTest
+						},
+					},
+				},
+			},
+			deadLetterQueue: &dlqmocks.Queue{
+				SendFn: func(ctx context.Context, entry *dlq.Entry) error {
+					// the original event is sent to the dead letter queue
+					require.Equal(t, newTestEvent([]wal.Column{
+						{Name: "column_1", Type: "text", Value: "one"},
+						{Name: "column_2", Type: "int", Value: 1},
+					}), entry.Event)
+					require.Equal(t, "wal_transformer", entry.Processor)
+					require.Equal(t, "transforming column column_2: oh noes", entry.Error)
+					require.Equal(t, 1, entry.Attempts)
+					return nil
+				},
+			},
+
+			wantErr: nil,
+		},
+		{
+			name: "error - sending to dead letter queue",
+			event: newTestEvent([]wal.Column{
+				{Name: "column_1", Type: "text", Value: "one"},
+			}),
+			processor: &mocks.Processor{
+				ProcessWALEventFn: func(ctx context.Context, walEvent *wal.Event) error {
+					return errors.New("ProcessWALEventFn: should not be called")
+				},
+			},
+			transformerMap: map[string]ColumnTransformers{
+				testKey: {
+					"column_1": &transformermocks.Transformer{
+						TransformFn: func(a transformers.Value) (any, error) {
+							return nil, errTest
+						},
+					},
+				},
+			},
+			deadLetterQueue: &dlqmocks.Queue{
+				SendFn: func(ctx context.Context, entry *dlq.Entry) error {
+					return errTest
+				},
+			},
+
+			wantErr: errTest,
+		},
 	}
 
 	for _, tc := range tests {
@@ -241,6 +312,10 @@ func TestTransformer_ProcessWALEvent(t *testing.T) {
 				processor:      tc.processor,
 			}
 
+			if tc.deadLetterQueue != nil {
+				transformer.deadLetterQueue = tc.deadLetterQueue
+			}
+
 			err := transformer.ProcessWALEvent(context.Background(), tc.event)
 			require.ErrorIs(t, err, tc.wantErr)
 		})
@@ -18,8 +18,10 @@ import (
 	loglib "github.com/xataio/pgstream/pkg/log"
 	"github.com/xataio/pgstream/pkg/wal"
 	"github.com/xataio/pgstream/pkg/wal/checkpointer"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
 	"github.com/xataio/pgstream/pkg/wal/processor"
 	"github.com/xataio/pgstream/pkg/wal/processor/webhook/subscription"
+	"golang.org/x/sync/errgroup"
 )
 
 // Notifier represents the process that notifies any subscribed webhooks when
@@ -39,6 +41,9 @@ type Notifier struct {
 	notifyDone     chan (error)
 	notifyErr      error
 	once           *sync.Once
+	// deadLetterQueue receives the events that fail to be sent to a webhook.
+	// If not set, the failures are logged and the notifications dropped.
+	deadLetterQueue dlq.Queue
 }
 
 type subscriptionRetriever interface {
@@ -88,6 +93,12 @@ func WithCheckpoint(c checkpointer.Checkpoint) Option {
 	}
 }
 
+func WithDeadLetterQueue(q dlq.Queue) Option {
+	return func(n *Notifier) {
+		n.deadLetterQueue = q
+	}
+}
+
 // ProcessWALEvent will process the wal event on input and notify all configured
 // webhooks. It can be called concurrently.
 func (n *Notifier) ProcessWALEvent(ctx context.Context, walEvent *wal.Event) (err error) {
@@ -103,12 +114,14 @@ func (n *Notifier) ProcessWALEvent(ctx context.Context, walEvent *wal.Event) (er
 	}()
 
 	subscriptions := []*subscription.Subscription{}
-	if walEvent.Data != nil {
+	// transaction boundaries are not notified, they are only checkpointed
+	if walEvent.Data != nil && !walEvent.Data.IsTransactionBoundary() {
 		data := walEvent.Data
 		subscriptions, err = n.subscriptionStore.GetSubscriptions(ctx, data.Action, data.Schema, data.Table)
 		if err != nil {
 			return fmt.Errorf("retrieving subscriptions: %w", err)
 		}
+		subscriptions = filterMessageSubscriptions(data, subscriptions)
 		n.logger.Debug("matching subscriptions", loglib.Fields{"subscriptions": subscriptions})
 	}
 
@@ -116,6 +129,9 @@ func (n *Notifier) ProcessWALEvent(ctx context.Context, walEvent *wal.Event) (er
 	if err != nil {
 		return err
 	}
+	if n.deadLetterQueue != nil {
+		msg.event = walEvent
+	}
 
 	// make sure we don't reach the queue memory limit before adding the new
 	// message to the channel. This will block until messages have been read
@@ -189,10 +205,11 @@ func (n *Notifier) notify(ctx context.Context, msg *notifyMsg) error {
 	n.logger.Trace("notifying", loglib.Fields{"urls": msg.urls})
 	if len(msg.urls) > 0 {
 		urlChan := make(chan string, n.workerCount)
-		wg := &sync.WaitGroup{}
+		eg := errgroup.Group{}
 		for i := 0; i < int(n.workerCount); i++ {
-			wg.Add(1)
-			go n.webhookWorker(ctx, wg, msg.payload, urlChan)
+			eg.Go(func() error {
+				return n.webhookWorker(ctx, msg, urlChan)
+			})
 		}
 
 		for _, url := range msg.urls {
@@ -200,7 +217,9 @@ func (n *Notifier) notify(ctx context.Context, msg *notifyMsg) error {
 		}
 
 		close(urlChan)
-		wg.Wait()
+		if err := eg.Wait(); err != nil {
+			return err
+		}
 	}
 
 	if n.checkpointer != nil {
@@ -212,17 +231,29 @@ func (n *Notifier) notify(ctx context.Context, msg *notifyMsg) error {
 	return nil
 }
 
-func (n *Notifier) webhookWorker(ctx context.Context, wg *sync.WaitGroup, payload []byte, urls <-chan string) {
-	defer wg.Done()
+// webhookWorker sends the message payload to the urls received on the channel
+// until it's closed. It returns an error if any of the failed notifications
+// can't be sent to the dead letter queue.
+func (n *Notifier) webhookWorker(ctx context.Context, msg *notifyMsg, urls <-chan string) error {
+	var dlqErr error
 	for url := range urls {
-		if err := n.sendWebhook(ctx, payload, url); err != nil {
+		if err := n.sendWebhook(ctx, msg.payload, url); err != nil {
 			n.logger.Error(err, "sending webhook payload", loglib.Fields{
-				"payload": payload,
+				"payload": msg.payload,
 				"url":     url,
 			})
+			// keep consuming the urls even if the dead letter queue fails, so
+			// that the sender doesn't block
+			if n.deadLetterQueue != nil && dlqErr == nil {
+				entry := dlq.NewEntry(msg.event, "webhook_notifier", fmt.Errorf("sending webhook to %s: %w", url, err), 1)
+				if err := n.deadLetterQueue.Send(ctx, entry); err != nil {
+					dlqErr = fmt.Errorf("sending webhook notification to dead letter queue: %w", err)
+				}
+			}
 			continue
 		}
 	}
+	return dlqErr
 }
 
 func (n *Notifier) sendWebhook(ctx context.Context, payload []byte, url string) error {
@@ -252,3 +283,20 @@ func getResponseBody(respBody io.ReadCloser) string {
 	}
 	return string(bodyBytes)
 }
+
+// filterMessageSubscriptions makes sure logical decoding messages are only
+// notified to subscriptions matching their prefix, and that subscriptions
+// restricted to logical messages are not notified of any other events.
+func filterMessageSubscriptions(data *wal.Data, subscriptions []*subscription.Subscription) []*subscription.Subscription {
+	filtered := make([]*subscription.Subscription, 0, len(subscriptions))
+	for _, s := range subscriptions {
+		if data.IsLogicalMessage() && !s.IsForMessage(data.Prefix) {
+			continue
+		}
+		if !data.IsLogicalMessage() && s.IsMessageSubscription() {
+			continue
+		}
+		filtered = append(filtered, s)
+	}
+	return filtered
+}
@@ -18,6 +18,8 @@ import (
 	syncmocks "github.com/xataio/pgstream/internal/sync/mocks"
 	"github.com/xataio/pgstream/pkg/wal"
 	"github.com/xataio/pgstream/pkg/wal/checkpointer"
+	"github.com/xataio/pgstream/pkg/wal/dlq"
+	dlqmocks "github.com/xataio/pgstream/pkg/wal/dlq/mocks"
 	"github.com/xataio/pgstream/pkg/wal/processor"
 	"github.com/xataio/pgstream/pkg/wal/processor/webhook"
 	"github.com/xataio/pgstream/pkg/wal/processor/webhook/subscription"
@@ -43,6 +45,21 @@ func TestNotifier_ProcessWALEvent(t *testing.T) {
 	testPayload, err := json.Marshal(&webhook.Payload{Data: testEvent.Data})
 	require.NoError(t, err)
 
+	testMessageEvent := &wal.Event{
+		Data: &wal.Data{
+			Action:  "M",
+			Prefix:  "outbox",
+			Content: "test",
+		},
+		CommitPosition: testCommitPos,
+	}
+	testMessagePayload, err := json.Marshal(&webhook.Payload{Data: testMessageEvent.Data})
+	require.NoError(t, err)
+
+	testMessageSubscription := func(url, prefix string) *subscription.Subscription {
+		return &subscription.Subscription{URL: url, MessagePrefix: prefix}
+	}
+
 	tests := []struct {
 		name              string
 		store             subscriptionRetriever
@@ -93,6 +110,54 @@ func TestNotifier_ProcessWALEvent(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name: "ok - message subscriptions not notified of row events",
+			store: &mocks.Store{
+				GetSubscriptionsFn: func(ctx context.Context, action, schema, table string) ([]*subscription.Subscription, error) {
+					return []*subscription.Subscription{
+						testSubscription("url-1"), testMessageSubscription("url-2", "outbox"),
+					}, nil
+				},
+			},
+			weightedSemaphore: &syncmocks.WeightedSemaphore{
+				TryAcquireFn: func(i int64) bool {
+					require.Equal(t, int64(len(testPayload)+len("url-1")), i)
+					return true
+				},
+			},
+			event: testEvent,
+
+			wantMsgs: []*notifyMsg{
+				testNotifyMsg([]string{"url-1"}, testPayload),
+			},
+			wantErr: nil,
+		},
+		{
+			name: "ok - subscriptions for logical message event",
+			store: &mocks.Store{
+				GetSubscriptionsFn: func(ctx context.Context, action, schema, table string) ([]*subscription.Subscription, error) {
+					require.Equal(t, "M", action)
+					return []*subscription.Subscription{
+						testSubscription("url-1"),
+						testMessageSubscription("url-2", "outbox"),
+						testMessageSubscription("url-3", "another_prefix"),
+						newTestSubscription("url-4", "test_schema", "test_table", nil),
+					}, nil
+				},
+			},
+			weightedSemaphore: &syncmocks.WeightedSemaphore{
+				TryAcquireFn: func(i int64) bool {
+					require.Equal(t, int64(len(testMessagePayload)+len("url-1")+len("url-2")), i)
+					return true
+				},
+			},
+			event: testMessageEvent,
+
+			wantMsgs: []*notifyMsg{
+				testNotifyMsg([]string{"url-1", "url-2"}, testMessagePayload),
+			},
+			wantErr: nil,
+		},
 		{
 			name: "error - getting subscriptions",
 			store: &mocks.Store{
@@ -192,11 +257,12 @@ func TestNotifier_Notify(t *testing.T) {
 	}
 
 	tests := []struct {
-		name         string
-		semaphore    *syncmocks.WeightedSemaphore
-		client       httplib.Client
-		msgs         []*notifyMsg
-		checkpointer func(chan struct{}) checkpointer.Checkpoint
+		name            string
+		semaphore       *syncmocks.WeightedSemaphore
+		client          httplib.Client
+		msgs            []*notifyMsg
+		checkpointer    func(chan struct{}) checkpointer.Checkpoint
+		deadLetterQueue func(chan struct{}) *dlqmocks.Queue
 
 		wantErr error
 	}{
@@ -264,6 +330,77 @@ func TestNotifier_Notify(t *testing.T) {
 
 			wantErr: context.Canceled,
 		},
+		{
+			name: "ok - error sending webhook, sent to dead letter queue",
+			client: &httpmocks.Client{
+				DoFn: func(r *http.Request) (*http.Response, error) {
+					return nil, errTest
+				},
+			},
+			semaphore: &syncmocks.WeightedSemaphore{
+				ReleaseFn: func(i uint64, bytes int64) {},
+			},
+			msgs: []*notifyMsg{
+				func() *notifyMsg {
+					msg := testNotifyMsg([]string{url1}, testPayload)
+					msg.event = &wal.Event{CommitPosition: testCommitPos}
+					return msg
+				}(),
+			},
+			checkpointer: func(doneChan chan struct{}) checkpointer.Checkpoint {
+				return func(ctx context.Context, positions []wal.CommitPosition) error {
+					defer func() {
+						doneChan <- struct{}{}
+					}()
+					require.Equal(t, []wal.CommitPosition{testCommitPos}, positions)
+					return nil
+				}
+			},
+			deadLetterQueue: func(doneChan chan struct{}) *dlqmocks.Queue {
+				return &dlqmocks.Queue{
+					SendFn: func(ctx context.Context, entry *dlq.Entry) error {
+						require.Equal(t, &wal.Event{CommitPosition: testCommitPos}, entry.Event)
+						require.Equal(t, "webhook_notifier", entry.Processor)
+						require.Contains(t, entry.Error, url1)
+						require.Equal(t, 1, entry.Attempts)
+						return nil
+					},
+				}
+			},
+
+			wantErr: context.Canceled,
+		},
+		{
+			name: "error - sending to dead letter queue",
+			client: &httpmocks.Client{
+				DoFn: func(r *http.Request) (*http.Response, error) {
+					return nil, errTest
+				},
+			},
+			semaphore: &syncmocks.WeightedSemaphore{
+				ReleaseFn: func(i uint64, bytes int64) {},
+			},
+			msgs: []*notifyMsg{
+				testNotifyMsg([]string{url1}, testPayload),
+			},
+			checkpointer: func(doneChan chan struct{}) checkpointer.Checkpoint {
+				return func(ctx context.Context, positions []wal.CommitPosition) error {
+					return errors.New("checkpointer: should not be called")
+				}
+			},
+			deadLetterQueue: func(doneChan chan struct{}) *dlqmocks.Queue {
+				return &dlqmocks.Queue{
+					SendFn: func(ctx context.Context, entry *dlq.Entry) error {
+						defer func() {
+							doneChan <- struct{}{}
+						}()
+						return errTest
+					},
+				}
+			},
+
+			wantErr: errTest,
+		},
 		{
 			name: "error - checkpointing",
 			client: &httpmocks.Client{
@@ -302,6 +439,9 @@ func TestNotifier_Notify(t *testing.T) {
 			n.client = tc.client
 			n.queueBytesSema = tc.semaphore
 			n.checkpointer = tc.checkpointer(doneChan)
+			if tc.deadLetterQueue != nil {
+				n.deadLetterQueue = tc.deadLetterQueue(doneChan)
+			}
 
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
@@ -14,6 +14,9 @@ type notifyMsg struct {
 	urls           []string
 	payload        []byte
 	commitPosition wal.CommitPosition
+	// event is the original WAL event, only kept when there's a dead letter
+	// queue to report the failed notifications to
+	event *wal.Event
 }
 
 type serialiser func(any) ([]byte, error)
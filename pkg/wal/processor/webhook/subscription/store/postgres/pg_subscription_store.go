@@ -54,15 +54,15 @@ func WithLogger(l loglib.Logger) Option {
 
 func (s *Store) CreateSubscription(ctx context.Context, subscription *subscription.Subscription) error {
 	query := fmt.Sprintf(`
-	INSERT INTO %s(url, schema_name, table_name, event_types) VALUES($1, $2, $3, $4)
-	ON CONFLICT (url,schema_name,table_name) DO UPDATE SET event_types = EXCLUDED.event_types;`, subscriptionsTable())
-	_, err := s.conn.Exec(ctx, query, subscription.URL, subscription.Schema, subscription.Table, subscription.EventTypes)
+	INSERT INTO %s(url, schema_name, table_name, event_types, message_prefix) VALUES($1, $2, $3, $4, $5)
+	ON CONFLICT (url,schema_name,table_name,message_prefix) DO UPDATE SET event_types = EXCLUDED.event_types;`, subscriptionsTable())
+	_, err := s.conn.Exec(ctx, query, subscription.URL, subscription.Schema, subscription.Table, subscription.EventTypes, subscription.MessagePrefix)
 	return err
 }
 
 func (s *Store) DeleteSubscription(ctx context.Context, subscription *subscription.Subscription) error {
-	query := fmt.Sprintf(`DELETE FROM %s WHERE url=$1 AND schema_name=$2 AND table=$3;`, subscriptionsTable())
-	_, err := s.conn.Exec(ctx, query, subscription.URL, subscription.Schema, subscription.Table)
+	query := fmt.Sprintf(`DELETE FROM %s WHERE url=$1 AND schema_name=$2 AND table_name=$3 AND message_prefix=$4;`, subscriptionsTable())
+	_, err := s.conn.Exec(ctx, query, subscription.URL, subscription.Schema, subscription.Table, subscription.MessagePrefix)
 	return err
 }
 
@@ -81,7 +81,7 @@ func (s *Store) GetSubscriptions(ctx context.Context, action, schema, table stri
 	subscriptions := []*subscription.Subscription{}
 	for rows.Next() {
 		subscription := &subscription.Subscription{}
-		if err := rows.Scan(&subscription.URL, &subscription.Schema, &subscription.Table, &subscription.EventTypes); err != nil {
+		if err := rows.Scan(&subscription.URL, &subscription.Schema, &subscription.Table, &subscription.EventTypes, &subscription.MessagePrefix); err != nil {
 			return nil, fmt.Errorf("scanning subscription row: %w", err)
 		}
 
@@ -97,13 +97,45 @@ func (s *Store) createTable(ctx context.Context) error {
 	schema_name TEXT,
 	table_name TEXT,
 	event_types TEXT[],
-	PRIMARY KEY(url,schema_name,table_name))`, subscriptionsTable())
-	_, err := s.conn.Exec(ctx, query)
+	message_prefix TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY(url,schema_name,table_name,message_prefix))`, subscriptionsTable())
+	if _, err := s.conn.Exec(ctx, query); err != nil {
+		return err
+	}
+
+	// subscription tables created by previous versions don't have the message
+	// prefix column, and their primary key doesn't include it
+	alterQuery := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS message_prefix TEXT NOT NULL DEFAULT ''`, subscriptionsTable())
+	if _, err := s.conn.Exec(ctx, alterQuery); err != nil {
+		return err
+	}
+
+	_, err := s.conn.Exec(ctx, primaryKeyMigrationQuery())
 	return err
 }
 
+// primaryKeyMigrationQuery returns the query that recreates the primary key of
+// the subscriptions table to include the message prefix, if it doesn't already.
+func primaryKeyMigrationQuery() string {
+	return fmt.Sprintf(`DO $$
+DECLARE
+	pkey_name TEXT;
+BEGIN
+	SELECT c.conname INTO pkey_name FROM pg_constraint c
+	WHERE c.conrelid = '%[1]s'::regclass AND c.contype = 'p'
+	AND NOT EXISTS (
+		SELECT 1 FROM pg_attribute a
+		WHERE a.attrelid = c.conrelid AND a.attnum = ANY(c.conkey) AND a.attname = 'message_prefix'
+	);
+	IF pkey_name IS NOT NULL THEN
+		EXECUTE format('ALTER TABLE %[1]s DROP CONSTRAINT %%I', pkey_name);
+		ALTER TABLE %[1]s ADD PRIMARY KEY(url,schema_name,table_name,message_prefix);
+	END IF;
+END $$;`, subscriptionsTable())
+}
+
 func (s *Store) buildGetQuery(action, schema, table string) (string, []any) {
-	query := fmt.Sprintf(`SELECT url, schema_name, table_name, event_types FROM %s`, subscriptionsTable())
+	query := fmt.Sprintf(`SELECT url, schema_name, table_name, event_types, message_prefix FROM %s`, subscriptionsTable())
 
 	separator := func(params []any) string {
 		if len(params) == 0 {
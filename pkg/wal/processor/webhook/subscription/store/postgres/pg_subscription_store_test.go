@@ -3,10 +3,14 @@
 package postgres
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	"github.com/xataio/pgstream/pkg/wal/processor/webhook/subscription"
 )
 
 func TestStore_buildGetQuery(t *testing.T) {
@@ -23,25 +27,25 @@ func TestStore_buildGetQuery(t *testing.T) {
 	}{
 		{
 			name:       "no filters",
-			wantQuery:  fmt.Sprintf(`SELECT url, schema_name, table_name, event_types FROM %s LIMIT 1000`, subscriptionsTable()),
+			wantQuery:  fmt.Sprintf(`SELECT url, schema_name, table_name, event_types, message_prefix FROM %s LIMIT 1000`, subscriptionsTable()),
 			wantParams: nil,
 		},
 		{
 			name:       "with action filter",
 			action:     "I",
-			wantQuery:  fmt.Sprintf(`SELECT url, schema_name, table_name, event_types FROM %s WHERE ($1=ANY(event_types) OR event_types IS NULL) LIMIT 1000`, subscriptionsTable()),
+			wantQuery:  fmt.Sprintf(`SELECT url, schema_name, table_name, event_types, message_prefix FROM %s WHERE ($1=ANY(event_types) OR event_types IS NULL) LIMIT 1000`, subscriptionsTable()),
 			wantParams: []any{"I"},
 		},
 		{
 			name:       "with schema filter",
 			schema:     "test_schema",
-			wantQuery:  fmt.Sprintf(`SELECT url, schema_name, table_name, event_types FROM %s WHERE (schema_name=$1 OR schema_name='') LIMIT 1000`, subscriptionsTable()),
+			wantQuery:  fmt.Sprintf(`SELECT url, schema_name, table_name, event_types, message_prefix FROM %s WHERE (schema_name=$1 OR schema_name='') LIMIT 1000`, subscriptionsTable()),
 			wantParams: []any{"test_schema"},
 		},
 		{
 			name:       "with table filter",
 			table:      "test_table",
-			wantQuery:  fmt.Sprintf(`SELECT url, schema_name, table_name, event_types FROM %s WHERE (table_name=$1 OR table_name='') LIMIT 1000`, subscriptionsTable()),
+			wantQuery:  fmt.Sprintf(`SELECT url, schema_name, table_name, event_types, message_prefix FROM %s WHERE (table_name=$1 OR table_name='') LIMIT 1000`, subscriptionsTable()),
 			wantParams: []any{"test_table"},
 		},
 		{
@@ -49,7 +53,7 @@ func TestStore_buildGetQuery(t *testing.T) {
 			action: "I",
 			schema: "test_schema",
 			table:  "test_table",
-			wantQuery: fmt.Sprintf(`SELECT url, schema_name, table_name, event_types FROM %s `, subscriptionsTable()) +
+			wantQuery: fmt.Sprintf(`SELECT url, schema_name, table_name, event_types, message_prefix FROM %s `, subscriptionsTable()) +
 				"WHERE (schema_name=$1 OR schema_name='') " +
 				"AND (table_name=$2 OR table_name='') " +
 				"AND ($3=ANY(event_types) OR event_types IS NULL) LIMIT 1000",
@@ -68,3 +72,25 @@ func TestStore_buildGetQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestStore_DeleteSubscription(t *testing.T) {
+	t.Parallel()
+
+	store := &Store{
+		conn: &pgmocks.Querier{
+			ExecFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.CommandTag, error) {
+				require.Equal(t, fmt.Sprintf("DELETE FROM %s WHERE url=$1 AND schema_name=$2 AND table_name=$3 AND message_prefix=$4;", subscriptionsTable()), query)
+				require.Equal(t, []any{"https://example.com", "public", "users", "audit"}, args)
+				return pglib.CommandTag{}, nil
+			},
+		},
+	}
+
+	err := store.DeleteSubscription(context.Background(), &subscription.Subscription{
+		URL:           "https://example.com",
+		Schema:        "public",
+		Table:         "users",
+		MessagePrefix: "audit",
+	})
+	require.NoError(t, err)
+}
@@ -12,6 +12,9 @@ type Subscription struct {
 	EventTypes []string `json:"event_types"`
 	Schema     string   `json:"schema"`
 	Table      string   `json:"table"`
+	// MessagePrefix restricts the subscription to the logical decoding
+	// messages with the given prefix.
+	MessagePrefix string `json:"message_prefix,omitempty"`
 }
 
 func (s *Subscription) IsFor(action, schema, table string) bool {
@@ -34,6 +37,25 @@ func (s *Subscription) IsFor(action, schema, table string) bool {
 	return true
 }
 
+// IsForMessage returns true if the subscription matches the logical decoding
+// message prefix on input. Subscriptions filtering by schema or table never
+// match logical messages, since they're not linked to any table.
+func (s *Subscription) IsForMessage(prefix string) bool {
+	if s.Schema != "" || s.Table != "" {
+		return false
+	}
+	return s.MessagePrefix == "" || s.MessagePrefix == prefix
+}
+
+// IsMessageSubscription returns true if the subscription is restricted to
+// logical decoding messages.
+func (s *Subscription) IsMessageSubscription() bool {
+	return s.MessagePrefix != ""
+}
+
 func (s *Subscription) Key() string {
+	if s.MessagePrefix != "" {
+		return fmt.Sprintf("%s/%s/%s/%s", s.URL, s.Schema, s.Table, s.MessagePrefix)
+	}
 	return fmt.Sprintf("%s/%s/%s", s.URL, s.Schema, s.Table)
 }
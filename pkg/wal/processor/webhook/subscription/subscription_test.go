@@ -74,6 +74,58 @@ func TestSubscription_IsFor(t *testing.T) {
 	}
 }
 
+func TestSubscription_IsForMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		subscription *Subscription
+		name         string
+		prefix       string
+
+		wantMatch bool
+	}{
+		{
+			name:         "no filters, subscription matched",
+			subscription: newTestSubscription("url-1", "", "", nil),
+			prefix:       "outbox",
+			wantMatch:    true,
+		},
+		{
+			name: "filter by prefix, subscription matched",
+			subscription: &Subscription{
+				URL:           "url-1",
+				MessagePrefix: "outbox",
+			},
+			prefix:    "outbox",
+			wantMatch: true,
+		},
+		{
+			name: "filter by prefix, subscription not matched",
+			subscription: &Subscription{
+				URL:           "url-1",
+				MessagePrefix: "outbox",
+			},
+			prefix:    "another_prefix",
+			wantMatch: false,
+		},
+		{
+			name:         "filter by table, subscription not matched",
+			subscription: newTestSubscription("url-1", "test_schema", "test_table", nil),
+			prefix:       "outbox",
+			wantMatch:    false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			match := tc.subscription.IsForMessage(tc.prefix)
+			require.Equal(t, tc.wantMatch, match)
+		})
+	}
+}
+
 func newTestSubscription(url, schema, table string, eventTypes []string) *Subscription {
 	return &Subscription{
 		URL:        url,
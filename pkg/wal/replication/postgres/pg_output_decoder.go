@@ -0,0 +1,279 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/xataio/pgstream/internal/json"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// pgoutputDecoder translates the binary pgoutput protocol messages into the
+// wal2json (format-version 2) representation of the changes, so that the
+// replication plugin used is transparent to the rest of the pipeline.
+type pgoutputDecoder struct {
+	// relations keeps track of the relation messages received, which describe
+	// the tables referenced by the subsequent row change messages.
+	relations    map[uint32]*pglogrepl.RelationMessage
+	typeResolver typeResolver
+	// xid and commitTime identify the transaction being decoded
+	xid        uint32
+	commitTime time.Time
+	marshaler  func(any) ([]byte, error)
+}
+
+type typeResolver interface {
+	resolve(ctx context.Context, oid uint32, modifier int32) (string, error)
+}
+
+// pgTypeResolver resolves the postgres type names for the type oid/modifier
+// pairs received in the relation messages, keeping a cache to limit the
+// number of queries to the database.
+type pgTypeResolver struct {
+	connBuilder func() (pglib.Querier, error)
+	cache       map[typeKey]string
+}
+
+type typeKey struct {
+	oid      uint32
+	modifier int32
+}
+
+const iso8601Format = "2006-01-02 15:04:05.999999+00"
+
+var errUnknownRelation = errors.New("unknown relation")
+
+func newPgoutputDecoder(connBuilder func() (pglib.Querier, error)) *pgoutputDecoder {
+	return &pgoutputDecoder{
+		relations: map[uint32]*pglogrepl.RelationMessage{},
+		typeResolver: &pgTypeResolver{
+			connBuilder: connBuilder,
+			cache:       map[typeKey]string{},
+		},
+		marshaler: json.Marshal,
+	}
+}
+
+// decode returns the wal2json serialised representation of the pgoutput
+// message on input. Relation, type and origin messages only update the decoder
+// state and produce no data. Truncate messages can affect multiple tables, so
+// one entry is returned per table.
+func (d *pgoutputDecoder) decode(ctx context.Context, data []byte, lsn string) ([][]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	msg, err := pglogrepl.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing pgoutput message: %w", err)
+	}
+
+	var walDataList []*wal.Data
+	switch msg := msg.(type) {
+	case *pglogrepl.RelationMessage:
+		d.relations[msg.RelationID] = msg
+		return nil, nil
+	case *pglogrepl.BeginMessage:
+		d.xid = msg.Xid
+		d.commitTime = msg.CommitTime
+		walDataList = append(walDataList, &wal.Data{
+			Action:    "B",
+			Timestamp: d.commitTime.UTC().Format(iso8601Format),
+			LSN:       msg.FinalLSN.String(),
+			XID:       d.xid,
+		})
+	case *pglogrepl.CommitMessage:
+		walDataList = append(walDataList, &wal.Data{
+			Action:    "C",
+			Timestamp: msg.CommitTime.UTC().Format(iso8601Format),
+			LSN:       msg.CommitLSN.String(),
+			XID:       d.xid,
+		})
+	case *pglogrepl.LogicalDecodingMessage:
+		walData := &wal.Data{
+			Action:        "M",
+			Timestamp:     d.commitTime.UTC().Format(iso8601Format),
+			LSN:           msg.LSN.String(),
+			Transactional: msg.Transactional,
+			Prefix:        msg.Prefix,
+			Content:       string(msg.Content),
+		}
+		// non transactional messages are sent as soon as they're decoded, they
+		// don't belong to the transaction in progress
+		if msg.Transactional {
+			walData.XID = d.xid
+		} else {
+			walData.Timestamp = time.Now().UTC().Format(iso8601Format)
+		}
+		walDataList = append(walDataList, walData)
+	case *pglogrepl.InsertMessage:
+		walData, err := d.rowChangeToWalData(ctx, "I", msg.RelationID, msg.Tuple, nil, lsn)
+		if err != nil {
+			return nil, err
+		}
+		walDataList = append(walDataList, walData)
+	case *pglogrepl.UpdateMessage:
+		walData, err := d.rowChangeToWalData(ctx, "U", msg.RelationID, msg.NewTuple, msg.OldTuple, lsn)
+		if err != nil {
+			return nil, err
+		}
+		walDataList = append(walDataList, walData)
+	case *pglogrepl.DeleteMessage:
+		walData, err := d.rowChangeToWalData(ctx, "D", msg.RelationID, nil, msg.OldTuple, lsn)
+		if err != nil {
+			return nil, err
+		}
+		if msg.OldTupleType == pglogrepl.DeleteMessageTupleTypeKey {
+			walData.Identity = d.keyColumns(msg.RelationID, walData.Identity)
+		}
+		walDataList = append(walDataList, walData)
+	case *pglogrepl.TruncateMessage:
+		for _, relationID := range msg.RelationIDs {
+			relation, found := d.relations[relationID]
+			if !found {
+				return nil, fmt.Errorf("truncate relation %d: %w", relationID, errUnknownRelation)
+			}
+			walDataList = append(walDataList, d.newWalData("T", relation, lsn))
+		}
+	default:
+		// type and origin messages don't need any processing
+		return nil, nil
+	}
+
+	serialisedData := make([][]byte, 0, len(walDataList))
+	for _, walData := range walDataList {
+		bytes, err := d.marshaler(walData)
+		if err != nil {
+			return nil, fmt.Errorf("serialising wal data: %w", err)
+		}
+		serialisedData = append(serialisedData, bytes)
+	}
+	return serialisedData, nil
+}
+
+func (d *pgoutputDecoder) rowChangeToWalData(ctx context.Context, action string, relationID uint32, newTuple, oldTuple *pglogrepl.TupleData, lsn string) (*wal.Data, error) {
+	relation, found := d.relations[relationID]
+	if !found {
+		return nil, fmt.Errorf("relation %d: %w", relationID, errUnknownRelation)
+	}
+
+	walData := d.newWalData(action, relation, lsn)
+
+	var err error
+	if newTuple != nil {
+		walData.Columns, err = d.tupleToColumns(ctx, relation, newTuple)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case oldTuple != nil:
+		walData.Identity, err = d.tupleToColumns(ctx, relation, oldTuple)
+		if err != nil {
+			return nil, err
+		}
+	case action == "U":
+		// the old tuple is only sent when the replica identity has changed or
+		// the table is configured with replica identity full. Use the new
+		// values for the identity columns, the same way wal2json does.
+		walData.Identity = d.keyColumns(relationID, walData.Columns)
+	}
+
+	return walData, nil
+}
+
+func (d *pgoutputDecoder) newWalData(action string, relation *pglogrepl.RelationMessage, lsn string) *wal.Data {
+	return &wal.Data{
+		Action:    action,
+		Timestamp: d.commitTime.UTC().Format(iso8601Format),
+		LSN:       lsn,
+		XID:       d.xid,
+		Schema:    relation.Namespace,
+		Table:     relation.RelationName,
+	}
+}
+
+func (d *pgoutputDecoder) tupleToColumns(ctx context.Context, relation *pglogrepl.RelationMessage, tuple *pglogrepl.TupleData) ([]wal.Column, error) {
+	columns := make([]wal.Column, 0, len(tuple.Columns))
+	for i, tupleCol := range tuple.Columns {
+		if i >= len(relation.Columns) {
+			return nil, fmt.Errorf("tuple column %d not found in relation %s.%s", i, relation.Namespace, relation.RelationName)
+		}
+		relationCol := relation.Columns[i]
+
+		var value any
+		switch tupleCol.DataType {
+		case pglogrepl.TupleDataTypeToast:
+			// unchanged toasted values are not sent, wal2json omits them
+			continue
+		case pglogrepl.TupleDataTypeNull:
+			value = nil
+		default:
+			value = pglib.TextToWalValue(relationCol.DataType, string(tupleCol.Data))
+		}
+
+		typeName, err := d.typeResolver.resolve(ctx, relationCol.DataType, relationCol.TypeModifier)
+		if err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, wal.Column{
+			Name:  relationCol.Name,
+			Type:  typeName,
+			Value: value,
+		})
+	}
+	return columns, nil
+}
+
+// keyColumns returns the subset of columns that are part of the relation
+// replica identity key.
+func (d *pgoutputDecoder) keyColumns(relationID uint32, columns []wal.Column) []wal.Column {
+	relation, found := d.relations[relationID]
+	if !found {
+		return nil
+	}
+
+	keyColumnNames := map[string]struct{}{}
+	for _, col := range relation.Columns {
+		if col.Flags == 1 {
+			keyColumnNames[col.Name] = struct{}{}
+		}
+	}
+
+	keyColumns := []wal.Column{}
+	for _, col := range columns {
+		if _, found := keyColumnNames[col.Name]; found {
+			keyColumns = append(keyColumns, col)
+		}
+	}
+	return keyColumns
+}
+
+func (r *pgTypeResolver) resolve(ctx context.Context, oid uint32, modifier int32) (string, error) {
+	key := typeKey{oid: oid, modifier: modifier}
+	if typeName, found := r.cache[key]; found {
+		return typeName, nil
+	}
+
+	conn, err := r.connBuilder()
+	if err != nil {
+		return "", fmt.Errorf("creating pg connection: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	var typeName string
+	if err := conn.QueryRow(ctx, "SELECT format_type($1, $2)", oid, modifier).Scan(&typeName); err != nil {
+		return "", fmt.Errorf("resolving type name for oid %d: %w", oid, err)
+	}
+
+	r.cache[key] = typeName
+	return typeName, nil
+}
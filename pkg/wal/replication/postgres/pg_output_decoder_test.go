@@ -0,0 +1,487 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/internal/json"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func TestPgoutputDecoder_decode(t *testing.T) {
+	t.Parallel()
+
+	const (
+		testRelationID = uint32(16385)
+		testXID        = uint32(1)
+		testTimestamp  = "2024-05-02 10:30:15.123456+00"
+	)
+	commitTime := time.Date(2024, 5, 2, 10, 30, 15, 123456000, time.UTC)
+
+	testRelation := &pglogrepl.RelationMessage{
+		RelationID:   testRelationID,
+		Namespace:    "public",
+		RelationName: "users",
+		Columns: []*pglogrepl.RelationMessageColumn{
+			{Flags: 1, Name: "id", DataType: pgtype.Int8OID, TypeModifier: -1},
+			{Name: "name", DataType: pgtype.TextOID, TypeModifier: -1},
+			{Name: "active", DataType: pgtype.BoolOID, TypeModifier: -1},
+		},
+	}
+
+	testTypeResolver := &mockTypeResolver{
+		types: map[uint32]string{
+			pgtype.Int8OID: "bigint",
+			pgtype.TextOID: "text",
+			pgtype.BoolOID: "boolean",
+		},
+	}
+
+	idCol := wal.Column{Name: "id", Type: "bigint", Value: float64(1)}
+	nameCol := wal.Column{Name: "name", Type: "text", Value: "alice"}
+	activeCol := wal.Column{Name: "active", Type: "boolean", Value: true}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		resolver typeResolver
+
+		wantData []*wal.Data
+		wantErr  error
+	}{
+		{
+			name:     "ok - begin",
+			data:     encodeBeginMessage(commitTime),
+			resolver: testTypeResolver,
+
+			wantData: []*wal.Data{
+				{
+					Action:    "B",
+					Timestamp: testTimestamp,
+					LSN:       testLSNStr,
+					XID:       testXID,
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "ok - commit",
+			data:     encodeCommitMessage(commitTime),
+			resolver: testTypeResolver,
+
+			wantData: []*wal.Data{
+				{
+					Action:    "C",
+					Timestamp: testTimestamp,
+					LSN:       testLSNStr,
+					XID:       testXID,
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "ok - transactional logical decoding message",
+			data:     encodeLogicalDecodingMessage(true, "outbox", []byte(`{"order_id":1}`)),
+			resolver: testTypeResolver,
+
+			wantData: []*wal.Data{
+				{
+					Action:        "M",
+					Timestamp:     testTimestamp,
+					LSN:           testLSNStr,
+					XID:           testXID,
+					Transactional: true,
+					Prefix:        "outbox",
+					Content:       `{"order_id":1}`,
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "ok - insert",
+			data:     encodeInsertMessage(testRelationID, []*string{ptr("1"), ptr("alice"), ptr("t")}),
+			resolver: testTypeResolver,
+
+			wantData: []*wal.Data{
+				{
+					Action:    "I",
+					Timestamp: testTimestamp,
+					LSN:       testLSNStr,
+					XID:       testXID,
+					Schema:    "public",
+					Table:     "users",
+					Columns:   []wal.Column{idCol, nameCol, activeCol},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "ok - insert with null value",
+			data:     encodeInsertMessage(testRelationID, []*string{ptr("1"), nil, ptr("f")}),
+			resolver: testTypeResolver,
+
+			wantData: []*wal.Data{
+				{
+					Action:    "I",
+					Timestamp: testTimestamp,
+					LSN:       testLSNStr,
+					XID:       testXID,
+					Schema:    "public",
+					Table:     "users",
+					Columns: []wal.Column{
+						idCol,
+						{Name: "name", Type: "text", Value: nil},
+						{Name: "active", Type: "boolean", Value: false},
+					},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "ok - update without old tuple",
+			data:     encodeUpdateMessage(testRelationID, 0, nil, []*string{ptr("1"), ptr("alice"), ptr("t")}),
+			resolver: testTypeResolver,
+
+			wantData: []*wal.Data{
+				{
+					Action:    "U",
+					Timestamp: testTimestamp,
+					LSN:       testLSNStr,
+					XID:       testXID,
+					Schema:    "public",
+					Table:     "users",
+					Columns:   []wal.Column{idCol, nameCol, activeCol},
+					Identity:  []wal.Column{idCol},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "ok - update with key tuple",
+			data:     encodeUpdateMessage(testRelationID, 'K', []*string{ptr("2"), nil, nil}, []*string{ptr("1"), ptr("alice"), ptr("t")}),
+			resolver: testTypeResolver,
+
+			wantData: []*wal.Data{
+				{
+					Action:    "U",
+					Timestamp: testTimestamp,
+					LSN:       testLSNStr,
+					XID:       testXID,
+					Schema:    "public",
+					Table:     "users",
+					Columns:   []wal.Column{idCol, nameCol, activeCol},
+					Identity: []wal.Column{
+						{Name: "id", Type: "bigint", Value: float64(2)},
+						{Name: "name", Type: "text", Value: nil},
+						{Name: "active", Type: "boolean", Value: nil},
+					},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "ok - delete with key tuple",
+			data:     encodeDeleteMessage(testRelationID, 'K', []*string{ptr("1"), nil, nil}),
+			resolver: testTypeResolver,
+
+			wantData: []*wal.Data{
+				{
+					Action:    "D",
+					Timestamp: testTimestamp,
+					LSN:       testLSNStr,
+					XID:       testXID,
+					Schema:    "public",
+					Table:     "users",
+					Identity:  []wal.Column{idCol},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "ok - delete with old tuple",
+			data:     encodeDeleteMessage(testRelationID, 'O', []*string{ptr("1"), ptr("alice"), ptr("t")}),
+			resolver: testTypeResolver,
+
+			wantData: []*wal.Data{
+				{
+					Action:    "D",
+					Timestamp: testTimestamp,
+					LSN:       testLSNStr,
+					XID:       testXID,
+					Schema:    "public",
+					Table:     "users",
+					Identity:  []wal.Column{idCol, nameCol, activeCol},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "ok - truncate",
+			data:     encodeTruncateMessage(testRelationID),
+			resolver: testTypeResolver,
+
+			wantData: []*wal.Data{
+				{
+					Action:    "T",
+					Timestamp: testTimestamp,
+					LSN:       testLSNStr,
+					XID:       testXID,
+					Schema:    "public",
+					Table:     "users",
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "error - unknown relation",
+			data:     encodeInsertMessage(1, []*string{ptr("1")}),
+			resolver: testTypeResolver,
+
+			wantData: nil,
+			wantErr:  errUnknownRelation,
+		},
+		{
+			name:     "error - resolving type",
+			data:     encodeInsertMessage(testRelationID, []*string{ptr("1"), ptr("alice"), ptr("t")}),
+			resolver: &mockTypeResolver{err: errTest},
+
+			wantData: nil,
+			wantErr:  errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := &pgoutputDecoder{
+				relations:    map[uint32]*pglogrepl.RelationMessage{testRelationID: testRelation},
+				typeResolver: tc.resolver,
+				xid:          testXID,
+				commitTime:   commitTime,
+				marshaler:    json.Marshal,
+			}
+
+			data, err := d.decode(context.Background(), tc.data, testLSNStr)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantData, unmarshalWalData(t, data))
+		})
+	}
+}
+
+func TestPgoutputDecoder_decode_relationMessage(t *testing.T) {
+	t.Parallel()
+
+	d := newPgoutputDecoder(nil)
+
+	data, err := d.decode(context.Background(), encodeRelationMessage(&pglogrepl.RelationMessage{
+		RelationID:   1,
+		Namespace:    "public",
+		RelationName: "test",
+		Columns: []*pglogrepl.RelationMessageColumn{
+			{Flags: 1, Name: "id", DataType: pgtype.Int4OID, TypeModifier: -1},
+		},
+	}), testLSNStr)
+	require.NoError(t, err)
+	require.Nil(t, data)
+
+	relation, found := d.relations[1]
+	require.True(t, found)
+	require.Equal(t, "public", relation.Namespace)
+	require.Equal(t, "test", relation.RelationName)
+	require.Len(t, relation.Columns, 1)
+	require.Equal(t, "id", relation.Columns[0].Name)
+}
+
+func TestPgoutputDecoder_decode_nonTransactionalMessage(t *testing.T) {
+	t.Parallel()
+
+	d := newPgoutputDecoder(nil)
+	d.xid = 1
+
+	data, err := d.decode(context.Background(), encodeLogicalDecodingMessage(false, "outbox", []byte("hello")), testLSNStr)
+	require.NoError(t, err)
+
+	walDataList := unmarshalWalData(t, data)
+	require.Len(t, walDataList, 1)
+	walData := walDataList[0]
+	require.True(t, walData.IsLogicalMessage())
+	require.False(t, walData.Transactional)
+	require.Equal(t, "outbox", walData.Prefix)
+	require.Equal(t, "hello", walData.Content)
+	require.Equal(t, testLSNStr, walData.LSN)
+	// non transactional messages don't belong to the transaction in progress
+	require.Zero(t, walData.XID)
+	_, err = walData.GetTimestamp()
+	require.NoError(t, err)
+}
+
+func TestPgTypeResolver_resolve(t *testing.T) {
+	t.Parallel()
+
+	queryCalls := 0
+	r := &pgTypeResolver{
+		connBuilder: func() (pglib.Querier, error) {
+			return &pgmocks.Querier{
+				QueryRowFn: func(ctx context.Context, query string, args ...any) pglib.Row {
+					queryCalls++
+					require.Equal(t, "SELECT format_type($1, $2)", query)
+					require.Equal(t, []any{uint32(pgtype.VarcharOID), int32(259)}, args)
+					return &mockRow{lsn: "character varying(255)"}
+				},
+				CloseFn: func(ctx context.Context) error { return nil },
+			}, nil
+		},
+		cache: map[typeKey]string{},
+	}
+
+	for i := 0; i < 2; i++ {
+		typeName, err := r.resolve(context.Background(), pgtype.VarcharOID, 259)
+		require.NoError(t, err)
+		require.Equal(t, "character varying(255)", typeName)
+	}
+	// the second call should be served from the cache
+	require.Equal(t, 1, queryCalls)
+}
+
+type mockTypeResolver struct {
+	types map[uint32]string
+	err   error
+}
+
+func (m *mockTypeResolver) resolve(_ context.Context, oid uint32, _ int32) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	typeName, found := m.types[oid]
+	if !found {
+		return "", fmt.Errorf("unexpected type oid: %d", oid)
+	}
+	return typeName, nil
+}
+
+func unmarshalWalData(t *testing.T, data [][]byte) []*wal.Data {
+	if data == nil {
+		return nil
+	}
+	walDataList := make([]*wal.Data, 0, len(data))
+	for _, d := range data {
+		walData := &wal.Data{}
+		require.NoError(t, json.Unmarshal(d, walData))
+		walDataList = append(walDataList, walData)
+	}
+	return walDataList
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+// postgres epoch used by the pgoutput protocol timestamps
+var pgEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func encodeBeginMessage(commitTime time.Time) []byte {
+	buf := []byte{'B'}
+	buf = binary.BigEndian.AppendUint64(buf, testLSN)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(commitTime.Sub(pgEpoch).Microseconds()))
+	buf = binary.BigEndian.AppendUint32(buf, 1)
+	return buf
+}
+
+func encodeCommitMessage(commitTime time.Time) []byte {
+	buf := []byte{'C', 0}
+	buf = binary.BigEndian.AppendUint64(buf, testLSN)
+	buf = binary.BigEndian.AppendUint64(buf, testLSN+1)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(commitTime.Sub(pgEpoch).Microseconds()))
+	return buf
+}
+
+func encodeLogicalDecodingMessage(transactional bool, prefix string, content []byte) []byte {
+	buf := []byte{'M', 0}
+	if transactional {
+		buf[1] = 1
+	}
+	buf = binary.BigEndian.AppendUint64(buf, testLSN)
+	buf = append(buf, []byte(prefix)...)
+	buf = append(buf, 0)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(content)))
+	return append(buf, content...)
+}
+
+func encodeRelationMessage(relation *pglogrepl.RelationMessage) []byte {
+	buf := []byte{'R'}
+	buf = binary.BigEndian.AppendUint32(buf, relation.RelationID)
+	buf = append(buf, []byte(relation.Namespace)...)
+	buf = append(buf, 0)
+	buf = append(buf, []byte(relation.RelationName)...)
+	buf = append(buf, 0)
+	buf = append(buf, relation.ReplicaIdentity)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(relation.Columns)))
+	for _, col := range relation.Columns {
+		buf = append(buf, col.Flags)
+		buf = append(buf, []byte(col.Name)...)
+		buf = append(buf, 0)
+		buf = binary.BigEndian.AppendUint32(buf, col.DataType)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(col.TypeModifier))
+	}
+	return buf
+}
+
+func encodeInsertMessage(relationID uint32, values []*string) []byte {
+	buf := []byte{'I'}
+	buf = binary.BigEndian.AppendUint32(buf, relationID)
+	buf = append(buf, 'N')
+	return appendTupleData(buf, values)
+}
+
+func encodeUpdateMessage(relationID uint32, oldTupleType byte, oldValues, newValues []*string) []byte {
+	buf := []byte{'U'}
+	buf = binary.BigEndian.AppendUint32(buf, relationID)
+	if oldTupleType != 0 {
+		buf = append(buf, oldTupleType)
+		buf = appendTupleData(buf, oldValues)
+	}
+	buf = append(buf, 'N')
+	return appendTupleData(buf, newValues)
+}
+
+func encodeDeleteMessage(relationID uint32, oldTupleType byte, oldValues []*string) []byte {
+	buf := []byte{'D'}
+	buf = binary.BigEndian.AppendUint32(buf, relationID)
+	buf = append(buf, oldTupleType)
+	return appendTupleData(buf, oldValues)
+}
+
+func encodeTruncateMessage(relationIDs ...uint32) []byte {
+	buf := []byte{'T'}
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(relationIDs)))
+	buf = append(buf, 0)
+	for _, id := range relationIDs {
+		buf = binary.BigEndian.AppendUint32(buf, id)
+	}
+	return buf
+}
+
+func appendTupleData(buf []byte, values []*string) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(values)))
+	for _, v := range values {
+		if v == nil {
+			buf = append(buf, 'n')
+			continue
+		}
+		buf = append(buf, 't')
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(*v)))
+		buf = append(buf, []byte(*v)...)
+	}
+	return buf
+}
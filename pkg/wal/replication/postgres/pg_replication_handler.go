@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 
 	pglib "github.com/xataio/pgstream/internal/postgres"
 	loglib "github.com/xataio/pgstream/pkg/log"
@@ -20,6 +21,14 @@ type Handler struct {
 	pgReplicationConn     pgReplicationConn
 	pgReplicationSlotName string
 	pgConnBuilder         func() (pglib.Querier, error)
+	pluginArguments       []string
+
+	// decoder is only required when the output plugin doesn't produce wal2json
+	// formatted messages (pgoutput).
+	decoder *pgoutputDecoder
+	// pendingMessages holds the decoded messages that have not yet been
+	// returned, since a single pgoutput message can map to multiple events.
+	pendingMessages []*replication.Message
 
 	lsnParser replication.LSNParser
 }
@@ -37,6 +46,12 @@ type Config struct {
 	// Name of the replication slot to listen on. If not provided, it defaults
 	// to "pgstream_<dbname>_slot".
 	ReplicationSlotName string
+	// Logical decoding output plugin used by the replication slot. Supported
+	// values are "wal2json" and "pgoutput". Defaults to "wal2json".
+	Plugin string
+	// Name of the publication used by the pgoutput plugin. Defaults to
+	// "pgstream_publication".
+	PublicationName string
 }
 
 type Option func(h *Handler)
@@ -49,12 +64,26 @@ const (
 	logSystemID    = "system_id"
 )
 
-var pluginArguments = []string{
+const (
+	PluginWal2json = "wal2json"
+	PluginPgoutput = "pgoutput"
+
+	DefaultPublicationName = "pgstream_publication"
+
+	// pgoutputMessagesMinVersion is the first postgres version (14) whose
+	// pgoutput plugin supports the logical decoding messages option
+	pgoutputMessagesMinVersion = 140000
+)
+
+var errUnsupportedPlugin = errors.New("unsupported replication plugin")
+
+var wal2jsonPluginArguments = []string{
 	`"include-timestamp" '1'`,
 	`"format-version" '2'`,
 	`"write-in-chunks" '1'`,
 	`"include-lsn" '1'`,
-	`"include-transaction" '0'`,
+	`"include-transaction" '1'`,
+	`"include-xids" '1'`,
 }
 
 // NewHandler returns a new postgres replication handler for the database on input.
@@ -92,6 +121,20 @@ func NewHandler(ctx context.Context, cfg Config, opts ...Option) (*Handler, erro
 		},
 	}
 
+	switch cfg.GetPlugin() {
+	case PluginWal2json:
+		h.pluginArguments = wal2jsonPluginArguments
+	case PluginPgoutput:
+		serverVersion, err := h.getServerVersion(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("retrieving server version: %w", err)
+		}
+		h.pluginArguments = pgoutputPluginArguments(cfg.GetPublicationName(), serverVersion)
+		h.decoder = newPgoutputDecoder(connBuilder)
+	default:
+		return nil, fmt.Errorf("%s: %w", cfg.Plugin, errUnsupportedPlugin)
+	}
+
 	for _, opt := range opts {
 		opt(h)
 	}
@@ -154,7 +197,7 @@ func (h *Handler) StartReplicationFromLSN(ctx context.Context, lsn replication.L
 		ctx, pglib.ReplicationConfig{
 			SlotName:        h.pgReplicationSlotName,
 			StartPos:        uint64(lsn),
-			PluginArguments: pluginArguments,
+			PluginArguments: h.pluginArguments,
 		})
 	if err != nil {
 		return fmt.Errorf("startReplication: %w", err)
@@ -168,18 +211,56 @@ func (h *Handler) StartReplicationFromLSN(ctx context.Context, lsn replication.L
 // ReceiveMessage will listen for messages from the WAL. It returns an error if
 // an unexpected message is received.
 func (h *Handler) ReceiveMessage(ctx context.Context) (*replication.Message, error) {
+	if len(h.pendingMessages) > 0 {
+		msg := h.pendingMessages[0]
+		h.pendingMessages = h.pendingMessages[1:]
+		return msg, nil
+	}
+
 	pgMsg, err := h.pgReplicationConn.ReceiveMessage(ctx)
 	if err != nil {
 		h.logger.Error(err, "receiving message")
 		return nil, mapPostgresError(err)
 	}
 
-	return &replication.Message{
+	msg := &replication.Message{
 		LSN:            replication.LSN(pgMsg.LSN),
 		Data:           pgMsg.WALData,
 		ServerTime:     pgMsg.ServerTime,
 		ReplyRequested: pgMsg.ReplyRequested,
-	}, nil
+	}
+
+	if h.decoder == nil {
+		return msg, nil
+	}
+
+	return h.decodeMessage(ctx, msg)
+}
+
+// decodeMessage translates the pgoutput message data into the wal2json format.
+// Any additional messages resulting from the decoding are kept to be returned
+// by subsequent calls to ReceiveMessage.
+func (h *Handler) decodeMessage(ctx context.Context, msg *replication.Message) (*replication.Message, error) {
+	data, err := h.decoder.decode(ctx, msg.Data, h.lsnParser.ToString(msg.LSN))
+	if err != nil {
+		h.logger.Error(err, "decoding pgoutput message")
+		return nil, fmt.Errorf("decoding pgoutput message: %w", err)
+	}
+
+	if len(data) == 0 {
+		msg.Data = nil
+		return msg, nil
+	}
+
+	msg.Data = data[0]
+	for _, d := range data[1:] {
+		h.pendingMessages = append(h.pendingMessages, &replication.Message{
+			LSN:        msg.LSN,
+			Data:       d,
+			ServerTime: msg.ServerTime,
+		})
+	}
+	return msg, nil
 }
 
 // SyncLSN notifies Postgres how far we have processed in the WAL.
@@ -267,6 +348,22 @@ func (h *Handler) getLastSyncedLSN(ctx context.Context, conn pglib.Querier) (rep
 	return h.lsnParser.FromString(confirmedFlushLSN)
 }
 
+// getServerVersion returns the postgres server version number (i.e. 140005 for
+// 14.5).
+func (h *Handler) getServerVersion(ctx context.Context) (int, error) {
+	conn, err := h.pgConnBuilder()
+	if err != nil {
+		return 0, fmt.Errorf("creating pg connection: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	var version string
+	if err := conn.QueryRow(ctx, `SHOW server_version_num`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(version)
+}
+
 func (h *Handler) verifyReplicationSlotExists(ctx context.Context) error {
 	slotExists := false
 	conn, err := h.pgConnBuilder()
@@ -285,6 +382,34 @@ func (h *Handler) verifyReplicationSlotExists(ctx context.Context) error {
 	return nil
 }
 
+func (c *Config) GetPlugin() string {
+	if c.Plugin == "" {
+		return PluginWal2json
+	}
+	return c.Plugin
+}
+
+func (c *Config) GetPublicationName() string {
+	if c.PublicationName == "" {
+		return DefaultPublicationName
+	}
+	return c.PublicationName
+}
+
+// pgoutputPluginArguments returns the pgoutput plugin arguments for the
+// publication on input. The logical decoding messages are only sent by pgoutput
+// when requested, which older server versions reject.
+func pgoutputPluginArguments(publicationName string, serverVersion int) []string {
+	args := []string{
+		`proto_version '1'`,
+		fmt.Sprintf(`publication_names '%s'`, publicationName),
+	}
+	if serverVersion >= pgoutputMessagesMinVersion {
+		args = append(args, `messages 'true'`)
+	}
+	return args
+}
+
 func mapPostgresError(err error) error {
 	if errors.Is(err, pglib.ErrConnTimeout) {
 		return replication.ErrConnTimeout
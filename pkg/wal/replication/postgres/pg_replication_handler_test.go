@@ -8,10 +8,13 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/jackc/pglogrepl"
 	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/internal/json"
 	pglib "github.com/xataio/pgstream/internal/postgres"
 	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
 	"github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
 	"github.com/xataio/pgstream/pkg/wal/replication"
 )
 
@@ -38,7 +41,7 @@ func TestHandler_StartReplication(t *testing.T) {
 				StartReplicationFn: func(ctx context.Context, cfg pglib.ReplicationConfig) error {
 					require.Equal(t, testLSN, cfg.StartPos)
 					require.Equal(t, testSlot, cfg.SlotName)
-					require.Equal(t, pluginArguments, cfg.PluginArguments)
+					require.Equal(t, wal2jsonPluginArguments, cfg.PluginArguments)
 					return nil
 				},
 				SendStandbyStatusUpdateFn: func(ctx context.Context, lsn uint64) error {
@@ -79,7 +82,7 @@ func TestHandler_StartReplication(t *testing.T) {
 				StartReplicationFn: func(ctx context.Context, cfg pglib.ReplicationConfig) error {
 					require.Equal(t, testLSN, cfg.StartPos)
 					require.Equal(t, testSlot, cfg.SlotName)
-					require.Equal(t, pluginArguments, cfg.PluginArguments)
+					require.Equal(t, wal2jsonPluginArguments, cfg.PluginArguments)
 					return nil
 				},
 				SendStandbyStatusUpdateFn: func(ctx context.Context, lsn uint64) error {
@@ -229,7 +232,7 @@ func TestHandler_StartReplication(t *testing.T) {
 				StartReplicationFn: func(ctx context.Context, cfg pglib.ReplicationConfig) error {
 					require.Equal(t, testLSN, cfg.StartPos)
 					require.Equal(t, testSlot, cfg.SlotName)
-					require.Equal(t, pluginArguments, cfg.PluginArguments)
+					require.Equal(t, wal2jsonPluginArguments, cfg.PluginArguments)
 					return nil
 				},
 				SendStandbyStatusUpdateFn: func(ctx context.Context, lsn uint64) error {
@@ -266,6 +269,7 @@ func TestHandler_StartReplication(t *testing.T) {
 				pgReplicationConn:     tc.replicationConn,
 				pgConnBuilder:         tc.connBuilder,
 				pgReplicationSlotName: tc.slotName,
+				pluginArguments:       wal2jsonPluginArguments,
 				lsnParser:             NewLSNParser(),
 			}
 
@@ -587,3 +591,164 @@ func TestHandler_verifyReplicationSlotExists(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_getServerVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		connBuilder func() (pglib.Querier, error)
+
+		wantVersion int
+		wantErr     error
+	}{
+		{
+			name: "ok",
+			connBuilder: func() (pglib.Querier, error) {
+				return &pgmocks.Querier{
+					QueryRowFn: func(ctx context.Context, query string, args ...any) pglib.Row {
+						require.Equal(t, "SHOW server_version_num", query)
+						return &mockRow{lsn: "140005"}
+					},
+					CloseFn: func(ctx context.Context) error { return nil },
+				}, nil
+			},
+
+			wantVersion: 140005,
+			wantErr:     nil,
+		},
+		{
+			name: "error - building connection",
+			connBuilder: func() (pglib.Querier, error) {
+				return nil, errTest
+			},
+
+			wantVersion: 0,
+			wantErr:     errTest,
+		},
+		{
+			name: "error - query execution",
+			connBuilder: func() (pglib.Querier, error) {
+				return &pgmocks.Querier{
+					QueryRowFn: func(ctx context.Context, query string, args ...any) pglib.Row {
+						return &mockRow{scanFn: func(args ...any) error { return errTest }}
+					},
+					CloseFn: func(ctx context.Context) error { return nil },
+				}, nil
+			},
+
+			wantVersion: 0,
+			wantErr:     errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := Handler{
+				logger:        log.NewNoopLogger(),
+				pgConnBuilder: tc.connBuilder,
+			}
+
+			version, err := h.getServerVersion(context.Background())
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantVersion, version)
+		})
+	}
+}
+
+func TestPgoutputPluginArguments(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		serverVersion int
+
+		wantArgs []string
+	}{
+		{
+			name:          "postgres 14",
+			serverVersion: 140005,
+
+			wantArgs: []string{
+				`proto_version '1'`,
+				`publication_names 'pgstream_publication'`,
+				`messages 'true'`,
+			},
+		},
+		{
+			name:          "postgres 13",
+			serverVersion: 130012,
+
+			wantArgs: []string{
+				`proto_version '1'`,
+				`publication_names 'pgstream_publication'`,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			args := pgoutputPluginArguments(DefaultPublicationName, tc.serverVersion)
+			require.Equal(t, tc.wantArgs, args)
+		})
+	}
+}
+
+func TestHandler_ReceiveMessage_pgoutput(t *testing.T) {
+	t.Parallel()
+
+	relations := map[uint32]*pglogrepl.RelationMessage{
+		1: {RelationID: 1, Namespace: "public", RelationName: "a"},
+		2: {RelationID: 2, Namespace: "public", RelationName: "b"},
+	}
+
+	receivedMessages := [][]byte{encodeBeginMessage(now), encodeTruncateMessage(1, 2)}
+	h := Handler{
+		logger: log.NewNoopLogger(),
+		pgReplicationConn: &pgmocks.ReplicationConn{
+			ReceiveMessageFn: func(ctx context.Context) (*pglib.ReplicationMessage, error) {
+				require.NotEmpty(t, receivedMessages)
+				data := receivedMessages[0]
+				receivedMessages = receivedMessages[1:]
+				return &pglib.ReplicationMessage{
+					LSN:        testLSN,
+					ServerTime: now,
+					WALData:    data,
+				}, nil
+			},
+		},
+		decoder: &pgoutputDecoder{
+			relations:    relations,
+			typeResolver: &mockTypeResolver{},
+			marshaler:    json.Marshal,
+		},
+		lsnParser: NewLSNParser(),
+	}
+
+	msg, err := h.ReceiveMessage(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, replication.LSN(testLSN), msg.LSN)
+	beginData := &wal.Data{}
+	require.NoError(t, json.Unmarshal(msg.Data, beginData))
+	require.True(t, beginData.IsBegin())
+
+	// truncate message produces one message per table, the second one is
+	// returned from the pending messages without receiving a new message
+	for _, table := range []string{"a", "b"} {
+		msg, err = h.ReceiveMessage(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, replication.LSN(testLSN), msg.LSN)
+
+		walData := &wal.Data{}
+		require.NoError(t, json.Unmarshal(msg.Data, walData))
+		require.Equal(t, "T", walData.Action)
+		require.Equal(t, table, walData.Table)
+		require.Equal(t, testLSNStr, walData.LSN)
+	}
+	require.Empty(t, receivedMessages)
+	require.Empty(t, h.pendingMessages)
+}
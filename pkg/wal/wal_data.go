@@ -16,18 +16,57 @@ import (
 type Event struct {
 	Data           *Data
 	CommitPosition CommitPosition
+	// Route is optional routing information set by the processor modifiers to
+	// override how the event is delivered by the target.
+	Route *Route
+	// Headers are the headers of the source message, for the events read from
+	// a message broker (i.e. kafka). Optional.
+	Headers map[string]string
+}
+
+// Route contains the delivery overrides for an event. Empty fields fall back to
+// the target defaults.
+type Route struct {
+	// Topic is the destination topic for the event.
+	Topic string
+	// Key is the key used to partition the event.
+	Key []byte
+	// Headers are additional headers delivered with the event.
+	Headers map[string]string
+	// Payload replaces the serialised event data as the delivered value.
+	Payload []byte
 }
 
 // Data contains the wal data properties identifying the table operation.
 type Data struct {
-	Action    string   `json:"action"`    // "I" -- insert, "U" -- update, "D" -- delete, "T" -- truncate
-	Timestamp string   `json:"timestamp"` // ISO8601, i.e. 2019-12-29 04:58:34.806671
-	LSN       string   `json:"lsn"`
-	Schema    string   `json:"schema"`
-	Table     string   `json:"table"`
-	Columns   []Column `json:"columns"`
-	Identity  []Column `json:"identity"`
-	Metadata  Metadata `json:"metadata"` // pgstream specific metadata
+	Action      string       `json:"action"`    // "I" -- insert, "U" -- update, "D" -- delete, "T" -- truncate, "B" -- begin, "C" -- commit, "M" -- logical decoding message
+	Timestamp   string       `json:"timestamp"` // ISO8601, i.e. 2019-12-29 04:58:34.806671
+	LSN         string       `json:"lsn"`
+	XID         uint32       `json:"xid,omitempty"` // id of the source transaction
+	Schema      string       `json:"schema"`
+	Table       string       `json:"table"`
+	Columns     []Column     `json:"columns"`
+	Identity    []Column     `json:"identity"`
+	Metadata    Metadata     `json:"metadata"`              // pgstream specific metadata
+	Transaction *Transaction `json:"transaction,omitempty"` // pgstream transaction metadata
+	// logical decoding message properties, only populated for "M" actions
+	Transactional bool   `json:"transactional,omitempty"`
+	Prefix        string `json:"prefix,omitempty"`
+	Content       string `json:"content,omitempty"`
+}
+
+// Transaction contains the metadata of the source transaction the event
+// belongs to, which allows downstream consumers to reconstruct transactions.
+type Transaction struct {
+	XID uint32 `json:"xid"`
+	// CommitLSN is the LSN of the transaction commit. It is always populated
+	// for commit events, and for all the transaction events when the output
+	// plugin provides it upfront on the begin message (pgoutput).
+	CommitLSN       string `json:"commit_lsn,omitempty"`
+	CommitTimestamp string `json:"commit_timestamp,omitempty"`
+	// Sequence is the position of the event within the transaction. The begin
+	// event has sequence 0, and the commit event is always the last one.
+	Sequence uint64 `json:"sequence"`
 }
 
 // Metadata is pgstream specific properties to help identify the id/version
@@ -76,6 +115,26 @@ func (d *Data) IsInsert() bool {
 	return d.Action == "I"
 }
 
+func (d *Data) IsBegin() bool {
+	return d.Action == "B"
+}
+
+func (d *Data) IsCommit() bool {
+	return d.Action == "C"
+}
+
+// IsLogicalMessage returns true if the data represents a logical decoding
+// message (emitted with pg_logical_emit_message), false otherwise.
+func (d *Data) IsLogicalMessage() bool {
+	return d.Action == "M"
+}
+
+// IsTransactionBoundary returns true if the data represents the begin or
+// commit of a transaction, false otherwise.
+func (d *Data) IsTransactionBoundary() bool {
+	return d.IsBegin() || d.IsCommit()
+}
+
 // IsEmpty returns true if the pgstream metadata hasn't been populated, false
 // otherwise.
 func (m Metadata) IsEmpty() bool {